@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/segmentio/ksuid"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"survey-platform/internal/models"
+)
+
+// importFormats guesses the format of an import from the extension of the file
+var importFormats = map[string]models.ImportFormat{
+	".json":   models.ImportDump,
+	".ndjson": models.ImportNDJSON,
+	".jsonl":  models.ImportNDJSON,
+	".csv":    models.ImportCSV,
+}
+
+// runImport imports the file in args into the storage chosen with the STORAGE environment variable
+// the report is printed to stdout, the exit code is 1 when any row was rejected and 2 when the import failed
+// the json storage is dumped once the import completes, so the server must not be running on the same files
+func runImport(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", "", "dump, ndjson or csv, guessed from the file extension when empty")
+	survey := flags.String("survey", "", "survey the csv rows answer, and the survey of ndjson responses without a survey_id")
+	dryRun := flags.Bool("dry-run", false, "validate the file without storing anything")
+	preserveIDs := flags.Bool("preserve-ids", false, "keep the ids in the file instead of generating new ones")
+	workspace := flags.String("workspace", string(models.DefaultWorkspace), "workspace the surveys and responses are imported into")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: survey-platform import [flags] <file|->")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	path := flags.Arg(0)
+	options := models.ImportOptions{Format: models.ImportFormat(*format), DryRun: *dryRun, PreserveIDs: *preserveIDs}
+	if options.Format == "" {
+		options.Format = importFormats[filepath.Ext(path)]
+	}
+	if !models.WorkspaceID(*workspace).Valid() {
+		log.Printf("invalid workspace %q", *workspace)
+		return 2
+	}
+	if !options.Format.Valid() {
+		log.Printf("cannot tell the format of %s, pass -format", path)
+		return 2
+	}
+	if *survey != "" {
+		id, err := ksuid.Parse(*survey)
+		if err != nil {
+			log.Printf("invalid survey id %q", *survey)
+			return 2
+		}
+		options.SurveyID = id
+	}
+	var input io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Println("error while opening import file", err)
+			return 2
+		}
+		defer file.Close()
+		input = file
+	}
+	store, err := newStorage()
+	if err != nil {
+		log.Println("error while initiating storage", err)
+		return 2
+	}
+	surveyService := newSurveyService(store)
+	report, err := surveyService.Import(models.WorkspaceID(*workspace), input, options)
+	if err != nil {
+		log.Println("import failed", err)
+		return 2
+	}
+	if !options.DryRun && store.snapshots {
+		surveyApp, _ := newApp(store, surveyService)
+		if err = surveyApp.Dump(); err != nil {
+			log.Println("dumping data failed", err)
+			return 2
+		}
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(report); err != nil {
+		log.Println("error while printing import report", err)
+		return 2
+	}
+	if len(report.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
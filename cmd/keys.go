@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/segmentio/ksuid"
+	"log"
+	"os"
+	"strings"
+	"survey-platform/internal/models"
+)
+
+// runKeys creates, lists or revokes the api keys in the storage chosen with the STORAGE environment variable
+// it is the way to create the first key, further keys can be managed over the api with a keys:manage key
+// the result is printed to stdout and the exit code is 2 when the command failed
+// the json storage is dumped once a key is written, so the server must not be running on the same files
+func runKeys(args []string) int {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "usage: survey-platform keys create [-workspace <workspace>] [-user <id>] -name <name> -scopes <scope,...>")
+		fmt.Fprintln(os.Stderr, "       survey-platform keys list [-workspace <workspace>]")
+		fmt.Fprintln(os.Stderr, "       survey-platform keys revoke [-workspace <workspace>] <id>")
+		fmt.Fprintln(os.Stderr, "scopes:", joinScopes(models.Scopes))
+	}
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+	flags := flag.NewFlagSet("keys "+args[0], flag.ContinueOnError)
+	workspace := flags.String("workspace", string(models.DefaultWorkspace), "workspace the key belongs to")
+	var run func(store *storage) (interface{}, error)
+	switch args[0] {
+	case "create":
+		name := flags.String("name", "", "name telling what the key is used for")
+		scopes := flags.String("scopes", "", "comma separated scopes granted to the key")
+		user := flags.String("user", "", "user the key is bound to, the key is then limited to the roles of the user")
+		if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 0 {
+			usage()
+			return 2
+		}
+		var userID *ksuid.KSUID
+		if *user != "" {
+			id, err := ksuid.Parse(*user)
+			if err != nil {
+				log.Printf("invalid user id %q", *user)
+				return 2
+			}
+			userID = &id
+		}
+		var granted []models.Scope
+		for _, scope := range strings.Split(*scopes, ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				granted = append(granted, models.Scope(scope))
+			}
+		}
+		run = func(store *storage) (interface{}, error) {
+			return newAPIKeyService(store).CreateKey(models.WorkspaceID(*workspace), *name, granted, userID)
+		}
+	case "list":
+		if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 0 {
+			usage()
+			return 2
+		}
+		run = func(store *storage) (interface{}, error) {
+			return newAPIKeyService(store).GetKeys(models.WorkspaceID(*workspace))
+		}
+	case "revoke":
+		if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 1 {
+			usage()
+			return 2
+		}
+		id, err := ksuid.Parse(flags.Arg(0))
+		if err != nil {
+			log.Printf("invalid api key id %q", flags.Arg(0))
+			return 2
+		}
+		run = func(store *storage) (interface{}, error) {
+			return newAPIKeyService(store).RevokeKey(models.WorkspaceID(*workspace), id)
+		}
+	default:
+		usage()
+		return 2
+	}
+	store, err := newStorage()
+	if err != nil {
+		log.Println("error while initiating storage", err)
+		return 2
+	}
+	result, err := run(store)
+	if err != nil {
+		log.Println(args[0], "failed", err)
+		return 2
+	}
+	if args[0] != "list" && store.snapshots {
+		surveyApp, _ := newApp(store, newSurveyService(store))
+		if err = surveyApp.Dump(); err != nil {
+			log.Println("dumping data failed", err)
+			return 2
+		}
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(result); err != nil {
+		log.Println("error while printing api keys", err)
+		return 2
+	}
+	return 0
+}
+
+func joinScopes(scopes []models.Scope) string {
+	names := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		names = append(names, string(scope))
+	}
+	return strings.Join(names, ", ")
+}
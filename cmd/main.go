@@ -3,32 +3,67 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"survey-platform/internal/app"
-	"survey-platform/internal/db/jsondb"
-	"survey-platform/internal/models"
-	"survey-platform/internal/repositories/responserepo"
-	"survey-platform/internal/repositories/surveyrepo"
+	"survey-platform/internal/db/snapshotter"
+	"survey-platform/internal/services/apikeyservice"
+	"survey-platform/internal/services/invitationservice"
+	"survey-platform/internal/services/mailservice"
 	"survey-platform/internal/services/surveyservice"
+	"survey-platform/internal/services/userservice"
 	"survey-platform/pkg/idgenerator/ksuidgenerator"
+	"survey-platform/pkg/mailer"
 	"survey-platform/pkg/timegenerator/actualtimegenerator"
+	"sync"
 	"syscall"
 	"time"
 )
 
 const (
-	AppPortEnv  = "APP_PORT"
-	AppPort     = ":8080"
-	surveyAppDB = "survey_app.json"
+	AppPortEnv          = "APP_PORT"
+	AppPort             = ":8080"
+	SnapshotIntervalEnv = "SNAPSHOT_INTERVAL"
+	SnapshotInterval    = time.Minute
+	SnapshotKeepEnv     = "SNAPSHOT_KEEP"
+	SnapshotKeep        = 5
+	IdempotencyTTLEnv   = "IDEMPOTENCY_TTL"
+	// DraftTTLEnv is how long a draft response which is not updated can be resumed
+	DraftTTLEnv = "DRAFT_TTL"
+	// InvitationSecretEnv holds the secret invitation tokens are signed with
+	InvitationSecretEnv = "INVITATION_SECRET"
+	// SMTPAddrEnv is the host:port of the SMTP server invitations are emailed through, emailing is disabled when it is not set
+	SMTPAddrEnv        = "SMTP_ADDR"
+	SMTPUsernameEnv    = "SMTP_USERNAME"
+	SMTPPasswordEnv    = "SMTP_PASSWORD"
+	MailFromEnv        = "MAIL_FROM"
+	MailFrom           = "surveys@localhost"
+	InvitationLinkEnv  = "INVITATION_LINK"
+	InvitationLink     = "http://localhost:8080/invitation/"
+	MailTemplatesEnv   = "MAIL_TEMPLATES"
+	MailIntervalEnv    = "MAIL_INTERVAL"
+	MailInterval       = 10 * time.Second
+	MailMaxAttemptsEnv = "MAIL_MAX_ATTEMPTS"
+	MailBackoffEnv     = "MAIL_BACKOFF"
+	ReminderAfterEnv   = "REMINDER_AFTER"
+	ReminderMaxEnv     = "REMINDER_MAX"
+	// OrphanIntervalEnv is how often the stored responses are checked for responses of deleted surveys
+	OrphanIntervalEnv = "ORPHAN_INTERVAL"
+	OrphanInterval    = time.Hour
+	// DraftPurgeIntervalEnv is how often the expired drafts are deleted
+	DraftPurgeIntervalEnv = "DRAFT_PURGE_INTERVAL"
+	DraftPurgeInterval    = time.Hour
 )
 
 // serve handles the logic of running  server in a goroutine and waiting for signal to gracefully stop the server
 // on ctx.Done signal a request to shut down the server is sent, so that no new requests will be served
-// after that the data is dumped to the file
-func serve(ctx context.Context, surveyApp *app.SurveyApp) {
+// after that the data is dumped to the file, once the periodic snapshots tracked by snapshots stopped
+func serve(ctx context.Context, surveyApp *app.SurveyApp, snapshots *sync.WaitGroup) {
 	router := surveyApp.SetupRoutes()
 	port := os.Getenv(AppPortEnv)
 	if port == "" {
@@ -53,6 +88,8 @@ func serve(ctx context.Context, surveyApp *app.SurveyApp) {
 	if err := srv.Shutdown(ctxShutDown); err != nil {
 		log.Fatalf("server Shutdown Failed:%s", err.Error())
 	}
+	// the snapshotter stops along with ctx, the last dump waits for it so that the two never overlap
+	snapshots.Wait()
 	log.Println("application stopped accepting requests, dumping data")
 	if err := surveyApp.Dump(); err != nil {
 		log.Fatalln("dumping data failed", err.Error())
@@ -60,26 +97,153 @@ func serve(ctx context.Context, surveyApp *app.SurveyApp) {
 	log.Println("dumping data complete. app exiting!!")
 }
 
-// main initiates new app and calls serve to start the server
+// durationFromEnv reads a duration like 30s or 5m from the environment variable name, falling back to def
+func durationFromEnv(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Fatalf("invalid value %q for %s", value, name)
+	}
+	return duration
+}
+
+// intFromEnv reads a non negative integer from the environment variable name, falling back to def
+func intFromEnv(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Fatalf("invalid value %q for %s", value, name)
+	}
+	return n
+}
+
+// newSurveyService returns the service working on store, drafts expire after DRAFT_TTL
+func newSurveyService(store *storage) *surveyservice.SurveyService {
+	idGenerator := ksuidgenerator.NewKSUIDGenerator()
+	timeGenerator := actualtimegenerator.NewActualTimeGenerator()
+	surveyService := surveyservice.NewSurveyService(3, store.surveyRepo, store.responseRepo, idGenerator, timeGenerator)
+	surveyService.SetDrafts(store.draftRepo, durationFromEnv(DraftTTLEnv, surveyservice.DefaultDraftTTL))
+	return surveyService
+}
+
+// newAPIKeyService returns the service authenticating requests with the api keys in store
+func newAPIKeyService(store *storage) *apikeyservice.APIKeyService {
+	return apikeyservice.NewAPIKeyService(store.apiKeyRepo, store.userRepo, ksuidgenerator.NewKSUIDGenerator(), actualtimegenerator.NewActualTimeGenerator())
+}
+
+// newUserService returns the service deciding what the keys bound to the users in store may do
+func newUserService(store *storage) *userservice.UserService {
+	return userservice.NewUserService(store.userRepo, store.surveyRepo, ksuidgenerator.NewKSUIDGenerator(), actualtimegenerator.NewActualTimeGenerator())
+}
+
+// newInvitationService returns the service inviting the respondents in store
+func newInvitationService(store *storage) *invitationservice.InvitationService {
+	return invitationservice.NewInvitationService(store.invitationRepo, store.respondentRepo, store.surveyRepo,
+		ksuidgenerator.NewKSUIDGenerator(), actualtimegenerator.NewActualTimeGenerator(), invitationSecret())
+}
+
+// invitationSecret returns the secret in INVITATION_SECRET, or a random one when it is not set
+// tokens signed with a random secret stop working once the process exits
+func invitationSecret() []byte {
+	if secret := os.Getenv(InvitationSecretEnv); secret != "" {
+		return []byte(secret)
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatalln("error while generating invitation secret", err)
+	}
+	return secret
+}
+
+// newMailService returns the service emailing the invitations of invitationService through the SMTP server in SMTP_ADDR
+// without SMTP_ADDR nothing is emailed, the outbox is still loaded so that it is dumped again
+func newMailService(store *storage, invitationService *invitationservice.InvitationService) *mailservice.MailService {
+	config := mailservice.DefaultConfig()
+	config.Link = os.Getenv(InvitationLinkEnv)
+	if config.Link == "" {
+		config.Link = InvitationLink
+	}
+	config.Invitation = mailTemplate("invitation", config.Invitation)
+	config.Reminder = mailTemplate("reminder", config.Reminder)
+	config.ReminderAfter = durationFromEnv(ReminderAfterEnv, config.ReminderAfter)
+	config.MaxReminders = intFromEnv(ReminderMaxEnv, config.MaxReminders)
+	config.MaxAttempts = intFromEnv(MailMaxAttemptsEnv, config.MaxAttempts)
+	config.Backoff = durationFromEnv(MailBackoffEnv, config.Backoff)
+	var sender mailer.Sender
+	if addr := os.Getenv(SMTPAddrEnv); addr != "" {
+		from := os.Getenv(MailFromEnv)
+		if from == "" {
+			from = MailFrom
+		}
+		sender = mailer.NewSMTPSender(addr, from, mailer.PlainAuth(addr, os.Getenv(SMTPUsernameEnv), os.Getenv(SMTPPasswordEnv)))
+	}
+	return mailservice.NewMailService(store.outboxRepo, store.surveyRepo, invitationService, sender,
+		ksuidgenerator.NewKSUIDGenerator(), actualtimegenerator.NewActualTimeGenerator(), config)
+}
+
+// mailTemplate returns the template <name>.tmpl of the MAIL_TEMPLATES directory, or def when there is no such file
+func mailTemplate(name string, def *mailer.Template) *mailer.Template {
+	dir := os.Getenv(MailTemplatesEnv)
+	if dir == "" {
+		return def
+	}
+	fileName := filepath.Join(dir, name+".tmpl")
+	if _, err := os.Stat(fileName); os.IsNotExist(err) {
+		return def
+	}
+	template, err := mailer.ParseTemplateFile(fileName)
+	if err != nil {
+		log.Fatalf("invalid %s template %s: %s", name, fileName, err)
+	}
+	return template
+}
+
+// newApp returns the app serving store along with the service emailing its invitations
+// it is also used by the subcommands to dump what they wrote
+func newApp(store *storage, surveyService *surveyservice.SurveyService) (*app.SurveyApp, *mailservice.MailService) {
+	invitationService := newInvitationService(store)
+	mailService := newMailService(store, invitationService)
+	surveyApp := app.NewSurveyApp(store.db, surveyService, newAPIKeyService(store), newUserService(store), invitationService)
+	surveyApp.SetMailService(mailService)
+	return surveyApp, mailService
+}
+
+// main initiates new app and calls serve to start the server, or runs the import, keys, users or orphans subcommand
 // it also spawns a goroutine to listen to os signals SIGINT or SIGTERM
+// and, for the json storage, a snapshotter which dumps the data periodically while the server runs
+// along with the mail service sending the queued emails when SMTP_ADDR is set
+// and a check logging the responses left behind by deleted surveys
 // once the os signal is received the cancel func of ctx passed to serve is called
 // notifying it to initiate a graceful shutdown
 func main() {
-	jsonDB, err := jsondb.NewJsonDB(surveyAppDB)
-	if err != nil {
-		log.Fatalln("error while initiating db")
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(runImport(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "keys" {
+		os.Exit(runKeys(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "users" {
+		os.Exit(runUsers(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "orphans" {
+		os.Exit(runOrphans(os.Args[2:]))
 	}
-	var dbEntry = models.DBEntry{}
-	err = jsonDB.Load(&dbEntry)
+	store, err := newStorage()
 	if err != nil {
-		log.Fatalln("error while loading persisted entries")
+		log.Fatalln("error while initiating storage", err)
 	}
-	surveyRepo := surveyrepo.NewSurveyRepo(dbEntry.Surveys)
-	responseRepo := responserepo.NewResponseRepo(dbEntry.Responses)
-	idGenerator := ksuidgenerator.NewKSUIDGenerator()
-	timeGenerator := actualtimegenerator.NewActualTimeGenerator()
-	surveyService := surveyservice.NewSurveyService(3, surveyRepo, responseRepo, idGenerator, timeGenerator)
-	surveyApp := app.NewSurveyApp(jsonDB, surveyService)
+	if os.Getenv(InvitationSecretEnv) == "" {
+		log.Printf("%s is not set, invitation tokens will stop working when the app restarts", InvitationSecretEnv)
+	}
+	surveyService := newSurveyService(store)
+	surveyApp, mailService := newApp(store, surveyService)
+	surveyApp.SetIdempotencyTTL(durationFromEnv(IdempotencyTTLEnv, app.DefaultIdempotencyTTL))
 	defer func() {
 		if err := recover(); err != nil {
 			log.Println("recovering from panic, dumping data")
@@ -100,5 +264,20 @@ func main() {
 		log.Printf("system call received")
 		cancel()
 	}()
-	serve(ctx, surveyApp)
+	var snapshots sync.WaitGroup
+	if store.snapshots {
+		snapshots.Add(1)
+		go func() {
+			defer snapshots.Done()
+			snapshotter.NewSnapshotter(durationFromEnv(SnapshotIntervalEnv, SnapshotInterval), surveyApp).Run(ctx)
+		}()
+	}
+	if os.Getenv(SMTPAddrEnv) != "" {
+		go mailService.Run(ctx, durationFromEnv(MailIntervalEnv, MailInterval))
+	} else {
+		log.Printf("%s is not set, invitations will not be emailed", SMTPAddrEnv)
+	}
+	go surveyService.WatchOrphans(ctx, durationFromEnv(OrphanIntervalEnv, OrphanInterval))
+	go surveyService.WatchDrafts(ctx, durationFromEnv(DraftPurgeIntervalEnv, DraftPurgeInterval))
+	serve(ctx, surveyApp, &snapshots)
 }
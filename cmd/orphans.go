@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+)
+
+// runOrphans prints the responses left behind by surveys deleted before deleting a survey took care of its responses
+// it reads the storage chosen with the STORAGE environment variable without changing it, opening the json storage still
+// repairs its journal so the server must not be running on the same files, a running server logs the same report instead
+// the orphaned response sets are printed to stdout, the exit code is 1 when any was found and 2 when the command failed
+func runOrphans(args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: survey-platform orphans")
+		return 2
+	}
+	store, err := newStorage()
+	if err != nil {
+		log.Println("error while initiating storage", err)
+		return 2
+	}
+	orphans, err := newSurveyService(store).FindOrphans()
+	if err != nil {
+		log.Println("looking for orphaned responses failed", err)
+		return 2
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(orphans); err != nil {
+		log.Println("error while printing orphaned responses", err)
+		return 2
+	}
+	if len(orphans) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"survey-platform/internal/db"
+	"survey-platform/internal/db/jsondb"
+	"survey-platform/internal/db/nopdb"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/apikeyrepo"
+	"survey-platform/internal/repositories/draftrepo"
+	"survey-platform/internal/repositories/invitationrepo"
+	"survey-platform/internal/repositories/outboxrepo"
+	"survey-platform/internal/repositories/respondentrepo"
+	"survey-platform/internal/repositories/responserepo"
+	"survey-platform/internal/repositories/sqliterepo"
+	"survey-platform/internal/repositories/surveyrepo"
+	"survey-platform/internal/repositories/userrepo"
+)
+
+const (
+	StorageEnv         = "STORAGE"
+	StorageJSON        = "json"
+	StorageSQLite      = "sqlite"
+	SQLitePathEnv      = "SQLITE_PATH"
+	SQLitePath         = "survey_app.db"
+	surveyAppDB        = "survey_app.json"
+	surveyAppDBJournal = "survey_app.json.wal"
+)
+
+// storage is the persistence chosen with the STORAGE environment variable
+// snapshots tells whether the data needs to be dumped periodically
+type storage struct {
+	db           db.DB
+	surveyRepo   repositories.SurveyRepoInterface
+	responseRepo repositories.ResponseRepoInterface
+	apiKeyRepo   repositories.APIKeyRepoInterface
+	userRepo     repositories.UserRepoInterface
+	// respondentRepo and invitationRepo hold the respondents invited to surveys, responseRepo completes their invitations
+	respondentRepo repositories.RespondentRepoInterface
+	invitationRepo repositories.InvitationRepoInterface
+	// outboxRepo holds the emails to respondents until they are sent
+	outboxRepo repositories.OutboxRepoInterface
+	// draftRepo holds the responses being answered, submitting a draft stores its response in responseRepo
+	draftRepo repositories.DraftRepoInterface
+	snapshots bool
+}
+
+func newStorage() (*storage, error) {
+	switch kind := os.Getenv(StorageEnv); kind {
+	case "", StorageJSON:
+		return newJSONStorage()
+	case StorageSQLite:
+		return newSQLiteStorage()
+	default:
+		return nil, fmt.Errorf("unknown storage %q, use %s or %s", kind, StorageJSON, StorageSQLite)
+	}
+}
+
+// newJSONStorage loads the last dump into in memory repos and replays the journal written after it
+func newJSONStorage() (*storage, error) {
+	jsonDB, err := jsondb.NewJsonDB(surveyAppDB, surveyAppDBJournal, intFromEnv(SnapshotKeepEnv, SnapshotKeep))
+	if err != nil {
+		return nil, err
+	}
+	var dbEntry = models.DBEntry{}
+	err = jsonDB.Load(&dbEntry)
+	if err != nil {
+		return nil, fmt.Errorf("loading persisted entries: %w", err)
+	}
+	dbEntry.Migrate()
+	surveys, versions, responses := dbEntry.Flatten()
+	invitationRepo := invitationrepo.NewInvitationRepo(dbEntry.Invitations, jsonDB)
+	responseRepo := responserepo.NewResponseRepo(responses, jsonDB).WithInvitations(invitationRepo)
+	surveyRepo := surveyrepo.NewSurveyRepo(surveys, versions, jsonDB).WithResponses(responseRepo)
+	apiKeyRepo := apikeyrepo.NewAPIKeyRepo(dbEntry.APIKeys, jsonDB)
+	userRepo := userrepo.NewUserRepo(dbEntry.Users, jsonDB)
+	respondentRepo := respondentrepo.NewRespondentRepo(dbEntry.Respondents, jsonDB)
+	outboxRepo := outboxrepo.NewOutboxRepo(dbEntry.Outbox, jsonDB)
+	draftRepo := draftrepo.NewDraftRepo(dbEntry.Drafts, jsonDB).WithExpired(dbEntry.ExpiredDrafts).WithResponses(responseRepo)
+	err = repositories.Replay(jsonDB, surveyRepo, responseRepo, apiKeyRepo, userRepo, respondentRepo, invitationRepo, outboxRepo, draftRepo)
+	if err != nil {
+		return nil, fmt.Errorf("replaying journal: %w", err)
+	}
+	return &storage{
+		db:             jsonDB,
+		surveyRepo:     surveyRepo,
+		responseRepo:   responseRepo,
+		apiKeyRepo:     apiKeyRepo,
+		userRepo:       userRepo,
+		respondentRepo: respondentRepo,
+		invitationRepo: invitationRepo,
+		outboxRepo:     outboxRepo,
+		draftRepo:      draftRepo,
+		snapshots:      true,
+	}, nil
+}
+
+// newSQLiteStorage persists every write to sqlite, so dumping the data is a no-op
+func newSQLiteStorage() (*storage, error) {
+	fileName := os.Getenv(SQLitePathEnv)
+	if fileName == "" {
+		fileName = SQLitePath
+	}
+	sqliteDB, err := sqliterepo.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("using sqlite storage %s", fileName)
+	return &storage{
+		db:             nopdb.NewNopDB(),
+		surveyRepo:     sqliterepo.NewSurveyRepo(sqliteDB),
+		responseRepo:   sqliterepo.NewResponseRepo(sqliteDB),
+		apiKeyRepo:     sqliterepo.NewAPIKeyRepo(sqliteDB),
+		userRepo:       sqliterepo.NewUserRepo(sqliteDB),
+		respondentRepo: sqliterepo.NewRespondentRepo(sqliteDB),
+		invitationRepo: sqliterepo.NewInvitationRepo(sqliteDB),
+		outboxRepo:     sqliterepo.NewOutboxRepo(sqliteDB),
+		draftRepo:      sqliterepo.NewDraftRepo(sqliteDB),
+	}, nil
+}
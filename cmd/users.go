@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/segmentio/ksuid"
+	"log"
+	"os"
+	"strings"
+	"survey-platform/internal/models"
+)
+
+// runUsers creates or lists the users in the storage chosen with the STORAGE environment variable
+// it is the way to create the first owner of a workspace, further users can be managed over the api with a users:manage key
+// the result is printed to stdout and the exit code is 2 when the command failed
+// the json storage is dumped once a user is written, so the server must not be running on the same files
+func runUsers(args []string) int {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "usage: survey-platform users create [-workspace <workspace>] -name <name> [-email <email>] -role <role> [-survey <id>]")
+		fmt.Fprintln(os.Stderr, "       survey-platform users list [-workspace <workspace>]")
+		fmt.Fprintln(os.Stderr, "roles:", joinRoles(models.Roles))
+	}
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+	flags := flag.NewFlagSet("users "+args[0], flag.ContinueOnError)
+	workspace := flags.String("workspace", string(models.DefaultWorkspace), "workspace the user belongs to")
+	var run func(store *storage) (interface{}, error)
+	switch args[0] {
+	case "create":
+		name := flags.String("name", "", "name of the user")
+		email := flags.String("email", "", "email of the user")
+		role := flags.String("role", "", "role of the user on the workspace, or on the survey given with -survey")
+		survey := flags.String("survey", "", "survey the role is limited to")
+		if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 0 || *role == "" {
+			usage()
+			return 2
+		}
+		assignment := models.RoleAssignment{Role: models.Role(*role)}
+		if *survey != "" {
+			id, err := ksuid.Parse(*survey)
+			if err != nil {
+				log.Printf("invalid survey id %q", *survey)
+				return 2
+			}
+			assignment.SurveyID = &id
+		}
+		user := models.User{Name: *name, Email: *email, Roles: []models.RoleAssignment{assignment}}
+		run = func(store *storage) (interface{}, error) {
+			return newUserService(store).CreateUser(models.WorkspaceID(*workspace), user)
+		}
+	case "list":
+		if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 0 {
+			usage()
+			return 2
+		}
+		run = func(store *storage) (interface{}, error) {
+			return newUserService(store).GetUsers(models.WorkspaceID(*workspace))
+		}
+	default:
+		usage()
+		return 2
+	}
+	store, err := newStorage()
+	if err != nil {
+		log.Println("error while initiating storage", err)
+		return 2
+	}
+	result, err := run(store)
+	if err != nil {
+		log.Println(args[0], "failed", err)
+		return 2
+	}
+	if args[0] != "list" && store.snapshots {
+		surveyApp, _ := newApp(store, newSurveyService(store))
+		if err = surveyApp.Dump(); err != nil {
+			log.Println("dumping data failed", err)
+			return 2
+		}
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(result); err != nil {
+		log.Println("error while printing users", err)
+		return 2
+	}
+	return 0
+}
+
+func joinRoles(roles []models.Role) string {
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		names = append(names, string(role))
+	}
+	return strings.Join(names, ", ")
+}
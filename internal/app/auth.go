@@ -0,0 +1,229 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/segmentio/ksuid"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/services"
+	"survey-platform/internal/services/surveyservice"
+)
+
+// apiKeyContextKey holds the api key which authenticated the request in the gin context
+const apiKeyContextKey = "api_key"
+
+// authorize returns a middleware letting through the requests carrying an `Authorization: Bearer <key>` header
+// with an active api key granting every scope in scopes, the key is then available under apiKeyContextKey
+// requests without a valid key are rejected with 401 and keys missing a scope with 403
+// keys bound to a user also need the user to hold a role granting the scopes on the whole workspace
+func (a *SurveyApp) authorize(scopes ...models.Scope) gin.HandlerFunc {
+	return a.authorizeOn(nil, scopes...)
+}
+
+// authorizeOn is authorize for the routes of a single survey, the user of a key bound to a user needs a role
+// granting the scopes on the survey surveyOf returns, or on the whole workspace when it returns nil
+// the survey service checks the roles again for every call, see surveys
+func (a *SurveyApp) authorizeOn(surveyOf func(c *gin.Context) *ksuid.KSUID, scopes ...models.Scope) gin.HandlerFunc {
+	if a.apiKeyService == nil {
+		return func(c *gin.Context) {}
+	}
+	return func(c *gin.Context) {
+		fields := strings.Fields(c.GetHeader("Authorization"))
+		if len(fields) != 2 || !strings.EqualFold(fields[0], "Bearer") {
+			unauthorized(c, "missing bearer api key")
+			return
+		}
+		key, err := a.apiKeyService.Authenticate(fields[1])
+		if err == services.ErrInvalidAPIKey {
+			unauthorized(c, err.Error())
+			return
+		} else if err != nil {
+			log.Println("error while authenticating api key", err)
+			c.JSONP(http.StatusInternalServerError, Response{Message: "error while authenticating " + err.Error(), ApiVersion: ApiVersion})
+			c.Abort()
+			return
+		}
+		if !key.Allows(scopes...) {
+			log.Println("api key without scope", key.ID.String(), scopes)
+			c.JSONP(http.StatusForbidden, Response{Message: "api key does not grant " + joinScopes(scopes), ApiVersion: ApiVersion})
+			c.Abort()
+			return
+		}
+		if a.userService != nil {
+			var surveyID *ksuid.KSUID
+			if surveyOf != nil {
+				surveyID = surveyOf(c)
+			}
+			err = a.userService.Authorize(key, surveyID, scopes...)
+			if errors.Is(err, services.ErrForbidden) {
+				log.Println("api key denied by the roles of its user", key.ID.String(), err)
+				c.JSONP(http.StatusForbidden, Response{Message: err.Error(), ApiVersion: ApiVersion})
+				c.Abort()
+				return
+			} else if err != nil {
+				log.Println("error while authorizing api key", err)
+				c.JSONP(http.StatusInternalServerError, Response{Message: "error while authorizing " + err.Error(), ApiVersion: ApiVersion})
+				c.Abort()
+				return
+			}
+		}
+		c.Set(apiKeyContextKey, key)
+	}
+}
+
+// surveyParam returns the survey in the id param, nil when it is not a valid id
+func surveyParam(c *gin.Context) *ksuid.KSUID {
+	return parseID(c.Param("id"))
+}
+
+// surveyInQuery returns the survey in the survey_id query parameter, nil when it is not a valid id
+func surveyInQuery(c *gin.Context) *ksuid.KSUID {
+	return parseID(c.Query("survey_id"))
+}
+
+// surveyInBody returns the survey in the survey_id field of the json body, nil when it is not a valid id
+// the body is put back so that the handler can read it again
+func surveyInBody(c *gin.Context) *ksuid.KSUID {
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	var target struct {
+		SurveyID ksuid.KSUID `json:"survey_id"`
+	}
+	if err = json.Unmarshal(body, &target); err != nil || target.SurveyID.IsNil() {
+		return nil
+	}
+	return &target.SurveyID
+}
+
+// surveyInToken returns the survey the invitation token in the token param was signed for, nil when it has none
+// the signature is not checked here, a token altered to reach another survey is rejected by the handler
+func surveyInToken(c *gin.Context) *ksuid.KSUID {
+	return parseID(strings.SplitN(c.Param("token"), ".", 2)[0])
+}
+
+// surveyInTokenHeader returns the survey the invitation token in the Invitation-Token header was signed for, see surveyInToken
+func surveyInTokenHeader(c *gin.Context) *ksuid.KSUID {
+	return parseID(strings.SplitN(c.GetHeader(InvitationTokenHeader), ".", 2)[0])
+}
+
+func parseID(value string) *ksuid.KSUID {
+	id, err := ksuid.Parse(value)
+	if err != nil {
+		return nil
+	}
+	return &id
+}
+
+// workspace returns the workspace of the api key which authenticated the request, every read and write of the
+// request is scoped to it, the default workspace is used when api keys are disabled
+func workspace(c *gin.Context) models.WorkspaceID {
+	if key, ok := c.Get(apiKeyContextKey); ok {
+		return key.(*models.APIKey).Workspace()
+	}
+	return models.DefaultWorkspace
+}
+
+// surveys returns the survey service acting for the api key of the request, so that the service checks the scopes
+// and roles of the key for the surveys each call reaches, the plain service is used when api keys are disabled
+func (a *SurveyApp) surveys(c *gin.Context) services.SurveyServiceInterface {
+	key, ok := c.Get(apiKeyContextKey)
+	if !ok {
+		return a.surveyService
+	}
+	return surveyservice.NewAuthorizedService(a.surveyService, a.userService, key.(*models.APIKey))
+}
+
+// forbidden answers 403 when err is services.ErrForbidden and reports whether it did
+func forbidden(c *gin.Context, err error) bool {
+	if !errors.Is(err, services.ErrForbidden) {
+		return false
+	}
+	log.Println("access denied by the survey service", c.Request.Method, c.Request.URL.Path, err)
+	c.JSONP(http.StatusForbidden, Response{Message: err.Error(), ApiVersion: ApiVersion})
+	return true
+}
+
+func unauthorized(c *gin.Context, message string) {
+	log.Println("unauthenticated request", c.Request.Method, c.Request.URL.Path, message)
+	c.Header("WWW-Authenticate", `Bearer realm="survey-platform"`)
+	c.JSONP(http.StatusUnauthorized, Response{Message: message, ApiVersion: ApiVersion})
+	c.Abort()
+}
+
+func joinScopes(scopes []models.Scope) string {
+	names := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		names = append(names, string(scope))
+	}
+	return strings.Join(names, ", ")
+}
+
+type apiKeyRequest struct {
+	Name   string         `json:"name"`
+	Scopes []models.Scope `json:"scopes"`
+	UserID *ksuid.KSUID   `json:"user_id"`
+}
+
+// CreateAPIKey creates a key with the name and scopes in the body in the workspace of the caller,
+// bound to the user in user_id when it is set, the key is only returned in this response
+func (a *SurveyApp) CreateAPIKey(c *gin.Context) {
+	var request apiKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Println("error while reading api key body", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "malformed body", ApiVersion: ApiVersion})
+		return
+	}
+	created, err := a.apiKeyService.CreateKey(workspace(c), request.Name, request.Scopes, request.UserID)
+	if errors.Is(err, services.ErrInvalidAPIKeySpec) {
+		log.Println("invalid api key", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
+		log.Println("error while creating api key", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while creating api key " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	c.JSONP(http.StatusCreated, Response{Message: "api key created, it cannot be read again", Data: created, ApiVersion: ApiVersion})
+}
+
+// GetAPIKeys lists every api key of the workspace of the caller, revoked ones included
+func (a *SurveyApp) GetAPIKeys(c *gin.Context) {
+	keys, err := a.apiKeyService.GetKeys(workspace(c))
+	if err != nil {
+		log.Println("error while getting api keys", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while reading api keys " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	c.JSONP(http.StatusOK, Response{Message: "api keys", Data: keys, ApiVersion: ApiVersion})
+}
+
+// RevokeAPIKey stops a key from authenticating requests
+func (a *SurveyApp) RevokeAPIKey(c *gin.Context) {
+	id, err := ksuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Println("error while parsing api key id", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid api key id", ApiVersion: ApiVersion})
+		return
+	}
+	key, err := a.apiKeyService.RevokeKey(workspace(c), id)
+	if err == repositories.ErrNotFound {
+		log.Println("api key not found while revoking", id.String())
+		c.JSONP(http.StatusNotFound, Response{Message: "error while revoking api key " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
+		log.Println("error while revoking api key", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while revoking api key " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	c.JSONP(http.StatusOK, Response{Message: "api key revoked", Data: key, ApiVersion: ApiVersion})
+}
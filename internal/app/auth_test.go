@@ -0,0 +1,306 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/userrepo"
+	"survey-platform/internal/services"
+	"survey-platform/internal/services/services_mock"
+	"survey-platform/internal/services/userservice"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSurveyApp_Authorize(t *testing.T) {
+	forms := &models.APIKey{ID: ksuid.New(), Name: "forms", Scopes: []models.Scope{models.ScopeSurveysRead, models.ScopeResponsesWrite}}
+	t.Run("should let through keys granting the scope of the route", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockAPIKeyService := services_mock.NewMockAPIKeyServiceInterface(ctrl)
+		mockAPIKeyService.EXPECT().Authenticate("sp_forms").Return(forms, nil)
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().GetAllSurveys(models.DefaultWorkspace, gomock.Any()).Return(&models.SurveyPage{Surveys: []models.Survey{}}, nil)
+		router := NewSurveyApp(nil, mockService, mockAPIKeyService, nil, nil).SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, "/survey/", nil)
+		req.Header.Set("Authorization", "Bearer sp_forms")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+	t.Run("should scope the request to the workspace of the key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		acme := &models.APIKey{ID: ksuid.New(), WorkspaceID: "acme", Scopes: []models.Scope{models.ScopeSurveysRead}}
+		mockAPIKeyService := services_mock.NewMockAPIKeyServiceInterface(ctrl)
+		mockAPIKeyService.EXPECT().Authenticate("sp_acme").Return(acme, nil).Times(2)
+		surveyID := ksuid.New()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().GetAllSurveys(models.WorkspaceID("acme"), gomock.Any()).Return(&models.SurveyPage{Surveys: []models.Survey{}}, nil)
+		mockService.EXPECT().GetSurveyDetails(models.WorkspaceID("acme"), surveyID).Return(nil, repositories.ErrNotFound)
+		router := NewSurveyApp(nil, mockService, mockAPIKeyService, nil, nil).SetupRoutes()
+		for path, code := range map[string]int{"/survey/": http.StatusOK, "/survey/" + surveyID.String(): http.StatusNotFound} {
+			req, _ := http.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("Authorization", "Bearer sp_acme")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.Equal(t, code, resp.Code, path)
+		}
+	})
+	t.Run("should return statusForbidden(403) for keys missing the scope of the route", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockAPIKeyService := services_mock.NewMockAPIKeyServiceInterface(ctrl)
+		mockAPIKeyService.EXPECT().Authenticate("sp_forms").Return(forms, nil).Times(3)
+		router := NewSurveyApp(nil, services_mock.NewMockSurveyServiceInterface(ctrl), mockAPIKeyService, nil, nil).SetupRoutes()
+		for _, route := range []struct{ method, path string }{
+			{http.MethodDelete, fmt.Sprintf("/survey/%s", ksuid.New().String())},
+			{http.MethodGet, fmt.Sprintf("/response/?survey_id=%s", ksuid.New().String())},
+			{http.MethodGet, "/key/"},
+		} {
+			req, _ := http.NewRequest(route.method, route.path, nil)
+			req.Header.Set("Authorization", "Bearer sp_forms")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusForbidden, resp.Code, route.path)
+		}
+	})
+	t.Run("should return statusUnauthorized(401) without a valid key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockAPIKeyService := services_mock.NewMockAPIKeyServiceInterface(ctrl)
+		mockAPIKeyService.EXPECT().Authenticate("sp_revoked").Return(nil, services.ErrInvalidAPIKey)
+		router := NewSurveyApp(nil, services_mock.NewMockSurveyServiceInterface(ctrl), mockAPIKeyService, nil, nil).SetupRoutes()
+		for _, header := range []string{"", "sp_revoked", "Basic sp_revoked", "Bearer sp_revoked"} {
+			req, _ := http.NewRequest(http.MethodPost, "/response/", nil)
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusUnauthorized, resp.Code, header)
+			assert.Contains(t, resp.Header().Get("WWW-Authenticate"), "Bearer")
+		}
+	})
+	t.Run("should return StatusInternalServerError(500) when keys cannot be read", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockAPIKeyService := services_mock.NewMockAPIKeyServiceInterface(ctrl)
+		mockAPIKeyService.EXPECT().Authenticate("sp_forms").Return(nil, errors.New("something went wrong"))
+		router := NewSurveyApp(nil, services_mock.NewMockSurveyServiceInterface(ctrl), mockAPIKeyService, nil, nil).SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, "/survey/", nil)
+		req.Header.Set("Authorization", "Bearer sp_forms")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	})
+	t.Run("should leave the health check open", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		router := NewSurveyApp(nil, nil, services_mock.NewMockAPIKeyServiceInterface(ctrl), nil, nil).SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+}
+
+// adminApp returns an app whose requests are authenticated as a key allowed to manage keys
+func adminApp(ctrl *gomock.Controller) (*services_mock.MockAPIKeyServiceInterface, http.Handler) {
+	mockAPIKeyService := services_mock.NewMockAPIKeyServiceInterface(ctrl)
+	mockAPIKeyService.EXPECT().Authenticate("sp_admin").
+		Return(&models.APIKey{ID: ksuid.New(), Scopes: []models.Scope{models.ScopeKeysManage}}, nil).AnyTimes()
+	return mockAPIKeyService, NewSurveyApp(nil, nil, mockAPIKeyService, nil, nil).SetupRoutes()
+}
+
+func adminRequest(method, path string, body []byte) *http.Request {
+	req, _ := http.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer sp_admin")
+	return req
+}
+
+func TestSurveyApp_CreateAPIKey(t *testing.T) {
+	t.Run("should return statusCreated(201) with the key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockAPIKeyService, router := adminApp(ctrl)
+		created := &models.CreatedAPIKey{APIKey: models.APIKey{ID: ksuid.New(), Name: "forms"}, Key: "sp_new"}
+		mockAPIKeyService.EXPECT().CreateKey(models.DefaultWorkspace, "forms", []models.Scope{models.ScopeResponsesWrite}, nil).Return(created, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, adminRequest(http.MethodPost, "/key/", []byte(`{"name": "forms", "scopes": ["responses:write"]}`)))
+		assert.Equal(t, http.StatusCreated, resp.Code)
+		var body struct {
+			Data models.CreatedAPIKey `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.Equal(t, "sp_new", body.Data.Key)
+	})
+	t.Run("should return statusUnprocessableEntity(422) for malformed bodies and invalid keys", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockAPIKeyService, router := adminApp(ctrl)
+		mockAPIKeyService.EXPECT().CreateKey(models.DefaultWorkspace, "forms", []models.Scope{"responses:delete"}, nil).
+			Return(nil, fmt.Errorf("%w: unknown scope", services.ErrInvalidAPIKeySpec))
+		for _, body := range []string{`{"name": "forms", "scopes": ["responses:delete"]}`, `hello`} {
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, adminRequest(http.MethodPost, "/key/", []byte(body)))
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.Code, body)
+		}
+	})
+}
+
+func TestSurveyApp_GetAPIKeys(t *testing.T) {
+	t.Run("should return statusOK(200) with the keys", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockAPIKeyService, router := adminApp(ctrl)
+		mockAPIKeyService.EXPECT().GetKeys(models.DefaultWorkspace).Return([]models.APIKey{{ID: ksuid.New(), Name: "forms"}}, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, adminRequest(http.MethodGet, "/key/", nil))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"name":"forms"`)
+	})
+}
+
+func TestSurveyApp_RevokeAPIKey(t *testing.T) {
+	t.Run("should return statusOK(200) with the revoked key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockAPIKeyService, router := adminApp(ctrl)
+		id, now := ksuid.New(), time.Now()
+		mockAPIKeyService.EXPECT().RevokeKey(models.DefaultWorkspace, id).Return(&models.APIKey{ID: id, RevokedAt: &now}, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, adminRequest(http.MethodDelete, "/key/"+id.String(), nil))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"revoked_at"`)
+	})
+	t.Run("should return statusNotFound(404) for unknown keys", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockAPIKeyService, router := adminApp(ctrl)
+		id := ksuid.New()
+		mockAPIKeyService.EXPECT().RevokeKey(models.DefaultWorkspace, id).Return(nil, repositories.ErrNotFound)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, adminRequest(http.MethodDelete, "/key/"+id.String(), nil))
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+	t.Run("should return statusUnprocessableEntity(422) when the id is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		_, router := adminApp(ctrl)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, adminRequest(http.MethodDelete, "/key/xxx", nil))
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	})
+}
+
+func TestSurveyApp_Permissions(t *testing.T) {
+	surveyID, otherID := ksuid.New(), ksuid.New()
+	users := map[string]models.User{
+		"analyst":    {Name: "analyst", Roles: []models.RoleAssignment{{Role: models.RoleAnalyst}}},
+		"editor":     {Name: "editor", Roles: []models.RoleAssignment{{Role: models.RoleEditor, SurveyID: &surveyID}}},
+		"respondent": {Name: "respondent", Roles: []models.RoleAssignment{{Role: models.RoleRespondent, SurveyID: &surveyID}}},
+		"owner":      {Name: "owner", Roles: []models.RoleAssignment{{Role: models.RoleOwner}}},
+	}
+	stored := make(map[ksuid.KSUID]models.User, len(users))
+	keys := make(map[string]*models.APIKey, len(users))
+	for name, user := range users {
+		user.ID, user.WorkspaceID = ksuid.New(), models.DefaultWorkspace
+		stored[user.ID] = user
+		userID := user.ID
+		keys["sp_"+name] = &models.APIKey{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, UserID: &userID, Scopes: models.Scopes}
+	}
+	survey, other := "/survey/"+surveyID.String(), "/survey/"+otherID.String()
+	answer := func(id ksuid.KSUID) string { return `{"survey_id": "` + id.String() + `"}` }
+	for _, tc := range []struct {
+		user, method, path, body string
+		want                     int
+	}{
+		{"analyst", http.MethodGet, "/response/?survey_id=" + surveyID.String(), "", http.StatusOK},
+		{"analyst", http.MethodGet, survey + "/results", "", http.StatusOK},
+		{"analyst", http.MethodGet, "/survey/", "", http.StatusOK},
+		{"analyst", http.MethodPut, survey, "{}", http.StatusForbidden},
+		{"analyst", http.MethodDelete, survey, "", http.StatusForbidden},
+		{"analyst", http.MethodPost, "/response/", answer(surveyID), http.StatusForbidden},
+		{"analyst", http.MethodGet, "/key/", "", http.StatusForbidden},
+		{"editor", http.MethodPut, survey, "{}", http.StatusOK},
+		{"editor", http.MethodDelete, survey, "", http.StatusNoContent},
+		{"editor", http.MethodGet, "/response/?survey_id=" + surveyID.String(), "", http.StatusOK},
+		{"editor", http.MethodPut, other, "{}", http.StatusForbidden},
+		{"editor", http.MethodPost, "/survey/", "{}", http.StatusForbidden},
+		{"editor", http.MethodGet, "/survey/", "", http.StatusForbidden},
+		{"respondent", http.MethodGet, survey, "", http.StatusOK},
+		{"respondent", http.MethodPost, "/response/", answer(surveyID), http.StatusCreated},
+		{"respondent", http.MethodPost, "/response/", answer(otherID), http.StatusForbidden},
+		{"respondent", http.MethodGet, survey + "/results", "", http.StatusForbidden},
+		{"respondent", http.MethodGet, "/response/?survey_id=" + surveyID.String(), "", http.StatusForbidden},
+		{"owner", http.MethodDelete, other, "", http.StatusNoContent},
+		{"owner", http.MethodGet, "/user/", "", http.StatusOK},
+		{"owner", http.MethodGet, "/key/", "", http.StatusOK},
+	} {
+		t.Run(fmt.Sprintf("should answer %d to %s %s by the %s", tc.want, tc.method, tc.path, tc.user), func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockAPIKeyService := services_mock.NewMockAPIKeyServiceInterface(ctrl)
+			mockAPIKeyService.EXPECT().Authenticate("sp_"+tc.user).Return(keys["sp_"+tc.user], nil)
+			mockAPIKeyService.EXPECT().GetKeys(models.DefaultWorkspace).Return([]models.APIKey{}, nil).AnyTimes()
+			mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+			mockService.EXPECT().GetAllSurveys(models.DefaultWorkspace, gomock.Any()).Return(&models.SurveyPage{}, nil).AnyTimes()
+			mockService.EXPECT().GetSurveyDetails(models.DefaultWorkspace, gomock.Any()).Return(&models.SurveyDetails{}, nil).AnyTimes()
+			mockService.EXPECT().UpdateSurvey(models.DefaultWorkspace, gomock.Any(), gomock.Any()).Return(&models.Survey{}, nil).AnyTimes()
+			mockService.EXPECT().DeleteSurvey(models.DefaultWorkspace, gomock.Any(), false).Return(nil).AnyTimes()
+			mockService.EXPECT().GetResults(models.DefaultWorkspace, gomock.Any(), gomock.Any()).Return(&models.Results{}, nil).AnyTimes()
+			mockService.EXPECT().GetResponses(models.DefaultWorkspace, gomock.Any(), gomock.Any()).Return(&models.ResponsePage{}, nil).AnyTimes()
+			mockService.EXPECT().SaveResponse(models.DefaultWorkspace, gomock.Any()).Return(&models.Response{}, nil).AnyTimes()
+			userService := userservice.NewUserService(userrepo.NewUserRepo(stored, nil), nil, nil, nil)
+			router := NewSurveyApp(nil, mockService, mockAPIKeyService, userService, nil).SetupRoutes()
+			req, _ := http.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Authorization", "Bearer sp_"+tc.user)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.Equal(t, tc.want, resp.Code, resp.Body.String())
+			if tc.want == http.StatusForbidden {
+				assert.Contains(t, resp.Body.String(), "access denied: user "+tc.user)
+			}
+		})
+	}
+}
+
+func TestSurveyApp_RoutesAuthorize(t *testing.T) {
+	t.Run("should deny every route to a user without a role before reaching the services", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		user := models.User{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, Name: "nobody"}
+		key := &models.APIKey{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, UserID: &user.ID, Scopes: models.Scopes}
+		mockAPIKeyService := services_mock.NewMockAPIKeyServiceInterface(ctrl)
+		mockAPIKeyService.EXPECT().Authenticate("sp_nobody").Return(key, nil).AnyTimes()
+		userService := userservice.NewUserService(userrepo.NewUserRepo(map[ksuid.KSUID]models.User{user.ID: user}, nil), nil, nil, nil)
+		// the services have no expectations, any route reaching them fails the test
+		app := NewSurveyApp(nil, services_mock.NewMockSurveyServiceInterface(ctrl), mockAPIKeyService, userService,
+			services_mock.NewMockInvitationServiceInterface(ctrl))
+		app.SetMailService(services_mock.NewMockMailServiceInterface(ctrl))
+		router := app.SetupRoutes()
+		path := strings.NewReplacer(":id", ksuid.New().String(), ":token", ksuid.New().String()+".token")
+		for _, route := range router.Routes() {
+			if route.Path == "/" || strings.HasPrefix(route.Path, "/swagger/") {
+				continue
+			}
+			req, _ := http.NewRequest(route.Method, path.Replace(route.Path), strings.NewReader("{}"))
+			req.Header.Set("Authorization", "Bearer sp_nobody")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusForbidden, resp.Code, route.Method+" "+route.Path)
+			assert.Contains(t, resp.Body.String(), "access denied: user nobody has no role", route.Method+" "+route.Path)
+		}
+	})
+}
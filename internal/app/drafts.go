@@ -0,0 +1,153 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"io"
+	"log"
+	"net/http"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/services"
+)
+
+// draftRequest is the body starting a draft, both the token of the invitation it answers and the first answers are optional
+type draftRequest struct {
+	Token   string          `json:"token,omitempty"`
+	Answers []models.Answer `json:"answers,omitempty"`
+}
+
+// draftAnswers is the body updating a draft
+type draftAnswers struct {
+	Answers []models.Answer `json:"answers"`
+}
+
+// StartDraft starts a draft of a response to the survey and returns it along with the token it is resumed with
+// a draft started with the token of an invitation answers that invitation once submitted
+func (a *SurveyApp) StartDraft(c *gin.Context) {
+	id, ok := invitedSurveyID(c)
+	if !ok {
+		return
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Println("error while reading draft body", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "malformed body", ApiVersion: ApiVersion})
+		return
+	}
+	var request draftRequest
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err = binding.JSON.BindBody(body, &request); err != nil {
+			log.Println("error while reading draft body", err)
+			c.JSONP(http.StatusUnprocessableEntity, Response{Message: "malformed body", ApiVersion: ApiVersion})
+			return
+		}
+	}
+	draft := models.Draft{SurveyID: id, Answers: request.Answers}
+	if request.Token != "" {
+		invitation, err := a.verifyInvitation(workspace(c), id, request.Token)
+		if err != nil {
+			draftError(c, "starting", err)
+			return
+		}
+		draft.InvitationID, draft.RespondentID = &invitation.ID, &invitation.RespondentID
+	}
+	session, err := a.surveys(c).StartDraft(workspace(c), draft)
+	if err != nil {
+		draftError(c, "starting", err)
+		return
+	}
+	c.JSONP(http.StatusCreated, Response{Message: "draft started", Data: session, ApiVersion: ApiVersion})
+}
+
+// GetDraft resumes the draft of the token
+func (a *SurveyApp) GetDraft(c *gin.Context) {
+	draft, err := a.surveys(c).GetDraft(workspace(c), c.Param("token"))
+	if err != nil {
+		draftError(c, "resuming", err)
+		return
+	}
+	c.JSONP(http.StatusOK, Response{Message: "draft", Data: draft, ApiVersion: ApiVersion})
+}
+
+// UpdateDraft answers the questions in the body in the draft of the token, a null answer clears the answer to its question
+func (a *SurveyApp) UpdateDraft(c *gin.Context) {
+	var request draftAnswers
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Println("error while reading draft answers", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "malformed body", ApiVersion: ApiVersion})
+		return
+	}
+	draft, err := a.surveys(c).UpdateDraft(workspace(c), c.Param("token"), request.Answers)
+	if err != nil {
+		draftError(c, "updating", err)
+		return
+	}
+	c.JSONP(http.StatusOK, Response{Message: "draft updated", Data: draft, ApiVersion: ApiVersion})
+}
+
+// SubmitDraft saves the draft of the token as a response and returns the response, which keeps the id of the draft
+func (a *SurveyApp) SubmitDraft(c *gin.Context) {
+	response, err := a.surveys(c).SubmitDraft(workspace(c), c.Param("token"))
+	if err != nil {
+		draftError(c, "submitting", err)
+		return
+	}
+	c.JSONP(http.StatusCreated, Response{Message: "saved response", Data: response, ApiVersion: ApiVersion})
+}
+
+// GetFunnel counts the drafts of the survey which were started, are in progress, expired and were submitted
+func (a *SurveyApp) GetFunnel(c *gin.Context) {
+	id, ok := invitedSurveyID(c)
+	if !ok {
+		return
+	}
+	funnel, err := a.surveys(c).GetFunnel(workspace(c), id)
+	if forbidden(c, err) {
+		return
+	}
+	if err == repositories.ErrNotFound {
+		log.Println("survey not found while getting funnel", id.String())
+		c.JSONP(http.StatusNotFound, Response{Message: "error while reading funnel " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
+		log.Println("error while getting funnel", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while reading funnel " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	c.JSONP(http.StatusOK, Response{Message: "funnel", Data: funnel, ApiVersion: ApiVersion})
+}
+
+// draftError answers a request on a draft which failed with err, action tells what was done with the draft
+func draftError(c *gin.Context, action string, err error) {
+	var validationErr *services.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		log.Println("invalid answers while "+action+" draft", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid response", Data: validationErr.Violations, ApiVersion: ApiVersion})
+	case err == repositories.ErrNotFound:
+		log.Println("survey not found while "+action+" draft", err)
+		c.JSONP(http.StatusNotFound, Response{Message: "error while " + action + " draft " + err.Error(), ApiVersion: ApiVersion})
+	case errors.Is(err, services.ErrForbidden):
+		log.Println("access denied while "+action+" draft", err)
+		c.JSONP(http.StatusForbidden, Response{Message: err.Error(), ApiVersion: ApiVersion})
+	case err == services.ErrInvalidResumeToken || errors.Is(err, services.ErrInvalidInvitation):
+		log.Println("invalid token while "+action+" draft", err)
+		c.JSONP(http.StatusForbidden, Response{Message: err.Error(), ApiVersion: ApiVersion})
+	case err == services.ErrDraftExpired:
+		log.Println("draft expired while " + action + " it")
+		c.JSONP(http.StatusGone, Response{Message: err.Error(), ApiVersion: ApiVersion})
+	case err == repositories.ErrDraftSubmitted || err == repositories.ErrInvitationCompleted || err == services.ErrSurveyNotPublished ||
+		err == services.ErrSurveyNotOpen || err == services.ErrSurveyClosed || err == services.ErrQuotaReached:
+		log.Println("draft cannot be changed while "+action+" it", err)
+		c.JSONP(http.StatusConflict, Response{Message: "error while " + action + " draft " + err.Error(), ApiVersion: ApiVersion})
+	case err == services.ErrDraftsDisabled:
+		log.Println("drafts are disabled")
+		c.JSONP(http.StatusNotImplemented, Response{Message: err.Error(), ApiVersion: ApiVersion})
+	default:
+		log.Println("error while "+action+" draft", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while " + action + " draft " + err.Error(), ApiVersion: ApiVersion})
+	}
+}
@@ -0,0 +1,171 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/services"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSurveyApp_StartDraft(t *testing.T) {
+	t.Run("should return statusCreated(201) with the draft and its resume token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService, _, router := invitationsApp(ctrl)
+		surveyID, questionID := ksuid.New(), ksuid.New()
+		session := &models.DraftSession{Draft: models.Draft{ID: ksuid.New(), SurveyID: surveyID}, ResumeToken: "resume"}
+		mockService.EXPECT().StartDraft(models.DefaultWorkspace, models.Draft{SurveyID: surveyID}).Return(session, nil)
+		mockService.EXPECT().StartDraft(models.DefaultWorkspace, models.Draft{SurveyID: surveyID,
+			Answers: []models.Answer{{QuestionID: questionID, Answer: models.BoolValue(true)}}}).Return(session, nil)
+		for _, body := range []string{"", `{"answers": [{"question_id": "` + questionID.String() + `", "answer": true}]}`} {
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/survey/"+surveyID.String()+"/drafts", bytes.NewReader([]byte(body))))
+			assert.Equal(t, http.StatusCreated, resp.Code)
+			assert.Contains(t, resp.Body.String(), `"resume_token":"resume"`)
+			assert.Contains(t, resp.Body.String(), session.ID.String())
+		}
+	})
+	t.Run("should start a draft answering the invitation of the token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService, mockInvitationService, router := invitationsApp(ctrl)
+		surveyID := ksuid.New()
+		invitation := &models.Invitation{ID: ksuid.New(), SurveyID: surveyID, RespondentID: ksuid.New()}
+		mockInvitationService.EXPECT().Verify(models.DefaultWorkspace, surveyID, "signed").Return(invitation, nil)
+		mockInvitationService.EXPECT().Verify(models.DefaultWorkspace, surveyID, "forged").Return(nil, services.ErrInvalidInvitation)
+		mockInvitationService.EXPECT().Verify(models.DefaultWorkspace, surveyID, "answered").Return(nil, repositories.ErrInvitationCompleted)
+		mockService.EXPECT().StartDraft(models.DefaultWorkspace, models.Draft{SurveyID: surveyID,
+			InvitationID: &invitation.ID, RespondentID: &invitation.RespondentID}).Return(&models.DraftSession{}, nil)
+		for _, tc := range []struct {
+			token  string
+			status int
+		}{
+			{"signed", http.StatusCreated},
+			{"forged", http.StatusForbidden},
+			{"answered", http.StatusConflict},
+		} {
+			resp := httptest.NewRecorder()
+			body := []byte(`{"token": "` + tc.token + `"}`)
+			router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/survey/"+surveyID.String()+"/drafts", bytes.NewReader(body)))
+			assert.Equal(t, tc.status, resp.Code, tc.token)
+		}
+	})
+	t.Run("should answer invalid requests with 422, missing surveys with 404 and closed surveys with 409", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService, _, router := invitationsApp(ctrl)
+		invalid, missing, closed, disabled := ksuid.New(), ksuid.New(), ksuid.New(), ksuid.New()
+		mockService.EXPECT().StartDraft(models.DefaultWorkspace, models.Draft{SurveyID: invalid}).
+			Return(nil, &services.ValidationError{Violations: []services.Violation{{Message: "answer must be true or false"}}})
+		mockService.EXPECT().StartDraft(models.DefaultWorkspace, models.Draft{SurveyID: missing}).Return(nil, repositories.ErrNotFound)
+		mockService.EXPECT().StartDraft(models.DefaultWorkspace, models.Draft{SurveyID: closed}).Return(nil, services.ErrSurveyClosed)
+		mockService.EXPECT().StartDraft(models.DefaultWorkspace, models.Draft{SurveyID: disabled}).Return(nil, services.ErrDraftsDisabled)
+		for _, tc := range []struct {
+			path   string
+			body   string
+			status int
+		}{
+			{"/survey/" + invalid.String() + "/drafts", "{}", http.StatusUnprocessableEntity},
+			{"/survey/" + invalid.String() + "/drafts", "hello", http.StatusUnprocessableEntity},
+			{"/survey/invalid/drafts", "{}", http.StatusUnprocessableEntity},
+			{"/survey/" + missing.String() + "/drafts", "{}", http.StatusNotFound},
+			{"/survey/" + closed.String() + "/drafts", "{}", http.StatusConflict},
+			{"/survey/" + disabled.String() + "/drafts", "{}", http.StatusNotImplemented},
+		} {
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, tc.path, bytes.NewReader([]byte(tc.body))))
+			assert.Equal(t, tc.status, resp.Code, tc.path)
+		}
+	})
+}
+
+func TestSurveyApp_Drafts(t *testing.T) {
+	surveyID, questionID := ksuid.New(), ksuid.New()
+	token := surveyID.String() + "." + ksuid.New().String() + ".secret"
+	draft := &models.Draft{ID: ksuid.New(), SurveyID: surveyID}
+	t.Run("should resume, update and submit the draft of the token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService, _, router := invitationsApp(ctrl)
+		response := &models.Response{ID: draft.ID, SurveyID: surveyID}
+		mockService.EXPECT().GetDraft(models.DefaultWorkspace, token).Return(draft, nil)
+		mockService.EXPECT().UpdateDraft(models.DefaultWorkspace, token, []models.Answer{
+			{QuestionID: questionID, Answer: models.BoolValue(true)},
+			{QuestionID: surveyID, Answer: models.Value{}},
+		}).Return(draft, nil)
+		mockService.EXPECT().SubmitDraft(models.DefaultWorkspace, token).Return(response, nil)
+		body := `{"answers": [{"question_id": "` + questionID.String() + `", "answer": true}, {"question_id": "` + surveyID.String() + `", "answer": null}]}`
+		for _, tc := range []struct {
+			method string
+			path   string
+			body   string
+			status int
+		}{
+			{http.MethodGet, "/draft/" + token, "", http.StatusOK},
+			{http.MethodPatch, "/draft/" + token, body, http.StatusOK},
+			{http.MethodPost, "/draft/" + token + "/submit", "", http.StatusCreated},
+		} {
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, httptest.NewRequest(tc.method, tc.path, bytes.NewReader([]byte(tc.body))))
+			assert.Equal(t, tc.status, resp.Code, tc.method)
+			assert.Contains(t, resp.Body.String(), draft.ID.String())
+		}
+	})
+	t.Run("should answer invalid tokens with 403, expired drafts with 410 and submitted drafts with 409", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService, _, router := invitationsApp(ctrl)
+		mockService.EXPECT().GetDraft(models.DefaultWorkspace, "forged").Return(nil, services.ErrInvalidResumeToken)
+		mockService.EXPECT().GetDraft(models.DefaultWorkspace, token).Return(nil, services.ErrDraftExpired)
+		mockService.EXPECT().UpdateDraft(models.DefaultWorkspace, token, gomock.Any()).Return(nil, repositories.ErrDraftSubmitted)
+		mockService.EXPECT().UpdateDraft(models.DefaultWorkspace, "invalid", gomock.Any()).
+			Return(nil, &services.ValidationError{Violations: []services.Violation{{QuestionID: questionID, Message: "answer must be true or false"}}})
+		mockService.EXPECT().SubmitDraft(models.DefaultWorkspace, token).Return(nil, services.ErrQuotaReached)
+		mockService.EXPECT().SubmitDraft(models.DefaultWorkspace, "incomplete").
+			Return(nil, &services.ValidationError{Violations: []services.Violation{{QuestionID: questionID, Message: "question is required"}}})
+		for _, tc := range []struct {
+			method string
+			path   string
+			body   string
+			status int
+		}{
+			{http.MethodGet, "/draft/forged", "", http.StatusForbidden},
+			{http.MethodGet, "/draft/" + token, "", http.StatusGone},
+			{http.MethodPatch, "/draft/" + token, `{"answers": []}`, http.StatusConflict},
+			{http.MethodPatch, "/draft/" + token, "hello", http.StatusUnprocessableEntity},
+			{http.MethodPatch, "/draft/invalid", `{"answers": []}`, http.StatusUnprocessableEntity},
+			{http.MethodPost, "/draft/" + token + "/submit", "", http.StatusConflict},
+			{http.MethodPost, "/draft/incomplete/submit", "", http.StatusUnprocessableEntity},
+		} {
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, httptest.NewRequest(tc.method, tc.path, bytes.NewReader([]byte(tc.body))))
+			assert.Equal(t, tc.status, resp.Code, tc.method+" "+tc.path)
+		}
+	})
+}
+
+func TestSurveyApp_GetFunnel(t *testing.T) {
+	t.Run("should return the funnel of the survey and 404 for missing surveys", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService, _, router := invitationsApp(ctrl)
+		surveyID, missing := ksuid.New(), ksuid.New()
+		mockService.EXPECT().GetFunnel(models.DefaultWorkspace, surveyID).Return(&models.Funnel{SurveyID: surveyID, Started: 4, Submitted: 1, CompletionRate: 25}, nil)
+		mockService.EXPECT().GetFunnel(models.DefaultWorkspace, missing).Return(nil, repositories.ErrNotFound)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/survey/"+surveyID.String()+"/funnel", nil))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"started":4`)
+		assert.Contains(t, resp.Body.String(), `"completion_rate":25`)
+		resp = httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/survey/"+missing.String()+"/funnel", nil))
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+}
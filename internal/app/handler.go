@@ -1,41 +1,96 @@
 package app
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/segmentio/ksuid"
 	swaggerFiles "github.com/swaggo/files"     // swagger embed files
 	ginSwagger "github.com/swaggo/gin-swagger" // gin-swagger middleware
+	"io"
 	"log"
 	"net/http"
+	"strconv"
 	_ "survey-platform/docs"
 	"survey-platform/internal/db"
 	"survey-platform/internal/models"
 	"survey-platform/internal/repositories"
 	"survey-platform/internal/services"
+	"survey-platform/pkg/export"
+	"survey-platform/pkg/idempotency"
+	"survey-platform/pkg/search"
+	"survey-platform/pkg/timegenerator/actualtimegenerator"
+	"sync"
+	"time"
 )
 
 var ApiVersion = "1.0.0"
 
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+	// IdempotencyKeyHeader makes retries of POST /response/ safe, IdempotentReplayedHeader marks replayed results
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	MaxIdempotencyKeyLength  = 255
+	// InvitationTokenHeader carries the invitation token respondents read, edit and withdraw their response with
+	InvitationTokenHeader = "Invitation-Token"
+	// DefaultIdempotencyTTL is how long the result of a request with an idempotency key is replayed
+	DefaultIdempotencyTTL = 24 * time.Hour
+)
+
 type Response struct {
 	Message    string      `json:"success,omitempty"`
 	Data       interface{} `json:"data,omitempty"`
 	ApiVersion string      `json:"api_version,omitempty"`
+	// NextCursor continues a paged listing, it is left out on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // SurveyApp handles the hit and dump from high level
 type SurveyApp struct {
 	db            db.DB
 	surveyService services.SurveyServiceInterface
+	apiKeyService services.APIKeyServiceInterface
+	userService   services.UserServiceInterface
+	// invitationService checks the invitation tokens responses are saved with, nil rejects every token
+	invitationService services.InvitationServiceInterface
+	// mailService emails the invitations, nil leaves sending them to the clients
+	mailService services.MailServiceInterface
+	idempotency *idempotency.Store
+	// dumpMu serialises dumps, so that an older dump cannot replace a newer one after the newer one truncated the journal
+	dumpMu sync.Mutex
 }
 
 // NewSurveyApp returns app configured with passed surveyService
-func NewSurveyApp(persistence db.DB, surveyService services.SurveyServiceInterface) *SurveyApp {
+// requests are authenticated with the api keys of apiKeyService, a nil apiKeyService leaves every route open
+// keys bound to users are limited to the roles of the users in userService, a nil userService only checks the scopes of keys
+// respondents are invited to surveys with invitationService
+func NewSurveyApp(persistence db.DB, surveyService services.SurveyServiceInterface, apiKeyService services.APIKeyServiceInterface,
+	userService services.UserServiceInterface, invitationService services.InvitationServiceInterface) *SurveyApp {
 	return &SurveyApp{
-		db:            persistence,
-		surveyService: surveyService,
+		db:                persistence,
+		surveyService:     surveyService,
+		apiKeyService:     apiKeyService,
+		userService:       userService,
+		invitationService: invitationService,
+		idempotency:       idempotency.NewStore(DefaultIdempotencyTTL, actualtimegenerator.NewActualTimeGenerator()),
 	}
 }
 
+// SetIdempotencyTTL sets how long the results of requests with an idempotency key are replayed, results kept so far are dropped
+func (a *SurveyApp) SetIdempotencyTTL(ttl time.Duration) {
+	a.idempotency = idempotency.NewStore(ttl, actualtimegenerator.NewActualTimeGenerator())
+}
+
+// SetMailService makes the app email the respondents it invites with mailService
+func (a *SurveyApp) SetMailService(mailService services.MailServiceInterface) {
+	a.mailService = mailService
+}
+
 // @title Survey app API
 // @version 1.0
 // @description maintains survey CRUD
@@ -55,18 +110,60 @@ func NewSurveyApp(persistence db.DB, surveyService services.SurveyServiceInterfa
 func (a *SurveyApp) SetupRoutes() *gin.Engine {
 	router := gin.Default()
 	router.GET("/", a.HealthCheck)
+	surveysRead, surveysWrite := a.authorize(models.ScopeSurveysRead), a.authorize(models.ScopeSurveysWrite)
+	surveyRead, surveyWrite := a.authorizeOn(surveyParam, models.ScopeSurveysRead), a.authorizeOn(surveyParam, models.ScopeSurveysWrite)
+	surveyResponsesRead := a.authorizeOn(surveyParam, models.ScopeResponsesRead)
 	surveyRouter := router.Group("/survey")
 	{
-		surveyRouter.GET("/", a.GetAllSurveys)
-		surveyRouter.POST("/", a.CreateSurvey)
-		surveyRouter.GET("/:id", a.GetSurvey)
-		surveyRouter.PUT("/:id", a.UpdateSurvey)
-		surveyRouter.DELETE("/:id", a.DeleteSurvey)
+		surveyRouter.GET("/", surveysRead, a.GetAllSurveys)
+		surveyRouter.POST("/", surveysWrite, a.CreateSurvey)
+		surveyRouter.GET("/search", surveysRead, a.SearchSurveys)
+		surveyRouter.GET("/:id", surveyRead, a.GetSurvey)
+		surveyRouter.GET("/:id/versions", surveyRead, a.GetSurveyVersions)
+		surveyRouter.GET("/:id/results", surveyResponsesRead, a.GetResults)
+		surveyRouter.GET("/:id/crosstab", surveyResponsesRead, a.GetCrossTab)
+		surveyRouter.GET("/:id/responses/export", surveyResponsesRead, a.ExportResponses)
+		surveyRouter.PUT("/:id", surveyWrite, a.UpdateSurvey)
+		surveyRouter.DELETE("/:id", surveyWrite, a.DeleteSurvey)
+		surveyRouter.POST("/:id/publish", surveyWrite, a.PublishSurvey)
+		surveyRouter.POST("/:id/close", surveyWrite, a.CloseSurvey)
+		surveyRouter.POST("/:id/archive", surveyWrite, a.ArchiveSurvey)
+		surveyRouter.POST("/:id/invitations", surveyWrite, a.Invite)
+		surveyRouter.GET("/:id/invitations", surveyWrite, a.GetInvitations)
+		surveyRouter.GET("/:id/invitations/summary", surveyResponsesRead, a.GetInvitationSummary)
+		surveyRouter.POST("/:id/drafts", a.authorizeOn(surveyParam, models.ScopeResponsesWrite), a.StartDraft)
+		surveyRouter.GET("/:id/funnel", surveyResponsesRead, a.GetFunnel)
+	}
+	router.GET("/invitation/:token", a.authorizeOn(surveyInToken, models.ScopeSurveysRead), a.OpenInvitation)
+	draftRouter := router.Group("/draft", a.authorizeOn(surveyInToken, models.ScopeResponsesWrite))
+	{
+		draftRouter.GET("/:token", a.GetDraft)
+		draftRouter.PATCH("/:token", a.UpdateDraft)
+		draftRouter.POST("/:token/submit", a.SubmitDraft)
 	}
 	responseRouter := router.Group("/response")
 	{
-		responseRouter.POST("/", a.SaveResponse)
-		responseRouter.GET("/", a.GetResponses)
+		responseRouter.POST("/", a.authorizeOn(surveyInBody, models.ScopeResponsesWrite), a.SaveResponse)
+		responseRouter.GET("/", a.authorizeOn(surveyInQuery, models.ScopeResponsesRead), a.GetResponses)
+		respondentResponse := a.authorizeOn(surveyInTokenHeader, models.ScopeResponsesWrite)
+		responseRouter.GET("/:id", respondentResponse, a.GetResponse)
+		responseRouter.PUT("/:id", respondentResponse, a.UpdateResponse)
+		responseRouter.DELETE("/:id", respondentResponse, a.DeleteResponse)
+	}
+	router.POST("/import", a.authorize(models.ScopeSurveysWrite, models.ScopeResponsesWrite), a.Import)
+	keyRouter := router.Group("/key", a.authorize(models.ScopeKeysManage))
+	{
+		keyRouter.POST("/", a.CreateAPIKey)
+		keyRouter.GET("/", a.GetAPIKeys)
+		keyRouter.DELETE("/:id", a.RevokeAPIKey)
+	}
+	userRouter := router.Group("/user", a.authorize(models.ScopeUsersManage))
+	{
+		userRouter.POST("/", a.CreateUser)
+		userRouter.GET("/", a.GetUsers)
+		userRouter.GET("/:id", a.GetUser)
+		userRouter.PUT("/:id/roles", a.SetUserRoles)
+		userRouter.DELETE("/:id", a.DeleteUser)
 	}
 	router.GET("/swagger/*any", ginSwagger.DisablingWrapHandler(swaggerFiles.Handler, "NAME_OF_ENV_VARIABLE"))
 	return router
@@ -102,8 +199,15 @@ func (a *SurveyApp) CreateSurvey(c *gin.Context) {
 		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "malformed body", ApiVersion: ApiVersion})
 		return
 	}
-	newSurvey, err := a.surveyService.CreateSurvey(&survey)
-	if err != nil {
+	newSurvey, err := a.surveys(c).CreateSurvey(workspace(c), &survey)
+	if forbidden(c, err) {
+		return
+	}
+	if errors.Is(err, services.ErrInvalidSurvey) {
+		log.Println("invalid survey while creating survey", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
 		log.Println("error while reading survey body", err)
 		c.JSONP(http.StatusInternalServerError, Response{Message: "error while creating survey " + err.Error(), ApiVersion: ApiVersion})
 		return
@@ -111,6 +215,7 @@ func (a *SurveyApp) CreateSurvey(c *gin.Context) {
 	c.JSONP(http.StatusCreated, Response{Message: "survey created", Data: newSurvey, ApiVersion: ApiVersion})
 }
 
+// GetSurvey returns the survey along with its availability, the remaining quota and the time left until it closes
 func (a *SurveyApp) GetSurvey(c *gin.Context) {
 	id, err := ksuid.Parse(c.Param("id"))
 	if err != nil {
@@ -118,7 +223,10 @@ func (a *SurveyApp) GetSurvey(c *gin.Context) {
 		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid survey id", ApiVersion: ApiVersion})
 		return
 	}
-	survey, err := a.surveyService.GetSurvey(id)
+	survey, err := a.surveys(c).GetSurveyDetails(workspace(c), id)
+	if forbidden(c, err) {
+		return
+	}
 	if err != nil && err == repositories.ErrNotFound {
 		log.Println("survey not found while getting survey", id.String())
 		c.JSONP(http.StatusNotFound, Response{Message: "error while reading survey " + err.Error(), ApiVersion: ApiVersion})
@@ -131,6 +239,202 @@ func (a *SurveyApp) GetSurvey(c *gin.Context) {
 	c.JSONP(http.StatusOK, Response{Message: "success", Data: survey})
 }
 
+// GetSurveyVersions returns every version of the survey oldest first
+func (a *SurveyApp) GetSurveyVersions(c *gin.Context) {
+	id, err := ksuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Println("error while parsing surveyID", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid survey id", ApiVersion: ApiVersion})
+		return
+	}
+	versions, err := a.surveys(c).GetSurveyVersions(workspace(c), id)
+	if forbidden(c, err) {
+		return
+	}
+	if err != nil && err == repositories.ErrNotFound {
+		log.Println("survey not found while getting survey versions", id.String())
+		c.JSONP(http.StatusNotFound, Response{Message: "error while reading survey versions " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
+		log.Println("error while getting survey versions", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while reading survey versions " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	c.JSONP(http.StatusOK, Response{Message: "success", Data: versions})
+}
+
+// GetResults returns the aggregated answers to the survey
+// the responses can be narrowed with the version, compatible, from and to query parameters
+// and the drafts in progress are added with include_drafts
+func (a *SurveyApp) GetResults(c *gin.Context) {
+	id, err := ksuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Println("error while parsing surveyID", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid survey id", ApiVersion: ApiVersion})
+		return
+	}
+	filter, err := resultsFilter(c)
+	if err != nil {
+		log.Println("error while parsing results filter", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid filter " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	results, err := a.surveys(c).GetResults(workspace(c), id, filter)
+	if forbidden(c, err) {
+		return
+	}
+	if err != nil && err == repositories.ErrNotFound {
+		log.Println("survey not found while getting results", id.String())
+		c.JSONP(http.StatusNotFound, Response{Message: "error while reading results " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
+		log.Println("error while getting results", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while reading results " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	c.JSONP(http.StatusOK, Response{Message: "success", Data: results})
+}
+
+// GetCrossTab returns the contingency table between the row and col questions of the survey
+// the responses can be narrowed with the version and compatible query parameters
+func (a *SurveyApp) GetCrossTab(c *gin.Context) {
+	id, err := ksuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Println("error while parsing surveyID", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid survey id", ApiVersion: ApiVersion})
+		return
+	}
+	rowID, err := ksuid.Parse(c.Query("row"))
+	if err != nil {
+		log.Println("error while parsing row questionID", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid row question id", ApiVersion: ApiVersion})
+		return
+	}
+	columnID, err := ksuid.Parse(c.Query("col"))
+	if err != nil {
+		log.Println("error while parsing col questionID", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid col question id", ApiVersion: ApiVersion})
+		return
+	}
+	scope, err := versionScope(c)
+	if err != nil {
+		log.Println("error while parsing version", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid version", ApiVersion: ApiVersion})
+		return
+	}
+	crossTab, err := a.surveys(c).GetCrossTab(workspace(c), id, rowID, columnID, scope)
+	if forbidden(c, err) {
+		return
+	}
+	if err != nil && err == repositories.ErrNotFound {
+		log.Println("survey not found while getting cross tab", id.String())
+		c.JSONP(http.StatusNotFound, Response{Message: "error while reading cross tab " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if errors.Is(err, services.ErrQuestionNotFound) || errors.Is(err, services.ErrQuestionNotTabulated) {
+		log.Println("question cannot be cross tabulated", id.String(), err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "error while reading cross tab " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
+		log.Println("error while getting cross tab", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while reading cross tab " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	c.JSONP(http.StatusOK, Response{Message: "success", Data: crossTab})
+}
+
+// ExportResponses streams the responses to the survey as a csv, ndjson or xlsx file, csv by default
+// errors after the first byte is written can only be logged, the client sees a truncated file
+func (a *SurveyApp) ExportResponses(c *gin.Context) {
+	id, err := ksuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Println("error while parsing surveyID", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid survey id", ApiVersion: ApiVersion})
+		return
+	}
+	format := export.Format(c.DefaultQuery("format", string(export.FormatCSV)))
+	if _, err = export.NewWriter(format, io.Discard); err != nil {
+		log.Println("error while parsing export format", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	err = a.surveys(c).ExportResponses(workspace(c), id, func() (export.Writer, error) {
+		c.Header("Content-Type", format.ContentType())
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, id.String(), format))
+		c.Status(http.StatusOK)
+		return export.NewWriter(format, c.Writer)
+	})
+	if forbidden(c, err) {
+		return
+	}
+	if err != nil && err == repositories.ErrNotFound {
+		log.Println("survey not found while exporting responses", id.String())
+		c.JSONP(http.StatusNotFound, Response{Message: "error while exporting responses " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil && !c.Writer.Written() {
+		log.Println("error while exporting responses", err)
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while exporting responses " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
+		log.Println("error while streaming responses export", id.String(), err)
+	}
+}
+
+// Import reads surveys and responses from the request body, the format, survey_id, dry_run and preserve_ids
+// query params configure the import, rejected rows are listed in the report while the valid ones are stored
+func (a *SurveyApp) Import(c *gin.Context) {
+	options := models.ImportOptions{Format: models.ImportFormat(c.Query("format"))}
+	if !options.Format.Valid() {
+		log.Println("invalid import format", options.Format)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid format", ApiVersion: ApiVersion})
+		return
+	}
+	if surveyID := c.Query("survey_id"); surveyID != "" {
+		id, err := ksuid.Parse(surveyID)
+		if err != nil {
+			log.Println("error while parsing surveyID", err)
+			c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid survey id", ApiVersion: ApiVersion})
+			return
+		}
+		options.SurveyID = id
+	}
+	var err error
+	if options.DryRun, err = queryBool(c, "dry_run"); err != nil {
+		log.Println("error while parsing dry_run", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	if options.PreserveIDs, err = queryBool(c, "preserve_ids"); err != nil {
+		log.Println("error while parsing preserve_ids", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	report, err := a.surveys(c).Import(workspace(c), c.Request.Body, options)
+	if forbidden(c, err) {
+		return
+	}
+	if err != nil && err == repositories.ErrNotFound {
+		log.Println("survey not found while importing", options.SurveyID.String())
+		c.JSONP(http.StatusNotFound, Response{Message: "error while importing " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if errors.Is(err, services.ErrMalformedImport) || errors.Is(err, services.ErrImportNeedsSurvey) ||
+		errors.Is(err, services.ErrUnknownImportFormat) {
+		log.Println("invalid import", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "error while importing " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
+		log.Println("error while importing", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while importing " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	message := "import complete"
+	if report.DryRun {
+		message = "dry run complete"
+	}
+	c.JSONP(http.StatusOK, Response{Message: message, Data: report, ApiVersion: ApiVersion})
+}
+
 func (a *SurveyApp) UpdateSurvey(c *gin.Context) {
 	id, err := ksuid.Parse(c.Param("id"))
 	if err != nil {
@@ -145,11 +449,22 @@ func (a *SurveyApp) UpdateSurvey(c *gin.Context) {
 		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "malformed body", ApiVersion: ApiVersion})
 		return
 	}
-	updatedSurvey, err := a.surveyService.UpdateSurvey(id, survey)
+	updatedSurvey, err := a.surveys(c).UpdateSurvey(workspace(c), id, survey)
+	if forbidden(c, err) {
+		return
+	}
 	if err != nil && err == repositories.ErrNotFound {
 		log.Println("survey not found while updating survey", id.String())
 		c.JSONP(http.StatusNotFound, Response{Message: "error while updating survey " + err.Error(), ApiVersion: ApiVersion})
 		return
+	} else if errors.Is(err, services.ErrInvalidSurvey) {
+		log.Println("invalid survey while updating survey", id.String(), err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err == services.ErrSurveyArchived {
+		log.Println("survey cannot be updated", id.String(), err)
+		c.JSONP(http.StatusConflict, Response{Message: "error while updating survey " + err.Error(), ApiVersion: ApiVersion})
+		return
 	} else if err != nil {
 		log.Println("error while updating survey", err)
 		c.JSONP(http.StatusInternalServerError, Response{Message: "error while updating survey " + err.Error(), ApiVersion: ApiVersion})
@@ -158,6 +473,8 @@ func (a *SurveyApp) UpdateSurvey(c *gin.Context) {
 	c.JSONP(http.StatusOK, Response{Message: "survey updated", Data: updatedSurvey, ApiVersion: ApiVersion})
 }
 
+// DeleteSurvey deletes a survey without responses, the cascade query param deletes a survey with responses along with them
+// without it deleting a survey with responses is refused with a conflict
 func (a *SurveyApp) DeleteSurvey(c *gin.Context) {
 	id, err := ksuid.Parse(c.Param("id"))
 	if err != nil {
@@ -165,11 +482,25 @@ func (a *SurveyApp) DeleteSurvey(c *gin.Context) {
 		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid survey id", ApiVersion: ApiVersion})
 		return
 	}
-	err = a.surveyService.DeleteSurvey(id)
+	cascade, err := queryBool(c, "cascade")
+	if err != nil {
+		log.Println("error while parsing cascade", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	err = a.surveys(c).DeleteSurvey(workspace(c), id, cascade)
+	if forbidden(c, err) {
+		return
+	}
 	if err != nil && err == repositories.ErrNotFound {
 		log.Println("survey not found while deleting survey", id.String())
 		c.JSONP(http.StatusNotFound, Response{Message: "error while deleting survey " + err.Error(), ApiVersion: ApiVersion})
 		return
+	} else if err == repositories.ErrSurveyHasResponses {
+		log.Println("survey with responses not deleted without cascade", id.String())
+		c.JSONP(http.StatusConflict, Response{Message: "error while deleting survey " + err.Error() + ", delete them along with it with cascade=true",
+			ApiVersion: ApiVersion})
+		return
 	} else if err != nil {
 		log.Println("error while getting survey", err)
 		c.JSONP(http.StatusInternalServerError, Response{Message: "error while deleting survey " + err.Error(), ApiVersion: ApiVersion})
@@ -178,35 +509,211 @@ func (a *SurveyApp) DeleteSurvey(c *gin.Context) {
 	c.JSONP(http.StatusNoContent, Response{Message: "survey deleted", ApiVersion: ApiVersion})
 }
 
+// GetAllSurveys returns a page of surveys, the status and name query params filter the surveys
+// see pageQuery for the params selecting the page
 func (a *SurveyApp) GetAllSurveys(c *gin.Context) {
-	surveys, err := a.surveyService.GetAllSurveys()
+	var query models.SurveyQuery
+	query.Status = models.SurveyStatus(c.Query("status"))
+	if query.Status != "" && !query.Status.Valid() {
+		log.Println("invalid status while getting surveys", query.Status)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid status", ApiVersion: ApiVersion})
+		return
+	}
+	query.Name = c.Query("name")
+	var err error
+	if query.PageQuery, err = pageQuery(c, models.SortCreatedAt, models.SortUpdatedAt, models.SortName); err != nil {
+		log.Println("invalid page while getting surveys", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	page, err := a.surveys(c).GetAllSurveys(workspace(c), query)
+	if forbidden(c, err) {
+		return
+	}
 	if err != nil {
 		log.Println("error while getting surveys", err)
 		c.JSONP(http.StatusInternalServerError, Response{Message: "error while reading surveys " + err.Error()})
 		return
 	}
-	c.JSONP(http.StatusOK, Response{Message: "survey created", Data: surveys})
+	c.JSONP(http.StatusOK, Response{Message: "survey created", Data: page.Surveys, NextCursor: page.Next})
 }
 
+// SearchSurveys returns the surveys whose name or questions match the q query param, best match first
+// the limit query param caps the number of surveys as for pages
+func (a *SurveyApp) SearchSurveys(c *gin.Context) {
+	q := c.Query("q")
+	if len(search.Tokenize(q)) == 0 {
+		log.Println("empty search query", q)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "q needs at least one word to search for", ApiVersion: ApiVersion})
+		return
+	}
+	limit, err := queryLimit(c)
+	if err != nil {
+		log.Println("invalid limit while searching surveys", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	results, err := a.surveys(c).SearchSurveys(workspace(c), q, limit)
+	if forbidden(c, err) {
+		return
+	}
+	if err != nil {
+		log.Println("error while searching surveys", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while searching surveys " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	c.JSONP(http.StatusOK, Response{Message: "surveys found", Data: results, ApiVersion: ApiVersion})
+}
+
+func (a *SurveyApp) PublishSurvey(c *gin.Context) {
+	a.changeStatus(c, "publishing", a.surveys(c).PublishSurvey)
+}
+
+func (a *SurveyApp) CloseSurvey(c *gin.Context) {
+	a.changeStatus(c, "closing", a.surveys(c).CloseSurvey)
+}
+
+func (a *SurveyApp) ArchiveSurvey(c *gin.Context) {
+	a.changeStatus(c, "archiving", a.surveys(c).ArchiveSurvey)
+}
+
+// changeStatus moves the survey in the id param to another status using move
+// transitions not allowed from the current status of the survey are a conflict
+func (a *SurveyApp) changeStatus(c *gin.Context, action string, move func(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error)) {
+	id, err := ksuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Println("error while parsing surveyID", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid survey id", ApiVersion: ApiVersion})
+		return
+	}
+	survey, err := move(workspace(c), id)
+	if forbidden(c, err) {
+		return
+	}
+	if err != nil && err == repositories.ErrNotFound {
+		log.Println("survey not found while "+action+" survey", id.String())
+		c.JSONP(http.StatusNotFound, Response{Message: "error while " + action + " survey " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if errors.Is(err, services.ErrInvalidTransition) {
+		log.Println("invalid transition while "+action+" survey", err)
+		c.JSONP(http.StatusConflict, Response{Message: "error while " + action + " survey " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
+		log.Println("error while "+action+" survey", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while " + action + " survey " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	c.JSONP(http.StatusOK, Response{Message: "survey " + string(survey.Status), Data: survey, ApiVersion: ApiVersion})
+}
+
+// SaveResponse stores the response in the body and returns it
+// a request with an Idempotency-Key header is performed once, its retries with the same body get the result
+// of the first request back with the Idempotent-Replayed header, while retries with another body are a conflict
 func (a *SurveyApp) SaveResponse(c *gin.Context) {
-	var response models.Response
-	err := c.ShouldBindJSON(&response)
+	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
 		log.Println("error while reading response body", err)
 		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "malformed body"})
 		return
 	}
-	_, err = a.surveyService.SaveResponse(response)
-	if err != nil && err == repositories.ErrNotFound {
-		log.Println("survey not found while saving response", response.SurveyID.String())
-		c.JSONP(http.StatusNotFound, Response{Message: "error while saving response " + err.Error(), ApiVersion: ApiVersion})
+	key := c.GetHeader(IdempotencyKeyHeader)
+	if key == "" {
+		result := a.saveResponse(a.surveys(c), workspace(c), body)
+		c.JSONP(result.Status, result.Body)
+		return
+	}
+	if len(key) > MaxIdempotencyKeyLength {
+		log.Println("idempotency key too long while saving response", len(key))
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: fmt.Sprintf("idempotency key is longer than %d characters", MaxIdempotencyKeyLength), ApiVersion: ApiVersion})
+		return
+	}
+	fingerprint := sha256.Sum256(body)
+	result, replayed, err := a.idempotency.Do(c.Request.Context(), idempotencyScope(c)+key, hex.EncodeToString(fingerprint[:]), func() idempotency.Result {
+		return a.saveResponse(a.surveys(c), workspace(c), body)
+	})
+	if err == idempotency.ErrKeyReused {
+		log.Println("idempotency key reused with another response", key)
+		c.JSONP(http.StatusConflict, Response{Message: err.Error(), ApiVersion: ApiVersion})
 		return
 	} else if err != nil {
-		log.Println("error while saving survey response", err)
-		c.JSONP(http.StatusInternalServerError, Response{Message: "error while saving survey response " + err.Error(), ApiVersion: ApiVersion})
+		log.Println("gave up waiting for the first request with the idempotency key", key, err)
+		c.JSONP(http.StatusServiceUnavailable, Response{Message: "error while saving response " + err.Error(), ApiVersion: ApiVersion})
 		return
 	}
-	c.JSONP(http.StatusCreated, Response{Message: "saved response", ApiVersion: ApiVersion})
+	if replayed {
+		c.Header(IdempotentReplayedHeader, "true")
+	}
+	c.JSONP(result.Status, result.Body)
+}
+
+// responseRequest is a response along with the token of the invitation it answers, anonymous responses have no token
+type responseRequest struct {
+	models.Response
+	Token string `json:"token,omitempty"`
+}
+
+// saveResponse stores the response in body and returns the status and body to answer with
+// a response with a token answers the invitation of the token, which is completed along with the response
+func (a *SurveyApp) saveResponse(surveys services.SurveyServiceInterface, workspace models.WorkspaceID, body []byte) idempotency.Result {
+	var request responseRequest
+	if err := binding.JSON.BindBody(body, &request); err != nil {
+		log.Println("error while reading response body", err)
+		return idempotency.Result{Status: http.StatusUnprocessableEntity, Body: Response{Message: "malformed body"}}
+	}
+	response := request.Response
+	response.InvitationID, response.RespondentID = nil, nil
+	if request.Token != "" {
+		invitation, err := a.verifyInvitation(workspace, response.SurveyID, request.Token)
+		if errors.Is(err, services.ErrInvalidInvitation) {
+			log.Println("invalid invitation token for survey", response.SurveyID.String(), err)
+			return idempotency.Result{Status: http.StatusForbidden, Body: Response{Message: err.Error(), ApiVersion: ApiVersion}}
+		} else if err == repositories.ErrInvitationCompleted {
+			log.Println("invitation already answered for survey", response.SurveyID.String())
+			return idempotency.Result{Status: http.StatusConflict, Body: Response{Message: "error while saving response " + err.Error(), ApiVersion: ApiVersion}}
+		} else if err != nil {
+			log.Println("error while verifying invitation", err)
+			return idempotency.Result{Status: http.StatusInternalServerError, Body: Response{Message: "error while saving survey response " + err.Error(), ApiVersion: ApiVersion}}
+		}
+		response.InvitationID, response.RespondentID = &invitation.ID, &invitation.RespondentID
+	}
+	saved, err := surveys.SaveResponse(workspace, response)
+	var validationErr *services.ValidationError
+	if errors.Is(err, services.ErrForbidden) {
+		log.Println("access denied while saving response", response.SurveyID.String(), err)
+		return idempotency.Result{Status: http.StatusForbidden, Body: Response{Message: err.Error(), ApiVersion: ApiVersion}}
+	} else if errors.As(err, &validationErr) {
+		log.Println("invalid response for survey", response.SurveyID.String(), err)
+		return idempotency.Result{Status: http.StatusUnprocessableEntity, Body: Response{Message: "invalid response", Data: validationErr.Violations, ApiVersion: ApiVersion}}
+	} else if err != nil && err == repositories.ErrNotFound {
+		log.Println("survey not found while saving response", response.SurveyID.String())
+		return idempotency.Result{Status: http.StatusNotFound, Body: Response{Message: "error while saving response " + err.Error(), ApiVersion: ApiVersion}}
+	} else if err == services.ErrSurveyNotPublished || err == services.ErrSurveyNotOpen ||
+		err == services.ErrSurveyClosed || err == services.ErrQuotaReached || err == repositories.ErrInvitationCompleted {
+		log.Println("survey not accepting responses while saving response", response.SurveyID.String(), err)
+		return idempotency.Result{Status: http.StatusConflict, Body: Response{Message: "error while saving response " + err.Error(), ApiVersion: ApiVersion}}
+	} else if err != nil {
+		log.Println("error while saving survey response", err)
+		return idempotency.Result{Status: http.StatusInternalServerError, Body: Response{Message: "error while saving survey response " + err.Error(), ApiVersion: ApiVersion}}
+	}
+	return idempotency.Result{Status: http.StatusCreated, Body: Response{Message: "saved response", Data: saved, ApiVersion: ApiVersion}}
+}
+
+// verifyInvitation returns the invitation of token, every token is invalid when invitations are disabled
+func (a *SurveyApp) verifyInvitation(workspace models.WorkspaceID, surveyID ksuid.KSUID, token string) (*models.Invitation, error) {
+	if a.invitationService == nil {
+		return nil, services.ErrInvalidInvitation
+	}
+	return a.invitationService.Verify(workspace, surveyID, token)
+}
+
+// idempotencyScope keeps the idempotency keys of different callers apart, keys are scoped to the workspace
+// and to the api key of the request
+func idempotencyScope(c *gin.Context) string {
+	scope := string(workspace(c)) + "/"
+	if key, ok := c.Get(apiKeyContextKey); ok {
+		scope += key.(*models.APIKey).ID.String() + "/"
+	}
+	return scope
 }
 
 func (a *SurveyApp) GetResponses(c *gin.Context) {
@@ -216,7 +723,22 @@ func (a *SurveyApp) GetResponses(c *gin.Context) {
 		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid survey id", ApiVersion: ApiVersion})
 		return
 	}
-	responses, err := a.surveyService.GetResponses(id)
+	scope, err := versionScope(c)
+	if err != nil {
+		log.Println("error while parsing version", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid version", ApiVersion: ApiVersion})
+		return
+	}
+	query := models.ResponseQuery{SurveyID: id}
+	if query.PageQuery, err = pageQuery(c, models.SortCreatedAt); err != nil {
+		log.Println("invalid page while fetching responses", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	page, err := a.surveys(c).GetResponses(workspace(c), query, scope)
+	if forbidden(c, err) {
+		return
+	}
 	if err != nil && err == repositories.ErrNotFound {
 		log.Println("survey not found while fetching responses", id.String())
 		c.JSONP(http.StatusNotFound, Response{Message: "error while fetching responses " + err.Error(), ApiVersion: ApiVersion})
@@ -226,9 +748,182 @@ func (a *SurveyApp) GetResponses(c *gin.Context) {
 		c.JSONP(http.StatusInternalServerError, Response{Message: "error while fetching responses", ApiVersion: ApiVersion})
 		return
 	}
-	c.JSONP(http.StatusOK, Response{Message: "success", Data: responses})
+	c.JSONP(http.StatusOK, Response{Message: "success", Data: page.Responses, NextCursor: page.Next})
+}
+
+// versionScope reads the version and compatible query parameters, every version is selected when version is missing
+func versionScope(c *gin.Context) (models.VersionScope, error) {
+	var scope models.VersionScope
+	if version := c.Query("version"); version != "" {
+		parsed, err := strconv.Atoi(version)
+		if err != nil || parsed < 1 {
+			return scope, fmt.Errorf("invalid version %q", version)
+		}
+		scope.Version = parsed
+	}
+	if compatible := c.Query("compatible"); compatible != "" {
+		parsed, err := strconv.ParseBool(compatible)
+		if err != nil {
+			return scope, fmt.Errorf("invalid compatible %q", compatible)
+		}
+		if scope.Version == 0 {
+			return scope, errors.New("compatible needs a version")
+		}
+		scope.Compatible = parsed
+	}
+	return scope, nil
+}
+
+// resultsFilter reads the version scope, the from and to days and include_drafts of a results request
+func resultsFilter(c *gin.Context) (models.ResultsFilter, error) {
+	var filter models.ResultsFilter
+	scope, err := versionScope(c)
+	if err != nil {
+		return filter, err
+	}
+	filter.VersionScope = scope
+	if filter.From, err = queryDay(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = queryDay(c, "to"); err != nil {
+		return filter, err
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return filter, errors.New("to cannot be before from")
+	}
+	filter.IncludeDrafts, err = queryBool(c, "include_drafts")
+	return filter, err
+}
+
+// pageQuery reads the limit, cursor, sort, order, created_from and created_to query params of a listing sortable by sorts
+// limit defaults to DefaultPageLimit and cannot go over MaxPageLimit, sort defaults to the first of sorts and order to asc
+// a cursor keeps the sort and order of the page it was returned with, so they do not need to be repeated
+// created_from and created_to are inclusive, days cover the whole day in UTC
+func pageQuery(c *gin.Context, sorts ...models.SortField) (models.PageQuery, error) {
+	query := models.PageQuery{Sort: sorts[0]}
+	var err error
+	if query.Limit, err = queryLimit(c); err != nil {
+		return query, err
+	}
+	if sort := c.Query("sort"); sort != "" {
+		query.Sort = models.SortField(sort)
+	}
+	switch order := c.Query("order"); order {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return query, fmt.Errorf("invalid order %q", order)
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err := models.DecodeCursor(cursor)
+		if err != nil {
+			return query, err
+		}
+		query.After, query.Sort, query.Descending = after, after.Sort, after.Descending
+	}
+	valid := false
+	for _, sort := range sorts {
+		valid = valid || query.Sort == sort
+	}
+	if !valid {
+		return query, fmt.Errorf("invalid sort %q", query.Sort)
+	}
+	if query.CreatedFrom, err = queryTime(c, "created_from", false); err != nil {
+		return query, err
+	}
+	if query.CreatedTo, err = queryTime(c, "created_to", true); err != nil {
+		return query, err
+	}
+	if query.CreatedFrom != nil && query.CreatedTo != nil && query.CreatedTo.Before(*query.CreatedFrom) {
+		return query, errors.New("created_to cannot be before created_from")
+	}
+	return query, nil
+}
+
+// queryLimit parses the limit query param, DefaultPageLimit when it is missing
+func queryLimit(c *gin.Context) (int, error) {
+	limit := c.Query("limit")
+	if limit == "" {
+		return DefaultPageLimit, nil
+	}
+	parsed, err := strconv.Atoi(limit)
+	if err != nil || parsed < 1 || parsed > MaxPageLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", MaxPageLimit)
+	}
+	return parsed, nil
+}
+
+// queryTime parses the RFC3339 time or day in the query parameter key, nil when it is missing
+// a day is its first instant, or its last one for endOfDay
+func queryTime(c *gin.Context, key string, endOfDay bool) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return &t, nil
+	}
+	day, err := queryDay(c, key)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a RFC3339 time or a date formatted as %s", key, models.DateLayout)
+	}
+	if endOfDay {
+		end := day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		return &end, nil
+	}
+	return day, nil
 }
 
+// queryBool parses the bool in the query parameter key, false when it is missing
+func queryBool(c *gin.Context, key string) (bool, error) {
+	value := c.Query(key)
+	if value == "" {
+		return false, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q", key, value)
+	}
+	return parsed, nil
+}
+
+// queryDay parses the day in the query parameter key, nil when it is missing
+func queryDay(c *gin.Context, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	day, err := time.Parse(models.DateLayout, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a date formatted as %s", key, models.DateLayout)
+	}
+	return &day, nil
+}
+
+// Dump persists the current entries and drops the journaled operations covered by them
+// the journal position is taken before reading the entries, so operations racing with the dump
+// stay in the journal and are replayed over the dumped entries on the next load
+// dumps run one at a time from taking the position until the journal is truncated
 func (a *SurveyApp) Dump() error {
-	return a.db.Dump(a.surveyService.Entries())
+	a.dumpMu.Lock()
+	defer a.dumpMu.Unlock()
+	position := a.db.Position()
+	entries := a.surveyService.Entries()
+	if a.apiKeyService != nil {
+		entries.APIKeys = a.apiKeyService.Entries()
+	}
+	if a.userService != nil {
+		entries.Users = a.userService.Entries()
+	}
+	if a.invitationService != nil {
+		entries.Respondents, entries.Invitations = a.invitationService.Entries()
+	}
+	if a.mailService != nil {
+		entries.Outbox = a.mailService.Entries()
+	}
+	if err := a.db.Dump(entries); err != nil {
+		return err
+	}
+	return a.db.Truncate(position)
 }
@@ -5,23 +5,36 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"survey-platform/internal/db/db_mock"
+	"survey-platform/internal/db/jsondb"
 	"survey-platform/internal/models"
 	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/responserepo"
+	"survey-platform/internal/repositories/surveyrepo"
+	"survey-platform/internal/services"
 	"survey-platform/internal/services/services_mock"
+	"survey-platform/internal/services/surveyservice"
+	"survey-platform/pkg/export"
+	"survey-platform/pkg/idgenerator/ksuidgenerator"
+	"survey-platform/pkg/timegenerator/actualtimegenerator"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/segmentio/ksuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSurveyApp_HealthCheck(t *testing.T) {
 	t.Run("should return status ok(200) on hitting health check endpoint", func(t *testing.T) {
-		surveyApp := NewSurveyApp(nil, nil)
+		surveyApp := NewSurveyApp(nil, nil, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		req, _ := http.NewRequest(http.MethodGet, "/", nil)
 		resp := httptest.NewRecorder()
@@ -46,8 +59,8 @@ func TestSurveyApp_CreateSurvey(t *testing.T) {
 			},
 		}
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().CreateSurvey(&mockSurvey).SetArg(0, mockSurvey).Return(&mockSurvey, nil)
-		surveyApp := NewSurveyApp(nil, mockService)
+		mockService.EXPECT().CreateSurvey(models.DefaultWorkspace, &mockSurvey).SetArg(1, mockSurvey).Return(&mockSurvey, nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		marshalledSurvey, _ := json.Marshal(&mockSurvey)
 		body := bytes.NewReader(marshalledSurvey)
@@ -71,8 +84,8 @@ func TestSurveyApp_CreateSurvey(t *testing.T) {
 			},
 		}
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().CreateSurvey(&mockSurvey).Return(nil, errors.New("something went wrong")).SetArg(0, mockSurvey)
-		surveyApp := NewSurveyApp(nil, mockService)
+		mockService.EXPECT().CreateSurvey(models.DefaultWorkspace, &mockSurvey).Return(nil, errors.New("something went wrong")).SetArg(1, mockSurvey)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		marshalledSurvey, _ := json.Marshal(&mockSurvey)
 		body := bytes.NewReader(marshalledSurvey)
@@ -85,7 +98,7 @@ func TestSurveyApp_CreateSurvey(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		surveyApp := NewSurveyApp(nil, mockService)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		body := bytes.NewReader([]byte("hello"))
 		req, _ := http.NewRequest(http.MethodPost, "/survey/", body)
@@ -93,6 +106,39 @@ func TestSurveyApp_CreateSurvey(t *testing.T) {
 		router.ServeHTTP(resp, req)
 		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
 	})
+	for name, survey := range invalidRuleSurveys() {
+		survey := survey
+		t.Run("should return unprocessable entity(422) when the rules "+name, func(t *testing.T) {
+			surveyApp := NewSurveyApp(nil, surveyservice.NewSurveyService(3, nil, nil, nil, nil), nil, nil, nil)
+			router := surveyApp.SetupRoutes()
+			marshalledSurvey, _ := json.Marshal(&survey)
+			req, _ := http.NewRequest(http.MethodPost, "/survey/", bytes.NewReader(marshalledSurvey))
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+			assert.Contains(t, resp.Body.String(), services.ErrInvalidSurvey.Error())
+		})
+	}
+}
+
+// invalidRuleSurveys returns surveys whose rules are rejected, by the way they are broken
+func invalidRuleSurveys() map[string]models.Survey {
+	questions := []models.Question{
+		{ID: ksuid.New(), Question: "did you visit?", Type: models.QuestionYesNo},
+		{ID: ksuid.New(), Question: "what went wrong?", Type: models.QuestionText},
+	}
+	return map[string]models.Survey{
+		"form a cycle": {Name: "cyclic survey", Questions: questions, Rules: []models.Rule{{
+			Type:       models.RuleShowIf,
+			QuestionID: questions[1].ID,
+			Condition:  models.Condition{QuestionID: questions[1].ID, Operator: models.OpAnswered},
+		}}},
+		"reference an unknown question": {Name: "dangling survey", Questions: questions, Rules: []models.Rule{{
+			Type:       models.RuleShowIf,
+			QuestionID: questions[1].ID,
+			Condition:  models.Condition{QuestionID: ksuid.New(), Operator: models.OpAnswered},
+		}}},
+	}
 }
 
 func TestSurveyApp_GetSurvey(t *testing.T) {
@@ -112,19 +158,30 @@ func TestSurveyApp_GetSurvey(t *testing.T) {
 			},
 		}
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().GetSurvey(surveyID).Return(&mockSurvey, nil)
-		surveyApp := NewSurveyApp(nil, mockService)
+		remaining, closesIn := 7, int64(3600)
+		mockDetails := models.SurveyDetails{
+			Survey:       mockSurvey,
+			Availability: models.Availability{AcceptingResponses: true, RemainingResponses: &remaining, ClosesInSeconds: &closesIn},
+		}
+		mockService.EXPECT().GetSurveyDetails(models.DefaultWorkspace, surveyID).Return(&mockDetails, nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/survey/%s", surveyID.String()), nil)
 		resp := httptest.NewRecorder()
 		router.ServeHTTP(resp, req)
 		assert.Equal(t, http.StatusOK, resp.Code)
+		var body struct {
+			Data models.SurveyDetails `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.Equal(t, mockDetails.Name, body.Data.Name)
+		assert.Equal(t, mockDetails.Availability, body.Data.Availability)
 	})
 	t.Run("should return statusUnprocessableEntity(422) when id is invalid", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		surveyApp := NewSurveyApp(nil, mockService)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		req, _ := http.NewRequest(http.MethodGet, "/survey/xxx", nil)
 		resp := httptest.NewRecorder()
@@ -136,8 +193,8 @@ func TestSurveyApp_GetSurvey(t *testing.T) {
 		defer ctrl.Finish()
 		surveyID := ksuid.New()
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().GetSurvey(surveyID).Return(nil, repositories.ErrNotFound)
-		surveyApp := NewSurveyApp(nil, mockService)
+		mockService.EXPECT().GetSurveyDetails(models.DefaultWorkspace, surveyID).Return(nil, repositories.ErrNotFound)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/survey/%s", surveyID.String()), nil)
 		resp := httptest.NewRecorder()
@@ -149,8 +206,8 @@ func TestSurveyApp_GetSurvey(t *testing.T) {
 		defer ctrl.Finish()
 		surveyID := ksuid.New()
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().GetSurvey(surveyID).Return(nil, errors.New("something went wrong"))
-		surveyApp := NewSurveyApp(nil, mockService)
+		mockService.EXPECT().GetSurveyDetails(models.DefaultWorkspace, surveyID).Return(nil, errors.New("something went wrong"))
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/survey/%s", surveyID.String()), nil)
 		resp := httptest.NewRecorder()
@@ -177,8 +234,8 @@ func TestSurveyApp_UpdateSurvey(t *testing.T) {
 			},
 		}
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().UpdateSurvey(surveyID, mockSurvey).Return(&mockSurvey, nil)
-		surveyApp := NewSurveyApp(nil, mockService)
+		mockService.EXPECT().UpdateSurvey(models.DefaultWorkspace, surveyID, mockSurvey).Return(&mockSurvey, nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		marshalledSurvey, _ := json.Marshal(&mockSurvey)
 		body := bytes.NewReader(marshalledSurvey)
@@ -191,7 +248,7 @@ func TestSurveyApp_UpdateSurvey(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		surveyApp := NewSurveyApp(nil, mockService)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		req, _ := http.NewRequest(http.MethodPut, "/survey/xxx", nil)
 		resp := httptest.NewRecorder()
@@ -203,7 +260,7 @@ func TestSurveyApp_UpdateSurvey(t *testing.T) {
 		defer ctrl.Finish()
 		surveyID := ksuid.New()
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		surveyApp := NewSurveyApp(nil, mockService)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		body := bytes.NewReader([]byte("hello"))
 		req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/survey/%s", surveyID.String()), body)
@@ -228,8 +285,8 @@ func TestSurveyApp_UpdateSurvey(t *testing.T) {
 			},
 		}
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().UpdateSurvey(surveyID, mockSurvey).Return(nil, repositories.ErrNotFound)
-		surveyApp := NewSurveyApp(nil, mockService)
+		mockService.EXPECT().UpdateSurvey(models.DefaultWorkspace, surveyID, mockSurvey).Return(nil, repositories.ErrNotFound)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		marshalledSurvey, _ := json.Marshal(&mockSurvey)
 		body := bytes.NewReader(marshalledSurvey)
@@ -255,8 +312,8 @@ func TestSurveyApp_UpdateSurvey(t *testing.T) {
 			},
 		}
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().UpdateSurvey(surveyID, mockSurvey).Return(nil, errors.New("something went wrong"))
-		surveyApp := NewSurveyApp(nil, mockService)
+		mockService.EXPECT().UpdateSurvey(models.DefaultWorkspace, surveyID, mockSurvey).Return(nil, errors.New("something went wrong"))
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		marshalledSurvey, _ := json.Marshal(&mockSurvey)
 		body := bytes.NewReader(marshalledSurvey)
@@ -265,6 +322,19 @@ func TestSurveyApp_UpdateSurvey(t *testing.T) {
 		router.ServeHTTP(resp, req)
 		assert.Equal(t, http.StatusInternalServerError, resp.Code)
 	})
+	for name, survey := range invalidRuleSurveys() {
+		survey := survey
+		t.Run("should return statusUnprocessableEntity(422) when the rules "+name, func(t *testing.T) {
+			surveyApp := NewSurveyApp(nil, surveyservice.NewSurveyService(3, nil, nil, nil, nil), nil, nil, nil)
+			router := surveyApp.SetupRoutes()
+			marshalledSurvey, _ := json.Marshal(&survey)
+			req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/survey/%s", ksuid.New().String()), bytes.NewReader(marshalledSurvey))
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+			assert.Contains(t, resp.Body.String(), services.ErrInvalidSurvey.Error())
+		})
+	}
 }
 
 func TestSurveyApp_DeleteSurvey(t *testing.T) {
@@ -274,8 +344,8 @@ func TestSurveyApp_DeleteSurvey(t *testing.T) {
 		surveyID := ksuid.New()
 
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().DeleteSurvey(surveyID).Return(nil)
-		surveyApp := NewSurveyApp(nil, mockService)
+		mockService.EXPECT().DeleteSurvey(models.DefaultWorkspace, surveyID, false).Return(nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/survey/%s", surveyID.String()), nil)
 		resp := httptest.NewRecorder()
@@ -286,7 +356,7 @@ func TestSurveyApp_DeleteSurvey(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		surveyApp := NewSurveyApp(nil, mockService)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		req, _ := http.NewRequest(http.MethodDelete, "/survey/xxxx", nil)
 		resp := httptest.NewRecorder()
@@ -298,8 +368,8 @@ func TestSurveyApp_DeleteSurvey(t *testing.T) {
 		defer ctrl.Finish()
 		surveyID := ksuid.New()
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().DeleteSurvey(surveyID).Return(repositories.ErrNotFound)
-		surveyApp := NewSurveyApp(nil, mockService)
+		mockService.EXPECT().DeleteSurvey(models.DefaultWorkspace, surveyID, false).Return(repositories.ErrNotFound)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/survey/%s", surveyID.String()), nil)
 		resp := httptest.NewRecorder()
@@ -311,14 +381,51 @@ func TestSurveyApp_DeleteSurvey(t *testing.T) {
 		defer ctrl.Finish()
 		surveyID := ksuid.New()
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().DeleteSurvey(surveyID).Return(errors.New("something went wrong"))
-		surveyApp := NewSurveyApp(nil, mockService)
+		mockService.EXPECT().DeleteSurvey(models.DefaultWorkspace, surveyID, false).Return(errors.New("something went wrong"))
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/survey/%s", surveyID.String()), nil)
 		resp := httptest.NewRecorder()
 		router.ServeHTTP(resp, req)
 		assert.Equal(t, http.StatusInternalServerError, resp.Code)
 	})
+	t.Run("should return statusConflict(409) when survey has responses and cascade is not set", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().DeleteSurvey(models.DefaultWorkspace, surveyID, false).Return(repositories.ErrSurveyHasResponses)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/survey/%s", surveyID.String()), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+	})
+	t.Run("should delete survey along with its responses when cascade is set", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().DeleteSurvey(models.DefaultWorkspace, surveyID, true).Return(nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/survey/%s?cascade=true", surveyID.String()), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+	})
+	t.Run("should return statusUnprocessableEntity(422) when cascade is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/survey/%s?cascade=maybe", ksuid.New().String()), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	})
 }
 
 func TestSurveyApp_GetAllSurveys(t *testing.T) {
@@ -350,8 +457,8 @@ func TestSurveyApp_GetAllSurveys(t *testing.T) {
 			},
 		}
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().GetAllSurveys().Return(mockSurveys, nil)
-		surveyApp := NewSurveyApp(nil, mockService)
+		mockService.EXPECT().GetAllSurveys(models.DefaultWorkspace, models.SurveyQuery{PageQuery: models.PageQuery{Limit: DefaultPageLimit, Sort: models.SortCreatedAt}}).Return(&models.SurveyPage{Surveys: mockSurveys}, nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		req, _ := http.NewRequest(http.MethodGet, "/survey/", nil)
 		resp := httptest.NewRecorder()
@@ -362,14 +469,191 @@ func TestSurveyApp_GetAllSurveys(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().GetAllSurveys().Return(nil, errors.New("something went wrong"))
-		surveyApp := NewSurveyApp(nil, mockService)
+		mockService.EXPECT().GetAllSurveys(models.DefaultWorkspace, models.SurveyQuery{PageQuery: models.PageQuery{Limit: DefaultPageLimit, Sort: models.SortCreatedAt}}).Return(nil, errors.New("something went wrong"))
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		req, _ := http.NewRequest(http.MethodGet, "/survey/", nil)
 		resp := httptest.NewRecorder()
 		router.ServeHTTP(resp, req)
 		assert.Equal(t, http.StatusInternalServerError, resp.Code)
 	})
+	t.Run("should filter by status and name query params", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		query := models.SurveyQuery{Status: models.SurveyPublished, Name: "new", PageQuery: models.PageQuery{Limit: DefaultPageLimit, Sort: models.SortCreatedAt}}
+		mockService.EXPECT().GetAllSurveys(models.DefaultWorkspace, query).Return(&models.SurveyPage{Surveys: []models.Survey{{Name: "new survey"}}}, nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, "/survey/?status=published&name=new", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+	t.Run("should return statusUnprocessableEntity(422) when status is unknown", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, "/survey/?status=deleted", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	})
+	t.Run("should pass the page query to the service and return the next cursor", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		from := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2021, 6, 30, 23, 59, 59, 999999999, time.UTC)
+		query := models.SurveyQuery{PageQuery: models.PageQuery{Limit: 2, Sort: models.SortName, Descending: true, CreatedFrom: &from, CreatedTo: &to}}
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().GetAllSurveys(models.DefaultWorkspace, query).Return(&models.SurveyPage{Surveys: []models.Survey{{Name: "b"}, {Name: "a"}}, Next: "next"}, nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, "/survey/?limit=2&sort=name&order=desc&created_from=2021-06-01&created_to=2021-06-30", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var body struct {
+			Data       []models.Survey `json:"data"`
+			NextCursor string          `json:"next_cursor"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.Len(t, body.Data, 2)
+		assert.Equal(t, "next", body.NextCursor)
+	})
+	t.Run("should read the sort and order from the cursor", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		cursor := models.Cursor{Sort: models.SortUpdatedAt, Descending: true, Key: "2021-06-01T00:00:00.000000000Z", ID: ksuid.New()}
+		query := models.SurveyQuery{PageQuery: models.PageQuery{Limit: DefaultPageLimit, After: &cursor, Sort: models.SortUpdatedAt, Descending: true}}
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().GetAllSurveys(models.DefaultWorkspace, query).Return(&models.SurveyPage{Surveys: []models.Survey{}}, nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, "/survey/?sort=name&cursor="+cursor.Encode(), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+	for _, query := range []string{"limit=0", "limit=1001", "limit=ten", "sort=status", "order=up", "cursor=xxx", "created_from=yesterday",
+		"created_from=2021-06-02&created_to=2021-06-01"} {
+		t.Run("should return statusUnprocessableEntity(422) for "+query, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+			surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+			router := surveyApp.SetupRoutes()
+			req, _ := http.NewRequest(http.MethodGet, "/survey/?"+query, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		})
+	}
+}
+
+func TestSurveyApp_SearchSurveys(t *testing.T) {
+	t.Run("should return statusOK(200) with the matching surveys", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		results := []models.SearchResult{{Survey: models.Survey{ID: ksuid.New(), Name: "restaurant feedback"}, Score: 2.5}}
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().SearchSurveys(models.DefaultWorkspace, "restaurant food", 5).Return(results, nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, "/survey/search?q=restaurant+food&limit=5", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var body struct {
+			Data []models.SearchResult `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.Equal(t, results, body.Data)
+	})
+	t.Run("should return StatusInternalServerError(500) when service return other errors", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().SearchSurveys(models.DefaultWorkspace, "restaurant", DefaultPageLimit).Return(nil, errors.New("something went wrong"))
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, "/survey/search?q=restaurant", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	})
+	for _, query := range []string{"", "q=", "q=the+%3F", "q=restaurant&limit=0"} {
+		t.Run("should return statusUnprocessableEntity(422) for "+query, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+			surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+			router := surveyApp.SetupRoutes()
+			req, _ := http.NewRequest(http.MethodGet, "/survey/search?"+query, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		})
+	}
+}
+
+func TestSurveyApp_ChangeStatus(t *testing.T) {
+	t.Run("should return statusOK(200) with the survey on publish, close and archive", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().PublishSurvey(models.DefaultWorkspace, surveyID).Return(&models.Survey{ID: surveyID, Status: models.SurveyPublished}, nil)
+		mockService.EXPECT().CloseSurvey(models.DefaultWorkspace, surveyID).Return(&models.Survey{ID: surveyID, Status: models.SurveyClosed}, nil)
+		mockService.EXPECT().ArchiveSurvey(models.DefaultWorkspace, surveyID).Return(&models.Survey{ID: surveyID, Status: models.SurveyArchived}, nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		for _, action := range []string{"publish", "close", "archive"} {
+			req, _ := http.NewRequest(http.MethodPost, "/survey/"+surveyID.String()+"/"+action, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusOK, resp.Code, action)
+		}
+	})
+	t.Run("should return statusConflict(409) when transition is not allowed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().CloseSurvey(models.DefaultWorkspace, surveyID).Return(nil, fmt.Errorf("%w: draft to closed", services.ErrInvalidTransition))
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodPost, "/survey/"+surveyID.String()+"/close", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+	})
+	t.Run("should return statusNotFound(404) when survey is not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().PublishSurvey(models.DefaultWorkspace, surveyID).Return(nil, repositories.ErrNotFound)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodPost, "/survey/"+surveyID.String()+"/publish", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+	t.Run("should return statusUnprocessableEntity(422) when id is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodPost, "/survey/1/archive", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	})
 }
 
 func TestSurveyApp_SaveResponse(t *testing.T) {
@@ -382,17 +666,19 @@ func TestSurveyApp_SaveResponse(t *testing.T) {
 			Answers: []models.Answer{
 				{
 					QuestionID: qID1,
-					Answer:     false,
+					Answer:     models.BoolValue(false),
 				},
 				{
 					QuestionID: qID2,
-					Answer:     true,
+					Answer:     models.BoolValue(true),
 				},
 			},
 		}
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().SaveResponse(mockResponse).Return(&mockResponse, nil)
-		surveyApp := NewSurveyApp(nil, mockService)
+		saved := mockResponse
+		saved.ID = ksuid.New()
+		mockService.EXPECT().SaveResponse(models.DefaultWorkspace, mockResponse).Return(&saved, nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		marshalledSurvey, _ := json.Marshal(&mockResponse)
 		body := bytes.NewReader(marshalledSurvey)
@@ -400,6 +686,11 @@ func TestSurveyApp_SaveResponse(t *testing.T) {
 		resp := httptest.NewRecorder()
 		router.ServeHTTP(resp, req)
 		assert.Equal(t, http.StatusCreated, resp.Code)
+		var created struct {
+			Data models.Response `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+		assert.Equal(t, saved.ID, created.Data.ID)
 	})
 	t.Run("should not save response and return statusNotFound(404) when surveyID is not found", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -410,17 +701,17 @@ func TestSurveyApp_SaveResponse(t *testing.T) {
 			Answers: []models.Answer{
 				{
 					QuestionID: qID1,
-					Answer:     false,
+					Answer:     models.BoolValue(false),
 				},
 				{
 					QuestionID: qID2,
-					Answer:     true,
+					Answer:     models.BoolValue(true),
 				},
 			},
 		}
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().SaveResponse(mockResponse).Return(nil, repositories.ErrNotFound)
-		surveyApp := NewSurveyApp(nil, mockService)
+		mockService.EXPECT().SaveResponse(models.DefaultWorkspace, mockResponse).Return(nil, repositories.ErrNotFound)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		marshalledSurvey, _ := json.Marshal(&mockResponse)
 		body := bytes.NewReader(marshalledSurvey)
@@ -429,6 +720,46 @@ func TestSurveyApp_SaveResponse(t *testing.T) {
 		router.ServeHTTP(resp, req)
 		assert.Equal(t, http.StatusNotFound, resp.Code)
 	})
+	t.Run("should return statusConflict(409) when survey is not accepting responses", func(t *testing.T) {
+		for _, serviceErr := range []error{services.ErrSurveyNotPublished, services.ErrSurveyNotOpen, services.ErrSurveyClosed, services.ErrQuotaReached} {
+			ctrl := gomock.NewController(t)
+			mockResponse := models.Response{SurveyID: ksuid.New()}
+			mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+			mockService.EXPECT().SaveResponse(models.DefaultWorkspace, mockResponse).Return(nil, serviceErr)
+			surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+			router := surveyApp.SetupRoutes()
+			marshalledResponse, _ := json.Marshal(&mockResponse)
+			req, _ := http.NewRequest(http.MethodPost, "/response/", bytes.NewReader(marshalledResponse))
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusConflict, resp.Code, serviceErr.Error())
+			ctrl.Finish()
+		}
+	})
+	t.Run("should return unprocessable entity(422) with violations when answers are invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID, qID1, qID2 := ksuid.New(), ksuid.New(), ksuid.New()
+		mockResponse := models.Response{
+			SurveyID: surveyID,
+			Answers:  []models.Answer{{QuestionID: qID1, Answer: models.BoolValue(false)}},
+		}
+		violations := []services.Violation{{QuestionID: qID2, Message: "question is required"}}
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().SaveResponse(models.DefaultWorkspace, mockResponse).Return(nil, &services.ValidationError{Violations: violations})
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		marshalledResponse, _ := json.Marshal(&mockResponse)
+		req, _ := http.NewRequest(http.MethodPost, "/response/", bytes.NewReader(marshalledResponse))
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		var body struct {
+			Data []services.Violation `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.Equal(t, violations, body.Data)
+	})
 	t.Run("should return internal server error(500) when service returns error", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
@@ -438,17 +769,17 @@ func TestSurveyApp_SaveResponse(t *testing.T) {
 			Answers: []models.Answer{
 				{
 					QuestionID: qID1,
-					Answer:     false,
+					Answer:     models.BoolValue(false),
 				},
 				{
 					QuestionID: qID2,
-					Answer:     true,
+					Answer:     models.BoolValue(true),
 				},
 			},
 		}
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().SaveResponse(mockResponse).Return(nil, errors.New("something went wrong"))
-		surveyApp := NewSurveyApp(nil, mockService)
+		mockService.EXPECT().SaveResponse(models.DefaultWorkspace, mockResponse).Return(nil, errors.New("something went wrong"))
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		marshalledSurvey, _ := json.Marshal(&mockResponse)
 		body := bytes.NewReader(marshalledSurvey)
@@ -461,7 +792,7 @@ func TestSurveyApp_SaveResponse(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		surveyApp := NewSurveyApp(nil, mockService)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		body := bytes.NewReader([]byte("hello"))
 		req, _ := http.NewRequest(http.MethodPost, "/response/", body)
@@ -471,6 +802,83 @@ func TestSurveyApp_SaveResponse(t *testing.T) {
 	})
 }
 
+func TestSurveyApp_SaveResponse_Idempotency(t *testing.T) {
+	surveyID := ksuid.New()
+	response := models.Response{SurveyID: surveyID, Answers: []models.Answer{{QuestionID: ksuid.New(), Answer: models.BoolValue(true)}}}
+	body, _ := json.Marshal(&response)
+	post := func(router http.Handler, key string, body []byte) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/response/", bytes.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, key)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+	t.Run("should save the response once and replay the result to retries with the same key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		saved := response
+		saved.ID = ksuid.New()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().SaveResponse(models.DefaultWorkspace, response).Return(&saved, nil).Times(2)
+		router := NewSurveyApp(nil, mockService, nil, nil, nil).SetupRoutes()
+		first := post(router, "retry-1", body)
+		assert.Equal(t, http.StatusCreated, first.Code)
+		assert.Contains(t, first.Body.String(), saved.ID.String())
+		assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+		retry := post(router, "retry-1", body)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+		assert.Equal(t, http.StatusCreated, post(router, "retry-2", body).Code, "another key saves again")
+		assert.Equal(t, http.StatusUnprocessableEntity, post(router, strings.Repeat("k", MaxIdempotencyKeyLength+1), body).Code)
+	})
+	t.Run("should return statusConflict(409) for a key reused with another body", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().SaveResponse(models.DefaultWorkspace, response).Return(&response, nil)
+		router := NewSurveyApp(nil, mockService, nil, nil, nil).SetupRoutes()
+		assert.Equal(t, http.StatusCreated, post(router, "retry-1", body).Code)
+		other, _ := json.Marshal(models.Response{SurveyID: surveyID})
+		resp := post(router, "retry-1", other)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+		assert.Contains(t, resp.Body.String(), "idempotency key was already used for another request")
+	})
+	t.Run("should save again once a failed request is retried", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		gomock.InOrder(
+			mockService.EXPECT().SaveResponse(models.DefaultWorkspace, response).Return(nil, errors.New("disk full")),
+			mockService.EXPECT().SaveResponse(models.DefaultWorkspace, response).Return(&response, nil),
+		)
+		router := NewSurveyApp(nil, mockService, nil, nil, nil).SetupRoutes()
+		assert.Equal(t, http.StatusInternalServerError, post(router, "retry-1", body).Code)
+		assert.Equal(t, http.StatusCreated, post(router, "retry-1", body).Code)
+	})
+	t.Run("should keep the keys of different api keys apart", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockAPIKeyService := services_mock.NewMockAPIKeyServiceInterface(ctrl)
+		for _, token := range []string{"sp_one", "sp_two"} {
+			key := &models.APIKey{ID: ksuid.New(), Scopes: []models.Scope{models.ScopeResponsesWrite}}
+			mockAPIKeyService.EXPECT().Authenticate(token).Return(key, nil).AnyTimes()
+		}
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().SaveResponse(models.DefaultWorkspace, response).Return(&response, nil).Times(2)
+		router := NewSurveyApp(nil, mockService, mockAPIKeyService, nil, nil).SetupRoutes()
+		for _, token := range []string{"sp_one", "sp_two"} {
+			req, _ := http.NewRequest(http.MethodPost, "/response/", bytes.NewReader(body))
+			req.Header.Set(IdempotencyKeyHeader, "retry-1")
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusCreated, resp.Code)
+			assert.Empty(t, resp.Header().Get(IdempotentReplayedHeader))
+		}
+	})
+}
+
 func TestSurveyApp_GetResponses(t *testing.T) {
 	t.Run("should return statusOK(200) on success", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -482,11 +890,11 @@ func TestSurveyApp_GetResponses(t *testing.T) {
 				Answers: []models.Answer{
 					{
 						QuestionID: qID1,
-						Answer:     false,
+						Answer:     models.BoolValue(false),
 					},
 					{
 						QuestionID: qID2,
-						Answer:     true,
+						Answer:     models.BoolValue(true),
 					},
 				},
 			},
@@ -495,18 +903,18 @@ func TestSurveyApp_GetResponses(t *testing.T) {
 				Answers: []models.Answer{
 					{
 						QuestionID: qID1,
-						Answer:     true,
+						Answer:     models.BoolValue(true),
 					},
 					{
 						QuestionID: qID2,
-						Answer:     true,
+						Answer:     models.BoolValue(true),
 					},
 				},
 			},
 		}
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().GetResponses(surveyID).Return(mockResponses, nil)
-		surveyApp := NewSurveyApp(nil, mockService)
+		mockService.EXPECT().GetResponses(models.DefaultWorkspace, models.ResponseQuery{SurveyID: surveyID, PageQuery: models.PageQuery{Limit: DefaultPageLimit, Sort: models.SortCreatedAt}}, models.VersionScope{}).Return(&models.ResponsePage{Responses: mockResponses}, nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/response/?survey_id=%s", surveyID.String()), nil)
 		resp := httptest.NewRecorder()
@@ -517,7 +925,7 @@ func TestSurveyApp_GetResponses(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		surveyApp := NewSurveyApp(nil, mockService)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		req, _ := http.NewRequest(http.MethodGet, "/response/?survey_id=xxx", nil)
 		resp := httptest.NewRecorder()
@@ -529,8 +937,8 @@ func TestSurveyApp_GetResponses(t *testing.T) {
 		defer ctrl.Finish()
 		surveyID := ksuid.New()
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().GetResponses(surveyID).Return(nil, repositories.ErrNotFound)
-		surveyApp := NewSurveyApp(nil, mockService)
+		mockService.EXPECT().GetResponses(models.DefaultWorkspace, models.ResponseQuery{SurveyID: surveyID, PageQuery: models.PageQuery{Limit: DefaultPageLimit, Sort: models.SortCreatedAt}}, models.VersionScope{}).Return(nil, repositories.ErrNotFound)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/response/?survey_id=%s", surveyID.String()), nil)
 		resp := httptest.NewRecorder()
@@ -542,14 +950,320 @@ func TestSurveyApp_GetResponses(t *testing.T) {
 		defer ctrl.Finish()
 		surveyID := ksuid.New()
 		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
-		mockService.EXPECT().GetResponses(surveyID).Return(nil, errors.New("something went wrong"))
-		surveyApp := NewSurveyApp(nil, mockService)
+		mockService.EXPECT().GetResponses(models.DefaultWorkspace, models.ResponseQuery{SurveyID: surveyID, PageQuery: models.PageQuery{Limit: DefaultPageLimit, Sort: models.SortCreatedAt}}, models.VersionScope{}).Return(nil, errors.New("something went wrong"))
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
 		router := surveyApp.SetupRoutes()
 		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/response/?survey_id=%s", surveyID.String()), nil)
 		resp := httptest.NewRecorder()
 		router.ServeHTTP(resp, req)
 		assert.Equal(t, http.StatusInternalServerError, resp.Code)
 	})
+	t.Run("should pass the version scope to the service", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().GetResponses(models.DefaultWorkspace, models.ResponseQuery{SurveyID: surveyID, PageQuery: models.PageQuery{Limit: DefaultPageLimit, Sort: models.SortCreatedAt}}, models.VersionScope{Version: 2, Compatible: true}).
+			Return(&models.ResponsePage{Responses: []models.Response{{SurveyID: surveyID}}}, nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/response/?survey_id=%s&version=2&compatible=true", surveyID.String()), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+	t.Run("should pass the page query to the service and return the next cursor", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		cursor := models.Cursor{Sort: models.SortCreatedAt, Key: "2021-06-01T00:00:00.000000000Z", ID: ksuid.New()}
+		query := models.ResponseQuery{SurveyID: surveyID, PageQuery: models.PageQuery{Limit: 1, After: &cursor, Sort: models.SortCreatedAt}}
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().GetResponses(models.DefaultWorkspace, query, models.VersionScope{}).
+			Return(&models.ResponsePage{Responses: []models.Response{{SurveyID: surveyID}}, Next: "next"}, nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/response/?survey_id=%s&limit=1&cursor=%s", surveyID.String(), cursor.Encode()), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"next_cursor":"next"`)
+	})
+	for _, query := range []string{"version=0", "version=two", "version=1&compatible=maybe", "compatible=true", "sort=name", "limit=-1"} {
+		t.Run("should return statusUnprocessableEntity(422) for "+query, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+			surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+			router := surveyApp.SetupRoutes()
+			req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/response/?survey_id=%s&%s", ksuid.New().String(), query), nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		})
+	}
+}
+
+func TestSurveyApp_GetSurveyVersions(t *testing.T) {
+	t.Run("should return statusOK(200) with the versions", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().GetSurveyVersions(models.DefaultWorkspace, surveyID).Return([]models.Survey{{ID: surveyID, Version: 1}, {ID: surveyID, Version: 2}}, nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/survey/%s/versions", surveyID.String()), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"version":2`)
+	})
+	t.Run("should return statusNotFound(404) when survey does not exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().GetSurveyVersions(models.DefaultWorkspace, surveyID).Return(nil, repositories.ErrNotFound)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/survey/%s/versions", surveyID.String()), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+}
+
+func TestSurveyApp_GetResults(t *testing.T) {
+	t.Run("should return statusOK(200) with the results for the filter", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		from, to := time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, time.June, 30, 0, 0, 0, 0, time.UTC)
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().GetResults(models.DefaultWorkspace, surveyID, models.ResultsFilter{VersionScope: models.VersionScope{Version: 2}, From: &from, To: &to, IncludeDrafts: true}).
+			Return(&models.Results{SurveyID: surveyID, TotalResponses: 4}, nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/survey/%s/results?version=2&from=2021-06-01&to=2021-06-30&include_drafts=true", surveyID.String()), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"total_responses":4`)
+	})
+	for _, query := range []string{"from=yesterday", "to=2021-13-01", "from=2021-06-02&to=2021-06-01", "version=x", "include_drafts=maybe"} {
+		t.Run("should return statusUnprocessableEntity(422) for "+query, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+			surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+			router := surveyApp.SetupRoutes()
+			req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/survey/%s/results?%s", ksuid.New().String(), query), nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		})
+	}
+	t.Run("should return statusNotFound(404) when survey does not exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().GetResults(models.DefaultWorkspace, surveyID, models.ResultsFilter{}).Return(nil, repositories.ErrNotFound)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/survey/%s/results", surveyID.String()), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+}
+
+func TestSurveyApp_GetCrossTab(t *testing.T) {
+	t.Run("should return statusOK(200) with the cross tab", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID, rowID, columnID := ksuid.New(), ksuid.New(), ksuid.New()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().GetCrossTab(models.DefaultWorkspace, surveyID, rowID, columnID, models.VersionScope{}).Return(&models.CrossTab{SurveyID: surveyID, Total: 3}, nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/survey/%s/crosstab?row=%s&col=%s", surveyID, rowID, columnID), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"total":3`)
+	})
+	t.Run("should return statusUnprocessableEntity(422) when a question id is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/survey/%s/crosstab?row=%s", ksuid.New(), ksuid.New()), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	})
+	t.Run("should return statusUnprocessableEntity(422) when a question cannot be tabulated", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID, rowID, columnID := ksuid.New(), ksuid.New(), ksuid.New()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().GetCrossTab(models.DefaultWorkspace, surveyID, rowID, columnID, models.VersionScope{}).
+			Return(nil, fmt.Errorf("%w: text question %s", services.ErrQuestionNotTabulated, rowID))
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/survey/%s/crosstab?row=%s&col=%s", surveyID, rowID, columnID), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	})
+	t.Run("should return statusNotFound(404) when survey does not exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID, rowID, columnID := ksuid.New(), ksuid.New(), ksuid.New()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().GetCrossTab(models.DefaultWorkspace, surveyID, rowID, columnID, models.VersionScope{}).Return(nil, repositories.ErrNotFound)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/survey/%s/crosstab?row=%s&col=%s", surveyID, rowID, columnID), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+}
+
+func TestSurveyApp_ExportResponses(t *testing.T) {
+	t.Run("should stream the export in the requested format", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().ExportResponses(models.DefaultWorkspace, surveyID, gomock.Any()).DoAndReturn(func(_ models.WorkspaceID, id ksuid.KSUID, newWriter func() (export.Writer, error)) error {
+			writer, err := newWriter()
+			assert.NoError(t, err)
+			assert.NoError(t, writer.WriteHeader([]export.Column{{Key: "response_id", Title: "response_id"}}))
+			assert.NoError(t, writer.WriteRow([]interface{}{"1"}))
+			return writer.Close()
+		})
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/survey/%s/responses/export?format=ndjson", surveyID), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "application/x-ndjson", resp.Header().Get("Content-Type"))
+		assert.Equal(t, `{"response_id":"1"}`+"\n", resp.Body.String())
+	})
+	t.Run("should return statusUnprocessableEntity(422) for unknown formats", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/survey/%s/responses/export?format=pdf", ksuid.New()), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	})
+	t.Run("should return statusNotFound(404) when survey does not exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().ExportResponses(models.DefaultWorkspace, surveyID, gomock.Any()).Return(repositories.ErrNotFound)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/survey/%s/responses/export", surveyID), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+	t.Run("should return StatusInternalServerError(500) when nothing was streamed yet", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().ExportResponses(models.DefaultWorkspace, surveyID, gomock.Any()).DoAndReturn(func(_ models.WorkspaceID, id ksuid.KSUID, newWriter func() (export.Writer, error)) error {
+			_, err := newWriter()
+			assert.NoError(t, err)
+			return errors.New("something went wrong")
+		})
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/survey/%s/responses/export?format=xlsx", surveyID), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+		assert.Contains(t, resp.Header().Get("Content-Type"), "application/json")
+	})
+}
+
+func TestSurveyApp_Import(t *testing.T) {
+	t.Run("should import the body with the options in the query", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		options := models.ImportOptions{Format: models.ImportCSV, SurveyID: surveyID, DryRun: true, PreserveIDs: true}
+		report := &models.ImportReport{DryRun: true, ResponsesImported: 1}
+		mockService.EXPECT().Import(models.DefaultWorkspace, gomock.Any(), options).DoAndReturn(func(_ models.WorkspaceID, r io.Reader, _ models.ImportOptions) (*models.ImportReport, error) {
+			body, err := io.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, "response_id\n", string(body))
+			return report, nil
+		})
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		url := fmt.Sprintf("/import?format=csv&survey_id=%s&dry_run=true&preserve_ids=1", surveyID)
+		req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader("response_id\n"))
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var body struct {
+			Data models.ImportReport `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.Equal(t, *report, body.Data)
+	})
+	t.Run("should return statusUnprocessableEntity(422) for invalid options", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		for _, query := range []string{"format=xml", "format=csv&survey_id=1", "format=dump&dry_run=maybe"} {
+			req, _ := http.NewRequest(http.MethodPost, "/import?"+query, strings.NewReader(""))
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.Code, query)
+		}
+	})
+	t.Run("should return statusUnprocessableEntity(422) for malformed files", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().Import(models.DefaultWorkspace, gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("%w: unexpected EOF", services.ErrMalformedImport))
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodPost, "/import?format=dump", strings.NewReader("{"))
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	})
+	t.Run("should return statusNotFound(404) when survey does not exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().Import(models.DefaultWorkspace, gomock.Any(), gomock.Any()).Return(nil, repositories.ErrNotFound)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, nil)
+		router := surveyApp.SetupRoutes()
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/import?format=csv&survey_id=%s", ksuid.New()), strings.NewReader(""))
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
 }
 
 func TestSurveyApp_Dump(t *testing.T) {
@@ -568,11 +1282,11 @@ func TestSurveyApp_Dump(t *testing.T) {
 						Answers: []models.Answer{
 							{
 								QuestionID: qID1,
-								Answer:     true,
+								Answer:     models.BoolValue(true),
 							},
 							{
 								QuestionID: qID2,
-								Answer:     true,
+								Answer:     models.BoolValue(true),
 							},
 						},
 					},
@@ -597,10 +1311,124 @@ func TestSurveyApp_Dump(t *testing.T) {
 				},
 			},
 		}
-		mockSurveyService.EXPECT().Entries().Return(&dbEntry)
-		mockDB.EXPECT().Dump(&dbEntry).Return(nil)
-		surveyApp := NewSurveyApp(mockDB, mockSurveyService)
+		gomock.InOrder(
+			mockDB.EXPECT().Position().Return(uint64(7)),
+			mockSurveyService.EXPECT().Entries().Return(&dbEntry),
+			mockDB.EXPECT().Dump(&dbEntry).Return(nil),
+			mockDB.EXPECT().Truncate(uint64(7)).Return(nil),
+		)
+		surveyApp := NewSurveyApp(mockDB, mockSurveyService, nil, nil, nil)
 		err := surveyApp.Dump()
 		assert.NoError(t, err)
 	})
+	t.Run("should keep journal when dump fails", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockDB := db_mock.NewMockDB(ctrl)
+		mockSurveyService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		dbEntry := models.DBEntry{}
+		mockDB.EXPECT().Position().Return(uint64(7))
+		mockSurveyService.EXPECT().Entries().Return(&dbEntry)
+		mockDB.EXPECT().Dump(&dbEntry).Return(errors.New("disk full"))
+		surveyApp := NewSurveyApp(mockDB, mockSurveyService, nil, nil, nil)
+		err := surveyApp.Dump()
+		assert.Error(t, err)
+	})
+	t.Run("should dump the api keys along with the surveys", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockDB := db_mock.NewMockDB(ctrl)
+		mockSurveyService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockAPIKeyService := services_mock.NewMockAPIKeyServiceInterface(ctrl)
+		key := models.APIKey{ID: ksuid.New(), Name: "admin", Hash: "hash"}
+		mockDB.EXPECT().Position().Return(uint64(7))
+		mockSurveyService.EXPECT().Entries().Return(&models.DBEntry{})
+		mockAPIKeyService.EXPECT().Entries().Return(map[ksuid.KSUID]models.APIKey{key.ID: key})
+		mockDB.EXPECT().Dump(&models.DBEntry{APIKeys: map[ksuid.KSUID]models.APIKey{key.ID: key}}).Return(nil)
+		mockDB.EXPECT().Truncate(uint64(7)).Return(nil)
+		surveyApp := NewSurveyApp(mockDB, mockSurveyService, mockAPIKeyService, nil, nil)
+		assert.NoError(t, surveyApp.Dump())
+	})
+	t.Run("should dump the outbox of the mail service", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockDB := db_mock.NewMockDB(ctrl)
+		mockSurveyService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockMailService := services_mock.NewMockMailServiceInterface(ctrl)
+		message := models.OutboxMessage{ID: ksuid.New(), Kind: models.MailInvitation, To: "alice@example.com"}
+		mockDB.EXPECT().Position().Return(uint64(7))
+		mockSurveyService.EXPECT().Entries().Return(&models.DBEntry{})
+		mockMailService.EXPECT().Entries().Return(map[ksuid.KSUID]models.OutboxMessage{message.ID: message})
+		mockDB.EXPECT().Dump(&models.DBEntry{Outbox: map[ksuid.KSUID]models.OutboxMessage{message.ID: message}}).Return(nil)
+		mockDB.EXPECT().Truncate(uint64(7)).Return(nil)
+		surveyApp := NewSurveyApp(mockDB, mockSurveyService, nil, nil, nil)
+		surveyApp.SetMailService(mockMailService)
+		assert.NoError(t, surveyApp.Dump())
+	})
+	t.Run("should not lose journaled writes when dumps overlap", func(t *testing.T) {
+		dir := t.TempDir()
+		jsonDB, surveyService := loadJSON(t, filepath.Join(dir, "survey_app.json"), filepath.Join(dir, "survey_app.json.wal"))
+		slow := &slowDumpDB{JsonDB: jsonDB, dumping: make(chan struct{}), release: make(chan struct{})}
+		surveyApp := NewSurveyApp(slow, surveyService, nil, nil, nil)
+		create := func(name string) {
+			_, err := surveyService.CreateSurvey(models.DefaultWorkspace, &models.Survey{Name: name,
+				Questions: []models.Question{{Question: "is this a good product?"}}})
+			require.NoError(t, err)
+		}
+		create("before the first dump")
+		first := make(chan error, 1)
+		go func() { first <- surveyApp.Dump() }()
+		<-slow.dumping
+		create("while the first dump is written")
+		second := make(chan error, 1)
+		go func() { second <- surveyApp.Dump() }()
+		// the second dump may only start once the first one is done, give it the time to overtake the first one
+		time.Sleep(20 * time.Millisecond)
+		close(slow.release)
+		require.NoError(t, <-first)
+		require.NoError(t, <-second)
+		_, reloaded := loadJSON(t, filepath.Join(dir, "survey_app.json"), filepath.Join(dir, "survey_app.json.wal"))
+		stored, _, _ := reloaded.Entries().Flatten()
+		assert.Len(t, stored, 2)
+	})
+}
+
+// slowDumpDB holds the first dump back until release is closed, dumping is closed once it started
+type slowDumpDB struct {
+	*jsondb.JsonDB
+	dumping, release chan struct{}
+	once             sync.Once
+}
+
+func (s *slowDumpDB) Dump(contents interface{}) error {
+	first := false
+	s.once.Do(func() {
+		first = true
+		close(s.dumping)
+	})
+	if first {
+		<-s.release
+	}
+	return s.JsonDB.Dump(contents)
+}
+
+// loadJSON loads the survey service the json storage in fileName and journalFileName holds, as the app does when it starts
+func loadJSON(t *testing.T, fileName, journalFileName string) (*jsondb.JsonDB, *surveyservice.SurveyService) {
+	jsonDB, err := jsondb.NewJsonDB(fileName, journalFileName, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := models.DBEntry{}
+	if err = jsonDB.Load(&entry); err != nil {
+		t.Fatal(err)
+	}
+	entry.Migrate()
+	surveys, versions, responses := entry.Flatten()
+	responseRepo := responserepo.NewResponseRepo(responses, jsonDB)
+	surveyRepo := surveyrepo.NewSurveyRepo(surveys, versions, jsonDB).WithResponses(responseRepo)
+	if err = repositories.Replay(jsonDB, surveyRepo, responseRepo); err != nil {
+		t.Fatal(err)
+	}
+	return jsonDB, surveyservice.NewSurveyService(3, surveyRepo, responseRepo, ksuidgenerator.NewKSUIDGenerator(),
+		actualtimegenerator.NewActualTimeGenerator())
 }
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/segmentio/ksuid"
+	"log"
+	"net/http"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/services"
+)
+
+type inviteRequest struct {
+	Respondents []models.Respondent `json:"respondents"`
+}
+
+// openedInvitation is the invitation opened by a respondent along with the survey to answer
+type openedInvitation struct {
+	Invitation models.InvitationDetails `json:"invitation"`
+	Survey     *models.SurveyDetails    `json:"survey"`
+}
+
+// Invite invites the respondents in the body to the survey and returns their invitations along with the tokens
+// respondents who were already invited get their invitation back, new invitations are emailed when mailing is set up
+func (a *SurveyApp) Invite(c *gin.Context) {
+	id, ok := invitedSurveyID(c)
+	if !ok {
+		return
+	}
+	var request inviteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Println("error while reading invitation body", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "malformed body", ApiVersion: ApiVersion})
+		return
+	}
+	invitations, err := a.invitationService.Invite(workspace(c), id, request.Respondents)
+	if err == repositories.ErrNotFound {
+		log.Println("survey not found while inviting respondents", id.String())
+		c.JSONP(http.StatusNotFound, Response{Message: "error while inviting respondents " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if errors.Is(err, services.ErrInvalidRespondent) {
+		log.Println("invalid respondents", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err == services.ErrSurveyArchived {
+		log.Println("survey archived while inviting respondents", id.String())
+		c.JSONP(http.StatusConflict, Response{Message: "error while inviting respondents " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
+		log.Println("error while inviting respondents", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while inviting respondents " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	if a.mailService != nil {
+		// invitations which were already emailed are skipped, so a failed request is safe to send again
+		if err = a.mailService.QueueInvitations(workspace(c), invitations); err != nil {
+			log.Println("error while queueing invitation emails", err)
+			c.JSONP(http.StatusInternalServerError, Response{Message: "error while emailing invitations " + err.Error(), ApiVersion: ApiVersion})
+			return
+		}
+	}
+	c.JSONP(http.StatusCreated, Response{Message: "respondents invited", Data: invitations, ApiVersion: ApiVersion})
+}
+
+// GetInvitations lists the invitations to the survey along with their respondents and tokens
+func (a *SurveyApp) GetInvitations(c *gin.Context) {
+	id, ok := invitedSurveyID(c)
+	if !ok {
+		return
+	}
+	invitations, err := a.invitationService.GetInvitations(workspace(c), id)
+	if err == repositories.ErrNotFound {
+		log.Println("survey not found while getting invitations", id.String())
+		c.JSONP(http.StatusNotFound, Response{Message: "error while getting invitations " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
+		log.Println("error while getting invitations", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while getting invitations " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	c.JSONP(http.StatusOK, Response{Message: "invitations", Data: invitations, ApiVersion: ApiVersion})
+}
+
+// GetInvitationSummary counts the invitations to the survey which were sent, started and completed
+func (a *SurveyApp) GetInvitationSummary(c *gin.Context) {
+	id, ok := invitedSurveyID(c)
+	if !ok {
+		return
+	}
+	summary, err := a.invitationService.Summary(workspace(c), id)
+	if err == repositories.ErrNotFound {
+		log.Println("survey not found while summarising invitations", id.String())
+		c.JSONP(http.StatusNotFound, Response{Message: "error while summarising invitations " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
+		log.Println("error while summarising invitations", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while summarising invitations " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	c.JSONP(http.StatusOK, Response{Message: "invitation summary", Data: summary, ApiVersion: ApiVersion})
+}
+
+// OpenInvitation marks the invitation of the token as started and returns it along with the survey to answer
+func (a *SurveyApp) OpenInvitation(c *gin.Context) {
+	opened, err := a.invitationService.Open(workspace(c), c.Param("token"))
+	if errors.Is(err, services.ErrInvalidInvitation) {
+		log.Println("invalid invitation token", err)
+		c.JSONP(http.StatusForbidden, Response{Message: err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err == repositories.ErrInvitationCompleted {
+		log.Println("invitation already answered")
+		c.JSONP(http.StatusConflict, Response{Message: err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
+		log.Println("error while opening invitation", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while opening invitation " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	survey, err := a.surveys(c).GetSurveyDetails(workspace(c), opened.SurveyID)
+	if forbidden(c, err) {
+		return
+	}
+	if err == repositories.ErrNotFound {
+		log.Println("survey not found while opening invitation", opened.SurveyID.String())
+		c.JSONP(http.StatusNotFound, Response{Message: "error while opening invitation " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
+		log.Println("error while opening invitation", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while opening invitation " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	c.JSONP(http.StatusOK, Response{Message: "invitation", Data: openedInvitation{Invitation: *opened, Survey: survey}, ApiVersion: ApiVersion})
+}
+
+// invitedSurveyID parses the id param, requests with an invalid id are answered with 422
+func invitedSurveyID(c *gin.Context) (ksuid.KSUID, bool) {
+	id, err := ksuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Println("error while parsing surveyID", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid survey id", ApiVersion: ApiVersion})
+		return ksuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,213 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/services"
+	"survey-platform/internal/services/services_mock"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// invitationsApp returns an app without authentication inviting respondents with the returned service
+func invitationsApp(ctrl *gomock.Controller) (*services_mock.MockSurveyServiceInterface, *services_mock.MockInvitationServiceInterface, http.Handler) {
+	mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+	mockInvitationService := services_mock.NewMockInvitationServiceInterface(ctrl)
+	return mockService, mockInvitationService, NewSurveyApp(nil, mockService, nil, nil, mockInvitationService).SetupRoutes()
+}
+
+func TestSurveyApp_Invite(t *testing.T) {
+	t.Run("should return statusCreated(201) with the invitations and their tokens", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		_, mockInvitationService, router := invitationsApp(ctrl)
+		surveyID := ksuid.New()
+		respondents := []models.Respondent{{Email: "alice@example.com", Name: "Alice"}}
+		invitation := models.InvitationDetails{
+			Invitation: models.Invitation{ID: ksuid.New(), SurveyID: surveyID},
+			Respondent: respondents[0],
+			Token:      "signed",
+		}
+		mockInvitationService.EXPECT().Invite(models.DefaultWorkspace, surveyID, respondents).Return([]models.InvitationDetails{invitation}, nil)
+		resp := httptest.NewRecorder()
+		body := []byte(`{"respondents": [{"email": "alice@example.com", "name": "Alice"}]}`)
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/survey/"+surveyID.String()+"/invitations", bytes.NewReader(body)))
+		assert.Equal(t, http.StatusCreated, resp.Code)
+		assert.Contains(t, resp.Body.String(), invitation.ID.String())
+		assert.Contains(t, resp.Body.String(), `"token":"signed"`)
+	})
+	t.Run("should answer invalid requests with 422, missing surveys with 404 and archived surveys with 409", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		_, mockInvitationService, router := invitationsApp(ctrl)
+		invalid, missing, archived := ksuid.New(), ksuid.New(), ksuid.New()
+		mockInvitationService.EXPECT().Invite(models.DefaultWorkspace, invalid, gomock.Any()).
+			Return(nil, fmt.Errorf("%w: \"alice\" is not an email", services.ErrInvalidRespondent))
+		mockInvitationService.EXPECT().Invite(models.DefaultWorkspace, missing, gomock.Any()).Return(nil, repositories.ErrNotFound)
+		mockInvitationService.EXPECT().Invite(models.DefaultWorkspace, archived, gomock.Any()).Return(nil, services.ErrSurveyArchived)
+		body := `{"respondents": [{"email": "alice"}]}`
+		for _, tc := range []struct {
+			path   string
+			body   string
+			status int
+		}{
+			{"/survey/" + invalid.String() + "/invitations", body, http.StatusUnprocessableEntity},
+			{"/survey/" + invalid.String() + "/invitations", "hello", http.StatusUnprocessableEntity},
+			{"/survey/invalid/invitations", body, http.StatusUnprocessableEntity},
+			{"/survey/" + missing.String() + "/invitations", body, http.StatusNotFound},
+			{"/survey/" + archived.String() + "/invitations", body, http.StatusConflict},
+		} {
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, tc.path, bytes.NewReader([]byte(tc.body))))
+			assert.Equal(t, tc.status, resp.Code, tc.path)
+		}
+	})
+}
+
+func TestSurveyApp_Invite_Mail(t *testing.T) {
+	surveyID := ksuid.New()
+	invitations := []models.InvitationDetails{{Invitation: models.Invitation{ID: ksuid.New(), SurveyID: surveyID}, Token: "signed"}}
+	body := `{"respondents": [{"email": "alice@example.com"}]}`
+	t.Run("should queue the invitation emails", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockInvitationService := services_mock.NewMockInvitationServiceInterface(ctrl)
+		mockMailService := services_mock.NewMockMailServiceInterface(ctrl)
+		mockInvitationService.EXPECT().Invite(models.DefaultWorkspace, surveyID, gomock.Any()).Return(invitations, nil)
+		mockMailService.EXPECT().QueueInvitations(models.DefaultWorkspace, invitations).Return(nil)
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, mockInvitationService)
+		surveyApp.SetMailService(mockMailService)
+		resp := httptest.NewRecorder()
+		surveyApp.SetupRoutes().ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/survey/"+surveyID.String()+"/invitations", bytes.NewReader([]byte(body))))
+		assert.Equal(t, http.StatusCreated, resp.Code)
+	})
+	t.Run("should return statusInternalServerError(500) when the emails cannot be queued", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockInvitationService := services_mock.NewMockInvitationServiceInterface(ctrl)
+		mockMailService := services_mock.NewMockMailServiceInterface(ctrl)
+		mockInvitationService.EXPECT().Invite(models.DefaultWorkspace, surveyID, gomock.Any()).Return(invitations, nil)
+		mockMailService.EXPECT().QueueInvitations(models.DefaultWorkspace, invitations).Return(errors.New("disk full"))
+		surveyApp := NewSurveyApp(nil, mockService, nil, nil, mockInvitationService)
+		surveyApp.SetMailService(mockMailService)
+		resp := httptest.NewRecorder()
+		surveyApp.SetupRoutes().ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/survey/"+surveyID.String()+"/invitations", bytes.NewReader([]byte(body))))
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	})
+}
+
+func TestSurveyApp_GetInvitationSummary(t *testing.T) {
+	t.Run("should return statusOK(200) with the counts", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		_, mockInvitationService, router := invitationsApp(ctrl)
+		surveyID := ksuid.New()
+		mockInvitationService.EXPECT().Summary(models.DefaultWorkspace, surveyID).
+			Return(&models.InvitationSummary{Invited: 3, Started: 2, Completed: 1}, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/survey/"+surveyID.String()+"/invitations/summary", nil))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"data":{"invited":3,"started":2,"completed":1}`)
+	})
+}
+
+func TestSurveyApp_OpenInvitation(t *testing.T) {
+	t.Run("should return statusOK(200) with the invitation and the survey to answer", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService, mockInvitationService, router := invitationsApp(ctrl)
+		survey := models.Survey{ID: ksuid.New(), Name: "invited survey"}
+		token := survey.ID.String() + "." + ksuid.New().String() + ".signature"
+		opened := &models.InvitationDetails{Invitation: models.Invitation{ID: ksuid.New(), SurveyID: survey.ID}, Token: token}
+		mockInvitationService.EXPECT().Open(models.DefaultWorkspace, token).Return(opened, nil)
+		mockService.EXPECT().GetSurveyDetails(models.DefaultWorkspace, survey.ID).Return(&models.SurveyDetails{Survey: survey}, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/invitation/"+token, nil))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), opened.ID.String())
+		assert.Contains(t, resp.Body.String(), "invited survey")
+	})
+	t.Run("should answer invalid tokens with 403 and answered invitations with 409", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		_, mockInvitationService, router := invitationsApp(ctrl)
+		mockInvitationService.EXPECT().Open(models.DefaultWorkspace, "forged").Return(nil, services.ErrInvalidInvitation)
+		mockInvitationService.EXPECT().Open(models.DefaultWorkspace, "answered").Return(nil, repositories.ErrInvitationCompleted)
+		for token, status := range map[string]int{"forged": http.StatusForbidden, "answered": http.StatusConflict} {
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/invitation/"+token, nil))
+			assert.Equal(t, status, resp.Code, token)
+		}
+	})
+}
+
+func TestSurveyApp_SaveResponse_Invitation(t *testing.T) {
+	surveyID, invitationID, respondentID := ksuid.New(), ksuid.New(), ksuid.New()
+	invitation := &models.Invitation{ID: invitationID, SurveyID: surveyID, RespondentID: respondentID}
+	body := func(token string) []byte {
+		marshalled, _ := json.Marshal(map[string]interface{}{
+			"survey_id":     surveyID,
+			"invitation_id": ksuid.New(),
+			"answers":       []models.Answer{},
+			"token":         token,
+		})
+		return marshalled
+	}
+	t.Run("should save the response as answering the invitation of the token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService, mockInvitationService, router := invitationsApp(ctrl)
+		mockInvitationService.EXPECT().Verify(models.DefaultWorkspace, surveyID, "signed").Return(invitation, nil)
+		expected := models.Response{SurveyID: surveyID, InvitationID: &invitationID, RespondentID: &respondentID, Answers: []models.Answer{}}
+		saved := expected
+		saved.ID = ksuid.New()
+		mockService.EXPECT().SaveResponse(models.DefaultWorkspace, expected).Return(&saved, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/response/", bytes.NewReader(body("signed"))))
+		assert.Equal(t, http.StatusCreated, resp.Code)
+		assert.Contains(t, resp.Body.String(), respondentID.String())
+	})
+	t.Run("should drop the invitation sent without a token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService, _, router := invitationsApp(ctrl)
+		anonymous := models.Response{SurveyID: surveyID, Answers: []models.Answer{}}
+		mockService.EXPECT().SaveResponse(models.DefaultWorkspace, anonymous).Return(&anonymous, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/response/", bytes.NewReader(body(""))))
+		assert.Equal(t, http.StatusCreated, resp.Code)
+	})
+	t.Run("should answer invalid tokens with 403 and answered invitations with 409", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService, mockInvitationService, router := invitationsApp(ctrl)
+		mockInvitationService.EXPECT().Verify(models.DefaultWorkspace, surveyID, "forged").Return(nil, services.ErrInvalidInvitation)
+		mockInvitationService.EXPECT().Verify(models.DefaultWorkspace, surveyID, "answered").Return(nil, repositories.ErrInvitationCompleted)
+		mockInvitationService.EXPECT().Verify(models.DefaultWorkspace, surveyID, "racing").Return(invitation, nil)
+		mockService.EXPECT().SaveResponse(models.DefaultWorkspace, gomock.Any()).Return(nil, repositories.ErrInvitationCompleted)
+		for token, status := range map[string]int{"forged": http.StatusForbidden, "answered": http.StatusConflict, "racing": http.StatusConflict} {
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/response/", bytes.NewReader(body(token))))
+			assert.Equal(t, status, resp.Code, token)
+		}
+	})
+	t.Run("should reject every token when invitations are disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		router := NewSurveyApp(nil, services_mock.NewMockSurveyServiceInterface(ctrl), nil, nil, nil).SetupRoutes()
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/response/", bytes.NewReader(body("signed"))))
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+}
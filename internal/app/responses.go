@@ -0,0 +1,113 @@
+package app
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/segmentio/ksuid"
+	"log"
+	"net/http"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/services"
+)
+
+// responseAnswers is the body editing a response
+type responseAnswers struct {
+	Answers []models.Answer `json:"answers"`
+}
+
+// GetResponse returns the response saved with the invitation token of the request, along with its edit history
+// and the time until which it can be edited or withdrawn
+func (a *SurveyApp) GetResponse(c *gin.Context) {
+	id, invitation, ok := a.respondentRequest(c)
+	if !ok {
+		return
+	}
+	response, err := a.surveys(c).GetResponse(workspace(c), invitation.ID, id)
+	if err != nil {
+		respondentError(c, "reading", err)
+		return
+	}
+	c.JSONP(http.StatusOK, Response{Message: "response", Data: response, ApiVersion: ApiVersion})
+}
+
+// UpdateResponse replaces the answers of the response saved with the invitation token of the request
+func (a *SurveyApp) UpdateResponse(c *gin.Context) {
+	id, invitation, ok := a.respondentRequest(c)
+	if !ok {
+		return
+	}
+	var request responseAnswers
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Println("error while reading response answers", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "malformed body", ApiVersion: ApiVersion})
+		return
+	}
+	response, err := a.surveys(c).UpdateResponse(workspace(c), invitation.ID, id, request.Answers)
+	if err != nil {
+		respondentError(c, "editing", err)
+		return
+	}
+	c.JSONP(http.StatusOK, Response{Message: "response updated", Data: response, ApiVersion: ApiVersion})
+}
+
+// DeleteResponse withdraws the response saved with the invitation token of the request
+func (a *SurveyApp) DeleteResponse(c *gin.Context) {
+	id, invitation, ok := a.respondentRequest(c)
+	if !ok {
+		return
+	}
+	if err := a.surveys(c).DeleteResponse(workspace(c), invitation.ID, id); err != nil {
+		respondentError(c, "withdrawing", err)
+		return
+	}
+	c.JSONP(http.StatusNoContent, Response{Message: "response withdrawn", ApiVersion: ApiVersion})
+}
+
+// respondentRequest parses the id param and returns the invitation of the token in the Invitation-Token header
+// requests with an invalid id are answered with 422 and requests without a valid token with 403
+func (a *SurveyApp) respondentRequest(c *gin.Context) (ksuid.KSUID, *models.Invitation, bool) {
+	id, err := ksuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Println("error while parsing responseID", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid response id", ApiVersion: ApiVersion})
+		return ksuid.Nil, nil, false
+	}
+	token := c.GetHeader(InvitationTokenHeader)
+	if a.invitationService == nil || token == "" {
+		log.Println("missing invitation token for response", id.String())
+		c.JSONP(http.StatusForbidden, Response{Message: services.ErrInvalidInvitation.Error(), ApiVersion: ApiVersion})
+		return ksuid.Nil, nil, false
+	}
+	invitation, err := a.invitationService.Authenticate(workspace(c), token)
+	if err != nil {
+		respondentError(c, "authenticating", err)
+		return ksuid.Nil, nil, false
+	}
+	return id, invitation, true
+}
+
+// respondentError answers a request of a respondent on their response which failed with err, action tells what was being done
+func respondentError(c *gin.Context, action string, err error) {
+	var validationErr *services.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		log.Println("invalid answers while "+action+" response", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid response", Data: validationErr.Violations, ApiVersion: ApiVersion})
+	case err == repositories.ErrNotFound:
+		log.Println("response not found while " + action + " response")
+		c.JSONP(http.StatusNotFound, Response{Message: "error while " + action + " response " + err.Error(), ApiVersion: ApiVersion})
+	case errors.Is(err, services.ErrForbidden):
+		log.Println("access denied while "+action+" response", err)
+		c.JSONP(http.StatusForbidden, Response{Message: err.Error(), ApiVersion: ApiVersion})
+	case errors.Is(err, services.ErrInvalidInvitation):
+		log.Println("invalid invitation token while "+action+" response", err)
+		c.JSONP(http.StatusForbidden, Response{Message: err.Error(), ApiVersion: ApiVersion})
+	case err == services.ErrEditWindowClosed || err == services.ErrSurveyNotPublished || err == services.ErrSurveyClosed:
+		log.Println("response cannot be changed while "+action+" it", err)
+		c.JSONP(http.StatusConflict, Response{Message: "error while " + action + " response " + err.Error(), ApiVersion: ApiVersion})
+	default:
+		log.Println("error while "+action+" response", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while " + action + " response " + err.Error(), ApiVersion: ApiVersion})
+	}
+}
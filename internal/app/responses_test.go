@@ -0,0 +1,114 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/services"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func respondentRequest(method, path, token, body string) *http.Request {
+	request := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+	if token != "" {
+		request.Header.Set(InvitationTokenHeader, token)
+	}
+	return request
+}
+
+func TestSurveyApp_RespondentResponses(t *testing.T) {
+	invitation := &models.Invitation{ID: ksuid.New(), SurveyID: ksuid.New(), RespondentID: ksuid.New()}
+	questionID := ksuid.New()
+	t.Run("should read, edit and withdraw the response of the invitation token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService, mockInvitationService, router := invitationsApp(ctrl)
+		editableUntil := time.Now().Add(time.Hour)
+		response := &models.ResponseDetails{
+			Response:      models.Response{ID: ksuid.New(), SurveyID: invitation.SurveyID, InvitationID: &invitation.ID},
+			EditableUntil: &editableUntil,
+		}
+		answers := []models.Answer{{QuestionID: questionID, Answer: models.BoolValue(true)}}
+		mockInvitationService.EXPECT().Authenticate(models.DefaultWorkspace, "signed").Return(invitation, nil).Times(3)
+		mockService.EXPECT().GetResponse(models.DefaultWorkspace, invitation.ID, response.ID).Return(response, nil)
+		mockService.EXPECT().UpdateResponse(models.DefaultWorkspace, invitation.ID, response.ID, answers).Return(response, nil)
+		mockService.EXPECT().DeleteResponse(models.DefaultWorkspace, invitation.ID, response.ID).Return(nil)
+		body := `{"answers": [{"question_id": "` + questionID.String() + `", "answer": true}]}`
+		for _, tc := range []struct {
+			method string
+			body   string
+			status int
+		}{
+			{http.MethodGet, "", http.StatusOK},
+			{http.MethodPut, body, http.StatusOK},
+			{http.MethodDelete, "", http.StatusNoContent},
+		} {
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, respondentRequest(tc.method, "/response/"+response.ID.String(), "signed", tc.body))
+			assert.Equal(t, tc.status, resp.Code, tc.method)
+			if tc.status == http.StatusOK {
+				assert.Contains(t, resp.Body.String(), response.ID.String())
+				assert.Contains(t, resp.Body.String(), `"editable_until"`)
+			}
+		}
+	})
+	t.Run("should answer missing or invalid tokens with 403 and invalid requests with 422", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService, mockInvitationService, router := invitationsApp(ctrl)
+		id := ksuid.New()
+		mockInvitationService.EXPECT().Authenticate(models.DefaultWorkspace, "forged").Return(nil, services.ErrInvalidInvitation)
+		mockInvitationService.EXPECT().Authenticate(models.DefaultWorkspace, "signed").Return(invitation, nil).Times(2)
+		mockService.EXPECT().UpdateResponse(models.DefaultWorkspace, invitation.ID, id, gomock.Any()).
+			Return(nil, &services.ValidationError{Violations: []services.Violation{{QuestionID: questionID, Message: "question is required"}}})
+		for _, tc := range []struct {
+			method string
+			path   string
+			token  string
+			body   string
+			status int
+		}{
+			{http.MethodGet, "/response/" + id.String(), "", "", http.StatusForbidden},
+			{http.MethodGet, "/response/" + id.String(), "forged", "", http.StatusForbidden},
+			{http.MethodDelete, "/response/invalid", "signed", "", http.StatusUnprocessableEntity},
+			{http.MethodPut, "/response/" + id.String(), "signed", "hello", http.StatusUnprocessableEntity},
+			{http.MethodPut, "/response/" + id.String(), "signed", `{"answers": []}`, http.StatusUnprocessableEntity},
+		} {
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, respondentRequest(tc.method, tc.path, tc.token, tc.body))
+			assert.Equal(t, tc.status, resp.Code, tc.method+" "+tc.path+" "+tc.token)
+		}
+	})
+	t.Run("should answer responses of other invitations with 404 and closed edit windows with 409", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService, mockInvitationService, router := invitationsApp(ctrl)
+		other, closed := ksuid.New(), ksuid.New()
+		mockInvitationService.EXPECT().Authenticate(models.DefaultWorkspace, "signed").Return(invitation, nil).Times(4)
+		mockService.EXPECT().GetResponse(models.DefaultWorkspace, invitation.ID, other).Return(nil, repositories.ErrNotFound)
+		mockService.EXPECT().DeleteResponse(models.DefaultWorkspace, invitation.ID, other).Return(repositories.ErrNotFound)
+		mockService.EXPECT().UpdateResponse(models.DefaultWorkspace, invitation.ID, closed, gomock.Any()).Return(nil, services.ErrEditWindowClosed)
+		mockService.EXPECT().DeleteResponse(models.DefaultWorkspace, invitation.ID, closed).Return(services.ErrSurveyClosed)
+		for _, tc := range []struct {
+			method string
+			id     ksuid.KSUID
+			status int
+		}{
+			{http.MethodGet, other, http.StatusNotFound},
+			{http.MethodDelete, other, http.StatusNotFound},
+			{http.MethodPut, closed, http.StatusConflict},
+			{http.MethodDelete, closed, http.StatusConflict},
+		} {
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, respondentRequest(tc.method, "/response/"+tc.id.String(), "signed", `{"answers": []}`))
+			assert.Equal(t, tc.status, resp.Code, tc.method)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/segmentio/ksuid"
+	"log"
+	"net/http"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/services"
+)
+
+type rolesRequest struct {
+	Roles []models.RoleAssignment `json:"roles"`
+}
+
+// CreateUser adds the user in the body to the workspace of the caller along with its roles
+func (a *SurveyApp) CreateUser(c *gin.Context) {
+	var user models.User
+	if err := c.ShouldBindJSON(&user); err != nil {
+		log.Println("error while reading user body", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "malformed body", ApiVersion: ApiVersion})
+		return
+	}
+	created, err := a.userService.CreateUser(workspace(c), user)
+	if errors.Is(err, services.ErrInvalidUser) {
+		log.Println("invalid user", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
+		log.Println("error while creating user", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while creating user " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	c.JSONP(http.StatusCreated, Response{Message: "user created", Data: created, ApiVersion: ApiVersion})
+}
+
+// GetUsers lists the users of the workspace of the caller
+func (a *SurveyApp) GetUsers(c *gin.Context) {
+	users, err := a.userService.GetUsers(workspace(c))
+	if err != nil {
+		log.Println("error while getting users", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while reading users " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	c.JSONP(http.StatusOK, Response{Message: "users", Data: users, ApiVersion: ApiVersion})
+}
+
+func (a *SurveyApp) GetUser(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+	user, err := a.userService.GetUser(workspace(c), id)
+	if err == repositories.ErrNotFound {
+		log.Println("user not found", id.String())
+		c.JSONP(http.StatusNotFound, Response{Message: "error while getting user " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
+		log.Println("error while getting user", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while getting user " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	c.JSONP(http.StatusOK, Response{Message: "user", Data: user, ApiVersion: ApiVersion})
+}
+
+// SetUserRoles replaces the roles of the user with the ones in the body
+func (a *SurveyApp) SetUserRoles(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+	var request rolesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Println("error while reading roles body", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "malformed body", ApiVersion: ApiVersion})
+		return
+	}
+	user, err := a.userService.SetRoles(workspace(c), id, request.Roles)
+	if err == repositories.ErrNotFound {
+		log.Println("user not found while setting roles", id.String())
+		c.JSONP(http.StatusNotFound, Response{Message: "error while setting roles " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if errors.Is(err, services.ErrInvalidUser) {
+		log.Println("invalid roles", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
+		log.Println("error while setting roles", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while setting roles " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	c.JSONP(http.StatusOK, Response{Message: "roles updated", Data: user, ApiVersion: ApiVersion})
+}
+
+// DeleteUser removes a user, the keys bound to the user are denied every request from then on
+func (a *SurveyApp) DeleteUser(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+	err := a.userService.DeleteUser(workspace(c), id)
+	if err == repositories.ErrNotFound {
+		log.Println("user not found while deleting user", id.String())
+		c.JSONP(http.StatusNotFound, Response{Message: "error while deleting user " + err.Error(), ApiVersion: ApiVersion})
+		return
+	} else if err != nil {
+		log.Println("error while deleting user", err)
+		c.JSONP(http.StatusInternalServerError, Response{Message: "error while deleting user " + err.Error(), ApiVersion: ApiVersion})
+		return
+	}
+	c.JSONP(http.StatusNoContent, Response{Message: "user deleted", ApiVersion: ApiVersion})
+}
+
+// userID parses the id param, requests with an invalid id are answered with 422
+func userID(c *gin.Context) (ksuid.KSUID, bool) {
+	id, err := ksuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Println("error while parsing user id", err)
+		c.JSONP(http.StatusUnprocessableEntity, Response{Message: "invalid user id", ApiVersion: ApiVersion})
+		return ksuid.Nil, false
+	}
+	return id, true
+}
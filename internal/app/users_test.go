@@ -0,0 +1,101 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/services"
+	"survey-platform/internal/services/services_mock"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// usersApp returns an app whose requests are authenticated as a key allowed to manage users
+func usersApp(ctrl *gomock.Controller) (*services_mock.MockUserServiceInterface, http.Handler) {
+	mockAPIKeyService := services_mock.NewMockAPIKeyServiceInterface(ctrl)
+	key := &models.APIKey{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, Scopes: []models.Scope{models.ScopeUsersManage}}
+	mockAPIKeyService.EXPECT().Authenticate("sp_admin").Return(key, nil).AnyTimes()
+	mockUserService := services_mock.NewMockUserServiceInterface(ctrl)
+	mockUserService.EXPECT().Authorize(key, nil, models.ScopeUsersManage).Return(nil).AnyTimes()
+	return mockUserService, NewSurveyApp(nil, nil, mockAPIKeyService, mockUserService, nil).SetupRoutes()
+}
+
+func TestSurveyApp_CreateUser(t *testing.T) {
+	t.Run("should return statusCreated(201) with the user", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockUserService, router := usersApp(ctrl)
+		user := models.User{Name: "alice", Roles: []models.RoleAssignment{{Role: models.RoleAnalyst}}}
+		created := user
+		created.ID = ksuid.New()
+		mockUserService.EXPECT().CreateUser(models.DefaultWorkspace, user).Return(&created, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, adminRequest(http.MethodPost, "/user/", []byte(`{"name": "alice", "roles": [{"role": "analyst"}]}`)))
+		assert.Equal(t, http.StatusCreated, resp.Code)
+		assert.Contains(t, resp.Body.String(), created.ID.String())
+	})
+	t.Run("should return statusUnprocessableEntity(422) for malformed bodies and invalid users", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockUserService, router := usersApp(ctrl)
+		mockUserService.EXPECT().CreateUser(models.DefaultWorkspace, gomock.Any()).
+			Return(nil, fmt.Errorf("%w: unknown role \"admin\"", services.ErrInvalidUser))
+		for _, body := range []string{`{"name": "alice", "roles": [{"role": "admin"}]}`, `hello`} {
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, adminRequest(http.MethodPost, "/user/", []byte(body)))
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.Code, body)
+		}
+	})
+}
+
+func TestSurveyApp_SetUserRoles(t *testing.T) {
+	t.Run("should return statusOK(200) with the user holding the new roles", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockUserService, router := usersApp(ctrl)
+		id, surveyID := ksuid.New(), ksuid.New()
+		roles := []models.RoleAssignment{{Role: models.RoleEditor, SurveyID: &surveyID}}
+		mockUserService.EXPECT().SetRoles(models.DefaultWorkspace, id, roles).Return(&models.User{ID: id, Roles: roles}, nil)
+		resp := httptest.NewRecorder()
+		body := `{"roles": [{"role": "editor", "survey_id": "` + surveyID.String() + `"}]}`
+		router.ServeHTTP(resp, adminRequest(http.MethodPut, "/user/"+id.String()+"/roles", []byte(body)))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), surveyID.String())
+	})
+	t.Run("should return statusNotFound(404) for unknown users", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockUserService, router := usersApp(ctrl)
+		id := ksuid.New()
+		mockUserService.EXPECT().SetRoles(models.DefaultWorkspace, id, []models.RoleAssignment{}).Return(nil, repositories.ErrNotFound)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, adminRequest(http.MethodPut, "/user/"+id.String()+"/roles", []byte(`{"roles": []}`)))
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+}
+
+func TestSurveyApp_DeleteUser(t *testing.T) {
+	t.Run("should return statusNoContent(204) once the user is deleted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockUserService, router := usersApp(ctrl)
+		id := ksuid.New()
+		mockUserService.EXPECT().DeleteUser(models.DefaultWorkspace, id).Return(nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, adminRequest(http.MethodDelete, "/user/"+id.String(), nil))
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+	})
+	t.Run("should return statusUnprocessableEntity(422) when the id is invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		_, router := usersApp(ctrl)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, adminRequest(http.MethodDelete, "/user/xxx", nil))
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	})
+}
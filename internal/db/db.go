@@ -2,7 +2,19 @@ package db
 
 //go:generate mockgen -source=db.go -destination=./db_mock/db_mock.go -package=db_mock
 
+// DB persists a snapshot of the entries along with a journal of the mutations made after it
 type DB interface {
 	Load(target interface{}) error
 	Dump(contents interface{}) error
+	Journal
+}
+
+// Journal is a write-ahead log of mutations, replayed over the last snapshot on load
+// every appended operation gets a position, Truncate drops the operations up to a position
+// once a snapshot covering them has been dumped
+type Journal interface {
+	Append(op string, payload interface{}) error
+	Replay(apply func(op string, payload []byte) error) error
+	Position() uint64
+	Truncate(upTo uint64) error
 }
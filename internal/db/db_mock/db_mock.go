@@ -33,6 +33,20 @@ func (m *MockDB) EXPECT() *MockDBMockRecorder {
 	return m.recorder
 }
 
+// Append mocks base method.
+func (m *MockDB) Append(op string, payload interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Append", op, payload)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Append indicates an expected call of Append.
+func (mr *MockDBMockRecorder) Append(op, payload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Append", reflect.TypeOf((*MockDB)(nil).Append), op, payload)
+}
+
 // Dump mocks base method.
 func (m *MockDB) Dump(contents interface{}) error {
 	m.ctrl.T.Helper()
@@ -60,3 +74,124 @@ func (mr *MockDBMockRecorder) Load(target interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Load", reflect.TypeOf((*MockDB)(nil).Load), target)
 }
+
+// Position mocks base method.
+func (m *MockDB) Position() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Position")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// Position indicates an expected call of Position.
+func (mr *MockDBMockRecorder) Position() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Position", reflect.TypeOf((*MockDB)(nil).Position))
+}
+
+// Replay mocks base method.
+func (m *MockDB) Replay(apply func(string, []byte) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replay", apply)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Replay indicates an expected call of Replay.
+func (mr *MockDBMockRecorder) Replay(apply interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replay", reflect.TypeOf((*MockDB)(nil).Replay), apply)
+}
+
+// Truncate mocks base method.
+func (m *MockDB) Truncate(upTo uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Truncate", upTo)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Truncate indicates an expected call of Truncate.
+func (mr *MockDBMockRecorder) Truncate(upTo interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Truncate", reflect.TypeOf((*MockDB)(nil).Truncate), upTo)
+}
+
+// MockJournal is a mock of Journal interface.
+type MockJournal struct {
+	ctrl     *gomock.Controller
+	recorder *MockJournalMockRecorder
+}
+
+// MockJournalMockRecorder is the mock recorder for MockJournal.
+type MockJournalMockRecorder struct {
+	mock *MockJournal
+}
+
+// NewMockJournal creates a new mock instance.
+func NewMockJournal(ctrl *gomock.Controller) *MockJournal {
+	mock := &MockJournal{ctrl: ctrl}
+	mock.recorder = &MockJournalMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockJournal) EXPECT() *MockJournalMockRecorder {
+	return m.recorder
+}
+
+// Append mocks base method.
+func (m *MockJournal) Append(op string, payload interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Append", op, payload)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Append indicates an expected call of Append.
+func (mr *MockJournalMockRecorder) Append(op, payload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Append", reflect.TypeOf((*MockJournal)(nil).Append), op, payload)
+}
+
+// Position mocks base method.
+func (m *MockJournal) Position() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Position")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// Position indicates an expected call of Position.
+func (mr *MockJournalMockRecorder) Position() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Position", reflect.TypeOf((*MockJournal)(nil).Position))
+}
+
+// Replay mocks base method.
+func (m *MockJournal) Replay(apply func(string, []byte) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replay", apply)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Replay indicates an expected call of Replay.
+func (mr *MockJournalMockRecorder) Replay(apply interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replay", reflect.TypeOf((*MockJournal)(nil).Replay), apply)
+}
+
+// Truncate mocks base method.
+func (m *MockJournal) Truncate(upTo uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Truncate", upTo)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Truncate indicates an expected call of Truncate.
+func (mr *MockJournalMockRecorder) Truncate(upTo interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Truncate", reflect.TypeOf((*MockJournal)(nil).Truncate), upTo)
+}
@@ -1,48 +1,135 @@
 package jsondb
 
 import (
-	"bytes"
 	"encoding/json"
-	"log"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
 )
 
+const snapshotTimeFormat = "20060102T150405.000000000Z"
+
+// JsonDB keeps a json snapshot of the entries in one file and a journal of the mutations made after it in another
+// snapshots are written to a temporary file and renamed over the previous one, so the file is never left half written
+// the previous keepSnapshots snapshots are kept next to the file for rollback
 type JsonDB struct {
-	file *os.File
+	mu            *sync.Mutex
+	fileName      string
+	keepSnapshots int
+	journal       *WAL
 }
 
-func NewJsonDB(fileName string) (*JsonDB, error) {
-	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+func NewJsonDB(fileName string, journalFileName string, keepSnapshots int) (*JsonDB, error) {
+	journal, err := NewWAL(journalFileName)
 	if err != nil {
 		return nil, err
 	}
 	return &JsonDB{
-		file: file,
+		mu:            &sync.Mutex{},
+		fileName:      fileName,
+		keepSnapshots: keepSnapshots,
+		journal:       journal,
 	}, nil
 }
+
 func (j *JsonDB) Load(target interface{}) error {
-	buf := new(bytes.Buffer)
-	n, err := buf.ReadFrom(j.file)
+	data, err := ioutil.ReadFile(j.fileName)
+	if os.IsNotExist(err) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
-	if n == 0 {
+	if len(data) == 0 {
 		return nil
 	}
-	err = json.Unmarshal(buf.Bytes(), target)
-	return err
+	return json.Unmarshal(data, target)
 }
 
+// Dump atomically replaces the snapshot with contents, it can be called any number of times
 func (j *JsonDB) Dump(contents interface{}) error {
-	defer j.file.Close()
-	err := j.file.Truncate(0)
+	contentsJSON, err := json.Marshal(&contents)
 	if err != nil {
-		log.Println("error while truncating file", err)
+		return err
 	}
-	contentsJSON, err := json.Marshal(&contents)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	tmpName := j.fileName + ".tmp"
+	if err = writeFileSync(tmpName, contentsJSON); err != nil {
+		return err
+	}
+	if err = j.keepPrevious(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpName, j.fileName); err != nil {
+		return err
+	}
+	if err = syncDir(filepath.Dir(j.fileName)); err != nil {
+		return err
+	}
+	return j.pruneSnapshots()
+}
+
+// Snapshots returns the file names of the kept previous snapshots, oldest first
+func (j *JsonDB) Snapshots() ([]string, error) {
+	snapshots, err := filepath.Glob(j.fileName + ".snapshot-*")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(snapshots)
+	return snapshots, nil
+}
+
+func (j *JsonDB) Append(op string, payload interface{}) error {
+	return j.journal.Append(op, payload)
+}
+
+func (j *JsonDB) Replay(apply func(op string, payload []byte) error) error {
+	return j.journal.Replay(apply)
+}
+
+func (j *JsonDB) Position() uint64 {
+	return j.journal.Position()
+}
+
+func (j *JsonDB) Truncate(upTo uint64) error {
+	return j.journal.Truncate(upTo)
+}
+
+// keepPrevious links the current snapshot under a timestamped name before it gets replaced
+func (j *JsonDB) keepPrevious() error {
+	if j.keepSnapshots <= 0 {
+		return nil
+	}
+	if _, err := os.Stat(j.fileName); os.IsNotExist(err) {
+		return nil
+	}
+	snapshotName := j.fileName + ".snapshot-" + time.Now().UTC().Format(snapshotTimeFormat)
+	return os.Link(j.fileName, snapshotName)
+}
+
+func (j *JsonDB) pruneSnapshots() error {
+	snapshots, err := j.Snapshots()
+	if err != nil {
+		return err
+	}
+	for len(snapshots) > j.keepSnapshots {
+		if err = os.Remove(snapshots[0]); err != nil {
+			return err
+		}
+		snapshots = snapshots[1:]
+	}
+	return nil
+}
+
+func syncDir(dirName string) error {
+	dir, err := os.Open(dirName)
 	if err != nil {
 		return err
 	}
-	_, err = j.file.Write(contentsJSON)
-	return err
+	defer dir.Close()
+	return dir.Sync()
 }
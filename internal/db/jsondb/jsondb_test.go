@@ -4,6 +4,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -12,20 +13,31 @@ type person struct {
 	Place string `json:"place"`
 }
 
+func journalFile(t *testing.T) string {
+	return filepath.Join(t.TempDir(), "survey_app.json.wal")
+}
+
 func TestNewJsonDB(t *testing.T) {
-	t.Run("should return jsondb with opened file", func(t *testing.T) {
+	t.Run("should return jsondb with opened journal", func(t *testing.T) {
 		fileName := "./../../../testdata/dump-0.json"
-		jsonDB, err := NewJsonDB(fileName)
+		jsonDB, err := NewJsonDB(fileName, journalFile(t), 3)
 		assert.NoError(t, err)
-		assert.NotNil(t, jsonDB.file)
-		jsonDB.file.Close()
+		assert.Equal(t, fileName, jsonDB.fileName)
+		assert.Equal(t, 3, jsonDB.keepSnapshots)
+		assert.NotNil(t, jsonDB.journal)
+	})
+	t.Run("should return error when journal cannot be opened", func(t *testing.T) {
+		fileName := "./../../../testdata/dump-0.json"
+		jsonDB, err := NewJsonDB(fileName, t.TempDir(), 0)
+		assert.Error(t, err)
+		assert.Nil(t, jsonDB)
 	})
 }
 
 func TestJsonDB_Load(t *testing.T) {
 	t.Run("should load entries successfully", func(t *testing.T) {
 		fileName := "./../../../testdata/dump-0.json"
-		jsonDB, err := NewJsonDB(fileName)
+		jsonDB, err := NewJsonDB(fileName, journalFile(t), 0)
 		assert.NoError(t, err)
 		var target map[string]person
 		err = jsonDB.Load(&target)
@@ -40,7 +52,7 @@ func TestJsonDB_Load(t *testing.T) {
 	})
 	t.Run("should fail with invalid input", func(t *testing.T) {
 		fileName := "./../../../testdata/dump-1.json"
-		jsonDB, err := NewJsonDB(fileName)
+		jsonDB, err := NewJsonDB(fileName, journalFile(t), 0)
 		assert.NoError(t, err)
 		var target map[string]person
 		err = jsonDB.Load(&target)
@@ -48,7 +60,7 @@ func TestJsonDB_Load(t *testing.T) {
 	})
 	t.Run("should load empty json without error", func(t *testing.T) {
 		fileName := "./../../../testdata/dump-2.json"
-		jsonDB, err := NewJsonDB(fileName)
+		jsonDB, err := NewJsonDB(fileName, journalFile(t), 0)
 		assert.NoError(t, err)
 		var target map[string]person
 		err = jsonDB.Load(&target)
@@ -57,18 +69,25 @@ func TestJsonDB_Load(t *testing.T) {
 	})
 	t.Run("should load empty file without error", func(t *testing.T) {
 		fileName := "./../../../testdata/dump-3.json"
-		jsonDB, err := NewJsonDB(fileName)
+		jsonDB, err := NewJsonDB(fileName, journalFile(t), 0)
 		assert.NoError(t, err)
 		var target map[string]person
 		err = jsonDB.Load(&target)
 		assert.NoError(t, err)
 		assert.Empty(t, target)
 	})
-	t.Run("should return error if file is closed", func(t *testing.T) {
-		fileName := "./../../../testdata/dump-0.json"
-		jsonDB, err := NewJsonDB(fileName)
+	t.Run("should load nothing when file does not exist", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "survey_app.json")
+		jsonDB, err := NewJsonDB(fileName, journalFile(t), 0)
+		assert.NoError(t, err)
+		var target map[string]person
+		err = jsonDB.Load(&target)
+		assert.NoError(t, err)
+		assert.Empty(t, target)
+	})
+	t.Run("should return error if file cannot be read", func(t *testing.T) {
+		jsonDB, err := NewJsonDB(t.TempDir(), journalFile(t), 0)
 		assert.NoError(t, err)
-		_ = jsonDB.file.Close()
 		var target map[string]person
 		err = jsonDB.Load(&target)
 		assert.Error(t, err)
@@ -78,7 +97,7 @@ func TestJsonDB_Load(t *testing.T) {
 func TestJsonDB_Dump(t *testing.T) {
 	t.Run("should dump entries successfully", func(t *testing.T) {
 		fileName := "./../../../testdata/dump-4.json"
-		jsonDB, err := NewJsonDB(fileName)
+		jsonDB, err := NewJsonDB(fileName, journalFile(t), 0)
 		assert.NoError(t, err)
 		entries := map[string]person{"jeffy": {Age: 25, Place: "India"}}
 		err = jsonDB.Dump(entries)
@@ -90,13 +109,62 @@ func TestJsonDB_Dump(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, `{"jeffy":{"age":25,"place":"India"}}`, string(data))
 	})
-	t.Run("should return error when truncate file fails", func(t *testing.T) {
-		fileName := "./../../../testdata/dump-4.json"
-		jsonDB, err := NewJsonDB(fileName)
+	t.Run("should return error when directory does not exist", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "missing", "survey_app.json")
+		jsonDB, err := NewJsonDB(fileName, journalFile(t), 0)
 		assert.NoError(t, err)
-		jsonDB.file.Close()
 		entries := map[string]person{"jeffy": {Age: 25, Place: "India"}}
 		err = jsonDB.Dump(entries)
 		assert.Error(t, err)
 	})
+	t.Run("should replace previous dump on every call without leaving temporary file", func(t *testing.T) {
+		dir := t.TempDir()
+		fileName := filepath.Join(dir, "survey_app.json")
+		jsonDB, err := NewJsonDB(fileName, journalFile(t), 0)
+		assert.NoError(t, err)
+		assert.NoError(t, jsonDB.Dump(map[string]person{"jeffy": {Age: 25, Place: "India"}}))
+		assert.NoError(t, jsonDB.Dump(map[string]person{"jeffy": {Age: 26, Place: "India"}}))
+		data, err := ioutil.ReadFile(fileName)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"jeffy":{"age":26,"place":"India"}}`, string(data))
+		files, err := ioutil.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+	})
+	t.Run("should keep only the configured number of previous snapshots", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "survey_app.json")
+		jsonDB, err := NewJsonDB(fileName, journalFile(t), 2)
+		assert.NoError(t, err)
+		for age := 1; age <= 4; age++ {
+			assert.NoError(t, jsonDB.Dump(map[string]person{"jeffy": {Age: age}}))
+		}
+		snapshots, err := jsonDB.Snapshots()
+		assert.NoError(t, err)
+		assert.Len(t, snapshots, 2)
+		oldest, err := ioutil.ReadFile(snapshots[0])
+		assert.NoError(t, err)
+		assert.Equal(t, `{"jeffy":{"age":2,"place":""}}`, string(oldest))
+		latest, err := ioutil.ReadFile(snapshots[1])
+		assert.NoError(t, err)
+		assert.Equal(t, `{"jeffy":{"age":3,"place":""}}`, string(latest))
+	})
+}
+
+func TestJsonDB_Journal(t *testing.T) {
+	t.Run("should delegate journal operations to the write-ahead log", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "survey_app.json")
+		jsonDB, err := NewJsonDB(fileName, journalFile(t), 0)
+		assert.NoError(t, err)
+		assert.NoError(t, jsonDB.Append("survey.create", person{Age: 25, Place: "India"}))
+		assert.NoError(t, jsonDB.Append("survey.create", person{Age: 30, Place: "Spain"}))
+		assert.Equal(t, uint64(2), jsonDB.Position())
+		assert.NoError(t, jsonDB.Truncate(1))
+		var replayed []string
+		err = jsonDB.Replay(func(op string, payload []byte) error {
+			replayed = append(replayed, string(payload))
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{`{"age":30,"place":"Spain"}`}, replayed)
+	})
 }
@@ -0,0 +1,181 @@
+package jsondb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+type walRecord struct {
+	Seq     uint64          `json:"seq"`
+	Op      string          `json:"op"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// WAL is an append only, fsync'd journal of operations stored as one json record per line
+type WAL struct {
+	mu       *sync.Mutex
+	fileName string
+	file     *os.File
+	seq      uint64
+	size     int64
+}
+
+// NewWAL opens or creates the journal at fileName
+// a partially written record left behind by a crash is discarded
+func NewWAL(fileName string) (*WAL, error) {
+	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	w := &WAL{
+		mu:       &sync.Mutex{},
+		fileName: fileName,
+		file:     file,
+	}
+	var size int64
+	err = w.scan(func(record walRecord, end int64) error {
+		w.seq = record.Seq
+		size = end
+		return nil
+	})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err = file.Truncate(size); err != nil {
+		file.Close()
+		return nil, err
+	}
+	w.size = size
+	return w, nil
+}
+
+// Append writes the operation to the journal and syncs it to disk before returning
+func (w *WAL) Append(op string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	record, err := json.Marshal(walRecord{Seq: w.seq + 1, Op: op, Payload: payloadJSON})
+	if err != nil {
+		return err
+	}
+	record = append(record, '\n')
+	if _, err = w.file.Write(record); err != nil {
+		_ = w.file.Truncate(w.size)
+		return err
+	}
+	if err = w.file.Sync(); err != nil {
+		_ = w.file.Truncate(w.size)
+		return err
+	}
+	w.seq++
+	w.size += int64(len(record))
+	return nil
+}
+
+// Replay calls apply for every journaled operation in the order they were appended
+func (w *WAL) Replay(apply func(op string, payload []byte) error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.scan(func(record walRecord, _ int64) error {
+		return apply(record.Op, record.Payload)
+	})
+}
+
+// Position returns the sequence number of the last appended operation
+func (w *WAL) Position() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.seq
+}
+
+// Truncate drops every operation up to and including upTo
+// the remaining operations are written to a temporary file which atomically replaces the journal
+func (w *WAL) Truncate(upTo uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	buf := new(bytes.Buffer)
+	err := w.scan(func(record walRecord, _ int64) error {
+		if record.Seq <= upTo {
+			return nil
+		}
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if int64(buf.Len()) == w.size {
+		return nil
+	}
+	tmpName := w.fileName + ".tmp"
+	if err = writeFileSync(tmpName, buf.Bytes()); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpName, w.fileName); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(w.fileName, os.O_APPEND|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	_ = w.file.Close()
+	w.file = file
+	w.size = int64(buf.Len())
+	return nil
+}
+
+// scan reads every complete record from the start of the journal
+// end is the offset right after the record, a trailing record without a newline is ignored
+func (w *WAL) scan(fn func(record walRecord, end int64) error) error {
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	reader := bufio.NewReader(w.file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		offset += int64(len(line))
+		var record walRecord
+		if err = json.Unmarshal(line, &record); err != nil {
+			return err
+		}
+		if err = fn(record, offset); err != nil {
+			return err
+		}
+	}
+}
+
+func writeFileSync(fileName string, data []byte) error {
+	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,150 @@
+package jsondb
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type walEntry struct {
+	op      string
+	payload string
+}
+
+func replayAll(t *testing.T, wal *WAL) []walEntry {
+	var entries []walEntry
+	err := wal.Replay(func(op string, payload []byte) error {
+		entries = append(entries, walEntry{op: op, payload: string(payload)})
+		return nil
+	})
+	assert.NoError(t, err)
+	return entries
+}
+
+func TestNewWAL(t *testing.T) {
+	t.Run("should create journal file when it does not exist", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "app.wal")
+		wal, err := NewWAL(fileName)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(0), wal.Position())
+		_, err = os.Stat(fileName)
+		assert.NoError(t, err)
+	})
+	t.Run("should resume position from existing journal", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "app.wal")
+		wal, err := NewWAL(fileName)
+		assert.NoError(t, err)
+		assert.NoError(t, wal.Append("survey.create", person{Age: 25}))
+		assert.NoError(t, wal.Append("survey.create", person{Age: 26}))
+		reopened, err := NewWAL(fileName)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(2), reopened.Position())
+		assert.Len(t, replayAll(t, reopened), 2)
+	})
+	t.Run("should discard partially written record", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "app.wal")
+		wal, err := NewWAL(fileName)
+		assert.NoError(t, err)
+		assert.NoError(t, wal.Append("survey.create", person{Age: 25}))
+		file, err := os.OpenFile(fileName, os.O_APPEND|os.O_WRONLY, 0644)
+		assert.NoError(t, err)
+		_, err = file.WriteString(`{"seq":2,"op":"survey.cr`)
+		assert.NoError(t, err)
+		file.Close()
+		reopened, err := NewWAL(fileName)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), reopened.Position())
+		assert.NoError(t, reopened.Append("survey.update", person{Age: 27}))
+		assert.Equal(t, []walEntry{
+			{op: "survey.create", payload: `{"age":25,"place":""}`},
+			{op: "survey.update", payload: `{"age":27,"place":""}`},
+		}, replayAll(t, reopened))
+	})
+	t.Run("should return error for corrupt record", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "app.wal")
+		err := ioutil.WriteFile(fileName, []byte("hello\n"), 0644)
+		assert.NoError(t, err)
+		wal, err := NewWAL(fileName)
+		assert.Error(t, err)
+		assert.Nil(t, wal)
+	})
+}
+
+func TestWAL_Append(t *testing.T) {
+	t.Run("should append operations in order", func(t *testing.T) {
+		wal, err := NewWAL(filepath.Join(t.TempDir(), "app.wal"))
+		assert.NoError(t, err)
+		assert.NoError(t, wal.Append("survey.create", person{Age: 25, Place: "India"}))
+		assert.NoError(t, wal.Append("survey.delete", person{Age: 25, Place: "India"}))
+		assert.Equal(t, uint64(2), wal.Position())
+		assert.Equal(t, []walEntry{
+			{op: "survey.create", payload: `{"age":25,"place":"India"}`},
+			{op: "survey.delete", payload: `{"age":25,"place":"India"}`},
+		}, replayAll(t, wal))
+	})
+	t.Run("should return error when payload cannot be marshalled", func(t *testing.T) {
+		wal, err := NewWAL(filepath.Join(t.TempDir(), "app.wal"))
+		assert.NoError(t, err)
+		err = wal.Append("survey.create", make(chan int))
+		assert.Error(t, err)
+		assert.Equal(t, uint64(0), wal.Position())
+	})
+	t.Run("should return error when file is closed", func(t *testing.T) {
+		wal, err := NewWAL(filepath.Join(t.TempDir(), "app.wal"))
+		assert.NoError(t, err)
+		wal.file.Close()
+		err = wal.Append("survey.create", person{Age: 25})
+		assert.Error(t, err)
+		assert.Equal(t, uint64(0), wal.Position())
+	})
+}
+
+func TestWAL_Replay(t *testing.T) {
+	t.Run("should stop and return error returned by apply", func(t *testing.T) {
+		wal, err := NewWAL(filepath.Join(t.TempDir(), "app.wal"))
+		assert.NoError(t, err)
+		assert.NoError(t, wal.Append("survey.create", person{Age: 25}))
+		assert.NoError(t, wal.Append("survey.create", person{Age: 26}))
+		calls := 0
+		err = wal.Replay(func(op string, payload []byte) error {
+			calls++
+			return errors.New("something went wrong")
+		})
+		assert.Error(t, err)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestWAL_Truncate(t *testing.T) {
+	t.Run("should drop operations up to position and keep the rest", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "app.wal")
+		wal, err := NewWAL(fileName)
+		assert.NoError(t, err)
+		assert.NoError(t, wal.Append("survey.create", person{Age: 25}))
+		assert.NoError(t, wal.Append("survey.create", person{Age: 26}))
+		assert.NoError(t, wal.Append("survey.create", person{Age: 27}))
+		assert.NoError(t, wal.Truncate(2))
+		assert.NoError(t, wal.Append("survey.create", person{Age: 28}))
+		assert.Equal(t, uint64(4), wal.Position())
+		reopened, err := NewWAL(fileName)
+		assert.NoError(t, err)
+		assert.Equal(t, []walEntry{
+			{op: "survey.create", payload: `{"age":27,"place":""}`},
+			{op: "survey.create", payload: `{"age":28,"place":""}`},
+		}, replayAll(t, reopened))
+	})
+	t.Run("should empty the journal when every operation is covered", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "app.wal")
+		wal, err := NewWAL(fileName)
+		assert.NoError(t, err)
+		assert.NoError(t, wal.Append("survey.create", person{Age: 25}))
+		assert.NoError(t, wal.Truncate(wal.Position()))
+		info, err := os.Stat(fileName)
+		assert.NoError(t, err)
+		assert.Zero(t, info.Size())
+		assert.Equal(t, uint64(1), wal.Position())
+	})
+}
@@ -0,0 +1,34 @@
+package nopdb
+
+// NopDB is used with storage backends which persist every write themselves
+// there is nothing to load, dumps are discarded and nothing is journaled
+type NopDB struct {
+}
+
+func NewNopDB() *NopDB {
+	return &NopDB{}
+}
+
+func (n *NopDB) Load(target interface{}) error {
+	return nil
+}
+
+func (n *NopDB) Dump(contents interface{}) error {
+	return nil
+}
+
+func (n *NopDB) Append(op string, payload interface{}) error {
+	return nil
+}
+
+func (n *NopDB) Replay(apply func(op string, payload []byte) error) error {
+	return nil
+}
+
+func (n *NopDB) Position() uint64 {
+	return 0
+}
+
+func (n *NopDB) Truncate(upTo uint64) error {
+	return nil
+}
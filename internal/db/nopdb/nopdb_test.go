@@ -0,0 +1,26 @@
+package nopdb
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNopDB(t *testing.T) {
+	t.Run("should accept every call without doing anything", func(t *testing.T) {
+		nopDB := NewNopDB()
+		var target map[string]string
+		assert.NoError(t, nopDB.Load(&target))
+		assert.Nil(t, target)
+		assert.NoError(t, nopDB.Dump(map[string]string{"a": "b"}))
+		assert.NoError(t, nopDB.Append("survey.create", map[string]string{"a": "b"}))
+		replayed := 0
+		err := nopDB.Replay(func(op string, payload []byte) error {
+			replayed++
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Zero(t, replayed)
+		assert.Zero(t, nopDB.Position())
+		assert.NoError(t, nopDB.Truncate(1))
+	})
+}
@@ -0,0 +1,42 @@
+package snapshotter
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Dumper persists a snapshot of the current state
+type Dumper interface {
+	Dump() error
+}
+
+// Snapshotter periodically dumps a snapshot so that a crash only needs the journal written since the last one
+type Snapshotter struct {
+	interval time.Duration
+	dumper   Dumper
+}
+
+func NewSnapshotter(interval time.Duration, dumper Dumper) *Snapshotter {
+	return &Snapshotter{
+		interval: interval,
+		dumper:   dumper,
+	}
+}
+
+// Run dumps a snapshot every interval until ctx is done
+// a failed dump is logged and retried on the next tick
+func (s *Snapshotter) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.dumper.Dump(); err != nil {
+				log.Println("error while dumping snapshot", err)
+			}
+		}
+	}
+}
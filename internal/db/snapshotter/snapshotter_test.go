@@ -0,0 +1,45 @@
+package snapshotter
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingDumper struct {
+	dumps int32
+	err   error
+}
+
+func (c *countingDumper) Dump() error {
+	atomic.AddInt32(&c.dumps, 1)
+	return c.err
+}
+
+func TestSnapshotter_Run(t *testing.T) {
+	t.Run("should dump on every interval until context is done", func(t *testing.T) {
+		dumper := &countingDumper{}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			NewSnapshotter(5*time.Millisecond, dumper).Run(ctx)
+			close(done)
+		}()
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&dumper.dumps) >= 2 }, time.Second, time.Millisecond)
+		cancel()
+		<-done
+		dumps := atomic.LoadInt32(&dumper.dumps)
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, dumps, atomic.LoadInt32(&dumper.dumps))
+	})
+	t.Run("should keep dumping after a failed dump", func(t *testing.T) {
+		dumper := &countingDumper{err: errors.New("disk full")}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go NewSnapshotter(5*time.Millisecond, dumper).Run(ctx)
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&dumper.dumps) >= 2 }, time.Second, time.Millisecond)
+	})
+}
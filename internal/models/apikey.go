@@ -0,0 +1,77 @@
+package models
+
+import (
+	"github.com/segmentio/ksuid"
+	"time"
+)
+
+// Scope is an operation an api key is allowed to perform
+type Scope string
+
+const (
+	ScopeSurveysRead    Scope = "surveys:read"
+	ScopeSurveysWrite   Scope = "surveys:write"
+	ScopeResponsesRead  Scope = "responses:read"
+	ScopeResponsesWrite Scope = "responses:write"
+	// ScopeKeysManage allows creating, listing and revoking api keys
+	ScopeKeysManage Scope = "keys:manage"
+	// ScopeUsersManage allows creating, listing and deleting users and assigning their roles
+	ScopeUsersManage Scope = "users:manage"
+)
+
+// Scopes lists every known scope
+var Scopes = []Scope{ScopeSurveysRead, ScopeSurveysWrite, ScopeResponsesRead, ScopeResponsesWrite, ScopeKeysManage, ScopeUsersManage}
+
+// Valid reports whether s is one of the known scopes
+func (s Scope) Valid() bool {
+	for _, scope := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey authenticates the requests of a client, only the hash of the key is stored
+// Prefix is the start of the key, so that users can tell their keys apart, the hash is never returned by the api
+// a key only reaches the surveys and responses of its workspace
+// a key bound to a user through UserID is further limited to what the roles of the user grant
+type APIKey struct {
+	ID          ksuid.KSUID  `json:"id"`
+	WorkspaceID WorkspaceID  `json:"workspace_id,omitempty"`
+	UserID      *ksuid.KSUID `json:"user_id,omitempty"`
+	Name        string       `json:"name"`
+	Prefix      string       `json:"prefix"`
+	Hash        string       `json:"hash,omitempty"`
+	Scopes      []Scope      `json:"scopes"`
+	CreatedAt   time.Time    `json:"created_at"`
+	RevokedAt   *time.Time   `json:"revoked_at,omitempty"`
+}
+
+// Workspace returns the workspace of the key, keys created before workspaces were introduced are in the default workspace
+func (k APIKey) Workspace() WorkspaceID {
+	return k.WorkspaceID.orDefault()
+}
+
+// Allows reports whether the key is active and grants every scope in scopes
+func (k APIKey) Allows(scopes ...Scope) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	for _, scope := range scopes {
+		granted := false
+		for _, keyScope := range k.Scopes {
+			granted = granted || keyScope == scope
+		}
+		if !granted {
+			return false
+		}
+	}
+	return true
+}
+
+// CreatedAPIKey is a newly created api key along with the key itself, which cannot be read again later
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
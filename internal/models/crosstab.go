@@ -0,0 +1,33 @@
+package models
+
+import "github.com/segmentio/ksuid"
+
+// CrossTab is the contingency table of the answers to two questions of a survey
+// Counts[i][j] is the number of responses answering Row.Categories[i] and Column.Categories[j],
+// only responses answering both questions are counted and multi choice answers count once for every picked option
+type CrossTab struct {
+	SurveyID          ksuid.KSUID   `json:"survey_id"`
+	Row               CrossTabAxis  `json:"row"`
+	Column            CrossTabAxis  `json:"column"`
+	Counts            [][]int       `json:"counts"`
+	RowTotals         []int         `json:"row_totals"`
+	ColumnTotals      []int         `json:"column_totals"`
+	Total             int           `json:"total"`
+	RowPercentages    [][]float64   `json:"row_percentages"`
+	ColumnPercentages [][]float64   `json:"column_percentages"`
+	ChiSquare         ChiSquareTest `json:"chi_square"`
+}
+
+// CrossTabAxis is a question tabulated along one side of a cross tab
+type CrossTabAxis struct {
+	QuestionID ksuid.KSUID `json:"question_id"`
+	Question   string      `json:"question"`
+	Categories []string    `json:"categories"`
+}
+
+// ChiSquareTest is Pearson's test of independence between the two questions of a cross tab
+type ChiSquareTest struct {
+	Statistic        float64 `json:"statistic"`
+	DegreesOfFreedom int     `json:"degrees_of_freedom"`
+	PValue           float64 `json:"p_value"`
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"github.com/segmentio/ksuid"
+	"time"
+)
+
+// Draft is a response answered over several requests and submitted once complete, it is resumed with the token
+// handed out when it started, only the hash of the token is stored and it is never returned by the api
+// a draft which is not updated before ExpiresAt expires, a submitted draft is stored as the response with the same id
+type Draft struct {
+	ID            ksuid.KSUID  `json:"id"`
+	WorkspaceID   WorkspaceID  `json:"workspace_id,omitempty"`
+	SurveyID      ksuid.KSUID  `json:"survey_id"`
+	SurveyVersion int          `json:"survey_version"`
+	InvitationID  *ksuid.KSUID `json:"invitation_id,omitempty"`
+	RespondentID  *ksuid.KSUID `json:"respondent_id,omitempty"`
+	Answers       []Answer     `json:"answers"`
+	TokenHash     string       `json:"token_hash,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+	ExpiresAt     time.Time    `json:"expires_at"`
+	SubmittedAt   *time.Time   `json:"submitted_at,omitempty"`
+}
+
+// Workspace returns the workspace of the draft
+func (d Draft) Workspace() WorkspaceID {
+	return d.WorkspaceID.orDefault()
+}
+
+// Expired reports whether the draft was left unsubmitted past its expiry at now
+func (d Draft) Expired(now time.Time) bool {
+	return d.SubmittedAt == nil && !now.Before(d.ExpiresAt)
+}
+
+// Response returns the response submitting the draft, it keeps the id of the draft
+func (d Draft) Response() Response {
+	return Response{
+		ID:            d.ID,
+		WorkspaceID:   d.WorkspaceID,
+		SurveyID:      d.SurveyID,
+		SurveyVersion: d.SurveyVersion,
+		InvitationID:  d.InvitationID,
+		RespondentID:  d.RespondentID,
+		Answers:       d.Answers,
+		CreatedAt:     d.UpdatedAt,
+	}
+}
+
+// Submit marks the draft as submitted by response
+func (d *Draft) Submit(response Response) {
+	submittedAt := response.CreatedAt
+	d.SubmittedAt = &submittedAt
+}
+
+// ExpiredDrafts tallies the drafts of a survey which were deleted once they expired, so that the funnel keeps counting them
+// Reached is the number of those drafts which answered each question
+type ExpiredDrafts struct {
+	WorkspaceID WorkspaceID         `json:"workspace_id,omitempty"`
+	SurveyID    ksuid.KSUID         `json:"survey_id"`
+	Drafts      int                 `json:"drafts"`
+	Reached     map[ksuid.KSUID]int `json:"reached,omitempty"`
+}
+
+// Workspace returns the workspace of the expired drafts
+func (e ExpiredDrafts) Workspace() WorkspaceID {
+	return e.WorkspaceID.orDefault()
+}
+
+// Add tallies draft along with the questions it answered
+func (e *ExpiredDrafts) Add(draft Draft) {
+	if e.Reached == nil {
+		e.Reached = make(map[ksuid.KSUID]int)
+	}
+	e.Drafts++
+	for _, answer := range draft.Answers {
+		e.Reached[answer.QuestionID]++
+	}
+}
+
+// DraftSession is a draft which just started along with the token it is resumed with
+type DraftSession struct {
+	Draft
+	ResumeToken string `json:"resume_token"`
+}
+
+// Funnel follows the drafts of a survey from their start to their submission
+// InProgress drafts can still be submitted, Expired drafts were left unsubmitted past their expiry
+// CompletionRate is the percentage of the started drafts which were submitted
+type Funnel struct {
+	SurveyID       ksuid.KSUID  `json:"survey_id"`
+	Started        int          `json:"started"`
+	InProgress     int          `json:"in_progress"`
+	Expired        int          `json:"expired"`
+	Submitted      int          `json:"submitted"`
+	CompletionRate float64      `json:"completion_rate"`
+	Questions      []FunnelStep `json:"questions"`
+}
+
+// FunnelStep is the number of drafts which answered a question of the current survey
+type FunnelStep struct {
+	QuestionID ksuid.KSUID `json:"question_id"`
+	Question   string      `json:"question"`
+	Reached    int         `json:"reached"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "github.com/segmentio/ksuid"
+
+// ImportFormat is the kind of file an import reads
+type ImportFormat string
+
+const (
+	// ImportDump is a DBEntry as written to survey_app.json, surveys along with their versions and responses
+	ImportDump ImportFormat = "dump"
+	// ImportNDJSON is one response per line
+	ImportNDJSON ImportFormat = "ndjson"
+	// ImportCSV is one response per row, with a column per question headed by the question id or text
+	ImportCSV ImportFormat = "csv"
+)
+
+// Valid reports whether f is one of the known formats
+func (f ImportFormat) Valid() bool {
+	switch f {
+	case ImportDump, ImportNDJSON, ImportCSV:
+		return true
+	}
+	return false
+}
+
+// ImportOptions configures an import
+// SurveyID is the survey csv rows answer and the survey of ndjson responses without a survey_id
+// DryRun validates the file without storing anything and PreserveIDs keeps the ids in the file instead of generating new ones
+type ImportOptions struct {
+	Format      ImportFormat
+	SurveyID    ksuid.KSUID
+	DryRun      bool
+	PreserveIDs bool
+}
+
+// ImportReport tells what an import stored, or would store for a dry run, and why the rejected rows were left out
+type ImportReport struct {
+	DryRun            bool          `json:"dry_run"`
+	SurveysImported   int           `json:"surveys_imported"`
+	ResponsesImported int           `json:"responses_imported"`
+	Errors            []ImportError `json:"errors,omitempty"`
+}
+
+// ImportError is a rejected survey or response
+// Line is the line of the row in ndjson files and the number of the row in csv files counting the header,
+// dump entries are identified by their ids
+type ImportError struct {
+	Line       int    `json:"line,omitempty"`
+	SurveyID   string `json:"survey_id,omitempty"`
+	ResponseID string `json:"response_id,omitempty"`
+	Message    string `json:"message"`
+}
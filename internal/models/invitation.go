@@ -0,0 +1,64 @@
+package models
+
+import (
+	"github.com/segmentio/ksuid"
+	"time"
+)
+
+// Respondent is a person invited to answer the surveys of a workspace, respondents are told apart by their email
+type Respondent struct {
+	ID          ksuid.KSUID `json:"id"`
+	WorkspaceID WorkspaceID `json:"workspace_id,omitempty"`
+	Email       string      `json:"email"`
+	Name        string      `json:"name,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// Workspace returns the workspace of the respondent
+func (r Respondent) Workspace() WorkspaceID {
+	return r.WorkspaceID.orDefault()
+}
+
+// Invitation asks a respondent to answer a survey once
+// StartedAt is set the first time the respondent opens the survey and CompletedAt once the response is saved
+type Invitation struct {
+	ID           ksuid.KSUID  `json:"id"`
+	WorkspaceID  WorkspaceID  `json:"workspace_id,omitempty"`
+	SurveyID     ksuid.KSUID  `json:"survey_id"`
+	RespondentID ksuid.KSUID  `json:"respondent_id"`
+	CreatedAt    time.Time    `json:"created_at"`
+	StartedAt    *time.Time   `json:"started_at,omitempty"`
+	CompletedAt  *time.Time   `json:"completed_at,omitempty"`
+	ResponseID   *ksuid.KSUID `json:"response_id,omitempty"`
+}
+
+// Workspace returns the workspace of the invitation
+func (i Invitation) Workspace() WorkspaceID {
+	return i.WorkspaceID.orDefault()
+}
+
+// Complete marks the invitation as answered by response, an invitation answered without being opened first starts along with it
+func (i *Invitation) Complete(response Response) {
+	completedAt := response.CreatedAt
+	responseID := response.ID
+	if i.StartedAt == nil {
+		i.StartedAt = &completedAt
+	}
+	i.CompletedAt = &completedAt
+	i.ResponseID = &responseID
+}
+
+// InvitationDetails is an invitation along with its respondent and the token the respondent answers with
+// the token is signed again whenever the invitation is read, it is never stored
+type InvitationDetails struct {
+	Invitation
+	Respondent Respondent `json:"respondent"`
+	Token      string     `json:"token"`
+}
+
+// InvitationSummary counts the invitations to a survey, Started includes the completed invitations
+type InvitationSummary struct {
+	Invited   int `json:"invited"`
+	Started   int `json:"started"`
+	Completed int `json:"completed"`
+}
@@ -6,31 +6,405 @@ import (
 )
 
 type Survey struct {
-	ID        ksuid.KSUID `json:"id" example:"-"`
-	Name      string      `json:"name" example:"account name"`
-	Questions []Question  `json:"questions"`
-	CreatedAt time.Time   `json:"created_at" example:"-"`
-	UpdatedAt time.Time   `json:"updated_at" example:"-"`
+	ID          ksuid.KSUID  `json:"id" example:"-"`
+	WorkspaceID WorkspaceID  `json:"workspace_id,omitempty" example:"-"`
+	Name        string       `json:"name" example:"account name"`
+	Version     int          `json:"version" example:"-"`
+	Status      SurveyStatus `json:"status,omitempty" example:"-"`
+	Questions   []Question   `json:"questions"`
+	Rules       []Rule       `json:"rules,omitempty"`
+	// OpensAt and ClosesAt bound the time a published survey accepts responses, either can be left open
+	OpensAt  *time.Time `json:"opens_at,omitempty"`
+	ClosesAt *time.Time `json:"closes_at,omitempty"`
+	// MaxResponses stops the survey from accepting responses once reached, zero means no limit
+	MaxResponses int `json:"max_responses,omitempty"`
+	// EditWindowSeconds is how long after it was saved a response can be edited or withdrawn, zero means never
+	EditWindowSeconds int       `json:"edit_window_seconds,omitempty"`
+	CreatedAt         time.Time `json:"created_at" example:"-"`
+	UpdatedAt         time.Time `json:"updated_at" example:"-"`
 }
 
+// EditableUntil returns the time until which a response saved at createdAt can be changed, nil when it never can
+func (s Survey) EditableUntil(createdAt time.Time) *time.Time {
+	if s.EditWindowSeconds <= 0 {
+		return nil
+	}
+	until := createdAt.Add(time.Duration(s.EditWindowSeconds) * time.Second)
+	return &until
+}
+
+// Workspace returns the workspace of the survey, surveys stored before workspaces were introduced are in the default workspace
+func (s Survey) Workspace() WorkspaceID {
+	return s.WorkspaceID.orDefault()
+}
+
+// CurrentVersion returns the version of the survey, surveys stored before versions were introduced are at version 1
+func (s Survey) CurrentVersion() int {
+	if s.Version == 0 {
+		return 1
+	}
+	return s.Version
+}
+
+// CompatibleWith reports whether answers to the survey can be merged with answers to other
+// the surveys need the same questions in the same order with the same type and config, the wording may differ
+func (s Survey) CompatibleWith(other Survey) bool {
+	if len(s.Questions) != len(other.Questions) {
+		return false
+	}
+	for i, question := range s.Questions {
+		otherQuestion := other.Questions[i]
+		if question.ID != otherQuestion.ID || question.Kind() != otherQuestion.Kind() ||
+			question.MaxLength != otherQuestion.MaxLength || !equalBounds(question.Min, otherQuestion.Min) ||
+			!equalBounds(question.Max, otherQuestion.Max) || !equalOptions(question.Options, otherQuestion.Options) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalBounds(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalOptions(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// VersionScope selects the responses of some versions of a survey
+// the zero value selects every version, Version selects one version and Compatible adds every version compatible with it
+type VersionScope struct {
+	Version    int
+	Compatible bool
+}
+
+// Availability tells whether a survey accepts responses right now and for how long it will
+// Reason explains why responses are not accepted, RemainingResponses is left out for surveys without a quota
+// and OpensInSeconds and ClosesInSeconds are left out when the survey has no such timestamp or it already passed
+type Availability struct {
+	AcceptingResponses bool   `json:"accepting_responses"`
+	Reason             string `json:"reason,omitempty"`
+	RemainingResponses *int   `json:"remaining_responses,omitempty"`
+	OpensInSeconds     *int64 `json:"opens_in_seconds,omitempty"`
+	ClosesInSeconds    *int64 `json:"closes_in_seconds,omitempty"`
+}
+
+// SurveyDetails is a survey along with its current availability
+type SurveyDetails struct {
+	Survey
+	Availability Availability `json:"availability"`
+}
+
+// SearchResult is a survey matching a search along with its score, higher scores match better
+type SearchResult struct {
+	Survey
+	Score float64 `json:"score"`
+}
+
+// SurveyStatus is the lifecycle state of a survey, only published surveys accept responses
+type SurveyStatus string
+
+const (
+	SurveyDraft     SurveyStatus = "draft"
+	SurveyPublished SurveyStatus = "published"
+	SurveyClosed    SurveyStatus = "closed"
+	SurveyArchived  SurveyStatus = "archived"
+)
+
+// surveyTransitions lists the states a survey can move to from each state
+var surveyTransitions = map[SurveyStatus][]SurveyStatus{
+	SurveyDraft:     {SurveyPublished, SurveyArchived},
+	SurveyPublished: {SurveyClosed},
+	SurveyClosed:    {SurveyPublished, SurveyArchived},
+}
+
+// Valid reports whether s is one of the known states
+func (s SurveyStatus) Valid() bool {
+	switch s {
+	case SurveyDraft, SurveyPublished, SurveyClosed, SurveyArchived:
+		return true
+	}
+	return false
+}
+
+// CanMoveTo reports whether a survey in state s can move to state to
+func (s SurveyStatus) CanMoveTo(to SurveyStatus) bool {
+	for _, allowed := range surveyTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// State returns the status of the survey, surveys stored before statuses were introduced accepted responses so they are published
+func (s Survey) State() SurveyStatus {
+	if s.Status == "" {
+		return SurveyPublished
+	}
+	return s.Status
+}
+
+// QuestionType decides which answers a question accepts
+type QuestionType string
+
+const (
+	QuestionYesNo        QuestionType = "yes_no"
+	QuestionSingleChoice QuestionType = "single_choice"
+	QuestionMultiChoice  QuestionType = "multi_choice"
+	QuestionRating       QuestionType = "rating"
+	QuestionNPS          QuestionType = "nps"
+	QuestionText         QuestionType = "text"
+	QuestionNumeric      QuestionType = "numeric"
+	QuestionDate         QuestionType = "date"
+)
+
+// DateLayout is the format of answers to date questions
+const DateLayout = "2006-01-02"
+
+// Question is a question of a survey along with the type specific config
+// Options are the choices of single and multi choice questions
+// Min and Max bound rating and numeric answers and the number of choices picked for multi choice questions
+// MaxLength limits the number of characters of text answers, zero means no limit
+// responses have to answer Required questions
 type Question struct {
-	ID       ksuid.KSUID `json:"id" example:"ksuid"`
-	Question string      `json:"question" example:"question"`
+	ID        ksuid.KSUID  `json:"id" example:"ksuid"`
+	Question  string       `json:"question" example:"question"`
+	Type      QuestionType `json:"type,omitempty" example:"yes_no"`
+	Required  bool         `json:"required,omitempty"`
+	Options   []string     `json:"options,omitempty"`
+	Min       *float64     `json:"min,omitempty"`
+	Max       *float64     `json:"max,omitempty"`
+	MaxLength int          `json:"max_length,omitempty"`
+}
+
+// Kind returns the type of the question, questions stored before types were introduced are yes/no questions
+func (q Question) Kind() QuestionType {
+	if q.Type == "" {
+		return QuestionYesNo
+	}
+	return q.Type
 }
 
 type Answer struct {
 	QuestionID ksuid.KSUID `json:"question_id"`
-	Answer     bool        `json:"answer"`
+	Answer     Value       `json:"answer"`
 }
 
+// Response is a submission to a survey, SurveyVersion is the version of the survey it answered
+// responses answering an invitation carry the invitation and its respondent, anonymous responses carry neither
+// UpdatedAt is set once the response is edited and History keeps the answers it had before every edit, oldest first
 type Response struct {
-	ID        ksuid.KSUID `json:"id"`
-	SurveyID  ksuid.KSUID `json:"survey_id"`
-	Answers   []Answer    `json:"answers"`
-	CreatedAt time.Time   `json:"created_at"`
+	ID            ksuid.KSUID        `json:"id"`
+	WorkspaceID   WorkspaceID        `json:"workspace_id,omitempty"`
+	SurveyID      ksuid.KSUID        `json:"survey_id"`
+	SurveyVersion int                `json:"survey_version,omitempty"`
+	InvitationID  *ksuid.KSUID       `json:"invitation_id,omitempty"`
+	RespondentID  *ksuid.KSUID       `json:"respondent_id,omitempty"`
+	Answers       []Answer           `json:"answers"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     *time.Time         `json:"updated_at,omitempty"`
+	History       []ResponseRevision `json:"history,omitempty"`
+}
+
+// ResponseRevision is the answers a response had before an edit, SavedAt is when they were saved
+type ResponseRevision struct {
+	Revision      int       `json:"revision"`
+	SurveyVersion int       `json:"survey_version"`
+	Answers       []Answer  `json:"answers"`
+	SavedAt       time.Time `json:"saved_at"`
+}
+
+// Edit replaces the answers of the response with answers to version at now, keeping the previous answers in its history
+// the history is copied so that copies of the response sharing it are left unchanged
+func (r *Response) Edit(version int, answers []Answer, now time.Time) {
+	savedAt := r.CreatedAt
+	if r.UpdatedAt != nil {
+		savedAt = *r.UpdatedAt
+	}
+	history := make([]ResponseRevision, len(r.History), len(r.History)+1)
+	copy(history, r.History)
+	r.History = append(history, ResponseRevision{
+		Revision:      len(history) + 1,
+		SurveyVersion: r.AnsweredVersion(),
+		Answers:       r.Answers,
+		SavedAt:       savedAt,
+	})
+	r.SurveyVersion, r.Answers, r.UpdatedAt = version, answers, &now
+}
+
+// ResponseDetails is a response along with the time until which it can still be edited or withdrawn
+type ResponseDetails struct {
+	Response
+	EditableUntil *time.Time `json:"editable_until,omitempty"`
+}
+
+// ResponseSet sums up the responses stored for a survey of a workspace, FirstAt and LastAt are the oldest and newest creation times
+type ResponseSet struct {
+	WorkspaceID WorkspaceID `json:"workspace_id"`
+	SurveyID    ksuid.KSUID `json:"survey_id"`
+	Responses   int         `json:"responses"`
+	FirstAt     time.Time   `json:"first_at"`
+	LastAt      time.Time   `json:"last_at"`
 }
 
+// AnsweredVersion returns the survey version the response answered, responses stored before versions were introduced answered version 1
+func (r Response) AnsweredVersion() int {
+	if r.SurveyVersion == 0 {
+		return 1
+	}
+	return r.SurveyVersion
+}
+
+// Workspace returns the workspace of the response, responses stored before workspaces were introduced are in the default workspace
+func (r Response) Workspace() WorkspaceID {
+	return r.WorkspaceID.orDefault()
+}
+
+// DBEntry is everything persisted by the app, the surveys and responses of each workspace are kept apart in Workspaces
+// Surveys, SurveyVersions and Responses are only read from entries persisted before workspaces were introduced
 type DBEntry struct {
-	Surveys   map[ksuid.KSUID]Survey     `json:"surveys"`
-	Responses map[ksuid.KSUID][]Response `json:"responses"`
+	Workspaces     map[WorkspaceID]*WorkspaceEntry `json:"workspaces,omitempty"`
+	APIKeys        map[ksuid.KSUID]APIKey          `json:"api_keys,omitempty"`
+	Users          map[ksuid.KSUID]User            `json:"users,omitempty"`
+	Respondents    map[ksuid.KSUID]Respondent      `json:"respondents,omitempty"`
+	Invitations    map[ksuid.KSUID]Invitation      `json:"invitations,omitempty"`
+	Outbox         map[ksuid.KSUID]OutboxMessage   `json:"outbox,omitempty"`
+	Drafts         map[ksuid.KSUID]Draft           `json:"drafts,omitempty"`
+	ExpiredDrafts  []ExpiredDrafts                 `json:"expired_drafts,omitempty"`
+	Surveys        map[ksuid.KSUID]Survey          `json:"surveys,omitempty"`
+	SurveyVersions map[ksuid.KSUID][]Survey        `json:"survey_versions,omitempty"`
+	Responses      map[ksuid.KSUID][]Response      `json:"responses,omitempty"`
+}
+
+// NewDBEntry partitions the surveys, survey versions and responses stored by the repositories by workspace
+func NewDBEntry(surveys map[ksuid.KSUID]Survey, versions map[ksuid.KSUID][]Survey, responses map[ksuid.KSUID][]Response) *DBEntry {
+	entry := &DBEntry{}
+	for id, survey := range surveys {
+		entry.Workspace(survey.Workspace()).Surveys[id] = survey
+	}
+	for id, surveyVersions := range versions {
+		if len(surveyVersions) > 0 {
+			entry.Workspace(surveyVersions[0].Workspace()).SurveyVersions[id] = surveyVersions
+		}
+	}
+	for surveyID, surveyResponses := range responses {
+		if len(surveyResponses) > 0 {
+			entry.Workspace(surveyResponses[0].Workspace()).Responses[surveyID] = surveyResponses
+		}
+	}
+	return entry
+}
+
+// Workspace returns the entry of the workspace id, creating it when the workspace has nothing persisted yet
+func (e *DBEntry) Workspace(id WorkspaceID) *WorkspaceEntry {
+	if e.Workspaces == nil {
+		e.Workspaces = make(map[WorkspaceID]*WorkspaceEntry)
+	}
+	if e.Workspaces[id] == nil {
+		e.Workspaces[id] = newWorkspaceEntry()
+	}
+	return e.Workspaces[id]
+}
+
+// Migrate fills in the defaults for entries persisted by older versions of the app
+// data persisted before workspaces were introduced moves to the default workspace
+// and everything in a workspace entry is marked as belonging to that workspace
+func (e *DBEntry) Migrate() {
+	if len(e.Surveys) > 0 || len(e.SurveyVersions) > 0 || len(e.Responses) > 0 {
+		e.Workspace(DefaultWorkspace).merge(&WorkspaceEntry{
+			Surveys:        e.Surveys,
+			SurveyVersions: e.SurveyVersions,
+			Responses:      e.Responses,
+		})
+	}
+	e.Surveys, e.SurveyVersions, e.Responses = nil, nil, nil
+	for workspace, entry := range e.Workspaces {
+		if entry == nil {
+			delete(e.Workspaces, workspace)
+			continue
+		}
+		for id, survey := range entry.Surveys {
+			survey.WorkspaceID = workspace
+			entry.Surveys[id] = migrateSurvey(survey)
+		}
+		for _, versions := range entry.SurveyVersions {
+			for i := range versions {
+				versions[i].WorkspaceID = workspace
+				versions[i] = migrateSurvey(versions[i])
+			}
+		}
+		for _, responses := range entry.Responses {
+			for i := range responses {
+				responses[i].WorkspaceID = workspace
+				responses[i].SurveyVersion = responses[i].AnsweredVersion()
+			}
+		}
+	}
+	for id, key := range e.APIKeys {
+		key.WorkspaceID = key.Workspace()
+		e.APIKeys[id] = key
+	}
+	for id, user := range e.Users {
+		user.WorkspaceID = user.Workspace()
+		e.Users[id] = user
+	}
+	for id, respondent := range e.Respondents {
+		respondent.WorkspaceID = respondent.Workspace()
+		e.Respondents[id] = respondent
+	}
+	for id, invitation := range e.Invitations {
+		invitation.WorkspaceID = invitation.Workspace()
+		e.Invitations[id] = invitation
+	}
+	for id, message := range e.Outbox {
+		message.WorkspaceID = message.Workspace()
+		e.Outbox[id] = message
+	}
+	for id, draft := range e.Drafts {
+		draft.WorkspaceID = draft.Workspace()
+		e.Drafts[id] = draft
+	}
+	for i := range e.ExpiredDrafts {
+		e.ExpiredDrafts[i].WorkspaceID = e.ExpiredDrafts[i].Workspace()
+	}
+}
+
+// Flatten returns the surveys, survey versions and responses of every workspace, as stored by the repositories
+func (e *DBEntry) Flatten() (map[ksuid.KSUID]Survey, map[ksuid.KSUID][]Survey, map[ksuid.KSUID][]Response) {
+	surveys := make(map[ksuid.KSUID]Survey)
+	versions := make(map[ksuid.KSUID][]Survey)
+	responses := make(map[ksuid.KSUID][]Response)
+	for _, entry := range e.Workspaces {
+		for id, survey := range entry.Surveys {
+			surveys[id] = survey
+		}
+		for id, surveyVersions := range entry.SurveyVersions {
+			versions[id] = surveyVersions
+		}
+		for surveyID, surveyResponses := range entry.Responses {
+			responses[surveyID] = surveyResponses
+		}
+	}
+	return surveys, versions, responses
+}
+
+func migrateSurvey(survey Survey) Survey {
+	survey.Status = survey.State()
+	survey.Version = survey.CurrentVersion()
+	for i := range survey.Questions {
+		survey.Questions[i].Type = survey.Questions[i].Kind()
+	}
+	return survey
 }
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDBEntry_Migrate(t *testing.T) {
+	t.Run("should default question type of legacy surveys to yes/no and status to published", func(t *testing.T) {
+		var entry DBEntry
+		err := json.Unmarshal([]byte(`{"surveys":{"1srOrx2ZWZBpBUvZwXKQmoEYga2":{"id":"1srOrx2ZWZBpBUvZwXKQmoEYga2","questions":[{"id":"1srOrx2ZWZBpBUvZwXKQmoEYga2","question":"good?"}]}}}`), &entry)
+		assert.NoError(t, err)
+		entry.Migrate()
+		assert.Len(t, entry.Workspaces[DefaultWorkspace].Surveys, 1)
+		for _, survey := range entry.Workspaces[DefaultWorkspace].Surveys {
+			assert.Equal(t, SurveyPublished, survey.Status)
+			assert.Equal(t, QuestionYesNo, survey.Questions[0].Type)
+		}
+	})
+	t.Run("should put legacy surveys and responses at version 1", func(t *testing.T) {
+		var entry DBEntry
+		err := json.Unmarshal([]byte(`{"surveys":{"1srOrx2ZWZBpBUvZwXKQmoEYga2":{"id":"1srOrx2ZWZBpBUvZwXKQmoEYga2"}},"responses":{"1srOrx2ZWZBpBUvZwXKQmoEYga2":[{"id":"1srOrx2ZWZBpBUvZwXKQmoEYga2"}]}}`), &entry)
+		assert.NoError(t, err)
+		entry.Migrate()
+		workspace := entry.Workspaces[DefaultWorkspace]
+		assert.Len(t, workspace.Surveys, 1)
+		for id, survey := range workspace.Surveys {
+			assert.Equal(t, 1, survey.Version)
+			assert.Equal(t, 1, workspace.Responses[id][0].SurveyVersion)
+		}
+	})
+	t.Run("should move legacy data to the default workspace and mark data with its workspace", func(t *testing.T) {
+		var entry DBEntry
+		err := json.Unmarshal([]byte(`{"surveys":{"1srOrx2ZWZBpBUvZwXKQmoEYga2":{"id":"1srOrx2ZWZBpBUvZwXKQmoEYga2"}},"workspaces":{"acme":{"surveys":{"1srOrx2ZWZBpBUvZwXKQmoEYga3":{"id":"1srOrx2ZWZBpBUvZwXKQmoEYga3","workspace_id":"other"}},"responses":{"1srOrx2ZWZBpBUvZwXKQmoEYga3":[{"id":"1srOrx2ZWZBpBUvZwXKQmoEYga2"}]}}},"api_keys":{"1srOrx2ZWZBpBUvZwXKQmoEYga2":{"id":"1srOrx2ZWZBpBUvZwXKQmoEYga2"}}}`), &entry)
+		assert.NoError(t, err)
+		entry.Migrate()
+		assert.Nil(t, entry.Surveys)
+		legacyID, _ := ksuid.Parse("1srOrx2ZWZBpBUvZwXKQmoEYga2")
+		acmeID, _ := ksuid.Parse("1srOrx2ZWZBpBUvZwXKQmoEYga3")
+		assert.Equal(t, DefaultWorkspace, entry.Workspaces[DefaultWorkspace].Surveys[legacyID].WorkspaceID)
+		assert.Equal(t, WorkspaceID("acme"), entry.Workspaces["acme"].Surveys[acmeID].WorkspaceID)
+		assert.Equal(t, WorkspaceID("acme"), entry.Workspaces["acme"].Responses[acmeID][0].WorkspaceID)
+		assert.Equal(t, DefaultWorkspace, entry.APIKeys[legacyID].WorkspaceID)
+	})
+}
+
+func TestDBEntry_Flatten(t *testing.T) {
+	t.Run("should partition surveys and responses by workspace and flatten them back", func(t *testing.T) {
+		acme := Survey{ID: ksuid.New(), WorkspaceID: "acme"}
+		legacy := Survey{ID: ksuid.New()}
+		response := Response{ID: ksuid.New(), WorkspaceID: "acme", SurveyID: acme.ID}
+		surveys := map[ksuid.KSUID]Survey{acme.ID: acme, legacy.ID: legacy}
+		versions := map[ksuid.KSUID][]Survey{acme.ID: {acme}, legacy.ID: {legacy}}
+		responses := map[ksuid.KSUID][]Response{acme.ID: {response}}
+		entry := NewDBEntry(surveys, versions, responses)
+		assert.Len(t, entry.Workspaces, 2)
+		assert.Equal(t, map[ksuid.KSUID]Survey{acme.ID: acme}, entry.Workspaces["acme"].Surveys)
+		assert.Equal(t, map[ksuid.KSUID]Survey{legacy.ID: legacy}, entry.Workspaces[DefaultWorkspace].Surveys)
+		assert.Empty(t, entry.Workspaces[DefaultWorkspace].Responses)
+		flatSurveys, flatVersions, flatResponses := entry.Flatten()
+		assert.Equal(t, surveys, flatSurveys)
+		assert.Equal(t, versions, flatVersions)
+		assert.Equal(t, responses, flatResponses)
+	})
+}
+
+func TestWorkspaceID_Valid(t *testing.T) {
+	t.Run("should accept lowercase slugs only", func(t *testing.T) {
+		assert.True(t, DefaultWorkspace.Valid())
+		assert.True(t, WorkspaceID("team-42").Valid())
+		assert.False(t, WorkspaceID("").Valid())
+		assert.False(t, WorkspaceID("-team").Valid())
+		assert.False(t, WorkspaceID("Team").Valid())
+		assert.False(t, WorkspaceID("team/other").Valid())
+	})
+}
+
+func TestSurvey_CompatibleWith(t *testing.T) {
+	max := 5.0
+	survey := Survey{Questions: []Question{
+		{ID: ksuid.New(), Question: "how was it?", Type: QuestionRating, Max: &max},
+		{ID: ksuid.New(), Question: "what did you eat?", Type: QuestionSingleChoice, Options: []string{"pizza", "pasta"}},
+	}}
+	withQuestions := func(questions ...Question) Survey {
+		return Survey{Questions: questions}
+	}
+	reworded, otherOptions, otherMax := survey.Questions[0], survey.Questions[1], survey.Questions[0]
+	reworded.Question = "how did you like it?"
+	otherOptions.Options = []string{"pizza", "salad"}
+	otherMax.Max = nil
+	t.Run("should be compatible when only the wording changed", func(t *testing.T) {
+		assert.True(t, survey.CompatibleWith(withQuestions(reworded, survey.Questions[1])))
+	})
+	t.Run("should not be compatible when questions or their config changed", func(t *testing.T) {
+		assert.False(t, survey.CompatibleWith(withQuestions(survey.Questions[0])))
+		assert.False(t, survey.CompatibleWith(withQuestions(survey.Questions[1], survey.Questions[0])))
+		assert.False(t, survey.CompatibleWith(withQuestions(survey.Questions[0], otherOptions)))
+		assert.False(t, survey.CompatibleWith(withQuestions(otherMax, survey.Questions[1])))
+	})
+}
+
+func TestSurveyStatus_CanMoveTo(t *testing.T) {
+	t.Run("should allow only the lifecycle transitions", func(t *testing.T) {
+		assert.True(t, SurveyDraft.CanMoveTo(SurveyPublished))
+		assert.True(t, SurveyPublished.CanMoveTo(SurveyClosed))
+		assert.True(t, SurveyClosed.CanMoveTo(SurveyPublished))
+		assert.True(t, SurveyClosed.CanMoveTo(SurveyArchived))
+		assert.False(t, SurveyPublished.CanMoveTo(SurveyDraft))
+		assert.False(t, SurveyArchived.CanMoveTo(SurveyPublished))
+		assert.False(t, SurveyStatus("deleted").CanMoveTo(SurveyPublished))
+	})
+	t.Run("should treat surveys without status as published", func(t *testing.T) {
+		assert.Equal(t, SurveyPublished, Survey{}.State())
+		assert.False(t, SurveyStatus("").Valid())
+	})
+}
+
+func TestAPIKey_Allows(t *testing.T) {
+	t.Run("should allow active keys granting every scope", func(t *testing.T) {
+		key := APIKey{Scopes: []Scope{ScopeSurveysRead, ScopeResponsesWrite}}
+		assert.True(t, key.Allows(ScopeSurveysRead))
+		assert.True(t, key.Allows(ScopeSurveysRead, ScopeResponsesWrite))
+		assert.False(t, key.Allows(ScopeSurveysRead, ScopeSurveysWrite))
+		assert.True(t, key.Allows())
+	})
+	t.Run("should not allow revoked keys", func(t *testing.T) {
+		revokedAt := time.Now()
+		key := APIKey{Scopes: []Scope{ScopeSurveysRead}, RevokedAt: &revokedAt}
+		assert.False(t, key.Allows(ScopeSurveysRead))
+	})
+}
+
+func TestUser_Can(t *testing.T) {
+	surveyID, otherID := ksuid.New(), ksuid.New()
+	user := User{Roles: []RoleAssignment{{Role: RoleAnalyst}, {Role: RoleEditor, SurveyID: &surveyID}}}
+	for _, tc := range []struct {
+		name     string
+		scope    Scope
+		surveyID *ksuid.KSUID
+		want     bool
+	}{
+		{"workspace roles apply to the workspace", ScopeResponsesRead, nil, true},
+		{"workspace roles apply to every survey", ScopeResponsesRead, &otherID, true},
+		{"survey roles do not apply to the workspace", ScopeSurveysWrite, nil, false},
+		{"survey roles apply to their survey", ScopeSurveysWrite, &surveyID, true},
+		{"survey roles do not apply to other surveys", ScopeSurveysWrite, &otherID, false},
+		{"no role grants the scope", ScopeResponsesWrite, &surveyID, false},
+	} {
+		t.Run("should tell whether "+tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, user.Can(tc.scope, tc.surveyID))
+		})
+	}
+	t.Run("should list the roles applying to a survey", func(t *testing.T) {
+		assert.Equal(t, []Role{RoleAnalyst, RoleEditor}, user.RolesOn(&surveyID))
+		assert.Equal(t, []Role{RoleAnalyst}, user.RolesOn(nil))
+		assert.False(t, Role("admin").Valid())
+		assert.False(t, Role("admin").Grants(ScopeSurveysRead))
+	})
+}
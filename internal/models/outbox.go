@@ -0,0 +1,42 @@
+package models
+
+import (
+	"github.com/segmentio/ksuid"
+	"time"
+)
+
+// MailKind tells what an email sent to the respondent of an invitation is about
+type MailKind string
+
+const (
+	MailInvitation MailKind = "invitation"
+	MailReminder   MailKind = "reminder"
+)
+
+// OutboxMessage is an email to the respondent of an invitation, rendered when it is queued so that retries send the same email
+// a failed send is retried at NextAttemptAt, FailedAt is set once the attempts run out
+type OutboxMessage struct {
+	ID            ksuid.KSUID `json:"id"`
+	WorkspaceID   WorkspaceID `json:"workspace_id,omitempty"`
+	InvitationID  ksuid.KSUID `json:"invitation_id"`
+	Kind          MailKind    `json:"kind"`
+	To            string      `json:"to"`
+	Subject       string      `json:"subject"`
+	Body          string      `json:"body"`
+	CreatedAt     time.Time   `json:"created_at"`
+	Attempts      int         `json:"attempts"`
+	NextAttemptAt time.Time   `json:"next_attempt_at"`
+	LastError     string      `json:"last_error,omitempty"`
+	SentAt        *time.Time  `json:"sent_at,omitempty"`
+	FailedAt      *time.Time  `json:"failed_at,omitempty"`
+}
+
+// Workspace returns the workspace of the message
+func (m OutboxMessage) Workspace() WorkspaceID {
+	return m.WorkspaceID.orDefault()
+}
+
+// Pending reports whether the message still has to be sent
+func (m OutboxMessage) Pending() bool {
+	return m.SentAt == nil && m.FailedAt == nil
+}
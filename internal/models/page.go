@@ -0,0 +1,178 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/segmentio/ksuid"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SortField is the field a listing is ordered by, ties are broken by id which sorts by creation time as well
+type SortField string
+
+const (
+	SortCreatedAt SortField = "created_at"
+	SortUpdatedAt SortField = "updated_at"
+	SortName      SortField = "name"
+)
+
+// SortTimeLayout formats times in sort keys, fixed width UTC text sorts in time order
+const SortTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// PageQuery selects a page of a listing
+// the listing is ordered by Sort, creation time when empty, and continues after the item After points at
+// CreatedFrom and CreatedTo are inclusive bounds on the creation time, a nil bound leaves that side open
+type PageQuery struct {
+	Limit       int
+	After       *Cursor
+	Sort        SortField
+	Descending  bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
+
+// SortBy returns the field the listing is ordered by
+func (q PageQuery) SortBy() SortField {
+	if q.Sort == "" {
+		return SortCreatedAt
+	}
+	return q.Sort
+}
+
+// Cursor points at the last item of a page, Key is the sort key of the item
+// the sort of the listing is kept along, so that the next page is read in the same order
+type Cursor struct {
+	Sort       SortField   `json:"s"`
+	Descending bool        `json:"d,omitempty"`
+	Key        string      `json:"k"`
+	ID         ksuid.KSUID `json:"i"`
+}
+
+// Encode returns the cursor as an opaque url safe string
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor reads a cursor returned by Encode, ErrInvalidCursor is returned for anything else
+func DecodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err = json.Unmarshal(data, &cursor); err != nil || cursor.ID.IsNil() {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// SurveyQuery selects a page of surveys, Name keeps surveys whose name contains it and Status surveys in that status
+type SurveyQuery struct {
+	PageQuery
+	Name   string
+	Status SurveyStatus
+}
+
+// ResponseQuery selects a page of the responses to a survey, Versions keeps the responses to those versions, nil keeps all
+type ResponseQuery struct {
+	PageQuery
+	SurveyID ksuid.KSUID
+	Versions []int
+}
+
+// SurveyPage is a page of surveys, Next is the cursor of the following page and empty on the last page
+type SurveyPage struct {
+	Surveys []Survey `json:"surveys"`
+	Next    string   `json:"next_cursor,omitempty"`
+}
+
+// ResponsePage is a page of responses, Next is the cursor of the following page and empty on the last page
+type ResponsePage struct {
+	Responses []Response `json:"responses"`
+	Next      string     `json:"next_cursor,omitempty"`
+}
+
+// SortKey returns the key of the survey in a listing ordered by field
+func (s Survey) SortKey(field SortField) string {
+	switch field {
+	case SortUpdatedAt:
+		return s.UpdatedAt.UTC().Format(SortTimeLayout)
+	case SortName:
+		return s.Name
+	default:
+		return s.CreatedAt.UTC().Format(SortTimeLayout)
+	}
+}
+
+// SortKey returns the key of the response in a listing, responses can only be ordered by creation time
+func (r Response) SortKey(SortField) string {
+	return r.CreatedAt.UTC().Format(SortTimeLayout)
+}
+
+// Follows reports whether the item with key and id comes after the cursor of the query, every item does without a cursor
+func (q PageQuery) Follows(key string, id ksuid.KSUID) bool {
+	if q.After == nil {
+		return true
+	}
+	order := compareSortKeys(key, id, q.After.Key, q.After.ID)
+	if q.Descending {
+		return order < 0
+	}
+	return order > 0
+}
+
+// Less reports whether the item with keyA and idA is listed before the one with keyB and idB
+func (q PageQuery) Less(keyA string, idA ksuid.KSUID, keyB string, idB ksuid.KSUID) bool {
+	order := compareSortKeys(keyA, idA, keyB, idB)
+	if q.Descending {
+		return order > 0
+	}
+	return order < 0
+}
+
+// Created reports whether t is inside the creation time bounds of the query
+func (q PageQuery) Created(t time.Time) bool {
+	return (q.CreatedFrom == nil || !t.Before(*q.CreatedFrom)) && (q.CreatedTo == nil || !t.After(*q.CreatedTo))
+}
+
+// NextCursor returns the encoded cursor pointing at the item with key and id, in the order of the query
+func (q PageQuery) NextCursor(key string, id ksuid.KSUID) string {
+	return Cursor{Sort: q.SortBy(), Descending: q.Descending, Key: key, ID: id}.Encode()
+}
+
+func compareSortKeys(keyA string, idA ksuid.KSUID, keyB string, idB ksuid.KSUID) int {
+	switch {
+	case keyA < keyB:
+		return -1
+	case keyA > keyB:
+		return 1
+	default:
+		return ksuid.Compare(idA, idB)
+	}
+}
+
+// Matches reports whether survey passes the filters of the query, the cursor is not checked
+func (q SurveyQuery) Matches(survey Survey) bool {
+	return q.Created(survey.CreatedAt) && strings.Contains(survey.Name, q.Name) && (q.Status == "" || survey.State() == q.Status)
+}
+
+// Matches reports whether response passes the filters of the query, the cursor is not checked
+func (q ResponseQuery) Matches(response Response) bool {
+	if response.SurveyID != q.SurveyID || !q.Created(response.CreatedAt) {
+		return false
+	}
+	if q.Versions == nil {
+		return true
+	}
+	for _, version := range q.Versions {
+		if version == response.AnsweredVersion() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"github.com/segmentio/ksuid"
+	"time"
+)
+
+// ResultsFilter selects the responses aggregated into the results of a survey
+// From and To are inclusive days in UTC, a nil bound leaves that side of the range open
+// IncludeDrafts adds the answers of the drafts in progress, which are left out by default
+type ResultsFilter struct {
+	VersionScope
+	From          *time.Time
+	To            *time.Time
+	IncludeDrafts bool
+}
+
+// Results are the aggregated answers to a survey
+// Questions follow the questions of the scoped version, or of the current survey when every version is selected
+// Drafts is the number of drafts in progress counted in TotalResponses when they were included
+type Results struct {
+	SurveyID       ksuid.KSUID      `json:"survey_id"`
+	TotalResponses int              `json:"total_responses"`
+	Drafts         int              `json:"drafts,omitempty"`
+	PerDay         []DayCount       `json:"per_day"`
+	Questions      []QuestionResult `json:"questions"`
+}
+
+// DayCount is the number of responses saved on a day, formatted with DateLayout
+type DayCount struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// QuestionResult summarises the answers to a question
+// Options is set for yes/no and choice questions, Stats for rating, nps and numeric questions and NPS for nps questions
+type QuestionResult struct {
+	QuestionID ksuid.KSUID   `json:"question_id"`
+	Question   string        `json:"question"`
+	Type       QuestionType  `json:"type"`
+	Answered   int           `json:"answered"`
+	Options    []OptionTally `json:"options,omitempty"`
+	Stats      *NumericStats `json:"stats,omitempty"`
+	NPS        *NPSBreakdown `json:"nps,omitempty"`
+}
+
+// OptionTally is how often an option was picked, Percentage is relative to the responses answering the question
+type OptionTally struct {
+	Option     string  `json:"option"`
+	Count      int     `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
+
+type NumericStats struct {
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	StdDev float64 `json:"stddev"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+}
+
+// NPSBreakdown splits nps answers into promoters (9-10), passives (7-8) and detractors (0-6)
+// Score is the percentage of promoters minus the percentage of detractors
+type NPSBreakdown struct {
+	Score      float64 `json:"score"`
+	Promoters  int     `json:"promoters"`
+	Passives   int     `json:"passives"`
+	Detractors int     `json:"detractors"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "github.com/segmentio/ksuid"
+
+// RuleType decides what a rule does when its condition holds
+type RuleType string
+
+const (
+	// RuleShowIf shows the question only when the condition holds, it is hidden otherwise
+	RuleShowIf RuleType = "show_if"
+	// RuleJump skips the questions between the question and the target when the condition holds
+	RuleJump RuleType = "jump"
+)
+
+// Operator compares the answer to the question of a condition with the value of the condition
+type Operator string
+
+const (
+	OpEquals      Operator = "eq"
+	OpNotEquals   Operator = "neq"
+	OpLess        Operator = "lt"
+	OpLessOrEqual Operator = "lte"
+	OpGreater     Operator = "gt"
+	OpGreaterOrEq Operator = "gte"
+	// OpContains holds when the choices picked for a multi choice question include the value
+	OpContains Operator = "contains"
+	// OpAnswered holds when the question is answered, it takes no value
+	OpAnswered Operator = "answered"
+)
+
+// Condition is a check on the answer given to an earlier question
+// a condition on a question that is not answered never holds
+type Condition struct {
+	QuestionID ksuid.KSUID `json:"question_id"`
+	Operator   Operator    `json:"operator"`
+	Value      Value       `json:"value"`
+}
+
+// Rule attaches skip logic to the question QuestionID
+// show_if rules show the question only when their condition holds, a question with several show_if rules needs all of them to hold
+// jump rules skip every question between the question and Target when the question is shown and their condition holds
+type Rule struct {
+	Type       RuleType    `json:"type"`
+	QuestionID ksuid.KSUID `json:"question_id"`
+	Condition  Condition   `json:"condition"`
+	Target     ksuid.KSUID `json:"target"`
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"github.com/segmentio/ksuid"
+	"time"
+)
+
+// Role is a set of scopes a user holds on a whole workspace or on a single survey
+type Role string
+
+const (
+	// RoleOwner manages the workspace, its keys and its users
+	RoleOwner Role = "owner"
+	// RoleEditor writes surveys and reads their responses
+	RoleEditor Role = "editor"
+	// RoleAnalyst only reads surveys and their responses and results
+	RoleAnalyst Role = "analyst"
+	// RoleRespondent reads surveys and answers them
+	RoleRespondent Role = "respondent"
+)
+
+// Roles lists every known role
+var Roles = []Role{RoleOwner, RoleEditor, RoleAnalyst, RoleRespondent}
+
+var roleScopes = map[Role][]Scope{
+	RoleOwner:      {ScopeSurveysRead, ScopeSurveysWrite, ScopeResponsesRead, ScopeResponsesWrite, ScopeKeysManage, ScopeUsersManage},
+	RoleEditor:     {ScopeSurveysRead, ScopeSurveysWrite, ScopeResponsesRead},
+	RoleAnalyst:    {ScopeSurveysRead, ScopeResponsesRead},
+	RoleRespondent: {ScopeSurveysRead, ScopeResponsesWrite},
+}
+
+// Valid reports whether r is one of the known roles
+func (r Role) Valid() bool {
+	_, ok := roleScopes[r]
+	return ok
+}
+
+// Grants reports whether the role grants scope
+func (r Role) Grants(scope Scope) bool {
+	for _, granted := range roleScopes[r] {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleAssignment gives a role to a user on the whole workspace, or on a single survey when SurveyID is set
+type RoleAssignment struct {
+	Role     Role         `json:"role"`
+	SurveyID *ksuid.KSUID `json:"survey_id,omitempty"`
+}
+
+// Covers reports whether the assignment applies to the survey, nil asking for the whole workspace
+// workspace wide assignments apply to every survey of the workspace
+func (a RoleAssignment) Covers(surveyID *ksuid.KSUID) bool {
+	return a.SurveyID == nil || surveyID != nil && *a.SurveyID == *surveyID
+}
+
+// User is a member of a workspace, api keys bound to the user only reach what the roles of the user grant
+type User struct {
+	ID          ksuid.KSUID      `json:"id"`
+	WorkspaceID WorkspaceID      `json:"workspace_id,omitempty"`
+	Name        string           `json:"name"`
+	Email       string           `json:"email,omitempty"`
+	Roles       []RoleAssignment `json:"roles"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+// Workspace returns the workspace of the user
+func (u User) Workspace() WorkspaceID {
+	return u.WorkspaceID.orDefault()
+}
+
+// RolesOn returns the roles of the user applying to the survey, or to the whole workspace when surveyID is nil
+func (u User) RolesOn(surveyID *ksuid.KSUID) []Role {
+	roles := make([]Role, 0, len(u.Roles))
+	for _, assignment := range u.Roles {
+		if assignment.Covers(surveyID) {
+			roles = append(roles, assignment.Role)
+		}
+	}
+	return roles
+}
+
+// Can reports whether a role of the user applying to the survey, or to the whole workspace when surveyID is nil, grants scope
+func (u User) Can(scope Scope, surveyID *ksuid.KSUID) bool {
+	for _, role := range u.RolesOn(surveyID) {
+		if role.Grants(scope) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// ValueKind is the json type an answer was given in
+type ValueKind string
+
+const (
+	ValueNone    ValueKind = ""
+	ValueBool    ValueKind = "bool"
+	ValueNumber  ValueKind = "number"
+	ValueText    ValueKind = "text"
+	ValueChoices ValueKind = "choices"
+)
+
+var errInvalidValue = errors.New("answer must be a bool, number, string or array of strings")
+
+// Value is the typed answer to a question
+// in json it is the plain value, a bool for yes/no questions, a number for rating, nps and numeric questions,
+// a string for text, date and single choice questions and an array of strings for multi choice questions
+type Value struct {
+	Kind    ValueKind
+	Bool    bool
+	Number  float64
+	Text    string
+	Choices []string
+}
+
+func BoolValue(b bool) Value {
+	return Value{Kind: ValueBool, Bool: b}
+}
+
+func NumberValue(n float64) Value {
+	return Value{Kind: ValueNumber, Number: n}
+}
+
+func TextValue(text string) Value {
+	return Value{Kind: ValueText, Text: text}
+}
+
+func ChoicesValue(choices ...string) Value {
+	if choices == nil {
+		choices = []string{}
+	}
+	return Value{Kind: ValueChoices, Choices: choices}
+}
+
+func (v Value) MarshalJSON() ([]byte, error) {
+	switch v.Kind {
+	case ValueBool:
+		return json.Marshal(v.Bool)
+	case ValueNumber:
+		return json.Marshal(v.Number)
+	case ValueText:
+		return json.Marshal(v.Text)
+	case ValueChoices:
+		if v.Choices == nil {
+			return []byte("[]"), nil
+		}
+		return json.Marshal(v.Choices)
+	default:
+		return []byte("null"), nil
+	}
+}
+
+func (v *Value) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return errInvalidValue
+	}
+	switch data[0] {
+	case 'n':
+		*v = Value{}
+		return nil
+	case 't', 'f':
+		var b bool
+		if err := json.Unmarshal(data, &b); err != nil {
+			return err
+		}
+		*v = BoolValue(b)
+	case '"':
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		*v = TextValue(text)
+	case '[':
+		var choices []string
+		if err := json.Unmarshal(data, &choices); err != nil {
+			return errInvalidValue
+		}
+		*v = ChoicesValue(choices...)
+	default:
+		var n float64
+		if err := json.Unmarshal(data, &n); err != nil {
+			return errInvalidValue
+		}
+		*v = NumberValue(n)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValue_JSON(t *testing.T) {
+	t.Run("should round trip every kind of value", func(t *testing.T) {
+		values := []Value{BoolValue(true), NumberValue(7.5), TextValue("2021-07-30"), ChoicesValue("a", "b"), ChoicesValue(), {}}
+		for _, value := range values {
+			data, err := json.Marshal(value)
+			assert.NoError(t, err)
+			var decoded Value
+			assert.NoError(t, json.Unmarshal(data, &decoded))
+			assert.Equal(t, value.Kind, decoded.Kind)
+			assert.Equal(t, value, decoded, string(data))
+		}
+	})
+	t.Run("should load legacy bool answers", func(t *testing.T) {
+		var answer Answer
+		assert.NoError(t, json.Unmarshal([]byte(`{"question_id":"1srOrx2ZWZBpBUvZwXKQmoEYga2","answer":false}`), &answer))
+		assert.Equal(t, BoolValue(false), answer.Answer)
+	})
+	t.Run("should return error for objects", func(t *testing.T) {
+		var value Value
+		assert.Error(t, json.Unmarshal([]byte(`{"a":1}`), &value))
+	})
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"github.com/segmentio/ksuid"
+	"regexp"
+)
+
+// WorkspaceID identifies the workspace of a team, the surveys, responses and api keys of a workspace are only
+// visible to the api keys of the same workspace
+type WorkspaceID string
+
+// DefaultWorkspace holds everything stored before workspaces were introduced, and everything when api keys are disabled
+const DefaultWorkspace WorkspaceID = "default"
+
+var workspacePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Valid reports whether w is a lowercase slug of letters, digits and dashes, starting with a letter or digit
+func (w WorkspaceID) Valid() bool {
+	return workspacePattern.MatchString(string(w))
+}
+
+// orDefault returns w, or the default workspace for data stored before workspaces were introduced
+func (w WorkspaceID) orDefault() WorkspaceID {
+	if w == "" {
+		return DefaultWorkspace
+	}
+	return w
+}
+
+// WorkspaceEntry is everything persisted for a workspace, SurveyVersions holds every version of each survey, oldest first
+type WorkspaceEntry struct {
+	Surveys        map[ksuid.KSUID]Survey     `json:"surveys"`
+	SurveyVersions map[ksuid.KSUID][]Survey   `json:"survey_versions,omitempty"`
+	Responses      map[ksuid.KSUID][]Response `json:"responses"`
+}
+
+func newWorkspaceEntry() *WorkspaceEntry {
+	return &WorkspaceEntry{
+		Surveys:        make(map[ksuid.KSUID]Survey),
+		SurveyVersions: make(map[ksuid.KSUID][]Survey),
+		Responses:      make(map[ksuid.KSUID][]Response),
+	}
+}
+
+// merge adds the surveys, versions and responses of other to the entry, the ones of the entry win on conflicts
+func (w *WorkspaceEntry) merge(other *WorkspaceEntry) {
+	for id, survey := range other.Surveys {
+		if _, ok := w.Surveys[id]; !ok {
+			w.Surveys[id] = survey
+		}
+	}
+	for id, versions := range other.SurveyVersions {
+		if _, ok := w.SurveyVersions[id]; !ok {
+			w.SurveyVersions[id] = versions
+		}
+	}
+	for id, responses := range other.Responses {
+		if _, ok := w.Responses[id]; !ok {
+			w.Responses[id] = responses
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package apikeyrepo
+
+import (
+	"encoding/json"
+	"github.com/segmentio/ksuid"
+	"sort"
+	"survey-platform/internal/db"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"sync"
+	"time"
+)
+
+type APIKeyRepo struct {
+	mu     *sync.RWMutex
+	keys   map[ksuid.KSUID]models.APIKey
+	byHash map[string]ksuid.KSUID
+	// journal records every mutation before it is applied, nil disables journaling
+	journal db.Journal
+}
+
+// NewAPIKeyRepo returns a repo holding existingKeys
+// every mutation is appended to journal before it is applied, a nil journal disables journaling
+func NewAPIKeyRepo(existingKeys map[ksuid.KSUID]models.APIKey, journal db.Journal) *APIKeyRepo {
+	if existingKeys == nil {
+		existingKeys = make(map[ksuid.KSUID]models.APIKey)
+	}
+	apiKeyRepo := &APIKeyRepo{
+		mu:      &sync.RWMutex{},
+		keys:    existingKeys,
+		byHash:  make(map[string]ksuid.KSUID, len(existingKeys)),
+		journal: journal,
+	}
+	for id, key := range existingKeys {
+		apiKeyRepo.byHash[key.Hash] = id
+	}
+	return apiKeyRepo
+}
+
+func (r *APIKeyRepo) Create(key *models.APIKey) (*models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.record(repositories.OpCreateAPIKey, key); err != nil {
+		return nil, err
+	}
+	r.put(*key)
+	return key, nil
+}
+
+func (r *APIKeyRepo) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[id]
+	if !ok || key.Workspace() != workspace {
+		return nil, repositories.ErrNotFound
+	}
+	return &key, nil
+}
+
+func (r *APIKeyRepo) GetByHash(hash string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.byHash[hash]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	key := r.keys[id]
+	return &key, nil
+}
+
+// GetAll returns every key of the workspace ordered by creation time
+func (r *APIKeyRepo) GetAll(workspace models.WorkspaceID) ([]models.APIKey, error) {
+	r.mu.RLock()
+	keys := make([]models.APIKey, 0)
+	for _, key := range r.keys {
+		if key.Workspace() == workspace {
+			keys = append(keys, key)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return ksuid.Compare(keys[i].ID, keys[j].ID) < 0
+	})
+	return keys, nil
+}
+
+func (r *APIKeyRepo) Revoke(workspace models.WorkspaceID, id ksuid.KSUID, revokedAt time.Time) (*models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.keys[id]
+	if !ok || key.Workspace() != workspace {
+		return nil, repositories.ErrNotFound
+	}
+	if key.RevokedAt != nil {
+		return &key, nil
+	}
+	revoked := models.APIKey{ID: id, RevokedAt: &revokedAt}
+	if err := r.record(repositories.OpRevokeAPIKey, &revoked); err != nil {
+		return nil, err
+	}
+	key.RevokedAt = &revokedAt
+	r.keys[id] = key
+	return &key, nil
+}
+
+// Entries returns a copy of the stored keys which is safe to read while the repo is being written to
+func (r *APIKeyRepo) Entries() map[ksuid.KSUID]models.APIKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := make(map[ksuid.KSUID]models.APIKey, len(r.keys))
+	for id, key := range r.keys {
+		keys[id] = key
+	}
+	return keys
+}
+
+// Apply replays a journaled api key operation, a revocation is never undone by replaying the creation of the key
+// and revocations of missing keys are ignored so that replaying over a newer snapshot is harmless
+func (r *APIKeyRepo) Apply(op string, payload []byte) error {
+	if op != repositories.OpCreateAPIKey && op != repositories.OpRevokeAPIKey {
+		return nil
+	}
+	var key models.APIKey
+	if err := json.Unmarshal(payload, &key); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if op == repositories.OpCreateAPIKey {
+		if stored, ok := r.keys[key.ID]; ok && stored.RevokedAt != nil {
+			key.RevokedAt = stored.RevokedAt
+		}
+		r.put(key)
+		return nil
+	}
+	if stored, ok := r.keys[key.ID]; ok && stored.RevokedAt == nil {
+		stored.RevokedAt = key.RevokedAt
+		r.keys[key.ID] = stored
+	}
+	return nil
+}
+
+// put stores key, the caller must hold mu
+func (r *APIKeyRepo) put(key models.APIKey) {
+	if stored, ok := r.keys[key.ID]; ok {
+		delete(r.byHash, stored.Hash)
+	}
+	r.keys[key.ID] = key
+	r.byHash[key.Hash] = key.ID
+}
+
+func (r *APIKeyRepo) record(op string, payload interface{}) error {
+	if r.journal == nil {
+		return nil
+	}
+	return r.journal.Append(op, payload)
+}
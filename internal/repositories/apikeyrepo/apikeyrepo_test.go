@@ -0,0 +1,104 @@
+package apikeyrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"survey-platform/internal/db/db_mock"
+	"survey-platform/internal/db/jsondb"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/repotest"
+	"testing"
+	"time"
+)
+
+func TestNewAPIKeyRepo(t *testing.T) {
+	t.Run("should find existing keys by hash", func(t *testing.T) {
+		key := models.APIKey{ID: ksuid.New(), Name: "admin", Hash: "hash"}
+		apiKeyRepo := NewAPIKeyRepo(map[ksuid.KSUID]models.APIKey{key.ID: key}, nil)
+		stored, err := apiKeyRepo.GetByHash("hash")
+		assert.NoError(t, err)
+		assert.Equal(t, key, *stored)
+	})
+}
+
+func TestAPIKeyRepo_Journal(t *testing.T) {
+	t.Run("should journal every mutation before applying it", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		key := models.APIKey{ID: ksuid.New(), Name: "admin", Hash: "hash"}
+		revokedAt := time.Now()
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		gomock.InOrder(
+			mockJournal.EXPECT().Append(repositories.OpCreateAPIKey, &key).Return(nil),
+			mockJournal.EXPECT().Append(repositories.OpRevokeAPIKey, &models.APIKey{ID: key.ID, RevokedAt: &revokedAt}).Return(nil),
+		)
+		apiKeyRepo := NewAPIKeyRepo(nil, mockJournal)
+		_, err := apiKeyRepo.Create(&key)
+		assert.NoError(t, err)
+		_, err = apiKeyRepo.Revoke(models.DefaultWorkspace, key.ID, revokedAt)
+		assert.NoError(t, err)
+		_, err = apiKeyRepo.Revoke(models.DefaultWorkspace, key.ID, revokedAt)
+		assert.NoError(t, err, "revoking a revoked key is not journaled")
+	})
+	t.Run("should not apply mutation when journal returns error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		key := models.APIKey{ID: ksuid.New(), Name: "admin", Hash: "hash"}
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		mockJournal.EXPECT().Append(repositories.OpCreateAPIKey, &key).Return(errors.New("disk full"))
+		apiKeyRepo := NewAPIKeyRepo(nil, mockJournal)
+		created, err := apiKeyRepo.Create(&key)
+		assert.Error(t, err)
+		assert.Nil(t, created)
+		assert.Empty(t, apiKeyRepo.keys)
+		assert.Empty(t, apiKeyRepo.byHash)
+	})
+}
+
+func TestAPIKeyRepo_Apply(t *testing.T) {
+	t.Run("should apply journaled operations without undoing revocations", func(t *testing.T) {
+		key := models.APIKey{ID: ksuid.New(), Name: "admin", Hash: "hash"}
+		revokedAt := time.Now().UTC()
+		apiKeyRepo := NewAPIKeyRepo(nil, nil)
+		created, _ := json.Marshal(key)
+		revoked, _ := json.Marshal(models.APIKey{ID: key.ID, RevokedAt: &revokedAt})
+		assert.NoError(t, apiKeyRepo.Apply(repositories.OpCreateAPIKey, created))
+		assert.NoError(t, apiKeyRepo.Apply(repositories.OpRevokeAPIKey, revoked))
+		assert.NoError(t, apiKeyRepo.Apply(repositories.OpCreateAPIKey, created))
+		stored, err := apiKeyRepo.GetByHash("hash")
+		assert.NoError(t, err)
+		assert.True(t, revokedAt.Equal(*stored.RevokedAt))
+		assert.NoError(t, apiKeyRepo.Apply(repositories.OpRevokeAPIKey, []byte(`{"id":"`+ksuid.New().String()+`"}`)))
+		assert.Len(t, apiKeyRepo.keys, 1)
+	})
+	t.Run("should ignore operations of other repositories", func(t *testing.T) {
+		apiKeyRepo := NewAPIKeyRepo(nil, nil)
+		assert.NoError(t, apiKeyRepo.Apply(repositories.OpCreateSurvey, []byte("not a key")))
+		assert.Empty(t, apiKeyRepo.keys)
+	})
+	t.Run("should return error for malformed payload", func(t *testing.T) {
+		apiKeyRepo := NewAPIKeyRepo(nil, nil)
+		assert.Error(t, apiKeyRepo.Apply(repositories.OpCreateAPIKey, []byte("not a key")))
+	})
+}
+
+func TestAPIKeyRepo_Contract(t *testing.T) {
+	repotest.APIKeyRepoSuite(t, func(t *testing.T) repositories.APIKeyRepoInterface {
+		return NewAPIKeyRepo(nil, nil)
+	})
+}
+
+func TestAPIKeyRepo_JournalContract(t *testing.T) {
+	repotest.APIKeyRepoSuite(t, func(t *testing.T) repositories.APIKeyRepoInterface {
+		jsonDB, err := jsondb.NewJsonDB(filepath.Join(t.TempDir(), "survey_app.json"), filepath.Join(t.TempDir(), "survey_app.json.wal"), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewAPIKeyRepo(nil, jsonDB)
+	})
+}
@@ -0,0 +1,329 @@
+package draftrepo
+
+import (
+	"encoding/json"
+	"github.com/segmentio/ksuid"
+	"sort"
+	"survey-platform/internal/db"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/responserepo"
+	"sync"
+	"time"
+)
+
+// surveyKey identifies a survey of a workspace
+type surveyKey struct {
+	workspace models.WorkspaceID
+	surveyID  ksuid.KSUID
+}
+
+type DraftRepo struct {
+	mu     *sync.RWMutex
+	drafts map[ksuid.KSUID]models.Draft
+	// expired tallies the expired drafts which were deleted by survey
+	expired map[surveyKey]models.ExpiredDrafts
+	// journal records every mutation before it is applied, nil disables journaling
+	journal db.Journal
+	// responses stores the responses submitting drafts, nil when drafts cannot be submitted
+	responses *responserepo.ResponseRepo
+}
+
+// NewDraftRepo returns a repo holding existingDrafts
+// every mutation is appended to journal before it is applied, a nil journal disables journaling
+func NewDraftRepo(existingDrafts map[ksuid.KSUID]models.Draft, journal db.Journal) *DraftRepo {
+	if existingDrafts == nil {
+		existingDrafts = make(map[ksuid.KSUID]models.Draft)
+	}
+	return &DraftRepo{
+		mu:      &sync.RWMutex{},
+		drafts:  existingDrafts,
+		expired: make(map[surveyKey]models.ExpiredDrafts),
+		journal: journal,
+	}
+}
+
+// WithExpired restores the tallies of the expired drafts which were deleted, it must be called before the repo is used
+func (r *DraftRepo) WithExpired(existingExpired []models.ExpiredDrafts) *DraftRepo {
+	for _, expired := range existingExpired {
+		r.expired[surveyKey{expired.Workspace(), expired.SurveyID}] = expired
+	}
+	return r
+}
+
+// WithResponses makes the drafts submit their responses to responses, it must be called before the repo is used
+func (r *DraftRepo) WithResponses(responses *responserepo.ResponseRepo) *DraftRepo {
+	r.responses = responses
+	return r
+}
+
+func (r *DraftRepo) Create(draft *models.Draft) (*models.Draft, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.drafts[draft.ID]; ok {
+		return nil, repositories.ErrAlreadyExists
+	}
+	if err := r.record(repositories.OpCreateDraft, draft); err != nil {
+		return nil, err
+	}
+	r.drafts[draft.ID] = *draft
+	return draft, nil
+}
+
+func (r *DraftRepo) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Draft, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	draft, ok := r.get(workspace, id)
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	return &draft, nil
+}
+
+// GetBySurveyID returns every draft of a survey ordered by creation time, drafts created at the same time by id
+func (r *DraftRepo) GetBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) ([]models.Draft, error) {
+	r.mu.RLock()
+	drafts := make([]models.Draft, 0)
+	for _, draft := range r.drafts {
+		if draft.SurveyID == surveyID && draft.Workspace() == workspace {
+			drafts = append(drafts, draft)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(drafts, func(i, j int) bool {
+		if !drafts[i].CreatedAt.Equal(drafts[j].CreatedAt) {
+			return drafts[i].CreatedAt.Before(drafts[j].CreatedAt)
+		}
+		return ksuid.Compare(drafts[i].ID, drafts[j].ID) < 0
+	})
+	return drafts, nil
+}
+
+// Update stores the answers of draft unless it was submitted, the rest of the stored draft is kept
+func (r *DraftRepo) Update(draft *models.Draft) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.get(draft.Workspace(), draft.ID)
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	if stored.SubmittedAt != nil {
+		return repositories.ErrDraftSubmitted
+	}
+	updated := answered(stored, *draft)
+	if err := r.record(repositories.OpUpdateDraft, &updated); err != nil {
+		return err
+	}
+	r.drafts[draft.ID] = updated
+	return nil
+}
+
+// Submit stores response while the draft is locked, so that the draft cannot be submitted twice
+// the response is journaled as a single operation which both stores it and submits the draft when replayed
+func (r *DraftRepo) Submit(response *models.Response) (*models.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	draft, ok := r.get(response.Workspace(), response.ID)
+	if !ok || draft.SurveyID != response.SurveyID || r.responses == nil {
+		return nil, repositories.ErrNotFound
+	}
+	if draft.SubmittedAt != nil {
+		return nil, repositories.ErrDraftSubmitted
+	}
+	created, err := r.responses.Create(response)
+	if err != nil {
+		return nil, err
+	}
+	draft.Submit(*created)
+	r.drafts[draft.ID] = draft
+	return created, nil
+}
+
+// DeleteBySurveyID deletes every draft of a survey, it is journaled as a single operation with the survey as payload
+func (r *DraftRepo) DeleteBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.record(repositories.OpPurgeDrafts, &models.Survey{ID: surveyID, WorkspaceID: workspace}); err != nil {
+		return err
+	}
+	r.purge(workspace, surveyID)
+	return nil
+}
+
+// DeleteExpired deletes the drafts left unsubmitted with an expiry before cutoff, it is journaled as a single operation
+// with cutoff as payload, replaying it deletes the same drafts as they are replayed in the order they were written
+func (r *DraftRepo) DeleteExpired(cutoff time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.anyExpired(cutoff) {
+		return 0, nil
+	}
+	if err := r.record(repositories.OpExpireDrafts, cutoff); err != nil {
+		return 0, err
+	}
+	return r.expire(cutoff), nil
+}
+
+func (r *DraftRepo) GetExpired(workspace models.WorkspaceID, surveyID ksuid.KSUID) (*models.ExpiredDrafts, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	expired, ok := r.expired[surveyKey{workspace, surveyID}]
+	if !ok {
+		return &models.ExpiredDrafts{WorkspaceID: workspace, SurveyID: surveyID}, nil
+	}
+	return copyExpired(expired), nil
+}
+
+// Entries returns a copy of the stored drafts which is safe to read while the repo is being written to
+func (r *DraftRepo) Entries() map[ksuid.KSUID]models.Draft {
+	drafts, _ := r.EntriesWithExpired()
+	return drafts
+}
+
+// EntriesWithExpired returns copies of the stored drafts and expired drafts taken under the same lock,
+// the expired drafts are ordered by workspace and survey
+func (r *DraftRepo) EntriesWithExpired() (map[ksuid.KSUID]models.Draft, []models.ExpiredDrafts) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	drafts := make(map[ksuid.KSUID]models.Draft, len(r.drafts))
+	for id, draft := range r.drafts {
+		drafts[id] = draft
+	}
+	expired := make([]models.ExpiredDrafts, 0, len(r.expired))
+	for _, tally := range r.expired {
+		expired = append(expired, *copyExpired(tally))
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		if expired[i].WorkspaceID != expired[j].WorkspaceID {
+			return expired[i].WorkspaceID < expired[j].WorkspaceID
+		}
+		return ksuid.Compare(expired[i].SurveyID, expired[j].SurveyID) < 0
+	})
+	return drafts, expired
+}
+
+// Apply replays a journaled draft operation, along with the responses submitting drafts
+// stored drafts are not created again, updates older than the stored draft are skipped
+// and submitted drafts are neither updated nor submitted again
+func (r *DraftRepo) Apply(op string, payload []byte) error {
+	switch op {
+	case repositories.OpExpireDrafts:
+		var cutoff time.Time
+		if err := json.Unmarshal(payload, &cutoff); err != nil {
+			return err
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.expire(cutoff)
+	case repositories.OpPurgeDrafts:
+		var survey models.Survey
+		if err := json.Unmarshal(payload, &survey); err != nil {
+			return err
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.purge(survey.Workspace(), survey.ID)
+	case repositories.OpCreateDraft, repositories.OpUpdateDraft:
+		var draft models.Draft
+		if err := json.Unmarshal(payload, &draft); err != nil {
+			return err
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		stored, ok := r.drafts[draft.ID]
+		switch {
+		case op == repositories.OpCreateDraft && !ok:
+			r.drafts[draft.ID] = draft
+		case op == repositories.OpUpdateDraft && ok && stored.SubmittedAt == nil && !draft.UpdatedAt.Before(stored.UpdatedAt):
+			r.drafts[draft.ID] = answered(stored, draft)
+		}
+	case repositories.OpCreateResponse:
+		var response models.Response
+		if err := json.Unmarshal(payload, &response); err != nil {
+			return err
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if draft, ok := r.drafts[response.ID]; ok && draft.SubmittedAt == nil {
+			draft.Submit(response)
+			r.drafts[draft.ID] = draft
+		}
+	}
+	return nil
+}
+
+// answered returns stored with the answers of draft along with the version they answer and the times they moved
+func answered(stored, draft models.Draft) models.Draft {
+	stored.SurveyVersion = draft.SurveyVersion
+	stored.Answers = draft.Answers
+	stored.UpdatedAt = draft.UpdatedAt
+	stored.ExpiresAt = draft.ExpiresAt
+	return stored
+}
+
+// get returns the draft id when it is in workspace, the caller must hold mu
+func (r *DraftRepo) get(workspace models.WorkspaceID, id ksuid.KSUID) (models.Draft, bool) {
+	draft, ok := r.drafts[id]
+	if !ok || draft.Workspace() != workspace {
+		return models.Draft{}, false
+	}
+	return draft, true
+}
+
+// purge deletes the drafts of the survey surveyID of workspace along with its expired drafts, the caller must hold mu
+func (r *DraftRepo) purge(workspace models.WorkspaceID, surveyID ksuid.KSUID) {
+	for id, draft := range r.drafts {
+		if draft.SurveyID == surveyID && draft.Workspace() == workspace {
+			delete(r.drafts, id)
+		}
+	}
+	delete(r.expired, surveyKey{workspace, surveyID})
+}
+
+// anyExpired reports whether a draft would be deleted by expire, the caller must hold mu
+func (r *DraftRepo) anyExpired(cutoff time.Time) bool {
+	for _, draft := range r.drafts {
+		if draft.SubmittedAt == nil && draft.ExpiresAt.Before(cutoff) {
+			return true
+		}
+	}
+	return false
+}
+
+// expire deletes the drafts left unsubmitted with an expiry before cutoff and tallies them in the expired drafts
+// of their surveys, it returns how many were deleted, the caller must hold mu
+func (r *DraftRepo) expire(cutoff time.Time) int {
+	deleted := 0
+	for id, draft := range r.drafts {
+		if draft.SubmittedAt != nil || !draft.ExpiresAt.Before(cutoff) {
+			continue
+		}
+		key := surveyKey{draft.Workspace(), draft.SurveyID}
+		expired, ok := r.expired[key]
+		if !ok {
+			expired = models.ExpiredDrafts{WorkspaceID: draft.Workspace(), SurveyID: draft.SurveyID}
+		}
+		expired.Add(draft)
+		r.expired[key] = expired
+		delete(r.drafts, id)
+		deleted++
+	}
+	return deleted
+}
+
+// copyExpired returns a copy of expired which does not share its tally of questions
+func copyExpired(expired models.ExpiredDrafts) *models.ExpiredDrafts {
+	reached := make(map[ksuid.KSUID]int, len(expired.Reached))
+	for id, count := range expired.Reached {
+		reached[id] = count
+	}
+	expired.Reached = reached
+	return &expired
+}
+
+func (r *DraftRepo) record(op string, payload interface{}) error {
+	if r.journal == nil {
+		return nil
+	}
+	return r.journal.Append(op, payload)
+}
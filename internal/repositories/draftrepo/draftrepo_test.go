@@ -0,0 +1,182 @@
+package draftrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"survey-platform/internal/db/db_mock"
+	"survey-platform/internal/db/jsondb"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/repotest"
+	"survey-platform/internal/repositories/responserepo"
+	"testing"
+	"time"
+)
+
+func newDraft() models.Draft {
+	now := time.Now().UTC()
+	return models.Draft{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, SurveyID: ksuid.New(), SurveyVersion: 1,
+		Answers: []models.Answer{{QuestionID: ksuid.New(), Answer: models.BoolValue(true)}}, TokenHash: "hash",
+		CreatedAt: now, UpdatedAt: now, ExpiresAt: now.Add(time.Hour)}
+}
+
+func TestDraftRepo_Journal(t *testing.T) {
+	t.Run("should journal drafts, their updates and the responses submitting them before storing them", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		draft := newDraft()
+		updated := draft
+		updated.UpdatedAt = draft.UpdatedAt.Add(time.Minute)
+		response := updated.Response()
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		gomock.InOrder(
+			mockJournal.EXPECT().Append(repositories.OpCreateDraft, &draft).Return(nil),
+			mockJournal.EXPECT().Append(repositories.OpUpdateDraft, &updated).Return(nil),
+			mockJournal.EXPECT().Append(repositories.OpCreateResponse, &response).Return(nil),
+		)
+		draftRepo := NewDraftRepo(nil, mockJournal).WithResponses(responserepo.NewResponseRepo(nil, mockJournal))
+		_, err := draftRepo.Create(&draft)
+		assert.NoError(t, err)
+		assert.NoError(t, draftRepo.Update(&updated))
+		_, err = draftRepo.Submit(&response)
+		assert.NoError(t, err)
+	})
+	t.Run("should not submit the draft when the response cannot be stored", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		draft := newDraft()
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		mockJournal.EXPECT().Append(repositories.OpCreateResponse, gomock.Any()).Return(errors.New("disk full"))
+		draftRepo := NewDraftRepo(map[ksuid.KSUID]models.Draft{draft.ID: draft}, nil).
+			WithResponses(responserepo.NewResponseRepo(nil, mockJournal))
+		response := draft.Response()
+		_, err := draftRepo.Submit(&response)
+		assert.Error(t, err)
+		assert.Equal(t, draft, draftRepo.drafts[draft.ID])
+	})
+	t.Run("should keep the drafts of a survey when their purge cannot be journaled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		draft := newDraft()
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		mockJournal.EXPECT().Append(repositories.OpPurgeDrafts, &models.Survey{ID: draft.SurveyID, WorkspaceID: draft.WorkspaceID}).
+			Return(errors.New("disk full"))
+		draftRepo := NewDraftRepo(map[ksuid.KSUID]models.Draft{draft.ID: draft}, mockJournal)
+		assert.Error(t, draftRepo.DeleteBySurveyID(draft.WorkspaceID, draft.SurveyID))
+		assert.Equal(t, map[ksuid.KSUID]models.Draft{draft.ID: draft}, draftRepo.Entries())
+	})
+	t.Run("should journal the deletion of expired drafts only when a draft expired", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		draft := newDraft()
+		cutoff := draft.ExpiresAt.Add(time.Minute)
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		gomock.InOrder(
+			mockJournal.EXPECT().Append(repositories.OpExpireDrafts, cutoff).Return(errors.New("disk full")),
+			mockJournal.EXPECT().Append(repositories.OpExpireDrafts, cutoff).Return(nil),
+		)
+		draftRepo := NewDraftRepo(map[ksuid.KSUID]models.Draft{draft.ID: draft}, mockJournal)
+		deleted, err := draftRepo.DeleteExpired(draft.ExpiresAt)
+		assert.NoError(t, err)
+		assert.Zero(t, deleted)
+		_, err = draftRepo.DeleteExpired(cutoff)
+		assert.Error(t, err)
+		assert.Equal(t, map[ksuid.KSUID]models.Draft{draft.ID: draft}, draftRepo.Entries())
+		deleted, err = draftRepo.DeleteExpired(cutoff)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+		assert.Empty(t, draftRepo.Entries())
+	})
+	t.Run("should not submit drafts without a response repo", func(t *testing.T) {
+		draft := newDraft()
+		draftRepo := NewDraftRepo(map[ksuid.KSUID]models.Draft{draft.ID: draft}, nil)
+		response := draft.Response()
+		_, err := draftRepo.Submit(&response)
+		assert.Equal(t, repositories.ErrNotFound, err)
+	})
+}
+
+func TestDraftRepo_Apply(t *testing.T) {
+	t.Run("should replay creates, updates and the responses submitting drafts once", func(t *testing.T) {
+		draft := newDraft()
+		updated := draft
+		updated.Answers, updated.UpdatedAt = nil, draft.UpdatedAt.Add(time.Minute)
+		response := updated.Response()
+		created, _ := json.Marshal(draft)
+		changed, _ := json.Marshal(updated)
+		submitted, _ := json.Marshal(response)
+		draftRepo := NewDraftRepo(nil, nil)
+		for _, op := range []struct {
+			name    string
+			payload []byte
+		}{
+			{repositories.OpCreateDraft, created},
+			{repositories.OpUpdateDraft, changed},
+			{repositories.OpCreateResponse, submitted},
+			{repositories.OpCreateDraft, created},
+			{repositories.OpUpdateDraft, created},
+			{repositories.OpCreateResponse, submitted},
+		} {
+			assert.NoError(t, draftRepo.Apply(op.name, op.payload))
+		}
+		expected := updated
+		expected.Submit(response)
+		assert.Equal(t, map[ksuid.KSUID]models.Draft{draft.ID: expected}, draftRepo.Entries())
+	})
+	t.Run("should replay the purge of the drafts of a survey", func(t *testing.T) {
+		draft, kept := newDraft(), newDraft()
+		purged, _ := json.Marshal(models.Survey{ID: draft.SurveyID, WorkspaceID: draft.WorkspaceID})
+		draftRepo := NewDraftRepo(map[ksuid.KSUID]models.Draft{draft.ID: draft, kept.ID: kept}, nil)
+		assert.NoError(t, draftRepo.Apply(repositories.OpPurgeDrafts, purged))
+		assert.NoError(t, draftRepo.Apply(repositories.OpPurgeDrafts, purged))
+		assert.Equal(t, map[ksuid.KSUID]models.Draft{kept.ID: kept}, draftRepo.Entries())
+	})
+	t.Run("should replay the deletion of expired drafts once over the restored expired drafts", func(t *testing.T) {
+		draft, kept := newDraft(), newDraft()
+		kept.ExpiresAt = draft.ExpiresAt.Add(time.Hour)
+		restored := models.ExpiredDrafts{WorkspaceID: draft.WorkspaceID, SurveyID: draft.SurveyID, Drafts: 2,
+			Reached: map[ksuid.KSUID]int{draft.Answers[0].QuestionID: 1}}
+		cutoff, _ := json.Marshal(draft.ExpiresAt.Add(time.Minute))
+		draftRepo := NewDraftRepo(map[ksuid.KSUID]models.Draft{draft.ID: draft, kept.ID: kept}, nil).
+			WithExpired([]models.ExpiredDrafts{restored})
+		assert.NoError(t, draftRepo.Apply(repositories.OpExpireDrafts, cutoff))
+		assert.NoError(t, draftRepo.Apply(repositories.OpExpireDrafts, cutoff))
+		drafts, expired := draftRepo.EntriesWithExpired()
+		assert.Equal(t, map[ksuid.KSUID]models.Draft{kept.ID: kept}, drafts)
+		restored.Drafts, restored.Reached[draft.Answers[0].QuestionID] = 3, 2
+		assert.Equal(t, []models.ExpiredDrafts{restored}, expired)
+	})
+	t.Run("should ignore responses which do not submit a draft and operations of other repositories", func(t *testing.T) {
+		response, _ := json.Marshal(models.Response{ID: ksuid.New(), SurveyID: ksuid.New()})
+		draftRepo := NewDraftRepo(nil, nil)
+		assert.NoError(t, draftRepo.Apply(repositories.OpCreateResponse, response))
+		assert.NoError(t, draftRepo.Apply(repositories.OpCreateOutbox, []byte("not a draft")))
+		assert.Empty(t, draftRepo.drafts)
+	})
+	t.Run("should return error for malformed payload", func(t *testing.T) {
+		draftRepo := NewDraftRepo(nil, nil)
+		assert.Error(t, draftRepo.Apply(repositories.OpCreateDraft, []byte("not a draft")))
+	})
+}
+
+func TestDraftRepo_Contract(t *testing.T) {
+	repotest.DraftRepoSuite(t, func(t *testing.T) (repositories.DraftRepoInterface, repositories.ResponseRepoInterface) {
+		responseRepo := responserepo.NewResponseRepo(nil, nil)
+		return NewDraftRepo(nil, nil).WithResponses(responseRepo), responseRepo
+	})
+}
+
+func TestDraftRepo_JournalContract(t *testing.T) {
+	repotest.DraftRepoSuite(t, func(t *testing.T) (repositories.DraftRepoInterface, repositories.ResponseRepoInterface) {
+		jsonDB, err := jsondb.NewJsonDB(filepath.Join(t.TempDir(), "survey_app.json"), filepath.Join(t.TempDir(), "survey_app.json.wal"), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		responseRepo := responserepo.NewResponseRepo(nil, jsonDB)
+		return NewDraftRepo(nil, jsonDB).WithResponses(responseRepo), responseRepo
+	})
+}
@@ -0,0 +1,224 @@
+package invitationrepo
+
+import (
+	"encoding/json"
+	"github.com/segmentio/ksuid"
+	"sort"
+	"survey-platform/internal/db"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"sync"
+	"time"
+)
+
+// inviteeKey identifies a respondent invited to a survey
+type inviteeKey struct {
+	surveyID     ksuid.KSUID
+	respondentID ksuid.KSUID
+}
+
+type InvitationRepo struct {
+	mu          *sync.RWMutex
+	invitations map[ksuid.KSUID]models.Invitation
+	byInvitee   map[inviteeKey]ksuid.KSUID
+	// journal records every mutation before it is applied, nil disables journaling
+	journal db.Journal
+}
+
+// NewInvitationRepo returns a repo holding existingInvitations
+// every mutation is appended to journal before it is applied, a nil journal disables journaling
+func NewInvitationRepo(existingInvitations map[ksuid.KSUID]models.Invitation, journal db.Journal) *InvitationRepo {
+	if existingInvitations == nil {
+		existingInvitations = make(map[ksuid.KSUID]models.Invitation)
+	}
+	invitationRepo := &InvitationRepo{
+		mu:          &sync.RWMutex{},
+		invitations: existingInvitations,
+		byInvitee:   make(map[inviteeKey]ksuid.KSUID, len(existingInvitations)),
+		journal:     journal,
+	}
+	for id, invitation := range existingInvitations {
+		invitationRepo.byInvitee[inviteeKey{invitation.SurveyID, invitation.RespondentID}] = id
+	}
+	return invitationRepo
+}
+
+func (r *InvitationRepo) Create(invitation *models.Invitation) (*models.Invitation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.invitations[invitation.ID]; ok {
+		return nil, repositories.ErrAlreadyExists
+	}
+	if _, ok := r.byInvitee[inviteeKey{invitation.SurveyID, invitation.RespondentID}]; ok {
+		return nil, repositories.ErrAlreadyExists
+	}
+	if err := r.record(repositories.OpCreateInvitation, invitation); err != nil {
+		return nil, err
+	}
+	r.put(*invitation)
+	return invitation, nil
+}
+
+func (r *InvitationRepo) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Invitation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	invitation, ok := r.get(workspace, id)
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	return &invitation, nil
+}
+
+// GetBySurveyID returns every invitation to a survey ordered by creation time
+func (r *InvitationRepo) GetBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) ([]models.Invitation, error) {
+	r.mu.RLock()
+	invitations := make([]models.Invitation, 0)
+	for _, invitation := range r.invitations {
+		if invitation.SurveyID == surveyID && invitation.Workspace() == workspace {
+			invitations = append(invitations, invitation)
+		}
+	}
+	r.mu.RUnlock()
+	sortByCreation(invitations)
+	return invitations, nil
+}
+
+// GetPending returns the invitations of every workspace which were not completed, ordered by creation time
+func (r *InvitationRepo) GetPending() ([]models.Invitation, error) {
+	r.mu.RLock()
+	invitations := make([]models.Invitation, 0)
+	for _, invitation := range r.invitations {
+		if invitation.CompletedAt == nil {
+			invitations = append(invitations, invitation)
+		}
+	}
+	r.mu.RUnlock()
+	sortByCreation(invitations)
+	return invitations, nil
+}
+
+// Start marks the invitation as started at startedAt unless it already started
+func (r *InvitationRepo) Start(workspace models.WorkspaceID, id ksuid.KSUID, startedAt time.Time) (*models.Invitation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	invitation, ok := r.get(workspace, id)
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	if invitation.StartedAt != nil {
+		return &invitation, nil
+	}
+	if err := r.record(repositories.OpStartInvitation, &models.Invitation{ID: id, StartedAt: &startedAt}); err != nil {
+		return nil, err
+	}
+	invitation.StartedAt = &startedAt
+	r.invitations[id] = invitation
+	return &invitation, nil
+}
+
+// Complete checks that the invitation answered by response can be completed, calls store and completes the invitation
+// the invitation stays locked until it is completed, so that a concurrent response cannot answer it as well
+// nothing is completed when store returns an error
+func (r *InvitationRepo) Complete(response models.Response, store func() error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	invitation, ok := r.get(response.Workspace(), *response.InvitationID)
+	if !ok || invitation.SurveyID != response.SurveyID {
+		return repositories.ErrNotFound
+	}
+	if invitation.CompletedAt != nil {
+		return repositories.ErrInvitationCompleted
+	}
+	if err := store(); err != nil {
+		return err
+	}
+	invitation.Complete(response)
+	r.invitations[invitation.ID] = invitation
+	return nil
+}
+
+// Entries returns a copy of the stored invitations which is safe to read while the repo is being written to
+func (r *InvitationRepo) Entries() map[ksuid.KSUID]models.Invitation {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	invitations := make(map[ksuid.KSUID]models.Invitation, len(r.invitations))
+	for id, invitation := range r.invitations {
+		invitations[id] = invitation
+	}
+	return invitations
+}
+
+// Apply replays a journaled invitation operation, along with the responses completing invitations
+// stored invitations are not created again, and starts and completions keep the first time
+func (r *InvitationRepo) Apply(op string, payload []byte) error {
+	switch op {
+	case repositories.OpCreateInvitation:
+		var invitation models.Invitation
+		if err := json.Unmarshal(payload, &invitation); err != nil {
+			return err
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if _, ok := r.invitations[invitation.ID]; !ok {
+			r.put(invitation)
+		}
+	case repositories.OpStartInvitation:
+		var started models.Invitation
+		if err := json.Unmarshal(payload, &started); err != nil {
+			return err
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if invitation, ok := r.invitations[started.ID]; ok && invitation.StartedAt == nil {
+			invitation.StartedAt = started.StartedAt
+			r.invitations[started.ID] = invitation
+		}
+	case repositories.OpCreateResponse:
+		var response models.Response
+		if err := json.Unmarshal(payload, &response); err != nil {
+			return err
+		}
+		if response.InvitationID == nil {
+			return nil
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if invitation, ok := r.invitations[*response.InvitationID]; ok && invitation.CompletedAt == nil {
+			invitation.Complete(response)
+			r.invitations[invitation.ID] = invitation
+		}
+	}
+	return nil
+}
+
+// sortByCreation orders invitations by creation time, invitations created at the same time by id
+func sortByCreation(invitations []models.Invitation) {
+	sort.Slice(invitations, func(i, j int) bool {
+		if !invitations[i].CreatedAt.Equal(invitations[j].CreatedAt) {
+			return invitations[i].CreatedAt.Before(invitations[j].CreatedAt)
+		}
+		return ksuid.Compare(invitations[i].ID, invitations[j].ID) < 0
+	})
+}
+
+// get returns the invitation id when it is in workspace, the caller must hold mu
+func (r *InvitationRepo) get(workspace models.WorkspaceID, id ksuid.KSUID) (models.Invitation, bool) {
+	invitation, ok := r.invitations[id]
+	if !ok || invitation.Workspace() != workspace {
+		return models.Invitation{}, false
+	}
+	return invitation, true
+}
+
+// put stores invitation and indexes its respondent, the caller must hold mu
+func (r *InvitationRepo) put(invitation models.Invitation) {
+	r.invitations[invitation.ID] = invitation
+	r.byInvitee[inviteeKey{invitation.SurveyID, invitation.RespondentID}] = invitation.ID
+}
+
+func (r *InvitationRepo) record(op string, payload interface{}) error {
+	if r.journal == nil {
+		return nil
+	}
+	return r.journal.Append(op, payload)
+}
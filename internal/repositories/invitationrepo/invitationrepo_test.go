@@ -0,0 +1,84 @@
+package invitationrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"survey-platform/internal/db/db_mock"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"testing"
+	"time"
+)
+
+func TestInvitationRepo_Journal(t *testing.T) {
+	t.Run("should journal creates and first starts before applying them", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		invitation := models.Invitation{ID: ksuid.New(), SurveyID: ksuid.New(), RespondentID: ksuid.New()}
+		startedAt := time.Now()
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		gomock.InOrder(
+			mockJournal.EXPECT().Append(repositories.OpCreateInvitation, &invitation).Return(nil),
+			mockJournal.EXPECT().Append(repositories.OpStartInvitation, &models.Invitation{ID: invitation.ID, StartedAt: &startedAt}).Return(nil),
+		)
+		invitationRepo := NewInvitationRepo(nil, mockJournal)
+		_, err := invitationRepo.Create(&invitation)
+		assert.NoError(t, err)
+		_, err = invitationRepo.Start(models.DefaultWorkspace, invitation.ID, startedAt)
+		assert.NoError(t, err)
+		_, err = invitationRepo.Start(models.DefaultWorkspace, invitation.ID, startedAt.Add(time.Minute))
+		assert.NoError(t, err)
+	})
+	t.Run("should not complete the invitation when the response cannot be stored", func(t *testing.T) {
+		invitation := models.Invitation{ID: ksuid.New(), SurveyID: ksuid.New(), RespondentID: ksuid.New()}
+		invitationRepo := NewInvitationRepo(map[ksuid.KSUID]models.Invitation{invitation.ID: invitation}, nil)
+		response := models.Response{ID: ksuid.New(), SurveyID: invitation.SurveyID, InvitationID: &invitation.ID}
+		err := invitationRepo.Complete(response, func() error { return errors.New("disk full") })
+		assert.Error(t, err)
+		stored, err := invitationRepo.Get(models.DefaultWorkspace, invitation.ID)
+		assert.NoError(t, err)
+		assert.Nil(t, stored.CompletedAt)
+	})
+}
+
+func TestInvitationRepo_Apply(t *testing.T) {
+	t.Run("should replay creates, starts and the responses completing invitations", func(t *testing.T) {
+		invitation := models.Invitation{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, SurveyID: ksuid.New(), RespondentID: ksuid.New()}
+		startedAt := time.Now().UTC()
+		response := models.Response{ID: ksuid.New(), SurveyID: invitation.SurveyID, InvitationID: &invitation.ID, CreatedAt: startedAt.Add(time.Minute)}
+		created, _ := json.Marshal(invitation)
+		started, _ := json.Marshal(models.Invitation{ID: invitation.ID, StartedAt: &startedAt})
+		answered, _ := json.Marshal(response)
+		invitationRepo := NewInvitationRepo(nil, nil)
+		for _, op := range []struct {
+			name    string
+			payload []byte
+		}{
+			{repositories.OpCreateInvitation, created},
+			{repositories.OpStartInvitation, started},
+			{repositories.OpCreateResponse, answered},
+			{repositories.OpCreateInvitation, created},
+		} {
+			assert.NoError(t, invitationRepo.Apply(op.name, op.payload))
+		}
+		stored, err := invitationRepo.Get(models.DefaultWorkspace, invitation.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, startedAt, *stored.StartedAt)
+		assert.Equal(t, response.CreatedAt, *stored.CompletedAt)
+		assert.Equal(t, response.ID, *stored.ResponseID)
+	})
+	t.Run("should ignore responses without invitation and operations of other repositories", func(t *testing.T) {
+		invitationRepo := NewInvitationRepo(nil, nil)
+		anonymous, _ := json.Marshal(models.Response{ID: ksuid.New()})
+		assert.NoError(t, invitationRepo.Apply(repositories.OpCreateResponse, anonymous))
+		assert.NoError(t, invitationRepo.Apply(repositories.OpCreateUser, []byte("not an invitation")))
+		assert.Empty(t, invitationRepo.invitations)
+	})
+	t.Run("should return error for malformed payload", func(t *testing.T) {
+		invitationRepo := NewInvitationRepo(nil, nil)
+		assert.Error(t, invitationRepo.Apply(repositories.OpCreateInvitation, []byte("not an invitation")))
+	})
+}
@@ -0,0 +1,152 @@
+package outboxrepo
+
+import (
+	"encoding/json"
+	"github.com/segmentio/ksuid"
+	"sort"
+	"survey-platform/internal/db"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"sync"
+	"time"
+)
+
+type OutboxRepo struct {
+	mu       *sync.RWMutex
+	messages map[ksuid.KSUID]models.OutboxMessage
+	// journal records every mutation before it is applied, nil disables journaling
+	journal db.Journal
+}
+
+// NewOutboxRepo returns a repo holding existingMessages
+// every mutation is appended to journal before it is applied, a nil journal disables journaling
+func NewOutboxRepo(existingMessages map[ksuid.KSUID]models.OutboxMessage, journal db.Journal) *OutboxRepo {
+	if existingMessages == nil {
+		existingMessages = make(map[ksuid.KSUID]models.OutboxMessage)
+	}
+	return &OutboxRepo{
+		mu:       &sync.RWMutex{},
+		messages: existingMessages,
+		journal:  journal,
+	}
+}
+
+func (r *OutboxRepo) Create(message *models.OutboxMessage) (*models.OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.messages[message.ID]; ok {
+		return nil, repositories.ErrAlreadyExists
+	}
+	if err := r.record(repositories.OpCreateOutbox, message); err != nil {
+		return nil, err
+	}
+	r.messages[message.ID] = *message
+	return message, nil
+}
+
+// Due returns up to limit pending messages of every workspace whose next attempt is at or before now, earliest attempt first
+func (r *OutboxRepo) Due(now time.Time, limit int) ([]models.OutboxMessage, error) {
+	r.mu.RLock()
+	due := make([]models.OutboxMessage, 0)
+	for _, message := range r.messages {
+		if message.Pending() && !message.NextAttemptAt.After(now) {
+			due = append(due, message)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+		}
+		return ksuid.Compare(due[i].ID, due[j].ID) < 0
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// GetByInvitationID returns the messages sent for an invitation ordered by creation time
+func (r *OutboxRepo) GetByInvitationID(workspace models.WorkspaceID, invitationID ksuid.KSUID) ([]models.OutboxMessage, error) {
+	r.mu.RLock()
+	messages := make([]models.OutboxMessage, 0)
+	for _, message := range r.messages {
+		if message.InvitationID == invitationID && message.Workspace() == workspace {
+			messages = append(messages, message)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(messages, func(i, j int) bool {
+		if !messages[i].CreatedAt.Equal(messages[j].CreatedAt) {
+			return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+		}
+		return ksuid.Compare(messages[i].ID, messages[j].ID) < 0
+	})
+	return messages, nil
+}
+
+// Update stores the delivery state of message, the rest of the stored message is kept
+func (r *OutboxRepo) Update(message *models.OutboxMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.messages[message.ID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	updated := delivered(stored, *message)
+	if err := r.record(repositories.OpUpdateOutbox, &updated); err != nil {
+		return err
+	}
+	r.messages[message.ID] = updated
+	return nil
+}
+
+// Entries returns a copy of the stored messages which is safe to read while the repo is being written to
+func (r *OutboxRepo) Entries() map[ksuid.KSUID]models.OutboxMessage {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	messages := make(map[ksuid.KSUID]models.OutboxMessage, len(r.messages))
+	for id, message := range r.messages {
+		messages[id] = message
+	}
+	return messages
+}
+
+// Apply replays a journaled outbox operation
+// stored messages are not created again, and updates older than the stored delivery state are skipped
+func (r *OutboxRepo) Apply(op string, payload []byte) error {
+	if op != repositories.OpCreateOutbox && op != repositories.OpUpdateOutbox {
+		return nil
+	}
+	var message models.OutboxMessage
+	if err := json.Unmarshal(payload, &message); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.messages[message.ID]
+	switch {
+	case op == repositories.OpCreateOutbox && !ok:
+		r.messages[message.ID] = message
+	case op == repositories.OpUpdateOutbox && ok && message.Attempts >= stored.Attempts:
+		r.messages[message.ID] = delivered(stored, message)
+	}
+	return nil
+}
+
+// delivered returns stored with the delivery state of message
+func delivered(stored, message models.OutboxMessage) models.OutboxMessage {
+	stored.Attempts = message.Attempts
+	stored.NextAttemptAt = message.NextAttemptAt
+	stored.LastError = message.LastError
+	stored.SentAt = message.SentAt
+	stored.FailedAt = message.FailedAt
+	return stored
+}
+
+func (r *OutboxRepo) record(op string, payload interface{}) error {
+	if r.journal == nil {
+		return nil
+	}
+	return r.journal.Append(op, payload)
+}
@@ -0,0 +1,99 @@
+package outboxrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"survey-platform/internal/db/db_mock"
+	"survey-platform/internal/db/jsondb"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/repotest"
+	"testing"
+	"time"
+)
+
+func newMessage() models.OutboxMessage {
+	now := time.Now().UTC()
+	return models.OutboxMessage{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, InvitationID: ksuid.New(),
+		Kind: models.MailInvitation, To: "alice@example.com", CreatedAt: now, NextAttemptAt: now}
+}
+
+func TestOutboxRepo_Journal(t *testing.T) {
+	t.Run("should journal messages and their delivery before storing them", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		message := newMessage()
+		sent := message
+		sent.Attempts, sent.SentAt = 1, &message.CreatedAt
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		gomock.InOrder(
+			mockJournal.EXPECT().Append(repositories.OpCreateOutbox, &message).Return(nil),
+			mockJournal.EXPECT().Append(repositories.OpUpdateOutbox, &sent).Return(nil),
+		)
+		outboxRepo := NewOutboxRepo(nil, mockJournal)
+		_, err := outboxRepo.Create(&message)
+		assert.NoError(t, err)
+		assert.NoError(t, outboxRepo.Update(&sent))
+	})
+	t.Run("should not store the delivery when journal returns error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		message := newMessage()
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		mockJournal.EXPECT().Append(repositories.OpUpdateOutbox, gomock.Any()).Return(errors.New("disk full"))
+		outboxRepo := NewOutboxRepo(map[ksuid.KSUID]models.OutboxMessage{message.ID: message}, mockJournal)
+		sent := message
+		sent.SentAt = &message.CreatedAt
+		assert.Error(t, outboxRepo.Update(&sent))
+		assert.Equal(t, message, outboxRepo.messages[message.ID])
+	})
+}
+
+func TestOutboxRepo_Apply(t *testing.T) {
+	t.Run("should apply journaled messages and deliveries once", func(t *testing.T) {
+		message := newMessage()
+		created, _ := json.Marshal(message)
+		retried := message
+		retried.Attempts, retried.LastError = 1, "connection refused"
+		firstAttempt, _ := json.Marshal(retried)
+		sent := message
+		sent.Attempts, sent.SentAt = 2, &message.CreatedAt
+		secondAttempt, _ := json.Marshal(sent)
+		outboxRepo := NewOutboxRepo(nil, nil)
+		assert.NoError(t, outboxRepo.Apply(repositories.OpCreateOutbox, created))
+		assert.NoError(t, outboxRepo.Apply(repositories.OpUpdateOutbox, firstAttempt))
+		assert.NoError(t, outboxRepo.Apply(repositories.OpUpdateOutbox, secondAttempt))
+		assert.NoError(t, outboxRepo.Apply(repositories.OpCreateOutbox, created))
+		assert.NoError(t, outboxRepo.Apply(repositories.OpUpdateOutbox, firstAttempt))
+		assert.Equal(t, map[ksuid.KSUID]models.OutboxMessage{message.ID: sent}, outboxRepo.Entries())
+	})
+	t.Run("should ignore operations of other repositories", func(t *testing.T) {
+		outboxRepo := NewOutboxRepo(nil, nil)
+		assert.NoError(t, outboxRepo.Apply(repositories.OpCreateInvitation, []byte("not a message")))
+		assert.Empty(t, outboxRepo.messages)
+	})
+	t.Run("should return error for malformed payload", func(t *testing.T) {
+		outboxRepo := NewOutboxRepo(nil, nil)
+		assert.Error(t, outboxRepo.Apply(repositories.OpCreateOutbox, []byte("not a message")))
+	})
+}
+
+func TestOutboxRepo_Contract(t *testing.T) {
+	repotest.OutboxRepoSuite(t, func(t *testing.T) repositories.OutboxRepoInterface {
+		return NewOutboxRepo(nil, nil)
+	})
+}
+
+func TestOutboxRepo_JournalContract(t *testing.T) {
+	repotest.OutboxRepoSuite(t, func(t *testing.T) repositories.OutboxRepoInterface {
+		jsonDB, err := jsondb.NewJsonDB(filepath.Join(t.TempDir(), "survey_app.json"), filepath.Join(t.TempDir(), "survey_app.json.wal"), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewOutboxRepo(nil, jsonDB)
+	})
+}
@@ -5,24 +5,206 @@ package repositories
 import (
 	"errors"
 	"github.com/segmentio/ksuid"
+	"survey-platform/internal/db"
 	"survey-platform/internal/models"
+	"time"
 )
 
 var (
-	ErrNotFound = errors.New("resource not found")
+	ErrNotFound      = errors.New("resource not found")
+	ErrAlreadyExists = errors.New("resource already exists")
+	// ErrInvitationCompleted is returned when a response answers an invitation which was already answered
+	ErrInvitationCompleted = errors.New("invitation was already answered")
+	// ErrDraftSubmitted is returned when a draft is changed or submitted again after it was submitted
+	ErrDraftSubmitted = errors.New("draft was already submitted")
+	// ErrSurveyHasResponses is returned when a survey with responses is deleted without cascading to its responses
+	ErrSurveyHasResponses = errors.New("survey has responses")
 )
 
+// operations recorded in the journal by the repositories
+const (
+	OpCreateSurvey     = "survey.create"
+	OpUpdateSurvey     = "survey.update"
+	OpDeleteSurvey     = "survey.delete"
+	OpPurgeSurvey      = "survey.purge"
+	OpCreateResponse   = "response.create"
+	OpUpdateResponse   = "response.update"
+	OpDeleteResponse   = "response.delete"
+	OpCreateAPIKey     = "apikey.create"
+	OpRevokeAPIKey     = "apikey.revoke"
+	OpCreateUser       = "user.create"
+	OpUpdateUser       = "user.update"
+	OpDeleteUser       = "user.delete"
+	OpCreateRespondent = "respondent.create"
+	OpCreateInvitation = "invitation.create"
+	OpStartInvitation  = "invitation.start"
+	OpCreateOutbox     = "outbox.create"
+	OpUpdateOutbox     = "outbox.update"
+	OpCreateDraft      = "draft.create"
+	OpUpdateDraft      = "draft.update"
+	OpPurgeDrafts      = "draft.purge"
+	OpExpireDrafts     = "draft.expire"
+)
+
+// SurveyRepoInterface stores surveys along with their versions
+// creating or updating a survey stores it as the version in survey.Version, replacing a stored version with the same number
+// surveys are read and written within their workspace, a survey of another workspace is reported as ErrNotFound
 type SurveyRepoInterface interface {
+	// Create stores survey in survey.WorkspaceID, ErrAlreadyExists is returned when its id is taken in any workspace
 	Create(survey *models.Survey) (*models.Survey, error)
-	Get(id ksuid.KSUID) (*models.Survey, error)
-	Update(id ksuid.KSUID, survey *models.Survey) (*models.Survey, error)
-	Delete(id ksuid.KSUID) error
-	GetAll() ([]models.Survey, error)
+	Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error)
+	Update(workspace models.WorkspaceID, id ksuid.KSUID, survey *models.Survey) (*models.Survey, error)
+	// Delete removes the survey, ErrSurveyHasResponses is returned when it has responses unless cascade is set,
+	// in which case its responses are removed in the same operation
+	Delete(workspace models.WorkspaceID, id ksuid.KSUID, cascade bool) error
+	// GetAll returns the page of surveys selected by query, a query without a limit returns every matching survey
+	GetAll(workspace models.WorkspaceID, query models.SurveyQuery) (*models.SurveyPage, error)
+	// GetVersions returns every version of a survey oldest first, the last one is the current survey
+	GetVersions(workspace models.WorkspaceID, id ksuid.KSUID) ([]models.Survey, error)
+	// Entries and VersionEntries return the surveys of every workspace, they are meant for persisting the repo
 	Entries() map[ksuid.KSUID]models.Survey
+	VersionEntries() map[ksuid.KSUID][]models.Survey
 }
 
+// ResponseRepoInterface stores the responses of surveys, responses are read within their workspace
+// so the responses of a survey of another workspace are never returned
 type ResponseRepoInterface interface {
+	// Create stores response in response.WorkspaceID, backends with unique response ids return ErrAlreadyExists for a taken id
+	// a response with an InvitationID completes the invitation in the same operation, ErrNotFound is returned when the
+	// invitation is not one of the survey and ErrInvitationCompleted when it was already answered, storing nothing
+	// repos holding the surveys along with their responses return ErrNotFound when the survey is not stored,
+	// so that no response is stored once SurveyRepoInterface.Delete removed its survey
 	Create(response *models.Response) (*models.Response, error)
-	GetBySurveyID(surveyID ksuid.KSUID) ([]models.Response, error)
+	Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Response, error)
+	// Update replaces the survey version, answers, update time and history of the stored response with the id of response
+	// the rest of the response is kept, ErrNotFound is returned when it is not stored
+	Update(response *models.Response) (*models.Response, error)
+	// Delete removes the response, the invitation it answered stays completed
+	Delete(workspace models.WorkspaceID, id ksuid.KSUID) error
+	GetBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) ([]models.Response, error)
+	// GetAll returns the page of responses selected by query, a query without a limit returns every matching response
+	GetAll(workspace models.WorkspaceID, query models.ResponseQuery) (*models.ResponsePage, error)
+	CountBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) (int, error)
+	// EachBySurveyID calls fn with every response of a survey ordered by creation time, without loading them all at once
+	// it stops at the first error returned by fn and returns it, a survey without responses is not an error
+	EachBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID, fn func(models.Response) error) error
+	// Sets returns a summary of the responses of every survey of every workspace which has responses
+	Sets() ([]models.ResponseSet, error)
+	// Entries returns the responses of every workspace, it is meant for persisting the repo
 	Entries() map[ksuid.KSUID][]models.Response
 }
+
+// APIKeyRepoInterface stores api keys, revoked keys are kept so that they can still be listed
+// keys are managed within their workspace, a key of another workspace is reported as ErrNotFound
+type APIKeyRepoInterface interface {
+	Create(key *models.APIKey) (*models.APIKey, error)
+	Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.APIKey, error)
+	// GetByHash returns the key whose hash is hash, revoked or not, whatever its workspace
+	GetByHash(hash string) (*models.APIKey, error)
+	// GetAll returns every key of the workspace ordered by creation time
+	GetAll(workspace models.WorkspaceID) ([]models.APIKey, error)
+	// Revoke marks the key as revoked at revokedAt, revoking a revoked key keeps the first revocation time
+	Revoke(workspace models.WorkspaceID, id ksuid.KSUID, revokedAt time.Time) (*models.APIKey, error)
+	Entries() map[ksuid.KSUID]models.APIKey
+}
+
+// UserRepoInterface stores the users of workspaces along with their roles
+// users are managed within their workspace, a user of another workspace is reported as ErrNotFound
+type UserRepoInterface interface {
+	Create(user *models.User) (*models.User, error)
+	Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.User, error)
+	// GetAll returns every user of the workspace ordered by creation time
+	GetAll(workspace models.WorkspaceID) ([]models.User, error)
+	// Update replaces the name, email and roles of the user
+	Update(workspace models.WorkspaceID, id ksuid.KSUID, user *models.User) (*models.User, error)
+	Delete(workspace models.WorkspaceID, id ksuid.KSUID) error
+	Entries() map[ksuid.KSUID]models.User
+}
+
+// RespondentRepoInterface stores the respondents of workspaces, the email of a respondent is unique within its workspace
+// respondents are read within their workspace, a respondent of another workspace is reported as ErrNotFound
+type RespondentRepoInterface interface {
+	// Create returns ErrAlreadyExists when the id or the email of the respondent is taken
+	Create(respondent *models.Respondent) (*models.Respondent, error)
+	Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Respondent, error)
+	GetByEmail(workspace models.WorkspaceID, email string) (*models.Respondent, error)
+	Entries() map[ksuid.KSUID]models.Respondent
+}
+
+// InvitationRepoInterface stores the invitations of respondents to surveys, a respondent is invited once to a survey
+// invitations are read within their workspace, an invitation of another workspace is reported as ErrNotFound
+// invitations are completed by ResponseRepoInterface.Create along with the response answering them
+type InvitationRepoInterface interface {
+	// Create returns ErrAlreadyExists when the id is taken or the respondent is already invited to the survey
+	Create(invitation *models.Invitation) (*models.Invitation, error)
+	Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Invitation, error)
+	// GetBySurveyID returns every invitation to a survey ordered by creation time
+	GetBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) ([]models.Invitation, error)
+	// Start marks the invitation as started at startedAt, starting a started invitation keeps the first time
+	Start(workspace models.WorkspaceID, id ksuid.KSUID, startedAt time.Time) (*models.Invitation, error)
+	// GetPending returns the invitations of every workspace which were not completed, ordered by creation time
+	GetPending() ([]models.Invitation, error)
+	Entries() map[ksuid.KSUID]models.Invitation
+}
+
+// OutboxRepoInterface stores the emails sent to the respondents of invitations until they are delivered
+// messages are read within their workspace, except for Due which feeds the delivery of every workspace
+type OutboxRepoInterface interface {
+	// Create queues message, ErrAlreadyExists is returned when its id is taken
+	Create(message *models.OutboxMessage) (*models.OutboxMessage, error)
+	// Due returns up to limit pending messages of every workspace whose next attempt is at or before now, earliest attempt first
+	Due(now time.Time, limit int) ([]models.OutboxMessage, error)
+	// GetByInvitationID returns the messages sent for an invitation ordered by creation time
+	GetByInvitationID(workspace models.WorkspaceID, invitationID ksuid.KSUID) ([]models.OutboxMessage, error)
+	// Update stores the delivery state of message, its attempts, next attempt, last error, sent and failed times
+	// the rest of the message is never updated, ErrNotFound is returned when it is not stored
+	Update(message *models.OutboxMessage) error
+	Entries() map[ksuid.KSUID]models.OutboxMessage
+}
+
+// DraftRepoInterface stores the drafts of responses, drafts are read within their workspace
+// and a draft of another workspace is reported as ErrNotFound
+type DraftRepoInterface interface {
+	// Create returns ErrAlreadyExists when the id of the draft is taken
+	Create(draft *models.Draft) (*models.Draft, error)
+	Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Draft, error)
+	// GetBySurveyID returns every draft of a survey, submitted or not, ordered by creation time
+	GetBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) ([]models.Draft, error)
+	// Update replaces the survey version, answers, update and expiry times of the draft, the rest of the draft is kept
+	// ErrNotFound is returned when it is not stored and ErrDraftSubmitted when it was submitted
+	Update(draft *models.Draft) error
+	// Submit stores response and marks the draft with the id of the response as submitted in the same operation
+	// the response goes through ResponseRepoInterface.Create, so it completes its invitation as well
+	// ErrNotFound is returned when the draft is not stored and ErrDraftSubmitted when it was submitted, storing nothing
+	Submit(response *models.Response) (*models.Response, error)
+	// DeleteBySurveyID deletes every draft of a survey, submitted or not, along with its expired drafts
+	DeleteBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) error
+	// DeleteExpired deletes the drafts of every workspace left unsubmitted with an expiry before cutoff and returns how many
+	// were deleted, the deleted drafts are added to the expired drafts of their surveys
+	DeleteExpired(cutoff time.Time) (int, error)
+	// GetExpired returns the expired drafts of a survey which were deleted, the tally is empty when there are none
+	GetExpired(workspace models.WorkspaceID, surveyID ksuid.KSUID) (*models.ExpiredDrafts, error)
+	Entries() map[ksuid.KSUID]models.Draft
+	// EntriesWithExpired returns the stored drafts along with the expired drafts of every survey, read at once
+	// so that a deleted draft is either among the drafts or tallied in the expired drafts but never both
+	EntriesWithExpired() (map[ksuid.KSUID]models.Draft, []models.ExpiredDrafts)
+}
+
+// Applier applies a journaled operation to a repository without journaling it again
+// operations owned by other repositories are ignored
+// applying an operation which is already reflected in the repository must leave it unchanged
+type Applier interface {
+	Apply(op string, payload []byte) error
+}
+
+// Replay applies every operation in the journal to the appliers in order
+func Replay(journal db.Journal, appliers ...Applier) error {
+	return journal.Replay(func(op string, payload []byte) error {
+		for _, applier := range appliers {
+			if err := applier.Apply(op, payload); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
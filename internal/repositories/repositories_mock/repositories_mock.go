@@ -7,6 +7,7 @@ package repositories_mock
 import (
 	reflect "reflect"
 	models "survey-platform/internal/models"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	ksuid "github.com/segmentio/ksuid"
@@ -51,17 +52,17 @@ func (mr *MockSurveyRepoInterfaceMockRecorder) Create(survey interface{}) *gomoc
 }
 
 // Delete mocks base method.
-func (m *MockSurveyRepoInterface) Delete(id ksuid.KSUID) error {
+func (m *MockSurveyRepoInterface) Delete(workspace models.WorkspaceID, id ksuid.KSUID, cascade bool) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", id)
+	ret := m.ctrl.Call(m, "Delete", workspace, id, cascade)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockSurveyRepoInterfaceMockRecorder) Delete(id interface{}) *gomock.Call {
+func (mr *MockSurveyRepoInterfaceMockRecorder) Delete(workspace, id, cascade interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockSurveyRepoInterface)(nil).Delete), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockSurveyRepoInterface)(nil).Delete), workspace, id, cascade)
 }
 
 // Entries mocks base method.
@@ -79,48 +80,77 @@ func (mr *MockSurveyRepoInterfaceMockRecorder) Entries() *gomock.Call {
 }
 
 // Get mocks base method.
-func (m *MockSurveyRepoInterface) Get(id ksuid.KSUID) (*models.Survey, error) {
+func (m *MockSurveyRepoInterface) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", id)
+	ret := m.ctrl.Call(m, "Get", workspace, id)
 	ret0, _ := ret[0].(*models.Survey)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Get indicates an expected call of Get.
-func (mr *MockSurveyRepoInterfaceMockRecorder) Get(id interface{}) *gomock.Call {
+func (mr *MockSurveyRepoInterfaceMockRecorder) Get(workspace, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockSurveyRepoInterface)(nil).Get), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockSurveyRepoInterface)(nil).Get), workspace, id)
 }
 
 // GetAll mocks base method.
-func (m *MockSurveyRepoInterface) GetAll() ([]models.Survey, error) {
+func (m *MockSurveyRepoInterface) GetAll(workspace models.WorkspaceID, query models.SurveyQuery) (*models.SurveyPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll")
-	ret0, _ := ret[0].([]models.Survey)
+	ret := m.ctrl.Call(m, "GetAll", workspace, query)
+	ret0, _ := ret[0].(*models.SurveyPage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAll indicates an expected call of GetAll.
-func (mr *MockSurveyRepoInterfaceMockRecorder) GetAll() *gomock.Call {
+func (mr *MockSurveyRepoInterfaceMockRecorder) GetAll(workspace, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockSurveyRepoInterface)(nil).GetAll), workspace, query)
+}
+
+// GetVersions mocks base method.
+func (m *MockSurveyRepoInterface) GetVersions(workspace models.WorkspaceID, id ksuid.KSUID) ([]models.Survey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVersions", workspace, id)
+	ret0, _ := ret[0].([]models.Survey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVersions indicates an expected call of GetVersions.
+func (mr *MockSurveyRepoInterfaceMockRecorder) GetVersions(workspace, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockSurveyRepoInterface)(nil).GetAll))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVersions", reflect.TypeOf((*MockSurveyRepoInterface)(nil).GetVersions), workspace, id)
 }
 
 // Update mocks base method.
-func (m *MockSurveyRepoInterface) Update(id ksuid.KSUID, survey *models.Survey) (*models.Survey, error) {
+func (m *MockSurveyRepoInterface) Update(workspace models.WorkspaceID, id ksuid.KSUID, survey *models.Survey) (*models.Survey, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", id, survey)
+	ret := m.ctrl.Call(m, "Update", workspace, id, survey)
 	ret0, _ := ret[0].(*models.Survey)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockSurveyRepoInterfaceMockRecorder) Update(id, survey interface{}) *gomock.Call {
+func (mr *MockSurveyRepoInterfaceMockRecorder) Update(workspace, id, survey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockSurveyRepoInterface)(nil).Update), workspace, id, survey)
+}
+
+// VersionEntries mocks base method.
+func (m *MockSurveyRepoInterface) VersionEntries() map[ksuid.KSUID][]models.Survey {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VersionEntries")
+	ret0, _ := ret[0].(map[ksuid.KSUID][]models.Survey)
+	return ret0
+}
+
+// VersionEntries indicates an expected call of VersionEntries.
+func (mr *MockSurveyRepoInterfaceMockRecorder) VersionEntries() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockSurveyRepoInterface)(nil).Update), id, survey)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VersionEntries", reflect.TypeOf((*MockSurveyRepoInterface)(nil).VersionEntries))
 }
 
 // MockResponseRepoInterface is a mock of ResponseRepoInterface interface.
@@ -146,6 +176,21 @@ func (m *MockResponseRepoInterface) EXPECT() *MockResponseRepoInterfaceMockRecor
 	return m.recorder
 }
 
+// CountBySurveyID mocks base method.
+func (m *MockResponseRepoInterface) CountBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountBySurveyID", workspace, surveyID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountBySurveyID indicates an expected call of CountBySurveyID.
+func (mr *MockResponseRepoInterfaceMockRecorder) CountBySurveyID(workspace, surveyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountBySurveyID", reflect.TypeOf((*MockResponseRepoInterface)(nil).CountBySurveyID), workspace, surveyID)
+}
+
 // Create mocks base method.
 func (m *MockResponseRepoInterface) Create(response *models.Response) (*models.Response, error) {
 	m.ctrl.T.Helper()
@@ -161,6 +206,34 @@ func (mr *MockResponseRepoInterfaceMockRecorder) Create(response interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockResponseRepoInterface)(nil).Create), response)
 }
 
+// Delete mocks base method.
+func (m *MockResponseRepoInterface) Delete(workspace models.WorkspaceID, id ksuid.KSUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", workspace, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockResponseRepoInterfaceMockRecorder) Delete(workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockResponseRepoInterface)(nil).Delete), workspace, id)
+}
+
+// EachBySurveyID mocks base method.
+func (m *MockResponseRepoInterface) EachBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID, fn func(models.Response) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EachBySurveyID", workspace, surveyID, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EachBySurveyID indicates an expected call of EachBySurveyID.
+func (mr *MockResponseRepoInterfaceMockRecorder) EachBySurveyID(workspace, surveyID, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EachBySurveyID", reflect.TypeOf((*MockResponseRepoInterface)(nil).EachBySurveyID), workspace, surveyID, fn)
+}
+
 // Entries mocks base method.
 func (m *MockResponseRepoInterface) Entries() map[ksuid.KSUID][]models.Response {
 	m.ctrl.T.Helper()
@@ -175,17 +248,797 @@ func (mr *MockResponseRepoInterfaceMockRecorder) Entries() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Entries", reflect.TypeOf((*MockResponseRepoInterface)(nil).Entries))
 }
 
+// Get mocks base method.
+func (m *MockResponseRepoInterface) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", workspace, id)
+	ret0, _ := ret[0].(*models.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockResponseRepoInterfaceMockRecorder) Get(workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockResponseRepoInterface)(nil).Get), workspace, id)
+}
+
+// GetAll mocks base method.
+func (m *MockResponseRepoInterface) GetAll(workspace models.WorkspaceID, query models.ResponseQuery) (*models.ResponsePage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", workspace, query)
+	ret0, _ := ret[0].(*models.ResponsePage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockResponseRepoInterfaceMockRecorder) GetAll(workspace, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockResponseRepoInterface)(nil).GetAll), workspace, query)
+}
+
 // GetBySurveyID mocks base method.
-func (m *MockResponseRepoInterface) GetBySurveyID(surveyID ksuid.KSUID) ([]models.Response, error) {
+func (m *MockResponseRepoInterface) GetBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) ([]models.Response, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetBySurveyID", surveyID)
+	ret := m.ctrl.Call(m, "GetBySurveyID", workspace, surveyID)
 	ret0, _ := ret[0].([]models.Response)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetBySurveyID indicates an expected call of GetBySurveyID.
-func (mr *MockResponseRepoInterfaceMockRecorder) GetBySurveyID(surveyID interface{}) *gomock.Call {
+func (mr *MockResponseRepoInterfaceMockRecorder) GetBySurveyID(workspace, surveyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBySurveyID", reflect.TypeOf((*MockResponseRepoInterface)(nil).GetBySurveyID), workspace, surveyID)
+}
+
+// Sets mocks base method.
+func (m *MockResponseRepoInterface) Sets() ([]models.ResponseSet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Sets")
+	ret0, _ := ret[0].([]models.ResponseSet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Sets indicates an expected call of Sets.
+func (mr *MockResponseRepoInterfaceMockRecorder) Sets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sets", reflect.TypeOf((*MockResponseRepoInterface)(nil).Sets))
+}
+
+// Update mocks base method.
+func (m *MockResponseRepoInterface) Update(response *models.Response) (*models.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", response)
+	ret0, _ := ret[0].(*models.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockResponseRepoInterfaceMockRecorder) Update(response interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockResponseRepoInterface)(nil).Update), response)
+}
+
+// MockAPIKeyRepoInterface is a mock of APIKeyRepoInterface interface.
+type MockAPIKeyRepoInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPIKeyRepoInterfaceMockRecorder
+}
+
+// MockAPIKeyRepoInterfaceMockRecorder is the mock recorder for MockAPIKeyRepoInterface.
+type MockAPIKeyRepoInterfaceMockRecorder struct {
+	mock *MockAPIKeyRepoInterface
+}
+
+// NewMockAPIKeyRepoInterface creates a new mock instance.
+func NewMockAPIKeyRepoInterface(ctrl *gomock.Controller) *MockAPIKeyRepoInterface {
+	mock := &MockAPIKeyRepoInterface{ctrl: ctrl}
+	mock.recorder = &MockAPIKeyRepoInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAPIKeyRepoInterface) EXPECT() *MockAPIKeyRepoInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockAPIKeyRepoInterface) Create(key *models.APIKey) (*models.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", key)
+	ret0, _ := ret[0].(*models.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockAPIKeyRepoInterfaceMockRecorder) Create(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockAPIKeyRepoInterface)(nil).Create), key)
+}
+
+// Entries mocks base method.
+func (m *MockAPIKeyRepoInterface) Entries() map[ksuid.KSUID]models.APIKey {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Entries")
+	ret0, _ := ret[0].(map[ksuid.KSUID]models.APIKey)
+	return ret0
+}
+
+// Entries indicates an expected call of Entries.
+func (mr *MockAPIKeyRepoInterfaceMockRecorder) Entries() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Entries", reflect.TypeOf((*MockAPIKeyRepoInterface)(nil).Entries))
+}
+
+// Get mocks base method.
+func (m *MockAPIKeyRepoInterface) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", workspace, id)
+	ret0, _ := ret[0].(*models.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockAPIKeyRepoInterfaceMockRecorder) Get(workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockAPIKeyRepoInterface)(nil).Get), workspace, id)
+}
+
+// GetAll mocks base method.
+func (m *MockAPIKeyRepoInterface) GetAll(workspace models.WorkspaceID) ([]models.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", workspace)
+	ret0, _ := ret[0].([]models.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockAPIKeyRepoInterfaceMockRecorder) GetAll(workspace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockAPIKeyRepoInterface)(nil).GetAll), workspace)
+}
+
+// GetByHash mocks base method.
+func (m *MockAPIKeyRepoInterface) GetByHash(hash string) (*models.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByHash", hash)
+	ret0, _ := ret[0].(*models.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByHash indicates an expected call of GetByHash.
+func (mr *MockAPIKeyRepoInterfaceMockRecorder) GetByHash(hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByHash", reflect.TypeOf((*MockAPIKeyRepoInterface)(nil).GetByHash), hash)
+}
+
+// Revoke mocks base method.
+func (m *MockAPIKeyRepoInterface) Revoke(workspace models.WorkspaceID, id ksuid.KSUID, revokedAt time.Time) (*models.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", workspace, id, revokedAt)
+	ret0, _ := ret[0].(*models.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockAPIKeyRepoInterfaceMockRecorder) Revoke(workspace, id, revokedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockAPIKeyRepoInterface)(nil).Revoke), workspace, id, revokedAt)
+}
+
+// MockUserRepoInterface is a mock of UserRepoInterface interface.
+type MockUserRepoInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserRepoInterfaceMockRecorder
+}
+
+// MockUserRepoInterfaceMockRecorder is the mock recorder for MockUserRepoInterface.
+type MockUserRepoInterfaceMockRecorder struct {
+	mock *MockUserRepoInterface
+}
+
+// NewMockUserRepoInterface creates a new mock instance.
+func NewMockUserRepoInterface(ctrl *gomock.Controller) *MockUserRepoInterface {
+	mock := &MockUserRepoInterface{ctrl: ctrl}
+	mock.recorder = &MockUserRepoInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserRepoInterface) EXPECT() *MockUserRepoInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockUserRepoInterface) Create(user *models.User) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", user)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockUserRepoInterfaceMockRecorder) Create(user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUserRepoInterface)(nil).Create), user)
+}
+
+// Delete mocks base method.
+func (m *MockUserRepoInterface) Delete(workspace models.WorkspaceID, id ksuid.KSUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", workspace, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockUserRepoInterfaceMockRecorder) Delete(workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserRepoInterface)(nil).Delete), workspace, id)
+}
+
+// Entries mocks base method.
+func (m *MockUserRepoInterface) Entries() map[ksuid.KSUID]models.User {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Entries")
+	ret0, _ := ret[0].(map[ksuid.KSUID]models.User)
+	return ret0
+}
+
+// Entries indicates an expected call of Entries.
+func (mr *MockUserRepoInterfaceMockRecorder) Entries() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Entries", reflect.TypeOf((*MockUserRepoInterface)(nil).Entries))
+}
+
+// Get mocks base method.
+func (m *MockUserRepoInterface) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", workspace, id)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockUserRepoInterfaceMockRecorder) Get(workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockUserRepoInterface)(nil).Get), workspace, id)
+}
+
+// GetAll mocks base method.
+func (m *MockUserRepoInterface) GetAll(workspace models.WorkspaceID) ([]models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", workspace)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockUserRepoInterfaceMockRecorder) GetAll(workspace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockUserRepoInterface)(nil).GetAll), workspace)
+}
+
+// Update mocks base method.
+func (m *MockUserRepoInterface) Update(workspace models.WorkspaceID, id ksuid.KSUID, user *models.User) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", workspace, id, user)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockUserRepoInterfaceMockRecorder) Update(workspace, id, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUserRepoInterface)(nil).Update), workspace, id, user)
+}
+
+// MockRespondentRepoInterface is a mock of RespondentRepoInterface interface.
+type MockRespondentRepoInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockRespondentRepoInterfaceMockRecorder
+}
+
+// MockRespondentRepoInterfaceMockRecorder is the mock recorder for MockRespondentRepoInterface.
+type MockRespondentRepoInterfaceMockRecorder struct {
+	mock *MockRespondentRepoInterface
+}
+
+// NewMockRespondentRepoInterface creates a new mock instance.
+func NewMockRespondentRepoInterface(ctrl *gomock.Controller) *MockRespondentRepoInterface {
+	mock := &MockRespondentRepoInterface{ctrl: ctrl}
+	mock.recorder = &MockRespondentRepoInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRespondentRepoInterface) EXPECT() *MockRespondentRepoInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockRespondentRepoInterface) Create(respondent *models.Respondent) (*models.Respondent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", respondent)
+	ret0, _ := ret[0].(*models.Respondent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockRespondentRepoInterfaceMockRecorder) Create(respondent interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRespondentRepoInterface)(nil).Create), respondent)
+}
+
+// Entries mocks base method.
+func (m *MockRespondentRepoInterface) Entries() map[ksuid.KSUID]models.Respondent {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Entries")
+	ret0, _ := ret[0].(map[ksuid.KSUID]models.Respondent)
+	return ret0
+}
+
+// Entries indicates an expected call of Entries.
+func (mr *MockRespondentRepoInterfaceMockRecorder) Entries() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Entries", reflect.TypeOf((*MockRespondentRepoInterface)(nil).Entries))
+}
+
+// Get mocks base method.
+func (m *MockRespondentRepoInterface) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Respondent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", workspace, id)
+	ret0, _ := ret[0].(*models.Respondent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockRespondentRepoInterfaceMockRecorder) Get(workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockRespondentRepoInterface)(nil).Get), workspace, id)
+}
+
+// GetByEmail mocks base method.
+func (m *MockRespondentRepoInterface) GetByEmail(workspace models.WorkspaceID, email string) (*models.Respondent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByEmail", workspace, email)
+	ret0, _ := ret[0].(*models.Respondent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByEmail indicates an expected call of GetByEmail.
+func (mr *MockRespondentRepoInterfaceMockRecorder) GetByEmail(workspace, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByEmail", reflect.TypeOf((*MockRespondentRepoInterface)(nil).GetByEmail), workspace, email)
+}
+
+// MockInvitationRepoInterface is a mock of InvitationRepoInterface interface.
+type MockInvitationRepoInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockInvitationRepoInterfaceMockRecorder
+}
+
+// MockInvitationRepoInterfaceMockRecorder is the mock recorder for MockInvitationRepoInterface.
+type MockInvitationRepoInterfaceMockRecorder struct {
+	mock *MockInvitationRepoInterface
+}
+
+// NewMockInvitationRepoInterface creates a new mock instance.
+func NewMockInvitationRepoInterface(ctrl *gomock.Controller) *MockInvitationRepoInterface {
+	mock := &MockInvitationRepoInterface{ctrl: ctrl}
+	mock.recorder = &MockInvitationRepoInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInvitationRepoInterface) EXPECT() *MockInvitationRepoInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockInvitationRepoInterface) Create(invitation *models.Invitation) (*models.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", invitation)
+	ret0, _ := ret[0].(*models.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockInvitationRepoInterfaceMockRecorder) Create(invitation interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockInvitationRepoInterface)(nil).Create), invitation)
+}
+
+// Entries mocks base method.
+func (m *MockInvitationRepoInterface) Entries() map[ksuid.KSUID]models.Invitation {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Entries")
+	ret0, _ := ret[0].(map[ksuid.KSUID]models.Invitation)
+	return ret0
+}
+
+// Entries indicates an expected call of Entries.
+func (mr *MockInvitationRepoInterfaceMockRecorder) Entries() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Entries", reflect.TypeOf((*MockInvitationRepoInterface)(nil).Entries))
+}
+
+// Get mocks base method.
+func (m *MockInvitationRepoInterface) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", workspace, id)
+	ret0, _ := ret[0].(*models.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockInvitationRepoInterfaceMockRecorder) Get(workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockInvitationRepoInterface)(nil).Get), workspace, id)
+}
+
+// GetBySurveyID mocks base method.
+func (m *MockInvitationRepoInterface) GetBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) ([]models.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBySurveyID", workspace, surveyID)
+	ret0, _ := ret[0].([]models.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBySurveyID indicates an expected call of GetBySurveyID.
+func (mr *MockInvitationRepoInterfaceMockRecorder) GetBySurveyID(workspace, surveyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBySurveyID", reflect.TypeOf((*MockInvitationRepoInterface)(nil).GetBySurveyID), workspace, surveyID)
+}
+
+// GetPending mocks base method.
+func (m *MockInvitationRepoInterface) GetPending() ([]models.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPending")
+	ret0, _ := ret[0].([]models.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPending indicates an expected call of GetPending.
+func (mr *MockInvitationRepoInterfaceMockRecorder) GetPending() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPending", reflect.TypeOf((*MockInvitationRepoInterface)(nil).GetPending))
+}
+
+// Start mocks base method.
+func (m *MockInvitationRepoInterface) Start(workspace models.WorkspaceID, id ksuid.KSUID, startedAt time.Time) (*models.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Start", workspace, id, startedAt)
+	ret0, _ := ret[0].(*models.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Start indicates an expected call of Start.
+func (mr *MockInvitationRepoInterfaceMockRecorder) Start(workspace, id, startedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockInvitationRepoInterface)(nil).Start), workspace, id, startedAt)
+}
+
+// MockOutboxRepoInterface is a mock of OutboxRepoInterface interface.
+type MockOutboxRepoInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutboxRepoInterfaceMockRecorder
+}
+
+// MockOutboxRepoInterfaceMockRecorder is the mock recorder for MockOutboxRepoInterface.
+type MockOutboxRepoInterfaceMockRecorder struct {
+	mock *MockOutboxRepoInterface
+}
+
+// NewMockOutboxRepoInterface creates a new mock instance.
+func NewMockOutboxRepoInterface(ctrl *gomock.Controller) *MockOutboxRepoInterface {
+	mock := &MockOutboxRepoInterface{ctrl: ctrl}
+	mock.recorder = &MockOutboxRepoInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOutboxRepoInterface) EXPECT() *MockOutboxRepoInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockOutboxRepoInterface) Create(message *models.OutboxMessage) (*models.OutboxMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", message)
+	ret0, _ := ret[0].(*models.OutboxMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockOutboxRepoInterfaceMockRecorder) Create(message interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockOutboxRepoInterface)(nil).Create), message)
+}
+
+// Due mocks base method.
+func (m *MockOutboxRepoInterface) Due(now time.Time, limit int) ([]models.OutboxMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Due", now, limit)
+	ret0, _ := ret[0].([]models.OutboxMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Due indicates an expected call of Due.
+func (mr *MockOutboxRepoInterfaceMockRecorder) Due(now, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Due", reflect.TypeOf((*MockOutboxRepoInterface)(nil).Due), now, limit)
+}
+
+// Entries mocks base method.
+func (m *MockOutboxRepoInterface) Entries() map[ksuid.KSUID]models.OutboxMessage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Entries")
+	ret0, _ := ret[0].(map[ksuid.KSUID]models.OutboxMessage)
+	return ret0
+}
+
+// Entries indicates an expected call of Entries.
+func (mr *MockOutboxRepoInterfaceMockRecorder) Entries() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Entries", reflect.TypeOf((*MockOutboxRepoInterface)(nil).Entries))
+}
+
+// GetByInvitationID mocks base method.
+func (m *MockOutboxRepoInterface) GetByInvitationID(workspace models.WorkspaceID, invitationID ksuid.KSUID) ([]models.OutboxMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByInvitationID", workspace, invitationID)
+	ret0, _ := ret[0].([]models.OutboxMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByInvitationID indicates an expected call of GetByInvitationID.
+func (mr *MockOutboxRepoInterfaceMockRecorder) GetByInvitationID(workspace, invitationID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByInvitationID", reflect.TypeOf((*MockOutboxRepoInterface)(nil).GetByInvitationID), workspace, invitationID)
+}
+
+// Update mocks base method.
+func (m *MockOutboxRepoInterface) Update(message *models.OutboxMessage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", message)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockOutboxRepoInterfaceMockRecorder) Update(message interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockOutboxRepoInterface)(nil).Update), message)
+}
+
+// MockDraftRepoInterface is a mock of DraftRepoInterface interface.
+type MockDraftRepoInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockDraftRepoInterfaceMockRecorder
+}
+
+// MockDraftRepoInterfaceMockRecorder is the mock recorder for MockDraftRepoInterface.
+type MockDraftRepoInterfaceMockRecorder struct {
+	mock *MockDraftRepoInterface
+}
+
+// NewMockDraftRepoInterface creates a new mock instance.
+func NewMockDraftRepoInterface(ctrl *gomock.Controller) *MockDraftRepoInterface {
+	mock := &MockDraftRepoInterface{ctrl: ctrl}
+	mock.recorder = &MockDraftRepoInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDraftRepoInterface) EXPECT() *MockDraftRepoInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockDraftRepoInterface) Create(draft *models.Draft) (*models.Draft, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", draft)
+	ret0, _ := ret[0].(*models.Draft)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockDraftRepoInterfaceMockRecorder) Create(draft interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockDraftRepoInterface)(nil).Create), draft)
+}
+
+// DeleteBySurveyID mocks base method.
+func (m *MockDraftRepoInterface) DeleteBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBySurveyID", workspace, surveyID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteBySurveyID indicates an expected call of DeleteBySurveyID.
+func (mr *MockDraftRepoInterfaceMockRecorder) DeleteBySurveyID(workspace, surveyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBySurveyID", reflect.TypeOf((*MockDraftRepoInterface)(nil).DeleteBySurveyID), workspace, surveyID)
+}
+
+// DeleteExpired mocks base method.
+func (m *MockDraftRepoInterface) DeleteExpired(cutoff time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpired", cutoff)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpired indicates an expected call of DeleteExpired.
+func (mr *MockDraftRepoInterfaceMockRecorder) DeleteExpired(cutoff interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpired", reflect.TypeOf((*MockDraftRepoInterface)(nil).DeleteExpired), cutoff)
+}
+
+// Entries mocks base method.
+func (m *MockDraftRepoInterface) Entries() map[ksuid.KSUID]models.Draft {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Entries")
+	ret0, _ := ret[0].(map[ksuid.KSUID]models.Draft)
+	return ret0
+}
+
+// Entries indicates an expected call of Entries.
+func (mr *MockDraftRepoInterfaceMockRecorder) Entries() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Entries", reflect.TypeOf((*MockDraftRepoInterface)(nil).Entries))
+}
+
+// EntriesWithExpired mocks base method.
+func (m *MockDraftRepoInterface) EntriesWithExpired() (map[ksuid.KSUID]models.Draft, []models.ExpiredDrafts) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EntriesWithExpired")
+	ret0, _ := ret[0].(map[ksuid.KSUID]models.Draft)
+	ret1, _ := ret[1].([]models.ExpiredDrafts)
+	return ret0, ret1
+}
+
+// EntriesWithExpired indicates an expected call of EntriesWithExpired.
+func (mr *MockDraftRepoInterfaceMockRecorder) EntriesWithExpired() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EntriesWithExpired", reflect.TypeOf((*MockDraftRepoInterface)(nil).EntriesWithExpired))
+}
+
+// Get mocks base method.
+func (m *MockDraftRepoInterface) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Draft, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", workspace, id)
+	ret0, _ := ret[0].(*models.Draft)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockDraftRepoInterfaceMockRecorder) Get(workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockDraftRepoInterface)(nil).Get), workspace, id)
+}
+
+// GetBySurveyID mocks base method.
+func (m *MockDraftRepoInterface) GetBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) ([]models.Draft, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBySurveyID", workspace, surveyID)
+	ret0, _ := ret[0].([]models.Draft)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBySurveyID indicates an expected call of GetBySurveyID.
+func (mr *MockDraftRepoInterfaceMockRecorder) GetBySurveyID(workspace, surveyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBySurveyID", reflect.TypeOf((*MockDraftRepoInterface)(nil).GetBySurveyID), workspace, surveyID)
+}
+
+// GetExpired mocks base method.
+func (m *MockDraftRepoInterface) GetExpired(workspace models.WorkspaceID, surveyID ksuid.KSUID) (*models.ExpiredDrafts, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpired", workspace, surveyID)
+	ret0, _ := ret[0].(*models.ExpiredDrafts)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpired indicates an expected call of GetExpired.
+func (mr *MockDraftRepoInterfaceMockRecorder) GetExpired(workspace, surveyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpired", reflect.TypeOf((*MockDraftRepoInterface)(nil).GetExpired), workspace, surveyID)
+}
+
+// Submit mocks base method.
+func (m *MockDraftRepoInterface) Submit(response *models.Response) (*models.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Submit", response)
+	ret0, _ := ret[0].(*models.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Submit indicates an expected call of Submit.
+func (mr *MockDraftRepoInterfaceMockRecorder) Submit(response interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Submit", reflect.TypeOf((*MockDraftRepoInterface)(nil).Submit), response)
+}
+
+// Update mocks base method.
+func (m *MockDraftRepoInterface) Update(draft *models.Draft) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", draft)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockDraftRepoInterfaceMockRecorder) Update(draft interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockDraftRepoInterface)(nil).Update), draft)
+}
+
+// MockApplier is a mock of Applier interface.
+type MockApplier struct {
+	ctrl     *gomock.Controller
+	recorder *MockApplierMockRecorder
+}
+
+// MockApplierMockRecorder is the mock recorder for MockApplier.
+type MockApplierMockRecorder struct {
+	mock *MockApplier
+}
+
+// NewMockApplier creates a new mock instance.
+func NewMockApplier(ctrl *gomock.Controller) *MockApplier {
+	mock := &MockApplier{ctrl: ctrl}
+	mock.recorder = &MockApplierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockApplier) EXPECT() *MockApplierMockRecorder {
+	return m.recorder
+}
+
+// Apply mocks base method.
+func (m *MockApplier) Apply(op string, payload []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Apply", op, payload)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Apply indicates an expected call of Apply.
+func (mr *MockApplierMockRecorder) Apply(op, payload interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBySurveyID", reflect.TypeOf((*MockResponseRepoInterface)(nil).GetBySurveyID), surveyID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Apply", reflect.TypeOf((*MockApplier)(nil).Apply), op, payload)
 }
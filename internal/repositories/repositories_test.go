@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"survey-platform/internal/db/db_mock"
+	"testing"
+)
+
+type recordingApplier struct {
+	applied []string
+	err     error
+}
+
+func (r *recordingApplier) Apply(op string, payload []byte) error {
+	r.applied = append(r.applied, op+":"+string(payload))
+	return r.err
+}
+
+func replayJournal(ctrl *gomock.Controller, ops ...string) *db_mock.MockJournal {
+	mockJournal := db_mock.NewMockJournal(ctrl)
+	mockJournal.EXPECT().Replay(gomock.Any()).DoAndReturn(func(apply func(op string, payload []byte) error) error {
+		for _, op := range ops {
+			if err := apply(op, []byte("{}")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return mockJournal
+}
+
+func TestReplay(t *testing.T) {
+	t.Run("should hand every journaled operation to every applier in order", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockJournal := replayJournal(ctrl, OpCreateSurvey, OpCreateResponse)
+		surveys, responses := &recordingApplier{}, &recordingApplier{}
+		err := Replay(mockJournal, surveys, responses)
+		assert.NoError(t, err)
+		expected := []string{OpCreateSurvey + ":{}", OpCreateResponse + ":{}"}
+		assert.Equal(t, expected, surveys.applied)
+		assert.Equal(t, expected, responses.applied)
+	})
+	t.Run("should stop replaying when an applier returns error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockJournal := replayJournal(ctrl, OpCreateSurvey, OpCreateResponse)
+		surveys, responses := &recordingApplier{err: errors.New("something went wrong")}, &recordingApplier{}
+		err := Replay(mockJournal, surveys, responses)
+		assert.Error(t, err)
+		assert.Len(t, surveys.applied, 1)
+		assert.Empty(t, responses.applied)
+	})
+}
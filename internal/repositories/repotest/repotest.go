@@ -0,0 +1,1561 @@
+// Package repotest contains the behaviour every implementation of the repository interfaces has to conform to
+// each storage backend runs the suites from its own tests with a constructor returning an empty repo
+package repotest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"sync"
+	"testing"
+	"time"
+)
+
+// SurveyRepoFactory returns a new empty survey repo
+type SurveyRepoFactory func(t *testing.T) repositories.SurveyRepoInterface
+
+// ResponseRepoFactory returns a new empty response repo
+type ResponseRepoFactory func(t *testing.T) repositories.ResponseRepoInterface
+
+const concurrentWriters = 8
+
+// WithSurveys returns responseRepo storing a survey in surveyRepo for every response created through it whose survey
+// is not stored yet, so that the suites creating responses of surveys they never store run against backends which
+// only store the responses of stored surveys
+func WithSurveys(responseRepo repositories.ResponseRepoInterface, surveyRepo repositories.SurveyRepoInterface) repositories.ResponseRepoInterface {
+	return &surveyedResponseRepo{ResponseRepoInterface: responseRepo, surveys: surveyRepo}
+}
+
+type surveyedResponseRepo struct {
+	repositories.ResponseRepoInterface
+	surveys repositories.SurveyRepoInterface
+}
+
+// storeSurvey stores survey in the survey repo of responseRepo when it was returned by WithSurveys,
+// for the responses which are not created through responseRepo
+func storeSurvey(t *testing.T, responseRepo repositories.ResponseRepoInterface, survey models.Survey) {
+	if surveyed, ok := responseRepo.(*surveyedResponseRepo); ok {
+		_, err := surveyed.surveys.Create(&survey)
+		require.NoError(t, err)
+	}
+}
+
+func (r *surveyedResponseRepo) Create(response *models.Response) (*models.Response, error) {
+	if _, err := r.surveys.Get(response.Workspace(), response.SurveyID); err == repositories.ErrNotFound {
+		survey := models.Survey{ID: response.SurveyID, WorkspaceID: response.Workspace(), Name: "answered survey",
+			CreatedAt: response.CreatedAt, UpdatedAt: response.CreatedAt}
+		if _, err = r.surveys.Create(&survey); err != nil && err != repositories.ErrAlreadyExists {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return r.ResponseRepoInterface.Create(response)
+}
+
+// workspace holds the fixtures, otherWorkspace holds the ones which must stay out of reach
+const (
+	workspace      = models.DefaultWorkspace
+	otherWorkspace = models.WorkspaceID("other")
+)
+
+// newSurvey returns a survey with two questions created at createdAt
+// times are in UTC so that they compare equal after a round trip through any backend
+func newSurvey(name string, createdAt time.Time) models.Survey {
+	return models.Survey{
+		ID:          ksuid.New(),
+		WorkspaceID: workspace,
+		Name:        name,
+		CreatedAt:   createdAt.UTC(),
+		UpdatedAt:   createdAt.UTC(),
+		Questions: []models.Question{
+			{ID: ksuid.New(), Question: "is this place good?"},
+			{ID: ksuid.New(), Question: "does this place has parking?"},
+		},
+	}
+}
+
+func newResponse(survey models.Survey, createdAt time.Time) models.Response {
+	response := models.Response{ID: ksuid.New(), WorkspaceID: survey.WorkspaceID, SurveyID: survey.ID, CreatedAt: createdAt.UTC()}
+	for i, question := range survey.Questions {
+		response.Answers = append(response.Answers, models.Answer{QuestionID: question.ID, Answer: models.BoolValue(i%2 == 0)})
+	}
+	return response
+}
+
+// SurveyRepoSuite runs the survey repo contract against repos returned by newRepo
+func SurveyRepoSuite(t *testing.T, newRepo SurveyRepoFactory) {
+	t.Run("should get created survey by id", func(t *testing.T) {
+		surveyRepo := newRepo(t)
+		survey := newSurvey("new survey", time.Now())
+		createdSurvey, err := surveyRepo.Create(&survey)
+		require.NoError(t, err)
+		assert.Equal(t, survey, *createdSurvey)
+		storedSurvey, err := surveyRepo.Get(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, survey, *storedSurvey)
+	})
+	t.Run("should keep type and config of typed questions and rules", func(t *testing.T) {
+		surveyRepo := newRepo(t)
+		survey := newSurvey("typed survey", time.Now())
+		min, max := 1.0, 5.0
+		survey.Questions = []models.Question{
+			{ID: ksuid.New(), Question: "how was it?", Type: models.QuestionRating, Min: &min, Max: &max, Required: true},
+			{ID: ksuid.New(), Question: "what did you eat?", Type: models.QuestionMultiChoice, Options: []string{"pizza", "pasta", "salad"}},
+			{ID: ksuid.New(), Question: "anything else?", Type: models.QuestionText, MaxLength: 200},
+			{ID: ksuid.New(), Question: "when did you visit?", Type: models.QuestionDate},
+		}
+		survey.Rules = []models.Rule{
+			{
+				Type:       models.RuleShowIf,
+				QuestionID: survey.Questions[2].ID,
+				Condition:  models.Condition{QuestionID: survey.Questions[0].ID, Operator: models.OpLess, Value: models.NumberValue(3)},
+			},
+			{
+				Type:       models.RuleJump,
+				QuestionID: survey.Questions[0].ID,
+				Condition:  models.Condition{QuestionID: survey.Questions[0].ID, Operator: models.OpGreaterOrEq, Value: models.NumberValue(4)},
+				Target:     survey.Questions[3].ID,
+			},
+		}
+		_, err := surveyRepo.Create(&survey)
+		require.NoError(t, err)
+		storedSurvey, err := surveyRepo.Get(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, survey, *storedSurvey)
+	})
+	t.Run("should return ErrNotFound when getting missing survey", func(t *testing.T) {
+		surveyRepo := newRepo(t)
+		survey := newSurvey("new survey", time.Now())
+		_, err := surveyRepo.Create(&survey)
+		require.NoError(t, err)
+		storedSurvey, err := surveyRepo.Get(workspace, ksuid.New())
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Nil(t, storedSurvey)
+	})
+	t.Run("should replace survey on update", func(t *testing.T) {
+		surveyRepo := newRepo(t)
+		survey := newSurvey("new survey", time.Now())
+		_, err := surveyRepo.Create(&survey)
+		require.NoError(t, err)
+		updatedSurvey := survey
+		updatedSurvey.Name = "updated survey"
+		updatedSurvey.Status = models.SurveyClosed
+		opensAt, closesAt := survey.CreatedAt.Add(time.Hour), survey.CreatedAt.Add(2*time.Hour)
+		updatedSurvey.OpensAt, updatedSurvey.ClosesAt, updatedSurvey.MaxResponses = &opensAt, &closesAt, 100
+		updatedSurvey.EditWindowSeconds = 3600
+		updatedSurvey.UpdatedAt = survey.UpdatedAt.Add(time.Hour)
+		updatedSurvey.Questions = []models.Question{{ID: ksuid.New(), Question: "is apple m1 chip good?"}}
+		returnedSurvey, err := surveyRepo.Update(workspace, survey.ID, &updatedSurvey)
+		require.NoError(t, err)
+		assert.Equal(t, updatedSurvey, *returnedSurvey)
+		storedSurvey, err := surveyRepo.Get(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, updatedSurvey, *storedSurvey)
+	})
+	t.Run("should return ErrNotFound when updating missing survey without creating it", func(t *testing.T) {
+		surveyRepo := newRepo(t)
+		survey := newSurvey("new survey", time.Now())
+		updatedSurvey, err := surveyRepo.Update(workspace, survey.ID, &survey)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Nil(t, updatedSurvey)
+		_, err = surveyRepo.Get(workspace, survey.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+	})
+	t.Run("should keep every version of an updated survey", func(t *testing.T) {
+		surveyRepo := newRepo(t)
+		survey := newSurvey("new survey", time.Now())
+		survey.Version = 1
+		_, err := surveyRepo.Create(&survey)
+		require.NoError(t, err)
+		secondVersion := survey
+		secondVersion.Version = 2
+		secondVersion.Questions = []models.Question{{ID: ksuid.New(), Question: "is apple m1 chip good?"}}
+		_, err = surveyRepo.Update(workspace, survey.ID, &secondVersion)
+		require.NoError(t, err)
+		closedSecondVersion := secondVersion
+		closedSecondVersion.Status = models.SurveyClosed
+		_, err = surveyRepo.Update(workspace, survey.ID, &closedSecondVersion)
+		require.NoError(t, err)
+		versions, err := surveyRepo.GetVersions(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []models.Survey{survey, closedSecondVersion}, versions)
+		assert.Equal(t, map[ksuid.KSUID][]models.Survey{survey.ID: versions}, surveyRepo.VersionEntries())
+	})
+	t.Run("should drop the versions of a deleted survey", func(t *testing.T) {
+		surveyRepo := newRepo(t)
+		survey := newSurvey("new survey", time.Now())
+		_, err := surveyRepo.Create(&survey)
+		require.NoError(t, err)
+		require.NoError(t, surveyRepo.Delete(workspace, survey.ID, false))
+		versions, err := surveyRepo.GetVersions(workspace, survey.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Nil(t, versions)
+		assert.Empty(t, surveyRepo.VersionEntries())
+	})
+	t.Run("should not find survey after deleting it", func(t *testing.T) {
+		surveyRepo := newRepo(t)
+		survey := newSurvey("new survey", time.Now())
+		_, err := surveyRepo.Create(&survey)
+		require.NoError(t, err)
+		require.NoError(t, surveyRepo.Delete(workspace, survey.ID, false))
+		_, err = surveyRepo.Get(workspace, survey.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Equal(t, repositories.ErrNotFound, surveyRepo.Delete(workspace, survey.ID, false))
+	})
+	t.Run("should return an empty page when getting all surveys of an empty repo", func(t *testing.T) {
+		surveyRepo := newRepo(t)
+		page, err := surveyRepo.GetAll(workspace, models.SurveyQuery{})
+		require.NoError(t, err)
+		assert.Equal(t, &models.SurveyPage{Surveys: []models.Survey{}}, page)
+	})
+	t.Run("should return all surveys ordered by creation time", func(t *testing.T) {
+		surveyRepo := newRepo(t)
+		now := time.Now()
+		var expected []models.Survey
+		for i := 0; i < 5; i++ {
+			expected = append(expected, newSurvey(fmt.Sprintf("survey %d", i), now.Add(time.Duration(i)*time.Minute)))
+		}
+		for i := len(expected) - 1; i >= 0; i-- {
+			_, err := surveyRepo.Create(&expected[i])
+			require.NoError(t, err)
+		}
+		page, err := surveyRepo.GetAll(workspace, models.SurveyQuery{})
+		require.NoError(t, err)
+		assert.Equal(t, expected, page.Surveys)
+		assert.Empty(t, page.Next)
+	})
+	t.Run("should page through surveys in the requested order", func(t *testing.T) {
+		surveyRepo := newRepo(t)
+		now := time.Now()
+		names := []string{"delta", "alpha", "echo", "charlie", "bravo"}
+		surveys := make([]models.Survey, 0, len(names))
+		for i, name := range names {
+			survey := newSurvey(name, now.Add(time.Duration(i)*time.Minute))
+			survey.UpdatedAt = now.Add(-time.Duration(i) * time.Minute).UTC()
+			_, err := surveyRepo.Create(&survey)
+			require.NoError(t, err)
+			surveys = append(surveys, survey)
+		}
+		for _, tc := range []struct {
+			sort       models.SortField
+			descending bool
+			expected   []string
+		}{
+			{"", false, []string{"delta", "alpha", "echo", "charlie", "bravo"}},
+			{models.SortCreatedAt, true, []string{"bravo", "charlie", "echo", "alpha", "delta"}},
+			{models.SortUpdatedAt, false, []string{"bravo", "charlie", "echo", "alpha", "delta"}},
+			{models.SortName, false, []string{"alpha", "bravo", "charlie", "delta", "echo"}},
+			{models.SortName, true, []string{"echo", "delta", "charlie", "bravo", "alpha"}},
+		} {
+			query := models.SurveyQuery{PageQuery: models.PageQuery{Limit: 2, Sort: tc.sort, Descending: tc.descending}}
+			var listed []string
+			for pages := 0; pages < len(names); pages++ {
+				page, err := surveyRepo.GetAll(workspace, query)
+				require.NoError(t, err)
+				for _, survey := range page.Surveys {
+					listed = append(listed, survey.Name)
+				}
+				if page.Next == "" {
+					break
+				}
+				query.After, err = models.DecodeCursor(page.Next)
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.expected, listed, "sort %q descending %v", tc.sort, tc.descending)
+		}
+	})
+	t.Run("should filter surveys by name, status and creation time", func(t *testing.T) {
+		surveyRepo := newRepo(t)
+		now := time.Now().UTC()
+		legacy := newSurvey("legacy restaurant", now)
+		draft := newSurvey("draft restaurant", now.Add(time.Minute))
+		draft.Status = models.SurveyDraft
+		later := newSurvey("later cafe", now.Add(time.Hour))
+		later.Status = models.SurveyPublished
+		for _, survey := range []*models.Survey{&legacy, &draft, &later} {
+			_, err := surveyRepo.Create(survey)
+			require.NoError(t, err)
+		}
+		page, err := surveyRepo.GetAll(workspace, models.SurveyQuery{Name: "restaurant"})
+		require.NoError(t, err)
+		assert.Equal(t, []models.Survey{legacy, draft}, page.Surveys)
+		page, err = surveyRepo.GetAll(workspace, models.SurveyQuery{Status: models.SurveyPublished})
+		require.NoError(t, err)
+		assert.Equal(t, []models.Survey{legacy, later}, page.Surveys)
+		from, to := now.Add(time.Minute), now.Add(time.Hour)
+		page, err = surveyRepo.GetAll(workspace, models.SurveyQuery{PageQuery: models.PageQuery{CreatedFrom: &from, CreatedTo: &to}})
+		require.NoError(t, err)
+		assert.Equal(t, []models.Survey{draft, later}, page.Surveys)
+		page, err = surveyRepo.GetAll(workspace, models.SurveyQuery{Name: "Restaurant"})
+		require.NoError(t, err)
+		assert.Empty(t, page.Surveys)
+	})
+	t.Run("should keep every survey written by concurrent writers", func(t *testing.T) {
+		surveyRepo := newRepo(t)
+		now := time.Now()
+		surveys := make([][]models.Survey, concurrentWriters)
+		wg := &sync.WaitGroup{}
+		for writer := 0; writer < concurrentWriters; writer++ {
+			wg.Add(1)
+			go func(writer int) {
+				defer wg.Done()
+				for i := 0; i < 5; i++ {
+					survey := newSurvey(fmt.Sprintf("survey %d-%d", writer, i), now)
+					if _, err := surveyRepo.Create(&survey); err != nil {
+						t.Error(err)
+						return
+					}
+					survey.Name = "updated " + survey.Name
+					if _, err := surveyRepo.Update(workspace, survey.ID, &survey); err != nil {
+						t.Error(err)
+						return
+					}
+					_, _ = surveyRepo.GetAll(workspace, models.SurveyQuery{})
+					surveys[writer] = append(surveys[writer], survey)
+				}
+			}(writer)
+		}
+		wg.Wait()
+		expected := make(map[ksuid.KSUID]models.Survey)
+		for _, writerSurveys := range surveys {
+			for _, survey := range writerSurveys {
+				expected[survey.ID] = survey
+			}
+		}
+		assert.Equal(t, expected, surveyRepo.Entries())
+	})
+	t.Run("should keep surveys of other workspaces out of reach", func(t *testing.T) {
+		surveyRepo := newRepo(t)
+		survey := newSurvey("other survey", time.Now())
+		survey.WorkspaceID = otherWorkspace
+		_, err := surveyRepo.Create(&survey)
+		require.NoError(t, err)
+		_, err = surveyRepo.Get(workspace, survey.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		_, err = surveyRepo.GetVersions(workspace, survey.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		updated := survey
+		updated.Name = "taken over"
+		_, err = surveyRepo.Update(workspace, survey.ID, &updated)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Equal(t, repositories.ErrNotFound, surveyRepo.Delete(workspace, survey.ID, false))
+		page, err := surveyRepo.GetAll(workspace, models.SurveyQuery{})
+		require.NoError(t, err)
+		assert.Empty(t, page.Surveys)
+		stored, err := surveyRepo.Get(otherWorkspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, survey, *stored)
+		page, err = surveyRepo.GetAll(otherWorkspace, models.SurveyQuery{})
+		require.NoError(t, err)
+		assert.Equal(t, []models.Survey{survey}, page.Surveys)
+	})
+	t.Run("should not create a survey whose id is taken in another workspace", func(t *testing.T) {
+		surveyRepo := newRepo(t)
+		survey := newSurvey("other survey", time.Now())
+		survey.WorkspaceID = otherWorkspace
+		_, err := surveyRepo.Create(&survey)
+		require.NoError(t, err)
+		copied := survey
+		copied.WorkspaceID = workspace
+		_, err = surveyRepo.Create(&copied)
+		assert.Equal(t, repositories.ErrAlreadyExists, err)
+		stored, err := surveyRepo.Get(otherWorkspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, survey, *stored)
+	})
+	t.Run("should round trip surveys through entries", func(t *testing.T) {
+		surveyRepo := newRepo(t)
+		assert.Empty(t, surveyRepo.Entries())
+		survey1, survey2, survey3 := newSurvey("survey 1", time.Now()), newSurvey("survey 2", time.Now()), newSurvey("survey 3", time.Now())
+		for _, survey := range []*models.Survey{&survey1, &survey2, &survey3} {
+			_, err := surveyRepo.Create(survey)
+			require.NoError(t, err)
+		}
+		survey2.Name = "updated survey 2"
+		_, err := surveyRepo.Update(workspace, survey2.ID, &survey2)
+		require.NoError(t, err)
+		require.NoError(t, surveyRepo.Delete(workspace, survey3.ID, false))
+		assert.Equal(t, map[ksuid.KSUID]models.Survey{survey1.ID: survey1, survey2.ID: survey2}, surveyRepo.Entries())
+	})
+}
+
+// SurveyDeletionFactory returns a new empty survey repo along with the response repo holding the responses of its surveys
+// the response repo must only store the responses of surveys stored in the survey repo
+type SurveyDeletionFactory func(t *testing.T) (repositories.SurveyRepoInterface, repositories.ResponseRepoInterface)
+
+// SurveyDeletionSuite runs the contract of deleting surveys which have responses against repos returned by newRepos
+func SurveyDeletionSuite(t *testing.T, newRepos SurveyDeletionFactory) {
+	t.Run("should refuse deleting a survey with responses unless cascading to them", func(t *testing.T) {
+		surveyRepo, responseRepo := newRepos(t)
+		survey := newSurvey("answered survey", time.Now())
+		_, err := surveyRepo.Create(&survey)
+		require.NoError(t, err)
+		response := newResponse(survey, time.Now())
+		_, err = responseRepo.Create(&response)
+		require.NoError(t, err)
+		assert.Equal(t, repositories.ErrSurveyHasResponses, surveyRepo.Delete(workspace, survey.ID, false))
+		_, err = surveyRepo.Get(workspace, survey.ID)
+		require.NoError(t, err)
+		count, err := responseRepo.CountBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+		require.NoError(t, surveyRepo.Delete(workspace, survey.ID, true))
+		_, err = surveyRepo.Get(workspace, survey.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		_, err = responseRepo.Get(workspace, response.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Empty(t, responseRepo.Entries())
+	})
+	t.Run("should only cascade to the responses of the deleted survey", func(t *testing.T) {
+		surveyRepo, responseRepo := newRepos(t)
+		deleted, kept := newSurvey("deleted survey", time.Now()), newSurvey("kept survey", time.Now())
+		for _, survey := range []*models.Survey{&deleted, &kept} {
+			_, err := surveyRepo.Create(survey)
+			require.NoError(t, err)
+			response := newResponse(*survey, time.Now())
+			_, err = responseRepo.Create(&response)
+			require.NoError(t, err)
+		}
+		require.NoError(t, surveyRepo.Delete(workspace, deleted.ID, true))
+		sets, err := responseRepo.Sets()
+		require.NoError(t, err)
+		require.Len(t, sets, 1)
+		assert.Equal(t, kept.ID, sets[0].SurveyID)
+	})
+	t.Run("should not store a response of a deleted survey", func(t *testing.T) {
+		surveyRepo, responseRepo := newRepos(t)
+		survey := newSurvey("deleted survey", time.Now())
+		_, err := surveyRepo.Create(&survey)
+		require.NoError(t, err)
+		require.NoError(t, surveyRepo.Delete(workspace, survey.ID, false))
+		response := newResponse(survey, time.Now())
+		_, err = responseRepo.Create(&response)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Empty(t, responseRepo.Entries())
+	})
+	t.Run("should never leave a response behind when deleting a survey while responses are created", func(t *testing.T) {
+		surveyRepo, responseRepo := newRepos(t)
+		for round := 0; round < 20; round++ {
+			survey := newSurvey("contended survey", time.Now())
+			_, err := surveyRepo.Create(&survey)
+			require.NoError(t, err)
+			var wg sync.WaitGroup
+			for i := 0; i < concurrentWriters; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					response := newResponse(survey, time.Now())
+					if _, err := responseRepo.Create(&response); err != nil && err != repositories.ErrNotFound {
+						t.Errorf("unexpected error %v", err)
+					}
+				}()
+			}
+			deleteErr := surveyRepo.Delete(workspace, survey.ID, false)
+			wg.Wait()
+			count, err := responseRepo.CountBySurveyID(workspace, survey.ID)
+			require.NoError(t, err)
+			_, getErr := surveyRepo.Get(workspace, survey.ID)
+			if deleteErr == nil {
+				assert.Equal(t, repositories.ErrNotFound, getErr)
+				assert.Zero(t, count, "responses of deleted survey were stored")
+			} else {
+				assert.Equal(t, repositories.ErrSurveyHasResponses, deleteErr)
+				assert.NoError(t, getErr)
+				assert.NotZero(t, count)
+			}
+		}
+	})
+	t.Run("should return ErrNotFound when cascading from a missing survey or one of another workspace", func(t *testing.T) {
+		surveyRepo, responseRepo := newRepos(t)
+		survey := newSurvey("other survey", time.Now())
+		survey.WorkspaceID = otherWorkspace
+		_, err := surveyRepo.Create(&survey)
+		require.NoError(t, err)
+		response := newResponse(survey, time.Now())
+		_, err = responseRepo.Create(&response)
+		require.NoError(t, err)
+		assert.Equal(t, repositories.ErrNotFound, surveyRepo.Delete(workspace, survey.ID, true))
+		assert.Equal(t, repositories.ErrNotFound, surveyRepo.Delete(workspace, ksuid.New(), true))
+		_, err = responseRepo.Get(otherWorkspace, response.ID)
+		require.NoError(t, err)
+	})
+}
+
+// ResponseRepoSuite runs the response repo contract against repos returned by newRepo
+func ResponseRepoSuite(t *testing.T, newRepo ResponseRepoFactory) {
+	t.Run("should get created responses by survey id", func(t *testing.T) {
+		responseRepo := newRepo(t)
+		survey := newSurvey("new survey", time.Now())
+		response := newResponse(survey, time.Now())
+		createdResponse, err := responseRepo.Create(&response)
+		require.NoError(t, err)
+		assert.Equal(t, response, *createdResponse)
+		responses, err := responseRepo.GetBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []models.Response{response}, responses)
+	})
+	t.Run("should keep the answered survey version", func(t *testing.T) {
+		responseRepo := newRepo(t)
+		survey := newSurvey("new survey", time.Now())
+		response := newResponse(survey, time.Now())
+		response.SurveyVersion = 3
+		_, err := responseRepo.Create(&response)
+		require.NoError(t, err)
+		responses, err := responseRepo.GetBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []models.Response{response}, responses)
+	})
+	t.Run("should keep typed answers", func(t *testing.T) {
+		responseRepo := newRepo(t)
+		survey := newSurvey("typed survey", time.Now())
+		response := newResponse(survey, time.Now())
+		response.Answers = []models.Answer{
+			{QuestionID: ksuid.New(), Answer: models.NumberValue(4.5)},
+			{QuestionID: ksuid.New(), Answer: models.TextValue("it was great")},
+			{QuestionID: ksuid.New(), Answer: models.ChoicesValue("pizza", "salad")},
+			{QuestionID: ksuid.New(), Answer: models.BoolValue(false)},
+		}
+		_, err := responseRepo.Create(&response)
+		require.NoError(t, err)
+		responses, err := responseRepo.GetBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []models.Response{response}, responses)
+	})
+	t.Run("should count responses by survey", func(t *testing.T) {
+		responseRepo := newRepo(t)
+		survey, otherSurvey := newSurvey("new survey", time.Now()), newSurvey("other survey", time.Now())
+		count, err := responseRepo.CountBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+		for i := 0; i < 3; i++ {
+			response := newResponse(survey, time.Now())
+			_, err = responseRepo.Create(&response)
+			require.NoError(t, err)
+		}
+		otherResponse := newResponse(otherSurvey, time.Now())
+		_, err = responseRepo.Create(&otherResponse)
+		require.NoError(t, err)
+		count, err = responseRepo.CountBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+	t.Run("should return ErrNotFound when survey has no responses", func(t *testing.T) {
+		responseRepo := newRepo(t)
+		survey := newSurvey("new survey", time.Now())
+		response := newResponse(survey, time.Now())
+		_, err := responseRepo.Create(&response)
+		require.NoError(t, err)
+		responses, err := responseRepo.GetBySurveyID(workspace, ksuid.New())
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Nil(t, responses)
+	})
+	t.Run("should call fn with every response of a survey in creation order", func(t *testing.T) {
+		responseRepo := newRepo(t)
+		survey := newSurvey("new survey", time.Now())
+		now := time.Now()
+		var expected []models.Response
+		for i := 0; i < 3; i++ {
+			expected = append(expected, newResponse(survey, now.Add(time.Duration(i)*time.Second)))
+		}
+		for _, i := range []int{2, 0, 1} {
+			_, err := responseRepo.Create(&expected[i])
+			require.NoError(t, err)
+		}
+		otherResponse := newResponse(newSurvey("other survey", now), now)
+		_, err := responseRepo.Create(&otherResponse)
+		require.NoError(t, err)
+		var responses []models.Response
+		err = responseRepo.EachBySurveyID(workspace, survey.ID, func(response models.Response) error {
+			responses = append(responses, response)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, expected, responses)
+	})
+	t.Run("should stop at the first error returned by fn", func(t *testing.T) {
+		responseRepo := newRepo(t)
+		survey := newSurvey("new survey", time.Now())
+		for i := 0; i < 3; i++ {
+			response := newResponse(survey, time.Now().Add(time.Duration(i)*time.Second))
+			_, err := responseRepo.Create(&response)
+			require.NoError(t, err)
+		}
+		stop := errors.New("stop")
+		calls := 0
+		err := responseRepo.EachBySurveyID(workspace, survey.ID, func(response models.Response) error {
+			calls++
+			return stop
+		})
+		assert.Equal(t, stop, err)
+		assert.Equal(t, 1, calls)
+		assert.NoError(t, responseRepo.EachBySurveyID(workspace, ksuid.New(), func(response models.Response) error {
+			return stop
+		}))
+	})
+	t.Run("should page through responses newest or oldest first", func(t *testing.T) {
+		responseRepo := newRepo(t)
+		survey := newSurvey("new survey", time.Now())
+		now := time.Now()
+		var expected []models.Response
+		for i := 0; i < 5; i++ {
+			response := newResponse(survey, now.Add(time.Duration(i%3)*time.Second))
+			_, err := responseRepo.Create(&response)
+			require.NoError(t, err)
+			expected = append(expected, response)
+		}
+		other := newResponse(newSurvey("other survey", now), now)
+		_, err := responseRepo.Create(&other)
+		require.NoError(t, err)
+		for _, descending := range []bool{false, true} {
+			query := models.ResponseQuery{SurveyID: survey.ID, PageQuery: models.PageQuery{Limit: 2, Descending: descending}}
+			var listed []models.Response
+			for pages := 0; pages < len(expected); pages++ {
+				page, err := responseRepo.GetAll(workspace, query)
+				require.NoError(t, err)
+				listed = append(listed, page.Responses...)
+				if page.Next == "" {
+					break
+				}
+				query.After, err = models.DecodeCursor(page.Next)
+				require.NoError(t, err)
+			}
+			require.Len(t, listed, len(expected))
+			for i := 1; i < len(listed); i++ {
+				assert.True(t, query.Less(listed[i-1].SortKey(""), listed[i-1].ID, listed[i].SortKey(""), listed[i].ID))
+			}
+			assert.ElementsMatch(t, expected, listed)
+		}
+	})
+	t.Run("should filter responses by version and creation time", func(t *testing.T) {
+		responseRepo := newRepo(t)
+		survey := newSurvey("new survey", time.Now())
+		now := time.Now().UTC()
+		legacy := newResponse(survey, now)
+		second := newResponse(survey, now.Add(time.Minute))
+		second.SurveyVersion = 2
+		third := newResponse(survey, now.Add(time.Hour))
+		third.SurveyVersion = 3
+		for _, response := range []*models.Response{&legacy, &second, &third} {
+			_, err := responseRepo.Create(response)
+			require.NoError(t, err)
+		}
+		page, err := responseRepo.GetAll(workspace, models.ResponseQuery{SurveyID: survey.ID, Versions: []int{1, 3}})
+		require.NoError(t, err)
+		assert.Equal(t, []models.Response{legacy, third}, page.Responses)
+		to := now.Add(time.Minute)
+		page, err = responseRepo.GetAll(workspace, models.ResponseQuery{SurveyID: survey.ID, PageQuery: models.PageQuery{CreatedTo: &to}})
+		require.NoError(t, err)
+		assert.Equal(t, []models.Response{legacy, second}, page.Responses)
+		page, err = responseRepo.GetAll(workspace, models.ResponseQuery{SurveyID: ksuid.New()})
+		require.NoError(t, err)
+		assert.Equal(t, &models.ResponsePage{Responses: []models.Response{}}, page)
+	})
+	t.Run("should keep responses of different surveys apart", func(t *testing.T) {
+		responseRepo := newRepo(t)
+		survey1, survey2 := newSurvey("survey 1", time.Now()), newSurvey("survey 2", time.Now())
+		response1, response2 := newResponse(survey1, time.Now()), newResponse(survey2, time.Now())
+		_, err := responseRepo.Create(&response1)
+		require.NoError(t, err)
+		_, err = responseRepo.Create(&response2)
+		require.NoError(t, err)
+		responses, err := responseRepo.GetBySurveyID(workspace, survey2.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []models.Response{response2}, responses)
+	})
+	t.Run("should return responses ordered by creation time", func(t *testing.T) {
+		responseRepo := newRepo(t)
+		survey := newSurvey("new survey", time.Now())
+		now := time.Now()
+		var expected []models.Response
+		for i := 0; i < 5; i++ {
+			expected = append(expected, newResponse(survey, now.Add(time.Duration(i)*time.Second)))
+		}
+		for _, i := range []int{3, 0, 4, 1, 2} {
+			_, err := responseRepo.Create(&expected[i])
+			require.NoError(t, err)
+		}
+		responses, err := responseRepo.GetBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, expected, responses)
+	})
+	t.Run("should keep every response written by concurrent writers", func(t *testing.T) {
+		responseRepo := newRepo(t)
+		survey := newSurvey("new survey", time.Now())
+		now := time.Now()
+		wg := &sync.WaitGroup{}
+		for writer := 0; writer < concurrentWriters; writer++ {
+			wg.Add(1)
+			go func(writer int) {
+				defer wg.Done()
+				for i := 0; i < 5; i++ {
+					response := newResponse(survey, now.Add(time.Duration(writer*5+i)*time.Millisecond))
+					if _, err := responseRepo.Create(&response); err != nil {
+						t.Error(err)
+						return
+					}
+					_, _ = responseRepo.GetBySurveyID(workspace, survey.ID)
+				}
+			}(writer)
+		}
+		wg.Wait()
+		responses, err := responseRepo.GetBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Len(t, responses, concurrentWriters*5)
+	})
+	t.Run("should keep responses of other workspaces out of reach", func(t *testing.T) {
+		responseRepo := newRepo(t)
+		survey := newSurvey("other survey", time.Now())
+		survey.WorkspaceID = otherWorkspace
+		response := newResponse(survey, time.Now())
+		_, err := responseRepo.Create(&response)
+		require.NoError(t, err)
+		_, err = responseRepo.GetBySurveyID(workspace, survey.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		count, err := responseRepo.CountBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+		page, err := responseRepo.GetAll(workspace, models.ResponseQuery{SurveyID: survey.ID})
+		require.NoError(t, err)
+		assert.Empty(t, page.Responses)
+		err = responseRepo.EachBySurveyID(workspace, survey.ID, func(response models.Response) error {
+			t.Errorf("unexpected response %s", response.ID)
+			return nil
+		})
+		require.NoError(t, err)
+		responses, err := responseRepo.GetBySurveyID(otherWorkspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []models.Response{response}, responses)
+	})
+	t.Run("should sum up the responses of every survey of every workspace", func(t *testing.T) {
+		responseRepo := newRepo(t)
+		sets, err := responseRepo.Sets()
+		require.NoError(t, err)
+		assert.Empty(t, sets)
+		survey, otherSurvey := newSurvey("new survey", time.Now()), newSurvey("other survey", time.Now())
+		otherSurvey.WorkspaceID = otherWorkspace
+		now := time.Now().UTC()
+		for _, response := range []models.Response{
+			newResponse(survey, now.Add(time.Minute)), newResponse(survey, now), newResponse(otherSurvey, now),
+		} {
+			_, err = responseRepo.Create(&response)
+			require.NoError(t, err)
+		}
+		sets, err = responseRepo.Sets()
+		require.NoError(t, err)
+		assert.Equal(t, []models.ResponseSet{
+			{WorkspaceID: workspace, SurveyID: survey.ID, Responses: 2, FirstAt: now, LastAt: now.Add(time.Minute)},
+			{WorkspaceID: otherWorkspace, SurveyID: otherSurvey.ID, Responses: 1, FirstAt: now, LastAt: now},
+		}, sets)
+	})
+	t.Run("should get, edit and delete responses by id", func(t *testing.T) {
+		responseRepo := newRepo(t)
+		survey := newSurvey("new survey", time.Now())
+		now := time.Now()
+		response, other := newResponse(survey, now), newResponse(survey, now.Add(time.Second))
+		for _, created := range []*models.Response{&response, &other} {
+			_, err := responseRepo.Create(created)
+			require.NoError(t, err)
+		}
+		stored, err := responseRepo.Get(workspace, response.ID)
+		require.NoError(t, err)
+		assert.Equal(t, response, *stored)
+		edited := response
+		edited.Edit(2, []models.Answer{{QuestionID: survey.Questions[1].ID, Answer: models.BoolValue(true)}}, now.Add(time.Minute).UTC())
+		edited.CreatedAt, edited.RespondentID = now.Add(time.Hour).UTC(), &survey.ID
+		updated, err := responseRepo.Update(&edited)
+		require.NoError(t, err)
+		expected := response
+		expected.Edit(2, edited.Answers, *edited.UpdatedAt)
+		assert.Equal(t, expected, *updated)
+		stored, err = responseRepo.Get(workspace, response.ID)
+		require.NoError(t, err)
+		assert.Equal(t, expected, *stored)
+		require.NoError(t, responseRepo.Delete(workspace, response.ID))
+		_, err = responseRepo.Get(workspace, response.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Equal(t, repositories.ErrNotFound, responseRepo.Delete(workspace, response.ID))
+		_, err = responseRepo.Update(&expected)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		responses, err := responseRepo.GetBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []models.Response{other}, responses)
+	})
+	t.Run("should not get, edit or delete responses of other workspaces", func(t *testing.T) {
+		responseRepo := newRepo(t)
+		survey := newSurvey("other survey", time.Now())
+		survey.WorkspaceID = otherWorkspace
+		response := newResponse(survey, time.Now())
+		_, err := responseRepo.Create(&response)
+		require.NoError(t, err)
+		_, err = responseRepo.Get(workspace, response.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		edited := response
+		edited.WorkspaceID = workspace
+		edited.Edit(1, nil, time.Now().UTC())
+		_, err = responseRepo.Update(&edited)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Equal(t, repositories.ErrNotFound, responseRepo.Delete(workspace, response.ID))
+		stored, err := responseRepo.Get(otherWorkspace, response.ID)
+		require.NoError(t, err)
+		assert.Equal(t, response, *stored)
+	})
+	t.Run("should round trip responses through entries", func(t *testing.T) {
+		responseRepo := newRepo(t)
+		assert.Empty(t, responseRepo.Entries())
+		survey1, survey2 := newSurvey("survey 1", time.Now()), newSurvey("survey 2", time.Now())
+		now := time.Now()
+		response1, response2, response3 := newResponse(survey1, now), newResponse(survey1, now.Add(time.Second)), newResponse(survey2, now)
+		for _, response := range []*models.Response{&response1, &response2, &response3} {
+			_, err := responseRepo.Create(response)
+			require.NoError(t, err)
+		}
+		expected := map[ksuid.KSUID][]models.Response{
+			survey1.ID: {response1, response2},
+			survey2.ID: {response3},
+		}
+		assert.Equal(t, expected, responseRepo.Entries())
+	})
+}
+
+// APIKeyRepoFactory returns a new empty api key repo
+type APIKeyRepoFactory func(t *testing.T) repositories.APIKeyRepoInterface
+
+func newAPIKey(name string, createdAt time.Time, scopes ...models.Scope) models.APIKey {
+	id := ksuid.New()
+	return models.APIKey{
+		ID:          id,
+		WorkspaceID: workspace,
+		Name:        name,
+		Prefix:      "sp_" + name,
+		Hash:        "hash of " + id.String(),
+		Scopes:      scopes,
+		CreatedAt:   createdAt.UTC(),
+	}
+}
+
+// APIKeyRepoSuite runs the api key repo contract against repos returned by newRepo
+func APIKeyRepoSuite(t *testing.T, newRepo APIKeyRepoFactory) {
+	t.Run("should get created key by id and by hash", func(t *testing.T) {
+		apiKeyRepo := newRepo(t)
+		key := newAPIKey("admin", time.Now(), models.ScopeSurveysRead, models.ScopeKeysManage)
+		created, err := apiKeyRepo.Create(&key)
+		require.NoError(t, err)
+		assert.Equal(t, key, *created)
+		stored, err := apiKeyRepo.Get(workspace, key.ID)
+		require.NoError(t, err)
+		assert.Equal(t, key, *stored)
+		stored, err = apiKeyRepo.GetByHash(key.Hash)
+		require.NoError(t, err)
+		assert.Equal(t, key, *stored)
+	})
+	t.Run("should return ErrNotFound for unknown keys", func(t *testing.T) {
+		apiKeyRepo := newRepo(t)
+		_, err := apiKeyRepo.Get(workspace, ksuid.New())
+		assert.Equal(t, repositories.ErrNotFound, err)
+		_, err = apiKeyRepo.GetByHash("unknown")
+		assert.Equal(t, repositories.ErrNotFound, err)
+		_, err = apiKeyRepo.Revoke(workspace, ksuid.New(), time.Now())
+		assert.Equal(t, repositories.ErrNotFound, err)
+	})
+	t.Run("should list keys ordered by creation time", func(t *testing.T) {
+		apiKeyRepo := newRepo(t)
+		keys, err := apiKeyRepo.GetAll(workspace)
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+		now := time.Now()
+		later := newAPIKey("later", now.Add(time.Minute), models.ScopeResponsesWrite)
+		earlier := newAPIKey("earlier", now, models.ScopeSurveysRead)
+		for _, key := range []models.APIKey{later, earlier} {
+			key := key
+			_, err = apiKeyRepo.Create(&key)
+			require.NoError(t, err)
+		}
+		keys, err = apiKeyRepo.GetAll(workspace)
+		require.NoError(t, err)
+		assert.Equal(t, []models.APIKey{earlier, later}, keys)
+		assert.Equal(t, map[ksuid.KSUID]models.APIKey{earlier.ID: earlier, later.ID: later}, apiKeyRepo.Entries())
+	})
+	t.Run("should revoke a key once and keep it listed", func(t *testing.T) {
+		apiKeyRepo := newRepo(t)
+		key := newAPIKey("revoked", time.Now(), models.ScopeSurveysWrite)
+		_, err := apiKeyRepo.Create(&key)
+		require.NoError(t, err)
+		revokedAt := time.Now().Add(time.Hour).UTC()
+		revoked, err := apiKeyRepo.Revoke(workspace, key.ID, revokedAt)
+		require.NoError(t, err)
+		require.NotNil(t, revoked.RevokedAt)
+		assert.True(t, revokedAt.Equal(*revoked.RevokedAt))
+		again, err := apiKeyRepo.Revoke(workspace, key.ID, revokedAt.Add(time.Hour))
+		require.NoError(t, err)
+		assert.True(t, revokedAt.Equal(*again.RevokedAt))
+		stored, err := apiKeyRepo.GetByHash(key.Hash)
+		require.NoError(t, err)
+		assert.True(t, revokedAt.Equal(*stored.RevokedAt))
+		assert.False(t, stored.Allows(models.ScopeSurveysWrite))
+		keys, err := apiKeyRepo.GetAll(workspace)
+		require.NoError(t, err)
+		assert.Len(t, keys, 1)
+	})
+	t.Run("should keep the user a key is bound to", func(t *testing.T) {
+		apiKeyRepo := newRepo(t)
+		userID := ksuid.New()
+		key := newAPIKey("alice", time.Now(), models.ScopeSurveysRead)
+		key.UserID = &userID
+		_, err := apiKeyRepo.Create(&key)
+		require.NoError(t, err)
+		stored, err := apiKeyRepo.GetByHash(key.Hash)
+		require.NoError(t, err)
+		assert.Equal(t, key, *stored)
+	})
+	t.Run("should keep keys of other workspaces out of reach but authenticate them", func(t *testing.T) {
+		apiKeyRepo := newRepo(t)
+		key := newAPIKey("other", time.Now(), models.ScopeKeysManage)
+		key.WorkspaceID = otherWorkspace
+		_, err := apiKeyRepo.Create(&key)
+		require.NoError(t, err)
+		_, err = apiKeyRepo.Get(workspace, key.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		_, err = apiKeyRepo.Revoke(workspace, key.ID, time.Now())
+		assert.Equal(t, repositories.ErrNotFound, err)
+		keys, err := apiKeyRepo.GetAll(workspace)
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+		stored, err := apiKeyRepo.GetByHash(key.Hash)
+		require.NoError(t, err)
+		assert.Equal(t, key, *stored)
+		assert.True(t, stored.Allows(models.ScopeKeysManage))
+		keys, err = apiKeyRepo.GetAll(otherWorkspace)
+		require.NoError(t, err)
+		assert.Equal(t, []models.APIKey{key}, keys)
+	})
+}
+
+// UserRepoFactory returns a new empty user repo
+type UserRepoFactory func(t *testing.T) repositories.UserRepoInterface
+
+func newUser(name string, createdAt time.Time, roles ...models.RoleAssignment) models.User {
+	return models.User{
+		ID:          ksuid.New(),
+		WorkspaceID: workspace,
+		Name:        name,
+		Email:       name + "@example.com",
+		Roles:       roles,
+		CreatedAt:   createdAt.UTC(),
+	}
+}
+
+// UserRepoSuite runs the user repo contract against repos returned by newRepo
+func UserRepoSuite(t *testing.T, newRepo UserRepoFactory) {
+	surveyID := ksuid.New()
+	t.Run("should get created user along with the roles", func(t *testing.T) {
+		userRepo := newRepo(t)
+		user := newUser("alice", time.Now(), models.RoleAssignment{Role: models.RoleAnalyst}, models.RoleAssignment{Role: models.RoleEditor, SurveyID: &surveyID})
+		created, err := userRepo.Create(&user)
+		require.NoError(t, err)
+		assert.Equal(t, user, *created)
+		stored, err := userRepo.Get(workspace, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, user, *stored)
+		_, err = userRepo.Create(&user)
+		assert.Equal(t, repositories.ErrAlreadyExists, err)
+	})
+	t.Run("should return ErrNotFound for unknown users", func(t *testing.T) {
+		userRepo := newRepo(t)
+		user := newUser("alice", time.Now())
+		_, err := userRepo.Get(workspace, user.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		_, err = userRepo.Update(workspace, user.ID, &user)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Equal(t, repositories.ErrNotFound, userRepo.Delete(workspace, user.ID))
+	})
+	t.Run("should list users ordered by creation time", func(t *testing.T) {
+		userRepo := newRepo(t)
+		users, err := userRepo.GetAll(workspace)
+		require.NoError(t, err)
+		assert.Empty(t, users)
+		now := time.Now()
+		later := newUser("later", now.Add(time.Minute), models.RoleAssignment{Role: models.RoleOwner})
+		earlier := newUser("earlier", now, models.RoleAssignment{Role: models.RoleRespondent})
+		for _, user := range []models.User{later, earlier} {
+			user := user
+			_, err = userRepo.Create(&user)
+			require.NoError(t, err)
+		}
+		users, err = userRepo.GetAll(workspace)
+		require.NoError(t, err)
+		assert.Equal(t, []models.User{earlier, later}, users)
+		assert.Equal(t, map[ksuid.KSUID]models.User{earlier.ID: earlier, later.ID: later}, userRepo.Entries())
+	})
+	t.Run("should replace the roles of updated users and forget deleted ones", func(t *testing.T) {
+		userRepo := newRepo(t)
+		user := newUser("alice", time.Now(), models.RoleAssignment{Role: models.RoleAnalyst})
+		_, err := userRepo.Create(&user)
+		require.NoError(t, err)
+		user.Roles = []models.RoleAssignment{{Role: models.RoleEditor, SurveyID: &surveyID}}
+		updated, err := userRepo.Update(workspace, user.ID, &user)
+		require.NoError(t, err)
+		assert.Equal(t, user, *updated)
+		stored, err := userRepo.Get(workspace, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, user, *stored)
+		require.NoError(t, userRepo.Delete(workspace, user.ID))
+		_, err = userRepo.Get(workspace, user.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Empty(t, userRepo.Entries())
+	})
+	t.Run("should keep users of other workspaces out of reach", func(t *testing.T) {
+		userRepo := newRepo(t)
+		user := newUser("other", time.Now(), models.RoleAssignment{Role: models.RoleOwner})
+		user.WorkspaceID = otherWorkspace
+		_, err := userRepo.Create(&user)
+		require.NoError(t, err)
+		_, err = userRepo.Get(workspace, user.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		_, err = userRepo.Update(workspace, user.ID, &user)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Equal(t, repositories.ErrNotFound, userRepo.Delete(workspace, user.ID))
+		users, err := userRepo.GetAll(workspace)
+		require.NoError(t, err)
+		assert.Empty(t, users)
+		users, err = userRepo.GetAll(otherWorkspace)
+		require.NoError(t, err)
+		assert.Equal(t, []models.User{user}, users)
+	})
+}
+
+// RespondentRepoFactory returns a new empty respondent repo
+type RespondentRepoFactory func(t *testing.T) repositories.RespondentRepoInterface
+
+func newRespondent(email string, createdAt time.Time) models.Respondent {
+	return models.Respondent{ID: ksuid.New(), WorkspaceID: workspace, Email: email, Name: "name of " + email, CreatedAt: createdAt.UTC()}
+}
+
+// RespondentRepoSuite runs the respondent repo contract against repos returned by newRepo
+func RespondentRepoSuite(t *testing.T, newRepo RespondentRepoFactory) {
+	t.Run("should get created respondent by id and by email", func(t *testing.T) {
+		respondentRepo := newRepo(t)
+		respondent := newRespondent("alice@example.com", time.Now())
+		created, err := respondentRepo.Create(&respondent)
+		require.NoError(t, err)
+		assert.Equal(t, respondent, *created)
+		stored, err := respondentRepo.Get(workspace, respondent.ID)
+		require.NoError(t, err)
+		assert.Equal(t, respondent, *stored)
+		stored, err = respondentRepo.GetByEmail(workspace, respondent.Email)
+		require.NoError(t, err)
+		assert.Equal(t, respondent, *stored)
+		assert.Equal(t, map[ksuid.KSUID]models.Respondent{respondent.ID: respondent}, respondentRepo.Entries())
+	})
+	t.Run("should not create a respondent whose id or email is taken", func(t *testing.T) {
+		respondentRepo := newRepo(t)
+		respondent := newRespondent("alice@example.com", time.Now())
+		_, err := respondentRepo.Create(&respondent)
+		require.NoError(t, err)
+		_, err = respondentRepo.Create(&respondent)
+		assert.Equal(t, repositories.ErrAlreadyExists, err)
+		sameEmail := newRespondent("alice@example.com", time.Now())
+		_, err = respondentRepo.Create(&sameEmail)
+		assert.Equal(t, repositories.ErrAlreadyExists, err)
+	})
+	t.Run("should keep respondents of other workspaces out of reach", func(t *testing.T) {
+		respondentRepo := newRepo(t)
+		respondent := newRespondent("alice@example.com", time.Now())
+		respondent.WorkspaceID = otherWorkspace
+		_, err := respondentRepo.Create(&respondent)
+		require.NoError(t, err)
+		_, err = respondentRepo.Get(workspace, respondent.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		_, err = respondentRepo.GetByEmail(workspace, respondent.Email)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		sameEmail := newRespondent("alice@example.com", time.Now())
+		_, err = respondentRepo.Create(&sameEmail)
+		assert.NoError(t, err)
+	})
+}
+
+// InvitationRepoFactory returns a new empty invitation repo along with a response repo completing its invitations
+type InvitationRepoFactory func(t *testing.T) (repositories.InvitationRepoInterface, repositories.ResponseRepoInterface)
+
+func newInvitation(surveyID ksuid.KSUID, createdAt time.Time) models.Invitation {
+	return models.Invitation{ID: ksuid.New(), WorkspaceID: workspace, SurveyID: surveyID, RespondentID: ksuid.New(), CreatedAt: createdAt.UTC()}
+}
+
+// InvitationRepoSuite runs the invitation repo contract against repos returned by newRepos
+func InvitationRepoSuite(t *testing.T, newRepos InvitationRepoFactory) {
+	survey := newSurvey("invited survey", time.Now())
+	answer := func(invitation models.Invitation, createdAt time.Time) models.Response {
+		response := newResponse(survey, createdAt)
+		response.InvitationID, response.RespondentID = &invitation.ID, &invitation.RespondentID
+		return response
+	}
+	t.Run("should get created invitations by id and by survey ordered by creation time", func(t *testing.T) {
+		invitationRepo, _ := newRepos(t)
+		invitations, err := invitationRepo.GetBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Empty(t, invitations)
+		now := time.Now()
+		later, earlier := newInvitation(survey.ID, now.Add(time.Minute)), newInvitation(survey.ID, now)
+		for _, invitation := range []models.Invitation{later, earlier} {
+			invitation := invitation
+			_, err = invitationRepo.Create(&invitation)
+			require.NoError(t, err)
+		}
+		stored, err := invitationRepo.Get(workspace, later.ID)
+		require.NoError(t, err)
+		assert.Equal(t, later, *stored)
+		invitations, err = invitationRepo.GetBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []models.Invitation{earlier, later}, invitations)
+		assert.Equal(t, map[ksuid.KSUID]models.Invitation{earlier.ID: earlier, later.ID: later}, invitationRepo.Entries())
+	})
+	t.Run("should invite a respondent once to a survey", func(t *testing.T) {
+		invitationRepo, _ := newRepos(t)
+		invitation := newInvitation(survey.ID, time.Now())
+		_, err := invitationRepo.Create(&invitation)
+		require.NoError(t, err)
+		_, err = invitationRepo.Create(&invitation)
+		assert.Equal(t, repositories.ErrAlreadyExists, err)
+		again := newInvitation(survey.ID, time.Now())
+		again.RespondentID = invitation.RespondentID
+		_, err = invitationRepo.Create(&again)
+		assert.Equal(t, repositories.ErrAlreadyExists, err)
+		otherSurvey := newInvitation(ksuid.New(), time.Now())
+		otherSurvey.RespondentID = invitation.RespondentID
+		_, err = invitationRepo.Create(&otherSurvey)
+		assert.NoError(t, err)
+	})
+	t.Run("should keep the first start of an invitation", func(t *testing.T) {
+		invitationRepo, _ := newRepos(t)
+		invitation := newInvitation(survey.ID, time.Now())
+		_, err := invitationRepo.Create(&invitation)
+		require.NoError(t, err)
+		startedAt := time.Now().Add(time.Minute).UTC()
+		started, err := invitationRepo.Start(workspace, invitation.ID, startedAt)
+		require.NoError(t, err)
+		assert.Equal(t, startedAt, *started.StartedAt)
+		started, err = invitationRepo.Start(workspace, invitation.ID, startedAt.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, startedAt, *started.StartedAt)
+		_, err = invitationRepo.Start(workspace, ksuid.New(), startedAt)
+		assert.Equal(t, repositories.ErrNotFound, err)
+	})
+	t.Run("should complete the invitation answered by a created response only once", func(t *testing.T) {
+		invitationRepo, responseRepo := newRepos(t)
+		invitation := newInvitation(survey.ID, time.Now())
+		_, err := invitationRepo.Create(&invitation)
+		require.NoError(t, err)
+		response := answer(invitation, time.Now().Add(time.Minute))
+		_, err = responseRepo.Create(&response)
+		require.NoError(t, err)
+		completed, err := invitationRepo.Get(workspace, invitation.ID)
+		require.NoError(t, err)
+		assert.Equal(t, response.CreatedAt, *completed.StartedAt)
+		assert.Equal(t, response.CreatedAt, *completed.CompletedAt)
+		assert.Equal(t, response.ID, *completed.ResponseID)
+		stored, err := responseRepo.GetBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []models.Response{response}, stored)
+		again := answer(invitation, time.Now().Add(time.Hour))
+		_, err = responseRepo.Create(&again)
+		assert.Equal(t, repositories.ErrInvitationCompleted, err)
+		count, err := responseRepo.CountBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+	t.Run("should keep the invitation completed when its response is deleted", func(t *testing.T) {
+		invitationRepo, responseRepo := newRepos(t)
+		invitation := newInvitation(survey.ID, time.Now())
+		_, err := invitationRepo.Create(&invitation)
+		require.NoError(t, err)
+		response := answer(invitation, time.Now().Add(time.Minute))
+		_, err = responseRepo.Create(&response)
+		require.NoError(t, err)
+		require.NoError(t, responseRepo.Delete(workspace, response.ID))
+		completed, err := invitationRepo.Get(workspace, invitation.ID)
+		require.NoError(t, err)
+		assert.Equal(t, response.ID, *completed.ResponseID)
+		again := answer(invitation, time.Now().Add(time.Hour))
+		_, err = responseRepo.Create(&again)
+		assert.Equal(t, repositories.ErrInvitationCompleted, err)
+	})
+	t.Run("should get the invitations of every workspace which were not completed", func(t *testing.T) {
+		invitationRepo, responseRepo := newRepos(t)
+		now := time.Now()
+		completed, pending := newInvitation(survey.ID, now), newInvitation(survey.ID, now.Add(time.Minute))
+		otherWorkspaceInvitation := newInvitation(ksuid.New(), now.Add(time.Hour))
+		otherWorkspaceInvitation.WorkspaceID = otherWorkspace
+		for _, invitation := range []models.Invitation{otherWorkspaceInvitation, pending, completed} {
+			invitation := invitation
+			_, err := invitationRepo.Create(&invitation)
+			require.NoError(t, err)
+		}
+		response := answer(completed, now)
+		_, err := responseRepo.Create(&response)
+		require.NoError(t, err)
+		invitations, err := invitationRepo.GetPending()
+		require.NoError(t, err)
+		assert.Equal(t, []models.Invitation{pending, otherWorkspaceInvitation}, invitations)
+	})
+	t.Run("should not store a response answering an invitation of another survey or workspace", func(t *testing.T) {
+		invitationRepo, responseRepo := newRepos(t)
+		otherSurvey := newInvitation(ksuid.New(), time.Now())
+		otherWorkspaceInvitation := newInvitation(survey.ID, time.Now())
+		otherWorkspaceInvitation.WorkspaceID = otherWorkspace
+		for _, invitation := range []models.Invitation{otherSurvey, otherWorkspaceInvitation} {
+			invitation := invitation
+			_, err := invitationRepo.Create(&invitation)
+			require.NoError(t, err)
+			response := answer(invitation, time.Now())
+			_, err = responseRepo.Create(&response)
+			assert.Equal(t, repositories.ErrNotFound, err)
+		}
+		response := answer(newInvitation(survey.ID, time.Now()), time.Now())
+		_, err := responseRepo.Create(&response)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		count, err := responseRepo.CountBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+	t.Run("should let a single one of concurrent responses complete an invitation", func(t *testing.T) {
+		invitationRepo, responseRepo := newRepos(t)
+		invitation := newInvitation(survey.ID, time.Now())
+		_, err := invitationRepo.Create(&invitation)
+		require.NoError(t, err)
+		var wg sync.WaitGroup
+		errs := make(chan error, concurrentWriters)
+		for i := 0; i < concurrentWriters; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				response := answer(invitation, time.Now())
+				_, err := responseRepo.Create(&response)
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		completed := 0
+		for err := range errs {
+			if err == nil {
+				completed++
+			} else {
+				assert.Equal(t, repositories.ErrInvitationCompleted, err)
+			}
+		}
+		assert.Equal(t, 1, completed)
+		count, err := responseRepo.CountBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+}
+
+// OutboxRepoFactory returns a new empty outbox repo
+type OutboxRepoFactory func(t *testing.T) repositories.OutboxRepoInterface
+
+func newOutboxMessage(invitationID ksuid.KSUID, kind models.MailKind, createdAt time.Time) models.OutboxMessage {
+	return models.OutboxMessage{
+		ID:            ksuid.New(),
+		WorkspaceID:   workspace,
+		InvitationID:  invitationID,
+		Kind:          kind,
+		To:            "alice@example.com",
+		Subject:       "you are invited",
+		Body:          "answer the survey",
+		CreatedAt:     createdAt.UTC(),
+		NextAttemptAt: createdAt.UTC(),
+	}
+}
+
+// OutboxRepoSuite runs the outbox repo contract against repos returned by newRepo
+func OutboxRepoSuite(t *testing.T, newRepo OutboxRepoFactory) {
+	t.Run("should get queued messages by invitation ordered by creation time", func(t *testing.T) {
+		outboxRepo := newRepo(t)
+		invitationID, now := ksuid.New(), time.Now()
+		reminder, invitation := newOutboxMessage(invitationID, models.MailReminder, now.Add(time.Hour)), newOutboxMessage(invitationID, models.MailInvitation, now)
+		for _, message := range []models.OutboxMessage{reminder, invitation, newOutboxMessage(ksuid.New(), models.MailInvitation, now)} {
+			message := message
+			_, err := outboxRepo.Create(&message)
+			require.NoError(t, err)
+		}
+		_, err := outboxRepo.Create(&invitation)
+		assert.Equal(t, repositories.ErrAlreadyExists, err)
+		messages, err := outboxRepo.GetByInvitationID(workspace, invitationID)
+		require.NoError(t, err)
+		assert.Equal(t, []models.OutboxMessage{invitation, reminder}, messages)
+		messages, err = outboxRepo.GetByInvitationID(otherWorkspace, invitationID)
+		require.NoError(t, err)
+		assert.Empty(t, messages)
+		assert.Len(t, outboxRepo.Entries(), 3)
+	})
+	t.Run("should return the pending messages of every workspace which are due, earliest attempt first", func(t *testing.T) {
+		outboxRepo := newRepo(t)
+		now := time.Now().UTC()
+		later := newOutboxMessage(ksuid.New(), models.MailInvitation, now.Add(-time.Minute))
+		earlier := newOutboxMessage(ksuid.New(), models.MailInvitation, now.Add(-time.Hour))
+		earlier.WorkspaceID = otherWorkspace
+		notDue := newOutboxMessage(ksuid.New(), models.MailInvitation, now.Add(time.Minute))
+		sent, failed := newOutboxMessage(ksuid.New(), models.MailInvitation, now), newOutboxMessage(ksuid.New(), models.MailReminder, now)
+		sent.SentAt, failed.FailedAt = &now, &now
+		for _, message := range []models.OutboxMessage{later, earlier, notDue, sent, failed} {
+			message := message
+			_, err := outboxRepo.Create(&message)
+			require.NoError(t, err)
+		}
+		due, err := outboxRepo.Due(now, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []models.OutboxMessage{earlier, later}, due)
+		due, err = outboxRepo.Due(now, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []models.OutboxMessage{earlier}, due)
+	})
+	t.Run("should only update the delivery state of a message", func(t *testing.T) {
+		outboxRepo := newRepo(t)
+		now := time.Now().UTC()
+		message := newOutboxMessage(ksuid.New(), models.MailInvitation, now)
+		_, err := outboxRepo.Create(&message)
+		require.NoError(t, err)
+		retried := message
+		retried.Subject, retried.Body = "another subject", "another body"
+		retried.Attempts, retried.NextAttemptAt, retried.LastError = 1, now.Add(time.Minute), "connection refused"
+		require.NoError(t, outboxRepo.Update(&retried))
+		due, err := outboxRepo.Due(now, 10)
+		require.NoError(t, err)
+		assert.Empty(t, due)
+		expected := message
+		expected.Attempts, expected.NextAttemptAt, expected.LastError = 1, now.Add(time.Minute), "connection refused"
+		messages, err := outboxRepo.GetByInvitationID(workspace, message.InvitationID)
+		require.NoError(t, err)
+		assert.Equal(t, []models.OutboxMessage{expected}, messages)
+		sentAt := now.Add(time.Minute)
+		expected.Attempts, expected.LastError, expected.SentAt = 2, "", &sentAt
+		require.NoError(t, outboxRepo.Update(&expected))
+		messages, err = outboxRepo.GetByInvitationID(workspace, message.InvitationID)
+		require.NoError(t, err)
+		assert.Equal(t, []models.OutboxMessage{expected}, messages)
+		missing := newOutboxMessage(ksuid.New(), models.MailInvitation, now)
+		assert.Equal(t, repositories.ErrNotFound, outboxRepo.Update(&missing))
+	})
+}
+
+// DraftRepoFactory returns a new empty draft repo along with the response repo it submits drafts to
+type DraftRepoFactory func(t *testing.T) (repositories.DraftRepoInterface, repositories.ResponseRepoInterface)
+
+func newDraft(survey models.Survey, createdAt time.Time) models.Draft {
+	response := newResponse(survey, createdAt)
+	return models.Draft{
+		ID:            response.ID,
+		WorkspaceID:   workspace,
+		SurveyID:      survey.ID,
+		SurveyVersion: 1,
+		Answers:       response.Answers[:1],
+		TokenHash:     "hash",
+		CreatedAt:     createdAt.UTC(),
+		UpdatedAt:     createdAt.UTC(),
+		ExpiresAt:     createdAt.Add(time.Hour).UTC(),
+	}
+}
+
+// DraftRepoSuite runs the draft repo contract against repos returned by newRepos
+func DraftRepoSuite(t *testing.T, newRepos DraftRepoFactory) {
+	survey := newSurvey("drafted survey", time.Now())
+	t.Run("should get created drafts by id and by survey ordered by creation time", func(t *testing.T) {
+		draftRepo, _ := newRepos(t)
+		drafts, err := draftRepo.GetBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Empty(t, drafts)
+		now := time.Now()
+		later, earlier := newDraft(survey, now.Add(time.Minute)), newDraft(survey, now)
+		for _, draft := range []models.Draft{later, earlier} {
+			draft := draft
+			_, err = draftRepo.Create(&draft)
+			require.NoError(t, err)
+		}
+		_, err = draftRepo.Create(&later)
+		assert.Equal(t, repositories.ErrAlreadyExists, err)
+		stored, err := draftRepo.Get(workspace, later.ID)
+		require.NoError(t, err)
+		assert.Equal(t, later, *stored)
+		_, err = draftRepo.Get(otherWorkspace, later.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		drafts, err = draftRepo.GetBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []models.Draft{earlier, later}, drafts)
+		drafts, err = draftRepo.GetBySurveyID(otherWorkspace, survey.ID)
+		require.NoError(t, err)
+		assert.Empty(t, drafts)
+		assert.Equal(t, map[ksuid.KSUID]models.Draft{earlier.ID: earlier, later.ID: later}, draftRepo.Entries())
+	})
+	t.Run("should only update the answers of a draft along with its version and times", func(t *testing.T) {
+		draftRepo, _ := newRepos(t)
+		draft := newDraft(survey, time.Now())
+		_, err := draftRepo.Create(&draft)
+		require.NoError(t, err)
+		updated := draft
+		updated.TokenHash, updated.CreatedAt = "another hash", draft.CreatedAt.Add(time.Hour)
+		updated.SurveyVersion, updated.Answers = 2, newResponse(survey, draft.CreatedAt).Answers
+		updated.UpdatedAt, updated.ExpiresAt = draft.UpdatedAt.Add(time.Minute), draft.ExpiresAt.Add(time.Minute)
+		require.NoError(t, draftRepo.Update(&updated))
+		expected := draft
+		expected.SurveyVersion, expected.Answers, expected.UpdatedAt, expected.ExpiresAt = 2, updated.Answers, updated.UpdatedAt, updated.ExpiresAt
+		stored, err := draftRepo.Get(workspace, draft.ID)
+		require.NoError(t, err)
+		assert.Equal(t, expected, *stored)
+		missing := newDraft(survey, time.Now())
+		assert.Equal(t, repositories.ErrNotFound, draftRepo.Update(&missing))
+		updated.WorkspaceID = otherWorkspace
+		assert.Equal(t, repositories.ErrNotFound, draftRepo.Update(&updated))
+	})
+	t.Run("should store the response submitting a draft only once", func(t *testing.T) {
+		draftRepo, responseRepo := newRepos(t)
+		storeSurvey(t, responseRepo, survey)
+		draft := newDraft(survey, time.Now())
+		_, err := draftRepo.Create(&draft)
+		require.NoError(t, err)
+		response := draft.Response()
+		response.CreatedAt = draft.UpdatedAt.Add(time.Minute)
+		created, err := draftRepo.Submit(&response)
+		require.NoError(t, err)
+		assert.Equal(t, response, *created)
+		stored, err := draftRepo.Get(workspace, draft.ID)
+		require.NoError(t, err)
+		require.NotNil(t, stored.SubmittedAt)
+		assert.Equal(t, response.CreatedAt, *stored.SubmittedAt)
+		responses, err := responseRepo.GetBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []models.Response{response}, responses)
+		_, err = draftRepo.Submit(&response)
+		assert.Equal(t, repositories.ErrDraftSubmitted, err)
+		assert.Equal(t, repositories.ErrDraftSubmitted, draftRepo.Update(&draft))
+		count, err := responseRepo.CountBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+	t.Run("should delete the drafts of a survey only", func(t *testing.T) {
+		draftRepo, responseRepo := newRepos(t)
+		storeSurvey(t, responseRepo, survey)
+		other := newSurvey("other survey", time.Now())
+		submitted, pending, kept := newDraft(survey, time.Now()), newDraft(survey, time.Now()), newDraft(other, time.Now())
+		otherWorkspaceDraft := newDraft(survey, time.Now())
+		otherWorkspaceDraft.WorkspaceID = otherWorkspace
+		for _, draft := range []models.Draft{submitted, pending, kept, otherWorkspaceDraft} {
+			draft := draft
+			_, err := draftRepo.Create(&draft)
+			require.NoError(t, err)
+		}
+		response := submitted.Response()
+		_, err := draftRepo.Submit(&response)
+		require.NoError(t, err)
+		require.NoError(t, draftRepo.DeleteBySurveyID(workspace, survey.ID))
+		for _, draft := range []models.Draft{submitted, pending} {
+			_, err = draftRepo.Get(workspace, draft.ID)
+			assert.Equal(t, repositories.ErrNotFound, err)
+		}
+		drafts, err := draftRepo.GetBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Empty(t, drafts)
+		assert.Equal(t, map[ksuid.KSUID]models.Draft{kept.ID: kept, otherWorkspaceDraft.ID: otherWorkspaceDraft}, draftRepo.Entries())
+		require.NoError(t, draftRepo.DeleteBySurveyID(workspace, survey.ID))
+	})
+	t.Run("should delete the expired drafts and keep counting them by survey", func(t *testing.T) {
+		draftRepo, responseRepo := newRepos(t)
+		storeSurvey(t, responseRepo, survey)
+		now := time.Now()
+		cutoff := now.Add(2 * time.Hour)
+		expired, submitted, fresh := newDraft(survey, now), newDraft(survey, now), newDraft(survey, cutoff)
+		otherWorkspaceDraft := newDraft(survey, now)
+		otherWorkspaceDraft.WorkspaceID = otherWorkspace
+		for _, draft := range []models.Draft{expired, submitted, fresh, otherWorkspaceDraft} {
+			draft := draft
+			_, err := draftRepo.Create(&draft)
+			require.NoError(t, err)
+		}
+		response := submitted.Response()
+		_, err := draftRepo.Submit(&response)
+		require.NoError(t, err)
+		deleted, err := draftRepo.DeleteExpired(cutoff)
+		require.NoError(t, err)
+		assert.Equal(t, 2, deleted)
+		_, err = draftRepo.Get(workspace, expired.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		drafts, err := draftRepo.GetBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Len(t, drafts, 2)
+		tally, err := draftRepo.GetExpired(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.ExpiredDrafts{WorkspaceID: workspace, SurveyID: survey.ID, Drafts: 1,
+			Reached: map[ksuid.KSUID]int{expired.Answers[0].QuestionID: 1}}, *tally)
+		tally, err = draftRepo.GetExpired(otherWorkspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, tally.Drafts)
+		deleted, err = draftRepo.DeleteExpired(cutoff)
+		require.NoError(t, err)
+		assert.Zero(t, deleted)
+		entries, expiredEntries := draftRepo.EntriesWithExpired()
+		assert.Len(t, entries, 2)
+		assert.Len(t, expiredEntries, 2)
+		require.NoError(t, draftRepo.DeleteBySurveyID(workspace, survey.ID))
+		tally, err = draftRepo.GetExpired(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Zero(t, tally.Drafts)
+	})
+	t.Run("should not store the response of a draft which is not stored", func(t *testing.T) {
+		draftRepo, responseRepo := newRepos(t)
+		draft := newDraft(survey, time.Now())
+		_, err := draftRepo.Create(&draft)
+		require.NoError(t, err)
+		missing := newDraft(survey, time.Now()).Response()
+		_, err = draftRepo.Submit(&missing)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		otherSurvey := draft.Response()
+		otherSurvey.SurveyID = ksuid.New()
+		_, err = draftRepo.Submit(&otherSurvey)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		otherWorkspaceResponse := draft.Response()
+		otherWorkspaceResponse.WorkspaceID = otherWorkspace
+		_, err = draftRepo.Submit(&otherWorkspaceResponse)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		count, err := responseRepo.CountBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Zero(t, count)
+		stored, err := draftRepo.Get(workspace, draft.ID)
+		require.NoError(t, err)
+		assert.Nil(t, stored.SubmittedAt)
+	})
+	t.Run("should submit a draft once when submitted concurrently", func(t *testing.T) {
+		draftRepo, responseRepo := newRepos(t)
+		storeSurvey(t, responseRepo, survey)
+		draft := newDraft(survey, time.Now())
+		_, err := draftRepo.Create(&draft)
+		require.NoError(t, err)
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			submitted int
+		)
+		for i := 0; i < concurrentWriters; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				response := draft.Response()
+				_, err := draftRepo.Submit(&response)
+				mu.Lock()
+				defer mu.Unlock()
+				if err == nil {
+					submitted++
+				} else {
+					assert.Equal(t, repositories.ErrDraftSubmitted, err)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 1, submitted)
+		count, err := responseRepo.CountBySurveyID(workspace, survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+}
@@ -0,0 +1,118 @@
+package respondentrepo
+
+import (
+	"encoding/json"
+	"github.com/segmentio/ksuid"
+	"survey-platform/internal/db"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"sync"
+)
+
+// emailKey identifies the email of a respondent within its workspace
+type emailKey struct {
+	workspace models.WorkspaceID
+	email     string
+}
+
+type RespondentRepo struct {
+	mu          *sync.RWMutex
+	respondents map[ksuid.KSUID]models.Respondent
+	byEmail     map[emailKey]ksuid.KSUID
+	// journal records every mutation before it is applied, nil disables journaling
+	journal db.Journal
+}
+
+// NewRespondentRepo returns a repo holding existingRespondents
+// every mutation is appended to journal before it is applied, a nil journal disables journaling
+func NewRespondentRepo(existingRespondents map[ksuid.KSUID]models.Respondent, journal db.Journal) *RespondentRepo {
+	if existingRespondents == nil {
+		existingRespondents = make(map[ksuid.KSUID]models.Respondent)
+	}
+	respondentRepo := &RespondentRepo{
+		mu:          &sync.RWMutex{},
+		respondents: existingRespondents,
+		byEmail:     make(map[emailKey]ksuid.KSUID, len(existingRespondents)),
+		journal:     journal,
+	}
+	for id, respondent := range existingRespondents {
+		respondentRepo.byEmail[emailKey{respondent.Workspace(), respondent.Email}] = id
+	}
+	return respondentRepo
+}
+
+func (r *RespondentRepo) Create(respondent *models.Respondent) (*models.Respondent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.respondents[respondent.ID]; ok {
+		return nil, repositories.ErrAlreadyExists
+	}
+	if _, ok := r.byEmail[emailKey{respondent.Workspace(), respondent.Email}]; ok {
+		return nil, repositories.ErrAlreadyExists
+	}
+	if err := r.record(repositories.OpCreateRespondent, respondent); err != nil {
+		return nil, err
+	}
+	r.put(*respondent)
+	return respondent, nil
+}
+
+func (r *RespondentRepo) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Respondent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	respondent, ok := r.respondents[id]
+	if !ok || respondent.Workspace() != workspace {
+		return nil, repositories.ErrNotFound
+	}
+	return &respondent, nil
+}
+
+func (r *RespondentRepo) GetByEmail(workspace models.WorkspaceID, email string) (*models.Respondent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.byEmail[emailKey{workspace, email}]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	respondent := r.respondents[id]
+	return &respondent, nil
+}
+
+// Entries returns a copy of the stored respondents which is safe to read while the repo is being written to
+func (r *RespondentRepo) Entries() map[ksuid.KSUID]models.Respondent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	respondents := make(map[ksuid.KSUID]models.Respondent, len(r.respondents))
+	for id, respondent := range r.respondents {
+		respondents[id] = respondent
+	}
+	return respondents
+}
+
+// Apply replays a journaled respondent operation, creates overwrite the stored respondent
+func (r *RespondentRepo) Apply(op string, payload []byte) error {
+	if op != repositories.OpCreateRespondent {
+		return nil
+	}
+	var respondent models.Respondent
+	if err := json.Unmarshal(payload, &respondent); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.put(respondent)
+	return nil
+}
+
+// put stores respondent and indexes its email, the caller must hold mu
+func (r *RespondentRepo) put(respondent models.Respondent) {
+	r.respondents[respondent.ID] = respondent
+	r.byEmail[emailKey{respondent.Workspace(), respondent.Email}] = respondent.ID
+}
+
+func (r *RespondentRepo) record(op string, payload interface{}) error {
+	if r.journal == nil {
+		return nil
+	}
+	return r.journal.Append(op, payload)
+}
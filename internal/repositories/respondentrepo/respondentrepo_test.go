@@ -0,0 +1,90 @@
+package respondentrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"survey-platform/internal/db/db_mock"
+	"survey-platform/internal/db/jsondb"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/repotest"
+	"testing"
+)
+
+func TestNewRespondentRepo(t *testing.T) {
+	t.Run("should find existing respondents by email", func(t *testing.T) {
+		respondent := models.Respondent{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, Email: "alice@example.com"}
+		respondentRepo := NewRespondentRepo(map[ksuid.KSUID]models.Respondent{respondent.ID: respondent}, nil)
+		stored, err := respondentRepo.GetByEmail(models.DefaultWorkspace, respondent.Email)
+		assert.NoError(t, err)
+		assert.Equal(t, respondent, *stored)
+	})
+}
+
+func TestRespondentRepo_Journal(t *testing.T) {
+	t.Run("should journal respondent before storing it", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		respondent := models.Respondent{ID: ksuid.New(), Email: "alice@example.com"}
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		mockJournal.EXPECT().Append(repositories.OpCreateRespondent, &respondent).Return(nil)
+		respondentRepo := NewRespondentRepo(nil, mockJournal)
+		_, err := respondentRepo.Create(&respondent)
+		assert.NoError(t, err)
+	})
+	t.Run("should not store respondent when journal returns error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		respondent := models.Respondent{ID: ksuid.New(), Email: "alice@example.com"}
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		mockJournal.EXPECT().Append(repositories.OpCreateRespondent, &respondent).Return(errors.New("disk full"))
+		respondentRepo := NewRespondentRepo(nil, mockJournal)
+		created, err := respondentRepo.Create(&respondent)
+		assert.Error(t, err)
+		assert.Nil(t, created)
+		assert.Empty(t, respondentRepo.respondents)
+		assert.Empty(t, respondentRepo.byEmail)
+	})
+}
+
+func TestRespondentRepo_Apply(t *testing.T) {
+	t.Run("should apply journaled respondents", func(t *testing.T) {
+		respondent := models.Respondent{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, Email: "alice@example.com"}
+		payload, _ := json.Marshal(respondent)
+		respondentRepo := NewRespondentRepo(nil, nil)
+		assert.NoError(t, respondentRepo.Apply(repositories.OpCreateRespondent, payload))
+		assert.NoError(t, respondentRepo.Apply(repositories.OpCreateRespondent, payload))
+		stored, err := respondentRepo.GetByEmail(models.DefaultWorkspace, respondent.Email)
+		assert.NoError(t, err)
+		assert.Equal(t, respondent, *stored)
+	})
+	t.Run("should ignore operations of other repositories", func(t *testing.T) {
+		respondentRepo := NewRespondentRepo(nil, nil)
+		assert.NoError(t, respondentRepo.Apply(repositories.OpCreateUser, []byte("not a respondent")))
+		assert.Empty(t, respondentRepo.respondents)
+	})
+	t.Run("should return error for malformed payload", func(t *testing.T) {
+		respondentRepo := NewRespondentRepo(nil, nil)
+		assert.Error(t, respondentRepo.Apply(repositories.OpCreateRespondent, []byte("not a respondent")))
+	})
+}
+
+func TestRespondentRepo_Contract(t *testing.T) {
+	repotest.RespondentRepoSuite(t, func(t *testing.T) repositories.RespondentRepoInterface {
+		return NewRespondentRepo(nil, nil)
+	})
+}
+
+func TestRespondentRepo_JournalContract(t *testing.T) {
+	repotest.RespondentRepoSuite(t, func(t *testing.T) repositories.RespondentRepoInterface {
+		jsonDB, err := jsondb.NewJsonDB(filepath.Join(t.TempDir(), "survey_app.json"), filepath.Join(t.TempDir(), "survey_app.json.wal"), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewRespondentRepo(nil, jsonDB)
+	})
+}
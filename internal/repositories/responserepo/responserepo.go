@@ -1,52 +1,407 @@
 package responserepo
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/segmentio/ksuid"
+	"sort"
+	"survey-platform/internal/db"
 	"survey-platform/internal/models"
 	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/invitationrepo"
 	"sync"
 )
 
 type ResponseRepo struct {
 	mu        *sync.RWMutex
 	responses map[ksuid.KSUID][]models.Response
+	// surveyIDs indexes the survey of every stored response by the id of the response, so that a response is found
+	// among the responses of its survey only
+	surveyIDs map[ksuid.KSUID]ksuid.KSUID
+	journal   db.Journal
+	// invitations holds the invitations completed by the responses, nil when responses cannot answer invitations
+	invitations *invitationrepo.InvitationRepo
+	// surveyExists reports whether a survey is stored, nil when the responses of any survey are stored
+	surveyExists func(workspace models.WorkspaceID, surveyID ksuid.KSUID) bool
 }
 
-func NewResponseRepo(existingResponses map[ksuid.KSUID][]models.Response) *ResponseRepo {
+// NewResponseRepo returns a repo holding existingResponses
+// every mutation is appended to journal before it is applied, a nil journal disables journaling
+func NewResponseRepo(existingResponses map[ksuid.KSUID][]models.Response, journal db.Journal) *ResponseRepo {
 	if existingResponses == nil {
 		existingResponses = make(map[ksuid.KSUID][]models.Response)
 	}
+	surveyIDs := make(map[ksuid.KSUID]ksuid.KSUID)
+	for surveyID, responses := range existingResponses {
+		for _, response := range responses {
+			surveyIDs[response.ID] = surveyID
+		}
+	}
 	return &ResponseRepo{
 		mu:        &sync.RWMutex{},
 		responses: existingResponses,
+		surveyIDs: surveyIDs,
+		journal:   journal,
 	}
 }
 
+// WithInvitations makes the responses answering an invitation complete it in invitations, it must be called before the repo is used
+func (r *ResponseRepo) WithInvitations(invitations *invitationrepo.InvitationRepo) *ResponseRepo {
+	r.invitations = invitations
+	return r
+}
+
+// WithSurveys makes the repo refuse responses of surveys for which surveyExists returns false, it must be called before
+// the repo is used, surveyExists is called while the responses are locked so it must not lock the responses in turn
+func (r *ResponseRepo) WithSurveys(surveyExists func(workspace models.WorkspaceID, surveyID ksuid.KSUID) bool) *ResponseRepo {
+	r.surveyExists = surveyExists
+	return r
+}
+
+// Create stores response, a response answering an invitation is journaled and stored while the invitation is locked
+// so that the single journaled operation both stores the response and completes the invitation
+// ErrNotFound is returned when the survey of the response is not stored, it is checked while the responses are locked
+// so that a response is never stored after DeleteSurvey removed its survey
 func (r *ResponseRepo) Create(response *models.Response) (*models.Response, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	existingResponses, ok := r.responses[response.SurveyID]
-	if !ok {
-		r.responses[response.SurveyID] = []models.Response{*response}
-		return response, nil
+	if r.surveyExists != nil && !r.surveyExists(response.Workspace(), response.SurveyID) {
+		return nil, repositories.ErrNotFound
+	}
+	store := func() error {
+		if err := r.record(repositories.OpCreateResponse, response); err != nil {
+			return err
+		}
+		r.insert(*response)
+		return nil
+	}
+	var err error
+	switch {
+	case response.InvitationID == nil:
+		err = store()
+	case r.invitations == nil:
+		err = repositories.ErrNotFound
+	default:
+		err = r.invitations.Complete(*response, store)
+	}
+	if err != nil {
+		return nil, err
 	}
-	existingResponses = append(existingResponses, *response)
-	r.responses[response.SurveyID] = existingResponses
 	return response, nil
 }
 
-func (r *ResponseRepo) GetBySurveyID(surveyID ksuid.KSUID) ([]models.Response, error) {
+// Get returns the response with id, it is looked up among the responses of its survey
+func (r *ResponseRepo) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Response, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	responses, ok := r.responses[surveyID]
+	response, _, ok := r.find(workspace, id)
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	return &response, nil
+}
+
+// Update journals and stores the edit of response, the stored responses of the survey are copied before being changed
+func (r *ResponseRepo) Update(response *models.Response) (*models.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, _, ok := r.find(response.Workspace(), response.ID)
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	stored.SurveyVersion, stored.Answers, stored.UpdatedAt, stored.History =
+		response.SurveyVersion, response.Answers, response.UpdatedAt, response.History
+	if err := r.record(repositories.OpUpdateResponse, &stored); err != nil {
+		return nil, err
+	}
+	r.replace(stored)
+	return &stored, nil
+}
+
+// Delete journals the removal of the response before removing it
+func (r *ResponseRepo) Delete(workspace models.WorkspaceID, id ksuid.KSUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, _, ok := r.find(workspace, id)
 	if !ok {
+		return repositories.ErrNotFound
+	}
+	if err := r.record(repositories.OpDeleteResponse, &stored); err != nil {
+		return err
+	}
+	r.remove(stored)
+	return nil
+}
+
+// DeleteSurvey calls remove to journal and remove a survey while the responses are locked, as Create checks the survey
+// of a response under the same lock no response of the survey can be stored once it is removed
+// a survey without responses is removed with OpDeleteSurvey, one with responses is refused with ErrSurveyHasResponses
+// unless cascade is set, then it is purged with OpPurgeSurvey and its responses are dropped
+// remove returns ErrNotFound when the survey is not stored, which is also returned instead of ErrSurveyHasResponses
+func (r *ResponseRepo) DeleteSurvey(workspace models.WorkspaceID, surveyID ksuid.KSUID, cascade bool, remove func(op string) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := r.count(workspace, surveyID)
+	if count == 0 {
+		return remove(repositories.OpDeleteSurvey)
+	}
+	if !cascade {
+		if r.surveyExists != nil && !r.surveyExists(workspace, surveyID) {
+			return repositories.ErrNotFound
+		}
+		return repositories.ErrSurveyHasResponses
+	}
+	if err := remove(repositories.OpPurgeSurvey); err != nil {
+		return err
+	}
+	r.drop(workspace, surveyID)
+	return nil
+}
+
+// GetBySurveyID returns a copy of the responses of a survey ordered by creation time
+func (r *ResponseRepo) GetBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) ([]models.Response, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	responses := make([]models.Response, 0, len(r.responses[surveyID]))
+	for _, response := range r.responses[surveyID] {
+		if response.Workspace() == workspace {
+			responses = append(responses, response)
+		}
+	}
+	if len(responses) == 0 {
 		return nil, repositories.ErrNotFound
 	}
 	return responses, nil
 }
 
+// EachBySurveyID calls fn with the responses of a survey stored when it was called, without holding the lock while fn runs
+func (r *ResponseRepo) EachBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID, fn func(models.Response) error) error {
+	r.mu.RLock()
+	responses := r.responses[surveyID]
+	r.mu.RUnlock()
+	for _, response := range responses {
+		if response.Workspace() != workspace {
+			continue
+		}
+		if err := fn(response); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAll returns the page of responses of the workspace selected by query
+func (r *ResponseRepo) GetAll(workspace models.WorkspaceID, query models.ResponseQuery) (*models.ResponsePage, error) {
+	if query.SortBy() != models.SortCreatedAt {
+		return nil, fmt.Errorf("responses cannot be sorted by %q", query.SortBy())
+	}
+	sortBy := query.SortBy()
+	r.mu.RLock()
+	stored := r.responses[query.SurveyID]
+	r.mu.RUnlock()
+	responses := make([]models.Response, 0)
+	for _, response := range stored {
+		if response.Workspace() == workspace && query.Matches(response) && query.Follows(response.SortKey(sortBy), response.ID) {
+			responses = append(responses, response)
+		}
+	}
+	sort.Slice(responses, func(i, j int) bool {
+		return query.Less(responses[i].SortKey(sortBy), responses[i].ID, responses[j].SortKey(sortBy), responses[j].ID)
+	})
+	page := &models.ResponsePage{Responses: responses}
+	if query.Limit > 0 && len(responses) > query.Limit {
+		page.Responses = responses[:query.Limit]
+		last := page.Responses[query.Limit-1]
+		page.Next = query.NextCursor(last.SortKey(sortBy), last.ID)
+	}
+	return page, nil
+}
+
+// CountBySurveyID returns the number of responses of a survey, zero when it has none
+func (r *ResponseRepo) CountBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.count(workspace, surveyID), nil
+}
+
+// Sets returns a summary of the responses of every survey, ordered by workspace and survey
+func (r *ResponseRepo) Sets() ([]models.ResponseSet, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sets := make(map[models.WorkspaceID]map[ksuid.KSUID]*models.ResponseSet)
+	for surveyID, responses := range r.responses {
+		for _, response := range responses {
+			if sets[response.Workspace()] == nil {
+				sets[response.Workspace()] = make(map[ksuid.KSUID]*models.ResponseSet)
+			}
+			set, ok := sets[response.Workspace()][surveyID]
+			if !ok {
+				set = &models.ResponseSet{WorkspaceID: response.Workspace(), SurveyID: surveyID, FirstAt: response.CreatedAt}
+				sets[response.Workspace()][surveyID] = set
+			}
+			set.Responses++
+			if response.CreatedAt.Before(set.FirstAt) {
+				set.FirstAt = response.CreatedAt
+			}
+			if response.CreatedAt.After(set.LastAt) {
+				set.LastAt = response.CreatedAt
+			}
+		}
+	}
+	result := make([]models.ResponseSet, 0)
+	for _, surveys := range sets {
+		for _, set := range surveys {
+			result = append(result, *set)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].WorkspaceID != result[j].WorkspaceID {
+			return result[i].WorkspaceID < result[j].WorkspaceID
+		}
+		return result[i].SurveyID.String() < result[j].SurveyID.String()
+	})
+	return result, nil
+}
+
+// Entries returns a copy of the stored responses which is safe to read while the repo is being written to
 func (r *ResponseRepo) Entries() map[ksuid.KSUID][]models.Response {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	return r.responses
+	responses := make(map[ksuid.KSUID][]models.Response, len(r.responses))
+	for surveyID, surveyResponses := range r.responses {
+		responses[surveyID] = append([]models.Response(nil), surveyResponses...)
+	}
+	return responses
+}
+
+// Apply replays a journaled response operation, responses which are already stored are not created again,
+// edits which are already reflected in the history of the stored response are skipped and removing a missing response does nothing
+// purging a survey drops its responses, surveys deleted without purging keep the responses they had then
+func (r *ResponseRepo) Apply(op string, payload []byte) error {
+	if op == repositories.OpPurgeSurvey {
+		var survey models.Survey
+		if err := json.Unmarshal(payload, &survey); err != nil {
+			return err
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.drop(survey.Workspace(), survey.ID)
+		return nil
+	}
+	if op != repositories.OpCreateResponse && op != repositories.OpUpdateResponse && op != repositories.OpDeleteResponse {
+		return nil
+	}
+	var response models.Response
+	if err := json.Unmarshal(payload, &response); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, _, ok := r.find(response.Workspace(), response.ID)
+	switch {
+	case op == repositories.OpCreateResponse && !ok:
+		r.insert(response)
+	case op == repositories.OpUpdateResponse && ok && len(stored.History) < len(response.History):
+		r.replace(response)
+	case op == repositories.OpDeleteResponse && ok:
+		r.remove(stored)
+	}
+	return nil
+}
+
+// count returns the number of responses of a survey in workspace
+func (r *ResponseRepo) count(workspace models.WorkspaceID, surveyID ksuid.KSUID) int {
+	count := 0
+	for _, response := range r.responses[surveyID] {
+		if response.Workspace() == workspace {
+			count++
+		}
+	}
+	return count
+}
+
+// drop removes the responses of a survey in workspace, keeping the responses other workspaces stored under the same survey id
+func (r *ResponseRepo) drop(workspace models.WorkspaceID, surveyID ksuid.KSUID) {
+	responses := make([]models.Response, 0)
+	for _, response := range r.responses[surveyID] {
+		if response.Workspace() != workspace {
+			responses = append(responses, response)
+		} else {
+			delete(r.surveyIDs, response.ID)
+		}
+	}
+	if len(responses) == 0 {
+		delete(r.responses, surveyID)
+		return
+	}
+	r.responses[surveyID] = responses
+}
+
+// find returns the stored response with id along with its position among the responses of its survey
+func (r *ResponseRepo) find(workspace models.WorkspaceID, id ksuid.KSUID) (models.Response, int, bool) {
+	surveyID, ok := r.surveyIDs[id]
+	if !ok {
+		return models.Response{}, 0, false
+	}
+	for i, response := range r.responses[surveyID] {
+		if response.ID == id && response.Workspace() == workspace {
+			return response, i, true
+		}
+	}
+	return models.Response{}, 0, false
+}
+
+// replace stores response in place of the stored response with its id in a copy of the responses of its survey,
+// so that slices handed out by EachBySurveyID are left unchanged
+func (r *ResponseRepo) replace(response models.Response) {
+	_, i, ok := r.find(response.Workspace(), response.ID)
+	if !ok {
+		return
+	}
+	responses := append([]models.Response(nil), r.responses[response.SurveyID]...)
+	responses[i] = response
+	r.responses[response.SurveyID] = responses
+}
+
+// remove drops response from a copy of the responses of its survey
+func (r *ResponseRepo) remove(response models.Response) {
+	_, i, ok := r.find(response.Workspace(), response.ID)
+	if !ok {
+		return
+	}
+	delete(r.surveyIDs, response.ID)
+	existingResponses := r.responses[response.SurveyID]
+	if len(existingResponses) == 1 {
+		delete(r.responses, response.SurveyID)
+		return
+	}
+	responses := make([]models.Response, 0, len(existingResponses)-1)
+	responses = append(responses, existingResponses[:i]...)
+	r.responses[response.SurveyID] = append(responses, existingResponses[i+1:]...)
+}
+
+// insert keeps the responses of a survey ordered by creation time
+// responses created at the same time keep the order they were inserted in
+// stored responses are never moved in place, so slices handed out by EachBySurveyID stay valid
+func (r *ResponseRepo) insert(response models.Response) {
+	r.surveyIDs[response.ID] = response.SurveyID
+	existingResponses := r.responses[response.SurveyID]
+	i := sort.Search(len(existingResponses), func(i int) bool {
+		return existingResponses[i].CreatedAt.After(response.CreatedAt)
+	})
+	if i == len(existingResponses) {
+		r.responses[response.SurveyID] = append(existingResponses, response)
+		return
+	}
+	responses := make([]models.Response, 0, len(existingResponses)+1)
+	responses = append(responses, existingResponses[:i]...)
+	responses = append(responses, response)
+	r.responses[response.SurveyID] = append(responses, existingResponses[i:]...)
+}
+
+func (r *ResponseRepo) record(op string, payload interface{}) error {
+	if r.journal == nil {
+		return nil
+	}
+	return r.journal.Append(op, payload)
 }
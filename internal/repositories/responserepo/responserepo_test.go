@@ -1,17 +1,25 @@
 package responserepo
 
 import (
+	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
 	"github.com/segmentio/ksuid"
 	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"survey-platform/internal/db/db_mock"
+	"survey-platform/internal/db/jsondb"
 	"survey-platform/internal/models"
 	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/invitationrepo"
+	"survey-platform/internal/repositories/repotest"
 	"testing"
 	"time"
 )
 
 func TestNewResponseRepo(t *testing.T) {
 	t.Run("should initiate response repo with empty map when existing responses is nil", func(t *testing.T) {
-		responseRepo := NewResponseRepo(nil)
+		responseRepo := NewResponseRepo(nil, nil)
 		assert.NotNil(t, responseRepo.responses)
 	})
 }
@@ -31,17 +39,17 @@ func TestResponseRepo_Create(t *testing.T) {
 					Answers: []models.Answer{
 						{
 							QuestionID: qID1,
-							Answer:     true,
+							Answer:     models.BoolValue(true),
 						},
 						{
 							QuestionID: qID2,
-							Answer:     false,
+							Answer:     models.BoolValue(false),
 						},
 					},
 				},
 			},
 		}
-		responseRepo := NewResponseRepo(existingResponses)
+		responseRepo := NewResponseRepo(existingResponses, nil)
 		newResponse := models.Response{
 			ID:        ksuid.New(),
 			SurveyID:  surveyID1,
@@ -49,11 +57,11 @@ func TestResponseRepo_Create(t *testing.T) {
 			Answers: []models.Answer{
 				{
 					QuestionID: qID1,
-					Answer:     true,
+					Answer:     models.BoolValue(true),
 				},
 				{
 					QuestionID: qID2,
-					Answer:     false,
+					Answer:     models.BoolValue(false),
 				},
 			},
 		}
@@ -67,7 +75,7 @@ func TestResponseRepo_Create(t *testing.T) {
 		qID1 := ksuid.New()
 		qID2 := ksuid.New()
 		existingResponses := map[ksuid.KSUID][]models.Response{}
-		responseRepo := NewResponseRepo(existingResponses)
+		responseRepo := NewResponseRepo(existingResponses, nil)
 		newResponse := models.Response{
 			ID:        ksuid.New(),
 			SurveyID:  surveyID1,
@@ -75,11 +83,11 @@ func TestResponseRepo_Create(t *testing.T) {
 			Answers: []models.Answer{
 				{
 					QuestionID: qID1,
-					Answer:     true,
+					Answer:     models.BoolValue(true),
 				},
 				{
 					QuestionID: qID2,
-					Answer:     false,
+					Answer:     models.BoolValue(false),
 				},
 			},
 		}
@@ -101,11 +109,11 @@ func TestResponseRepo_Create(t *testing.T) {
 					Answers: []models.Answer{
 						{
 							QuestionID: q1ID1,
-							Answer:     true,
+							Answer:     models.BoolValue(true),
 						},
 						{
 							QuestionID: q1ID2,
-							Answer:     false,
+							Answer:     models.BoolValue(false),
 						},
 					},
 				},
@@ -114,7 +122,7 @@ func TestResponseRepo_Create(t *testing.T) {
 		surveyID2 := ksuid.New()
 		q2ID1 := ksuid.New()
 		q2ID2 := ksuid.New()
-		responseRepo := NewResponseRepo(existingResponses)
+		responseRepo := NewResponseRepo(existingResponses, nil)
 		newResponse := models.Response{
 			ID:        ksuid.New(),
 			SurveyID:  surveyID2,
@@ -122,11 +130,11 @@ func TestResponseRepo_Create(t *testing.T) {
 			Answers: []models.Answer{
 				{
 					QuestionID: q2ID1,
-					Answer:     true,
+					Answer:     models.BoolValue(true),
 				},
 				{
 					QuestionID: q2ID2,
-					Answer:     false,
+					Answer:     models.BoolValue(false),
 				},
 			},
 		}
@@ -152,11 +160,11 @@ func TestResponseRepo_GetBySurveyID(t *testing.T) {
 				Answers: []models.Answer{
 					{
 						QuestionID: qID1,
-						Answer:     true,
+						Answer:     models.BoolValue(true),
 					},
 					{
 						QuestionID: qID2,
-						Answer:     false,
+						Answer:     models.BoolValue(false),
 					},
 				},
 			},
@@ -167,11 +175,11 @@ func TestResponseRepo_GetBySurveyID(t *testing.T) {
 				Answers: []models.Answer{
 					{
 						QuestionID: qID1,
-						Answer:     false,
+						Answer:     models.BoolValue(false),
 					},
 					{
 						QuestionID: qID2,
-						Answer:     true,
+						Answer:     models.BoolValue(true),
 					},
 				},
 			},
@@ -179,8 +187,8 @@ func TestResponseRepo_GetBySurveyID(t *testing.T) {
 		existingEntries := map[ksuid.KSUID][]models.Response{
 			surveyID1: existingResponses,
 		}
-		responseRepo := NewResponseRepo(existingEntries)
-		responses, err := responseRepo.GetBySurveyID(surveyID1)
+		responseRepo := NewResponseRepo(existingEntries, nil)
+		responses, err := responseRepo.GetBySurveyID(models.DefaultWorkspace, surveyID1)
 		assert.NoError(t, err)
 		assert.Equal(t, existingResponses, responses)
 	})
@@ -197,11 +205,11 @@ func TestResponseRepo_GetBySurveyID(t *testing.T) {
 				Answers: []models.Answer{
 					{
 						QuestionID: qID1,
-						Answer:     true,
+						Answer:     models.BoolValue(true),
 					},
 					{
 						QuestionID: qID2,
-						Answer:     false,
+						Answer:     models.BoolValue(false),
 					},
 				},
 			},
@@ -212,11 +220,11 @@ func TestResponseRepo_GetBySurveyID(t *testing.T) {
 				Answers: []models.Answer{
 					{
 						QuestionID: qID1,
-						Answer:     false,
+						Answer:     models.BoolValue(false),
 					},
 					{
 						QuestionID: qID2,
-						Answer:     true,
+						Answer:     models.BoolValue(true),
 					},
 				},
 			},
@@ -224,8 +232,8 @@ func TestResponseRepo_GetBySurveyID(t *testing.T) {
 		existingEntries := map[ksuid.KSUID][]models.Response{
 			surveyID1: existingResponses,
 		}
-		responseRepo := NewResponseRepo(existingEntries)
-		responses, err := responseRepo.GetBySurveyID(ksuid.New())
+		responseRepo := NewResponseRepo(existingEntries, nil)
+		responses, err := responseRepo.GetBySurveyID(models.DefaultWorkspace, ksuid.New())
 		assert.Equal(t, repositories.ErrNotFound, err)
 		assert.Nil(t, responses)
 	})
@@ -245,11 +253,11 @@ func TestResponseRepo_Entries(t *testing.T) {
 				Answers: []models.Answer{
 					{
 						QuestionID: qID1,
-						Answer:     true,
+						Answer:     models.BoolValue(true),
 					},
 					{
 						QuestionID: qID2,
-						Answer:     false,
+						Answer:     models.BoolValue(false),
 					},
 				},
 			},
@@ -260,11 +268,11 @@ func TestResponseRepo_Entries(t *testing.T) {
 				Answers: []models.Answer{
 					{
 						QuestionID: qID1,
-						Answer:     false,
+						Answer:     models.BoolValue(false),
 					},
 					{
 						QuestionID: qID2,
-						Answer:     true,
+						Answer:     models.BoolValue(true),
 					},
 				},
 			},
@@ -272,8 +280,186 @@ func TestResponseRepo_Entries(t *testing.T) {
 		existingEntries := map[ksuid.KSUID][]models.Response{
 			surveyID1: existingResponses,
 		}
-		responseRepo := NewResponseRepo(existingEntries)
+		responseRepo := NewResponseRepo(existingEntries, nil)
 		entries := responseRepo.Entries()
 		assert.Equal(t, existingEntries, entries)
 	})
 }
+
+func TestResponseRepo_Journal(t *testing.T) {
+	t.Run("should journal response before storing it", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		response := models.Response{ID: ksuid.New(), SurveyID: ksuid.New(), CreatedAt: time.Now()}
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		mockJournal.EXPECT().Append(repositories.OpCreateResponse, &response).Return(nil)
+		responseRepo := NewResponseRepo(nil, mockJournal)
+		_, err := responseRepo.Create(&response)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(responseRepo.responses[response.SurveyID]))
+	})
+	t.Run("should not store response when journal returns error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		response := models.Response{ID: ksuid.New(), SurveyID: ksuid.New(), CreatedAt: time.Now()}
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		mockJournal.EXPECT().Append(repositories.OpCreateResponse, &response).Return(errors.New("disk full"))
+		responseRepo := NewResponseRepo(nil, mockJournal)
+		newResponse, err := responseRepo.Create(&response)
+		assert.Error(t, err)
+		assert.Nil(t, newResponse)
+		assert.Empty(t, responseRepo.responses)
+	})
+	t.Run("should journal edits and deletes before applying them", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		response := models.Response{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, SurveyID: ksuid.New(), CreatedAt: time.Now()}
+		edited := response
+		edited.Edit(1, []models.Answer{{QuestionID: ksuid.New(), Answer: models.BoolValue(true)}}, time.Now())
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		gomock.InOrder(
+			mockJournal.EXPECT().Append(repositories.OpUpdateResponse, &edited).Return(errors.New("disk full")),
+			mockJournal.EXPECT().Append(repositories.OpDeleteResponse, &response).Return(nil),
+		)
+		responseRepo := NewResponseRepo(map[ksuid.KSUID][]models.Response{response.SurveyID: {response}}, mockJournal)
+		_, err := responseRepo.Update(&edited)
+		assert.Error(t, err)
+		assert.Equal(t, []models.Response{response}, responseRepo.responses[response.SurveyID])
+		assert.NoError(t, responseRepo.Delete(models.DefaultWorkspace, response.ID))
+		assert.Empty(t, responseRepo.responses)
+	})
+}
+
+func TestResponseRepo_Apply(t *testing.T) {
+	t.Run("should add journaled response once", func(t *testing.T) {
+		response := models.Response{ID: ksuid.New(), SurveyID: ksuid.New()}
+		payload, _ := json.Marshal(response)
+		responseRepo := NewResponseRepo(nil, nil)
+		assert.NoError(t, responseRepo.Apply(repositories.OpCreateResponse, payload))
+		assert.NoError(t, responseRepo.Apply(repositories.OpCreateResponse, payload))
+		assert.Equal(t, []models.Response{response}, responseRepo.responses[response.SurveyID])
+	})
+	t.Run("should replay edits and deletes once", func(t *testing.T) {
+		response := models.Response{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, SurveyID: ksuid.New()}
+		edited := response
+		edited.Edit(1, []models.Answer{{QuestionID: ksuid.New(), Answer: models.BoolValue(true)}}, time.Now().UTC())
+		again := edited
+		again.Edit(1, nil, edited.UpdatedAt.Add(time.Minute))
+		created, _ := json.Marshal(response)
+		first, _ := json.Marshal(edited)
+		second, _ := json.Marshal(again)
+		responseRepo := NewResponseRepo(nil, nil)
+		for _, op := range []struct {
+			name    string
+			payload []byte
+		}{
+			{repositories.OpCreateResponse, created},
+			{repositories.OpUpdateResponse, first},
+			{repositories.OpUpdateResponse, second},
+			{repositories.OpUpdateResponse, first},
+		} {
+			assert.NoError(t, responseRepo.Apply(op.name, op.payload))
+		}
+		assert.Equal(t, []models.Response{again}, responseRepo.responses[response.SurveyID])
+		assert.NoError(t, responseRepo.Apply(repositories.OpDeleteResponse, created))
+		assert.NoError(t, responseRepo.Apply(repositories.OpDeleteResponse, created))
+		assert.Empty(t, responseRepo.responses)
+	})
+	t.Run("should drop the responses of purged surveys only", func(t *testing.T) {
+		surveyID := ksuid.New()
+		response := models.Response{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, SurveyID: surveyID}
+		other := models.Response{ID: ksuid.New(), WorkspaceID: "other", SurveyID: surveyID}
+		responseRepo := NewResponseRepo(map[ksuid.KSUID][]models.Response{surveyID: {response, other}}, nil)
+		survey, _ := json.Marshal(models.Survey{ID: surveyID, WorkspaceID: models.DefaultWorkspace})
+		assert.NoError(t, responseRepo.Apply(repositories.OpDeleteSurvey, survey))
+		assert.Len(t, responseRepo.responses[surveyID], 2)
+		assert.NoError(t, responseRepo.Apply(repositories.OpPurgeSurvey, survey))
+		assert.NoError(t, responseRepo.Apply(repositories.OpPurgeSurvey, survey))
+		assert.Equal(t, []models.Response{other}, responseRepo.responses[surveyID])
+	})
+	t.Run("should ignore operations of other repositories", func(t *testing.T) {
+		responseRepo := NewResponseRepo(nil, nil)
+		err := responseRepo.Apply(repositories.OpCreateSurvey, []byte("not a response"))
+		assert.NoError(t, err)
+		assert.Empty(t, responseRepo.responses)
+	})
+	t.Run("should return error for malformed payload", func(t *testing.T) {
+		responseRepo := NewResponseRepo(nil, nil)
+		err := responseRepo.Apply(repositories.OpCreateResponse, []byte("not a response"))
+		assert.Error(t, err)
+	})
+}
+
+func TestResponseRepo_Index(t *testing.T) {
+	t.Run("should index the survey of every response through creates, deletes and purges", func(t *testing.T) {
+		surveyID, otherSurveyID := ksuid.New(), ksuid.New()
+		existing := models.Response{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, SurveyID: surveyID}
+		responseRepo := NewResponseRepo(map[ksuid.KSUID][]models.Response{surveyID: {existing}}, nil)
+		created := models.Response{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, SurveyID: surveyID, CreatedAt: time.Now()}
+		other := models.Response{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, SurveyID: otherSurveyID, CreatedAt: time.Now()}
+		for _, response := range []models.Response{created, other} {
+			response := response
+			_, err := responseRepo.Create(&response)
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, map[ksuid.KSUID]ksuid.KSUID{existing.ID: surveyID, created.ID: surveyID, other.ID: otherSurveyID},
+			responseRepo.surveyIDs)
+		stored, err := responseRepo.Get(models.DefaultWorkspace, other.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, other, *stored)
+		assert.NoError(t, responseRepo.Delete(models.DefaultWorkspace, other.ID))
+		_, err = responseRepo.Get(models.DefaultWorkspace, other.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.NoError(t, responseRepo.DeleteSurvey(models.DefaultWorkspace, surveyID, true, func(string) error { return nil }))
+		assert.Empty(t, responseRepo.surveyIDs)
+		_, err = responseRepo.Get(models.DefaultWorkspace, existing.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+	})
+}
+
+func TestResponseRepo_EntriesCopy(t *testing.T) {
+	t.Run("should not reflect writes made after entries are returned", func(t *testing.T) {
+		surveyID := ksuid.New()
+		responseRepo := NewResponseRepo(nil, nil)
+		_, err := responseRepo.Create(&models.Response{ID: ksuid.New(), SurveyID: surveyID})
+		assert.NoError(t, err)
+		entries := responseRepo.Entries()
+		_, err = responseRepo.Create(&models.Response{ID: ksuid.New(), SurveyID: surveyID})
+		assert.NoError(t, err)
+		assert.Len(t, entries[surveyID], 1)
+	})
+}
+
+func TestResponseRepo_Contract(t *testing.T) {
+	repotest.ResponseRepoSuite(t, func(t *testing.T) repositories.ResponseRepoInterface {
+		return NewResponseRepo(nil, nil)
+	})
+}
+
+func TestResponseRepo_JournalContract(t *testing.T) {
+	repotest.ResponseRepoSuite(t, func(t *testing.T) repositories.ResponseRepoInterface {
+		jsonDB, err := jsondb.NewJsonDB(filepath.Join(t.TempDir(), "survey_app.json"), filepath.Join(t.TempDir(), "survey_app.json.wal"), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewResponseRepo(nil, jsonDB)
+	})
+}
+
+func TestResponseRepo_InvitationContract(t *testing.T) {
+	repotest.InvitationRepoSuite(t, func(t *testing.T) (repositories.InvitationRepoInterface, repositories.ResponseRepoInterface) {
+		invitationRepo := invitationrepo.NewInvitationRepo(nil, nil)
+		return invitationRepo, NewResponseRepo(nil, nil).WithInvitations(invitationRepo)
+	})
+}
+
+func TestResponseRepo_InvitationJournalContract(t *testing.T) {
+	repotest.InvitationRepoSuite(t, func(t *testing.T) (repositories.InvitationRepoInterface, repositories.ResponseRepoInterface) {
+		jsonDB, err := jsondb.NewJsonDB(filepath.Join(t.TempDir(), "survey_app.json"), filepath.Join(t.TempDir(), "survey_app.json.wal"), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		invitationRepo := invitationrepo.NewInvitationRepo(nil, jsonDB)
+		return invitationRepo, NewResponseRepo(nil, jsonDB).WithInvitations(invitationRepo)
+	})
+}
@@ -0,0 +1,128 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/segmentio/ksuid"
+	"log"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"time"
+)
+
+// APIKeyRepo stores api keys in the api_keys table, scopes are stored as a json array
+type APIKeyRepo struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepo(db *sql.DB) *APIKeyRepo {
+	return &APIKeyRepo{
+		db: db,
+	}
+}
+
+const apiKeyColumns = `id, workspace_id, user_id, name, prefix, hash, scopes, created_at, revoked_at`
+
+func (r *APIKeyRepo) Create(key *models.APIKey) (*models.APIKey, error) {
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	_, err = r.db.Exec(`INSERT INTO api_keys (`+apiKeyColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		key.ID.String(), string(key.Workspace()), formatNullID(key.UserID), key.Name, key.Prefix, key.Hash, string(scopes), formatTime(key.CreatedAt), formatNullTime(key.RevokedAt))
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (r *APIKeyRepo) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.APIKey, error) {
+	return scanAPIKey(r.db.QueryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ? AND workspace_id = ?`, id.String(), string(workspace)))
+}
+
+func (r *APIKeyRepo) GetByHash(hash string) (*models.APIKey, error) {
+	return scanAPIKey(r.db.QueryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE hash = ?`, hash))
+}
+
+// GetAll returns every key of the workspace ordered by creation time
+func (r *APIKeyRepo) GetAll(workspace models.WorkspaceID) ([]models.APIKey, error) {
+	return r.keys(`WHERE workspace_id = ?`, string(workspace))
+}
+
+// keys returns the keys matching the where clause ordered by creation time
+func (r *APIKeyRepo) keys(where string, args ...interface{}) ([]models.APIKey, error) {
+	rows, err := r.db.Query(`SELECT `+apiKeyColumns+` FROM api_keys `+where+` ORDER BY created_at, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := make([]models.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke sets revoked_at unless the key is already revoked
+func (r *APIKeyRepo) Revoke(workspace models.WorkspaceID, id ksuid.KSUID, revokedAt time.Time) (*models.APIKey, error) {
+	result, err := r.db.Exec(`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ? AND workspace_id = ?`,
+		formatTime(revokedAt), id.String(), string(workspace))
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if affected == 0 {
+		return nil, repositories.ErrNotFound
+	}
+	return r.Get(workspace, id)
+}
+
+// Entries exports every stored key, errors are logged and result in an empty export
+func (r *APIKeyRepo) Entries() map[ksuid.KSUID]models.APIKey {
+	keys, err := r.keys("")
+	if err != nil {
+		log.Println("error while exporting api keys", err)
+	}
+	entries := make(map[ksuid.KSUID]models.APIKey, len(keys))
+	for _, key := range keys {
+		entries[key.ID] = key
+	}
+	return entries
+}
+
+func scanAPIKey(row scanner) (*models.APIKey, error) {
+	var (
+		key                              models.APIKey
+		id, workspace, scopes, createdAt string
+		userID, revokedAt                sql.NullString
+	)
+	err := row.Scan(&id, &workspace, &userID, &key.Name, &key.Prefix, &key.Hash, &scopes, &createdAt, &revokedAt)
+	if err == sql.ErrNoRows {
+		return nil, repositories.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	key.WorkspaceID = models.WorkspaceID(workspace)
+	if key.ID, err = ksuid.Parse(id); err != nil {
+		return nil, err
+	}
+	if key.UserID, err = parseNullID(userID); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return nil, err
+	}
+	if key.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if key.RevokedAt, err = parseNullTime(revokedAt); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
@@ -0,0 +1,332 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/segmentio/ksuid"
+	"log"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"time"
+)
+
+// DraftRepo stores the drafts of responses in the drafts table, the answers of a draft are stored as json
+// the expired drafts which were deleted are tallied by survey in the expired_drafts table
+type DraftRepo struct {
+	db *sql.DB
+}
+
+func NewDraftRepo(db *sql.DB) *DraftRepo {
+	return &DraftRepo{
+		db: db,
+	}
+}
+
+const draftColumns = `id, workspace_id, survey_id, survey_version, invitation_id, respondent_id, answers, token_hash,
+	created_at, updated_at, expires_at, submitted_at`
+
+func (r *DraftRepo) Create(draft *models.Draft) (*models.Draft, error) {
+	answers, err := marshalAnswers(draft.Answers)
+	if err != nil {
+		return nil, err
+	}
+	_, err = r.db.Exec(`INSERT INTO drafts (`+draftColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		draft.ID.String(), string(draft.Workspace()), draft.SurveyID.String(), draft.SurveyVersion,
+		formatNullID(draft.InvitationID), formatNullID(draft.RespondentID), answers, draft.TokenHash,
+		formatTime(draft.CreatedAt), formatTime(draft.UpdatedAt), formatTime(draft.ExpiresAt), formatNullTime(draft.SubmittedAt))
+	if isPrimaryKeyConflict(err) {
+		return nil, repositories.ErrAlreadyExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
+
+func (r *DraftRepo) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Draft, error) {
+	row := r.db.QueryRow(`SELECT `+draftColumns+` FROM drafts WHERE id = ? AND workspace_id = ?`, id.String(), string(workspace))
+	draft, err := scanDraft(row)
+	if err == sql.ErrNoRows {
+		return nil, repositories.ErrNotFound
+	}
+	return draft, err
+}
+
+// GetBySurveyID returns every draft of a survey ordered by creation time, drafts created at the same time by id
+func (r *DraftRepo) GetBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) ([]models.Draft, error) {
+	return queryDrafts(r.db, `WHERE workspace_id = ? AND survey_id = ? ORDER BY created_at, id`, string(workspace), surveyID.String())
+}
+
+// Update stores the answers of draft unless it was submitted, the rest of the stored draft is kept
+func (r *DraftRepo) Update(draft *models.Draft) error {
+	answers, err := marshalAnswers(draft.Answers)
+	if err != nil {
+		return err
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	result, err := tx.Exec(`UPDATE drafts SET survey_version = ?, answers = ?, updated_at = ?, expires_at = ?
+		WHERE id = ? AND workspace_id = ? AND submitted_at IS NULL`,
+		draft.SurveyVersion, answers, formatTime(draft.UpdatedAt), formatTime(draft.ExpiresAt),
+		draft.ID.String(), string(draft.Workspace()))
+	if err != nil {
+		return err
+	}
+	if err = unsubmitted(tx, result, draft.Workspace(), draft.ID, ""); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Submit marks the draft as submitted and stores response in one transaction, so that either both are stored or none
+func (r *DraftRepo) Submit(response *models.Response) (*models.Response, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	result, err := tx.Exec(`UPDATE drafts SET submitted_at = ? WHERE id = ? AND workspace_id = ? AND survey_id = ? AND submitted_at IS NULL`,
+		formatTime(response.CreatedAt), response.ID.String(), string(response.Workspace()), response.SurveyID.String())
+	if err != nil {
+		return nil, err
+	}
+	if err = unsubmitted(tx, result, response.Workspace(), response.ID, response.SurveyID.String()); err != nil {
+		return nil, err
+	}
+	if err = insertResponse(tx, response); err != nil {
+		return nil, err
+	}
+	return response, tx.Commit()
+}
+
+// unsubmitted checks that result updated the draft id, ErrNotFound is returned when the draft is not stored
+// and ErrDraftSubmitted when the update was skipped as it was submitted, surveyID narrows the draft when it is set
+func unsubmitted(tx *sql.Tx, result sql.Result, workspace models.WorkspaceID, id ksuid.KSUID, surveyID string) error {
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 1 {
+		return nil
+	}
+	var storedSurveyID string
+	err := tx.QueryRow(`SELECT survey_id FROM drafts WHERE id = ? AND workspace_id = ?`, id.String(), string(workspace)).Scan(&storedSurveyID)
+	if err == sql.ErrNoRows || (err == nil && surveyID != "" && storedSurveyID != surveyID) {
+		return repositories.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return repositories.ErrDraftSubmitted
+}
+
+// DeleteBySurveyID deletes every draft of a survey, submitted or not, along with its expired drafts
+func (r *DraftRepo) DeleteBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.Exec(`DELETE FROM drafts WHERE workspace_id = ? AND survey_id = ?`, string(workspace), surveyID.String()); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(`DELETE FROM expired_drafts WHERE workspace_id = ? AND survey_id = ?`, string(workspace), surveyID.String()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteExpired deletes the expired drafts and adds them to the expired drafts of their surveys in one transaction
+func (r *DraftRepo) DeleteExpired(cutoff time.Time) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	const expired = `WHERE submitted_at IS NULL AND expires_at < ?`
+	drafts, err := queryDrafts(tx, expired, formatTime(cutoff))
+	if err != nil || len(drafts) == 0 {
+		return 0, err
+	}
+	tallies := make(map[string]*models.ExpiredDrafts)
+	for _, draft := range drafts {
+		key := string(draft.Workspace()) + "/" + draft.SurveyID.String()
+		tally, ok := tallies[key]
+		if !ok {
+			if tally, err = getExpired(tx, draft.Workspace(), draft.SurveyID); err != nil {
+				return 0, err
+			}
+			tallies[key] = tally
+		}
+		tally.Add(draft)
+	}
+	for _, tally := range tallies {
+		reached, err := json.Marshal(tally.Reached)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(`INSERT OR REPLACE INTO expired_drafts (survey_id, workspace_id, drafts, reached) VALUES (?, ?, ?, ?)`,
+			tally.SurveyID.String(), string(tally.Workspace()), tally.Drafts, string(reached))
+		if err != nil {
+			return 0, err
+		}
+	}
+	if _, err = tx.Exec(`DELETE FROM drafts `+expired, formatTime(cutoff)); err != nil {
+		return 0, err
+	}
+	return len(drafts), tx.Commit()
+}
+
+func (r *DraftRepo) GetExpired(workspace models.WorkspaceID, surveyID ksuid.KSUID) (*models.ExpiredDrafts, error) {
+	return getExpired(r.db, workspace, surveyID)
+}
+
+// Entries exports every stored draft, errors are logged and result in an empty export
+func (r *DraftRepo) Entries() map[ksuid.KSUID]models.Draft {
+	drafts, _ := r.EntriesWithExpired()
+	return drafts
+}
+
+// EntriesWithExpired exports every stored draft and expired drafts within one transaction,
+// errors are logged and result in an empty export
+func (r *DraftRepo) EntriesWithExpired() (map[ksuid.KSUID]models.Draft, []models.ExpiredDrafts) {
+	entries := make(map[ksuid.KSUID]models.Draft)
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Println("error while exporting drafts", err)
+		return entries, nil
+	}
+	defer tx.Rollback()
+	drafts, err := queryDrafts(tx, "")
+	if err != nil {
+		log.Println("error while exporting drafts", err)
+	}
+	for _, draft := range drafts {
+		entries[draft.ID] = draft
+	}
+	expired, err := queryExpired(tx)
+	if err != nil {
+		log.Println("error while exporting expired drafts", err)
+	}
+	return entries, expired
+}
+
+// getExpired returns the expired drafts of a survey, the tally is empty when none was stored
+func getExpired(q querier, workspace models.WorkspaceID, surveyID ksuid.KSUID) (*models.ExpiredDrafts, error) {
+	row := q.QueryRow(`SELECT survey_id, workspace_id, drafts, reached FROM expired_drafts WHERE survey_id = ? AND workspace_id = ?`,
+		surveyID.String(), string(workspace))
+	expired, err := scanExpired(row)
+	if err == sql.ErrNoRows {
+		return &models.ExpiredDrafts{WorkspaceID: workspace, SurveyID: surveyID}, nil
+	}
+	return expired, err
+}
+
+// queryExpired returns the expired drafts of every survey
+func queryExpired(q querier) ([]models.ExpiredDrafts, error) {
+	rows, err := q.Query(`SELECT survey_id, workspace_id, drafts, reached FROM expired_drafts ORDER BY workspace_id, survey_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tallies := make([]models.ExpiredDrafts, 0)
+	for rows.Next() {
+		expired, err := scanExpired(rows)
+		if err != nil {
+			return nil, err
+		}
+		tallies = append(tallies, *expired)
+	}
+	return tallies, rows.Err()
+}
+
+func scanExpired(row scanner) (*models.ExpiredDrafts, error) {
+	var (
+		expired                      models.ExpiredDrafts
+		surveyID, workspace, reached string
+	)
+	err := row.Scan(&surveyID, &workspace, &expired.Drafts, &reached)
+	if err != nil {
+		return nil, err
+	}
+	expired.WorkspaceID = models.WorkspaceID(workspace)
+	if expired.SurveyID, err = ksuid.Parse(surveyID); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(reached), &expired.Reached); err != nil {
+		return nil, err
+	}
+	return &expired, nil
+}
+
+// queryDrafts returns the drafts selected by the where clause, which also orders them
+func queryDrafts(q querier, where string, args ...interface{}) ([]models.Draft, error) {
+	rows, err := q.Query(`SELECT `+draftColumns+` FROM drafts `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	drafts := make([]models.Draft, 0)
+	for rows.Next() {
+		draft, err := scanDraft(rows)
+		if err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, *draft)
+	}
+	return drafts, rows.Err()
+}
+
+func marshalAnswers(answers []models.Answer) (string, error) {
+	if answers == nil {
+		answers = []models.Answer{}
+	}
+	encoded, err := json.Marshal(answers)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+func scanDraft(row scanner) (*models.Draft, error) {
+	var (
+		draft                                                          models.Draft
+		id, workspace, surveyID, answers, createdAt, updatedAt, expiry string
+		invitationID, respondentID, submittedAt                        sql.NullString
+	)
+	err := row.Scan(&id, &workspace, &surveyID, &draft.SurveyVersion, &invitationID, &respondentID, &answers,
+		&draft.TokenHash, &createdAt, &updatedAt, &expiry, &submittedAt)
+	if err != nil {
+		return nil, err
+	}
+	draft.WorkspaceID = models.WorkspaceID(workspace)
+	if draft.ID, err = ksuid.Parse(id); err != nil {
+		return nil, err
+	}
+	if draft.SurveyID, err = ksuid.Parse(surveyID); err != nil {
+		return nil, err
+	}
+	if draft.InvitationID, err = parseNullID(invitationID); err != nil {
+		return nil, err
+	}
+	if draft.RespondentID, err = parseNullID(respondentID); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(answers), &draft.Answers); err != nil {
+		return nil, err
+	}
+	if draft.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if draft.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return nil, err
+	}
+	if draft.ExpiresAt, err = parseTime(expiry); err != nil {
+		return nil, err
+	}
+	if draft.SubmittedAt, err = parseNullTime(submittedAt); err != nil {
+		return nil, err
+	}
+	return &draft, nil
+}
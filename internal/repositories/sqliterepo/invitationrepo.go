@@ -0,0 +1,165 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"github.com/segmentio/ksuid"
+	"log"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"time"
+)
+
+// InvitationRepo stores invitations in the invitations table, they are completed by ResponseRepo.Create
+// in the transaction storing the response
+type InvitationRepo struct {
+	db *sql.DB
+}
+
+func NewInvitationRepo(db *sql.DB) *InvitationRepo {
+	return &InvitationRepo{
+		db: db,
+	}
+}
+
+const invitationColumns = `id, workspace_id, survey_id, respondent_id, created_at, started_at, completed_at, response_id`
+
+func (r *InvitationRepo) Create(invitation *models.Invitation) (*models.Invitation, error) {
+	_, err := r.db.Exec(`INSERT INTO invitations (`+invitationColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		invitation.ID.String(), string(invitation.Workspace()), invitation.SurveyID.String(), invitation.RespondentID.String(),
+		formatTime(invitation.CreatedAt), formatNullTime(invitation.StartedAt), formatNullTime(invitation.CompletedAt),
+		formatNullID(invitation.ResponseID))
+	if isUniqueConflict(err) {
+		return nil, repositories.ErrAlreadyExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+func (r *InvitationRepo) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Invitation, error) {
+	return scanInvitation(r.db.QueryRow(`SELECT `+invitationColumns+` FROM invitations WHERE id = ? AND workspace_id = ?`,
+		id.String(), string(workspace)))
+}
+
+// GetBySurveyID returns every invitation to a survey ordered by creation time
+func (r *InvitationRepo) GetBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) ([]models.Invitation, error) {
+	return r.invitations(`WHERE workspace_id = ? AND survey_id = ?`, string(workspace), surveyID.String())
+}
+
+// Start marks the invitation as started at startedAt unless it already started
+func (r *InvitationRepo) Start(workspace models.WorkspaceID, id ksuid.KSUID, startedAt time.Time) (*models.Invitation, error) {
+	result, err := r.db.Exec(`UPDATE invitations SET started_at = COALESCE(started_at, ?) WHERE id = ? AND workspace_id = ?`,
+		formatTime(startedAt), id.String(), string(workspace))
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if affected == 0 {
+		return nil, repositories.ErrNotFound
+	}
+	return r.Get(workspace, id)
+}
+
+// GetPending returns the invitations of every workspace which were not completed, ordered by creation time
+func (r *InvitationRepo) GetPending() ([]models.Invitation, error) {
+	return r.invitations(`WHERE completed_at IS NULL`)
+}
+
+// Entries exports every stored invitation, errors are logged and result in an empty export
+func (r *InvitationRepo) Entries() map[ksuid.KSUID]models.Invitation {
+	invitations, err := r.invitations("")
+	if err != nil {
+		log.Println("error while exporting invitations", err)
+	}
+	entries := make(map[ksuid.KSUID]models.Invitation, len(invitations))
+	for _, invitation := range invitations {
+		entries[invitation.ID] = invitation
+	}
+	return entries
+}
+
+// invitations returns the invitations matching the where clause ordered by creation time
+func (r *InvitationRepo) invitations(where string, args ...interface{}) ([]models.Invitation, error) {
+	rows, err := r.db.Query(`SELECT `+invitationColumns+` FROM invitations `+where+` ORDER BY created_at, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	invitations := make([]models.Invitation, 0)
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, *invitation)
+	}
+	return invitations, rows.Err()
+}
+
+// completeInvitation completes the invitation answered by response within tx
+// ErrNotFound is returned when the invitation is not one of the survey and ErrInvitationCompleted when it was already answered
+func completeInvitation(tx *sql.Tx, response *models.Response) error {
+	id, workspace, surveyID := response.InvitationID.String(), string(response.Workspace()), response.SurveyID.String()
+	completedAt := formatTime(response.CreatedAt)
+	result, err := tx.Exec(`UPDATE invitations SET started_at = COALESCE(started_at, ?), completed_at = ?, response_id = ?
+		WHERE id = ? AND workspace_id = ? AND survey_id = ? AND completed_at IS NULL`,
+		completedAt, completedAt, response.ID.String(), id, workspace, surveyID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 1 {
+		return nil
+	}
+	var found int
+	err = tx.QueryRow(`SELECT 1 FROM invitations WHERE id = ? AND workspace_id = ? AND survey_id = ?`,
+		id, workspace, surveyID).Scan(&found)
+	if err == sql.ErrNoRows {
+		return repositories.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return repositories.ErrInvitationCompleted
+}
+
+func scanInvitation(row scanner) (*models.Invitation, error) {
+	var (
+		invitation                                       models.Invitation
+		id, workspace, surveyID, respondentID, createdAt string
+		startedAt, completedAt, responseID               sql.NullString
+	)
+	err := row.Scan(&id, &workspace, &surveyID, &respondentID, &createdAt, &startedAt, &completedAt, &responseID)
+	if err == sql.ErrNoRows {
+		return nil, repositories.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	invitation.WorkspaceID = models.WorkspaceID(workspace)
+	if invitation.ID, err = ksuid.Parse(id); err != nil {
+		return nil, err
+	}
+	if invitation.SurveyID, err = ksuid.Parse(surveyID); err != nil {
+		return nil, err
+	}
+	if invitation.RespondentID, err = ksuid.Parse(respondentID); err != nil {
+		return nil, err
+	}
+	if invitation.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if invitation.StartedAt, err = parseNullTime(startedAt); err != nil {
+		return nil, err
+	}
+	if invitation.CompletedAt, err = parseNullTime(completedAt); err != nil {
+		return nil, err
+	}
+	if invitation.ResponseID, err = parseNullID(responseID); err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
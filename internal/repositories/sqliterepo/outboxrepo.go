@@ -0,0 +1,130 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"github.com/segmentio/ksuid"
+	"log"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"time"
+)
+
+// OutboxRepo stores the emails to the respondents of invitations in the outbox table
+type OutboxRepo struct {
+	db *sql.DB
+}
+
+func NewOutboxRepo(db *sql.DB) *OutboxRepo {
+	return &OutboxRepo{
+		db: db,
+	}
+}
+
+const outboxColumns = `id, workspace_id, invitation_id, kind, recipient, subject, body, created_at,
+	attempts, next_attempt_at, last_error, sent_at, failed_at`
+
+func (r *OutboxRepo) Create(message *models.OutboxMessage) (*models.OutboxMessage, error) {
+	_, err := r.db.Exec(`INSERT INTO outbox (`+outboxColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		message.ID.String(), string(message.Workspace()), message.InvitationID.String(), string(message.Kind),
+		message.To, message.Subject, message.Body, formatTime(message.CreatedAt), message.Attempts,
+		formatTime(message.NextAttemptAt), message.LastError, formatNullTime(message.SentAt), formatNullTime(message.FailedAt))
+	if isUniqueConflict(err) {
+		return nil, repositories.ErrAlreadyExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// Due returns up to limit pending messages of every workspace whose next attempt is at or before now, earliest attempt first
+func (r *OutboxRepo) Due(now time.Time, limit int) ([]models.OutboxMessage, error) {
+	return r.messages(`WHERE sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?`,
+		formatTime(now), limit)
+}
+
+// GetByInvitationID returns the messages sent for an invitation ordered by creation time
+func (r *OutboxRepo) GetByInvitationID(workspace models.WorkspaceID, invitationID ksuid.KSUID) ([]models.OutboxMessage, error) {
+	return r.messages(`WHERE workspace_id = ? AND invitation_id = ? ORDER BY created_at, id`, string(workspace), invitationID.String())
+}
+
+// Update stores the delivery state of message, the rest of the stored message is kept
+func (r *OutboxRepo) Update(message *models.OutboxMessage) error {
+	result, err := r.db.Exec(`UPDATE outbox SET attempts = ?, next_attempt_at = ?, last_error = ?, sent_at = ?, failed_at = ? WHERE id = ?`,
+		message.Attempts, formatTime(message.NextAttemptAt), message.LastError, formatNullTime(message.SentAt),
+		formatNullTime(message.FailedAt), message.ID.String())
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+// Entries exports every stored message, errors are logged and result in an empty export
+func (r *OutboxRepo) Entries() map[ksuid.KSUID]models.OutboxMessage {
+	messages, err := r.messages("")
+	if err != nil {
+		log.Println("error while exporting outbox", err)
+	}
+	entries := make(map[ksuid.KSUID]models.OutboxMessage, len(messages))
+	for _, message := range messages {
+		entries[message.ID] = message
+	}
+	return entries
+}
+
+// messages returns the messages selected by the where clause, which also orders and limits them
+func (r *OutboxRepo) messages(where string, args ...interface{}) ([]models.OutboxMessage, error) {
+	rows, err := r.db.Query(`SELECT `+outboxColumns+` FROM outbox `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	messages := make([]models.OutboxMessage, 0)
+	for rows.Next() {
+		message, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, *message)
+	}
+	return messages, rows.Err()
+}
+
+func scanOutboxMessage(row scanner) (*models.OutboxMessage, error) {
+	var (
+		message                                                   models.OutboxMessage
+		id, workspace, invitationID, kind, createdAt, nextAttempt string
+		sentAt, failedAt                                          sql.NullString
+	)
+	err := row.Scan(&id, &workspace, &invitationID, &kind, &message.To, &message.Subject, &message.Body, &createdAt,
+		&message.Attempts, &nextAttempt, &message.LastError, &sentAt, &failedAt)
+	if err != nil {
+		return nil, err
+	}
+	message.WorkspaceID = models.WorkspaceID(workspace)
+	message.Kind = models.MailKind(kind)
+	if message.ID, err = ksuid.Parse(id); err != nil {
+		return nil, err
+	}
+	if message.InvitationID, err = ksuid.Parse(invitationID); err != nil {
+		return nil, err
+	}
+	if message.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if message.NextAttemptAt, err = parseTime(nextAttempt); err != nil {
+		return nil, err
+	}
+	if message.SentAt, err = parseNullTime(sentAt); err != nil {
+		return nil, err
+	}
+	if message.FailedAt, err = parseNullTime(failedAt); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
@@ -0,0 +1,60 @@
+package sqliterepo
+
+import (
+	"fmt"
+	"strings"
+	"survey-platform/internal/models"
+)
+
+// maxBoundIDs is the most ids bound as parameters of a single IN clause
+const maxBoundIDs = 500
+
+// sortColumns are the columns listings can be ordered by, the sort keys of times are formatted like the stored times
+var sortColumns = map[models.SortField]string{
+	models.SortCreatedAt: "created_at",
+	models.SortUpdatedAt: "updated_at",
+	models.SortName:      "name",
+}
+
+// pageClause returns the conditions shared by every listing, the creation time bounds and the position after the cursor,
+// along with the ORDER BY and LIMIT clauses of the page, one more row than the limit is read to tell whether a next page exists
+func pageClause(query models.PageQuery) (conditions []string, args []interface{}, orderLimit string, err error) {
+	column, ok := sortColumns[query.SortBy()]
+	if !ok {
+		return nil, nil, "", fmt.Errorf("cannot sort by %q", query.SortBy())
+	}
+	if query.CreatedFrom != nil {
+		conditions = append(conditions, `created_at >= ?`)
+		args = append(args, formatTime(*query.CreatedFrom))
+	}
+	if query.CreatedTo != nil {
+		conditions = append(conditions, `created_at <= ?`)
+		args = append(args, formatTime(*query.CreatedTo))
+	}
+	direction, comparison := "ASC", ">"
+	if query.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	if query.After != nil {
+		conditions = append(conditions, fmt.Sprintf(`(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))`, column, comparison))
+		args = append(args, query.After.Key, query.After.Key, query.After.ID.String())
+	}
+	orderLimit = fmt.Sprintf(`ORDER BY %[1]s %[2]s, id %[2]s`, column, direction)
+	if query.Limit > 0 {
+		orderLimit += fmt.Sprintf(` LIMIT %d`, query.Limit+1)
+	}
+	return conditions, args, orderLimit, nil
+}
+
+// where joins conditions into a WHERE clause, empty without conditions
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return `WHERE ` + strings.Join(conditions, ` AND `)
+}
+
+// placeholders returns n comma separated placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,90 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"github.com/segmentio/ksuid"
+	"log"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+)
+
+// RespondentRepo stores respondents in the respondents table, emails are unique within a workspace
+type RespondentRepo struct {
+	db *sql.DB
+}
+
+func NewRespondentRepo(db *sql.DB) *RespondentRepo {
+	return &RespondentRepo{
+		db: db,
+	}
+}
+
+const respondentColumns = `id, workspace_id, email, name, created_at`
+
+func (r *RespondentRepo) Create(respondent *models.Respondent) (*models.Respondent, error) {
+	_, err := r.db.Exec(`INSERT INTO respondents (`+respondentColumns+`) VALUES (?, ?, ?, ?, ?)`,
+		respondent.ID.String(), string(respondent.Workspace()), respondent.Email, respondent.Name, formatTime(respondent.CreatedAt))
+	if isUniqueConflict(err) {
+		return nil, repositories.ErrAlreadyExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return respondent, nil
+}
+
+func (r *RespondentRepo) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Respondent, error) {
+	return scanRespondent(r.db.QueryRow(`SELECT `+respondentColumns+` FROM respondents WHERE id = ? AND workspace_id = ?`,
+		id.String(), string(workspace)))
+}
+
+func (r *RespondentRepo) GetByEmail(workspace models.WorkspaceID, email string) (*models.Respondent, error) {
+	return scanRespondent(r.db.QueryRow(`SELECT `+respondentColumns+` FROM respondents WHERE email = ? AND workspace_id = ?`,
+		email, string(workspace)))
+}
+
+// Entries exports every stored respondent, errors are logged and result in an empty export
+func (r *RespondentRepo) Entries() map[ksuid.KSUID]models.Respondent {
+	rows, err := r.db.Query(`SELECT ` + respondentColumns + ` FROM respondents`)
+	if err != nil {
+		log.Println("error while exporting respondents", err)
+		return map[ksuid.KSUID]models.Respondent{}
+	}
+	defer rows.Close()
+	entries := make(map[ksuid.KSUID]models.Respondent)
+	for rows.Next() {
+		respondent, err := scanRespondent(rows)
+		if err != nil {
+			log.Println("error while exporting respondents", err)
+			return map[ksuid.KSUID]models.Respondent{}
+		}
+		entries[respondent.ID] = *respondent
+	}
+	if err = rows.Err(); err != nil {
+		log.Println("error while exporting respondents", err)
+		return map[ksuid.KSUID]models.Respondent{}
+	}
+	return entries
+}
+
+func scanRespondent(row scanner) (*models.Respondent, error) {
+	var (
+		respondent               models.Respondent
+		id, workspace, createdAt string
+	)
+	err := row.Scan(&id, &workspace, &respondent.Email, &respondent.Name, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, repositories.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	respondent.WorkspaceID = models.WorkspaceID(workspace)
+	if respondent.ID, err = ksuid.Parse(id); err != nil {
+		return nil, err
+	}
+	if respondent.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	return &respondent, nil
+}
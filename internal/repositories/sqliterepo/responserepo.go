@@ -0,0 +1,352 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/segmentio/ksuid"
+	"log"
+	"sort"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+)
+
+// ResponseRepo stores responses and their answers in sqlite tables
+type ResponseRepo struct {
+	db *sql.DB
+}
+
+func NewResponseRepo(db *sql.DB) *ResponseRepo {
+	return &ResponseRepo{
+		db: db,
+	}
+}
+
+func (r *ResponseRepo) Create(response *models.Response) (*models.Response, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if err = insertResponse(tx, response); err != nil {
+		return nil, err
+	}
+	return response, tx.Commit()
+}
+
+// insertResponse stores response and its answers in tx, completing the invitation it answers
+// the response is only inserted while its survey is stored, ErrNotFound is returned otherwise so that a response
+// committed after its survey was deleted is never left behind
+func insertResponse(tx *sql.Tx, response *models.Response) error {
+	if response.InvitationID != nil {
+		if err := completeInvitation(tx, response); err != nil {
+			return err
+		}
+	}
+	history, err := marshalHistory(response.History)
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec(`INSERT INTO responses (id, workspace_id, survey_id, survey_version, invitation_id, respondent_id, created_at,
+		updated_at, history) SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?
+		WHERE EXISTS (SELECT 1 FROM surveys WHERE id = ? AND workspace_id = ?)`,
+		response.ID.String(), string(response.Workspace()), response.SurveyID.String(), response.SurveyVersion,
+		formatNullID(response.InvitationID), formatNullID(response.RespondentID), formatTime(response.CreatedAt),
+		formatNullTime(response.UpdatedAt), history, response.SurveyID.String(), string(response.Workspace()))
+	if isPrimaryKeyConflict(err) {
+		return repositories.ErrAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return repositories.ErrNotFound
+	}
+	return insertAnswers(tx, response)
+}
+
+// insertAnswers stores the answers of response in tx in their order
+func insertAnswers(tx *sql.Tx, response *models.Response) error {
+	for position, answer := range response.Answers {
+		value, err := json.Marshal(answer.Answer)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO answers (response_id, position, question_id, value) VALUES (?, ?, ?, ?)`,
+			response.ID.String(), position, answer.QuestionID.String(), string(value))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ResponseRepo) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Response, error) {
+	responses, err := r.responses(`WHERE r.id = ? AND r.workspace_id = ?`, id.String(), string(workspace))
+	if err != nil {
+		return nil, err
+	}
+	for _, surveyResponses := range responses {
+		return &surveyResponses[0], nil
+	}
+	return nil, repositories.ErrNotFound
+}
+
+// Update replaces the edited columns of the response and its answers in a transaction
+func (r *ResponseRepo) Update(response *models.Response) (*models.Response, error) {
+	history, err := marshalHistory(response.History)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	result, err := tx.Exec(`UPDATE responses SET survey_version = ?, updated_at = ?, history = ? WHERE id = ? AND workspace_id = ?`,
+		response.SurveyVersion, formatNullTime(response.UpdatedAt), history, response.ID.String(), string(response.Workspace()))
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if affected == 0 {
+		return nil, repositories.ErrNotFound
+	}
+	if _, err = tx.Exec(`DELETE FROM answers WHERE response_id = ?`, response.ID.String()); err != nil {
+		return nil, err
+	}
+	if err = insertAnswers(tx, response); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.Get(response.Workspace(), response.ID)
+}
+
+// Delete removes the response, its answers are removed along with it
+func (r *ResponseRepo) Delete(workspace models.WorkspaceID, id ksuid.KSUID) error {
+	result, err := r.db.Exec(`DELETE FROM responses WHERE id = ? AND workspace_id = ?`, id.String(), string(workspace))
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+func (r *ResponseRepo) GetBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) ([]models.Response, error) {
+	responses, err := r.responses(`WHERE r.survey_id = ? AND r.workspace_id = ?`, surveyID.String(), string(workspace))
+	if err != nil {
+		return nil, err
+	}
+	if len(responses) == 0 {
+		return nil, repositories.ErrNotFound
+	}
+	return responses[surveyID], nil
+}
+
+// GetAll returns the page of responses of the workspace selected by query, the page is selected by id before the answers are joined
+func (r *ResponseRepo) GetAll(workspace models.WorkspaceID, query models.ResponseQuery) (*models.ResponsePage, error) {
+	if query.SortBy() != models.SortCreatedAt {
+		return nil, fmt.Errorf("responses cannot be sorted by %q", query.SortBy())
+	}
+	conditions, args, orderLimit, err := pageClause(query.PageQuery)
+	if err != nil {
+		return nil, err
+	}
+	conditions = append([]string{`survey_id = ?`, `workspace_id = ?`}, conditions...)
+	args = append([]interface{}{query.SurveyID.String(), string(workspace)}, args...)
+	if query.Versions != nil {
+		versions := make([]interface{}, 0, len(query.Versions))
+		for _, version := range query.Versions {
+			versions = append(versions, version)
+		}
+		// responses stored before versions were introduced may have version 0, they answered version 1
+		conditions = append(conditions, `(CASE survey_version WHEN 0 THEN 1 ELSE survey_version END) IN (`+placeholders(len(versions))+`)`)
+		args = append(args, versions...)
+	}
+	selected, err := r.responses(`WHERE r.id IN (SELECT id FROM responses `+where(conditions)+` `+orderLimit+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	responses := selected[query.SurveyID]
+	if responses == nil {
+		responses = make([]models.Response, 0)
+	}
+	sortBy := query.SortBy()
+	sort.Slice(responses, func(i, j int) bool {
+		return query.Less(responses[i].SortKey(sortBy), responses[i].ID, responses[j].SortKey(sortBy), responses[j].ID)
+	})
+	page := &models.ResponsePage{Responses: responses}
+	if query.Limit > 0 && len(responses) > query.Limit {
+		page.Responses = responses[:query.Limit]
+		last := page.Responses[query.Limit-1]
+		page.Next = query.NextCursor(last.SortKey(sortBy), last.ID)
+	}
+	return page, nil
+}
+
+// exportBatchSize is the number of responses EachBySurveyID reads at once
+// the connection is released between batches, so other queries are not blocked while fn runs
+const exportBatchSize = 500
+
+// EachBySurveyID reads the responses of a survey in batches, paging on creation time and id
+func (r *ResponseRepo) EachBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID, fn func(models.Response) error) error {
+	var lastCreatedAt, lastID string
+	for {
+		batch, err := r.responses(`WHERE r.id IN (SELECT id FROM responses WHERE survey_id = ? AND workspace_id = ?
+			AND (created_at > ? OR (created_at = ? AND id > ?)) ORDER BY created_at, id LIMIT ?)`,
+			surveyID.String(), string(workspace), lastCreatedAt, lastCreatedAt, lastID, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		responses := batch[surveyID]
+		for _, response := range responses {
+			if err = fn(response); err != nil {
+				return err
+			}
+		}
+		if len(responses) < exportBatchSize {
+			return nil
+		}
+		last := responses[len(responses)-1]
+		lastCreatedAt, lastID = formatTime(last.CreatedAt), last.ID.String()
+	}
+}
+
+// CountBySurveyID returns the number of responses of a survey, zero when it has none
+func (r *ResponseRepo) CountBySurveyID(workspace models.WorkspaceID, surveyID ksuid.KSUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM responses WHERE survey_id = ? AND workspace_id = ?`,
+		surveyID.String(), string(workspace)).Scan(&count)
+	return count, err
+}
+
+// Sets returns a summary of the responses of every survey, ordered by workspace and survey
+func (r *ResponseRepo) Sets() ([]models.ResponseSet, error) {
+	rows, err := r.db.Query(`SELECT workspace_id, survey_id, COUNT(*), MIN(created_at), MAX(created_at)
+		FROM responses GROUP BY workspace_id, survey_id ORDER BY workspace_id, survey_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sets := make([]models.ResponseSet, 0)
+	for rows.Next() {
+		var workspace, surveyID, firstAt, lastAt string
+		var set models.ResponseSet
+		if err = rows.Scan(&workspace, &surveyID, &set.Responses, &firstAt, &lastAt); err != nil {
+			return nil, err
+		}
+		set.WorkspaceID = models.WorkspaceID(workspace)
+		if set.SurveyID, err = ksuid.Parse(surveyID); err != nil {
+			return nil, err
+		}
+		if set.FirstAt, err = parseTime(firstAt); err != nil {
+			return nil, err
+		}
+		if set.LastAt, err = parseTime(lastAt); err != nil {
+			return nil, err
+		}
+		sets = append(sets, set)
+	}
+	return sets, rows.Err()
+}
+
+// Entries exports every stored response, errors are logged and result in an empty export
+func (r *ResponseRepo) Entries() map[ksuid.KSUID][]models.Response {
+	responses, err := r.responses("")
+	if err != nil {
+		log.Println("error while exporting responses", err)
+		return map[ksuid.KSUID][]models.Response{}
+	}
+	return responses
+}
+
+// responses returns the responses matching the where clause grouped by survey, ordered by creation time
+func (r *ResponseRepo) responses(where string, args ...interface{}) (map[ksuid.KSUID][]models.Response, error) {
+	rows, err := r.db.Query(`SELECT r.id, r.workspace_id, r.survey_id, r.survey_version, r.invitation_id, r.respondent_id,
+		r.created_at, r.updated_at, r.history, a.question_id, a.value
+		FROM responses r LEFT JOIN answers a ON a.response_id = r.id `+where+`
+		ORDER BY r.survey_id, r.created_at, r.id, a.position`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	responses := make(map[ksuid.KSUID][]models.Response)
+	var current *models.Response
+	for rows.Next() {
+		var id, workspace, surveyID, createdAt, history string
+		var surveyVersion int
+		var invitationID, respondentID, updatedAt sql.NullString
+		var questionID sql.NullString
+		var value sql.NullString
+		if err = rows.Scan(&id, &workspace, &surveyID, &surveyVersion, &invitationID, &respondentID, &createdAt, &updatedAt, &history,
+			&questionID, &value); err != nil {
+			return nil, err
+		}
+		if current == nil || current.ID.String() != id {
+			if current != nil {
+				responses[current.SurveyID] = append(responses[current.SurveyID], *current)
+			}
+			current = &models.Response{WorkspaceID: models.WorkspaceID(workspace), SurveyVersion: surveyVersion}
+			if current.ID, err = ksuid.Parse(id); err != nil {
+				return nil, err
+			}
+			if current.SurveyID, err = ksuid.Parse(surveyID); err != nil {
+				return nil, err
+			}
+			if current.InvitationID, err = parseNullID(invitationID); err != nil {
+				return nil, err
+			}
+			if current.RespondentID, err = parseNullID(respondentID); err != nil {
+				return nil, err
+			}
+			if current.CreatedAt, err = parseTime(createdAt); err != nil {
+				return nil, err
+			}
+			if current.UpdatedAt, err = parseNullTime(updatedAt); err != nil {
+				return nil, err
+			}
+			if err = json.Unmarshal([]byte(history), &current.History); err != nil {
+				return nil, err
+			}
+			if len(current.History) == 0 {
+				current.History = nil
+			}
+		}
+		if !questionID.Valid {
+			continue
+		}
+		parsedQuestionID, err := ksuid.Parse(questionID.String)
+		if err != nil {
+			return nil, err
+		}
+		answer := models.Answer{QuestionID: parsedQuestionID}
+		if err = json.Unmarshal([]byte(value.String), &answer.Answer); err != nil {
+			return nil, err
+		}
+		current.Answers = append(current.Answers, answer)
+	}
+	if current != nil {
+		responses[current.SurveyID] = append(responses[current.SurveyID], *current)
+	}
+	return responses, rows.Err()
+}
+
+func marshalHistory(history []models.ResponseRevision) (string, error) {
+	if history == nil {
+		history = []models.ResponseRevision{}
+	}
+	encoded, err := json.Marshal(history)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
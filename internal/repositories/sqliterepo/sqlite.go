@@ -0,0 +1,310 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"github.com/segmentio/ksuid"
+	"survey-platform/internal/models"
+	"time"
+)
+
+// timestamps are stored as fixed width UTC text so that they sort in time order, like the sort keys of listings
+const timeLayout = models.SortTimeLayout
+
+// migrations create and upgrade the schema, the number of applied migrations is kept in user_version
+// databases created before user_version was tracked are at zero, so the first migration only creates missing tables
+var migrations = [][]string{
+	{
+		`CREATE TABLE IF NOT EXISTS surveys (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS surveys_created_at ON surveys (created_at)`,
+		`CREATE TABLE IF NOT EXISTS questions (
+			id TEXT NOT NULL,
+			survey_id TEXT NOT NULL REFERENCES surveys (id) ON DELETE CASCADE,
+			position INTEGER NOT NULL,
+			question TEXT NOT NULL,
+			PRIMARY KEY (survey_id, id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS questions_survey_id ON questions (survey_id, position)`,
+		`CREATE TABLE IF NOT EXISTS responses (
+			id TEXT PRIMARY KEY,
+			survey_id TEXT NOT NULL,
+			created_at TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS responses_survey_id ON responses (survey_id, created_at)`,
+		`CREATE INDEX IF NOT EXISTS responses_created_at ON responses (created_at)`,
+		`CREATE TABLE IF NOT EXISTS answers (
+			response_id TEXT NOT NULL REFERENCES responses (id) ON DELETE CASCADE,
+			position INTEGER NOT NULL,
+			question_id TEXT NOT NULL,
+			answer INTEGER NOT NULL,
+			PRIMARY KEY (response_id, position)
+		)`,
+	},
+	{
+		`ALTER TABLE questions ADD COLUMN type TEXT NOT NULL DEFAULT 'yes_no'`,
+		`ALTER TABLE questions ADD COLUMN config TEXT NOT NULL DEFAULT '{}'`,
+		`CREATE TABLE answers_typed (
+			response_id TEXT NOT NULL REFERENCES responses (id) ON DELETE CASCADE,
+			position INTEGER NOT NULL,
+			question_id TEXT NOT NULL,
+			value TEXT NOT NULL,
+			PRIMARY KEY (response_id, position)
+		)`,
+		`INSERT INTO answers_typed (response_id, position, question_id, value)
+			SELECT response_id, position, question_id, CASE answer WHEN 0 THEN 'false' ELSE 'true' END FROM answers`,
+		`DROP TABLE answers`,
+		`ALTER TABLE answers_typed RENAME TO answers`,
+	},
+	{
+		`ALTER TABLE surveys ADD COLUMN rules TEXT NOT NULL DEFAULT '[]'`,
+	},
+	{
+		`ALTER TABLE surveys ADD COLUMN status TEXT NOT NULL DEFAULT 'published'`,
+		`CREATE INDEX surveys_status ON surveys (status, created_at)`,
+	},
+	{
+		`ALTER TABLE surveys ADD COLUMN opens_at TEXT`,
+		`ALTER TABLE surveys ADD COLUMN closes_at TEXT`,
+		`ALTER TABLE surveys ADD COLUMN max_responses INTEGER NOT NULL DEFAULT 0`,
+	},
+	{
+		`ALTER TABLE surveys ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+		`ALTER TABLE responses ADD COLUMN survey_version INTEGER NOT NULL DEFAULT 1`,
+		`CREATE TABLE survey_versions (
+			survey_id TEXT NOT NULL REFERENCES surveys (id) ON DELETE CASCADE,
+			version INTEGER NOT NULL,
+			data TEXT NOT NULL,
+			PRIMARY KEY (survey_id, version)
+		)`,
+	},
+	{
+		`CREATE TABLE api_keys (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			prefix TEXT NOT NULL,
+			hash TEXT NOT NULL UNIQUE,
+			scopes TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			revoked_at TEXT
+		)`,
+	},
+	{
+		`ALTER TABLE surveys ADD COLUMN workspace_id TEXT NOT NULL DEFAULT 'default'`,
+		`CREATE INDEX surveys_workspace_id ON surveys (workspace_id, created_at)`,
+		`ALTER TABLE responses ADD COLUMN workspace_id TEXT NOT NULL DEFAULT 'default'`,
+		`ALTER TABLE api_keys ADD COLUMN workspace_id TEXT NOT NULL DEFAULT 'default'`,
+	},
+	{
+		`CREATE TABLE users (
+			id TEXT PRIMARY KEY,
+			workspace_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			email TEXT NOT NULL,
+			roles TEXT NOT NULL,
+			created_at TEXT NOT NULL
+		)`,
+		`CREATE INDEX users_workspace_id ON users (workspace_id, created_at)`,
+		`ALTER TABLE api_keys ADD COLUMN user_id TEXT`,
+	},
+	{
+		`CREATE TABLE respondents (
+			id TEXT PRIMARY KEY,
+			workspace_id TEXT NOT NULL,
+			email TEXT NOT NULL,
+			name TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			UNIQUE (workspace_id, email)
+		)`,
+		`CREATE TABLE invitations (
+			id TEXT PRIMARY KEY,
+			workspace_id TEXT NOT NULL,
+			survey_id TEXT NOT NULL,
+			respondent_id TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			started_at TEXT,
+			completed_at TEXT,
+			response_id TEXT,
+			UNIQUE (survey_id, respondent_id)
+		)`,
+		`CREATE INDEX invitations_survey_id ON invitations (workspace_id, survey_id, created_at)`,
+		`ALTER TABLE responses ADD COLUMN invitation_id TEXT`,
+		`ALTER TABLE responses ADD COLUMN respondent_id TEXT`,
+		`CREATE UNIQUE INDEX responses_invitation_id ON responses (invitation_id) WHERE invitation_id IS NOT NULL`,
+	},
+	{
+		`CREATE TABLE outbox (
+			id TEXT PRIMARY KEY,
+			workspace_id TEXT NOT NULL,
+			invitation_id TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			recipient TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			body TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			attempts INTEGER NOT NULL,
+			next_attempt_at TEXT NOT NULL,
+			last_error TEXT NOT NULL,
+			sent_at TEXT,
+			failed_at TEXT
+		)`,
+		`CREATE INDEX outbox_invitation_id ON outbox (workspace_id, invitation_id, created_at)`,
+		`CREATE INDEX outbox_due ON outbox (next_attempt_at) WHERE sent_at IS NULL AND failed_at IS NULL`,
+	},
+	{
+		`CREATE TABLE drafts (
+			id TEXT PRIMARY KEY,
+			workspace_id TEXT NOT NULL,
+			survey_id TEXT NOT NULL,
+			survey_version INTEGER NOT NULL,
+			invitation_id TEXT,
+			respondent_id TEXT,
+			answers TEXT NOT NULL,
+			token_hash TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL,
+			expires_at TEXT NOT NULL,
+			submitted_at TEXT
+		)`,
+		`CREATE INDEX drafts_survey_id ON drafts (workspace_id, survey_id, created_at)`,
+	},
+	{
+		`ALTER TABLE surveys ADD COLUMN edit_window_seconds INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE responses ADD COLUMN updated_at TEXT`,
+		`ALTER TABLE responses ADD COLUMN history TEXT NOT NULL DEFAULT '[]'`,
+	},
+	{
+		`CREATE TABLE expired_drafts (
+			workspace_id TEXT NOT NULL,
+			survey_id TEXT NOT NULL,
+			drafts INTEGER NOT NULL,
+			reached TEXT NOT NULL,
+			PRIMARY KEY (workspace_id, survey_id)
+		)`,
+		`CREATE INDEX drafts_expires_at ON drafts (expires_at) WHERE submitted_at IS NULL`,
+	},
+}
+
+// Open opens the sqlite database at fileName and migrates it to the latest schema
+// a single connection is used, sqlite serialises writers anyway and this avoids busy errors
+func Open(fileName string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", fileName+"?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if err = migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	for ; version < len(migrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		for _, statement := range migrations[version] {
+			if _, err = tx.Exec(statement); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("migration %d: %w", version+1, err)
+			}
+		}
+		if _, err = tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, version+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+func parseTime(value string) (time.Time, error) {
+	t, err := time.Parse(timeLayout, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// formatNullTime stores an optional time, nil is stored as NULL
+func formatNullTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: formatTime(*t), Valid: true}
+}
+
+// parseNullTime reads a time stored by formatNullTime
+func parseNullTime(value sql.NullString) (*time.Time, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+	t, err := parseTime(value.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// formatNullID stores an optional id, nil is stored as NULL
+func formatNullID(id *ksuid.KSUID) sql.NullString {
+	if id == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: id.String(), Valid: true}
+}
+
+// parseNullID reads an id stored by formatNullID
+func parseNullID(value sql.NullString) (*ksuid.KSUID, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+	id, err := ksuid.Parse(value.String)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// isPrimaryKeyConflict reports whether err is a violation of a primary key
+func isPrimaryKeyConflict(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+}
+
+// isUniqueConflict reports whether err is a violation of a primary key or of a unique constraint
+func isUniqueConflict(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey || sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique)
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
@@ -0,0 +1,254 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/repotest"
+	"testing"
+	"time"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := Open(filepath.Join(t.TempDir(), "survey_app.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newSurvey(name string, createdAt time.Time) models.Survey {
+	return models.Survey{
+		ID:          ksuid.New(),
+		WorkspaceID: models.DefaultWorkspace,
+		Name:        name,
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+		Questions: []models.Question{
+			{ID: ksuid.New(), Question: "is this place good?"},
+			{ID: ksuid.New(), Question: "does this place has parking?"},
+		},
+	}
+}
+
+func newResponse(surveyID ksuid.KSUID, createdAt time.Time, questions ...models.Question) models.Response {
+	response := models.Response{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, SurveyID: surveyID, CreatedAt: createdAt}
+	for i, question := range questions {
+		response.Answers = append(response.Answers, models.Answer{QuestionID: question.ID, Answer: models.BoolValue(i%2 == 0)})
+	}
+	return response
+}
+
+func TestSurveyRepo_Contract(t *testing.T) {
+	repotest.SurveyRepoSuite(t, func(t *testing.T) repositories.SurveyRepoInterface {
+		return NewSurveyRepo(openTestDB(t))
+	})
+}
+
+func TestSurveyRepo_DeletionContract(t *testing.T) {
+	repotest.SurveyDeletionSuite(t, func(t *testing.T) (repositories.SurveyRepoInterface, repositories.ResponseRepoInterface) {
+		db := openTestDB(t)
+		return NewSurveyRepo(db), NewResponseRepo(db)
+	})
+}
+
+func TestResponseRepo_Contract(t *testing.T) {
+	repotest.ResponseRepoSuite(t, func(t *testing.T) repositories.ResponseRepoInterface {
+		db := openTestDB(t)
+		return repotest.WithSurveys(NewResponseRepo(db), NewSurveyRepo(db))
+	})
+}
+
+func TestAPIKeyRepo_Contract(t *testing.T) {
+	repotest.APIKeyRepoSuite(t, func(t *testing.T) repositories.APIKeyRepoInterface {
+		return NewAPIKeyRepo(openTestDB(t))
+	})
+}
+
+func TestUserRepo_Contract(t *testing.T) {
+	repotest.UserRepoSuite(t, func(t *testing.T) repositories.UserRepoInterface {
+		return NewUserRepo(openTestDB(t))
+	})
+}
+
+func TestRespondentRepo_Contract(t *testing.T) {
+	repotest.RespondentRepoSuite(t, func(t *testing.T) repositories.RespondentRepoInterface {
+		return NewRespondentRepo(openTestDB(t))
+	})
+}
+
+func TestInvitationRepo_Contract(t *testing.T) {
+	repotest.InvitationRepoSuite(t, func(t *testing.T) (repositories.InvitationRepoInterface, repositories.ResponseRepoInterface) {
+		db := openTestDB(t)
+		return NewInvitationRepo(db), repotest.WithSurveys(NewResponseRepo(db), NewSurveyRepo(db))
+	})
+}
+
+func TestOutboxRepo_Contract(t *testing.T) {
+	repotest.OutboxRepoSuite(t, func(t *testing.T) repositories.OutboxRepoInterface {
+		return NewOutboxRepo(openTestDB(t))
+	})
+}
+
+func TestDraftRepo_Contract(t *testing.T) {
+	repotest.DraftRepoSuite(t, func(t *testing.T) (repositories.DraftRepoInterface, repositories.ResponseRepoInterface) {
+		db := openTestDB(t)
+		return NewDraftRepo(db), repotest.WithSurveys(NewResponseRepo(db), NewSurveyRepo(db))
+	})
+}
+
+func TestOpen(t *testing.T) {
+	t.Run("should create schema and allow reopening existing database", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "survey_app.db")
+		db, err := Open(fileName)
+		assert.NoError(t, err)
+		survey := newSurvey("new survey", time.Now().UTC())
+		_, err = NewSurveyRepo(db).Create(&survey)
+		assert.NoError(t, err)
+		db.Close()
+		reopened, err := Open(fileName)
+		assert.NoError(t, err)
+		defer reopened.Close()
+		storedSurvey, err := NewSurveyRepo(reopened).Get(models.DefaultWorkspace, survey.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, survey, *storedSurvey)
+	})
+	t.Run("should migrate surveys, questions and answers of databases created by the first release", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "survey_app.db")
+		db, err := sql.Open("sqlite3", fileName)
+		assert.NoError(t, err)
+		for _, statement := range migrations[0] {
+			_, err = db.Exec(statement)
+			assert.NoError(t, err)
+		}
+		survey := newSurvey("old survey", time.Now().UTC())
+		response := newResponse(survey.ID, time.Now().UTC(), survey.Questions...)
+		_, err = db.Exec(`INSERT INTO surveys (id, name, created_at, updated_at) VALUES (?, ?, ?, ?)`,
+			survey.ID.String(), survey.Name, formatTime(survey.CreatedAt), formatTime(survey.UpdatedAt))
+		assert.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO responses (id, survey_id, created_at) VALUES (?, ?, ?)`,
+			response.ID.String(), survey.ID.String(), formatTime(response.CreatedAt))
+		assert.NoError(t, err)
+		for position, question := range survey.Questions {
+			_, err = db.Exec(`INSERT INTO questions (id, survey_id, position, question) VALUES (?, ?, ?, ?)`,
+				question.ID.String(), survey.ID.String(), position, question.Question)
+			assert.NoError(t, err)
+			_, err = db.Exec(`INSERT INTO answers (response_id, position, question_id, answer) VALUES (?, ?, ?, ?)`,
+				response.ID.String(), position, question.ID.String(), response.Answers[position].Answer.Bool)
+			assert.NoError(t, err)
+		}
+		db.Close()
+		migrated, err := Open(fileName)
+		assert.NoError(t, err)
+		defer migrated.Close()
+		storedSurvey, err := NewSurveyRepo(migrated).Get(models.DefaultWorkspace, survey.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.SurveyPublished, storedSurvey.Status)
+		assert.Equal(t, 1, storedSurvey.Version)
+		assert.Equal(t, models.DefaultWorkspace, storedSurvey.WorkspaceID)
+		for _, question := range storedSurvey.Questions {
+			assert.Equal(t, models.QuestionYesNo, question.Type)
+		}
+		responses, err := NewResponseRepo(migrated).GetBySurveyID(models.DefaultWorkspace, survey.ID)
+		assert.NoError(t, err)
+		response.SurveyVersion = 1
+		assert.Equal(t, []models.Response{response}, responses)
+		versions, err := NewSurveyRepo(migrated).GetVersions(models.DefaultWorkspace, survey.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Survey{*storedSurvey}, versions)
+	})
+	t.Run("should return error when database cannot be created", func(t *testing.T) {
+		db, err := Open(filepath.Join(t.TempDir(), "missing", "survey_app.db"))
+		assert.Error(t, err)
+		assert.Nil(t, db)
+	})
+}
+
+func TestSurveyRepo_Create(t *testing.T) {
+	t.Run("should return error when survey id already exists", func(t *testing.T) {
+		surveyRepo := NewSurveyRepo(openTestDB(t))
+		survey := newSurvey("new survey", time.Now().UTC())
+		_, err := surveyRepo.Create(&survey)
+		assert.NoError(t, err)
+		_, err = surveyRepo.Create(&survey)
+		assert.Error(t, err)
+	})
+}
+
+func TestSurveyRepo_Delete(t *testing.T) {
+	t.Run("should successfully delete survey with its questions", func(t *testing.T) {
+		db := openTestDB(t)
+		surveyRepo := NewSurveyRepo(db)
+		survey := newSurvey("new survey", time.Now().UTC())
+		_, err := surveyRepo.Create(&survey)
+		assert.NoError(t, err)
+		assert.NoError(t, surveyRepo.Delete(models.DefaultWorkspace, survey.ID, false))
+		_, err = surveyRepo.Get(models.DefaultWorkspace, survey.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		var questions int
+		assert.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM questions`).Scan(&questions))
+		assert.Zero(t, questions)
+	})
+}
+
+func TestResponseRepo_Create(t *testing.T) {
+	t.Run("should add responses to survey keeping answer order", func(t *testing.T) {
+		db := openTestDB(t)
+		responseRepo := NewResponseRepo(db)
+		survey := newSurvey("new survey", time.Now().UTC())
+		_, err := NewSurveyRepo(db).Create(&survey)
+		assert.NoError(t, err)
+		now := time.Now().UTC()
+		response1 := newResponse(survey.ID, now, survey.Questions[1], survey.Questions[0])
+		response2 := newResponse(survey.ID, now.Add(time.Second), survey.Questions...)
+		_, err = responseRepo.Create(&response1)
+		assert.NoError(t, err)
+		_, err = responseRepo.Create(&response2)
+		assert.NoError(t, err)
+		responses, err := responseRepo.GetBySurveyID(models.DefaultWorkspace, survey.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Response{response1, response2}, responses)
+	})
+	t.Run("should store response without answers", func(t *testing.T) {
+		db := openTestDB(t)
+		responseRepo := NewResponseRepo(db)
+		survey := newSurvey("new survey", time.Now().UTC())
+		_, err := NewSurveyRepo(db).Create(&survey)
+		assert.NoError(t, err)
+		response := newResponse(survey.ID, time.Now().UTC())
+		_, err = responseRepo.Create(&response)
+		assert.NoError(t, err)
+		responses, err := responseRepo.GetBySurveyID(models.DefaultWorkspace, response.SurveyID)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Response{response}, responses)
+	})
+}
+
+func TestResponseRepo_EachBySurveyID(t *testing.T) {
+	t.Run("should page through more responses than fit in a batch", func(t *testing.T) {
+		db := openTestDB(t)
+		responseRepo := NewResponseRepo(db)
+		survey, now := newSurvey("new survey", time.Now().UTC()), time.Now().UTC()
+		_, err := NewSurveyRepo(db).Create(&survey)
+		assert.NoError(t, err)
+		surveyID := survey.ID
+		for i := 0; i <= exportBatchSize; i++ {
+			response := newResponse(surveyID, now.Add(time.Duration(i%3)*time.Second))
+			_, err := responseRepo.Create(&response)
+			assert.NoError(t, err)
+		}
+		expected, err := responseRepo.GetBySurveyID(models.DefaultWorkspace, surveyID)
+		assert.NoError(t, err)
+		var responses []models.Response
+		err = responseRepo.EachBySurveyID(models.DefaultWorkspace, surveyID, func(response models.Response) error {
+			responses = append(responses, response)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, responses)
+	})
+}
@@ -0,0 +1,410 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/segmentio/ksuid"
+	"log"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+)
+
+// SurveyRepo stores surveys and their questions in sqlite tables
+type SurveyRepo struct {
+	db *sql.DB
+}
+
+func NewSurveyRepo(db *sql.DB) *SurveyRepo {
+	return &SurveyRepo{
+		db: db,
+	}
+}
+
+const surveyColumns = `id, workspace_id, name, version, status, created_at, updated_at, rules, opens_at, closes_at, max_responses,
+	edit_window_seconds`
+
+func (s *SurveyRepo) Create(survey *models.Survey) (*models.Survey, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	rules, err := json.Marshal(survey.Rules)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`INSERT INTO surveys (`+surveyColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		survey.ID.String(), string(survey.Workspace()), survey.Name, survey.Version, string(survey.Status), formatTime(survey.CreatedAt),
+		formatTime(survey.UpdatedAt), string(rules), formatNullTime(survey.OpensAt), formatNullTime(survey.ClosesAt), survey.MaxResponses,
+		survey.EditWindowSeconds)
+	if isPrimaryKeyConflict(err) {
+		return nil, repositories.ErrAlreadyExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = insertQuestions(tx, survey); err != nil {
+		return nil, err
+	}
+	if err = putVersion(tx, `INSERT OR REPLACE`, survey); err != nil {
+		return nil, err
+	}
+	return survey, tx.Commit()
+}
+
+func (s *SurveyRepo) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error) {
+	row := s.db.QueryRow(`SELECT `+surveyColumns+` FROM surveys WHERE id = ? AND workspace_id = ?`, id.String(), string(workspace))
+	survey, err := scanSurvey(row)
+	if err == sql.ErrNoRows {
+		return nil, repositories.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	questions, err := s.questions(`WHERE survey_id = ?`, id.String())
+	if err != nil {
+		return nil, err
+	}
+	survey.Questions = questions[id]
+	return survey, nil
+}
+
+// Update replaces the survey and stores it as its version
+// surveys created before versions were introduced have no stored version yet, so the replaced survey is kept as well
+func (s *SurveyRepo) Update(workspace models.WorkspaceID, id ksuid.KSUID, survey *models.Survey) (*models.Survey, error) {
+	previous, err := s.Get(workspace, id)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if err = putVersion(tx, `INSERT OR IGNORE`, previous); err != nil {
+		return nil, err
+	}
+	rules, err := json.Marshal(survey.Rules)
+	if err != nil {
+		return nil, err
+	}
+	result, err := tx.Exec(`UPDATE surveys SET name = ?, version = ?, status = ?, created_at = ?, updated_at = ?, rules = ?,
+		opens_at = ?, closes_at = ?, max_responses = ?, edit_window_seconds = ? WHERE id = ? AND workspace_id = ?`,
+		survey.Name, survey.Version, string(survey.Status), formatTime(survey.CreatedAt), formatTime(survey.UpdatedAt), string(rules),
+		formatNullTime(survey.OpensAt), formatNullTime(survey.ClosesAt), survey.MaxResponses, survey.EditWindowSeconds,
+		id.String(), string(workspace))
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if affected == 0 {
+		return nil, repositories.ErrNotFound
+	}
+	if _, err = tx.Exec(`DELETE FROM questions WHERE survey_id = ?`, id.String()); err != nil {
+		return nil, err
+	}
+	if err = insertQuestions(tx, survey); err != nil {
+		return nil, err
+	}
+	if err = putVersion(tx, `INSERT OR REPLACE`, survey); err != nil {
+		return nil, err
+	}
+	return survey, tx.Commit()
+}
+
+// Delete removes the survey along with its questions and versions, the responses are counted and removed
+// within the same transaction so that a response stored meanwhile is never left behind
+func (s *SurveyRepo) Delete(workspace models.WorkspaceID, id ksuid.KSUID, cascade bool) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var responses int
+	if err = tx.QueryRow(`SELECT COUNT(*) FROM responses WHERE survey_id = ? AND workspace_id = ?`,
+		id.String(), string(workspace)).Scan(&responses); err != nil {
+		return err
+	}
+	result, err := tx.Exec(`DELETE FROM surveys WHERE id = ? AND workspace_id = ?`, id.String(), string(workspace))
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repositories.ErrNotFound
+	}
+	if responses > 0 && !cascade {
+		return repositories.ErrSurveyHasResponses
+	}
+	if _, err = tx.Exec(`DELETE FROM responses WHERE survey_id = ? AND workspace_id = ?`, id.String(), string(workspace)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetAll returns the page of surveys of the workspace selected by query, the filters, order and page are pushed down to sqlite
+func (s *SurveyRepo) GetAll(workspace models.WorkspaceID, query models.SurveyQuery) (*models.SurveyPage, error) {
+	conditions, args, orderLimit, err := pageClause(query.PageQuery)
+	if err != nil {
+		return nil, err
+	}
+	conditions = append([]string{`workspace_id = ?`}, conditions...)
+	args = append([]interface{}{string(workspace)}, args...)
+	if query.Name != "" {
+		conditions = append(conditions, `instr(name, ?) > 0`)
+		args = append(args, query.Name)
+	}
+	if query.Status != "" {
+		conditions = append(conditions, `(status = ? OR (status = '' AND ? = ?))`)
+		args = append(args, string(query.Status), string(query.Status), string(models.SurveyPublished))
+	}
+	surveys, err := s.surveys(where(conditions)+` `+orderLimit, args...)
+	if err != nil {
+		return nil, err
+	}
+	page := &models.SurveyPage{Surveys: surveys}
+	if query.Limit > 0 && len(surveys) > query.Limit {
+		page.Surveys = surveys[:query.Limit]
+		last := page.Surveys[query.Limit-1]
+		page.Next = query.NextCursor(last.SortKey(query.SortBy()), last.ID)
+	}
+	return page, nil
+}
+
+// Entries exports every stored survey, errors are logged and result in an empty export
+func (s *SurveyRepo) Entries() map[ksuid.KSUID]models.Survey {
+	surveys, err := s.surveys(`ORDER BY created_at, id`)
+	if err != nil {
+		log.Println("error while exporting surveys", err)
+	}
+	entries := make(map[ksuid.KSUID]models.Survey, len(surveys))
+	for _, survey := range surveys {
+		entries[survey.ID] = survey
+	}
+	return entries
+}
+
+// GetVersions returns every version of a survey oldest first
+func (s *SurveyRepo) GetVersions(workspace models.WorkspaceID, id ksuid.KSUID) ([]models.Survey, error) {
+	survey, err := s.Get(workspace, id)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := s.versions(`WHERE survey_id = ?`, id.String())
+	if err != nil {
+		return nil, err
+	}
+	return withCurrent(versions[id], *survey), nil
+}
+
+// VersionEntries exports the versions of every stored survey, errors are logged and result in an empty export
+func (s *SurveyRepo) VersionEntries() map[ksuid.KSUID][]models.Survey {
+	surveys, err := s.surveys(`ORDER BY created_at, id`)
+	if err != nil {
+		log.Println("error while exporting surveys", err)
+		return map[ksuid.KSUID][]models.Survey{}
+	}
+	versions, err := s.versions("")
+	if err != nil {
+		log.Println("error while exporting survey versions", err)
+		return map[ksuid.KSUID][]models.Survey{}
+	}
+	for _, survey := range surveys {
+		versions[survey.ID] = withCurrent(versions[survey.ID], survey)
+	}
+	return versions
+}
+
+// versions returns the stored versions matching the where clause grouped by survey, oldest first
+func (s *SurveyRepo) versions(where string, args ...interface{}) (map[ksuid.KSUID][]models.Survey, error) {
+	rows, err := s.db.Query(`SELECT data FROM survey_versions `+where+` ORDER BY survey_id, version`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	versions := make(map[ksuid.KSUID][]models.Survey)
+	for rows.Next() {
+		var data string
+		if err = rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var version models.Survey
+		if err = json.Unmarshal([]byte(data), &version); err != nil {
+			return nil, err
+		}
+		versions[version.ID] = append(versions[version.ID], version)
+	}
+	return versions, rows.Err()
+}
+
+// withCurrent makes sure the current survey is the last version, surveys never updated since versions were introduced have no stored version
+// versions stored before workspaces were introduced are put in the workspace of the current survey
+func withCurrent(versions []models.Survey, current models.Survey) []models.Survey {
+	for i := range versions {
+		versions[i].WorkspaceID = current.WorkspaceID
+	}
+	if len(versions) == 0 || versions[len(versions)-1].CurrentVersion() != current.CurrentVersion() {
+		return append(versions, current)
+	}
+	return versions
+}
+
+// putVersion stores survey as its version, insert is the INSERT statement deciding what happens to a stored version with the same number
+func putVersion(tx *sql.Tx, insert string, survey *models.Survey) error {
+	data, err := json.Marshal(survey)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(insert+` INTO survey_versions (survey_id, version, data) VALUES (?, ?, ?)`,
+		survey.ID.String(), survey.CurrentVersion(), string(data))
+	return err
+}
+
+// surveys returns the surveys selected by clause with their questions, clause holds the WHERE, ORDER BY and LIMIT clauses
+func (s *SurveyRepo) surveys(clause string, args ...interface{}) ([]models.Survey, error) {
+	rows, err := s.db.Query(`SELECT `+surveyColumns+` FROM surveys `+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	surveys := make([]models.Survey, 0)
+	for rows.Next() {
+		survey, err := scanSurvey(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		surveys = append(surveys, *survey)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(surveys) == 0 {
+		return surveys, nil
+	}
+	// the questions of large selections are read in full rather than binding an id per survey
+	questionsWhere, ids := "", make([]interface{}, 0, len(surveys))
+	if len(surveys) <= maxBoundIDs {
+		for _, survey := range surveys {
+			ids = append(ids, survey.ID.String())
+		}
+		questionsWhere = `WHERE survey_id IN (` + placeholders(len(ids)) + `)`
+	}
+	questions, err := s.questions(questionsWhere, ids...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range surveys {
+		surveys[i].Questions = questions[surveys[i].ID]
+	}
+	return surveys, nil
+}
+
+// questions returns the questions matching the where clause grouped by survey in their original order
+func (s *SurveyRepo) questions(where string, args ...interface{}) (map[ksuid.KSUID][]models.Question, error) {
+	rows, err := s.db.Query(`SELECT survey_id, id, question, type, config FROM questions `+where+` ORDER BY survey_id, position`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	questions := make(map[ksuid.KSUID][]models.Question)
+	for rows.Next() {
+		var surveyID, questionID, questionType, config string
+		var question models.Question
+		if err = rows.Scan(&surveyID, &questionID, &question.Question, &questionType, &config); err != nil {
+			return nil, err
+		}
+		question.Type = models.QuestionType(questionType)
+		if err = json.Unmarshal([]byte(config), (*questionConfig)(&question)); err != nil {
+			return nil, err
+		}
+		parsedSurveyID, err := ksuid.Parse(surveyID)
+		if err != nil {
+			return nil, err
+		}
+		if question.ID, err = ksuid.Parse(questionID); err != nil {
+			return nil, err
+		}
+		questions[parsedSurveyID] = append(questions[parsedSurveyID], question)
+	}
+	return questions, rows.Err()
+}
+
+// questionConfig is the json stored in the config column, the type specific settings of a question
+type questionConfig models.Question
+
+func (q *questionConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Options   []string `json:"options,omitempty"`
+		Min       *float64 `json:"min,omitempty"`
+		Max       *float64 `json:"max,omitempty"`
+		MaxLength int      `json:"max_length,omitempty"`
+		Required  bool     `json:"required,omitempty"`
+	}{q.Options, q.Min, q.Max, q.MaxLength, q.Required})
+}
+
+func (q *questionConfig) UnmarshalJSON(data []byte) error {
+	var config struct {
+		Options   []string `json:"options"`
+		Min       *float64 `json:"min"`
+		Max       *float64 `json:"max"`
+		MaxLength int      `json:"max_length"`
+		Required  bool     `json:"required"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return err
+	}
+	q.Options, q.Min, q.Max, q.MaxLength, q.Required = config.Options, config.Min, config.Max, config.MaxLength, config.Required
+	return nil
+}
+
+func scanSurvey(row scanner) (*models.Survey, error) {
+	var id, workspace, status, createdAt, updatedAt, rules string
+	var opensAt, closesAt sql.NullString
+	var survey models.Survey
+	err := row.Scan(&id, &workspace, &survey.Name, &survey.Version, &status, &createdAt, &updatedAt, &rules, &opensAt, &closesAt, &survey.MaxResponses,
+		&survey.EditWindowSeconds)
+	if err != nil {
+		return nil, err
+	}
+	if survey.OpensAt, err = parseNullTime(opensAt); err != nil {
+		return nil, err
+	}
+	if survey.ClosesAt, err = parseNullTime(closesAt); err != nil {
+		return nil, err
+	}
+	survey.WorkspaceID = models.WorkspaceID(workspace)
+	survey.Status = models.SurveyStatus(status)
+	if err = json.Unmarshal([]byte(rules), &survey.Rules); err != nil {
+		return nil, err
+	}
+	if survey.ID, err = ksuid.Parse(id); err != nil {
+		return nil, err
+	}
+	if survey.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if survey.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return nil, err
+	}
+	return &survey, nil
+}
+
+func insertQuestions(tx *sql.Tx, survey *models.Survey) error {
+	for position, question := range survey.Questions {
+		config, err := json.Marshal((*questionConfig)(&question))
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO questions (id, survey_id, position, question, type, config) VALUES (?, ?, ?, ?, ?, ?)`,
+			question.ID.String(), survey.ID.String(), position, question.Question, string(question.Type), string(config))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/segmentio/ksuid"
+	"log"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+)
+
+// UserRepo stores users in the users table, role assignments are stored as a json array
+type UserRepo struct {
+	db *sql.DB
+}
+
+func NewUserRepo(db *sql.DB) *UserRepo {
+	return &UserRepo{
+		db: db,
+	}
+}
+
+const userColumns = `id, workspace_id, name, email, roles, created_at`
+
+func (r *UserRepo) Create(user *models.User) (*models.User, error) {
+	roles, err := json.Marshal(user.Roles)
+	if err != nil {
+		return nil, err
+	}
+	_, err = r.db.Exec(`INSERT INTO users (`+userColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		user.ID.String(), string(user.Workspace()), user.Name, user.Email, string(roles), formatTime(user.CreatedAt))
+	if isPrimaryKeyConflict(err) {
+		return nil, repositories.ErrAlreadyExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *UserRepo) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.User, error) {
+	return scanUser(r.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE id = ? AND workspace_id = ?`, id.String(), string(workspace)))
+}
+
+// GetAll returns every user of the workspace ordered by creation time
+func (r *UserRepo) GetAll(workspace models.WorkspaceID) ([]models.User, error) {
+	return r.users(`WHERE workspace_id = ?`, string(workspace))
+}
+
+// users returns the users matching the where clause ordered by creation time
+func (r *UserRepo) users(where string, args ...interface{}) ([]models.User, error) {
+	rows, err := r.db.Query(`SELECT `+userColumns+` FROM users `+where+` ORDER BY created_at, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := make([]models.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+func (r *UserRepo) Update(workspace models.WorkspaceID, id ksuid.KSUID, user *models.User) (*models.User, error) {
+	roles, err := json.Marshal(user.Roles)
+	if err != nil {
+		return nil, err
+	}
+	result, err := r.db.Exec(`UPDATE users SET name = ?, email = ?, roles = ? WHERE id = ? AND workspace_id = ?`,
+		user.Name, user.Email, string(roles), id.String(), string(workspace))
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if affected == 0 {
+		return nil, repositories.ErrNotFound
+	}
+	return user, nil
+}
+
+func (r *UserRepo) Delete(workspace models.WorkspaceID, id ksuid.KSUID) error {
+	result, err := r.db.Exec(`DELETE FROM users WHERE id = ? AND workspace_id = ?`, id.String(), string(workspace))
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+// Entries exports every stored user, errors are logged and result in an empty export
+func (r *UserRepo) Entries() map[ksuid.KSUID]models.User {
+	users, err := r.users("")
+	if err != nil {
+		log.Println("error while exporting users", err)
+	}
+	entries := make(map[ksuid.KSUID]models.User, len(users))
+	for _, user := range users {
+		entries[user.ID] = user
+	}
+	return entries
+}
+
+func scanUser(row scanner) (*models.User, error) {
+	var (
+		user                            models.User
+		id, workspace, roles, createdAt string
+	)
+	err := row.Scan(&id, &workspace, &user.Name, &user.Email, &roles, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, repositories.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	user.WorkspaceID = models.WorkspaceID(workspace)
+	if user.ID, err = ksuid.Parse(id); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(roles), &user.Roles); err != nil {
+		return nil, err
+	}
+	if user.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -1,79 +1,235 @@
 package surveyrepo
 
 import (
+	"encoding/json"
 	"github.com/segmentio/ksuid"
+	"sort"
+	"survey-platform/internal/db"
 	"survey-platform/internal/models"
 	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/responserepo"
 	"sync"
 )
 
 type SurveyRepo struct {
-	mu      *sync.RWMutex
-	surveys map[ksuid.KSUID]models.Survey
+	mu       *sync.RWMutex
+	surveys  map[ksuid.KSUID]models.Survey
+	versions map[ksuid.KSUID][]models.Survey
+	journal  db.Journal
+	// responses holds the responses of the surveys, nil when deleting a survey does not need to look at its responses
+	responses *responserepo.ResponseRepo
 }
 
-func NewSurveyRepo(existingSurveys map[ksuid.KSUID]models.Survey) *SurveyRepo {
+// NewSurveyRepo returns a repo holding existingSurveys and their existingVersions
+// surveys without versions, stored before versions were introduced, start with their current version
+// every mutation is appended to journal before it is applied, a nil journal disables journaling
+func NewSurveyRepo(existingSurveys map[ksuid.KSUID]models.Survey, existingVersions map[ksuid.KSUID][]models.Survey,
+	journal db.Journal) *SurveyRepo {
 	if existingSurveys == nil {
 		existingSurveys = make(map[ksuid.KSUID]models.Survey)
 	}
-	return &SurveyRepo{
-		mu:      &sync.RWMutex{},
-		surveys: existingSurveys,
+	if existingVersions == nil {
+		existingVersions = make(map[ksuid.KSUID][]models.Survey)
 	}
+	surveyRepo := &SurveyRepo{
+		mu:       &sync.RWMutex{},
+		surveys:  existingSurveys,
+		versions: existingVersions,
+		journal:  journal,
+	}
+	for _, survey := range existingSurveys {
+		surveyRepo.putVersion(survey)
+	}
+	return surveyRepo
 }
 
 func (s *SurveyRepo) Create(survey *models.Survey) (*models.Survey, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if _, ok := s.surveys[survey.ID]; ok {
+		return nil, repositories.ErrAlreadyExists
+	}
+	if err := s.record(repositories.OpCreateSurvey, survey); err != nil {
+		return nil, err
+	}
 	s.surveys[survey.ID] = *survey
+	s.putVersion(*survey)
 	return survey, nil
 }
 
-func (s *SurveyRepo) Get(id ksuid.KSUID) (*models.Survey, error) {
+func (s *SurveyRepo) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	survey, ok := s.surveys[id]
+	survey, ok := s.get(workspace, id)
 	if !ok {
 		return nil, repositories.ErrNotFound
 	}
 	return &survey, nil
 }
 
-func (s *SurveyRepo) Update(id ksuid.KSUID, survey *models.Survey) (*models.Survey, error) {
+func (s *SurveyRepo) Update(workspace models.WorkspaceID, id ksuid.KSUID, survey *models.Survey) (*models.Survey, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, ok := s.surveys[id]; !ok {
+	if _, ok := s.get(workspace, id); !ok {
 		return nil, repositories.ErrNotFound
 	}
+	if err := s.record(repositories.OpUpdateSurvey, survey); err != nil {
+		return nil, err
+	}
 	s.surveys[id] = *survey
+	s.putVersion(*survey)
 	return survey, nil
 }
 
-func (s *SurveyRepo) Delete(id ksuid.KSUID) error {
+// WithResponses makes deleting a survey check and cascade to its responses in responses
+// and makes responses refuse the responses of surveys which are not stored, it must be called before the repo is used
+func (s *SurveyRepo) WithResponses(responses *responserepo.ResponseRepo) *SurveyRepo {
+	s.responses = responses.WithSurveys(s.exists)
+	return s
+}
+
+// Delete removes the survey, it is journaled and removed while its responses are locked so that a survey
+// with responses is either kept or purged along with them by a single journaled operation
+func (s *SurveyRepo) Delete(workspace models.WorkspaceID, id ksuid.KSUID, cascade bool) error {
+	remove := func(op string) error {
+		return s.remove(workspace, id, op)
+	}
+	if s.responses == nil {
+		return remove(repositories.OpDeleteSurvey)
+	}
+	return s.responses.DeleteSurvey(workspace, id, cascade, remove)
+}
+
+// GetAll returns the page of surveys of the workspace selected by query
+func (s *SurveyRepo) GetAll(workspace models.WorkspaceID, query models.SurveyQuery) (*models.SurveyPage, error) {
+	sortBy := query.SortBy()
+	s.mu.RLock()
+	surveys := make([]models.Survey, 0)
+	for _, survey := range s.surveys {
+		if survey.Workspace() == workspace && query.Matches(survey) && query.Follows(survey.SortKey(sortBy), survey.ID) {
+			surveys = append(surveys, survey)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(surveys, func(i, j int) bool {
+		return query.Less(surveys[i].SortKey(sortBy), surveys[i].ID, surveys[j].SortKey(sortBy), surveys[j].ID)
+	})
+	page := &models.SurveyPage{Surveys: surveys}
+	if query.Limit > 0 && len(surveys) > query.Limit {
+		page.Surveys = surveys[:query.Limit]
+		last := page.Surveys[query.Limit-1]
+		page.Next = query.NextCursor(last.SortKey(sortBy), last.ID)
+	}
+	return page, nil
+}
+
+// Entries returns a copy of the stored surveys which is safe to read while the repo is being written to
+func (s *SurveyRepo) Entries() map[ksuid.KSUID]models.Survey {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, ok := s.surveys[id]; !ok {
-		return repositories.ErrNotFound
+	surveys := make(map[ksuid.KSUID]models.Survey, len(s.surveys))
+	for id, survey := range s.surveys {
+		surveys[id] = survey
 	}
-	delete(s.surveys, id)
-	return nil
+	return surveys
 }
 
-func (s *SurveyRepo) GetAll() ([]models.Survey, error) {
+// GetVersions returns a copy of every version of a survey, oldest first
+func (s *SurveyRepo) GetVersions(workspace models.WorkspaceID, id ksuid.KSUID) ([]models.Survey, error) {
 	s.mu.RLock()
-	s.mu.RUnlock()
-	if len(s.surveys) == 0 {
+	defer s.mu.RUnlock()
+	if _, ok := s.get(workspace, id); !ok {
 		return nil, repositories.ErrNotFound
 	}
-	var surveys []models.Survey
-	for _, survey := range s.surveys {
-		surveys = append(surveys, survey)
+	return append([]models.Survey(nil), s.versions[id]...), nil
+}
+
+// VersionEntries returns a copy of the stored versions which is safe to read while the repo is being written to
+func (s *SurveyRepo) VersionEntries() map[ksuid.KSUID][]models.Survey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	versions := make(map[ksuid.KSUID][]models.Survey, len(s.versions))
+	for id, surveyVersions := range s.versions {
+		versions[id] = append([]models.Survey(nil), surveyVersions...)
 	}
-	return surveys, nil
+	return versions
 }
 
-func (s *SurveyRepo) Entries() map[ksuid.KSUID]models.Survey {
+// Apply replays a journaled survey operation, creates and updates overwrite the stored survey
+// and deletes or purges of missing surveys are ignored so that replaying over a newer snapshot is harmless
+func (s *SurveyRepo) Apply(op string, payload []byte) error {
+	if op != repositories.OpCreateSurvey && op != repositories.OpUpdateSurvey && op != repositories.OpDeleteSurvey &&
+		op != repositories.OpPurgeSurvey {
+		return nil
+	}
+	var survey models.Survey
+	if err := json.Unmarshal(payload, &survey); err != nil {
+		return err
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.surveys
+	if op == repositories.OpDeleteSurvey || op == repositories.OpPurgeSurvey {
+		delete(s.surveys, survey.ID)
+		delete(s.versions, survey.ID)
+		return nil
+	}
+	s.surveys[survey.ID] = survey
+	s.putVersion(survey)
+	return nil
+}
+
+// remove journals op before removing the survey id of workspace, ErrNotFound is returned when it is not stored
+func (s *SurveyRepo) remove(workspace models.WorkspaceID, id ksuid.KSUID, op string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.get(workspace, id); !ok {
+		return repositories.ErrNotFound
+	}
+	if err := s.record(op, &models.Survey{ID: id, WorkspaceID: workspace}); err != nil {
+		return err
+	}
+	delete(s.surveys, id)
+	delete(s.versions, id)
+	return nil
+}
+
+// exists reports whether the survey id is stored in workspace
+func (s *SurveyRepo) exists(workspace models.WorkspaceID, id ksuid.KSUID) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.get(workspace, id)
+	return ok
+}
+
+// get returns the survey id when it is in workspace, the caller must hold mu
+func (s *SurveyRepo) get(workspace models.WorkspaceID, id ksuid.KSUID) (models.Survey, bool) {
+	survey, ok := s.surveys[id]
+	if !ok || survey.Workspace() != workspace {
+		return models.Survey{}, false
+	}
+	return survey, true
+}
+
+// putVersion stores survey as its version, replacing a stored version with the same number
+// versions are kept ordered so that replaying an older operation cannot reorder the history
+func (s *SurveyRepo) putVersion(survey models.Survey) {
+	versions := s.versions[survey.ID]
+	i := sort.Search(len(versions), func(i int) bool {
+		return versions[i].CurrentVersion() >= survey.CurrentVersion()
+	})
+	if i < len(versions) && versions[i].CurrentVersion() == survey.CurrentVersion() {
+		versions[i] = survey
+		return
+	}
+	versions = append(versions, models.Survey{})
+	copy(versions[i+1:], versions[i:])
+	versions[i] = survey
+	s.versions[survey.ID] = versions
+}
+
+func (s *SurveyRepo) record(op string, payload interface{}) error {
+	if s.journal == nil {
+		return nil
+	}
+	return s.journal.Append(op, payload)
 }
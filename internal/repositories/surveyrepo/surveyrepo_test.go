@@ -1,24 +1,46 @@
 package surveyrepo
 
 import (
+	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
 	"github.com/segmentio/ksuid"
 	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"survey-platform/internal/db/db_mock"
+	"survey-platform/internal/db/jsondb"
 	"survey-platform/internal/models"
 	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/repotest"
+	"survey-platform/internal/repositories/responserepo"
 	"testing"
 	"time"
 )
 
 func TestNewSurveyRepo(t *testing.T) {
 	t.Run("should initiate surverepo with empty map when existing survey is nil", func(t *testing.T) {
-		responseRepo := NewSurveyRepo(nil)
+		responseRepo := NewSurveyRepo(nil, nil, nil)
 		assert.NotNil(t, responseRepo.surveys)
 	})
+	t.Run("should seed versions and add the current survey of entries persisted before versions", func(t *testing.T) {
+		surveyID := ksuid.New()
+		first := models.Survey{ID: surveyID, Name: "first", Version: 1}
+		second := models.Survey{ID: surveyID, Name: "second", Version: 2}
+		legacy := models.Survey{ID: ksuid.New(), Name: "legacy", Version: 1}
+		surveyRepo := NewSurveyRepo(map[ksuid.KSUID]models.Survey{surveyID: second, legacy.ID: legacy},
+			map[ksuid.KSUID][]models.Survey{surveyID: {first}}, nil)
+		versions, err := surveyRepo.GetVersions(models.DefaultWorkspace, surveyID)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Survey{first, second}, versions)
+		versions, err = surveyRepo.GetVersions(models.DefaultWorkspace, legacy.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Survey{legacy}, versions)
+	})
 }
 
 func TestSurveyRepo_Create(t *testing.T) {
 	t.Run("should successfully create survey", func(t *testing.T) {
-		surveyRepo := NewSurveyRepo(map[ksuid.KSUID]models.Survey{})
+		surveyRepo := NewSurveyRepo(map[ksuid.KSUID]models.Survey{}, nil, nil)
 		survey := models.Survey{
 			ID:        ksuid.New(),
 			CreatedAt: time.Now(),
@@ -52,7 +74,7 @@ func TestSurveyRepo_Create(t *testing.T) {
 					Question: "does this place has parking?",
 				},
 			}}
-		surveyRepo := NewSurveyRepo(map[ksuid.KSUID]models.Survey{survey1.ID: survey1})
+		surveyRepo := NewSurveyRepo(map[ksuid.KSUID]models.Survey{survey1.ID: survey1}, nil, nil)
 		survey2 := models.Survey{
 			ID:        ksuid.New(),
 			CreatedAt: time.Now(),
@@ -96,9 +118,9 @@ func TestSurveyRepo_Get(t *testing.T) {
 		}
 		surveyRepo := NewSurveyRepo(map[ksuid.KSUID]models.Survey{
 			surveyID: survey,
-		})
+		}, nil, nil)
 		expectedSurvey := survey
-		newSurvey, err := surveyRepo.Get(surveyID)
+		newSurvey, err := surveyRepo.Get(models.DefaultWorkspace, surveyID)
 		assert.NoError(t, err)
 		assert.Equal(t, expectedSurvey, *newSurvey)
 	})
@@ -123,8 +145,8 @@ func TestSurveyRepo_Get(t *testing.T) {
 		}
 		surveyRepo := NewSurveyRepo(map[ksuid.KSUID]models.Survey{
 			surveyID: survey,
-		})
-		newSurvey, err := surveyRepo.Get(ksuid.New())
+		}, nil, nil)
+		newSurvey, err := surveyRepo.Get(models.DefaultWorkspace, ksuid.New())
 		assert.Equal(t, repositories.ErrNotFound, err)
 		assert.Nil(t, newSurvey)
 	})
@@ -152,7 +174,7 @@ func TestSurveyRepo_Update(t *testing.T) {
 		}
 		surveyRepo := NewSurveyRepo(map[ksuid.KSUID]models.Survey{
 			surveyID: survey,
-		})
+		}, nil, nil)
 		updatedSurvey := survey
 		q2ID1, q2ID2 := ksuid.New(), ksuid.New()
 		updatedSurvey.Questions = []models.Question{
@@ -165,7 +187,7 @@ func TestSurveyRepo_Update(t *testing.T) {
 				Question: "do you prefer linux over MacOS",
 			},
 		}
-		newSurvey, err := surveyRepo.Update(surveyID, &updatedSurvey)
+		newSurvey, err := surveyRepo.Update(models.DefaultWorkspace, surveyID, &updatedSurvey)
 		assert.NoError(t, err)
 		assert.NotEqual(t, survey.Questions, newSurvey.Questions)
 	})
@@ -190,7 +212,7 @@ func TestSurveyRepo_Update(t *testing.T) {
 		}
 		surveyRepo := NewSurveyRepo(map[ksuid.KSUID]models.Survey{
 			surveyID: survey,
-		})
+		}, nil, nil)
 		updatedSurvey := survey
 		q2ID1, q2ID2 := ksuid.New(), ksuid.New()
 		updatedSurvey.Questions = []models.Question{
@@ -203,7 +225,7 @@ func TestSurveyRepo_Update(t *testing.T) {
 				Question: "do you prefer linux over MacOS",
 			},
 		}
-		newSurvey, err := surveyRepo.Update(ksuid.New(), &updatedSurvey)
+		newSurvey, err := surveyRepo.Update(models.DefaultWorkspace, ksuid.New(), &updatedSurvey)
 		assert.Equal(t, repositories.ErrNotFound, err)
 		assert.Nil(t, newSurvey)
 	})
@@ -248,16 +270,16 @@ func TestSurveyRepo_GetAll(t *testing.T) {
 		surveyRepo := NewSurveyRepo(map[ksuid.KSUID]models.Survey{
 			surveyID1: survey1,
 			surveyID2: survey2,
-		})
-		surveys, err := surveyRepo.GetAll()
+		}, nil, nil)
+		page, err := surveyRepo.GetAll(models.DefaultWorkspace, models.SurveyQuery{})
 		assert.NoError(t, err)
-		assert.EqualValues(t, []models.Survey{survey1, survey2}, surveys)
+		assert.EqualValues(t, []models.Survey{survey1, survey2}, page.Surveys)
 	})
-	t.Run("should return error if no surveys are found", func(t *testing.T) {
-		surveyRepo := NewSurveyRepo(nil)
-		newSurvey, err := surveyRepo.GetAll()
-		assert.Equal(t, repositories.ErrNotFound, err)
-		assert.Nil(t, newSurvey)
+	t.Run("should return an empty page if no surveys are found", func(t *testing.T) {
+		surveyRepo := NewSurveyRepo(nil, nil, nil)
+		page, err := surveyRepo.GetAll(models.DefaultWorkspace, models.SurveyQuery{})
+		assert.NoError(t, err)
+		assert.Empty(t, page.Surveys)
 	})
 }
 
@@ -283,8 +305,8 @@ func TestSurveyRepo_Delete(t *testing.T) {
 		}
 		surveyRepo := NewSurveyRepo(map[ksuid.KSUID]models.Survey{
 			surveyID: survey,
-		})
-		err := surveyRepo.Delete(surveyID)
+		}, nil, nil)
+		err := surveyRepo.Delete(models.DefaultWorkspace, surveyID, false)
 		assert.NoError(t, err)
 		assert.Empty(t, surveyRepo.surveys)
 	})
@@ -309,8 +331,8 @@ func TestSurveyRepo_Delete(t *testing.T) {
 		}
 		surveyRepo := NewSurveyRepo(map[ksuid.KSUID]models.Survey{
 			surveyID: survey,
-		})
-		err := surveyRepo.Delete(ksuid.New())
+		}, nil, nil)
+		err := surveyRepo.Delete(models.DefaultWorkspace, ksuid.New(), false)
 		assert.Error(t, repositories.ErrNotFound, err)
 	})
 }
@@ -345,7 +367,7 @@ func TestSurveyRepo_Entries(t *testing.T) {
 					Question: "does this place has wheelchair accessible parking?",
 				},
 			}}
-		surveyRepo := NewSurveyRepo(map[ksuid.KSUID]models.Survey{survey1.ID: survey1, survey2.ID: survey2})
+		surveyRepo := NewSurveyRepo(map[ksuid.KSUID]models.Survey{survey1.ID: survey1, survey2.ID: survey2}, nil, nil)
 		entries := surveyRepo.Entries()
 		assert.Equal(t, map[ksuid.KSUID]models.Survey{survey1.ID: survey1, survey2.ID: survey2}, entries)
 	})
@@ -364,7 +386,7 @@ func TestSurveyRepo_Entries(t *testing.T) {
 					Question: "does this place has parking?",
 				},
 			}}
-		surveyRepo := NewSurveyRepo(map[ksuid.KSUID]models.Survey{survey1.ID: survey1})
+		surveyRepo := NewSurveyRepo(map[ksuid.KSUID]models.Survey{survey1.ID: survey1}, nil, nil)
 		survey2 := models.Survey{
 			ID:        ksuid.New(),
 			CreatedAt: time.Now(),
@@ -385,3 +407,144 @@ func TestSurveyRepo_Entries(t *testing.T) {
 		assert.Equal(t, map[ksuid.KSUID]models.Survey{survey1.ID: survey1, survey2.ID: survey2}, entries)
 	})
 }
+
+func TestSurveyRepo_Journal(t *testing.T) {
+	t.Run("should journal every mutation before applying it", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		survey := models.Survey{ID: ksuid.New(), Name: "new survey", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		updatedSurvey := survey
+		updatedSurvey.Name = "updated survey"
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		gomock.InOrder(
+			mockJournal.EXPECT().Append(repositories.OpCreateSurvey, &survey).Return(nil),
+			mockJournal.EXPECT().Append(repositories.OpUpdateSurvey, &updatedSurvey).Return(nil),
+			mockJournal.EXPECT().Append(repositories.OpDeleteSurvey, &models.Survey{ID: survey.ID, WorkspaceID: models.DefaultWorkspace}).Return(nil),
+		)
+		surveyRepo := NewSurveyRepo(nil, nil, mockJournal)
+		_, err := surveyRepo.Create(&survey)
+		assert.NoError(t, err)
+		_, err = surveyRepo.Update(models.DefaultWorkspace, survey.ID, &updatedSurvey)
+		assert.NoError(t, err)
+		err = surveyRepo.Delete(models.DefaultWorkspace, survey.ID, false)
+		assert.NoError(t, err)
+		assert.Empty(t, surveyRepo.surveys)
+	})
+	t.Run("should journal a single purge removing a survey along with its responses", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		survey := models.Survey{ID: ksuid.New(), Name: "answered survey"}
+		response := models.Response{ID: ksuid.New(), SurveyID: survey.ID, CreatedAt: time.Now()}
+		responseRepo := responserepo.NewResponseRepo(map[ksuid.KSUID][]models.Response{survey.ID: {response}}, nil)
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		mockJournal.EXPECT().Append(repositories.OpPurgeSurvey, &models.Survey{ID: survey.ID, WorkspaceID: models.DefaultWorkspace}).Return(nil)
+		surveyRepo := NewSurveyRepo(map[ksuid.KSUID]models.Survey{survey.ID: survey}, nil, mockJournal).WithResponses(responseRepo)
+		assert.Equal(t, repositories.ErrSurveyHasResponses, surveyRepo.Delete(models.DefaultWorkspace, survey.ID, false))
+		assert.NoError(t, surveyRepo.Delete(models.DefaultWorkspace, survey.ID, true))
+		assert.Empty(t, surveyRepo.surveys)
+		assert.Empty(t, responseRepo.Entries())
+	})
+	t.Run("should keep the survey and its responses when journaling the purge fails", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		survey := models.Survey{ID: ksuid.New(), Name: "answered survey"}
+		response := models.Response{ID: ksuid.New(), SurveyID: survey.ID, CreatedAt: time.Now()}
+		responseRepo := responserepo.NewResponseRepo(map[ksuid.KSUID][]models.Response{survey.ID: {response}}, nil)
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		mockJournal.EXPECT().Append(repositories.OpPurgeSurvey, gomock.Any()).Return(errors.New("disk full"))
+		surveyRepo := NewSurveyRepo(map[ksuid.KSUID]models.Survey{survey.ID: survey}, nil, mockJournal).WithResponses(responseRepo)
+		assert.Error(t, surveyRepo.Delete(models.DefaultWorkspace, survey.ID, true))
+		assert.Len(t, surveyRepo.surveys, 1)
+		assert.Len(t, responseRepo.Entries()[survey.ID], 1)
+	})
+	t.Run("should not apply mutation when journal returns error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		survey := models.Survey{ID: ksuid.New(), Name: "new survey"}
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		mockJournal.EXPECT().Append(repositories.OpCreateSurvey, &survey).Return(errors.New("disk full"))
+		surveyRepo := NewSurveyRepo(nil, nil, mockJournal)
+		newSurvey, err := surveyRepo.Create(&survey)
+		assert.Error(t, err)
+		assert.Nil(t, newSurvey)
+		assert.Empty(t, surveyRepo.surveys)
+	})
+}
+
+func TestSurveyRepo_Apply(t *testing.T) {
+	t.Run("should apply journaled operations without journaling them again", func(t *testing.T) {
+		survey := models.Survey{ID: ksuid.New(), Name: "new survey"}
+		updatedSurvey := survey
+		updatedSurvey.Name = "updated survey"
+		surveyRepo := NewSurveyRepo(nil, nil, nil)
+		created, _ := json.Marshal(survey)
+		updated, _ := json.Marshal(updatedSurvey)
+		assert.NoError(t, surveyRepo.Apply(repositories.OpCreateSurvey, created))
+		assert.NoError(t, surveyRepo.Apply(repositories.OpUpdateSurvey, updated))
+		assert.Equal(t, updatedSurvey, surveyRepo.surveys[survey.ID])
+		assert.NoError(t, surveyRepo.Apply(repositories.OpDeleteSurvey, created))
+		assert.NoError(t, surveyRepo.Apply(repositories.OpDeleteSurvey, created))
+		assert.Empty(t, surveyRepo.surveys)
+		assert.NoError(t, surveyRepo.Apply(repositories.OpCreateSurvey, created))
+		assert.NoError(t, surveyRepo.Apply(repositories.OpPurgeSurvey, created))
+		assert.NoError(t, surveyRepo.Apply(repositories.OpPurgeSurvey, created))
+		assert.Empty(t, surveyRepo.surveys)
+	})
+	t.Run("should ignore operations of other repositories", func(t *testing.T) {
+		surveyRepo := NewSurveyRepo(nil, nil, nil)
+		err := surveyRepo.Apply(repositories.OpCreateResponse, []byte("not a survey"))
+		assert.NoError(t, err)
+		assert.Empty(t, surveyRepo.surveys)
+	})
+	t.Run("should return error for malformed payload", func(t *testing.T) {
+		surveyRepo := NewSurveyRepo(nil, nil, nil)
+		err := surveyRepo.Apply(repositories.OpCreateSurvey, []byte("not a survey"))
+		assert.Error(t, err)
+	})
+}
+
+func TestSurveyRepo_EntriesCopy(t *testing.T) {
+	t.Run("should not reflect writes made after entries are returned", func(t *testing.T) {
+		surveyRepo := NewSurveyRepo(nil, nil, nil)
+		_, err := surveyRepo.Create(&models.Survey{ID: ksuid.New(), Name: "new survey"})
+		assert.NoError(t, err)
+		entries := surveyRepo.Entries()
+		_, err = surveyRepo.Create(&models.Survey{ID: ksuid.New(), Name: "another survey"})
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+}
+
+func TestSurveyRepo_Contract(t *testing.T) {
+	repotest.SurveyRepoSuite(t, func(t *testing.T) repositories.SurveyRepoInterface {
+		return NewSurveyRepo(nil, nil, nil)
+	})
+}
+
+func TestSurveyRepo_JournalContract(t *testing.T) {
+	repotest.SurveyRepoSuite(t, func(t *testing.T) repositories.SurveyRepoInterface {
+		jsonDB, err := jsondb.NewJsonDB(filepath.Join(t.TempDir(), "survey_app.json"), filepath.Join(t.TempDir(), "survey_app.json.wal"), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewSurveyRepo(nil, nil, jsonDB)
+	})
+}
+
+func TestSurveyRepo_DeletionContract(t *testing.T) {
+	repotest.SurveyDeletionSuite(t, func(t *testing.T) (repositories.SurveyRepoInterface, repositories.ResponseRepoInterface) {
+		responseRepo := responserepo.NewResponseRepo(nil, nil)
+		return NewSurveyRepo(nil, nil, nil).WithResponses(responseRepo), responseRepo
+	})
+}
+
+func TestSurveyRepo_DeletionJournalContract(t *testing.T) {
+	repotest.SurveyDeletionSuite(t, func(t *testing.T) (repositories.SurveyRepoInterface, repositories.ResponseRepoInterface) {
+		jsonDB, err := jsondb.NewJsonDB(filepath.Join(t.TempDir(), "survey_app.json"), filepath.Join(t.TempDir(), "survey_app.json.wal"), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		responseRepo := responserepo.NewResponseRepo(nil, jsonDB)
+		return NewSurveyRepo(nil, nil, jsonDB).WithResponses(responseRepo), responseRepo
+	})
+}
@@ -0,0 +1,146 @@
+package userrepo
+
+import (
+	"encoding/json"
+	"github.com/segmentio/ksuid"
+	"sort"
+	"survey-platform/internal/db"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"sync"
+)
+
+type UserRepo struct {
+	mu    *sync.RWMutex
+	users map[ksuid.KSUID]models.User
+	// journal records every mutation before it is applied, nil disables journaling
+	journal db.Journal
+}
+
+// NewUserRepo returns a repo holding existingUsers
+// every mutation is appended to journal before it is applied, a nil journal disables journaling
+func NewUserRepo(existingUsers map[ksuid.KSUID]models.User, journal db.Journal) *UserRepo {
+	if existingUsers == nil {
+		existingUsers = make(map[ksuid.KSUID]models.User)
+	}
+	return &UserRepo{
+		mu:      &sync.RWMutex{},
+		users:   existingUsers,
+		journal: journal,
+	}
+}
+
+func (r *UserRepo) Create(user *models.User) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[user.ID]; ok {
+		return nil, repositories.ErrAlreadyExists
+	}
+	if err := r.record(repositories.OpCreateUser, user); err != nil {
+		return nil, err
+	}
+	r.users[user.ID] = *user
+	return user, nil
+}
+
+func (r *UserRepo) Get(workspace models.WorkspaceID, id ksuid.KSUID) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	user, ok := r.get(workspace, id)
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	return &user, nil
+}
+
+// GetAll returns every user of the workspace ordered by creation time
+func (r *UserRepo) GetAll(workspace models.WorkspaceID) ([]models.User, error) {
+	r.mu.RLock()
+	users := make([]models.User, 0)
+	for _, user := range r.users {
+		if user.Workspace() == workspace {
+			users = append(users, user)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(users, func(i, j int) bool {
+		if !users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].CreatedAt.Before(users[j].CreatedAt)
+		}
+		return ksuid.Compare(users[i].ID, users[j].ID) < 0
+	})
+	return users, nil
+}
+
+func (r *UserRepo) Update(workspace models.WorkspaceID, id ksuid.KSUID, user *models.User) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.get(workspace, id); !ok {
+		return nil, repositories.ErrNotFound
+	}
+	if err := r.record(repositories.OpUpdateUser, user); err != nil {
+		return nil, err
+	}
+	r.users[id] = *user
+	return user, nil
+}
+
+func (r *UserRepo) Delete(workspace models.WorkspaceID, id ksuid.KSUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.get(workspace, id); !ok {
+		return repositories.ErrNotFound
+	}
+	if err := r.record(repositories.OpDeleteUser, &models.User{ID: id}); err != nil {
+		return err
+	}
+	delete(r.users, id)
+	return nil
+}
+
+// Entries returns a copy of the stored users which is safe to read while the repo is being written to
+func (r *UserRepo) Entries() map[ksuid.KSUID]models.User {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	users := make(map[ksuid.KSUID]models.User, len(r.users))
+	for id, user := range r.users {
+		users[id] = user
+	}
+	return users
+}
+
+// Apply replays a journaled user operation, creates and updates overwrite the stored user
+// and deletes of missing users are ignored so that replaying over a newer snapshot is harmless
+func (r *UserRepo) Apply(op string, payload []byte) error {
+	if op != repositories.OpCreateUser && op != repositories.OpUpdateUser && op != repositories.OpDeleteUser {
+		return nil
+	}
+	var user models.User
+	if err := json.Unmarshal(payload, &user); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if op == repositories.OpDeleteUser {
+		delete(r.users, user.ID)
+		return nil
+	}
+	r.users[user.ID] = user
+	return nil
+}
+
+// get returns the user id when it is in workspace, the caller must hold mu
+func (r *UserRepo) get(workspace models.WorkspaceID, id ksuid.KSUID) (models.User, bool) {
+	user, ok := r.users[id]
+	if !ok || user.Workspace() != workspace {
+		return models.User{}, false
+	}
+	return user, true
+}
+
+func (r *UserRepo) record(op string, payload interface{}) error {
+	if r.journal == nil {
+		return nil
+	}
+	return r.journal.Append(op, payload)
+}
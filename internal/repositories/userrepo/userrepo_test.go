@@ -0,0 +1,94 @@
+package userrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"survey-platform/internal/db/db_mock"
+	"survey-platform/internal/db/jsondb"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/repotest"
+	"testing"
+)
+
+func TestUserRepo_Journal(t *testing.T) {
+	t.Run("should journal every mutation before applying it", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		user := models.User{ID: ksuid.New(), Name: "alice", Roles: []models.RoleAssignment{{Role: models.RoleAnalyst}}}
+		updated := user
+		updated.Roles = []models.RoleAssignment{{Role: models.RoleEditor}}
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		gomock.InOrder(
+			mockJournal.EXPECT().Append(repositories.OpCreateUser, &user).Return(nil),
+			mockJournal.EXPECT().Append(repositories.OpUpdateUser, &updated).Return(nil),
+			mockJournal.EXPECT().Append(repositories.OpDeleteUser, &models.User{ID: user.ID}).Return(nil),
+		)
+		userRepo := NewUserRepo(nil, mockJournal)
+		_, err := userRepo.Create(&user)
+		assert.NoError(t, err)
+		_, err = userRepo.Update(models.DefaultWorkspace, user.ID, &updated)
+		assert.NoError(t, err)
+		assert.NoError(t, userRepo.Delete(models.DefaultWorkspace, user.ID))
+	})
+	t.Run("should not apply mutation when journal returns error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		user := models.User{ID: ksuid.New(), Name: "alice"}
+		mockJournal := db_mock.NewMockJournal(ctrl)
+		mockJournal.EXPECT().Append(repositories.OpCreateUser, &user).Return(errors.New("disk full"))
+		userRepo := NewUserRepo(nil, mockJournal)
+		created, err := userRepo.Create(&user)
+		assert.Error(t, err)
+		assert.Nil(t, created)
+		assert.Empty(t, userRepo.users)
+	})
+}
+
+func TestUserRepo_Apply(t *testing.T) {
+	t.Run("should apply journaled operations and ignore deletes of missing users", func(t *testing.T) {
+		user := models.User{ID: ksuid.New(), Name: "alice", Roles: []models.RoleAssignment{{Role: models.RoleAnalyst}}}
+		userRepo := NewUserRepo(nil, nil)
+		created, _ := json.Marshal(user)
+		user.Roles = []models.RoleAssignment{{Role: models.RoleOwner}}
+		updated, _ := json.Marshal(user)
+		assert.NoError(t, userRepo.Apply(repositories.OpCreateUser, created))
+		assert.NoError(t, userRepo.Apply(repositories.OpUpdateUser, updated))
+		stored, err := userRepo.Get(models.DefaultWorkspace, user.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, user.Roles, stored.Roles)
+		deleted, _ := json.Marshal(models.User{ID: user.ID})
+		assert.NoError(t, userRepo.Apply(repositories.OpDeleteUser, deleted))
+		assert.NoError(t, userRepo.Apply(repositories.OpDeleteUser, deleted))
+		assert.Empty(t, userRepo.users)
+	})
+	t.Run("should ignore operations of other repositories", func(t *testing.T) {
+		userRepo := NewUserRepo(nil, nil)
+		assert.NoError(t, userRepo.Apply(repositories.OpCreateAPIKey, []byte("not a user")))
+		assert.Empty(t, userRepo.users)
+	})
+	t.Run("should return error for malformed payload", func(t *testing.T) {
+		userRepo := NewUserRepo(nil, nil)
+		assert.Error(t, userRepo.Apply(repositories.OpCreateUser, []byte("not a user")))
+	})
+}
+
+func TestUserRepo_Contract(t *testing.T) {
+	repotest.UserRepoSuite(t, func(t *testing.T) repositories.UserRepoInterface {
+		return NewUserRepo(nil, nil)
+	})
+}
+
+func TestUserRepo_JournalContract(t *testing.T) {
+	repotest.UserRepoSuite(t, func(t *testing.T) repositories.UserRepoInterface {
+		jsonDB, err := jsondb.NewJsonDB(filepath.Join(t.TempDir(), "survey_app.json"), filepath.Join(t.TempDir(), "survey_app.json.wal"), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewUserRepo(nil, jsonDB)
+	})
+}
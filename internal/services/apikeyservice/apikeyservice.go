@@ -0,0 +1,154 @@
+package apikeyservice
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/segmentio/ksuid"
+	"io"
+	"strings"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/services"
+	"survey-platform/pkg/idgenerator"
+	"survey-platform/pkg/timegenerator"
+)
+
+const (
+	// KeyPrefix starts every api key, so that leaked keys are easy to spot
+	KeyPrefix = "sp_"
+	// keyBytes is the number of random bytes in a key
+	keyBytes = 32
+	// displayedChars is the length of the start of a key kept to tell keys apart
+	displayedChars = len(KeyPrefix) + 8
+)
+
+type APIKeyService struct {
+	apiKeyRepo    repositories.APIKeyRepoInterface
+	userRepo      repositories.UserRepoInterface
+	idGenerator   idgenerator.IDGenerator
+	timeGenerator timegenerator.TimeGenInterface
+	// random is the source of the keys
+	random io.Reader
+}
+
+func NewAPIKeyService(apiKeyRepo repositories.APIKeyRepoInterface, userRepo repositories.UserRepoInterface,
+	idGenerator idgenerator.IDGenerator, timeGenerator timegenerator.TimeGenInterface) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo:    apiKeyRepo,
+		userRepo:      userRepo,
+		idGenerator:   idGenerator,
+		timeGenerator: timeGenerator,
+		random:        rand.Reader,
+	}
+}
+
+// CreateKey generates a new key of workspace granting scopes, the key is only returned here as just its hash is stored
+// the workspace does not have to exist beforehand, creating its first key creates it
+// a key bound to the user userID, who has to be a user of workspace, is limited to what the roles of the user grant
+func (s *APIKeyService) CreateKey(workspace models.WorkspaceID, name string, scopes []models.Scope, userID *ksuid.KSUID) (*models.CreatedAPIKey, error) {
+	if strings.TrimSpace(name) == "" || len(scopes) == 0 {
+		return nil, services.ErrInvalidAPIKeySpec
+	}
+	if !workspace.Valid() {
+		return nil, fmt.Errorf("%w: invalid workspace %q", services.ErrInvalidAPIKeySpec, workspace)
+	}
+	granted := make([]models.Scope, 0, len(scopes))
+	seen := make(map[models.Scope]bool, len(scopes))
+	for _, scope := range scopes {
+		if !scope.Valid() {
+			return nil, fmt.Errorf("%w: unknown scope %q", services.ErrInvalidAPIKeySpec, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			granted = append(granted, scope)
+		}
+	}
+	if userID != nil {
+		_, err := s.userRepo.Get(workspace, *userID)
+		if err == repositories.ErrNotFound {
+			return nil, fmt.Errorf("%w: unknown user %s", services.ErrInvalidAPIKeySpec, userID.String())
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	secret := make([]byte, keyBytes)
+	if _, err := io.ReadFull(s.random, secret); err != nil {
+		return nil, err
+	}
+	key := KeyPrefix + hex.EncodeToString(secret)
+	created, err := s.apiKeyRepo.Create(&models.APIKey{
+		ID:          s.idGenerator.Generate(),
+		WorkspaceID: workspace,
+		UserID:      userID,
+		Name:        name,
+		Prefix:      key[:displayedChars],
+		Hash:        hash(key),
+		Scopes:      granted,
+		CreatedAt:   s.timeGenerator.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.CreatedAPIKey{APIKey: redact(*created), Key: key}, nil
+}
+
+// GetKeys returns every key of workspace, revoked ones included, without their hashes
+func (s *APIKeyService) GetKeys(workspace models.WorkspaceID) ([]models.APIKey, error) {
+	keys, err := s.apiKeyRepo.GetAll(workspace)
+	if err != nil {
+		return nil, err
+	}
+	for i := range keys {
+		keys[i] = redact(keys[i])
+	}
+	return keys, nil
+}
+
+// RevokeKey stops a key of workspace from authenticating requests, the key stays listed
+func (s *APIKeyService) RevokeKey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.APIKey, error) {
+	revoked, err := s.apiKeyRepo.Revoke(workspace, id, s.timeGenerator.Now())
+	if err != nil {
+		return nil, err
+	}
+	key := redact(*revoked)
+	return &key, nil
+}
+
+// Authenticate returns the active key matching key
+// keys are looked up by the hash of the whole key, which is random enough that a fast hash cannot be reversed
+func (s *APIKeyService) Authenticate(key string) (*models.APIKey, error) {
+	if !strings.HasPrefix(key, KeyPrefix) {
+		return nil, services.ErrInvalidAPIKey
+	}
+	stored, err := s.apiKeyRepo.GetByHash(hash(key))
+	if err == repositories.ErrNotFound {
+		return nil, services.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stored.RevokedAt != nil {
+		return nil, services.ErrInvalidAPIKey
+	}
+	authenticated := redact(*stored)
+	authenticated.WorkspaceID = authenticated.Workspace()
+	return &authenticated, nil
+}
+
+// Entries returns the stored keys along with their hashes, to be persisted
+func (s *APIKeyService) Entries() map[ksuid.KSUID]models.APIKey {
+	return s.apiKeyRepo.Entries()
+}
+
+func hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// redact leaves the hash out of key, it is of no use outside of the service
+func redact(key models.APIKey) models.APIKey {
+	key.Hash = ""
+	return key
+}
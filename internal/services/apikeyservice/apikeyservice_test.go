@@ -0,0 +1,185 @@
+package apikeyservice
+
+import (
+	"bytes"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/repositories_mock"
+	"survey-platform/internal/services"
+	"survey-platform/pkg/idgenerator/idgenerator_mock"
+	"survey-platform/pkg/timegenerator/timegenerator_mock"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyService_CreateKey(t *testing.T) {
+	t.Run("should store the hash of a new key and return the key once", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		id, now := ksuid.New(), time.Now()
+		idGeneratorMock := idgenerator_mock.NewMockIDGenerator(ctrl)
+		idGeneratorMock.EXPECT().Generate().Return(id)
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		timeGeneratorMock.EXPECT().Now().Return(now)
+		mockAPIKeyRepo := repositories_mock.NewMockAPIKeyRepoInterface(ctrl)
+		var stored models.APIKey
+		mockAPIKeyRepo.EXPECT().Create(gomock.Any()).DoAndReturn(func(key *models.APIKey) (*models.APIKey, error) {
+			stored = *key
+			return key, nil
+		})
+		apiKeyService := NewAPIKeyService(mockAPIKeyRepo, nil, idGeneratorMock, timeGeneratorMock)
+		apiKeyService.random = bytes.NewReader(bytes.Repeat([]byte{0xab}, keyBytes))
+		created, err := apiKeyService.CreateKey("acme", "forms", []models.Scope{models.ScopeResponsesWrite, models.ScopeSurveysRead, models.ScopeResponsesWrite}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "sp_"+strings.Repeat("ab", keyBytes), created.Key)
+		assert.Equal(t, models.APIKey{
+			ID:          id,
+			WorkspaceID: "acme",
+			Name:        "forms",
+			Prefix:      "sp_abababab",
+			Scopes:      []models.Scope{models.ScopeResponsesWrite, models.ScopeSurveysRead},
+			CreatedAt:   now,
+		}, created.APIKey)
+		assert.Equal(t, hash(created.Key), stored.Hash)
+		assert.NotContains(t, stored.Hash, strings.Repeat("ab", 4))
+	})
+	for name, scopes := range map[string][]models.Scope{
+		"":      {models.ScopeSurveysRead},
+		"admin": {},
+		"forms": {models.ScopeResponsesWrite, "responses:delete"},
+	} {
+		t.Run("should reject a key named "+name+" without known scopes", func(t *testing.T) {
+			apiKeyService := NewAPIKeyService(nil, nil, nil, nil)
+			created, err := apiKeyService.CreateKey(models.DefaultWorkspace, name, scopes, nil)
+			assert.True(t, errors.Is(err, services.ErrInvalidAPIKeySpec))
+			assert.Nil(t, created)
+		})
+	}
+	t.Run("should bind the key to a user of the workspace", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		id, userID, now := ksuid.New(), ksuid.New(), time.Now()
+		idGeneratorMock := idgenerator_mock.NewMockIDGenerator(ctrl)
+		idGeneratorMock.EXPECT().Generate().Return(id)
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		timeGeneratorMock.EXPECT().Now().Return(now)
+		mockUserRepo := repositories_mock.NewMockUserRepoInterface(ctrl)
+		mockUserRepo.EXPECT().Get(models.WorkspaceID("acme"), userID).Return(&models.User{ID: userID, WorkspaceID: "acme"}, nil)
+		mockAPIKeyRepo := repositories_mock.NewMockAPIKeyRepoInterface(ctrl)
+		mockAPIKeyRepo.EXPECT().Create(gomock.Any()).DoAndReturn(func(key *models.APIKey) (*models.APIKey, error) {
+			return key, nil
+		})
+		apiKeyService := NewAPIKeyService(mockAPIKeyRepo, mockUserRepo, idGeneratorMock, timeGeneratorMock)
+		created, err := apiKeyService.CreateKey("acme", "alice", []models.Scope{models.ScopeSurveysRead}, &userID)
+		require.NoError(t, err)
+		assert.Equal(t, &userID, created.UserID)
+	})
+	t.Run("should reject a key bound to a user of another workspace", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		userID := ksuid.New()
+		mockUserRepo := repositories_mock.NewMockUserRepoInterface(ctrl)
+		mockUserRepo.EXPECT().Get(models.WorkspaceID("acme"), userID).Return(nil, repositories.ErrNotFound)
+		apiKeyService := NewAPIKeyService(nil, mockUserRepo, nil, nil)
+		created, err := apiKeyService.CreateKey("acme", "alice", []models.Scope{models.ScopeSurveysRead}, &userID)
+		assert.True(t, errors.Is(err, services.ErrInvalidAPIKeySpec))
+		assert.Nil(t, created)
+	})
+	t.Run("should reject a key of an invalid workspace", func(t *testing.T) {
+		apiKeyService := NewAPIKeyService(nil, nil, nil, nil)
+		created, err := apiKeyService.CreateKey("Acme Inc", "forms", []models.Scope{models.ScopeSurveysRead}, nil)
+		assert.True(t, errors.Is(err, services.ErrInvalidAPIKeySpec))
+		assert.Nil(t, created)
+	})
+}
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	key := "sp_" + strings.Repeat("ab", keyBytes)
+	t.Run("should return the active key matching the hash of the key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		stored := models.APIKey{ID: ksuid.New(), Name: "forms", Hash: hash(key), Scopes: []models.Scope{models.ScopeResponsesWrite}}
+		mockAPIKeyRepo := repositories_mock.NewMockAPIKeyRepoInterface(ctrl)
+		mockAPIKeyRepo.EXPECT().GetByHash(hash(key)).Return(&stored, nil)
+		apiKeyService := NewAPIKeyService(mockAPIKeyRepo, nil, nil, nil)
+		authenticated, err := apiKeyService.Authenticate(key)
+		require.NoError(t, err)
+		assert.Equal(t, stored.ID, authenticated.ID)
+		assert.Empty(t, authenticated.Hash)
+		assert.Equal(t, models.DefaultWorkspace, authenticated.WorkspaceID)
+	})
+	t.Run("should reject unknown, revoked and malformed keys", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		revokedAt := time.Now()
+		mockAPIKeyRepo := repositories_mock.NewMockAPIKeyRepoInterface(ctrl)
+		gomock.InOrder(
+			mockAPIKeyRepo.EXPECT().GetByHash(hash(key)).Return(nil, repositories.ErrNotFound),
+			mockAPIKeyRepo.EXPECT().GetByHash(hash(key)).Return(&models.APIKey{ID: ksuid.New(), RevokedAt: &revokedAt}, nil),
+		)
+		apiKeyService := NewAPIKeyService(mockAPIKeyRepo, nil, nil, nil)
+		for _, candidate := range []string{key, key, "not a key"} {
+			authenticated, err := apiKeyService.Authenticate(candidate)
+			assert.Equal(t, services.ErrInvalidAPIKey, err)
+			assert.Nil(t, authenticated)
+		}
+	})
+	t.Run("should return error when repo returns error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		expectedErr := errors.New("something went wrong")
+		mockAPIKeyRepo := repositories_mock.NewMockAPIKeyRepoInterface(ctrl)
+		mockAPIKeyRepo.EXPECT().GetByHash(hash(key)).Return(nil, expectedErr)
+		apiKeyService := NewAPIKeyService(mockAPIKeyRepo, nil, nil, nil)
+		_, err := apiKeyService.Authenticate(key)
+		assert.Equal(t, expectedErr, err)
+	})
+}
+
+func TestAPIKeyService_GetKeys(t *testing.T) {
+	t.Run("should list keys without their hashes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		keys := []models.APIKey{{ID: ksuid.New(), Name: "admin", Hash: "hash"}}
+		mockAPIKeyRepo := repositories_mock.NewMockAPIKeyRepoInterface(ctrl)
+		mockAPIKeyRepo.EXPECT().GetAll(models.DefaultWorkspace).Return(keys, nil)
+		apiKeyService := NewAPIKeyService(mockAPIKeyRepo, nil, nil, nil)
+		listed, err := apiKeyService.GetKeys(models.DefaultWorkspace)
+		require.NoError(t, err)
+		assert.Equal(t, []models.APIKey{{ID: keys[0].ID, Name: "admin"}}, listed)
+	})
+}
+
+func TestAPIKeyService_RevokeKey(t *testing.T) {
+	t.Run("should revoke the key now", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		id, now := ksuid.New(), time.Now()
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		timeGeneratorMock.EXPECT().Now().Return(now)
+		mockAPIKeyRepo := repositories_mock.NewMockAPIKeyRepoInterface(ctrl)
+		mockAPIKeyRepo.EXPECT().Revoke(models.DefaultWorkspace, id, now).Return(&models.APIKey{ID: id, Hash: "hash", RevokedAt: &now}, nil)
+		apiKeyService := NewAPIKeyService(mockAPIKeyRepo, nil, nil, timeGeneratorMock)
+		revoked, err := apiKeyService.RevokeKey(models.DefaultWorkspace, id)
+		require.NoError(t, err)
+		assert.Equal(t, &models.APIKey{ID: id, RevokedAt: &now}, revoked)
+	})
+	t.Run("should return ErrNotFound for unknown keys", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		id, now := ksuid.New(), time.Now()
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		timeGeneratorMock.EXPECT().Now().Return(now)
+		mockAPIKeyRepo := repositories_mock.NewMockAPIKeyRepoInterface(ctrl)
+		mockAPIKeyRepo.EXPECT().Revoke(models.DefaultWorkspace, id, now).Return(nil, repositories.ErrNotFound)
+		apiKeyService := NewAPIKeyService(mockAPIKeyRepo, nil, nil, timeGeneratorMock)
+		_, err := apiKeyService.RevokeKey(models.DefaultWorkspace, id)
+		assert.Equal(t, repositories.ErrNotFound, err)
+	})
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"errors"
+	"github.com/segmentio/ksuid"
+	"strings"
+)
+
+var (
+	ErrInvalidTransition    = errors.New("survey cannot move to the requested status")
+	ErrSurveyNotPublished   = errors.New("survey is not accepting responses")
+	ErrSurveyArchived       = errors.New("survey is archived and cannot be changed")
+	ErrSurveyNotOpen        = errors.New("survey is not open for responses yet")
+	ErrSurveyClosed         = errors.New("survey is closed for responses")
+	ErrQuotaReached         = errors.New("survey reached its maximum number of responses")
+	ErrQuestionNotFound     = errors.New("question is not part of the survey")
+	ErrQuestionNotTabulated = errors.New("question has free form answers which cannot be tabulated")
+	ErrUnknownImportFormat  = errors.New("unknown import format")
+	ErrImportNeedsSurvey    = errors.New("csv imports need the survey the responses answer")
+	ErrMalformedImport      = errors.New("import file is malformed")
+	ErrInvalidAPIKey        = errors.New("api key is invalid or revoked")
+	ErrInvalidAPIKeySpec    = errors.New("api key needs a name, at least one known scope and a valid workspace")
+	ErrInvalidUser          = errors.New("user needs a name and known roles on the workspace or its surveys")
+	ErrForbidden            = errors.New("access denied")
+	ErrInvalidSurvey        = errors.New("invalid survey")
+	ErrInvalidRespondent    = errors.New("respondents need a valid email")
+	ErrInvalidInvitation    = errors.New("invitation token is invalid")
+	ErrDraftsDisabled       = errors.New("drafts are disabled")
+	ErrInvalidResumeToken   = errors.New("resume token is invalid")
+	ErrDraftExpired         = errors.New("draft expired")
+	ErrEditWindowClosed     = errors.New("response can no longer be edited or withdrawn")
+)
+
+// Violation is a single problem found while validating the answer to a question
+type Violation struct {
+	QuestionID ksuid.KSUID `json:"question_id"`
+	Message    string      `json:"message"`
+}
+
+// ValidationError is returned when a request breaks the rules of the survey it targets
+// it lists every violation found instead of stopping at the first one
+type ValidationError struct {
+	Violations []Violation `json:"violations"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, "question "+violation.QuestionID.String()+": "+violation.Message)
+	}
+	return "invalid response: " + strings.Join(messages, "; ")
+}
+
+// Add records a violation for questionID
+func (e *ValidationError) Add(questionID ksuid.KSUID, message string) {
+	e.Violations = append(e.Violations, Violation{QuestionID: questionID, Message: message})
+}
+
+// ErrOrNil returns the error when any violation was recorded and nil otherwise
+func (e *ValidationError) ErrOrNil() error {
+	if len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,307 @@
+package invitationservice
+
+import (
+	"fmt"
+	"github.com/segmentio/ksuid"
+	"net/mail"
+	"strings"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/services"
+	"survey-platform/pkg/idgenerator"
+	"survey-platform/pkg/signer"
+	"survey-platform/pkg/timegenerator"
+)
+
+type InvitationService struct {
+	invitationRepo repositories.InvitationRepoInterface
+	respondentRepo repositories.RespondentRepoInterface
+	surveyRepo     repositories.SurveyRepoInterface
+	idGenerator    idgenerator.IDGenerator
+	timeGenerator  timegenerator.TimeGenInterface
+	signer         *signer.Signer
+}
+
+// NewInvitationService returns a service signing invitation tokens with secret
+// tokens signed with one secret are rejected once the secret changes
+func NewInvitationService(invitationRepo repositories.InvitationRepoInterface, respondentRepo repositories.RespondentRepoInterface,
+	surveyRepo repositories.SurveyRepoInterface, idGenerator idgenerator.IDGenerator, timeGenerator timegenerator.TimeGenInterface,
+	secret []byte) *InvitationService {
+	return &InvitationService{
+		invitationRepo: invitationRepo,
+		respondentRepo: respondentRepo,
+		surveyRepo:     surveyRepo,
+		idGenerator:    idGenerator,
+		timeGenerator:  timeGenerator,
+		signer:         signer.NewSigner(secret),
+	}
+}
+
+// Invite invites the respondents to a survey of workspace which is not archived
+// emails are compared lowercased, so a respondent listed twice is invited once
+func (s *InvitationService) Invite(workspace models.WorkspaceID, surveyID ksuid.KSUID, respondents []models.Respondent) ([]models.InvitationDetails, error) {
+	survey, err := s.surveyRepo.Get(workspace, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if survey.State() == models.SurveyArchived {
+		return nil, services.ErrSurveyArchived
+	}
+	respondents, err = validRespondents(respondents)
+	if err != nil {
+		return nil, err
+	}
+	invited, err := s.invitedRespondents(workspace, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	details := make([]models.InvitationDetails, 0, len(respondents))
+	for _, respondent := range respondents {
+		stored, err := s.respondent(workspace, respondent)
+		if err != nil {
+			return nil, err
+		}
+		invitation, ok := invited[stored.ID]
+		if !ok {
+			if invitation, err = s.invite(workspace, surveyID, stored.ID); err != nil {
+				return nil, err
+			}
+		}
+		details = append(details, s.details(invitation, *stored))
+	}
+	return details, nil
+}
+
+// validRespondents normalises the emails of respondents and drops the respondents listed more than once
+func validRespondents(respondents []models.Respondent) ([]models.Respondent, error) {
+	if len(respondents) == 0 {
+		return nil, fmt.Errorf("%w: no respondent to invite", services.ErrInvalidRespondent)
+	}
+	valid := make([]models.Respondent, 0, len(respondents))
+	seen := make(map[string]bool, len(respondents))
+	for _, respondent := range respondents {
+		email := strings.ToLower(strings.TrimSpace(respondent.Email))
+		if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+			return nil, fmt.Errorf("%w: %q is not an email", services.ErrInvalidRespondent, respondent.Email)
+		}
+		if seen[email] {
+			continue
+		}
+		seen[email] = true
+		valid = append(valid, models.Respondent{Email: email, Name: strings.TrimSpace(respondent.Name)})
+	}
+	return valid, nil
+}
+
+// invitedRespondents returns the invitations to a survey by respondent
+func (s *InvitationService) invitedRespondents(workspace models.WorkspaceID, surveyID ksuid.KSUID) (map[ksuid.KSUID]models.Invitation, error) {
+	invitations, err := s.invitationRepo.GetBySurveyID(workspace, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	invited := make(map[ksuid.KSUID]models.Invitation, len(invitations))
+	for _, invitation := range invitations {
+		invited[invitation.RespondentID] = invitation
+	}
+	return invited, nil
+}
+
+// invite creates the invitation of a respondent to a survey, or returns the one created by a concurrent invitation
+func (s *InvitationService) invite(workspace models.WorkspaceID, surveyID, respondentID ksuid.KSUID) (models.Invitation, error) {
+	created, err := s.invitationRepo.Create(&models.Invitation{
+		ID:           s.idGenerator.Generate(),
+		WorkspaceID:  workspace,
+		SurveyID:     surveyID,
+		RespondentID: respondentID,
+		CreatedAt:    s.timeGenerator.Now(),
+	})
+	if err == nil {
+		return *created, nil
+	}
+	if err != repositories.ErrAlreadyExists {
+		return models.Invitation{}, err
+	}
+	invited, err := s.invitedRespondents(workspace, surveyID)
+	if err != nil {
+		return models.Invitation{}, err
+	}
+	invitation, ok := invited[respondentID]
+	if !ok {
+		return models.Invitation{}, repositories.ErrAlreadyExists
+	}
+	return invitation, nil
+}
+
+// respondent returns the stored respondent with the email of respondent, creating it when there is none
+func (s *InvitationService) respondent(workspace models.WorkspaceID, respondent models.Respondent) (*models.Respondent, error) {
+	stored, err := s.respondentRepo.GetByEmail(workspace, respondent.Email)
+	if err != repositories.ErrNotFound {
+		return stored, err
+	}
+	stored, err = s.respondentRepo.Create(&models.Respondent{
+		ID:          s.idGenerator.Generate(),
+		WorkspaceID: workspace,
+		Email:       respondent.Email,
+		Name:        respondent.Name,
+		CreatedAt:   s.timeGenerator.Now(),
+	})
+	if err == repositories.ErrAlreadyExists {
+		// the respondent was created by a concurrent invitation
+		return s.respondentRepo.GetByEmail(workspace, respondent.Email)
+	}
+	return stored, err
+}
+
+// GetInvitations returns every invitation to a survey of workspace along with the respondents and their tokens
+func (s *InvitationService) GetInvitations(workspace models.WorkspaceID, surveyID ksuid.KSUID) ([]models.InvitationDetails, error) {
+	if _, err := s.surveyRepo.Get(workspace, surveyID); err != nil {
+		return nil, err
+	}
+	invitations, err := s.invitationRepo.GetBySurveyID(workspace, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	details := make([]models.InvitationDetails, 0, len(invitations))
+	for _, invitation := range invitations {
+		respondent, err := s.respondentRepo.Get(workspace, invitation.RespondentID)
+		if err != nil {
+			return nil, err
+		}
+		details = append(details, s.details(invitation, *respondent))
+	}
+	return details, nil
+}
+
+// Summary counts the invitations to a survey of workspace
+func (s *InvitationService) Summary(workspace models.WorkspaceID, surveyID ksuid.KSUID) (*models.InvitationSummary, error) {
+	if _, err := s.surveyRepo.Get(workspace, surveyID); err != nil {
+		return nil, err
+	}
+	invitations, err := s.invitationRepo.GetBySurveyID(workspace, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	summary := &models.InvitationSummary{Invited: len(invitations)}
+	for _, invitation := range invitations {
+		if invitation.StartedAt != nil {
+			summary.Started++
+		}
+		if invitation.CompletedAt != nil {
+			summary.Completed++
+		}
+	}
+	return summary, nil
+}
+
+// Open marks the invitation of token as started the first time the respondent opens it
+// answered invitations cannot be opened again
+func (s *InvitationService) Open(workspace models.WorkspaceID, token string) (*models.InvitationDetails, error) {
+	invitation, err := s.invitation(workspace, token)
+	if err != nil {
+		return nil, err
+	}
+	if invitation.CompletedAt != nil {
+		return nil, repositories.ErrInvitationCompleted
+	}
+	started, err := s.invitationRepo.Start(workspace, invitation.ID, s.timeGenerator.Now())
+	if err != nil {
+		return nil, err
+	}
+	respondent, err := s.respondentRepo.Get(workspace, started.RespondentID)
+	if err != nil {
+		return nil, err
+	}
+	details := s.details(*started, *respondent)
+	return &details, nil
+}
+
+// Verify returns the invitation of token when it invites to the survey and was not answered yet
+// the invitation is only completed once the response answering it is stored
+func (s *InvitationService) Verify(workspace models.WorkspaceID, surveyID ksuid.KSUID, token string) (*models.Invitation, error) {
+	invitation, err := s.invitation(workspace, token)
+	if err != nil {
+		return nil, err
+	}
+	if invitation.SurveyID != surveyID {
+		return nil, fmt.Errorf("%w: the invitation is to another survey", services.ErrInvalidInvitation)
+	}
+	if invitation.CompletedAt != nil {
+		return nil, repositories.ErrInvitationCompleted
+	}
+	return invitation, nil
+}
+
+// Authenticate returns the invitation of token whether it was answered or not
+func (s *InvitationService) Authenticate(workspace models.WorkspaceID, token string) (*models.Invitation, error) {
+	return s.invitation(workspace, token)
+}
+
+// GetInvitation returns the invitation id of workspace
+func (s *InvitationService) GetInvitation(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Invitation, error) {
+	return s.invitationRepo.Get(workspace, id)
+}
+
+// GetPending returns the invitations of every workspace which were not completed, along with the respondents and their tokens
+func (s *InvitationService) GetPending() ([]models.InvitationDetails, error) {
+	invitations, err := s.invitationRepo.GetPending()
+	if err != nil {
+		return nil, err
+	}
+	details := make([]models.InvitationDetails, 0, len(invitations))
+	for _, invitation := range invitations {
+		respondent, err := s.respondentRepo.Get(invitation.Workspace(), invitation.RespondentID)
+		if err != nil {
+			return nil, err
+		}
+		details = append(details, s.details(invitation, *respondent))
+	}
+	return details, nil
+}
+
+// invitation returns the stored invitation token was signed for, the invitations to deleted surveys are no longer valid
+func (s *InvitationService) invitation(workspace models.WorkspaceID, token string) (*models.Invitation, error) {
+	value, err := s.signer.Verify(token)
+	if err != nil {
+		return nil, services.ErrInvalidInvitation
+	}
+	parts := strings.Split(value, ".")
+	if len(parts) != 2 {
+		return nil, services.ErrInvalidInvitation
+	}
+	surveyID, err := ksuid.Parse(parts[0])
+	if err != nil {
+		return nil, services.ErrInvalidInvitation
+	}
+	id, err := ksuid.Parse(parts[1])
+	if err != nil {
+		return nil, services.ErrInvalidInvitation
+	}
+	invitation, err := s.invitationRepo.Get(workspace, id)
+	if err == repositories.ErrNotFound || err == nil && invitation.SurveyID != surveyID {
+		return nil, services.ErrInvalidInvitation
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err = s.surveyRepo.Get(workspace, surveyID); err == repositories.ErrNotFound {
+		return nil, fmt.Errorf("%w: the survey was deleted", services.ErrInvalidInvitation)
+	} else if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// details adds the respondent and the token of the invitation, tokens are the survey and invitation ids signed together
+// so that the survey of a token is known before it is verified
+func (s *InvitationService) details(invitation models.Invitation, respondent models.Respondent) models.InvitationDetails {
+	return models.InvitationDetails{
+		Invitation: invitation,
+		Respondent: respondent,
+		Token:      s.signer.Sign(invitation.SurveyID.String() + "." + invitation.ID.String()),
+	}
+}
+
+// Entries returns the respondents and invitations of every workspace
+func (s *InvitationService) Entries() (map[ksuid.KSUID]models.Respondent, map[ksuid.KSUID]models.Invitation) {
+	return s.respondentRepo.Entries(), s.invitationRepo.Entries()
+}
@@ -0,0 +1,227 @@
+package invitationservice
+
+import (
+	"errors"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/invitationrepo"
+	"survey-platform/internal/repositories/respondentrepo"
+	"survey-platform/internal/repositories/responserepo"
+	"survey-platform/internal/repositories/surveyrepo"
+	"survey-platform/internal/services"
+	"survey-platform/pkg/idgenerator/ksuidgenerator"
+	"survey-platform/pkg/timegenerator/actualtimegenerator"
+	"testing"
+	"time"
+)
+
+// fixture is an invitation service over in memory repos holding a published survey
+type fixture struct {
+	service      *InvitationService
+	surveyRepo   *surveyrepo.SurveyRepo
+	responseRepo *responserepo.ResponseRepo
+	survey       models.Survey
+}
+
+func newFixture(t *testing.T, secret string) *fixture {
+	survey := models.Survey{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, Name: "survey", Status: models.SurveyPublished}
+	surveyRepo := surveyrepo.NewSurveyRepo(nil, nil, nil)
+	_, err := surveyRepo.Create(&survey)
+	require.NoError(t, err)
+	invitationRepo := invitationrepo.NewInvitationRepo(nil, nil)
+	return &fixture{
+		service: NewInvitationService(invitationRepo, respondentrepo.NewRespondentRepo(nil, nil), surveyRepo,
+			ksuidgenerator.NewKSUIDGenerator(), actualtimegenerator.NewActualTimeGenerator(), []byte(secret)),
+		surveyRepo:   surveyRepo,
+		responseRepo: responserepo.NewResponseRepo(nil, nil).WithInvitations(invitationRepo),
+		survey:       survey,
+	}
+}
+
+// answer stores a response completing invitation, as saving a response with its token does
+func (f *fixture) answer(t *testing.T, invitation models.Invitation) {
+	response := models.Response{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, SurveyID: invitation.SurveyID,
+		InvitationID: &invitation.ID, RespondentID: &invitation.RespondentID, CreatedAt: time.Now()}
+	_, err := f.responseRepo.Create(&response)
+	require.NoError(t, err)
+}
+
+func TestInvitationService_Invite(t *testing.T) {
+	t.Run("should invite each respondent once and give the same token back", func(t *testing.T) {
+		f := newFixture(t, "secret")
+		invitations, err := f.service.Invite(models.DefaultWorkspace, f.survey.ID, []models.Respondent{
+			{Email: " Alice@Example.com ", Name: "Alice"}, {Email: "alice@example.com"}, {Email: "bob@example.com"},
+		})
+		require.NoError(t, err)
+		require.Len(t, invitations, 2)
+		alice := invitations[0]
+		assert.Equal(t, "alice@example.com", alice.Respondent.Email)
+		assert.Equal(t, "Alice", alice.Respondent.Name)
+		assert.Equal(t, alice.Respondent.ID, alice.RespondentID)
+		assert.Equal(t, f.survey.ID, alice.SurveyID)
+		assert.NotEmpty(t, alice.Token)
+		assert.NotEqual(t, alice.Token, invitations[1].Token)
+		again, err := f.service.Invite(models.DefaultWorkspace, f.survey.ID, []models.Respondent{{Email: "alice@example.com"}})
+		require.NoError(t, err)
+		assert.Equal(t, []models.InvitationDetails{alice}, again)
+		listed, err := f.service.GetInvitations(models.DefaultWorkspace, f.survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, invitations, listed)
+	})
+	t.Run("should invite the same respondent to several surveys", func(t *testing.T) {
+		f := newFixture(t, "secret")
+		other := models.Survey{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, Name: "other survey"}
+		_, err := f.surveyRepo.Create(&other)
+		require.NoError(t, err)
+		first, err := f.service.Invite(models.DefaultWorkspace, f.survey.ID, []models.Respondent{{Email: "alice@example.com"}})
+		require.NoError(t, err)
+		second, err := f.service.Invite(models.DefaultWorkspace, other.ID, []models.Respondent{{Email: "alice@example.com"}})
+		require.NoError(t, err)
+		assert.Equal(t, first[0].RespondentID, second[0].RespondentID)
+		assert.NotEqual(t, first[0].ID, second[0].ID)
+	})
+	t.Run("should reject invalid emails and missing or archived surveys", func(t *testing.T) {
+		f := newFixture(t, "secret")
+		for _, respondents := range [][]models.Respondent{nil, {{Email: "alice"}}, {{Email: "Alice <alice@example.com>"}}} {
+			_, err := f.service.Invite(models.DefaultWorkspace, f.survey.ID, respondents)
+			assert.True(t, errors.Is(err, services.ErrInvalidRespondent), respondents)
+		}
+		respondents := []models.Respondent{{Email: "alice@example.com"}}
+		_, err := f.service.Invite("other", f.survey.ID, respondents)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		archived := models.Survey{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, Name: "archived", Status: models.SurveyArchived}
+		_, err = f.surveyRepo.Create(&archived)
+		require.NoError(t, err)
+		_, err = f.service.Invite(models.DefaultWorkspace, archived.ID, respondents)
+		assert.Equal(t, services.ErrSurveyArchived, err)
+	})
+}
+
+func TestInvitationService_Verify(t *testing.T) {
+	t.Run("should accept the token of an unanswered invitation to the survey only", func(t *testing.T) {
+		f := newFixture(t, "secret")
+		invitations, err := f.service.Invite(models.DefaultWorkspace, f.survey.ID, []models.Respondent{{Email: "alice@example.com"}})
+		require.NoError(t, err)
+		invitation := invitations[0]
+		verified, err := f.service.Verify(models.DefaultWorkspace, f.survey.ID, invitation.Token)
+		require.NoError(t, err)
+		assert.Equal(t, invitation.Invitation, *verified)
+		other := newFixture(t, "other secret")
+		otherToken := other.service.details(invitation.Invitation, invitation.Respondent).Token
+		for _, rejected := range []struct {
+			workspace models.WorkspaceID
+			surveyID  ksuid.KSUID
+			token     string
+		}{
+			{models.DefaultWorkspace, ksuid.New(), invitation.Token},
+			{"other", f.survey.ID, invitation.Token},
+			{models.DefaultWorkspace, f.survey.ID, invitation.Token[:len(invitation.Token)-1]},
+			{models.DefaultWorkspace, f.survey.ID, otherToken},
+			{models.DefaultWorkspace, f.survey.ID, "not a token"},
+		} {
+			_, err = f.service.Verify(rejected.workspace, rejected.surveyID, rejected.token)
+			assert.True(t, errors.Is(err, services.ErrInvalidInvitation), rejected)
+		}
+		f.answer(t, *verified)
+		_, err = f.service.Verify(models.DefaultWorkspace, f.survey.ID, invitation.Token)
+		assert.Equal(t, repositories.ErrInvitationCompleted, err)
+	})
+}
+
+func TestInvitationService_DeletedSurvey(t *testing.T) {
+	t.Run("should reject the tokens of invitations to a deleted survey", func(t *testing.T) {
+		f := newFixture(t, "secret")
+		invitations, err := f.service.Invite(models.DefaultWorkspace, f.survey.ID, []models.Respondent{{Email: "alice@example.com"}})
+		require.NoError(t, err)
+		invitation := invitations[0]
+		require.NoError(t, f.surveyRepo.Delete(models.DefaultWorkspace, f.survey.ID, false))
+		_, err = f.service.Open(models.DefaultWorkspace, invitation.Token)
+		assert.True(t, errors.Is(err, services.ErrInvalidInvitation))
+		_, err = f.service.Verify(models.DefaultWorkspace, f.survey.ID, invitation.Token)
+		assert.True(t, errors.Is(err, services.ErrInvalidInvitation))
+		_, err = f.service.Authenticate(models.DefaultWorkspace, invitation.Token)
+		assert.True(t, errors.Is(err, services.ErrInvalidInvitation))
+	})
+}
+
+func TestInvitationService_Authenticate(t *testing.T) {
+	t.Run("should return the invitation of the token once it is answered as well", func(t *testing.T) {
+		f := newFixture(t, "secret")
+		invitations, err := f.service.Invite(models.DefaultWorkspace, f.survey.ID, []models.Respondent{{Email: "alice@example.com"}})
+		require.NoError(t, err)
+		invitation := invitations[0]
+		f.answer(t, invitation.Invitation)
+		authenticated, err := f.service.Authenticate(models.DefaultWorkspace, invitation.Token)
+		require.NoError(t, err)
+		assert.Equal(t, invitation.ID, authenticated.ID)
+		assert.NotNil(t, authenticated.CompletedAt)
+		for _, rejected := range []struct {
+			workspace models.WorkspaceID
+			token     string
+		}{
+			{"other", invitation.Token},
+			{models.DefaultWorkspace, invitation.Token[:len(invitation.Token)-1]},
+			{models.DefaultWorkspace, "not a token"},
+		} {
+			_, err = f.service.Authenticate(rejected.workspace, rejected.token)
+			assert.True(t, errors.Is(err, services.ErrInvalidInvitation), rejected)
+		}
+	})
+}
+
+func TestInvitationService_GetInvitation(t *testing.T) {
+	t.Run("should return the invitation within its workspace, answered or not", func(t *testing.T) {
+		f := newFixture(t, "secret")
+		invitations, err := f.service.Invite(models.DefaultWorkspace, f.survey.ID, []models.Respondent{{Email: "alice@example.com"}})
+		require.NoError(t, err)
+		invitation := invitations[0]
+		pending, err := f.service.GetInvitation(models.DefaultWorkspace, invitation.ID)
+		require.NoError(t, err)
+		assert.Nil(t, pending.CompletedAt)
+		f.answer(t, invitation.Invitation)
+		completed, err := f.service.GetInvitation(models.DefaultWorkspace, invitation.ID)
+		require.NoError(t, err)
+		assert.NotNil(t, completed.CompletedAt)
+		_, err = f.service.GetInvitation("other", invitation.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+	})
+}
+
+func TestInvitationService_Summary(t *testing.T) {
+	t.Run("should count invited, started and completed invitations", func(t *testing.T) {
+		f := newFixture(t, "secret")
+		invitations, err := f.service.Invite(models.DefaultWorkspace, f.survey.ID, []models.Respondent{
+			{Email: "alice@example.com"}, {Email: "bob@example.com"}, {Email: "carol@example.com"},
+		})
+		require.NoError(t, err)
+		opened, err := f.service.Open(models.DefaultWorkspace, invitations[0].Token)
+		require.NoError(t, err)
+		assert.NotNil(t, opened.StartedAt)
+		assert.Equal(t, invitations[0].Token, opened.Token)
+		f.answer(t, invitations[1].Invitation)
+		summary, err := f.service.Summary(models.DefaultWorkspace, f.survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.InvitationSummary{Invited: 3, Started: 2, Completed: 1}, *summary)
+		_, err = f.service.Open(models.DefaultWorkspace, invitations[1].Token)
+		assert.Equal(t, repositories.ErrInvitationCompleted, err)
+		_, err = f.service.Summary(models.DefaultWorkspace, ksuid.New())
+		assert.Equal(t, repositories.ErrNotFound, err)
+	})
+}
+
+func TestInvitationService_GetPending(t *testing.T) {
+	t.Run("should return the invitations which were not completed along with their tokens", func(t *testing.T) {
+		f := newFixture(t, "secret")
+		invitations, err := f.service.Invite(models.DefaultWorkspace, f.survey.ID, []models.Respondent{
+			{Email: "alice@example.com"}, {Email: "bob@example.com"},
+		})
+		require.NoError(t, err)
+		f.answer(t, invitations[0].Invitation)
+		pending, err := f.service.GetPending()
+		require.NoError(t, err)
+		assert.Equal(t, invitations[1:], pending)
+	})
+}
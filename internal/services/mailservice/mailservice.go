@@ -0,0 +1,341 @@
+package mailservice
+
+import (
+	"context"
+	"github.com/segmentio/ksuid"
+	"log"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/services"
+	"survey-platform/pkg/idgenerator"
+	"survey-platform/pkg/mailer"
+	"survey-platform/pkg/timegenerator"
+	"sync"
+	"time"
+)
+
+// Config decides what is emailed to respondents and how sends are retried
+type Config struct {
+	// Link is followed by the token of an invitation to form the link the respondent answers with
+	Link       string
+	Invitation *mailer.Template
+	Reminder   *mailer.Template
+	// ReminderAfter is waited after the last email to a respondent before reminding them, MaxReminders are sent at most
+	ReminderAfter time.Duration
+	MaxReminders  int
+	// MaxAttempts sends are made before a message is given up, Backoff is waited after the first failed send
+	// and doubles after every further one, up to MaxBackoff
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	// BatchSize messages are sent at most by each delivery
+	BatchSize int
+}
+
+// DefaultConfig returns the config sending the default templates, it only misses the link
+func DefaultConfig() Config {
+	invitation, err := mailer.ParseTemplate("invitation", mailer.DefaultInvitation)
+	if err != nil {
+		panic(err)
+	}
+	reminder, err := mailer.ParseTemplate("reminder", mailer.DefaultReminder)
+	if err != nil {
+		panic(err)
+	}
+	return Config{
+		Invitation:    invitation,
+		Reminder:      reminder,
+		ReminderAfter: 72 * time.Hour,
+		MaxReminders:  2,
+		MaxAttempts:   5,
+		Backoff:       time.Minute,
+		MaxBackoff:    time.Hour,
+		BatchSize:     50,
+	}
+}
+
+// mailData is what the invitation and reminder templates are rendered with
+type mailData struct {
+	Name     string
+	Email    string
+	Survey   string
+	Link     string
+	ClosesAt *time.Time
+}
+
+type MailService struct {
+	outboxRepo        repositories.OutboxRepoInterface
+	surveyRepo        repositories.SurveyRepoInterface
+	invitationService services.InvitationServiceInterface
+	sender            mailer.Sender
+	idGenerator       idgenerator.IDGenerator
+	timeGenerator     timegenerator.TimeGenInterface
+	config            Config
+	// mu serialises queueing, so that an invitation is not emailed twice by concurrent calls
+	mu sync.Mutex
+}
+
+// NewMailService returns a service sending emails with sender, a nil sender disables emailing and keeps the outbox as it is
+func NewMailService(outboxRepo repositories.OutboxRepoInterface, surveyRepo repositories.SurveyRepoInterface,
+	invitationService services.InvitationServiceInterface, sender mailer.Sender, idGenerator idgenerator.IDGenerator,
+	timeGenerator timegenerator.TimeGenInterface, config Config) *MailService {
+	return &MailService{
+		outboxRepo:        outboxRepo,
+		surveyRepo:        surveyRepo,
+		invitationService: invitationService,
+		sender:            sender,
+		idGenerator:       idGenerator,
+		timeGenerator:     timeGenerator,
+		config:            config,
+	}
+}
+
+// QueueInvitations queues the invitation email of every invitation which was neither emailed nor completed yet
+func (s *MailService) QueueInvitations(workspace models.WorkspaceID, invitations []models.InvitationDetails) error {
+	if s.sender == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	surveys := make(map[ksuid.KSUID]*models.Survey)
+	for _, invitation := range invitations {
+		if invitation.CompletedAt != nil {
+			continue
+		}
+		messages, err := s.outboxRepo.GetByInvitationID(workspace, invitation.ID)
+		if err != nil {
+			return err
+		}
+		if len(messages) > 0 {
+			continue
+		}
+		survey, ok := surveys[invitation.SurveyID]
+		if !ok {
+			if survey, err = s.surveyRepo.Get(workspace, invitation.SurveyID); err != nil {
+				return err
+			}
+			surveys[invitation.SurveyID] = survey
+		}
+		if err = s.queue(models.MailInvitation, invitation, *survey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remind queues a reminder to the respondents of pending invitations to surveys accepting responses
+// once ReminderAfter passed since the last email to them was sent, or given up
+// invitations which were never emailed are not reminded of
+func (s *MailService) Remind() (int, error) {
+	if s.sender == nil || s.config.MaxReminders == 0 {
+		return 0, nil
+	}
+	invitations, err := s.invitationService.GetPending()
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.timeGenerator.Now()
+	surveys := make(map[ksuid.KSUID]*models.Survey)
+	reminded := 0
+	for _, invitation := range invitations {
+		survey, ok := surveys[invitation.SurveyID]
+		if !ok {
+			survey, err = s.surveyRepo.Get(invitation.Workspace(), invitation.SurveyID)
+			if err == repositories.ErrNotFound {
+				survey = nil
+			} else if err != nil {
+				return reminded, err
+			}
+			surveys[invitation.SurveyID] = survey
+		}
+		if survey == nil || !accepting(*survey, now) {
+			continue
+		}
+		messages, err := s.outboxRepo.GetByInvitationID(invitation.Workspace(), invitation.ID)
+		if err != nil {
+			return reminded, err
+		}
+		if !s.remindable(messages, now) {
+			continue
+		}
+		if err = s.queue(models.MailReminder, invitation, *survey); err != nil {
+			return reminded, err
+		}
+		reminded++
+	}
+	return reminded, nil
+}
+
+// remindable reports whether a reminder is due after messages, the emails already queued for an invitation
+func (s *MailService) remindable(messages []models.OutboxMessage, now time.Time) bool {
+	if len(messages) == 0 {
+		return false
+	}
+	reminders := 0
+	for _, message := range messages {
+		if message.Pending() {
+			return false
+		}
+		if message.Kind == models.MailReminder {
+			reminders++
+		}
+	}
+	// the last message is either sent or given up, the delay runs from then
+	last := messages[len(messages)-1]
+	lastAt := last.FailedAt
+	if last.SentAt != nil {
+		lastAt = last.SentAt
+	}
+	return reminders < s.config.MaxReminders && !now.Before(lastAt.Add(s.config.ReminderAfter))
+}
+
+// accepting reports whether survey is published and within its response window at now
+func accepting(survey models.Survey, now time.Time) bool {
+	if survey.State() != models.SurveyPublished {
+		return false
+	}
+	if survey.OpensAt != nil && now.Before(*survey.OpensAt) {
+		return false
+	}
+	return survey.ClosesAt == nil || now.Before(*survey.ClosesAt)
+}
+
+// queue renders the email of kind to the respondent of invitation and queues it to be sent right away
+func (s *MailService) queue(kind models.MailKind, invitation models.InvitationDetails, survey models.Survey) error {
+	template := s.config.Invitation
+	if kind == models.MailReminder {
+		template = s.config.Reminder
+	}
+	subject, body, err := template.Render(mailData{
+		Name:     invitation.Respondent.Name,
+		Email:    invitation.Respondent.Email,
+		Survey:   survey.Name,
+		Link:     s.config.Link + invitation.Token,
+		ClosesAt: survey.ClosesAt,
+	})
+	if err != nil {
+		return err
+	}
+	now := s.timeGenerator.Now()
+	_, err = s.outboxRepo.Create(&models.OutboxMessage{
+		ID:            s.idGenerator.Generate(),
+		WorkspaceID:   invitation.Workspace(),
+		InvitationID:  invitation.ID,
+		Kind:          kind,
+		To:            invitation.Respondent.Email,
+		Subject:       subject,
+		Body:          body,
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	})
+	return err
+}
+
+// Deliver sends up to BatchSize messages which are due, a failed send is retried after the backoff
+// until MaxAttempts sends failed, then the message is given up, obsolete messages are given up without being sent
+// failed sends are logged, only errors of the outbox are returned
+func (s *MailService) Deliver() (int, error) {
+	if s.sender == nil {
+		return 0, nil
+	}
+	due, err := s.outboxRepo.Due(s.timeGenerator.Now(), s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, message := range due {
+		reason, err := s.obsolete(message)
+		if err != nil {
+			log.Println("error while checking email", message.ID.String(), err)
+			continue
+		}
+		if reason != "" {
+			log.Println("giving up sending email", message.ID.String(), reason)
+			now := s.timeGenerator.Now()
+			message.FailedAt, message.LastError = &now, reason
+			if err = s.outboxRepo.Update(&message); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		err = s.sender.Send(mailer.Message{ID: message.ID.String(), To: message.To, Subject: message.Subject, Body: message.Body})
+		now := s.timeGenerator.Now()
+		message.Attempts++
+		switch {
+		case err == nil:
+			message.SentAt, message.LastError = &now, ""
+			sent++
+		case message.Attempts >= s.config.MaxAttempts:
+			log.Println("giving up sending email", message.ID.String(), "after", message.Attempts, "attempts", err)
+			message.FailedAt, message.LastError = &now, err.Error()
+		default:
+			log.Println("error while sending email", message.ID.String(), err)
+			message.NextAttemptAt, message.LastError = now.Add(s.backoff(message.Attempts)), err.Error()
+		}
+		if err = s.outboxRepo.Update(&message); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// obsolete returns why message must no longer be sent, an empty reason when it must
+// a message is obsolete once its invitation or the survey it invites to was deleted
+// and a reminder once its invitation is no longer pending
+func (s *MailService) obsolete(message models.OutboxMessage) (string, error) {
+	invitation, err := s.invitationService.GetInvitation(message.Workspace(), message.InvitationID)
+	if err == repositories.ErrNotFound {
+		return "invitation no longer exists", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err = s.surveyRepo.Get(message.Workspace(), invitation.SurveyID); err == repositories.ErrNotFound {
+		return "survey was deleted", nil
+	} else if err != nil {
+		return "", err
+	}
+	if message.Kind == models.MailReminder && invitation.CompletedAt != nil {
+		return "invitation was already answered", nil
+	}
+	return "", nil
+}
+
+// backoff returns the time waited after the failed attempt, doubling from Backoff up to MaxBackoff
+func (s *MailService) backoff(attempt int) time.Duration {
+	backoff := s.config.Backoff
+	for i := 1; i < attempt && backoff < s.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > s.config.MaxBackoff {
+		return s.config.MaxBackoff
+	}
+	return backoff
+}
+
+// Run queues the reminders and delivers the due messages right away and then every interval until ctx is done
+// errors are logged and the work is retried on the next tick
+func (s *MailService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.Remind(); err != nil {
+			log.Println("error while queueing reminders", err)
+		}
+		if _, err := s.Deliver(); err != nil {
+			log.Println("error while delivering emails", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Entries returns the messages of every workspace
+func (s *MailService) Entries() map[ksuid.KSUID]models.OutboxMessage {
+	return s.outboxRepo.Entries()
+}
@@ -0,0 +1,389 @@
+package mailservice
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/outboxrepo"
+	"survey-platform/internal/repositories/surveyrepo"
+	"survey-platform/internal/services/services_mock"
+	"survey-platform/pkg/idgenerator/ksuidgenerator"
+	"survey-platform/pkg/mailer"
+	"survey-platform/pkg/mailer/mailer_mock"
+	"survey-platform/pkg/timegenerator/timegenerator_mock"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fixture is a mail service over in memory repos holding a published survey, its clock is moved with advance
+// the invitation service returns the invitations made by invitation, or invitationErr when it is set
+type fixture struct {
+	service           *MailService
+	outboxRepo        *outboxrepo.OutboxRepo
+	surveyRepo        *surveyrepo.SurveyRepo
+	invitationService *services_mock.MockInvitationServiceInterface
+	sender            *mailer_mock.MockSender
+	survey            models.Survey
+	mu                sync.Mutex
+	now               time.Time
+	invitations       map[ksuid.KSUID]models.Invitation
+	invitationErr     error
+}
+
+func newFixture(t *testing.T) *fixture {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+	f := &fixture{
+		outboxRepo:        outboxrepo.NewOutboxRepo(nil, nil),
+		surveyRepo:        surveyrepo.NewSurveyRepo(nil, nil, nil),
+		invitationService: services_mock.NewMockInvitationServiceInterface(ctrl),
+		sender:            mailer_mock.NewMockSender(ctrl),
+		survey:            models.Survey{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, Name: "Onboarding", Status: models.SurveyPublished},
+		now:               time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC),
+		invitations:       make(map[ksuid.KSUID]models.Invitation),
+	}
+	f.invitationService.EXPECT().GetInvitation(gomock.Any(), gomock.Any()).DoAndReturn(f.getInvitation).AnyTimes()
+	_, err := f.surveyRepo.Create(&f.survey)
+	require.NoError(t, err)
+	timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+	timeGeneratorMock.EXPECT().Now().DoAndReturn(func() time.Time {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return f.now
+	}).AnyTimes()
+	config := DefaultConfig()
+	config.Link = "https://example.com/answer/"
+	config.ReminderAfter, config.MaxReminders = 24*time.Hour, 2
+	config.MaxAttempts, config.Backoff, config.MaxBackoff = 3, time.Minute, time.Hour
+	f.service = NewMailService(f.outboxRepo, f.surveyRepo, f.invitationService, f.sender, ksuidgenerator.NewKSUIDGenerator(),
+		timeGeneratorMock, config)
+	return f
+}
+
+func (f *fixture) advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func (f *fixture) invitation(email string) models.InvitationDetails {
+	invitation := models.InvitationDetails{
+		Invitation: models.Invitation{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, SurveyID: f.survey.ID, RespondentID: ksuid.New()},
+		Respondent: models.Respondent{Email: email, Name: "Alice"},
+		Token:      "token-of-" + email,
+	}
+	f.setInvitation(invitation.Invitation)
+	return invitation
+}
+
+// setInvitation stores invitation in the invitation service, replacing the stored one
+func (f *fixture) setInvitation(invitation models.Invitation) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.invitations[invitation.ID] = invitation
+}
+
+// removeInvitation removes the invitation id from the invitation service
+func (f *fixture) removeInvitation(id ksuid.KSUID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.invitations, id)
+}
+
+// failInvitations makes the invitation service fail with err, a nil err makes it return the invitations again
+func (f *fixture) failInvitations(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.invitationErr = err
+}
+
+func (f *fixture) getInvitation(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Invitation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.invitationErr != nil {
+		return nil, f.invitationErr
+	}
+	invitation, ok := f.invitations[id]
+	if !ok || invitation.Workspace() != workspace {
+		return nil, repositories.ErrNotFound
+	}
+	return &invitation, nil
+}
+
+func (f *fixture) messages(t *testing.T, invitation models.InvitationDetails) []models.OutboxMessage {
+	messages, err := f.outboxRepo.GetByInvitationID(models.DefaultWorkspace, invitation.ID)
+	require.NoError(t, err)
+	return messages
+}
+
+// deliver sends the due messages, expecting a send to each of the addresses
+func (f *fixture) deliver(t *testing.T, to ...string) {
+	for _, address := range to {
+		f.sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(message mailer.Message) error {
+			assert.Equal(t, address, message.To)
+			return nil
+		})
+	}
+	sent, err := f.service.Deliver()
+	require.NoError(t, err)
+	assert.Equal(t, len(to), sent)
+}
+
+// deliverSent delivers the due messages with the sends already expected and checks how many were sent
+func (f *fixture) deliverSent(t *testing.T, expected int) {
+	sent, err := f.service.Deliver()
+	require.NoError(t, err)
+	assert.Equal(t, expected, sent)
+}
+
+// remind queues the reminders which are due and checks how many were queued
+func (f *fixture) remind(t *testing.T, expected int) {
+	reminded, err := f.service.Remind()
+	require.NoError(t, err)
+	assert.Equal(t, expected, reminded)
+}
+
+func TestMailService_QueueInvitations(t *testing.T) {
+	t.Run("should queue the rendered invitation once for every invitation which was not completed", func(t *testing.T) {
+		f := newFixture(t)
+		alice, bob := f.invitation("alice@example.com"), f.invitation("bob@example.com")
+		bob.CompletedAt = &f.now
+		require.NoError(t, f.service.QueueInvitations(models.DefaultWorkspace, []models.InvitationDetails{alice, bob}))
+		require.NoError(t, f.service.QueueInvitations(models.DefaultWorkspace, []models.InvitationDetails{alice}))
+		messages := f.messages(t, alice)
+		require.Len(t, messages, 1)
+		assert.Equal(t, models.OutboxMessage{
+			ID:            messages[0].ID,
+			WorkspaceID:   models.DefaultWorkspace,
+			InvitationID:  alice.ID,
+			Kind:          models.MailInvitation,
+			To:            "alice@example.com",
+			Subject:       "You are invited to answer Onboarding",
+			Body:          messages[0].Body,
+			CreatedAt:     f.now,
+			NextAttemptAt: f.now,
+		}, messages[0])
+		assert.Contains(t, messages[0].Body, "Hello Alice,")
+		assert.Contains(t, messages[0].Body, "https://example.com/answer/token-of-alice@example.com")
+		assert.Empty(t, f.messages(t, bob))
+	})
+	t.Run("should return error when the survey is not found", func(t *testing.T) {
+		f := newFixture(t)
+		invitation := f.invitation("alice@example.com")
+		invitation.SurveyID = ksuid.New()
+		err := f.service.QueueInvitations(models.DefaultWorkspace, []models.InvitationDetails{invitation})
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Empty(t, f.outboxRepo.Entries())
+	})
+}
+
+func TestMailService_Deliver(t *testing.T) {
+	t.Run("should send the due messages once", func(t *testing.T) {
+		f := newFixture(t)
+		alice := f.invitation("alice@example.com")
+		require.NoError(t, f.service.QueueInvitations(models.DefaultWorkspace, []models.InvitationDetails{alice}))
+		queued := f.messages(t, alice)[0]
+		f.sender.EXPECT().Send(mailer.Message{ID: queued.ID.String(), To: queued.To, Subject: queued.Subject, Body: queued.Body}).Return(nil)
+		f.deliverSent(t, 1)
+		f.deliver(t)
+		sent := f.messages(t, alice)[0]
+		assert.Equal(t, 1, sent.Attempts)
+		assert.Equal(t, f.now, *sent.SentAt)
+	})
+	t.Run("should retry failed sends with a doubling backoff and give up after the last attempt", func(t *testing.T) {
+		f := newFixture(t)
+		alice := f.invitation("alice@example.com")
+		require.NoError(t, f.service.QueueInvitations(models.DefaultWorkspace, []models.InvitationDetails{alice}))
+		f.sender.EXPECT().Send(gomock.Any()).Return(errors.New("connection refused")).Times(3)
+		f.deliverSent(t, 0)
+		failed := f.messages(t, alice)[0]
+		assert.Equal(t, 1, failed.Attempts)
+		assert.Equal(t, f.now.Add(time.Minute), failed.NextAttemptAt)
+		assert.Equal(t, "connection refused", failed.LastError)
+		f.advance(59 * time.Second)
+		f.deliverSent(t, 0)
+		f.advance(time.Second)
+		f.deliverSent(t, 0)
+		assert.Equal(t, f.now.Add(2*time.Minute), f.messages(t, alice)[0].NextAttemptAt)
+		f.advance(2 * time.Minute)
+		f.deliverSent(t, 0)
+		givenUp := f.messages(t, alice)[0]
+		assert.Equal(t, 3, givenUp.Attempts)
+		assert.Equal(t, f.now, *givenUp.FailedAt)
+		assert.Nil(t, givenUp.SentAt)
+		f.advance(time.Hour)
+		f.deliverSent(t, 0)
+	})
+	t.Run("should give up the messages of invitations to a deleted survey without sending them", func(t *testing.T) {
+		f := newFixture(t)
+		alice := f.invitation("alice@example.com")
+		require.NoError(t, f.service.QueueInvitations(models.DefaultWorkspace, []models.InvitationDetails{alice}))
+		require.NoError(t, f.surveyRepo.Delete(models.DefaultWorkspace, f.survey.ID, false))
+		f.deliverSent(t, 0)
+		givenUp := f.messages(t, alice)[0]
+		assert.Equal(t, f.now, *givenUp.FailedAt)
+		assert.Equal(t, "survey was deleted", givenUp.LastError)
+		assert.Zero(t, givenUp.Attempts)
+		f.deliverSent(t, 0)
+	})
+	t.Run("should send the messages the outbox held when the app restarted", func(t *testing.T) {
+		f := newFixture(t)
+		alice := f.invitation("alice@example.com")
+		require.NoError(t, f.service.QueueInvitations(models.DefaultWorkspace, []models.InvitationDetails{alice}))
+		restarted := NewMailService(outboxrepo.NewOutboxRepo(f.outboxRepo.Entries(), nil), f.surveyRepo, f.invitationService,
+			f.sender, ksuidgenerator.NewKSUIDGenerator(), f.service.timeGenerator, f.service.config)
+		f.sender.EXPECT().Send(gomock.Any()).Return(nil)
+		sent, err := restarted.Deliver()
+		require.NoError(t, err)
+		assert.Equal(t, 1, sent)
+	})
+}
+
+func TestMailService_Remind(t *testing.T) {
+	t.Run("should remind the respondents who did not answer once the delay passed, up to the maximum", func(t *testing.T) {
+		f := newFixture(t)
+		alice := f.invitation("alice@example.com")
+		f.invitationService.EXPECT().GetPending().Return([]models.InvitationDetails{alice}, nil).AnyTimes()
+		require.NoError(t, f.service.QueueInvitations(models.DefaultWorkspace, []models.InvitationDetails{alice}))
+		f.advance(48 * time.Hour)
+		f.remind(t, 0)
+		f.deliver(t, "alice@example.com")
+		f.advance(24*time.Hour - time.Second)
+		f.remind(t, 0)
+		f.advance(time.Second)
+		f.remind(t, 1)
+		f.remind(t, 0)
+		f.deliver(t, "alice@example.com")
+		f.advance(24 * time.Hour)
+		f.remind(t, 1)
+		f.deliver(t, "alice@example.com")
+		f.advance(24 * time.Hour)
+		f.remind(t, 0)
+		messages := f.messages(t, alice)
+		require.Len(t, messages, 3)
+		assert.Equal(t, models.MailReminder, messages[1].Kind)
+		assert.Equal(t, "Reminder: Onboarding is waiting for your answers", messages[1].Subject)
+		assert.Contains(t, messages[1].Body, "https://example.com/answer/token-of-alice@example.com")
+	})
+	t.Run("should not remind of invitations which were never emailed or to surveys not accepting responses", func(t *testing.T) {
+		f := newFixture(t)
+		emailed, notEmailed, deleted := f.invitation("alice@example.com"), f.invitation("bob@example.com"), f.invitation("carol@example.com")
+		deleted.SurveyID = ksuid.New()
+		f.invitationService.EXPECT().GetPending().Return([]models.InvitationDetails{emailed, notEmailed, deleted}, nil).AnyTimes()
+		require.NoError(t, f.service.QueueInvitations(models.DefaultWorkspace, []models.InvitationDetails{emailed}))
+		f.deliver(t, "alice@example.com")
+		f.survey.Status = models.SurveyClosed
+		_, err := f.surveyRepo.Update(models.DefaultWorkspace, f.survey.ID, &f.survey)
+		require.NoError(t, err)
+		f.advance(48 * time.Hour)
+		f.remind(t, 0)
+		f.survey.Status, f.survey.ClosesAt = models.SurveyPublished, &f.now
+		_, err = f.surveyRepo.Update(models.DefaultWorkspace, f.survey.ID, &f.survey)
+		require.NoError(t, err)
+		f.remind(t, 0)
+		closesAt := f.now.Add(time.Hour)
+		f.survey.ClosesAt = &closesAt
+		_, err = f.surveyRepo.Update(models.DefaultWorkspace, f.survey.ID, &f.survey)
+		require.NoError(t, err)
+		f.remind(t, 1)
+		assert.Contains(t, f.messages(t, emailed)[1].Body, "it closes on July 3, 2021 11:00 UTC")
+	})
+	t.Run("should give up reminders of invitations answered or removed since they were queued", func(t *testing.T) {
+		f := newFixture(t)
+		alice, bob := f.invitation("alice@example.com"), f.invitation("bob@example.com")
+		f.invitationService.EXPECT().GetPending().Return([]models.InvitationDetails{alice, bob}, nil)
+		require.NoError(t, f.service.QueueInvitations(models.DefaultWorkspace, []models.InvitationDetails{alice, bob}))
+		f.sender.EXPECT().Send(gomock.Any()).Return(nil).Times(2)
+		f.deliverSent(t, 2)
+		f.advance(24 * time.Hour)
+		f.remind(t, 2)
+		completed := alice.Invitation
+		completedAt := f.now
+		completed.CompletedAt = &completedAt
+		f.setInvitation(completed)
+		f.removeInvitation(bob.ID)
+		f.deliverSent(t, 0)
+		for _, invitation := range []models.InvitationDetails{alice, bob} {
+			reminder := f.messages(t, invitation)[1]
+			assert.Equal(t, models.MailReminder, reminder.Kind)
+			assert.Equal(t, f.now, *reminder.FailedAt)
+			assert.Zero(t, reminder.Attempts)
+			assert.Nil(t, reminder.SentAt)
+		}
+		assert.Equal(t, "invitation was already answered", f.messages(t, alice)[1].LastError)
+		f.deliverSent(t, 0)
+	})
+	t.Run("should keep a reminder queued when its invitation cannot be checked", func(t *testing.T) {
+		f := newFixture(t)
+		alice := f.invitation("alice@example.com")
+		f.invitationService.EXPECT().GetPending().Return([]models.InvitationDetails{alice}, nil)
+		require.NoError(t, f.service.QueueInvitations(models.DefaultWorkspace, []models.InvitationDetails{alice}))
+		f.deliver(t, "alice@example.com")
+		f.advance(24 * time.Hour)
+		f.remind(t, 1)
+		f.failInvitations(errors.New("disk failure"))
+		f.deliverSent(t, 0)
+		assert.True(t, f.messages(t, alice)[1].Pending())
+		f.failInvitations(nil)
+		f.deliver(t, "alice@example.com")
+	})
+	t.Run("should not remind anyone when reminders are disabled", func(t *testing.T) {
+		f := newFixture(t)
+		f.service.config.MaxReminders = 0
+		f.remind(t, 0)
+	})
+}
+
+func TestMailService_Disabled(t *testing.T) {
+	t.Run("should neither queue nor send emails without a sender", func(t *testing.T) {
+		f := newFixture(t)
+		message := models.OutboxMessage{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, InvitationID: ksuid.New(), To: "bob@example.com"}
+		_, err := f.outboxRepo.Create(&message)
+		require.NoError(t, err)
+		f.service.sender = nil
+		require.NoError(t, f.service.QueueInvitations(models.DefaultWorkspace, []models.InvitationDetails{f.invitation("alice@example.com")}))
+		f.remind(t, 0)
+		f.deliverSent(t, 0)
+		assert.Equal(t, map[ksuid.KSUID]models.OutboxMessage{message.ID: message}, f.service.Entries())
+	})
+}
+
+func TestMailService_Run(t *testing.T) {
+	t.Run("should deliver right away and on every interval until context is done", func(t *testing.T) {
+		f := newFixture(t)
+		f.service.config.MaxReminders = 0
+		alice := f.invitation("alice@example.com")
+		require.NoError(t, f.service.QueueInvitations(models.DefaultWorkspace, []models.InvitationDetails{alice}))
+		sent := make(chan mailer.Message, 1)
+		f.sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(message mailer.Message) error {
+			sent <- message
+			return nil
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			f.service.Run(ctx, time.Millisecond)
+			close(done)
+		}()
+		assert.Equal(t, "alice@example.com", (<-sent).To)
+		cancel()
+		<-done
+	})
+}
+
+func TestMailService_backoff(t *testing.T) {
+	t.Run("should double the backoff up to the maximum", func(t *testing.T) {
+		f := newFixture(t)
+		assert.Equal(t, time.Minute, f.service.backoff(1))
+		assert.Equal(t, 2*time.Minute, f.service.backoff(2))
+		assert.Equal(t, 32*time.Minute, f.service.backoff(6))
+		assert.Equal(t, time.Hour, f.service.backoff(7))
+		assert.Equal(t, time.Hour, f.service.backoff(100))
+	})
+}
@@ -4,16 +4,115 @@ package services
 
 import (
 	"github.com/segmentio/ksuid"
+	"io"
 	"survey-platform/internal/models"
+	"survey-platform/pkg/export"
 )
 
+// SurveyServiceInterface manages the surveys and responses of workspaces, every call is scoped to the workspace passed in
+// and surveys of other workspaces are reported as missing
+// the scopes and roles of an api key are enforced by wrapping the service in surveyservice.AuthorizedService for the key
 type SurveyServiceInterface interface {
-	CreateSurvey(survey *models.Survey) (*models.Survey, error)
-	GetSurvey(id ksuid.KSUID) (*models.Survey, error)
-	UpdateSurvey(id ksuid.KSUID, survey models.Survey) (*models.Survey, error)
-	DeleteSurvey(id ksuid.KSUID) error
-	GetAllSurveys() ([]models.Survey, error)
-	SaveResponse(response models.Response) (*models.Response, error)
-	GetResponses(surveyID ksuid.KSUID) ([]models.Response, error)
+	CreateSurvey(workspace models.WorkspaceID, survey *models.Survey) (*models.Survey, error)
+	GetSurvey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error)
+	GetSurveyDetails(workspace models.WorkspaceID, id ksuid.KSUID) (*models.SurveyDetails, error)
+	GetSurveyVersions(workspace models.WorkspaceID, id ksuid.KSUID) ([]models.Survey, error)
+	UpdateSurvey(workspace models.WorkspaceID, id ksuid.KSUID, survey models.Survey) (*models.Survey, error)
+	// DeleteSurvey returns repositories.ErrSurveyHasResponses for a survey with responses unless cascade is set,
+	// in which case its responses are deleted along with it
+	DeleteSurvey(workspace models.WorkspaceID, id ksuid.KSUID, cascade bool) error
+	GetAllSurveys(workspace models.WorkspaceID, query models.SurveyQuery) (*models.SurveyPage, error)
+	SearchSurveys(workspace models.WorkspaceID, query string, limit int) ([]models.SearchResult, error)
+	PublishSurvey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error)
+	CloseSurvey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error)
+	ArchiveSurvey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error)
+	SaveResponse(workspace models.WorkspaceID, response models.Response) (*models.Response, error)
+	GetResponses(workspace models.WorkspaceID, query models.ResponseQuery, scope models.VersionScope) (*models.ResponsePage, error)
+	// GetResponse, UpdateResponse and DeleteResponse work on the response saved with the invitation invitationID,
+	// ErrNotFound is returned for the responses of other invitations and ErrEditWindowClosed once they can no longer be changed
+	GetResponse(workspace models.WorkspaceID, invitationID, id ksuid.KSUID) (*models.ResponseDetails, error)
+	UpdateResponse(workspace models.WorkspaceID, invitationID, id ksuid.KSUID, answers []models.Answer) (*models.ResponseDetails, error)
+	DeleteResponse(workspace models.WorkspaceID, invitationID, id ksuid.KSUID) error
+	GetResults(workspace models.WorkspaceID, surveyID ksuid.KSUID, filter models.ResultsFilter) (*models.Results, error)
+	GetCrossTab(workspace models.WorkspaceID, surveyID, rowID, columnID ksuid.KSUID, scope models.VersionScope) (*models.CrossTab, error)
+	// StartDraft starts a draft of a response to the survey of draft, which is returned along with its resume token
+	StartDraft(workspace models.WorkspaceID, draft models.Draft) (*models.DraftSession, error)
+	// GetDraft, UpdateDraft and SubmitDraft resume the draft of a resume token, ErrInvalidResumeToken is returned
+	// for tokens which do not match a draft and ErrDraftExpired for drafts which were left alone past their expiry
+	GetDraft(workspace models.WorkspaceID, token string) (*models.Draft, error)
+	UpdateDraft(workspace models.WorkspaceID, token string, answers []models.Answer) (*models.Draft, error)
+	SubmitDraft(workspace models.WorkspaceID, token string) (*models.Response, error)
+	// GetFunnel counts the drafts of a survey which were started, are in progress, expired and were submitted
+	GetFunnel(workspace models.WorkspaceID, surveyID ksuid.KSUID) (*models.Funnel, error)
+	ExportResponses(workspace models.WorkspaceID, surveyID ksuid.KSUID, newWriter func() (export.Writer, error)) error
+	Import(workspace models.WorkspaceID, r io.Reader, options models.ImportOptions) (*models.ImportReport, error)
+	// FindOrphans returns the response sets of every workspace whose survey is no longer stored
+	FindOrphans() ([]models.ResponseSet, error)
+	// Entries returns the data of every workspace, it is meant for persisting the app
 	Entries() *models.DBEntry
 }
+
+// APIKeyServiceInterface manages the api keys of workspaces and authenticates requests with them
+type APIKeyServiceInterface interface {
+	// CreateKey creates a key of workspace, a key bound to the user userID is limited to what the roles of the user grant
+	CreateKey(workspace models.WorkspaceID, name string, scopes []models.Scope, userID *ksuid.KSUID) (*models.CreatedAPIKey, error)
+	GetKeys(workspace models.WorkspaceID) ([]models.APIKey, error)
+	RevokeKey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.APIKey, error)
+	// Authenticate returns the active key matching key, ErrInvalidAPIKey is returned for unknown and revoked keys
+	// the workspace of the returned key is the one every request made with it is scoped to
+	Authenticate(key string) (*models.APIKey, error)
+	Entries() map[ksuid.KSUID]models.APIKey
+}
+
+// UserServiceInterface manages the users of workspaces along with their roles
+// and decides what the api keys bound to users may do
+type UserServiceInterface interface {
+	CreateUser(workspace models.WorkspaceID, user models.User) (*models.User, error)
+	GetUsers(workspace models.WorkspaceID) ([]models.User, error)
+	GetUser(workspace models.WorkspaceID, id ksuid.KSUID) (*models.User, error)
+	// SetRoles replaces every role of the user
+	SetRoles(workspace models.WorkspaceID, id ksuid.KSUID, roles []models.RoleAssignment) (*models.User, error)
+	DeleteUser(workspace models.WorkspaceID, id ksuid.KSUID) error
+	// Authorize returns ErrForbidden along with the reason when key may not use scopes on the survey,
+	// or on the whole workspace when surveyID is nil, keys which are not bound to a user are only limited by their scopes
+	Authorize(key *models.APIKey, surveyID *ksuid.KSUID, scopes ...models.Scope) error
+	Entries() map[ksuid.KSUID]models.User
+}
+
+// InvitationServiceInterface invites respondents to the surveys of workspaces and checks the signed tokens
+// the respondents answer with, a token is single use as the invitation completes along with the response saved with it
+type InvitationServiceInterface interface {
+	// Invite invites the respondents to the survey, respondents are found by email or created
+	// respondents who are already invited keep their invitation, which is returned again
+	Invite(workspace models.WorkspaceID, surveyID ksuid.KSUID, respondents []models.Respondent) ([]models.InvitationDetails, error)
+	// GetInvitations returns every invitation to the survey ordered by creation time
+	GetInvitations(workspace models.WorkspaceID, surveyID ksuid.KSUID) ([]models.InvitationDetails, error)
+	// Summary counts the invitations to the survey which were sent, started and completed
+	Summary(workspace models.WorkspaceID, surveyID ksuid.KSUID) (*models.InvitationSummary, error)
+	// Open marks the invitation of token as started and returns it
+	Open(workspace models.WorkspaceID, token string) (*models.InvitationDetails, error)
+	// Verify returns the invitation of token when it invites to the survey and was not answered yet,
+	// ErrInvalidInvitation is returned for tokens which do not and ErrInvitationCompleted for answered invitations
+	Verify(workspace models.WorkspaceID, surveyID ksuid.KSUID, token string) (*models.Invitation, error)
+	// Authenticate returns the invitation of token, answered or not, so that respondents can get back to their response
+	// ErrInvalidInvitation is returned for tokens which do not match an invitation of the workspace
+	Authenticate(workspace models.WorkspaceID, token string) (*models.Invitation, error)
+	// GetInvitation returns the invitation id of workspace, answered or not
+	GetInvitation(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Invitation, error)
+	// GetPending returns the invitations of every workspace which were not completed, ordered by creation time
+	GetPending() ([]models.InvitationDetails, error)
+	Entries() (map[ksuid.KSUID]models.Respondent, map[ksuid.KSUID]models.Invitation)
+}
+
+// MailServiceInterface emails invitations and reminders to respondents through an outbox
+// emails are queued in the outbox and sent by Deliver, so that they survive restarts and failed sends are retried
+type MailServiceInterface interface {
+	// QueueInvitations queues the invitation email of every invitation which was not emailed yet
+	QueueInvitations(workspace models.WorkspaceID, invitations []models.InvitationDetails) error
+	// Remind queues a reminder to the respondents of pending invitations which were not emailed for a while
+	// and returns the number of reminders queued
+	Remind() (int, error)
+	// Deliver sends the queued emails which are due and returns the number of emails sent
+	Deliver() (int, error)
+	Entries() map[ksuid.KSUID]models.OutboxMessage
+}
@@ -5,8 +5,10 @@
 package services_mock
 
 import (
+	io "io"
 	reflect "reflect"
 	models "survey-platform/internal/models"
+	export "survey-platform/pkg/export"
 
 	gomock "github.com/golang/mock/gomock"
 	ksuid "github.com/segmentio/ksuid"
@@ -35,33 +37,77 @@ func (m *MockSurveyServiceInterface) EXPECT() *MockSurveyServiceInterfaceMockRec
 	return m.recorder
 }
 
+// ArchiveSurvey mocks base method.
+func (m *MockSurveyServiceInterface) ArchiveSurvey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveSurvey", workspace, id)
+	ret0, _ := ret[0].(*models.Survey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ArchiveSurvey indicates an expected call of ArchiveSurvey.
+func (mr *MockSurveyServiceInterfaceMockRecorder) ArchiveSurvey(workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveSurvey", reflect.TypeOf((*MockSurveyServiceInterface)(nil).ArchiveSurvey), workspace, id)
+}
+
+// CloseSurvey mocks base method.
+func (m *MockSurveyServiceInterface) CloseSurvey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseSurvey", workspace, id)
+	ret0, _ := ret[0].(*models.Survey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CloseSurvey indicates an expected call of CloseSurvey.
+func (mr *MockSurveyServiceInterfaceMockRecorder) CloseSurvey(workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseSurvey", reflect.TypeOf((*MockSurveyServiceInterface)(nil).CloseSurvey), workspace, id)
+}
+
 // CreateSurvey mocks base method.
-func (m *MockSurveyServiceInterface) CreateSurvey(survey *models.Survey) (*models.Survey, error) {
+func (m *MockSurveyServiceInterface) CreateSurvey(workspace models.WorkspaceID, survey *models.Survey) (*models.Survey, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateSurvey", survey)
+	ret := m.ctrl.Call(m, "CreateSurvey", workspace, survey)
 	ret0, _ := ret[0].(*models.Survey)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateSurvey indicates an expected call of CreateSurvey.
-func (mr *MockSurveyServiceInterfaceMockRecorder) CreateSurvey(survey interface{}) *gomock.Call {
+func (mr *MockSurveyServiceInterfaceMockRecorder) CreateSurvey(workspace, survey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSurvey", reflect.TypeOf((*MockSurveyServiceInterface)(nil).CreateSurvey), workspace, survey)
+}
+
+// DeleteResponse mocks base method.
+func (m *MockSurveyServiceInterface) DeleteResponse(workspace models.WorkspaceID, invitationID, id ksuid.KSUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteResponse", workspace, invitationID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteResponse indicates an expected call of DeleteResponse.
+func (mr *MockSurveyServiceInterfaceMockRecorder) DeleteResponse(workspace, invitationID, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSurvey", reflect.TypeOf((*MockSurveyServiceInterface)(nil).CreateSurvey), survey)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteResponse", reflect.TypeOf((*MockSurveyServiceInterface)(nil).DeleteResponse), workspace, invitationID, id)
 }
 
 // DeleteSurvey mocks base method.
-func (m *MockSurveyServiceInterface) DeleteSurvey(id ksuid.KSUID) error {
+func (m *MockSurveyServiceInterface) DeleteSurvey(workspace models.WorkspaceID, id ksuid.KSUID, cascade bool) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteSurvey", id)
+	ret := m.ctrl.Call(m, "DeleteSurvey", workspace, id, cascade)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteSurvey indicates an expected call of DeleteSurvey.
-func (mr *MockSurveyServiceInterfaceMockRecorder) DeleteSurvey(id interface{}) *gomock.Call {
+func (mr *MockSurveyServiceInterfaceMockRecorder) DeleteSurvey(workspace, id, cascade interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSurvey", reflect.TypeOf((*MockSurveyServiceInterface)(nil).DeleteSurvey), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSurvey", reflect.TypeOf((*MockSurveyServiceInterface)(nil).DeleteSurvey), workspace, id, cascade)
 }
 
 // Entries mocks base method.
@@ -78,77 +124,782 @@ func (mr *MockSurveyServiceInterfaceMockRecorder) Entries() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Entries", reflect.TypeOf((*MockSurveyServiceInterface)(nil).Entries))
 }
 
+// ExportResponses mocks base method.
+func (m *MockSurveyServiceInterface) ExportResponses(workspace models.WorkspaceID, surveyID ksuid.KSUID, newWriter func() (export.Writer, error)) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportResponses", workspace, surveyID, newWriter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportResponses indicates an expected call of ExportResponses.
+func (mr *MockSurveyServiceInterfaceMockRecorder) ExportResponses(workspace, surveyID, newWriter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportResponses", reflect.TypeOf((*MockSurveyServiceInterface)(nil).ExportResponses), workspace, surveyID, newWriter)
+}
+
+// FindOrphans mocks base method.
+func (m *MockSurveyServiceInterface) FindOrphans() ([]models.ResponseSet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOrphans")
+	ret0, _ := ret[0].([]models.ResponseSet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOrphans indicates an expected call of FindOrphans.
+func (mr *MockSurveyServiceInterfaceMockRecorder) FindOrphans() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrphans", reflect.TypeOf((*MockSurveyServiceInterface)(nil).FindOrphans))
+}
+
 // GetAllSurveys mocks base method.
-func (m *MockSurveyServiceInterface) GetAllSurveys() ([]models.Survey, error) {
+func (m *MockSurveyServiceInterface) GetAllSurveys(workspace models.WorkspaceID, query models.SurveyQuery) (*models.SurveyPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllSurveys")
-	ret0, _ := ret[0].([]models.Survey)
+	ret := m.ctrl.Call(m, "GetAllSurveys", workspace, query)
+	ret0, _ := ret[0].(*models.SurveyPage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAllSurveys indicates an expected call of GetAllSurveys.
-func (mr *MockSurveyServiceInterfaceMockRecorder) GetAllSurveys() *gomock.Call {
+func (mr *MockSurveyServiceInterfaceMockRecorder) GetAllSurveys(workspace, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllSurveys", reflect.TypeOf((*MockSurveyServiceInterface)(nil).GetAllSurveys), workspace, query)
+}
+
+// GetCrossTab mocks base method.
+func (m *MockSurveyServiceInterface) GetCrossTab(workspace models.WorkspaceID, surveyID, rowID, columnID ksuid.KSUID, scope models.VersionScope) (*models.CrossTab, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCrossTab", workspace, surveyID, rowID, columnID, scope)
+	ret0, _ := ret[0].(*models.CrossTab)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCrossTab indicates an expected call of GetCrossTab.
+func (mr *MockSurveyServiceInterfaceMockRecorder) GetCrossTab(workspace, surveyID, rowID, columnID, scope interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCrossTab", reflect.TypeOf((*MockSurveyServiceInterface)(nil).GetCrossTab), workspace, surveyID, rowID, columnID, scope)
+}
+
+// GetDraft mocks base method.
+func (m *MockSurveyServiceInterface) GetDraft(workspace models.WorkspaceID, token string) (*models.Draft, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDraft", workspace, token)
+	ret0, _ := ret[0].(*models.Draft)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDraft indicates an expected call of GetDraft.
+func (mr *MockSurveyServiceInterfaceMockRecorder) GetDraft(workspace, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDraft", reflect.TypeOf((*MockSurveyServiceInterface)(nil).GetDraft), workspace, token)
+}
+
+// GetFunnel mocks base method.
+func (m *MockSurveyServiceInterface) GetFunnel(workspace models.WorkspaceID, surveyID ksuid.KSUID) (*models.Funnel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFunnel", workspace, surveyID)
+	ret0, _ := ret[0].(*models.Funnel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFunnel indicates an expected call of GetFunnel.
+func (mr *MockSurveyServiceInterfaceMockRecorder) GetFunnel(workspace, surveyID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllSurveys", reflect.TypeOf((*MockSurveyServiceInterface)(nil).GetAllSurveys))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFunnel", reflect.TypeOf((*MockSurveyServiceInterface)(nil).GetFunnel), workspace, surveyID)
+}
+
+// GetResponse mocks base method.
+func (m *MockSurveyServiceInterface) GetResponse(workspace models.WorkspaceID, invitationID, id ksuid.KSUID) (*models.ResponseDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetResponse", workspace, invitationID, id)
+	ret0, _ := ret[0].(*models.ResponseDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetResponse indicates an expected call of GetResponse.
+func (mr *MockSurveyServiceInterfaceMockRecorder) GetResponse(workspace, invitationID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResponse", reflect.TypeOf((*MockSurveyServiceInterface)(nil).GetResponse), workspace, invitationID, id)
 }
 
 // GetResponses mocks base method.
-func (m *MockSurveyServiceInterface) GetResponses(surveyID ksuid.KSUID) ([]models.Response, error) {
+func (m *MockSurveyServiceInterface) GetResponses(workspace models.WorkspaceID, query models.ResponseQuery, scope models.VersionScope) (*models.ResponsePage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetResponses", surveyID)
-	ret0, _ := ret[0].([]models.Response)
+	ret := m.ctrl.Call(m, "GetResponses", workspace, query, scope)
+	ret0, _ := ret[0].(*models.ResponsePage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetResponses indicates an expected call of GetResponses.
-func (mr *MockSurveyServiceInterfaceMockRecorder) GetResponses(surveyID interface{}) *gomock.Call {
+func (mr *MockSurveyServiceInterfaceMockRecorder) GetResponses(workspace, query, scope interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResponses", reflect.TypeOf((*MockSurveyServiceInterface)(nil).GetResponses), workspace, query, scope)
+}
+
+// GetResults mocks base method.
+func (m *MockSurveyServiceInterface) GetResults(workspace models.WorkspaceID, surveyID ksuid.KSUID, filter models.ResultsFilter) (*models.Results, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetResults", workspace, surveyID, filter)
+	ret0, _ := ret[0].(*models.Results)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetResults indicates an expected call of GetResults.
+func (mr *MockSurveyServiceInterfaceMockRecorder) GetResults(workspace, surveyID, filter interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResponses", reflect.TypeOf((*MockSurveyServiceInterface)(nil).GetResponses), surveyID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResults", reflect.TypeOf((*MockSurveyServiceInterface)(nil).GetResults), workspace, surveyID, filter)
 }
 
 // GetSurvey mocks base method.
-func (m *MockSurveyServiceInterface) GetSurvey(id ksuid.KSUID) (*models.Survey, error) {
+func (m *MockSurveyServiceInterface) GetSurvey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSurvey", id)
+	ret := m.ctrl.Call(m, "GetSurvey", workspace, id)
 	ret0, _ := ret[0].(*models.Survey)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetSurvey indicates an expected call of GetSurvey.
-func (mr *MockSurveyServiceInterfaceMockRecorder) GetSurvey(id interface{}) *gomock.Call {
+func (mr *MockSurveyServiceInterfaceMockRecorder) GetSurvey(workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSurvey", reflect.TypeOf((*MockSurveyServiceInterface)(nil).GetSurvey), workspace, id)
+}
+
+// GetSurveyDetails mocks base method.
+func (m *MockSurveyServiceInterface) GetSurveyDetails(workspace models.WorkspaceID, id ksuid.KSUID) (*models.SurveyDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSurveyDetails", workspace, id)
+	ret0, _ := ret[0].(*models.SurveyDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSurveyDetails indicates an expected call of GetSurveyDetails.
+func (mr *MockSurveyServiceInterfaceMockRecorder) GetSurveyDetails(workspace, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSurvey", reflect.TypeOf((*MockSurveyServiceInterface)(nil).GetSurvey), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSurveyDetails", reflect.TypeOf((*MockSurveyServiceInterface)(nil).GetSurveyDetails), workspace, id)
+}
+
+// GetSurveyVersions mocks base method.
+func (m *MockSurveyServiceInterface) GetSurveyVersions(workspace models.WorkspaceID, id ksuid.KSUID) ([]models.Survey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSurveyVersions", workspace, id)
+	ret0, _ := ret[0].([]models.Survey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSurveyVersions indicates an expected call of GetSurveyVersions.
+func (mr *MockSurveyServiceInterfaceMockRecorder) GetSurveyVersions(workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSurveyVersions", reflect.TypeOf((*MockSurveyServiceInterface)(nil).GetSurveyVersions), workspace, id)
+}
+
+// Import mocks base method.
+func (m *MockSurveyServiceInterface) Import(workspace models.WorkspaceID, r io.Reader, options models.ImportOptions) (*models.ImportReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Import", workspace, r, options)
+	ret0, _ := ret[0].(*models.ImportReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Import indicates an expected call of Import.
+func (mr *MockSurveyServiceInterfaceMockRecorder) Import(workspace, r, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Import", reflect.TypeOf((*MockSurveyServiceInterface)(nil).Import), workspace, r, options)
+}
+
+// PublishSurvey mocks base method.
+func (m *MockSurveyServiceInterface) PublishSurvey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PublishSurvey", workspace, id)
+	ret0, _ := ret[0].(*models.Survey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PublishSurvey indicates an expected call of PublishSurvey.
+func (mr *MockSurveyServiceInterfaceMockRecorder) PublishSurvey(workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishSurvey", reflect.TypeOf((*MockSurveyServiceInterface)(nil).PublishSurvey), workspace, id)
 }
 
 // SaveResponse mocks base method.
-func (m *MockSurveyServiceInterface) SaveResponse(response models.Response) (*models.Response, error) {
+func (m *MockSurveyServiceInterface) SaveResponse(workspace models.WorkspaceID, response models.Response) (*models.Response, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveResponse", response)
+	ret := m.ctrl.Call(m, "SaveResponse", workspace, response)
 	ret0, _ := ret[0].(*models.Response)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SaveResponse indicates an expected call of SaveResponse.
-func (mr *MockSurveyServiceInterfaceMockRecorder) SaveResponse(response interface{}) *gomock.Call {
+func (mr *MockSurveyServiceInterfaceMockRecorder) SaveResponse(workspace, response interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveResponse", reflect.TypeOf((*MockSurveyServiceInterface)(nil).SaveResponse), workspace, response)
+}
+
+// SearchSurveys mocks base method.
+func (m *MockSurveyServiceInterface) SearchSurveys(workspace models.WorkspaceID, query string, limit int) ([]models.SearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchSurveys", workspace, query, limit)
+	ret0, _ := ret[0].([]models.SearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchSurveys indicates an expected call of SearchSurveys.
+func (mr *MockSurveyServiceInterfaceMockRecorder) SearchSurveys(workspace, query, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchSurveys", reflect.TypeOf((*MockSurveyServiceInterface)(nil).SearchSurveys), workspace, query, limit)
+}
+
+// StartDraft mocks base method.
+func (m *MockSurveyServiceInterface) StartDraft(workspace models.WorkspaceID, draft models.Draft) (*models.DraftSession, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartDraft", workspace, draft)
+	ret0, _ := ret[0].(*models.DraftSession)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartDraft indicates an expected call of StartDraft.
+func (mr *MockSurveyServiceInterfaceMockRecorder) StartDraft(workspace, draft interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartDraft", reflect.TypeOf((*MockSurveyServiceInterface)(nil).StartDraft), workspace, draft)
+}
+
+// SubmitDraft mocks base method.
+func (m *MockSurveyServiceInterface) SubmitDraft(workspace models.WorkspaceID, token string) (*models.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubmitDraft", workspace, token)
+	ret0, _ := ret[0].(*models.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubmitDraft indicates an expected call of SubmitDraft.
+func (mr *MockSurveyServiceInterfaceMockRecorder) SubmitDraft(workspace, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubmitDraft", reflect.TypeOf((*MockSurveyServiceInterface)(nil).SubmitDraft), workspace, token)
+}
+
+// UpdateDraft mocks base method.
+func (m *MockSurveyServiceInterface) UpdateDraft(workspace models.WorkspaceID, token string, answers []models.Answer) (*models.Draft, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDraft", workspace, token, answers)
+	ret0, _ := ret[0].(*models.Draft)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateDraft indicates an expected call of UpdateDraft.
+func (mr *MockSurveyServiceInterfaceMockRecorder) UpdateDraft(workspace, token, answers interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveResponse", reflect.TypeOf((*MockSurveyServiceInterface)(nil).SaveResponse), response)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDraft", reflect.TypeOf((*MockSurveyServiceInterface)(nil).UpdateDraft), workspace, token, answers)
+}
+
+// UpdateResponse mocks base method.
+func (m *MockSurveyServiceInterface) UpdateResponse(workspace models.WorkspaceID, invitationID, id ksuid.KSUID, answers []models.Answer) (*models.ResponseDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateResponse", workspace, invitationID, id, answers)
+	ret0, _ := ret[0].(*models.ResponseDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateResponse indicates an expected call of UpdateResponse.
+func (mr *MockSurveyServiceInterfaceMockRecorder) UpdateResponse(workspace, invitationID, id, answers interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateResponse", reflect.TypeOf((*MockSurveyServiceInterface)(nil).UpdateResponse), workspace, invitationID, id, answers)
 }
 
 // UpdateSurvey mocks base method.
-func (m *MockSurveyServiceInterface) UpdateSurvey(id ksuid.KSUID, survey models.Survey) (*models.Survey, error) {
+func (m *MockSurveyServiceInterface) UpdateSurvey(workspace models.WorkspaceID, id ksuid.KSUID, survey models.Survey) (*models.Survey, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateSurvey", id, survey)
+	ret := m.ctrl.Call(m, "UpdateSurvey", workspace, id, survey)
 	ret0, _ := ret[0].(*models.Survey)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdateSurvey indicates an expected call of UpdateSurvey.
-func (mr *MockSurveyServiceInterfaceMockRecorder) UpdateSurvey(id, survey interface{}) *gomock.Call {
+func (mr *MockSurveyServiceInterfaceMockRecorder) UpdateSurvey(workspace, id, survey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSurvey", reflect.TypeOf((*MockSurveyServiceInterface)(nil).UpdateSurvey), workspace, id, survey)
+}
+
+// MockAPIKeyServiceInterface is a mock of APIKeyServiceInterface interface.
+type MockAPIKeyServiceInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPIKeyServiceInterfaceMockRecorder
+}
+
+// MockAPIKeyServiceInterfaceMockRecorder is the mock recorder for MockAPIKeyServiceInterface.
+type MockAPIKeyServiceInterfaceMockRecorder struct {
+	mock *MockAPIKeyServiceInterface
+}
+
+// NewMockAPIKeyServiceInterface creates a new mock instance.
+func NewMockAPIKeyServiceInterface(ctrl *gomock.Controller) *MockAPIKeyServiceInterface {
+	mock := &MockAPIKeyServiceInterface{ctrl: ctrl}
+	mock.recorder = &MockAPIKeyServiceInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAPIKeyServiceInterface) EXPECT() *MockAPIKeyServiceInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockAPIKeyServiceInterface) Authenticate(key string) (*models.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", key)
+	ret0, _ := ret[0].(*models.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockAPIKeyServiceInterfaceMockRecorder) Authenticate(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockAPIKeyServiceInterface)(nil).Authenticate), key)
+}
+
+// CreateKey mocks base method.
+func (m *MockAPIKeyServiceInterface) CreateKey(workspace models.WorkspaceID, name string, scopes []models.Scope, userID *ksuid.KSUID) (*models.CreatedAPIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateKey", workspace, name, scopes, userID)
+	ret0, _ := ret[0].(*models.CreatedAPIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateKey indicates an expected call of CreateKey.
+func (mr *MockAPIKeyServiceInterfaceMockRecorder) CreateKey(workspace, name, scopes, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateKey", reflect.TypeOf((*MockAPIKeyServiceInterface)(nil).CreateKey), workspace, name, scopes, userID)
+}
+
+// Entries mocks base method.
+func (m *MockAPIKeyServiceInterface) Entries() map[ksuid.KSUID]models.APIKey {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Entries")
+	ret0, _ := ret[0].(map[ksuid.KSUID]models.APIKey)
+	return ret0
+}
+
+// Entries indicates an expected call of Entries.
+func (mr *MockAPIKeyServiceInterfaceMockRecorder) Entries() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Entries", reflect.TypeOf((*MockAPIKeyServiceInterface)(nil).Entries))
+}
+
+// GetKeys mocks base method.
+func (m *MockAPIKeyServiceInterface) GetKeys(workspace models.WorkspaceID) ([]models.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetKeys", workspace)
+	ret0, _ := ret[0].([]models.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetKeys indicates an expected call of GetKeys.
+func (mr *MockAPIKeyServiceInterfaceMockRecorder) GetKeys(workspace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetKeys", reflect.TypeOf((*MockAPIKeyServiceInterface)(nil).GetKeys), workspace)
+}
+
+// RevokeKey mocks base method.
+func (m *MockAPIKeyServiceInterface) RevokeKey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeKey", workspace, id)
+	ret0, _ := ret[0].(*models.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeKey indicates an expected call of RevokeKey.
+func (mr *MockAPIKeyServiceInterfaceMockRecorder) RevokeKey(workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeKey", reflect.TypeOf((*MockAPIKeyServiceInterface)(nil).RevokeKey), workspace, id)
+}
+
+// MockUserServiceInterface is a mock of UserServiceInterface interface.
+type MockUserServiceInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserServiceInterfaceMockRecorder
+}
+
+// MockUserServiceInterfaceMockRecorder is the mock recorder for MockUserServiceInterface.
+type MockUserServiceInterfaceMockRecorder struct {
+	mock *MockUserServiceInterface
+}
+
+// NewMockUserServiceInterface creates a new mock instance.
+func NewMockUserServiceInterface(ctrl *gomock.Controller) *MockUserServiceInterface {
+	mock := &MockUserServiceInterface{ctrl: ctrl}
+	mock.recorder = &MockUserServiceInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserServiceInterface) EXPECT() *MockUserServiceInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Authorize mocks base method.
+func (m *MockUserServiceInterface) Authorize(key *models.APIKey, surveyID *ksuid.KSUID, scopes ...models.Scope) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{key, surveyID}
+	for _, a := range scopes {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Authorize", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Authorize indicates an expected call of Authorize.
+func (mr *MockUserServiceInterfaceMockRecorder) Authorize(key, surveyID interface{}, scopes ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{key, surveyID}, scopes...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authorize", reflect.TypeOf((*MockUserServiceInterface)(nil).Authorize), varargs...)
+}
+
+// CreateUser mocks base method.
+func (m *MockUserServiceInterface) CreateUser(workspace models.WorkspaceID, user models.User) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", workspace, user)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockUserServiceInterfaceMockRecorder) CreateUser(workspace, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserServiceInterface)(nil).CreateUser), workspace, user)
+}
+
+// DeleteUser mocks base method.
+func (m *MockUserServiceInterface) DeleteUser(workspace models.WorkspaceID, id ksuid.KSUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", workspace, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockUserServiceInterfaceMockRecorder) DeleteUser(workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserServiceInterface)(nil).DeleteUser), workspace, id)
+}
+
+// Entries mocks base method.
+func (m *MockUserServiceInterface) Entries() map[ksuid.KSUID]models.User {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Entries")
+	ret0, _ := ret[0].(map[ksuid.KSUID]models.User)
+	return ret0
+}
+
+// Entries indicates an expected call of Entries.
+func (mr *MockUserServiceInterfaceMockRecorder) Entries() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Entries", reflect.TypeOf((*MockUserServiceInterface)(nil).Entries))
+}
+
+// GetUser mocks base method.
+func (m *MockUserServiceInterface) GetUser(workspace models.WorkspaceID, id ksuid.KSUID) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUser", workspace, id)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUser indicates an expected call of GetUser.
+func (mr *MockUserServiceInterfaceMockRecorder) GetUser(workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockUserServiceInterface)(nil).GetUser), workspace, id)
+}
+
+// GetUsers mocks base method.
+func (m *MockUserServiceInterface) GetUsers(workspace models.WorkspaceID) ([]models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsers", workspace)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsers indicates an expected call of GetUsers.
+func (mr *MockUserServiceInterfaceMockRecorder) GetUsers(workspace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*MockUserServiceInterface)(nil).GetUsers), workspace)
+}
+
+// SetRoles mocks base method.
+func (m *MockUserServiceInterface) SetRoles(workspace models.WorkspaceID, id ksuid.KSUID, roles []models.RoleAssignment) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRoles", workspace, id, roles)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetRoles indicates an expected call of SetRoles.
+func (mr *MockUserServiceInterfaceMockRecorder) SetRoles(workspace, id, roles interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRoles", reflect.TypeOf((*MockUserServiceInterface)(nil).SetRoles), workspace, id, roles)
+}
+
+// MockInvitationServiceInterface is a mock of InvitationServiceInterface interface.
+type MockInvitationServiceInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockInvitationServiceInterfaceMockRecorder
+}
+
+// MockInvitationServiceInterfaceMockRecorder is the mock recorder for MockInvitationServiceInterface.
+type MockInvitationServiceInterfaceMockRecorder struct {
+	mock *MockInvitationServiceInterface
+}
+
+// NewMockInvitationServiceInterface creates a new mock instance.
+func NewMockInvitationServiceInterface(ctrl *gomock.Controller) *MockInvitationServiceInterface {
+	mock := &MockInvitationServiceInterface{ctrl: ctrl}
+	mock.recorder = &MockInvitationServiceInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInvitationServiceInterface) EXPECT() *MockInvitationServiceInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockInvitationServiceInterface) Authenticate(workspace models.WorkspaceID, token string) (*models.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", workspace, token)
+	ret0, _ := ret[0].(*models.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockInvitationServiceInterfaceMockRecorder) Authenticate(workspace, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockInvitationServiceInterface)(nil).Authenticate), workspace, token)
+}
+
+// Entries mocks base method.
+func (m *MockInvitationServiceInterface) Entries() (map[ksuid.KSUID]models.Respondent, map[ksuid.KSUID]models.Invitation) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Entries")
+	ret0, _ := ret[0].(map[ksuid.KSUID]models.Respondent)
+	ret1, _ := ret[1].(map[ksuid.KSUID]models.Invitation)
+	return ret0, ret1
+}
+
+// Entries indicates an expected call of Entries.
+func (mr *MockInvitationServiceInterfaceMockRecorder) Entries() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Entries", reflect.TypeOf((*MockInvitationServiceInterface)(nil).Entries))
+}
+
+// GetInvitation mocks base method.
+func (m *MockInvitationServiceInterface) GetInvitation(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInvitation", workspace, id)
+	ret0, _ := ret[0].(*models.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInvitation indicates an expected call of GetInvitation.
+func (mr *MockInvitationServiceInterfaceMockRecorder) GetInvitation(workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInvitation", reflect.TypeOf((*MockInvitationServiceInterface)(nil).GetInvitation), workspace, id)
+}
+
+// GetInvitations mocks base method.
+func (m *MockInvitationServiceInterface) GetInvitations(workspace models.WorkspaceID, surveyID ksuid.KSUID) ([]models.InvitationDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInvitations", workspace, surveyID)
+	ret0, _ := ret[0].([]models.InvitationDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInvitations indicates an expected call of GetInvitations.
+func (mr *MockInvitationServiceInterfaceMockRecorder) GetInvitations(workspace, surveyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInvitations", reflect.TypeOf((*MockInvitationServiceInterface)(nil).GetInvitations), workspace, surveyID)
+}
+
+// GetPending mocks base method.
+func (m *MockInvitationServiceInterface) GetPending() ([]models.InvitationDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPending")
+	ret0, _ := ret[0].([]models.InvitationDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPending indicates an expected call of GetPending.
+func (mr *MockInvitationServiceInterfaceMockRecorder) GetPending() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPending", reflect.TypeOf((*MockInvitationServiceInterface)(nil).GetPending))
+}
+
+// Invite mocks base method.
+func (m *MockInvitationServiceInterface) Invite(workspace models.WorkspaceID, surveyID ksuid.KSUID, respondents []models.Respondent) ([]models.InvitationDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Invite", workspace, surveyID, respondents)
+	ret0, _ := ret[0].([]models.InvitationDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Invite indicates an expected call of Invite.
+func (mr *MockInvitationServiceInterfaceMockRecorder) Invite(workspace, surveyID, respondents interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Invite", reflect.TypeOf((*MockInvitationServiceInterface)(nil).Invite), workspace, surveyID, respondents)
+}
+
+// Open mocks base method.
+func (m *MockInvitationServiceInterface) Open(workspace models.WorkspaceID, token string) (*models.InvitationDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Open", workspace, token)
+	ret0, _ := ret[0].(*models.InvitationDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Open indicates an expected call of Open.
+func (mr *MockInvitationServiceInterfaceMockRecorder) Open(workspace, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Open", reflect.TypeOf((*MockInvitationServiceInterface)(nil).Open), workspace, token)
+}
+
+// Summary mocks base method.
+func (m *MockInvitationServiceInterface) Summary(workspace models.WorkspaceID, surveyID ksuid.KSUID) (*models.InvitationSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Summary", workspace, surveyID)
+	ret0, _ := ret[0].(*models.InvitationSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Summary indicates an expected call of Summary.
+func (mr *MockInvitationServiceInterfaceMockRecorder) Summary(workspace, surveyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Summary", reflect.TypeOf((*MockInvitationServiceInterface)(nil).Summary), workspace, surveyID)
+}
+
+// Verify mocks base method.
+func (m *MockInvitationServiceInterface) Verify(workspace models.WorkspaceID, surveyID ksuid.KSUID, token string) (*models.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Verify", workspace, surveyID, token)
+	ret0, _ := ret[0].(*models.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Verify indicates an expected call of Verify.
+func (mr *MockInvitationServiceInterfaceMockRecorder) Verify(workspace, surveyID, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockInvitationServiceInterface)(nil).Verify), workspace, surveyID, token)
+}
+
+// MockMailServiceInterface is a mock of MailServiceInterface interface.
+type MockMailServiceInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockMailServiceInterfaceMockRecorder
+}
+
+// MockMailServiceInterfaceMockRecorder is the mock recorder for MockMailServiceInterface.
+type MockMailServiceInterfaceMockRecorder struct {
+	mock *MockMailServiceInterface
+}
+
+// NewMockMailServiceInterface creates a new mock instance.
+func NewMockMailServiceInterface(ctrl *gomock.Controller) *MockMailServiceInterface {
+	mock := &MockMailServiceInterface{ctrl: ctrl}
+	mock.recorder = &MockMailServiceInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMailServiceInterface) EXPECT() *MockMailServiceInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Deliver mocks base method.
+func (m *MockMailServiceInterface) Deliver() (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Deliver")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Deliver indicates an expected call of Deliver.
+func (mr *MockMailServiceInterfaceMockRecorder) Deliver() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deliver", reflect.TypeOf((*MockMailServiceInterface)(nil).Deliver))
+}
+
+// Entries mocks base method.
+func (m *MockMailServiceInterface) Entries() map[ksuid.KSUID]models.OutboxMessage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Entries")
+	ret0, _ := ret[0].(map[ksuid.KSUID]models.OutboxMessage)
+	return ret0
+}
+
+// Entries indicates an expected call of Entries.
+func (mr *MockMailServiceInterfaceMockRecorder) Entries() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Entries", reflect.TypeOf((*MockMailServiceInterface)(nil).Entries))
+}
+
+// QueueInvitations mocks base method.
+func (m *MockMailServiceInterface) QueueInvitations(workspace models.WorkspaceID, invitations []models.InvitationDetails) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueueInvitations", workspace, invitations)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// QueueInvitations indicates an expected call of QueueInvitations.
+func (mr *MockMailServiceInterfaceMockRecorder) QueueInvitations(workspace, invitations interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueueInvitations", reflect.TypeOf((*MockMailServiceInterface)(nil).QueueInvitations), workspace, invitations)
+}
+
+// Remind mocks base method.
+func (m *MockMailServiceInterface) Remind() (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Remind")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Remind indicates an expected call of Remind.
+func (mr *MockMailServiceInterfaceMockRecorder) Remind() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSurvey", reflect.TypeOf((*MockSurveyServiceInterface)(nil).UpdateSurvey), id, survey)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remind", reflect.TypeOf((*MockMailServiceInterface)(nil).Remind))
 }
@@ -0,0 +1,245 @@
+package surveyservice
+
+import (
+	"fmt"
+	"github.com/segmentio/ksuid"
+	"io"
+	"strings"
+	"survey-platform/internal/models"
+	"survey-platform/internal/services"
+	"survey-platform/pkg/export"
+)
+
+// AuthorizedService is a SurveyServiceInterface acting for an api key, every call checks that the key grants the scopes
+// the call needs and that its user holds a role granting them on the survey of the call, or on the whole workspace
+// for calls which are not about a single survey, services.ErrForbidden is returned along with the reason otherwise
+type AuthorizedService struct {
+	service services.SurveyServiceInterface
+	// users decides what the keys bound to users may do, nil only checks the scopes of keys
+	users services.UserServiceInterface
+	key   *models.APIKey
+}
+
+// NewAuthorizedService returns service acting for key, the roles of the user of key are looked up in users
+func NewAuthorizedService(service services.SurveyServiceInterface, users services.UserServiceInterface, key *models.APIKey) *AuthorizedService {
+	return &AuthorizedService{
+		service: service,
+		users:   users,
+		key:     key,
+	}
+}
+
+func (a *AuthorizedService) CreateSurvey(workspace models.WorkspaceID, survey *models.Survey) (*models.Survey, error) {
+	if err := a.authorize(nil, models.ScopeSurveysWrite); err != nil {
+		return nil, err
+	}
+	return a.service.CreateSurvey(workspace, survey)
+}
+
+func (a *AuthorizedService) GetSurvey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error) {
+	if err := a.authorize(&id, models.ScopeSurveysRead); err != nil {
+		return nil, err
+	}
+	return a.service.GetSurvey(workspace, id)
+}
+
+func (a *AuthorizedService) GetSurveyDetails(workspace models.WorkspaceID, id ksuid.KSUID) (*models.SurveyDetails, error) {
+	if err := a.authorize(&id, models.ScopeSurveysRead); err != nil {
+		return nil, err
+	}
+	return a.service.GetSurveyDetails(workspace, id)
+}
+
+func (a *AuthorizedService) GetSurveyVersions(workspace models.WorkspaceID, id ksuid.KSUID) ([]models.Survey, error) {
+	if err := a.authorize(&id, models.ScopeSurveysRead); err != nil {
+		return nil, err
+	}
+	return a.service.GetSurveyVersions(workspace, id)
+}
+
+func (a *AuthorizedService) UpdateSurvey(workspace models.WorkspaceID, id ksuid.KSUID, survey models.Survey) (*models.Survey, error) {
+	if err := a.authorize(&id, models.ScopeSurveysWrite); err != nil {
+		return nil, err
+	}
+	return a.service.UpdateSurvey(workspace, id, survey)
+}
+
+func (a *AuthorizedService) DeleteSurvey(workspace models.WorkspaceID, id ksuid.KSUID, cascade bool) error {
+	if err := a.authorize(&id, models.ScopeSurveysWrite); err != nil {
+		return err
+	}
+	return a.service.DeleteSurvey(workspace, id, cascade)
+}
+
+func (a *AuthorizedService) GetAllSurveys(workspace models.WorkspaceID, query models.SurveyQuery) (*models.SurveyPage, error) {
+	if err := a.authorize(nil, models.ScopeSurveysRead); err != nil {
+		return nil, err
+	}
+	return a.service.GetAllSurveys(workspace, query)
+}
+
+func (a *AuthorizedService) SearchSurveys(workspace models.WorkspaceID, query string, limit int) ([]models.SearchResult, error) {
+	if err := a.authorize(nil, models.ScopeSurveysRead); err != nil {
+		return nil, err
+	}
+	return a.service.SearchSurveys(workspace, query, limit)
+}
+
+func (a *AuthorizedService) PublishSurvey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error) {
+	if err := a.authorize(&id, models.ScopeSurveysWrite); err != nil {
+		return nil, err
+	}
+	return a.service.PublishSurvey(workspace, id)
+}
+
+func (a *AuthorizedService) CloseSurvey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error) {
+	if err := a.authorize(&id, models.ScopeSurveysWrite); err != nil {
+		return nil, err
+	}
+	return a.service.CloseSurvey(workspace, id)
+}
+
+func (a *AuthorizedService) ArchiveSurvey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error) {
+	if err := a.authorize(&id, models.ScopeSurveysWrite); err != nil {
+		return nil, err
+	}
+	return a.service.ArchiveSurvey(workspace, id)
+}
+
+func (a *AuthorizedService) SaveResponse(workspace models.WorkspaceID, response models.Response) (*models.Response, error) {
+	if err := a.authorize(&response.SurveyID, models.ScopeResponsesWrite); err != nil {
+		return nil, err
+	}
+	return a.service.SaveResponse(workspace, response)
+}
+
+func (a *AuthorizedService) GetResponses(workspace models.WorkspaceID, query models.ResponseQuery, scope models.VersionScope) (*models.ResponsePage, error) {
+	if err := a.authorize(&query.SurveyID, models.ScopeResponsesRead); err != nil {
+		return nil, err
+	}
+	return a.service.GetResponses(workspace, query, scope)
+}
+
+// GetResponse checks the survey of the response once it is read, as only the response tells which survey it answers
+func (a *AuthorizedService) GetResponse(workspace models.WorkspaceID, invitationID, id ksuid.KSUID) (*models.ResponseDetails, error) {
+	response, err := a.service.GetResponse(workspace, invitationID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = a.authorize(&response.SurveyID, models.ScopeResponsesWrite); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// UpdateResponse reads the response first to check the survey it answers
+func (a *AuthorizedService) UpdateResponse(workspace models.WorkspaceID, invitationID, id ksuid.KSUID, answers []models.Answer) (*models.ResponseDetails, error) {
+	if _, err := a.GetResponse(workspace, invitationID, id); err != nil {
+		return nil, err
+	}
+	return a.service.UpdateResponse(workspace, invitationID, id, answers)
+}
+
+// DeleteResponse reads the response first to check the survey it answers
+func (a *AuthorizedService) DeleteResponse(workspace models.WorkspaceID, invitationID, id ksuid.KSUID) error {
+	if _, err := a.GetResponse(workspace, invitationID, id); err != nil {
+		return err
+	}
+	return a.service.DeleteResponse(workspace, invitationID, id)
+}
+
+func (a *AuthorizedService) GetResults(workspace models.WorkspaceID, surveyID ksuid.KSUID, filter models.ResultsFilter) (*models.Results, error) {
+	if err := a.authorize(&surveyID, models.ScopeResponsesRead); err != nil {
+		return nil, err
+	}
+	return a.service.GetResults(workspace, surveyID, filter)
+}
+
+func (a *AuthorizedService) GetCrossTab(workspace models.WorkspaceID, surveyID, rowID, columnID ksuid.KSUID, scope models.VersionScope) (*models.CrossTab, error) {
+	if err := a.authorize(&surveyID, models.ScopeResponsesRead); err != nil {
+		return nil, err
+	}
+	return a.service.GetCrossTab(workspace, surveyID, rowID, columnID, scope)
+}
+
+func (a *AuthorizedService) StartDraft(workspace models.WorkspaceID, draft models.Draft) (*models.DraftSession, error) {
+	if err := a.authorize(&draft.SurveyID, models.ScopeResponsesWrite); err != nil {
+		return nil, err
+	}
+	return a.service.StartDraft(workspace, draft)
+}
+
+func (a *AuthorizedService) GetDraft(workspace models.WorkspaceID, token string) (*models.Draft, error) {
+	if err := a.authorize(surveyInToken(token), models.ScopeResponsesWrite); err != nil {
+		return nil, err
+	}
+	return a.service.GetDraft(workspace, token)
+}
+
+func (a *AuthorizedService) UpdateDraft(workspace models.WorkspaceID, token string, answers []models.Answer) (*models.Draft, error) {
+	if err := a.authorize(surveyInToken(token), models.ScopeResponsesWrite); err != nil {
+		return nil, err
+	}
+	return a.service.UpdateDraft(workspace, token, answers)
+}
+
+func (a *AuthorizedService) SubmitDraft(workspace models.WorkspaceID, token string) (*models.Response, error) {
+	if err := a.authorize(surveyInToken(token), models.ScopeResponsesWrite); err != nil {
+		return nil, err
+	}
+	return a.service.SubmitDraft(workspace, token)
+}
+
+func (a *AuthorizedService) GetFunnel(workspace models.WorkspaceID, surveyID ksuid.KSUID) (*models.Funnel, error) {
+	if err := a.authorize(&surveyID, models.ScopeResponsesRead); err != nil {
+		return nil, err
+	}
+	return a.service.GetFunnel(workspace, surveyID)
+}
+
+func (a *AuthorizedService) ExportResponses(workspace models.WorkspaceID, surveyID ksuid.KSUID, newWriter func() (export.Writer, error)) error {
+	if err := a.authorize(&surveyID, models.ScopeResponsesRead); err != nil {
+		return err
+	}
+	return a.service.ExportResponses(workspace, surveyID, newWriter)
+}
+
+func (a *AuthorizedService) Import(workspace models.WorkspaceID, r io.Reader, options models.ImportOptions) (*models.ImportReport, error) {
+	if err := a.authorize(nil, models.ScopeSurveysWrite, models.ScopeResponsesWrite); err != nil {
+		return nil, err
+	}
+	return a.service.Import(workspace, r, options)
+}
+
+// FindOrphans is refused, the orphans span every workspace while a key is bound to one
+func (a *AuthorizedService) FindOrphans() ([]models.ResponseSet, error) {
+	return nil, fmt.Errorf("%w: orphaned responses span every workspace", services.ErrForbidden)
+}
+
+// Entries returns no data, the data of every workspace is never handed to a key bound to one
+func (a *AuthorizedService) Entries() *models.DBEntry {
+	return &models.DBEntry{}
+}
+
+// authorize checks that the key grants scopes and that its user holds a role granting them on the survey surveyID,
+// or on the whole workspace when surveyID is nil
+func (a *AuthorizedService) authorize(surveyID *ksuid.KSUID, scopes ...models.Scope) error {
+	for _, scope := range scopes {
+		if !a.key.Allows(scope) {
+			return fmt.Errorf("%w: api key does not grant %s", services.ErrForbidden, scope)
+		}
+	}
+	if a.users == nil {
+		return nil
+	}
+	return a.users.Authorize(a.key, surveyID, scopes...)
+}
+
+// surveyInToken returns the survey a resume token was made for, nil when the token does not start with a survey id
+func surveyInToken(token string) *ksuid.KSUID {
+	id, err := ksuid.Parse(strings.SplitN(token, ".", 2)[0])
+	if err != nil {
+		return nil
+	}
+	return &id
+}
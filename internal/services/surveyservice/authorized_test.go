@@ -0,0 +1,143 @@
+package surveyservice
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories/userrepo"
+	"survey-platform/internal/services"
+	"survey-platform/internal/services/services_mock"
+	"survey-platform/internal/services/userservice"
+	"testing"
+)
+
+func TestAuthorizedService(t *testing.T) {
+	surveyID, otherID, invitationID, responseID := ksuid.New(), ksuid.New(), ksuid.New(), ksuid.New()
+	users := map[string]models.User{
+		"analyst":    {Name: "analyst", Roles: []models.RoleAssignment{{Role: models.RoleAnalyst}}},
+		"editor":     {Name: "editor", Roles: []models.RoleAssignment{{Role: models.RoleEditor, SurveyID: &surveyID}}},
+		"respondent": {Name: "respondent", Roles: []models.RoleAssignment{{Role: models.RoleRespondent, SurveyID: &surveyID}}},
+	}
+	stored := make(map[ksuid.KSUID]models.User, len(users))
+	keys := make(map[string]*models.APIKey, len(users))
+	for name, user := range users {
+		user.ID, user.WorkspaceID = ksuid.New(), models.DefaultWorkspace
+		stored[user.ID] = user
+		userID := user.ID
+		keys[name] = &models.APIKey{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, UserID: &userID, Scopes: models.Scopes}
+	}
+	ws := models.DefaultWorkspace
+	for _, tc := range []struct {
+		user, call string
+		// expect sets up the call on the wrapped service when it is allowed
+		expect  func(service *services_mock.MockSurveyServiceInterface)
+		do      func(service services.SurveyServiceInterface) error
+		allowed bool
+	}{
+		{"analyst", "GetResponses", func(m *services_mock.MockSurveyServiceInterface) {
+			m.EXPECT().GetResponses(ws, models.ResponseQuery{SurveyID: surveyID}, models.VersionScope{}).Return(&models.ResponsePage{}, nil)
+		}, func(s services.SurveyServiceInterface) error {
+			_, err := s.GetResponses(ws, models.ResponseQuery{SurveyID: surveyID}, models.VersionScope{})
+			return err
+		}, true},
+		{"analyst", "GetResults", func(m *services_mock.MockSurveyServiceInterface) {
+			m.EXPECT().GetResults(ws, otherID, models.ResultsFilter{}).Return(&models.Results{}, nil)
+		}, func(s services.SurveyServiceInterface) error {
+			_, err := s.GetResults(ws, otherID, models.ResultsFilter{})
+			return err
+		}, true},
+		{"analyst", "UpdateSurvey", nil, func(s services.SurveyServiceInterface) error {
+			_, err := s.UpdateSurvey(ws, surveyID, models.Survey{})
+			return err
+		}, false},
+		{"analyst", "DeleteSurvey", nil, func(s services.SurveyServiceInterface) error {
+			return s.DeleteSurvey(ws, surveyID, true)
+		}, false},
+		{"analyst", "SaveResponse", nil, func(s services.SurveyServiceInterface) error {
+			_, err := s.SaveResponse(ws, models.Response{SurveyID: surveyID})
+			return err
+		}, false},
+		{"analyst", "Import", nil, func(s services.SurveyServiceInterface) error {
+			_, err := s.Import(ws, nil, models.ImportOptions{})
+			return err
+		}, false},
+		{"editor", "UpdateSurvey", func(m *services_mock.MockSurveyServiceInterface) {
+			m.EXPECT().UpdateSurvey(ws, surveyID, models.Survey{}).Return(&models.Survey{}, nil)
+		}, func(s services.SurveyServiceInterface) error {
+			_, err := s.UpdateSurvey(ws, surveyID, models.Survey{})
+			return err
+		}, true},
+		{"editor", "DeleteSurvey of another survey", nil, func(s services.SurveyServiceInterface) error {
+			return s.DeleteSurvey(ws, otherID, false)
+		}, false},
+		{"editor", "CreateSurvey", nil, func(s services.SurveyServiceInterface) error {
+			_, err := s.CreateSurvey(ws, &models.Survey{})
+			return err
+		}, false},
+		{"respondent", "StartDraft", func(m *services_mock.MockSurveyServiceInterface) {
+			m.EXPECT().StartDraft(ws, models.Draft{SurveyID: surveyID}).Return(&models.DraftSession{}, nil)
+		}, func(s services.SurveyServiceInterface) error {
+			_, err := s.StartDraft(ws, models.Draft{SurveyID: surveyID})
+			return err
+		}, true},
+		{"respondent", "SubmitDraft of another survey", nil, func(s services.SurveyServiceInterface) error {
+			_, err := s.SubmitDraft(ws, otherID.String()+"."+ksuid.New().String()+".secret")
+			return err
+		}, false},
+		{"respondent", "GetResults", nil, func(s services.SurveyServiceInterface) error {
+			_, err := s.GetResults(ws, surveyID, models.ResultsFilter{})
+			return err
+		}, false},
+		{"respondent", "UpdateResponse", func(m *services_mock.MockSurveyServiceInterface) {
+			m.EXPECT().GetResponse(ws, invitationID, responseID).
+				Return(&models.ResponseDetails{Response: models.Response{SurveyID: surveyID}}, nil)
+			m.EXPECT().UpdateResponse(ws, invitationID, responseID, nil).Return(&models.ResponseDetails{}, nil)
+		}, func(s services.SurveyServiceInterface) error {
+			_, err := s.UpdateResponse(ws, invitationID, responseID, nil)
+			return err
+		}, true},
+		{"respondent", "DeleteResponse of another survey", func(m *services_mock.MockSurveyServiceInterface) {
+			m.EXPECT().GetResponse(ws, invitationID, responseID).
+				Return(&models.ResponseDetails{Response: models.Response{SurveyID: otherID}}, nil)
+		}, func(s services.SurveyServiceInterface) error {
+			return s.DeleteResponse(ws, invitationID, responseID)
+		}, false},
+	} {
+		verdict := "get ErrForbidden on"
+		if tc.allowed {
+			verdict = "call"
+		}
+		t.Run(fmt.Sprintf("should let the %s %s %s", tc.user, verdict, tc.call), func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+			if tc.expect != nil {
+				tc.expect(mockService)
+			}
+			userService := userservice.NewUserService(userrepo.NewUserRepo(stored, nil), nil, nil, nil)
+			err := tc.do(NewAuthorizedService(mockService, userService, keys[tc.user]))
+			if tc.allowed {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, services.ErrForbidden), err)
+		})
+	}
+	t.Run("should check the scopes of keys which are not bound to a user", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockService := services_mock.NewMockSurveyServiceInterface(ctrl)
+		mockService.EXPECT().GetSurvey(ws, surveyID).Return(&models.Survey{}, nil)
+		key := &models.APIKey{ID: ksuid.New(), WorkspaceID: ws, Scopes: []models.Scope{models.ScopeSurveysRead}}
+		service := NewAuthorizedService(mockService, nil, key)
+		_, err := service.GetSurvey(ws, surveyID)
+		assert.NoError(t, err)
+		err = service.DeleteSurvey(ws, surveyID, false)
+		assert.EqualError(t, err, "access denied: api key does not grant surveys:write")
+		_, err = service.FindOrphans()
+		assert.True(t, errors.Is(err, services.ErrForbidden))
+	})
+}
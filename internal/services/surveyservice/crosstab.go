@@ -0,0 +1,188 @@
+package surveyservice
+
+import (
+	"fmt"
+	"github.com/segmentio/ksuid"
+	"strconv"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/services"
+	"survey-platform/pkg/stats"
+)
+
+// GetCrossTab tabulates the answers to the row question against the answers to the column question
+// for the responses to the versions selected by scope, the questions are looked up in the selected version
+func (s *SurveyService) GetCrossTab(workspace models.WorkspaceID, surveyID, rowID, columnID ksuid.KSUID,
+	scope models.VersionScope) (*models.CrossTab, error) {
+	versions, err := s.surveyRepo.GetVersions(workspace, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	selected, scoped, err := selectVersions(versions, scope)
+	if err != nil {
+		return nil, err
+	}
+	row, err := tabulatedQuestion(selected, rowID)
+	if err != nil {
+		return nil, err
+	}
+	column, err := tabulatedQuestion(selected, columnID)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := s.responseRepo.GetBySurveyID(workspace, surveyID)
+	if err != nil && err != repositories.ErrNotFound {
+		return nil, err
+	}
+	crossTab := newCrossTab(surveyID, row, column)
+	for _, response := range responses {
+		if scoped != nil && !scoped[response.AnsweredVersion()] {
+			continue
+		}
+		crossTab.add(response, row, column)
+	}
+	return crossTab.summarise(), nil
+}
+
+// tabulatedQuestion returns the question of survey with the given id, it must have a fixed set of answers
+func tabulatedQuestion(survey *models.Survey, id ksuid.KSUID) (models.Question, error) {
+	for _, question := range survey.Questions {
+		if question.ID == id {
+			if categories(question) == nil {
+				return question, fmt.Errorf("%w: %s question %s", services.ErrQuestionNotTabulated, question.Kind(), id)
+			}
+			return question, nil
+		}
+	}
+	return models.Question{}, fmt.Errorf("%w: %s", services.ErrQuestionNotFound, id)
+}
+
+// categories returns the answers a question can be tabulated by, nil for questions with free form answers
+func categories(question models.Question) []string {
+	switch question.Kind() {
+	case models.QuestionYesNo:
+		return []string{yesOption, noOption}
+	case models.QuestionSingleChoice, models.QuestionMultiChoice:
+		return append([]string(nil), question.Options...)
+	case models.QuestionRating:
+		return numberRange(bound(question.Min, defaultRatingMin), *question.Max)
+	case models.QuestionNPS:
+		return numberRange(npsMin, npsMax)
+	default:
+		return nil
+	}
+}
+
+func numberRange(min, max float64) []string {
+	var numbers []string
+	for number := min; number <= max; number++ {
+		numbers = append(numbers, formatNumber(number))
+	}
+	return numbers
+}
+
+func formatNumber(number float64) string {
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
+
+// answerCategories returns the categories picked by an answer to a question of the given kind
+func answerCategories(kind models.QuestionType, value models.Value) []string {
+	switch value.Kind {
+	case models.ValueBool:
+		if kind != models.QuestionYesNo {
+			return nil
+		}
+		if value.Bool {
+			return []string{yesOption}
+		}
+		return []string{noOption}
+	case models.ValueText:
+		return []string{value.Text}
+	case models.ValueChoices:
+		return value.Choices
+	case models.ValueNumber:
+		return []string{formatNumber(value.Number)}
+	default:
+		return nil
+	}
+}
+
+// crossTab builds a models.CrossTab, indexes map the categories of each question to their position
+type crossTab struct {
+	table         *models.CrossTab
+	rowIndexes    map[string]int
+	columnIndexes map[string]int
+}
+
+func newCrossTab(surveyID ksuid.KSUID, row, column models.Question) *crossTab {
+	rowCategories, columnCategories := categories(row), categories(column)
+	table := &models.CrossTab{
+		SurveyID: surveyID,
+		Row:      models.CrossTabAxis{QuestionID: row.ID, Question: row.Question, Categories: rowCategories},
+		Column:   models.CrossTabAxis{QuestionID: column.ID, Question: column.Question, Categories: columnCategories},
+		Counts:   make([][]int, len(rowCategories)),
+	}
+	for i := range table.Counts {
+		table.Counts[i] = make([]int, len(columnCategories))
+	}
+	return &crossTab{table: table, rowIndexes: indexes(rowCategories), columnIndexes: indexes(columnCategories)}
+}
+
+func indexes(categories []string) map[string]int {
+	positions := make(map[string]int, len(categories))
+	for i, category := range categories {
+		positions[category] = i
+	}
+	return positions
+}
+
+// add counts the response when it answers both questions, answers outside the categories are ignored
+func (c *crossTab) add(response models.Response, row, column models.Question) {
+	var rowAnswers, columnAnswers []string
+	for _, answer := range response.Answers {
+		if answer.QuestionID == row.ID && rowAnswers == nil {
+			rowAnswers = answerCategories(row.Kind(), answer.Answer)
+		}
+		if answer.QuestionID == column.ID && columnAnswers == nil {
+			columnAnswers = answerCategories(column.Kind(), answer.Answer)
+		}
+	}
+	for _, rowAnswer := range rowAnswers {
+		i, ok := c.rowIndexes[rowAnswer]
+		if !ok {
+			continue
+		}
+		for _, columnAnswer := range columnAnswers {
+			if j, ok := c.columnIndexes[columnAnswer]; ok {
+				c.table.Counts[i][j]++
+			}
+		}
+	}
+}
+
+// summarise fills in the totals, percentages and chi-square test of the counted table
+func (c *crossTab) summarise() *models.CrossTab {
+	table := c.table
+	table.RowTotals = make([]int, len(table.Counts))
+	table.ColumnTotals = make([]int, len(table.Column.Categories))
+	for i, row := range table.Counts {
+		for j, count := range row {
+			table.RowTotals[i] += count
+			table.ColumnTotals[j] += count
+			table.Total += count
+		}
+	}
+	table.RowPercentages = make([][]float64, len(table.Counts))
+	table.ColumnPercentages = make([][]float64, len(table.Counts))
+	for i, row := range table.Counts {
+		table.RowPercentages[i] = make([]float64, len(row))
+		table.ColumnPercentages[i] = make([]float64, len(row))
+		for j, count := range row {
+			table.RowPercentages[i][j] = stats.Percentage(count, table.RowTotals[i])
+			table.ColumnPercentages[i][j] = stats.Percentage(count, table.ColumnTotals[j])
+		}
+	}
+	statistic, degreesOfFreedom, pValue := stats.ChiSquare(table.Counts)
+	table.ChiSquare = models.ChiSquareTest{Statistic: statistic, DegreesOfFreedom: degreesOfFreedom, PValue: pValue}
+	return table
+}
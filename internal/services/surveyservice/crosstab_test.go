@@ -0,0 +1,77 @@
+package surveyservice
+
+import (
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/repositories_mock"
+	"survey-platform/internal/services"
+	"testing"
+	"time"
+)
+
+func TestSurveyService_GetCrossTab(t *testing.T) {
+	survey := resultsSurvey()
+	survey.Questions = append(survey.Questions, models.Question{ID: ksuid.New(), Question: "anything else?", Type: models.QuestionText})
+	good, dish, rating := survey.Questions[0], survey.Questions[1], survey.Questions[2]
+	now := time.Now()
+	responses := []models.Response{
+		resultsResponse(survey, now, true, "pizza", 5, 10),
+		resultsResponse(survey, now, true, "pizza", 4, 9),
+		resultsResponse(survey, now, true, "pasta", 4, 9),
+		resultsResponse(survey, now, false, "pasta", 1, 2),
+		{ID: ksuid.New(), SurveyID: survey.ID, Answers: []models.Answer{{QuestionID: good.ID, Answer: models.BoolValue(false)}}},
+	}
+	newService := func(ctrl *gomock.Controller) *SurveyService {
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().GetVersions(models.DefaultWorkspace, survey.ID).Return([]models.Survey{*survey}, nil)
+		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		mockResponseRepo.EXPECT().GetBySurveyID(models.DefaultWorkspace, survey.ID).Return(responses, nil).AnyTimes()
+		return NewSurveyService(3, mockSurveyRepo, mockResponseRepo, nil, nil)
+	}
+	t.Run("should count the responses answering both questions", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		crossTab, err := newService(ctrl).GetCrossTab(models.DefaultWorkspace, survey.ID, good.ID, dish.ID, models.VersionScope{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"yes", "no"}, crossTab.Row.Categories)
+		assert.Equal(t, []string{"pizza", "pasta"}, crossTab.Column.Categories)
+		assert.Equal(t, [][]int{{2, 1}, {0, 1}}, crossTab.Counts)
+		assert.Equal(t, []int{3, 1}, crossTab.RowTotals)
+		assert.Equal(t, []int{2, 2}, crossTab.ColumnTotals)
+		assert.Equal(t, 4, crossTab.Total)
+		assert.Equal(t, [][]float64{{200.0 / 3, 100.0 / 3}, {0, 100}}, crossTab.RowPercentages)
+		assert.Equal(t, [][]float64{{100, 50}, {0, 50}}, crossTab.ColumnPercentages)
+		assert.InDelta(t, 1.333, crossTab.ChiSquare.Statistic, 0.001)
+		assert.Equal(t, 1, crossTab.ChiSquare.DegreesOfFreedom)
+		assert.InDelta(t, 0.248, crossTab.ChiSquare.PValue, 0.001)
+	})
+	t.Run("should tabulate rating questions by every point of the scale", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		crossTab, err := newService(ctrl).GetCrossTab(models.DefaultWorkspace, survey.ID, rating.ID, good.ID, models.VersionScope{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1", "2", "3", "4", "5"}, crossTab.Row.Categories)
+		assert.Equal(t, []int{1, 0, 0, 2, 1}, crossTab.RowTotals)
+	})
+	t.Run("should return error for questions outside the survey or with free form answers", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		_, err := newService(ctrl).GetCrossTab(models.DefaultWorkspace, survey.ID, good.ID, ksuid.New(), models.VersionScope{})
+		assert.True(t, errors.Is(err, services.ErrQuestionNotFound))
+		_, err = newService(ctrl).GetCrossTab(models.DefaultWorkspace, survey.ID, survey.Questions[4].ID, good.ID, models.VersionScope{})
+		assert.True(t, errors.Is(err, services.ErrQuestionNotTabulated))
+	})
+	t.Run("should return error when survey does not exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().GetVersions(models.DefaultWorkspace, survey.ID).Return(nil, repositories.ErrNotFound)
+		crossTab, err := NewSurveyService(3, mockSurveyRepo, nil, nil, nil).GetCrossTab(models.DefaultWorkspace, survey.ID, good.ID, dish.ID, models.VersionScope{})
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Nil(t, crossTab)
+	})
+}
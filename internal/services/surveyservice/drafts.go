@@ -0,0 +1,331 @@
+package surveyservice
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"github.com/segmentio/ksuid"
+	"io"
+	"log"
+	"strings"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/services"
+	"survey-platform/pkg/stats"
+	"time"
+)
+
+const (
+	// DefaultDraftTTL is how long a draft which is not updated can still be resumed
+	DefaultDraftTTL = 7 * 24 * time.Hour
+	// resumeTokenBytes is the number of random bytes in a resume token
+	resumeTokenBytes = 24
+)
+
+// StartDraft starts a draft of a response to a survey of workspace accepting responses and returns it along with
+// its resume token, draft carries the invitation it answers along with the first answers, the rest of it is filled in
+// drafts are started under the lock DeleteSurvey holds, so that no draft of a deleted survey is left behind
+func (s *SurveyService) StartDraft(workspace models.WorkspaceID, draft models.Draft) (*models.DraftSession, error) {
+	if s.drafts == nil {
+		return nil, services.ErrDraftsDisabled
+	}
+	s.draftMu.Lock()
+	defer s.draftMu.Unlock()
+	survey, err := s.surveyRepo.Get(workspace, draft.SurveyID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.timeGenerator.Now()
+	count := 0
+	if survey.MaxResponses > 0 {
+		if count, err = s.responseRepo.CountBySurveyID(workspace, survey.ID); err != nil {
+			return nil, err
+		}
+	}
+	if err = acceptingResponses(survey, now, count); err != nil {
+		return nil, err
+	}
+	answers, err := mergeAnswers(survey, nil, draft.Answers)
+	if err != nil {
+		return nil, err
+	}
+	secret := make([]byte, resumeTokenBytes)
+	if _, err = io.ReadFull(s.random, secret); err != nil {
+		return nil, err
+	}
+	draft.ID = s.idGenerator.Generate()
+	draft.WorkspaceID = workspace
+	draft.SurveyVersion = survey.CurrentVersion()
+	draft.Answers = answers
+	draft.CreatedAt, draft.UpdatedAt, draft.ExpiresAt = now, now, now.Add(s.draftTTL)
+	draft.SubmittedAt = nil
+	token := survey.ID.String() + "." + draft.ID.String() + "." + hex.EncodeToString(secret)
+	draft.TokenHash = hashToken(token)
+	created, err := s.drafts.Create(&draft)
+	if err != nil {
+		return nil, err
+	}
+	return &models.DraftSession{Draft: redactDraft(*created), ResumeToken: token}, nil
+}
+
+// GetDraft returns the draft of token, submitted drafts are returned as well so that clients can tell they are done
+func (s *SurveyService) GetDraft(workspace models.WorkspaceID, token string) (*models.Draft, error) {
+	draft, err := s.draft(workspace, token)
+	if err != nil {
+		return nil, err
+	}
+	if draft.Expired(s.timeGenerator.Now()) {
+		return nil, services.ErrDraftExpired
+	}
+	redacted := redactDraft(*draft)
+	return &redacted, nil
+}
+
+// UpdateDraft answers the questions of answers in the draft of token and pushes its expiry back
+// an answer replaces the previous answer to its question and a null answer clears it, every answer must be valid
+// for its question of the current survey, the rules and required questions are only checked once the draft is submitted
+func (s *SurveyService) UpdateDraft(workspace models.WorkspaceID, token string, answers []models.Answer) (*models.Draft, error) {
+	s.draftMu.Lock()
+	defer s.draftMu.Unlock()
+	draft, err := s.openDraft(workspace, token)
+	if err != nil {
+		return nil, err
+	}
+	survey, err := s.surveyRepo.Get(workspace, draft.SurveyID)
+	if err != nil {
+		return nil, err
+	}
+	if draft.Answers, err = mergeAnswers(survey, draft.Answers, answers); err != nil {
+		return nil, err
+	}
+	now := s.timeGenerator.Now()
+	draft.SurveyVersion = survey.CurrentVersion()
+	draft.UpdatedAt, draft.ExpiresAt = now, now.Add(s.draftTTL)
+	if err = s.drafts.Update(draft); err != nil {
+		return nil, err
+	}
+	redacted := redactDraft(*draft)
+	return &redacted, nil
+}
+
+// SubmitDraft saves the answers of the draft of token as a response, which goes through the checks of SaveResponse
+// the response keeps the id of the draft, and a draft which cannot be submitted yet can still be updated
+func (s *SurveyService) SubmitDraft(workspace models.WorkspaceID, token string) (*models.Response, error) {
+	s.draftMu.Lock()
+	defer s.draftMu.Unlock()
+	draft, err := s.openDraft(workspace, token)
+	if err != nil {
+		return nil, err
+	}
+	return s.saveResponse(workspace, draft.Response(), draft.ID, s.drafts.Submit)
+}
+
+// GetFunnel follows the drafts of a survey of workspace from their start to their submission
+func (s *SurveyService) GetFunnel(workspace models.WorkspaceID, surveyID ksuid.KSUID) (*models.Funnel, error) {
+	survey, err := s.surveyRepo.Get(workspace, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	funnel := &models.Funnel{SurveyID: surveyID, Questions: make([]models.FunnelStep, 0, len(survey.Questions))}
+	drafts := make([]models.Draft, 0)
+	reached := make(map[ksuid.KSUID]int, len(survey.Questions))
+	if s.drafts != nil {
+		if drafts, err = s.drafts.GetBySurveyID(workspace, surveyID); err != nil {
+			return nil, err
+		}
+		expired, err := s.drafts.GetExpired(workspace, surveyID)
+		if err != nil {
+			return nil, err
+		}
+		funnel.Started, funnel.Expired = expired.Drafts, expired.Drafts
+		for id, count := range expired.Reached {
+			reached[id] = count
+		}
+	}
+	now := s.timeGenerator.Now()
+	for _, draft := range drafts {
+		funnel.Started++
+		switch {
+		case draft.SubmittedAt != nil:
+			funnel.Submitted++
+		case draft.Expired(now):
+			funnel.Expired++
+		default:
+			funnel.InProgress++
+		}
+		for _, answer := range draft.Answers {
+			reached[answer.QuestionID]++
+		}
+	}
+	funnel.CompletionRate = stats.Percentage(funnel.Submitted, funnel.Started)
+	for _, question := range survey.Questions {
+		funnel.Questions = append(funnel.Questions, models.FunnelStep{
+			QuestionID: question.ID,
+			Question:   question.Question,
+			Reached:    reached[question.ID],
+		})
+	}
+	return funnel, nil
+}
+
+// DeleteExpiredDrafts deletes the drafts left unsubmitted past their expiry and returns how many were deleted,
+// the funnel keeps counting them as expired
+func (s *SurveyService) DeleteExpiredDrafts() (int, error) {
+	if s.drafts == nil {
+		return 0, nil
+	}
+	return s.drafts.DeleteExpired(s.timeGenerator.Now())
+}
+
+// WatchDrafts deletes the expired drafts right away and then every interval until ctx is done
+// errors are logged and the deletion is retried on the next tick
+func (s *SurveyService) WatchDrafts(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		deleted, err := s.DeleteExpiredDrafts()
+		if err != nil {
+			log.Println("error while deleting expired drafts", err)
+		} else if deleted > 0 {
+			log.Printf("deleted %d expired drafts", deleted)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// addDrafts tallies the answers of the drafts in progress of a survey into total and perDay
+// the drafts are bucketed by the day they were last updated and returns the number of drafts added
+func (s *SurveyService) addDrafts(workspace models.WorkspaceID, surveyID ksuid.KSUID, versions []models.Survey,
+	scoped map[int]bool, filter models.ResultsFilter, total *bucket, perDay map[string]int) (int, error) {
+	if s.drafts == nil {
+		return 0, nil
+	}
+	drafts, err := s.drafts.GetBySurveyID(workspace, surveyID)
+	if err != nil {
+		return 0, err
+	}
+	now := s.timeGenerator.Now()
+	tally := newSurveyTally(versions)
+	for _, draft := range drafts {
+		if draft.SubmittedAt == nil && !draft.Expired(now) {
+			tally.add(draft.Response())
+		}
+	}
+	draftTotal, draftsPerDay := tally.merged(scoped, formatDay(filter.From), formatDay(filter.To))
+	total.merge(draftTotal)
+	for day, count := range draftsPerDay {
+		perDay[day] += count
+	}
+	return draftTotal.responses, nil
+}
+
+// openDraft returns the draft of token when it can still be changed
+func (s *SurveyService) openDraft(workspace models.WorkspaceID, token string) (*models.Draft, error) {
+	draft, err := s.draft(workspace, token)
+	if err != nil {
+		return nil, err
+	}
+	if draft.SubmittedAt != nil {
+		return nil, repositories.ErrDraftSubmitted
+	}
+	if draft.Expired(s.timeGenerator.Now()) {
+		return nil, services.ErrDraftExpired
+	}
+	return draft, nil
+}
+
+// draft returns the draft of token, a token is made of the survey id, the draft id and a random secret
+// ErrInvalidResumeToken is returned for tokens which do not match a draft of workspace
+func (s *SurveyService) draft(workspace models.WorkspaceID, token string) (*models.Draft, error) {
+	if s.drafts == nil {
+		return nil, services.ErrDraftsDisabled
+	}
+	parts := strings.SplitN(token, ".", 3)
+	if len(parts) != 3 {
+		return nil, services.ErrInvalidResumeToken
+	}
+	surveyID, err := ksuid.Parse(parts[0])
+	if err != nil {
+		return nil, services.ErrInvalidResumeToken
+	}
+	id, err := ksuid.Parse(parts[1])
+	if err != nil {
+		return nil, services.ErrInvalidResumeToken
+	}
+	draft, err := s.drafts.Get(workspace, id)
+	if err == repositories.ErrNotFound {
+		return nil, services.ErrInvalidResumeToken
+	} else if err != nil {
+		return nil, err
+	}
+	if draft.SurveyID != surveyID || subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(draft.TokenHash)) != 1 {
+		return nil, services.ErrInvalidResumeToken
+	}
+	return draft, nil
+}
+
+// mergeAnswers returns stored with answers applied, an answer replaces the stored answer to its question
+// and a null answer removes it, the violations of every answer are returned in a services.ValidationError
+func mergeAnswers(survey *models.Survey, stored, answers []models.Answer) ([]models.Answer, error) {
+	questions := make(map[ksuid.KSUID]models.Question, len(survey.Questions))
+	for _, question := range survey.Questions {
+		questions[question.ID] = question
+	}
+	validationErr := &services.ValidationError{}
+	changed := make(map[ksuid.KSUID]models.Value, len(answers))
+	order := make([]ksuid.KSUID, 0, len(answers))
+	for _, answer := range answers {
+		question, ok := questions[answer.QuestionID]
+		if !ok {
+			validationErr.Add(answer.QuestionID, "question is not part of the survey")
+			continue
+		}
+		if _, ok = changed[answer.QuestionID]; ok {
+			validationErr.Add(answer.QuestionID, "question is answered more than once")
+			continue
+		}
+		if answer.Answer.Kind != models.ValueNone {
+			if err := validateAnswer(question, answer.Answer); err != nil {
+				validationErr.Add(answer.QuestionID, err.Error())
+				continue
+			}
+		}
+		changed[answer.QuestionID] = answer.Answer
+		order = append(order, answer.QuestionID)
+	}
+	if err := validationErr.ErrOrNil(); err != nil {
+		return nil, err
+	}
+	merged := make([]models.Answer, 0, len(stored)+len(answers))
+	for _, answer := range stored {
+		if value, ok := changed[answer.QuestionID]; ok {
+			answer.Answer = value
+			delete(changed, answer.QuestionID)
+		}
+		if answer.Answer.Kind != models.ValueNone {
+			merged = append(merged, answer)
+		}
+	}
+	for _, questionID := range order {
+		if value, ok := changed[questionID]; ok && value.Kind != models.ValueNone {
+			merged = append(merged, models.Answer{QuestionID: questionID, Answer: value})
+		}
+	}
+	return merged, nil
+}
+
+// redactDraft leaves the hash of the resume token out of drafts returned by the api
+func redactDraft(draft models.Draft) models.Draft {
+	draft.TokenHash = ""
+	return draft
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
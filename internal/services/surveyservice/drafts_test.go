@@ -0,0 +1,386 @@
+package surveyservice
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/draftrepo"
+	"survey-platform/internal/repositories/repositories_mock"
+	"survey-platform/internal/repositories/responserepo"
+	"survey-platform/internal/repositories/surveyrepo"
+	"survey-platform/internal/services"
+	"survey-platform/pkg/idgenerator/ksuidgenerator"
+	"survey-platform/pkg/timegenerator/timegenerator_mock"
+	"sync"
+	"testing"
+	"time"
+)
+
+const draftTTL = 24 * time.Hour
+
+// draftFixture is a survey service over in memory repos holding a published survey, its clock is moved with advance
+type draftFixture struct {
+	service   *SurveyService
+	draftRepo *draftrepo.DraftRepo
+	survey    *models.Survey
+	mu        sync.Mutex
+	now       time.Time
+}
+
+func newDraftFixture(t *testing.T) *draftFixture {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+	f := &draftFixture{now: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)}
+	timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+	timeGeneratorMock.EXPECT().Now().DoAndReturn(func() time.Time {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return f.now
+	}).AnyTimes()
+	surveyRepo := surveyrepo.NewSurveyRepo(nil, nil, nil)
+	responseRepo := responserepo.NewResponseRepo(nil, nil)
+	f.draftRepo = draftrepo.NewDraftRepo(nil, nil).WithResponses(responseRepo)
+	f.service = NewSurveyService(4, surveyRepo, responseRepo, ksuidgenerator.NewKSUIDGenerator(), timeGeneratorMock)
+	f.service.SetDrafts(f.draftRepo, draftTTL)
+	f.survey = resultsSurvey()
+	f.survey.WorkspaceID, f.survey.Name = models.DefaultWorkspace, "Lunch"
+	f.survey.Questions[0].Required = true
+	_, err := surveyRepo.Create(f.survey)
+	require.NoError(t, err)
+	return f
+}
+
+func (f *draftFixture) advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func (f *draftFixture) answer(question int, value models.Value) models.Answer {
+	return models.Answer{QuestionID: f.survey.Questions[question].ID, Answer: value}
+}
+
+func (f *draftFixture) start(t *testing.T, answers ...models.Answer) *models.DraftSession {
+	session, err := f.service.StartDraft(models.DefaultWorkspace, models.Draft{SurveyID: f.survey.ID, Answers: answers})
+	require.NoError(t, err)
+	return session
+}
+
+func TestSurveyService_StartDraft(t *testing.T) {
+	t.Run("should start a draft resumed with a token whose hash is stored", func(t *testing.T) {
+		f := newDraftFixture(t)
+		session := f.start(t, f.answer(0, models.BoolValue(true)))
+		assert.True(t, strings.HasPrefix(session.ResumeToken, f.survey.ID.String()+"."+session.ID.String()+"."))
+		assert.Empty(t, session.TokenHash)
+		assert.Equal(t, models.DefaultWorkspace, session.WorkspaceID)
+		assert.Equal(t, 1, session.SurveyVersion)
+		assert.Equal(t, []models.Answer{f.answer(0, models.BoolValue(true))}, session.Answers)
+		assert.Equal(t, f.now, session.CreatedAt)
+		assert.Equal(t, f.now.Add(draftTTL), session.ExpiresAt)
+		stored, err := f.draftRepo.Get(models.DefaultWorkspace, session.ID)
+		require.NoError(t, err)
+		assert.Equal(t, hashToken(session.ResumeToken), stored.TokenHash)
+		assert.NotContains(t, stored.TokenHash, session.ResumeToken[strings.LastIndex(session.ResumeToken, ".")+1:])
+		other := f.start(t)
+		assert.NotEqual(t, session.ResumeToken, other.ResumeToken)
+		assert.Equal(t, []models.Answer{}, other.Answers)
+	})
+	t.Run("should not start drafts of surveys which do not accept responses", func(t *testing.T) {
+		f := newDraftFixture(t)
+		_, err := f.service.StartDraft(models.DefaultWorkspace, models.Draft{SurveyID: ksuid.New()})
+		assert.Equal(t, repositories.ErrNotFound, err)
+		_, err = f.service.StartDraft("other", models.Draft{SurveyID: f.survey.ID})
+		assert.Equal(t, repositories.ErrNotFound, err)
+		_, err = f.service.CloseSurvey(models.DefaultWorkspace, f.survey.ID)
+		require.NoError(t, err)
+		_, err = f.service.StartDraft(models.DefaultWorkspace, models.Draft{SurveyID: f.survey.ID})
+		assert.Equal(t, services.ErrSurveyNotPublished, err)
+	})
+	t.Run("should reject invalid first answers", func(t *testing.T) {
+		f := newDraftFixture(t)
+		_, err := f.service.StartDraft(models.DefaultWorkspace, models.Draft{SurveyID: f.survey.ID,
+			Answers: []models.Answer{f.answer(1, models.TextValue("soup"))}})
+		var validationErr *services.ValidationError
+		require.True(t, errors.As(err, &validationErr))
+		assert.Len(t, validationErr.Violations, 1)
+		assert.Empty(t, f.draftRepo.Entries())
+	})
+	t.Run("should return error when drafts are disabled", func(t *testing.T) {
+		surveyService := NewSurveyService(3, nil, nil, nil, nil)
+		_, err := surveyService.StartDraft(models.DefaultWorkspace, models.Draft{SurveyID: ksuid.New()})
+		assert.Equal(t, services.ErrDraftsDisabled, err)
+		_, err = surveyService.GetDraft(models.DefaultWorkspace, "token")
+		assert.Equal(t, services.ErrDraftsDisabled, err)
+	})
+}
+
+func TestSurveyService_UpdateDraft(t *testing.T) {
+	t.Run("should replace, add and clear answers and push the expiry back", func(t *testing.T) {
+		f := newDraftFixture(t)
+		session := f.start(t, f.answer(0, models.BoolValue(true)), f.answer(1, models.TextValue("pizza")))
+		f.advance(time.Hour)
+		draft, err := f.service.UpdateDraft(models.DefaultWorkspace, session.ResumeToken, []models.Answer{
+			f.answer(2, models.NumberValue(4)),
+			f.answer(0, models.BoolValue(false)),
+			f.answer(1, models.Value{}),
+		})
+		require.NoError(t, err)
+		expected := []models.Answer{f.answer(0, models.BoolValue(false)), f.answer(2, models.NumberValue(4))}
+		assert.Equal(t, expected, draft.Answers)
+		assert.Equal(t, f.now, draft.UpdatedAt)
+		assert.Equal(t, f.now.Add(draftTTL), draft.ExpiresAt)
+		assert.Empty(t, draft.TokenHash)
+		resumed, err := f.service.GetDraft(models.DefaultWorkspace, session.ResumeToken)
+		require.NoError(t, err)
+		assert.Equal(t, *draft, *resumed)
+	})
+	t.Run("should keep the draft when any answer is invalid", func(t *testing.T) {
+		f := newDraftFixture(t)
+		session := f.start(t, f.answer(0, models.BoolValue(true)))
+		unknown := ksuid.New()
+		_, err := f.service.UpdateDraft(models.DefaultWorkspace, session.ResumeToken, []models.Answer{
+			f.answer(0, models.BoolValue(false)),
+			f.answer(2, models.NumberValue(9)),
+			{QuestionID: unknown, Answer: models.BoolValue(true)},
+			f.answer(0, models.BoolValue(true)),
+		})
+		var validationErr *services.ValidationError
+		require.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, []services.Violation{
+			{QuestionID: f.survey.Questions[2].ID, Message: "answer must be between 1 and 5"},
+			{QuestionID: unknown, Message: "question is not part of the survey"},
+			{QuestionID: f.survey.Questions[0].ID, Message: "question is answered more than once"},
+		}, validationErr.Violations)
+		draft, err := f.service.GetDraft(models.DefaultWorkspace, session.ResumeToken)
+		require.NoError(t, err)
+		assert.Equal(t, session.Answers, draft.Answers)
+	})
+	t.Run("should reject tokens which do not match a draft", func(t *testing.T) {
+		f := newDraftFixture(t)
+		session := f.start(t)
+		other := f.start(t)
+		secret := session.ResumeToken[strings.LastIndex(session.ResumeToken, ".")+1:]
+		for _, token := range []string{
+			"",
+			"garbage",
+			f.survey.ID.String() + "." + session.ID.String(),
+			f.survey.ID.String() + "." + session.ID.String() + ".00",
+			f.survey.ID.String() + "." + ksuid.New().String() + "." + secret,
+			ksuid.New().String() + "." + session.ID.String() + "." + secret,
+			f.survey.ID.String() + "." + other.ID.String() + "." + secret,
+		} {
+			_, err := f.service.GetDraft(models.DefaultWorkspace, token)
+			assert.Equal(t, services.ErrInvalidResumeToken, err, token)
+			_, err = f.service.UpdateDraft(models.DefaultWorkspace, token, nil)
+			assert.Equal(t, services.ErrInvalidResumeToken, err, token)
+			_, err = f.service.SubmitDraft(models.DefaultWorkspace, token)
+			assert.Equal(t, services.ErrInvalidResumeToken, err, token)
+		}
+		_, err := f.service.GetDraft("other", session.ResumeToken)
+		assert.Equal(t, services.ErrInvalidResumeToken, err)
+	})
+	t.Run("should expire drafts which are not updated for the ttl", func(t *testing.T) {
+		f := newDraftFixture(t)
+		session := f.start(t, f.answer(0, models.BoolValue(true)))
+		f.advance(draftTTL - time.Second)
+		_, err := f.service.UpdateDraft(models.DefaultWorkspace, session.ResumeToken, nil)
+		require.NoError(t, err)
+		f.advance(draftTTL - time.Second)
+		_, err = f.service.GetDraft(models.DefaultWorkspace, session.ResumeToken)
+		require.NoError(t, err)
+		f.advance(time.Second)
+		_, err = f.service.GetDraft(models.DefaultWorkspace, session.ResumeToken)
+		assert.Equal(t, services.ErrDraftExpired, err)
+		_, err = f.service.UpdateDraft(models.DefaultWorkspace, session.ResumeToken, nil)
+		assert.Equal(t, services.ErrDraftExpired, err)
+		_, err = f.service.SubmitDraft(models.DefaultWorkspace, session.ResumeToken)
+		assert.Equal(t, services.ErrDraftExpired, err)
+	})
+}
+
+func TestSurveyService_SubmitDraft(t *testing.T) {
+	t.Run("should save the answers of the draft as a response with the id of the draft once", func(t *testing.T) {
+		f := newDraftFixture(t)
+		session := f.start(t, f.answer(1, models.TextValue("pizza")))
+		_, err := f.service.SubmitDraft(models.DefaultWorkspace, session.ResumeToken)
+		var validationErr *services.ValidationError
+		require.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, []services.Violation{{QuestionID: f.survey.Questions[0].ID, Message: "question is required"}}, validationErr.Violations)
+		_, err = f.service.UpdateDraft(models.DefaultWorkspace, session.ResumeToken, []models.Answer{f.answer(0, models.BoolValue(true))})
+		require.NoError(t, err)
+		f.advance(time.Minute)
+		response, err := f.service.SubmitDraft(models.DefaultWorkspace, session.ResumeToken)
+		require.NoError(t, err)
+		assert.Equal(t, session.ID, response.ID)
+		assert.Equal(t, f.now, response.CreatedAt)
+		assert.Equal(t, []models.Answer{f.answer(1, models.TextValue("pizza")), f.answer(0, models.BoolValue(true))}, response.Answers)
+		page, err := f.service.GetResponses(models.DefaultWorkspace, models.ResponseQuery{SurveyID: f.survey.ID}, models.VersionScope{})
+		require.NoError(t, err)
+		assert.Equal(t, []models.Response{*response}, page.Responses)
+		_, err = f.service.SubmitDraft(models.DefaultWorkspace, session.ResumeToken)
+		assert.Equal(t, repositories.ErrDraftSubmitted, err)
+		_, err = f.service.UpdateDraft(models.DefaultWorkspace, session.ResumeToken, nil)
+		assert.Equal(t, repositories.ErrDraftSubmitted, err)
+		f.advance(2 * draftTTL)
+		draft, err := f.service.GetDraft(models.DefaultWorkspace, session.ResumeToken)
+		require.NoError(t, err)
+		assert.Equal(t, response.CreatedAt, *draft.SubmittedAt)
+	})
+	t.Run("should not submit drafts to surveys which stopped accepting responses", func(t *testing.T) {
+		f := newDraftFixture(t)
+		session := f.start(t, f.answer(0, models.BoolValue(true)))
+		_, err := f.service.CloseSurvey(models.DefaultWorkspace, f.survey.ID)
+		require.NoError(t, err)
+		_, err = f.service.SubmitDraft(models.DefaultWorkspace, session.ResumeToken)
+		assert.Equal(t, services.ErrSurveyNotPublished, err)
+		draft, err := f.service.GetDraft(models.DefaultWorkspace, session.ResumeToken)
+		require.NoError(t, err)
+		assert.Nil(t, draft.SubmittedAt)
+	})
+}
+
+func TestSurveyService_GetFunnel(t *testing.T) {
+	t.Run("should count the drafts which started, are in progress, expired and were submitted", func(t *testing.T) {
+		f := newDraftFixture(t)
+		f.start(t)
+		f.advance(2 * draftTTL)
+		f.start(t, f.answer(1, models.TextValue("pizza")))
+		submitted := f.start(t, f.answer(0, models.BoolValue(true)), f.answer(1, models.TextValue("pasta")))
+		_, err := f.service.SubmitDraft(models.DefaultWorkspace, submitted.ResumeToken)
+		require.NoError(t, err)
+		f.start(t, f.answer(0, models.BoolValue(false)))
+		funnel, err := f.service.GetFunnel(models.DefaultWorkspace, f.survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, f.survey.ID, funnel.SurveyID)
+		assert.Equal(t, 4, funnel.Started)
+		assert.Equal(t, 2, funnel.InProgress)
+		assert.Equal(t, 1, funnel.Expired)
+		assert.Equal(t, 1, funnel.Submitted)
+		assert.Equal(t, 25.0, funnel.CompletionRate)
+		reached := make([]int, 0, len(funnel.Questions))
+		for _, step := range funnel.Questions {
+			reached = append(reached, step.Reached)
+		}
+		assert.Equal(t, []int{2, 2, 0, 0}, reached)
+		_, err = f.service.GetFunnel(models.DefaultWorkspace, ksuid.New())
+		assert.Equal(t, repositories.ErrNotFound, err)
+	})
+	t.Run("should keep counting the expired drafts once they are deleted", func(t *testing.T) {
+		f := newDraftFixture(t)
+		expired := f.start(t, f.answer(1, models.TextValue("pizza")))
+		f.start(t, f.answer(0, models.BoolValue(true)))
+		f.advance(draftTTL / 2)
+		f.start(t, f.answer(1, models.TextValue("pasta")))
+		f.advance(draftTTL * 3 / 4)
+		before, err := f.service.GetFunnel(models.DefaultWorkspace, f.survey.ID)
+		require.NoError(t, err)
+		deleted, err := f.service.DeleteExpiredDrafts()
+		require.NoError(t, err)
+		assert.Equal(t, 2, deleted)
+		assert.Len(t, f.draftRepo.Entries(), 1)
+		_, err = f.service.GetDraft(models.DefaultWorkspace, expired.ResumeToken)
+		assert.Equal(t, services.ErrInvalidResumeToken, err)
+		after, err := f.service.GetFunnel(models.DefaultWorkspace, f.survey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, before, after)
+		assert.Equal(t, 3, after.Started)
+		assert.Equal(t, 2, after.Expired)
+		assert.Equal(t, 1, after.InProgress)
+	})
+}
+
+func TestSurveyService_WatchDrafts(t *testing.T) {
+	t.Run("should delete the expired drafts right away and on every interval until context is done", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		timeGeneratorMock.EXPECT().Now().Return(now).AnyTimes()
+		mockDraftRepo := repositories_mock.NewMockDraftRepoInterface(ctrl)
+		deleted := make(chan struct{}, 2)
+		mockDraftRepo.EXPECT().DeleteExpired(now).DoAndReturn(func(time.Time) (int, error) {
+			select {
+			case deleted <- struct{}{}:
+			default:
+			}
+			return 1, nil
+		}).MinTimes(2)
+		surveyService := NewSurveyService(3, nil, nil, nil, timeGeneratorMock)
+		surveyService.SetDrafts(mockDraftRepo, draftTTL)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			surveyService.WatchDrafts(ctx, time.Millisecond)
+			close(done)
+		}()
+		<-deleted
+		<-deleted
+		cancel()
+		<-done
+	})
+}
+
+func TestSurveyService_DeleteSurvey_Drafts(t *testing.T) {
+	t.Run("should delete the drafts of the survey so that they can no longer be resumed", func(t *testing.T) {
+		f := newDraftFixture(t)
+		session := f.start(t, f.answer(1, models.TextValue("pizza")))
+		require.NoError(t, f.service.DeleteSurvey(models.DefaultWorkspace, f.survey.ID, false))
+		_, err := f.service.GetDraft(models.DefaultWorkspace, session.ResumeToken)
+		assert.Equal(t, services.ErrInvalidResumeToken, err)
+		_, err = f.service.UpdateDraft(models.DefaultWorkspace, session.ResumeToken, nil)
+		assert.Equal(t, services.ErrInvalidResumeToken, err)
+		_, err = f.service.GetFunnel(models.DefaultWorkspace, f.survey.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Empty(t, f.draftRepo.Entries())
+	})
+	t.Run("should return error when the drafts of the deleted survey cannot be deleted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockDraftRepo := repositories_mock.NewMockDraftRepoInterface(ctrl)
+		surveyID := ksuid.New()
+		gomock.InOrder(
+			mockSurveyRepo.EXPECT().Delete(models.DefaultWorkspace, surveyID, true).Return(nil),
+			mockDraftRepo.EXPECT().DeleteBySurveyID(models.DefaultWorkspace, surveyID).Return(errors.New("disk full")),
+		)
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
+		surveyService.SetDrafts(mockDraftRepo, draftTTL)
+		assert.EqualError(t, surveyService.DeleteSurvey(models.DefaultWorkspace, surveyID, true), "disk full")
+	})
+}
+
+func TestSurveyService_GetResults_Drafts(t *testing.T) {
+	t.Run("should only tally the drafts in progress when they are included", func(t *testing.T) {
+		f := newDraftFixture(t)
+		f.start(t, f.answer(0, models.BoolValue(false)))
+		f.advance(2 * draftTTL)
+		submitted := f.start(t, f.answer(0, models.BoolValue(true)))
+		_, err := f.service.SubmitDraft(models.DefaultWorkspace, submitted.ResumeToken)
+		require.NoError(t, err)
+		f.start(t, f.answer(0, models.BoolValue(false)), f.answer(1, models.TextValue("pizza")))
+		results, err := f.service.GetResults(models.DefaultWorkspace, f.survey.ID, models.ResultsFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, results.TotalResponses)
+		assert.Zero(t, results.Drafts)
+		assert.Equal(t, 1, results.Questions[0].Answered)
+		results, err = f.service.GetResults(models.DefaultWorkspace, f.survey.ID, models.ResultsFilter{IncludeDrafts: true})
+		require.NoError(t, err)
+		assert.Equal(t, 2, results.TotalResponses)
+		assert.Equal(t, 1, results.Drafts)
+		assert.Equal(t, []models.OptionTally{
+			{Option: "yes", Count: 1, Percentage: 50},
+			{Option: "no", Count: 1, Percentage: 50},
+		}, results.Questions[0].Options)
+		assert.Equal(t, 1, results.Questions[1].Answered)
+		assert.Equal(t, []models.DayCount{{Date: "2021-06-03", Count: 2}}, results.PerDay)
+		results, err = f.service.GetResults(models.DefaultWorkspace, f.survey.ID, models.ResultsFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, results.TotalResponses)
+	})
+}
@@ -0,0 +1,73 @@
+package surveyservice
+
+import (
+	"github.com/segmentio/ksuid"
+	"survey-platform/internal/models"
+	"survey-platform/pkg/export"
+)
+
+// the columns written before the question columns of an export, imports read them back
+const (
+	columnResponseID    = "response_id"
+	columnCreatedAt     = "created_at"
+	columnSurveyVersion = "survey_version"
+)
+
+var exportColumns = []export.Column{
+	{Key: columnResponseID, Title: columnResponseID},
+	{Key: columnCreatedAt, Title: columnCreatedAt},
+	{Key: columnSurveyVersion, Title: columnSurveyVersion},
+}
+
+// ExportResponses streams the responses to a survey, one row per response with a column per question of the current survey
+// question columns are headed by the question text and keyed by the question id, answers to removed questions are left out
+// newWriter is only called once the survey is found, so nothing is written for a missing survey
+func (s *SurveyService) ExportResponses(workspace models.WorkspaceID, surveyID ksuid.KSUID, newWriter func() (export.Writer, error)) error {
+	survey, err := s.surveyRepo.Get(workspace, surveyID)
+	if err != nil {
+		return err
+	}
+	writer, err := newWriter()
+	if err != nil {
+		return err
+	}
+	columns := append([]export.Column(nil), exportColumns...)
+	positions := make(map[ksuid.KSUID]int, len(survey.Questions))
+	for _, question := range survey.Questions {
+		positions[question.ID] = len(columns)
+		columns = append(columns, export.Column{Key: question.ID.String(), Title: question.Question})
+	}
+	if err = writer.WriteHeader(columns); err != nil {
+		return err
+	}
+	err = s.responseRepo.EachBySurveyID(workspace, surveyID, func(response models.Response) error {
+		cells := make([]interface{}, len(columns))
+		cells[0], cells[1], cells[2] = response.ID.String(), response.CreatedAt, float64(response.AnsweredVersion())
+		for _, answer := range response.Answers {
+			if position, ok := positions[answer.QuestionID]; ok {
+				cells[position] = exportCell(answer.Answer)
+			}
+		}
+		return writer.WriteRow(cells)
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// exportCell returns the cell holding an answer
+func exportCell(value models.Value) interface{} {
+	switch value.Kind {
+	case models.ValueBool:
+		return value.Bool
+	case models.ValueNumber:
+		return value.Number
+	case models.ValueText:
+		return value.Text
+	case models.ValueChoices:
+		return value.Choices
+	default:
+		return nil
+	}
+}
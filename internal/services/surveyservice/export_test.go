@@ -0,0 +1,65 @@
+package surveyservice
+
+import (
+	"bytes"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/repositories_mock"
+	"survey-platform/pkg/export"
+	"testing"
+	"time"
+)
+
+func TestSurveyService_ExportResponses(t *testing.T) {
+	survey := resultsSurvey()
+	createdAt := time.Date(2021, time.June, 1, 10, 0, 0, 0, time.UTC)
+	response := resultsResponse(survey, createdAt, true, "pizza", 5, 10)
+	response.SurveyVersion = 1
+	t.Run("should write a row per response with a column per question", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, survey.ID).Return(survey, nil)
+		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		mockResponseRepo.EXPECT().EachBySurveyID(models.DefaultWorkspace, survey.ID, gomock.Any()).DoAndReturn(func(_ models.WorkspaceID, _ interface{}, fn func(models.Response) error) error {
+			return fn(response)
+		})
+		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, nil, nil)
+		var buf bytes.Buffer
+		err := surveyService.ExportResponses(models.DefaultWorkspace, survey.ID, func() (export.Writer, error) {
+			return export.NewWriter(export.FormatCSV, &buf)
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "response_id,created_at,survey_version,is this place good?,what did you eat?,how was it?,would you recommend us?\n"+
+			response.ID.String()+",2021-06-01T10:00:00Z,1,true,pizza,5,10\n", buf.String())
+	})
+	t.Run("should stop at the first error while streaming", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		expectedErr := errors.New("something went wrong")
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, survey.ID).Return(survey, nil)
+		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		mockResponseRepo.EXPECT().EachBySurveyID(models.DefaultWorkspace, survey.ID, gomock.Any()).Return(expectedErr)
+		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, nil, nil)
+		err := surveyService.ExportResponses(models.DefaultWorkspace, survey.ID, func() (export.Writer, error) {
+			return export.NewWriter(export.FormatCSV, &bytes.Buffer{})
+		})
+		assert.Equal(t, expectedErr, err)
+	})
+	t.Run("should not create a writer when survey does not exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, survey.ID).Return(nil, repositories.ErrNotFound)
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
+		err := surveyService.ExportResponses(models.DefaultWorkspace, survey.ID, func() (export.Writer, error) {
+			t.Fatal("writer should not be created")
+			return nil, nil
+		})
+		assert.Equal(t, repositories.ErrNotFound, err)
+	})
+}
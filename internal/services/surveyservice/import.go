@@ -0,0 +1,490 @@
+package surveyservice
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/segmentio/ksuid"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/services"
+	"survey-platform/pkg/export"
+	"time"
+)
+
+// Import reads surveys and responses from r in the format of options and stores the ones following the survey rules in workspace
+// rejected surveys and responses are listed in the report and do not stop the import, while a file which cannot be read
+// at all returns services.ErrMalformedImport, csv imports of a survey which does not exist return repositories.ErrNotFound
+// surveys keep their status and timestamps, responses are checked against the version they answered
+// but not against the response window or quota of the survey, as they were given before the import
+// responses are only imported into surveys of workspace, and dumps of several workspaces are imported into workspace
+func (s *SurveyService) Import(workspace models.WorkspaceID, r io.Reader, options models.ImportOptions) (*models.ImportReport, error) {
+	im := &importer{
+		service:     s,
+		workspace:   workspace,
+		options:     options,
+		report:      &models.ImportReport{DryRun: options.DryRun},
+		now:         s.timeGenerator.Now(),
+		versions:    make(map[ksuid.KSUID][]models.Survey),
+		imported:    make(map[ksuid.KSUID]bool),
+		surveyIDs:   make(map[ksuid.KSUID]ksuid.KSUID),
+		rejected:    make(map[ksuid.KSUID]bool),
+		questionIDs: make(map[ksuid.KSUID]ksuid.KSUID),
+		responseIDs: make(map[ksuid.KSUID]map[ksuid.KSUID]bool),
+	}
+	var err error
+	switch options.Format {
+	case models.ImportDump:
+		err = im.dump(r)
+	case models.ImportNDJSON:
+		err = im.ndjson(r)
+	case models.ImportCSV:
+		err = im.csv(r)
+	default:
+		err = fmt.Errorf("%w %q", services.ErrUnknownImportFormat, options.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return im.report, nil
+}
+
+// importer holds the state of a single import
+type importer struct {
+	service   *SurveyService
+	workspace models.WorkspaceID
+	options   models.ImportOptions
+	report    *models.ImportReport
+	now       time.Time
+	// versions caches the versions of the surveys responses are imported into, imported marks the ones imported from the file
+	versions map[ksuid.KSUID][]models.Survey
+	imported map[ksuid.KSUID]bool
+	// surveyIDs and questionIDs map the ids in the file to the stored ones, rejected holds the surveys left out
+	surveyIDs   map[ksuid.KSUID]ksuid.KSUID
+	rejected    map[ksuid.KSUID]bool
+	questionIDs map[ksuid.KSUID]ksuid.KSUID
+	// responseIDs holds the stored and imported response ids of each survey, it is only filled when ids are preserved
+	responseIDs map[ksuid.KSUID]map[ksuid.KSUID]bool
+}
+
+func (im *importer) reject(importErr models.ImportError) {
+	im.report.Errors = append(im.report.Errors, importErr)
+}
+
+// dump imports the surveys of a DBEntry oldest first, followed by their responses
+// the surveys of every workspace in the dump are imported, into the workspace of the import
+func (im *importer) dump(r io.Reader) error {
+	var entry models.DBEntry
+	if err := json.NewDecoder(r).Decode(&entry); err != nil {
+		return fmt.Errorf("%w: %v", services.ErrMalformedImport, err)
+	}
+	entry.Migrate()
+	dumpedSurveys, dumpedVersions, dumpedResponses := entry.Flatten()
+	surveys := make([]models.Survey, 0, len(dumpedSurveys))
+	for _, survey := range dumpedSurveys {
+		surveys = append(surveys, survey)
+	}
+	sort.Slice(surveys, func(i, j int) bool {
+		if !surveys[i].CreatedAt.Equal(surveys[j].CreatedAt) {
+			return surveys[i].CreatedAt.Before(surveys[j].CreatedAt)
+		}
+		return bytes.Compare(surveys[i].ID.Bytes(), surveys[j].ID.Bytes()) < 0
+	})
+	for _, survey := range surveys {
+		if err := im.importSurvey(dumpVersions(survey, dumpedVersions[survey.ID])); err != nil {
+			return err
+		}
+	}
+	surveyIDs := make([]ksuid.KSUID, 0, len(dumpedResponses))
+	for surveyID := range dumpedResponses {
+		surveyIDs = append(surveyIDs, surveyID)
+	}
+	sort.Slice(surveyIDs, func(i, j int) bool {
+		return bytes.Compare(surveyIDs[i].Bytes(), surveyIDs[j].Bytes()) < 0
+	})
+	for _, surveyID := range surveyIDs {
+		for _, response := range dumpedResponses[surveyID] {
+			if response.SurveyID.IsNil() {
+				response.SurveyID = surveyID
+			}
+			if err := im.importResponse(0, response); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dumpVersions returns the versions of a dumped survey oldest first, ending with the survey itself
+func dumpVersions(survey models.Survey, stored []models.Survey) []models.Survey {
+	versions := make([]models.Survey, 0, len(stored)+1)
+	for _, version := range stored {
+		if version.CurrentVersion() < survey.CurrentVersion() {
+			versions = append(versions, version)
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].CurrentVersion() < versions[j].CurrentVersion()
+	})
+	return append(versions, survey)
+}
+
+// importSurvey validates every version of a survey and stores them, generating new ids unless they are preserved
+// the error returned is a failure of the repo, rejected surveys are reported
+func (im *importer) importSurvey(versions []models.Survey) error {
+	original := versions[len(versions)-1].ID
+	rejectErr := func(err error) error {
+		im.rejected[original] = true
+		im.reject(models.ImportError{SurveyID: original.String(), Message: err.Error()})
+		return nil
+	}
+	for i := range versions {
+		if !versions[i].State().Valid() {
+			return rejectErr(fmt.Errorf("version %d: unknown status %q", versions[i].CurrentVersion(), versions[i].Status))
+		}
+		if err := im.service.validateSurvey(&versions[i]); err != nil {
+			return rejectErr(fmt.Errorf("version %d: %w", versions[i].CurrentVersion(), err))
+		}
+	}
+	id := original
+	if im.options.PreserveIDs && !id.IsNil() {
+		if _, err := im.service.surveyRepo.Get(im.workspace, id); err == nil {
+			return rejectErr(errors.New("survey already exists"))
+		} else if err != repositories.ErrNotFound {
+			return err
+		}
+	} else {
+		id = im.service.idGenerator.Generate()
+	}
+	generated := make(map[ksuid.KSUID]ksuid.KSUID)
+	for i := range versions {
+		versions[i].ID = id
+		versions[i].WorkspaceID = im.workspace
+		for j := range versions[i].Questions {
+			question := &versions[i].Questions[j]
+			if !question.ID.IsNil() && im.options.PreserveIDs {
+				continue
+			}
+			if _, ok := generated[question.ID]; !ok || question.ID.IsNil() {
+				generated[question.ID] = im.service.idGenerator.Generate()
+			}
+			question.ID = generated[question.ID]
+		}
+		if !im.options.PreserveIDs {
+			remapRules(versions[i].Rules, generated)
+		}
+		if versions[i].CreatedAt.IsZero() {
+			versions[i].CreatedAt = im.now
+		}
+		if versions[i].UpdatedAt.IsZero() {
+			versions[i].UpdatedAt = versions[i].CreatedAt
+		}
+	}
+	if !im.options.DryRun {
+		// the id of a preserved survey may be taken in another workspace
+		if _, err := im.service.surveyRepo.Create(&versions[0]); err == repositories.ErrAlreadyExists {
+			return rejectErr(errors.New("survey already exists"))
+		} else if err != nil {
+			return err
+		}
+		for i := 1; i < len(versions); i++ {
+			if _, err := im.service.surveyRepo.Update(im.workspace, id, &versions[i]); err != nil {
+				return err
+			}
+		}
+		im.service.search.add(&versions[len(versions)-1])
+	}
+	for from, to := range generated {
+		if !from.IsNil() {
+			im.questionIDs[from] = to
+		}
+	}
+	im.surveyIDs[original] = id
+	im.versions[id] = versions
+	im.imported[id] = true
+	im.report.SurveysImported++
+	return nil
+}
+
+// ndjson imports a response from every line of r, blank lines are skipped
+func (im *importer) ndjson(r io.Reader) error {
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 {
+			var response models.Response
+			if decodeErr := json.Unmarshal(trimmed, &response); decodeErr != nil {
+				im.reject(models.ImportError{Line: line, Message: "malformed response: " + decodeErr.Error()})
+			} else if importErr := im.importResponse(line, response); importErr != nil {
+				return importErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// csv imports a response from every row of r answering the survey in the options, rows are numbered from the header
+// the header names the column of every answer with the question id, or the question text of the current survey,
+// response_id, created_at and survey_version columns are read as written by exports
+func (im *importer) csv(r io.Reader) error {
+	if im.options.SurveyID.IsNil() {
+		return services.ErrImportNeedsSurvey
+	}
+	versions, err := im.surveyVersions(im.options.SurveyID)
+	if err != nil {
+		return err
+	}
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("%w: %v", services.ErrMalformedImport, err)
+	}
+	columns, err := csvColumns(header, versions)
+	if err != nil {
+		return err
+	}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			im.reject(models.ImportError{Line: line, Message: parseErr.Err.Error()})
+			continue
+		} else if err != nil {
+			return err
+		}
+		response, err := csvResponse(columns, record)
+		if err != nil {
+			im.reject(models.ImportError{Line: line, Message: err.Error()})
+			continue
+		}
+		response.SurveyID = im.options.SurveyID
+		if err = im.importResponse(line, response); err != nil {
+			return err
+		}
+	}
+}
+
+// csvColumn is what a column of a csv import holds, the question is set for answer columns
+type csvColumn struct {
+	name     string
+	question *models.Question
+}
+
+// csvColumns matches the header of a csv import with the questions of every version of the survey, latest first
+func csvColumns(header []string, versions []models.Survey) ([]csvColumn, error) {
+	byID := make(map[ksuid.KSUID]*models.Question)
+	byText := make(map[string]*models.Question)
+	for i := len(versions) - 1; i >= 0; i-- {
+		for j := range versions[i].Questions {
+			question := &versions[i].Questions[j]
+			if _, ok := byID[question.ID]; !ok {
+				byID[question.ID] = question
+			}
+			if _, ok := byText[question.Question]; !ok && i == len(versions)-1 {
+				byText[question.Question] = question
+			}
+		}
+	}
+	columns := make([]csvColumn, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		columns[i].name = name
+		switch name {
+		case columnResponseID, columnCreatedAt, columnSurveyVersion:
+			continue
+		}
+		if id, err := ksuid.Parse(name); err == nil && byID[id] != nil {
+			columns[i].question = byID[id]
+		} else if byText[name] != nil {
+			columns[i].question = byText[name]
+		} else {
+			return nil, fmt.Errorf("%w: column %q is not a question of the survey", services.ErrMalformedImport, name)
+		}
+	}
+	return columns, nil
+}
+
+// csvResponse reads a response from a csv row, empty cells are left unanswered
+func csvResponse(columns []csvColumn, record []string) (models.Response, error) {
+	var response models.Response
+	for i, cell := range record {
+		if cell == "" {
+			continue
+		}
+		var err error
+		switch column := columns[i]; {
+		case column.question != nil:
+			var value models.Value
+			if value, err = csvValue(*column.question, cell); err == nil {
+				response.Answers = append(response.Answers, models.Answer{QuestionID: column.question.ID, Answer: value})
+			}
+		case column.name == columnResponseID:
+			response.ID, err = ksuid.Parse(cell)
+		case column.name == columnCreatedAt:
+			response.CreatedAt, err = time.Parse(time.RFC3339Nano, cell)
+		case column.name == columnSurveyVersion:
+			response.SurveyVersion, err = strconv.Atoi(cell)
+		}
+		if err != nil {
+			return response, fmt.Errorf("column %q: invalid value %q", columns[i].name, cell)
+		}
+	}
+	return response, nil
+}
+
+// csvValue reads the answer to question from a cell, multi choice answers are separated as in exports
+func csvValue(question models.Question, cell string) (models.Value, error) {
+	switch question.Kind() {
+	case models.QuestionYesNo:
+		switch strings.ToLower(cell) {
+		case yesOption:
+			return models.BoolValue(true), nil
+		case noOption:
+			return models.BoolValue(false), nil
+		}
+		b, err := strconv.ParseBool(cell)
+		return models.BoolValue(b), err
+	case models.QuestionRating, models.QuestionNPS, models.QuestionNumeric:
+		number, err := strconv.ParseFloat(cell, 64)
+		return models.NumberValue(number), err
+	case models.QuestionMultiChoice:
+		return models.ChoicesValue(strings.Split(cell, export.ListSeparator)...), nil
+	default:
+		return models.TextValue(cell), nil
+	}
+}
+
+// importResponse validates a response against the version it answered and stores it
+// the error returned is a failure of a repo, rejected responses are reported along with their line
+func (im *importer) importResponse(line int, response models.Response) error {
+	original := response.ID
+	rejectErr := func(message string) error {
+		importErr := models.ImportError{Line: line, SurveyID: response.SurveyID.String(), Message: message}
+		if !original.IsNil() {
+			importErr.ResponseID = original.String()
+		}
+		im.reject(importErr)
+		return nil
+	}
+	if response.SurveyID.IsNil() {
+		response.SurveyID = im.options.SurveyID
+	}
+	if response.SurveyID.IsNil() {
+		return rejectErr("response needs a survey_id")
+	}
+	if im.rejected[response.SurveyID] {
+		return rejectErr("survey was not imported")
+	}
+	if id, ok := im.surveyIDs[response.SurveyID]; ok {
+		response.SurveyID = id
+	}
+	versions, err := im.surveyVersions(response.SurveyID)
+	if err == repositories.ErrNotFound {
+		return rejectErr("survey not found")
+	} else if err != nil {
+		return err
+	}
+	if !im.imported[response.SurveyID] && versions[len(versions)-1].State() == models.SurveyArchived {
+		return rejectErr(services.ErrSurveyArchived.Error())
+	}
+	var survey *models.Survey
+	for i := range versions {
+		if versions[i].CurrentVersion() == response.AnsweredVersion() {
+			survey = &versions[i]
+		}
+	}
+	if survey == nil {
+		return rejectErr(fmt.Sprintf("survey has no version %d", response.AnsweredVersion()))
+	}
+	for i := range response.Answers {
+		if id, ok := im.questionIDs[response.Answers[i].QuestionID]; ok {
+			response.Answers[i].QuestionID = id
+		}
+	}
+	if err = validateResponse(survey, response); err != nil {
+		return rejectErr(err.Error())
+	}
+	if im.options.PreserveIDs && !response.ID.IsNil() {
+		seen, err := im.storedResponseIDs(response.SurveyID)
+		if err != nil {
+			return err
+		}
+		if seen[response.ID] {
+			return rejectErr("response already exists")
+		}
+		seen[response.ID] = true
+	} else {
+		response.ID = im.service.idGenerator.Generate()
+	}
+	response.WorkspaceID = im.workspace
+	response.SurveyVersion = survey.CurrentVersion()
+	// invitations are not part of imports, imported responses are anonymous
+	response.InvitationID, response.RespondentID = nil, nil
+	if response.CreatedAt.IsZero() {
+		response.CreatedAt = im.now
+	}
+	if !im.options.DryRun {
+		created, err := im.service.responseRepo.Create(&response)
+		if err == repositories.ErrAlreadyExists {
+			return rejectErr("response already exists")
+		}
+		if err != nil {
+			return err
+		}
+		im.service.results.add(survey, *created)
+	}
+	im.report.ResponsesImported++
+	return nil
+}
+
+// surveyVersions returns the versions of a survey, reading them from the repo for surveys which are not part of the file
+func (im *importer) surveyVersions(id ksuid.KSUID) ([]models.Survey, error) {
+	if versions, ok := im.versions[id]; ok {
+		return versions, nil
+	}
+	versions, err := im.service.surveyRepo.GetVersions(im.workspace, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, repositories.ErrNotFound
+	}
+	im.versions[id] = versions
+	return versions, nil
+}
+
+// storedResponseIDs returns the ids of the responses of a survey, reading the stored ones the first time
+func (im *importer) storedResponseIDs(surveyID ksuid.KSUID) (map[ksuid.KSUID]bool, error) {
+	if seen, ok := im.responseIDs[surveyID]; ok {
+		return seen, nil
+	}
+	seen := make(map[ksuid.KSUID]bool)
+	if !im.imported[surveyID] {
+		err := im.service.responseRepo.EachBySurveyID(im.workspace, surveyID, func(response models.Response) error {
+			seen[response.ID] = true
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	im.responseIDs[surveyID] = seen
+	return seen, nil
+}
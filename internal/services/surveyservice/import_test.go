@@ -0,0 +1,271 @@
+package surveyservice
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/repositories_mock"
+	"survey-platform/internal/services"
+	"survey-platform/pkg/idgenerator/idgenerator_mock"
+	"survey-platform/pkg/timegenerator/timegenerator_mock"
+	"testing"
+	"time"
+)
+
+// importMocks returns the repo mocks of an import along with a service generating fresh ids at a fixed time
+func importMocks(ctrl *gomock.Controller, now time.Time) (*repositories_mock.MockSurveyRepoInterface,
+	*repositories_mock.MockResponseRepoInterface, *SurveyService) {
+	mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+	mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+	mockIDGenerator := idgenerator_mock.NewMockIDGenerator(ctrl)
+	mockIDGenerator.EXPECT().Generate().DoAndReturn(ksuid.New).AnyTimes()
+	timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+	timeGeneratorMock.EXPECT().Now().Return(now)
+	return mockSurveyRepo, mockResponseRepo, NewSurveyService(4, mockSurveyRepo, mockResponseRepo, mockIDGenerator, timeGeneratorMock)
+}
+
+func TestSurveyService_Import_Dump(t *testing.T) {
+	now := time.Date(2021, time.June, 3, 0, 0, 0, 0, time.UTC)
+	newDump := func(t *testing.T) (*models.Survey, models.Response, string) {
+		survey := resultsSurvey()
+		survey.Name = "restaurant"
+		response := resultsResponse(survey, time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC), true, "pizza", 4, 9)
+		response.SurveyVersion = 1
+		data, err := json.Marshal(models.DBEntry{
+			Surveys:   map[ksuid.KSUID]models.Survey{survey.ID: *survey},
+			Responses: map[ksuid.KSUID][]models.Response{survey.ID: {response}},
+		})
+		require.NoError(t, err)
+		return survey, response, string(data)
+	}
+	t.Run("should store surveys and responses under generated ids", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		survey, response, dump := newDump(t)
+		mockSurveyRepo, mockResponseRepo, surveyService := importMocks(ctrl, now)
+		var stored *models.Survey
+		mockSurveyRepo.EXPECT().Create(gomock.Any()).DoAndReturn(func(created *models.Survey) (*models.Survey, error) {
+			stored = created
+			return created, nil
+		})
+		mockResponseRepo.EXPECT().Create(gomock.Any()).DoAndReturn(func(created *models.Response) (*models.Response, error) {
+			assert.Equal(t, stored.ID, created.SurveyID)
+			assert.NotEqual(t, response.ID, created.ID)
+			assert.Equal(t, stored.Questions[0].ID, created.Answers[0].QuestionID)
+			assert.Equal(t, response.CreatedAt, created.CreatedAt)
+			return created, nil
+		})
+		report, err := surveyService.Import(models.DefaultWorkspace, strings.NewReader(dump), models.ImportOptions{Format: models.ImportDump})
+		assert.NoError(t, err)
+		assert.Equal(t, &models.ImportReport{SurveysImported: 1, ResponsesImported: 1}, report)
+		assert.NotEqual(t, survey.ID, stored.ID)
+		assert.NotEqual(t, survey.Questions[0].ID, stored.Questions[0].ID)
+		assert.Equal(t, models.SurveyPublished, stored.Status)
+	})
+	t.Run("should keep the ids and reject surveys which already exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		survey, response, dump := newDump(t)
+		mockSurveyRepo, _, surveyService := importMocks(ctrl, now)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, survey.ID).Return(survey, nil)
+		report, err := surveyService.Import(models.DefaultWorkspace, strings.NewReader(dump), models.ImportOptions{Format: models.ImportDump, PreserveIDs: true})
+		assert.NoError(t, err)
+		assert.Equal(t, []models.ImportError{
+			{SurveyID: survey.ID.String(), Message: "survey already exists"},
+			{SurveyID: survey.ID.String(), ResponseID: response.ID.String(), Message: "survey was not imported"},
+		}, report.Errors)
+	})
+	t.Run("should import the surveys of every dumped workspace into the workspace of the import", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		survey := resultsSurvey()
+		survey.Name = "restaurant"
+		response := resultsResponse(survey, time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC), true, "pizza", 4, 9)
+		data, err := json.Marshal(models.DBEntry{Workspaces: map[models.WorkspaceID]*models.WorkspaceEntry{
+			"other": {
+				Surveys:   map[ksuid.KSUID]models.Survey{survey.ID: *survey},
+				Responses: map[ksuid.KSUID][]models.Response{survey.ID: {response}},
+			},
+		}})
+		require.NoError(t, err)
+		mockSurveyRepo, mockResponseRepo, surveyService := importMocks(ctrl, now)
+		mockSurveyRepo.EXPECT().Create(gomock.Any()).DoAndReturn(func(created *models.Survey) (*models.Survey, error) {
+			assert.Equal(t, models.WorkspaceID("acme"), created.WorkspaceID)
+			return created, nil
+		})
+		mockResponseRepo.EXPECT().Create(gomock.Any()).DoAndReturn(func(created *models.Response) (*models.Response, error) {
+			assert.Equal(t, models.WorkspaceID("acme"), created.WorkspaceID)
+			return created, nil
+		})
+		report, err := surveyService.Import("acme", strings.NewReader(string(data)), models.ImportOptions{Format: models.ImportDump})
+		assert.NoError(t, err)
+		assert.Equal(t, &models.ImportReport{SurveysImported: 1, ResponsesImported: 1}, report)
+	})
+	t.Run("should reject preserved ids taken in another workspace", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		survey, response, dump := newDump(t)
+		mockSurveyRepo, _, surveyService := importMocks(ctrl, now)
+		mockSurveyRepo.EXPECT().Get(models.WorkspaceID("acme"), survey.ID).Return(nil, repositories.ErrNotFound)
+		mockSurveyRepo.EXPECT().Create(gomock.Any()).Return(nil, repositories.ErrAlreadyExists)
+		report, err := surveyService.Import("acme", strings.NewReader(dump), models.ImportOptions{Format: models.ImportDump, PreserveIDs: true})
+		assert.NoError(t, err)
+		assert.Equal(t, []models.ImportError{
+			{SurveyID: survey.ID.String(), Message: "survey already exists"},
+			{SurveyID: survey.ID.String(), ResponseID: response.ID.String(), Message: "survey was not imported"},
+		}, report.Errors)
+	})
+	t.Run("should validate without storing anything on a dry run", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		survey, _, dump := newDump(t)
+		mockSurveyRepo, _, surveyService := importMocks(ctrl, now)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, survey.ID).Return(nil, repositories.ErrNotFound)
+		report, err := surveyService.Import(models.DefaultWorkspace, strings.NewReader(dump), models.ImportOptions{Format: models.ImportDump, DryRun: true, PreserveIDs: true})
+		assert.NoError(t, err)
+		assert.Equal(t, &models.ImportReport{DryRun: true, SurveysImported: 1, ResponsesImported: 1}, report)
+	})
+	t.Run("should reject surveys breaking the survey rules", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		survey := resultsSurvey()
+		survey.Name = "restaurant"
+		survey.Questions[1].Options = []string{"pizza"}
+		data, err := json.Marshal(models.DBEntry{Surveys: map[ksuid.KSUID]models.Survey{survey.ID: *survey}})
+		require.NoError(t, err)
+		_, _, surveyService := importMocks(ctrl, now)
+		report, err := surveyService.Import(models.DefaultWorkspace, strings.NewReader(string(data)), models.ImportOptions{Format: models.ImportDump})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, report.SurveysImported)
+		assert.Equal(t, "version 1: invalid survey: question 2: choice question needs at least 2 options", report.Errors[0].Message)
+	})
+	t.Run("should return error for a malformed dump", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		_, _, surveyService := importMocks(ctrl, now)
+		report, err := surveyService.Import(models.DefaultWorkspace, strings.NewReader("{"), models.ImportOptions{Format: models.ImportDump})
+		assert.True(t, errors.Is(err, services.ErrMalformedImport))
+		assert.Nil(t, report)
+	})
+}
+
+func TestSurveyService_Import_NDJSON(t *testing.T) {
+	now := time.Date(2021, time.June, 3, 0, 0, 0, 0, time.UTC)
+	t.Run("should import valid lines and report the others", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		survey := resultsSurvey()
+		valid := resultsResponse(survey, time.Time{}, false, "pasta", 2, 5)
+		valid.SurveyID = ksuid.Nil
+		invalid := resultsResponse(survey, time.Time{}, false, "soup", 2, 5)
+		lines := make([]string, 0, 3)
+		for _, response := range []models.Response{valid, invalid} {
+			data, err := json.Marshal(response)
+			require.NoError(t, err)
+			lines = append(lines, string(data))
+		}
+		mockSurveyRepo, mockResponseRepo, surveyService := importMocks(ctrl, now)
+		mockSurveyRepo.EXPECT().GetVersions(models.DefaultWorkspace, survey.ID).Return([]models.Survey{*survey}, nil)
+		mockResponseRepo.EXPECT().Create(gomock.Any()).DoAndReturn(func(created *models.Response) (*models.Response, error) {
+			assert.Equal(t, survey.ID, created.SurveyID)
+			assert.Equal(t, 1, created.SurveyVersion)
+			assert.Equal(t, now, created.CreatedAt)
+			return created, nil
+		})
+		input := lines[0] + "\n\nnot json\n" + lines[1]
+		report, err := surveyService.Import(models.DefaultWorkspace, strings.NewReader(input), models.ImportOptions{Format: models.ImportNDJSON, SurveyID: survey.ID})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.ResponsesImported)
+		assert.Len(t, report.Errors, 2)
+		assert.Equal(t, 3, report.Errors[0].Line)
+		assert.Equal(t, 4, report.Errors[1].Line)
+		assert.Contains(t, report.Errors[1].Message, "invalid response")
+	})
+	t.Run("should reject duplicate ids when they are preserved", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		survey := resultsSurvey()
+		stored := resultsResponse(survey, now, true, "pizza", 5, 10)
+		data, err := json.Marshal(stored)
+		require.NoError(t, err)
+		mockSurveyRepo, mockResponseRepo, surveyService := importMocks(ctrl, now)
+		mockSurveyRepo.EXPECT().GetVersions(models.DefaultWorkspace, survey.ID).Return([]models.Survey{*survey}, nil)
+		mockResponseRepo.EXPECT().EachBySurveyID(models.DefaultWorkspace, survey.ID, gomock.Any()).DoAndReturn(func(_ models.WorkspaceID, _ ksuid.KSUID, fn func(models.Response) error) error {
+			return fn(stored)
+		})
+		report, err := surveyService.Import(models.DefaultWorkspace, strings.NewReader(string(data)), models.ImportOptions{Format: models.ImportNDJSON, PreserveIDs: true})
+		assert.NoError(t, err)
+		assert.Equal(t, []models.ImportError{
+			{Line: 1, SurveyID: survey.ID.String(), ResponseID: stored.ID.String(), Message: "response already exists"},
+		}, report.Errors)
+	})
+	t.Run("should reject responses to archived surveys", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		survey := resultsSurvey()
+		survey.Status = models.SurveyArchived
+		data, err := json.Marshal(resultsResponse(survey, now, true, "pizza", 5, 10))
+		require.NoError(t, err)
+		mockSurveyRepo, _, surveyService := importMocks(ctrl, now)
+		mockSurveyRepo.EXPECT().GetVersions(models.DefaultWorkspace, survey.ID).Return([]models.Survey{*survey}, nil)
+		report, err := surveyService.Import(models.DefaultWorkspace, strings.NewReader(string(data)), models.ImportOptions{Format: models.ImportNDJSON})
+		assert.NoError(t, err)
+		assert.Equal(t, services.ErrSurveyArchived.Error(), report.Errors[0].Message)
+	})
+}
+
+func TestSurveyService_Import_CSV(t *testing.T) {
+	now := time.Date(2021, time.June, 3, 0, 0, 0, 0, time.UTC)
+	survey := resultsSurvey()
+	t.Run("should read answers from columns headed by question id or text", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo, mockResponseRepo, surveyService := importMocks(ctrl, now)
+		mockSurveyRepo.EXPECT().GetVersions(models.DefaultWorkspace, survey.ID).Return([]models.Survey{*survey}, nil)
+		mockResponseRepo.EXPECT().Create(gomock.Any()).DoAndReturn(func(created *models.Response) (*models.Response, error) {
+			assert.Equal(t, []models.Answer{
+				{QuestionID: survey.Questions[0].ID, Answer: models.BoolValue(true)},
+				{QuestionID: survey.Questions[1].ID, Answer: models.TextValue("pasta")},
+				{QuestionID: survey.Questions[3].ID, Answer: models.NumberValue(7)},
+			}, created.Answers)
+			assert.Equal(t, time.Date(2021, time.June, 1, 10, 0, 0, 0, time.UTC), created.CreatedAt)
+			return created, nil
+		})
+		input := "created_at,is this place good?," + survey.Questions[1].ID.String() + ",how was it?,would you recommend us?\n" +
+			"2021-06-01T10:00:00Z,yes,pasta,,7\n" +
+			"2021-06-01T11:00:00Z,maybe,pasta,3,7\n"
+		report, err := surveyService.Import(models.DefaultWorkspace, strings.NewReader(input), models.ImportOptions{Format: models.ImportCSV, SurveyID: survey.ID})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.ResponsesImported)
+		assert.Equal(t, []models.ImportError{{Line: 3, Message: `column "is this place good?": invalid value "maybe"`}}, report.Errors)
+	})
+	t.Run("should return error for columns which are not questions of the survey", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo, _, surveyService := importMocks(ctrl, now)
+		mockSurveyRepo.EXPECT().GetVersions(models.DefaultWorkspace, survey.ID).Return([]models.Survey{*survey}, nil)
+		_, err := surveyService.Import(models.DefaultWorkspace, strings.NewReader("favourite colour\nblue\n"), models.ImportOptions{Format: models.ImportCSV, SurveyID: survey.ID})
+		assert.True(t, errors.Is(err, services.ErrMalformedImport))
+	})
+	t.Run("should return error without a survey", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		_, _, surveyService := importMocks(ctrl, now)
+		_, err := surveyService.Import(models.DefaultWorkspace, strings.NewReader(""), models.ImportOptions{Format: models.ImportCSV})
+		assert.Equal(t, services.ErrImportNeedsSurvey, err)
+	})
+	t.Run("should return error when survey does not exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo, _, surveyService := importMocks(ctrl, now)
+		mockSurveyRepo.EXPECT().GetVersions(models.DefaultWorkspace, survey.ID).Return(nil, repositories.ErrNotFound)
+		_, err := surveyService.Import(models.DefaultWorkspace, strings.NewReader(""), models.ImportOptions{Format: models.ImportCSV, SurveyID: survey.ID})
+		assert.Equal(t, repositories.ErrNotFound, err)
+	})
+}
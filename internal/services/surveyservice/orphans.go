@@ -0,0 +1,50 @@
+package surveyservice
+
+import (
+	"context"
+	"log"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"time"
+)
+
+// FindOrphans returns the response sets of every workspace whose survey is no longer stored,
+// they were left behind by surveys deleted before deleting a survey took care of its responses
+func (s *SurveyService) FindOrphans() ([]models.ResponseSet, error) {
+	sets, err := s.responseRepo.Sets()
+	if err != nil {
+		return nil, err
+	}
+	orphans := make([]models.ResponseSet, 0)
+	for _, set := range sets {
+		_, err = s.surveyRepo.Get(set.WorkspaceID, set.SurveyID)
+		if err == repositories.ErrNotFound {
+			orphans = append(orphans, set)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return orphans, nil
+}
+
+// WatchOrphans logs the orphaned response sets right away and then every interval until ctx is done
+// errors are logged and the check is retried on the next tick
+func (s *SurveyService) WatchOrphans(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		orphans, err := s.FindOrphans()
+		if err != nil {
+			log.Println("error while looking for orphaned responses", err)
+		}
+		for _, orphan := range orphans {
+			log.Printf("orphaned responses: survey %s of workspace %s is deleted but its %d responses created from %s to %s are stored",
+				orphan.SurveyID, orphan.WorkspaceID, orphan.Responses, orphan.FirstAt.Format(time.RFC3339), orphan.LastAt.Format(time.RFC3339))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
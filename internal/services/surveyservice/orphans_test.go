@@ -0,0 +1,72 @@
+package surveyservice
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/repositories_mock"
+	"testing"
+	"time"
+)
+
+func TestSurveyService_FindOrphans(t *testing.T) {
+	now := time.Now()
+	kept := models.ResponseSet{WorkspaceID: models.DefaultWorkspace, SurveyID: ksuid.New(), Responses: 2, FirstAt: now, LastAt: now}
+	orphaned := models.ResponseSet{WorkspaceID: "other", SurveyID: ksuid.New(), Responses: 1, FirstAt: now, LastAt: now}
+	t.Run("should return the response sets whose survey is not stored in their workspace", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		mockResponseRepo.EXPECT().Sets().Return([]models.ResponseSet{kept, orphaned}, nil)
+		mockSurveyRepo.EXPECT().Get(kept.WorkspaceID, kept.SurveyID).Return(&models.Survey{ID: kept.SurveyID}, nil)
+		mockSurveyRepo.EXPECT().Get(orphaned.WorkspaceID, orphaned.SurveyID).Return(nil, repositories.ErrNotFound)
+		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, nil, nil)
+		orphans, err := surveyService.FindOrphans()
+		assert.NoError(t, err)
+		assert.Equal(t, []models.ResponseSet{orphaned}, orphans)
+	})
+	t.Run("should return error when a survey cannot be looked up", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		mockResponseRepo.EXPECT().Sets().Return([]models.ResponseSet{kept}, nil)
+		mockSurveyRepo.EXPECT().Get(kept.WorkspaceID, kept.SurveyID).Return(nil, errors.New("disk failure"))
+		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, nil, nil)
+		orphans, err := surveyService.FindOrphans()
+		assert.Error(t, err)
+		assert.Nil(t, orphans)
+	})
+}
+
+func TestSurveyService_WatchOrphans(t *testing.T) {
+	t.Run("should look for orphans right away and on every interval until context is done", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		checked := make(chan struct{}, 2)
+		mockResponseRepo.EXPECT().Sets().DoAndReturn(func() ([]models.ResponseSet, error) {
+			select {
+			case checked <- struct{}{}:
+			default:
+			}
+			return nil, nil
+		}).MinTimes(2)
+		surveyService := NewSurveyService(3, nil, mockResponseRepo, nil, nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			surveyService.WatchOrphans(ctx, time.Millisecond)
+			close(done)
+		}()
+		<-checked
+		<-checked
+		cancel()
+		<-done
+	})
+}
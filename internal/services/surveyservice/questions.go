@@ -0,0 +1,232 @@
+package surveyservice
+
+import (
+	"errors"
+	"fmt"
+	"github.com/segmentio/ksuid"
+	"math"
+	"survey-platform/internal/models"
+	"survey-platform/internal/services"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultRatingMin = 1
+	npsMin           = 0
+	npsMax           = 10
+)
+
+// validateQuestions checks the config of every question of a survey, a bad config is a services.ErrInvalidSurvey
+func validateQuestions(questions []models.Question) error {
+	for i := range questions {
+		if err := validateQuestion(&questions[i]); err != nil {
+			return fmt.Errorf("%w: question %d: %v", services.ErrInvalidSurvey, i+1, err)
+		}
+	}
+	return nil
+}
+
+// validateQuestion checks the type specific config of a question
+// questions without a type are yes/no questions, the type is filled in on the passed question
+func validateQuestion(question *models.Question) error {
+	if question.Question == "" {
+		return errors.New("question text cannot be empty")
+	}
+	question.Type = question.Kind()
+	switch question.Type {
+	case models.QuestionYesNo, models.QuestionNPS, models.QuestionDate:
+		return nil
+	case models.QuestionSingleChoice, models.QuestionMultiChoice:
+		return validateOptions(question)
+	case models.QuestionRating:
+		if question.Max == nil {
+			return errors.New("rating question needs a max")
+		}
+		min, max := bound(question.Min, defaultRatingMin), *question.Max
+		if min != math.Trunc(min) || max != math.Trunc(max) {
+			return errors.New("rating scale must be whole numbers")
+		}
+		if max <= min {
+			return errors.New("rating max must be greater than min")
+		}
+		return nil
+	case models.QuestionNumeric:
+		if question.Min != nil && question.Max != nil && *question.Max < *question.Min {
+			return errors.New("numeric max cannot be less than min")
+		}
+		return nil
+	case models.QuestionText:
+		if question.MaxLength < 0 {
+			return errors.New("max length cannot be negative")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown question type %q", question.Type)
+	}
+}
+
+func validateOptions(question *models.Question) error {
+	if len(question.Options) < 2 {
+		return errors.New("choice question needs at least 2 options")
+	}
+	seen := make(map[string]bool, len(question.Options))
+	for _, option := range question.Options {
+		if option == "" {
+			return errors.New("options cannot be empty")
+		}
+		if seen[option] {
+			return fmt.Errorf("duplicate option %q", option)
+		}
+		seen[option] = true
+	}
+	if question.Min != nil && question.Max != nil && *question.Max < *question.Min {
+		return errors.New("max choices cannot be less than min")
+	}
+	return nil
+}
+
+// validateResponse checks the answers of response against the questions of survey
+// every answer must belong to a question of the survey shown by its rules, a question can be answered once
+// and shown required questions must be answered, all violations are returned in a services.ValidationError
+func validateResponse(survey *models.Survey, response models.Response) error {
+	questions := make(map[ksuid.KSUID]models.Question, len(survey.Questions))
+	for _, question := range survey.Questions {
+		questions[question.ID] = question
+	}
+	answers := make(map[ksuid.KSUID]models.Value, len(response.Answers))
+	for _, answer := range response.Answers {
+		if _, ok := answers[answer.QuestionID]; !ok {
+			answers[answer.QuestionID] = answer.Answer
+		}
+	}
+	visible := visibleQuestions(survey, answers)
+	validationErr := &services.ValidationError{}
+	answered := make(map[ksuid.KSUID]bool, len(response.Answers))
+	for _, answer := range response.Answers {
+		question, ok := questions[answer.QuestionID]
+		if !ok {
+			validationErr.Add(answer.QuestionID, "question is not part of the survey")
+			continue
+		}
+		if answered[answer.QuestionID] {
+			validationErr.Add(answer.QuestionID, "question is answered more than once")
+			continue
+		}
+		answered[answer.QuestionID] = true
+		if !visible[answer.QuestionID] {
+			validationErr.Add(answer.QuestionID, "question is hidden by the survey rules")
+			continue
+		}
+		if err := validateAnswer(question, answer.Answer); err != nil {
+			validationErr.Add(answer.QuestionID, err.Error())
+		}
+	}
+	for _, question := range survey.Questions {
+		if question.Required && visible[question.ID] && !answered[question.ID] {
+			validationErr.Add(question.ID, "question is required")
+		}
+	}
+	return validationErr.ErrOrNil()
+}
+
+// validateAnswer checks that value is a valid answer to question
+func validateAnswer(question models.Question, value models.Value) error {
+	switch question.Kind() {
+	case models.QuestionYesNo:
+		if value.Kind != models.ValueBool {
+			return errors.New("answer must be true or false")
+		}
+	case models.QuestionSingleChoice:
+		if value.Kind != models.ValueText {
+			return errors.New("answer must be one of the options")
+		}
+		if !hasOption(question, value.Text) {
+			return fmt.Errorf("%q is not an option", value.Text)
+		}
+	case models.QuestionMultiChoice:
+		return validateChoices(question, value)
+	case models.QuestionRating:
+		return validateWholeNumber(value, bound(question.Min, defaultRatingMin), *question.Max)
+	case models.QuestionNPS:
+		return validateWholeNumber(value, npsMin, npsMax)
+	case models.QuestionNumeric:
+		if value.Kind != models.ValueNumber {
+			return errors.New("answer must be a number")
+		}
+		if question.Min != nil && value.Number < *question.Min {
+			return fmt.Errorf("answer cannot be less than %v", *question.Min)
+		}
+		if question.Max != nil && value.Number > *question.Max {
+			return fmt.Errorf("answer cannot be greater than %v", *question.Max)
+		}
+	case models.QuestionText:
+		if value.Kind != models.ValueText {
+			return errors.New("answer must be text")
+		}
+		if question.MaxLength > 0 && utf8.RuneCountInString(value.Text) > question.MaxLength {
+			return fmt.Errorf("answer cannot be longer than %d characters", question.MaxLength)
+		}
+	case models.QuestionDate:
+		if value.Kind != models.ValueText {
+			return errors.New("answer must be a date")
+		}
+		if _, err := time.Parse(models.DateLayout, value.Text); err != nil {
+			return fmt.Errorf("answer must be a date formatted as %s", models.DateLayout)
+		}
+	default:
+		return fmt.Errorf("unknown question type %q", question.Type)
+	}
+	return nil
+}
+
+func validateChoices(question models.Question, value models.Value) error {
+	if value.Kind != models.ValueChoices {
+		return errors.New("answer must be a list of options")
+	}
+	count := float64(len(value.Choices))
+	if count < bound(question.Min, 1) {
+		return fmt.Errorf("at least %v options must be picked", bound(question.Min, 1))
+	}
+	if question.Max != nil && count > *question.Max {
+		return fmt.Errorf("at most %v options can be picked", *question.Max)
+	}
+	picked := make(map[string]bool, len(value.Choices))
+	for _, choice := range value.Choices {
+		if !hasOption(question, choice) {
+			return fmt.Errorf("%q is not an option", choice)
+		}
+		if picked[choice] {
+			return fmt.Errorf("%q is picked more than once", choice)
+		}
+		picked[choice] = true
+	}
+	return nil
+}
+
+func validateWholeNumber(value models.Value, min, max float64) error {
+	if value.Kind != models.ValueNumber || value.Number != math.Trunc(value.Number) {
+		return errors.New("answer must be a whole number")
+	}
+	if value.Number < min || value.Number > max {
+		return fmt.Errorf("answer must be between %v and %v", min, max)
+	}
+	return nil
+}
+
+func hasOption(question models.Question, option string) bool {
+	for _, existingOption := range question.Options {
+		if existingOption == option {
+			return true
+		}
+	}
+	return false
+}
+
+// bound returns the configured bound or def when it is not set
+func bound(configured *float64, def float64) float64 {
+	if configured == nil {
+		return def
+	}
+	return *configured
+}
@@ -0,0 +1,91 @@
+package surveyservice
+
+import (
+	"github.com/stretchr/testify/assert"
+	"survey-platform/internal/models"
+	"testing"
+)
+
+func float(f float64) *float64 {
+	return &f
+}
+
+func TestValidateQuestion(t *testing.T) {
+	t.Run("should default questions without a type to yes/no", func(t *testing.T) {
+		question := models.Question{Question: "is this place good?"}
+		assert.NoError(t, validateQuestion(&question))
+		assert.Equal(t, models.QuestionYesNo, question.Type)
+	})
+	t.Run("should accept valid configs", func(t *testing.T) {
+		questions := []models.Question{
+			{Question: "pick one", Type: models.QuestionSingleChoice, Options: []string{"a", "b"}},
+			{Question: "pick some", Type: models.QuestionMultiChoice, Options: []string{"a", "b", "c"}, Min: float(1), Max: float(2)},
+			{Question: "rate it", Type: models.QuestionRating, Max: float(5)},
+			{Question: "recommend it", Type: models.QuestionNPS},
+			{Question: "tell us", Type: models.QuestionText, MaxLength: 100},
+			{Question: "how many", Type: models.QuestionNumeric, Min: float(-1.5), Max: float(2.5)},
+			{Question: "when", Type: models.QuestionDate},
+		}
+		for _, question := range questions {
+			assert.NoError(t, validateQuestion(&question), question.Question)
+		}
+	})
+	t.Run("should reject invalid configs", func(t *testing.T) {
+		questions := []models.Question{
+			{Type: models.QuestionYesNo},
+			{Question: "pick one", Type: models.QuestionSingleChoice, Options: []string{"a"}},
+			{Question: "pick one", Type: models.QuestionSingleChoice, Options: []string{"a", "a"}},
+			{Question: "pick some", Type: models.QuestionMultiChoice, Options: []string{"a", ""}},
+			{Question: "pick some", Type: models.QuestionMultiChoice, Options: []string{"a", "b"}, Min: float(2), Max: float(1)},
+			{Question: "rate it", Type: models.QuestionRating},
+			{Question: "rate it", Type: models.QuestionRating, Max: float(4.5)},
+			{Question: "rate it", Type: models.QuestionRating, Min: float(5), Max: float(5)},
+			{Question: "how many", Type: models.QuestionNumeric, Min: float(2), Max: float(1)},
+			{Question: "tell us", Type: models.QuestionText, MaxLength: -1},
+			{Question: "what", Type: "essay"},
+		}
+		for _, question := range questions {
+			assert.Error(t, validateQuestion(&question), question.Question)
+		}
+	})
+}
+
+func TestValidateAnswer(t *testing.T) {
+	multi := models.Question{Type: models.QuestionMultiChoice, Options: []string{"a", "b", "c"}, Max: float(2)}
+	tests := []struct {
+		name     string
+		question models.Question
+		value    models.Value
+		valid    bool
+	}{
+		{"yes/no with bool", models.Question{}, models.BoolValue(true), true},
+		{"yes/no with text", models.Question{}, models.TextValue("yes"), false},
+		{"single choice with option", models.Question{Type: models.QuestionSingleChoice, Options: []string{"a", "b"}}, models.TextValue("b"), true},
+		{"single choice with unknown option", models.Question{Type: models.QuestionSingleChoice, Options: []string{"a", "b"}}, models.TextValue("c"), false},
+		{"multi choice with options", multi, models.ChoicesValue("a", "c"), true},
+		{"multi choice with too many options", multi, models.ChoicesValue("a", "b", "c"), false},
+		{"multi choice with nothing picked", multi, models.ChoicesValue(), false},
+		{"multi choice with repeated option", multi, models.ChoicesValue("a", "a"), false},
+		{"rating within scale", models.Question{Type: models.QuestionRating, Max: float(5)}, models.NumberValue(1), true},
+		{"rating below scale", models.Question{Type: models.QuestionRating, Max: float(5)}, models.NumberValue(0), false},
+		{"rating with fraction", models.Question{Type: models.QuestionRating, Max: float(5)}, models.NumberValue(2.5), false},
+		{"nps within scale", models.Question{Type: models.QuestionNPS}, models.NumberValue(0), true},
+		{"nps above scale", models.Question{Type: models.QuestionNPS}, models.NumberValue(11), false},
+		{"numeric within bounds", models.Question{Type: models.QuestionNumeric, Min: float(0)}, models.NumberValue(3.14), true},
+		{"numeric below bounds", models.Question{Type: models.QuestionNumeric, Min: float(0)}, models.NumberValue(-1), false},
+		{"text within max length", models.Question{Type: models.QuestionText, MaxLength: 5}, models.TextValue("héllo"), true},
+		{"text over max length", models.Question{Type: models.QuestionText, MaxLength: 5}, models.TextValue("hello!"), false},
+		{"date with layout", models.Question{Type: models.QuestionDate}, models.TextValue("2021-07-30"), true},
+		{"date without layout", models.Question{Type: models.QuestionDate}, models.TextValue("30/07/2021"), false},
+	}
+	for _, test := range tests {
+		t.Run("should validate "+test.name, func(t *testing.T) {
+			err := validateAnswer(test.question, test.value)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package surveyservice
+
+import (
+	"github.com/segmentio/ksuid"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+)
+
+// GetResponse returns the response with id saved with the invitation invitationID,
+// along with the time until which it can be edited or withdrawn
+func (s *SurveyService) GetResponse(workspace models.WorkspaceID, invitationID, id ksuid.KSUID) (*models.ResponseDetails, error) {
+	response, survey, err := s.invitedResponse(workspace, invitationID, id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ResponseDetails{Response: *response, EditableUntil: survey.EditableUntil(response.CreatedAt)}, nil
+}
+
+// UpdateResponse replaces the answers of the response with id saved with the invitation invitationID
+// the answers go through the checks of SaveResponse against the current survey and the previous answers are kept in the history
+func (s *SurveyService) UpdateResponse(workspace models.WorkspaceID, invitationID, id ksuid.KSUID, answers []models.Answer) (*models.ResponseDetails, error) {
+	s.editMu.Lock()
+	defer s.editMu.Unlock()
+	response, survey, err := s.invitedResponse(workspace, invitationID, id)
+	if err != nil {
+		return nil, err
+	}
+	now := s.timeGenerator.Now()
+	if err = editable(survey, response, now); err != nil {
+		return nil, err
+	}
+	response.Edit(survey.CurrentVersion(), answers, now)
+	if err = validateResponse(survey, *response); err != nil {
+		return nil, err
+	}
+	updated, err := s.responseRepo.Update(response)
+	if err != nil {
+		return nil, err
+	}
+	s.results.drop(survey.ID)
+	return &models.ResponseDetails{Response: *updated, EditableUntil: survey.EditableUntil(updated.CreatedAt)}, nil
+}
+
+// DeleteResponse withdraws the response with id saved with the invitation invitationID, the invitation stays answered
+func (s *SurveyService) DeleteResponse(workspace models.WorkspaceID, invitationID, id ksuid.KSUID) error {
+	s.editMu.Lock()
+	defer s.editMu.Unlock()
+	response, survey, err := s.invitedResponse(workspace, invitationID, id)
+	if err != nil {
+		return err
+	}
+	if err = editable(survey, response, s.timeGenerator.Now()); err != nil {
+		return err
+	}
+	if err = s.responseRepo.Delete(workspace, id); err != nil {
+		return err
+	}
+	s.results.drop(survey.ID)
+	return nil
+}
+
+// invitedResponse returns the response with id along with the survey it answers
+// responses which were not saved with the invitation invitationID are reported as ErrNotFound
+func (s *SurveyService) invitedResponse(workspace models.WorkspaceID, invitationID, id ksuid.KSUID) (*models.Response, *models.Survey, error) {
+	response, err := s.responseRepo.Get(workspace, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if response.InvitationID == nil || *response.InvitationID != invitationID {
+		return nil, nil, repositories.ErrNotFound
+	}
+	survey, err := s.surveyRepo.Get(workspace, response.SurveyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return response, survey, nil
+}
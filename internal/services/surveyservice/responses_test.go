@@ -0,0 +1,137 @@
+package surveyservice
+
+import (
+	"errors"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/responserepo"
+	"survey-platform/internal/services"
+	"testing"
+	"time"
+)
+
+const editWindow = time.Hour
+
+// invitedResponse updates the survey of the fixture to a second version with an edit window
+// and stores a response to the first version answering a new invitation
+func (f *draftFixture) invitedResponse(t *testing.T, answers ...models.Answer) (ksuid.KSUID, models.Response) {
+	survey := *f.survey
+	survey.Version, survey.EditWindowSeconds = 2, int(editWindow.Seconds())
+	_, err := f.service.surveyRepo.Update(models.DefaultWorkspace, survey.ID, &survey)
+	require.NoError(t, err)
+	invitationID, respondentID := ksuid.New(), ksuid.New()
+	response := models.Response{ID: ksuid.New(), WorkspaceID: models.DefaultWorkspace, SurveyID: survey.ID, SurveyVersion: 1,
+		InvitationID: &invitationID, RespondentID: &respondentID, Answers: answers, CreatedAt: f.now}
+	f.service.responseRepo = responserepo.NewResponseRepo(map[ksuid.KSUID][]models.Response{survey.ID: {response}}, nil)
+	return invitationID, response
+}
+
+func TestSurveyService_GetResponse(t *testing.T) {
+	t.Run("should return the response of the invitation along with the end of its edit window", func(t *testing.T) {
+		f := newDraftFixture(t)
+		invitationID, response := f.invitedResponse(t, f.answer(0, models.BoolValue(true)))
+		details, err := f.service.GetResponse(models.DefaultWorkspace, invitationID, response.ID)
+		require.NoError(t, err)
+		assert.Equal(t, response, details.Response)
+		assert.Equal(t, response.CreatedAt.Add(editWindow), *details.EditableUntil)
+	})
+	t.Run("should return ErrNotFound for responses of other invitations and missing responses", func(t *testing.T) {
+		f := newDraftFixture(t)
+		_, response := f.invitedResponse(t, f.answer(0, models.BoolValue(true)))
+		_, err := f.service.GetResponse(models.DefaultWorkspace, ksuid.New(), response.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		_, err = f.service.GetResponse(models.DefaultWorkspace, *response.InvitationID, ksuid.New())
+		assert.Equal(t, repositories.ErrNotFound, err)
+		_, err = f.service.GetResponse("other", *response.InvitationID, response.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+	})
+}
+
+func TestSurveyService_UpdateResponse(t *testing.T) {
+	t.Run("should replace the answers, keep the previous ones in the history and update the results", func(t *testing.T) {
+		f := newDraftFixture(t)
+		invitationID, response := f.invitedResponse(t, f.answer(0, models.BoolValue(true)))
+		results, err := f.service.GetResults(models.DefaultWorkspace, f.survey.ID, models.ResultsFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, "yes", results.Questions[0].Options[0].Option)
+		assert.Equal(t, 1, results.Questions[0].Options[0].Count)
+		f.advance(time.Minute)
+		answers := []models.Answer{f.answer(0, models.BoolValue(false)), f.answer(1, models.TextValue("pizza"))}
+		details, err := f.service.UpdateResponse(models.DefaultWorkspace, invitationID, response.ID, answers)
+		require.NoError(t, err)
+		assert.Equal(t, answers, details.Answers)
+		assert.Equal(t, 2, details.SurveyVersion)
+		assert.Equal(t, f.now, *details.UpdatedAt)
+		assert.Equal(t, []models.ResponseRevision{{Revision: 1, SurveyVersion: 1, Answers: response.Answers, SavedAt: response.CreatedAt}}, details.History)
+		assert.Equal(t, response.CreatedAt.Add(editWindow), *details.EditableUntil)
+		stored, err := f.service.GetResponse(models.DefaultWorkspace, invitationID, response.ID)
+		require.NoError(t, err)
+		assert.Equal(t, *details, *stored)
+		results, err = f.service.GetResults(models.DefaultWorkspace, f.survey.ID, models.ResultsFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, results.TotalResponses)
+		assert.Equal(t, []models.OptionTally{{Option: "yes", Count: 0}, {Option: "no", Count: 1, Percentage: 100}}, results.Questions[0].Options)
+		assert.Equal(t, 1, results.Questions[1].Answered)
+	})
+	t.Run("should reject invalid answers and leave the response unchanged", func(t *testing.T) {
+		f := newDraftFixture(t)
+		invitationID, response := f.invitedResponse(t, f.answer(0, models.BoolValue(true)))
+		_, err := f.service.UpdateResponse(models.DefaultWorkspace, invitationID, response.ID, []models.Answer{f.answer(1, models.TextValue("soup"))})
+		var validationErr *services.ValidationError
+		require.True(t, errors.As(err, &validationErr))
+		assert.Len(t, validationErr.Violations, 2)
+		stored, err := f.service.GetResponse(models.DefaultWorkspace, invitationID, response.ID)
+		require.NoError(t, err)
+		assert.Equal(t, response, stored.Response)
+	})
+	t.Run("should not change responses past their edit window or once the survey is closed", func(t *testing.T) {
+		f := newDraftFixture(t)
+		invitationID, response := f.invitedResponse(t, f.answer(0, models.BoolValue(true)))
+		answers := []models.Answer{f.answer(0, models.BoolValue(false))}
+		_, err := f.service.UpdateResponse(models.DefaultWorkspace, ksuid.New(), response.ID, answers)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		_, err = f.service.CloseSurvey(models.DefaultWorkspace, f.survey.ID)
+		require.NoError(t, err)
+		_, err = f.service.UpdateResponse(models.DefaultWorkspace, invitationID, response.ID, answers)
+		assert.Equal(t, services.ErrSurveyNotPublished, err)
+		assert.Equal(t, services.ErrSurveyNotPublished, f.service.DeleteResponse(models.DefaultWorkspace, invitationID, response.ID))
+		_, err = f.service.PublishSurvey(models.DefaultWorkspace, f.survey.ID)
+		require.NoError(t, err)
+		f.advance(editWindow)
+		_, err = f.service.UpdateResponse(models.DefaultWorkspace, invitationID, response.ID, answers)
+		assert.Equal(t, services.ErrEditWindowClosed, err)
+		assert.Equal(t, services.ErrEditWindowClosed, f.service.DeleteResponse(models.DefaultWorkspace, invitationID, response.ID))
+	})
+	t.Run("should not change responses to surveys without an edit window", func(t *testing.T) {
+		f := newDraftFixture(t)
+		invitationID, response := f.invitedResponse(t, f.answer(0, models.BoolValue(true)))
+		survey := *f.survey
+		_, err := f.service.surveyRepo.Update(models.DefaultWorkspace, survey.ID, &survey)
+		require.NoError(t, err)
+		details, err := f.service.GetResponse(models.DefaultWorkspace, invitationID, response.ID)
+		require.NoError(t, err)
+		assert.Nil(t, details.EditableUntil)
+		assert.Equal(t, services.ErrEditWindowClosed, f.service.DeleteResponse(models.DefaultWorkspace, invitationID, response.ID))
+	})
+}
+
+func TestSurveyService_DeleteResponse(t *testing.T) {
+	t.Run("should withdraw the response of the invitation and drop it from the results", func(t *testing.T) {
+		f := newDraftFixture(t)
+		invitationID, response := f.invitedResponse(t, f.answer(0, models.BoolValue(true)))
+		results, err := f.service.GetResults(models.DefaultWorkspace, f.survey.ID, models.ResultsFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, results.TotalResponses)
+		assert.Equal(t, repositories.ErrNotFound, f.service.DeleteResponse(models.DefaultWorkspace, ksuid.New(), response.ID))
+		require.NoError(t, f.service.DeleteResponse(models.DefaultWorkspace, invitationID, response.ID))
+		_, err = f.service.GetResponse(models.DefaultWorkspace, invitationID, response.ID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		results, err = f.service.GetResults(models.DefaultWorkspace, f.survey.ID, models.ResultsFilter{})
+		require.NoError(t, err)
+		assert.Zero(t, results.TotalResponses)
+		assert.Equal(t, repositories.ErrNotFound, f.service.DeleteResponse(models.DefaultWorkspace, invitationID, response.ID))
+	})
+}
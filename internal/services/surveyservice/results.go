@@ -0,0 +1,317 @@
+package surveyservice
+
+import (
+	"github.com/segmentio/ksuid"
+	"math"
+	"sort"
+	"survey-platform/internal/models"
+	"survey-platform/pkg/stats"
+	"sync"
+	"time"
+)
+
+const (
+	yesOption      = "yes"
+	noOption       = "no"
+	npsPromoterMin = 9
+	npsPassiveMin  = 7
+)
+
+// resultsCache keeps the answers of the surveys whose results were requested tallied in buckets,
+// surveys are loaded from the response repo once and kept up to date as responses are saved
+type resultsCache struct {
+	mu      sync.Mutex
+	surveys map[ksuid.KSUID]*surveyTally
+}
+
+func newResultsCache() *resultsCache {
+	return &resultsCache{surveys: make(map[ksuid.KSUID]*surveyTally)}
+}
+
+// surveyTally buckets the answers of a survey by the version they answered and the day they were saved
+// counted keeps responses added while the survey was being loaded from being tallied twice
+type surveyTally struct {
+	counted map[ksuid.KSUID]bool
+	kinds   map[int]map[ksuid.KSUID]models.QuestionType
+	buckets map[bucketKey]*bucket
+}
+
+type bucketKey struct {
+	version int
+	day     string
+}
+
+type bucket struct {
+	responses int
+	answers   map[ksuid.KSUID]*answerTally
+}
+
+// answerTally holds the answers to a question, counts for yes/no and choice questions and values for numeric ones
+type answerTally struct {
+	answered int
+	counts   map[string]int
+	values   []float64
+}
+
+// merged sums the answers to the scoped versions of a survey saved between the days from and to, see surveyTally.merged
+func (c *resultsCache) merged(id ksuid.KSUID, versions []models.Survey, scoped map[int]bool, from, to string,
+	responses func() ([]models.Response, error)) (*bucket, map[string]int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tally, err := c.load(id, versions, responses)
+	if err != nil {
+		return nil, nil, err
+	}
+	total, perDay := tally.merged(scoped, from, to)
+	return total, perDay, nil
+}
+
+// load returns the tally of a survey, building it from the responses returned by responses when it is not cached
+// the caller must hold mu
+func (c *resultsCache) load(id ksuid.KSUID, versions []models.Survey, responses func() ([]models.Response, error)) (*surveyTally, error) {
+	if tally, ok := c.surveys[id]; ok {
+		for i := range versions {
+			tally.addVersion(&versions[i])
+		}
+		return tally, nil
+	}
+	stored, err := responses()
+	if err != nil {
+		return nil, err
+	}
+	tally := newSurveyTally(versions)
+	for _, response := range stored {
+		tally.add(response)
+	}
+	c.surveys[id] = tally
+	return tally, nil
+}
+
+// add tallies a response saved for survey when the results of the survey are cached
+func (c *resultsCache) add(survey *models.Survey, response models.Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tally, ok := c.surveys[survey.ID]
+	if !ok {
+		return
+	}
+	tally.addVersion(survey)
+	tally.add(response)
+}
+
+func (c *resultsCache) drop(id ksuid.KSUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.surveys, id)
+}
+
+// newSurveyTally returns an empty tally of the answers to versions
+func newSurveyTally(versions []models.Survey) *surveyTally {
+	tally := &surveyTally{
+		counted: make(map[ksuid.KSUID]bool),
+		kinds:   make(map[int]map[ksuid.KSUID]models.QuestionType, len(versions)),
+		buckets: make(map[bucketKey]*bucket),
+	}
+	for i := range versions {
+		tally.addVersion(&versions[i])
+	}
+	return tally
+}
+
+func (t *surveyTally) addVersion(survey *models.Survey) {
+	if _, ok := t.kinds[survey.CurrentVersion()]; ok {
+		return
+	}
+	kinds := make(map[ksuid.KSUID]models.QuestionType, len(survey.Questions))
+	for _, question := range survey.Questions {
+		kinds[question.ID] = question.Kind()
+	}
+	t.kinds[survey.CurrentVersion()] = kinds
+}
+
+func (t *surveyTally) add(response models.Response) {
+	if t.counted[response.ID] {
+		return
+	}
+	t.counted[response.ID] = true
+	key := bucketKey{version: response.AnsweredVersion(), day: response.CreatedAt.UTC().Format(models.DateLayout)}
+	day, ok := t.buckets[key]
+	if !ok {
+		day = &bucket{answers: make(map[ksuid.KSUID]*answerTally)}
+		t.buckets[key] = day
+	}
+	day.responses++
+	kinds := t.kinds[key.version]
+	for _, answer := range response.Answers {
+		kind, ok := kinds[answer.QuestionID]
+		if !ok {
+			continue
+		}
+		tally, ok := day.answers[answer.QuestionID]
+		if !ok {
+			tally = &answerTally{counts: make(map[string]int)}
+			day.answers[answer.QuestionID] = tally
+		}
+		tally.add(kind, answer.Answer)
+	}
+}
+
+func (a *answerTally) add(kind models.QuestionType, value models.Value) {
+	a.answered++
+	switch kind {
+	case models.QuestionYesNo:
+		if value.Bool {
+			a.counts[yesOption]++
+		} else {
+			a.counts[noOption]++
+		}
+	case models.QuestionSingleChoice:
+		a.counts[value.Text]++
+	case models.QuestionMultiChoice:
+		for _, choice := range value.Choices {
+			a.counts[choice]++
+		}
+	case models.QuestionRating, models.QuestionNPS, models.QuestionNumeric:
+		a.values = append(a.values, value.Number)
+	}
+}
+
+func (a *answerTally) merge(other *answerTally) {
+	a.answered += other.answered
+	for option, count := range other.counts {
+		a.counts[option] += count
+	}
+	a.values = append(a.values, other.values...)
+}
+
+// merged sums the buckets of the scoped versions saved between from and to, versions is nil for every version
+// the returned bucket and day counts are copies which can be used without holding the cache lock
+func (t *surveyTally) merged(versions map[int]bool, from, to string) (*bucket, map[string]int) {
+	total := &bucket{answers: make(map[ksuid.KSUID]*answerTally)}
+	perDay := make(map[string]int)
+	for key, day := range t.buckets {
+		if versions != nil && !versions[key.version] {
+			continue
+		}
+		if (from != "" && key.day < from) || (to != "" && key.day > to) {
+			continue
+		}
+		total.merge(day)
+		perDay[key.day] += day.responses
+	}
+	return total, perDay
+}
+
+// merge adds the responses and answers of other to b
+func (b *bucket) merge(other *bucket) {
+	b.responses += other.responses
+	for questionID, tally := range other.answers {
+		merged, ok := b.answers[questionID]
+		if !ok {
+			merged = &answerTally{counts: make(map[string]int)}
+			b.answers[questionID] = merged
+		}
+		merged.merge(tally)
+	}
+}
+
+// results summarises the answers in total to the questions of survey
+func results(survey *models.Survey, total *bucket, perDay map[string]int) *models.Results {
+	result := &models.Results{
+		SurveyID:       survey.ID,
+		TotalResponses: total.responses,
+		PerDay:         make([]models.DayCount, 0, len(perDay)),
+		Questions:      make([]models.QuestionResult, 0, len(survey.Questions)),
+	}
+	for day, count := range perDay {
+		result.PerDay = append(result.PerDay, models.DayCount{Date: day, Count: count})
+	}
+	sort.Slice(result.PerDay, func(i, j int) bool {
+		return result.PerDay[i].Date < result.PerDay[j].Date
+	})
+	for _, question := range survey.Questions {
+		tally, ok := total.answers[question.ID]
+		if !ok {
+			tally = &answerTally{}
+		}
+		result.Questions = append(result.Questions, questionResult(question, tally))
+	}
+	return result
+}
+
+func questionResult(question models.Question, tally *answerTally) models.QuestionResult {
+	result := models.QuestionResult{
+		QuestionID: question.ID,
+		Question:   question.Question,
+		Type:       question.Kind(),
+		Answered:   tally.answered,
+	}
+	switch result.Type {
+	case models.QuestionYesNo:
+		result.Options = optionTallies([]string{yesOption, noOption}, tally)
+	case models.QuestionSingleChoice, models.QuestionMultiChoice:
+		result.Options = optionTallies(question.Options, tally)
+	case models.QuestionRating, models.QuestionNumeric:
+		result.Stats = numericStats(tally.values)
+	case models.QuestionNPS:
+		result.Stats = numericStats(tally.values)
+		result.NPS = npsBreakdown(tally.values)
+	}
+	return result
+}
+
+func optionTallies(options []string, tally *answerTally) []models.OptionTally {
+	tallies := make([]models.OptionTally, 0, len(options))
+	for _, option := range options {
+		count := tally.counts[option]
+		tallies = append(tallies, models.OptionTally{
+			Option:     option,
+			Count:      count,
+			Percentage: stats.Percentage(count, tally.answered),
+		})
+	}
+	return tallies
+}
+
+// numericStats summarises values, nil when there are none
+func numericStats(values []float64) *models.NumericStats {
+	if len(values) == 0 {
+		return nil
+	}
+	summary := &models.NumericStats{
+		Mean:   stats.Mean(values),
+		Median: stats.Median(values),
+		StdDev: stats.StdDev(values),
+		Min:    math.Inf(1),
+		Max:    math.Inf(-1),
+	}
+	for _, value := range values {
+		summary.Min = math.Min(summary.Min, value)
+		summary.Max = math.Max(summary.Max, value)
+	}
+	return summary
+}
+
+func npsBreakdown(values []float64) *models.NPSBreakdown {
+	breakdown := &models.NPSBreakdown{}
+	for _, value := range values {
+		switch {
+		case value >= npsPromoterMin:
+			breakdown.Promoters++
+		case value >= npsPassiveMin:
+			breakdown.Passives++
+		default:
+			breakdown.Detractors++
+		}
+	}
+	breakdown.Score = stats.Percentage(breakdown.Promoters, len(values)) - stats.Percentage(breakdown.Detractors, len(values))
+	return breakdown
+}
+
+// formatDay returns the day of an optional bound of a date range, empty for an open bound
+func formatDay(bound *time.Time) string {
+	if bound == nil {
+		return ""
+	}
+	return bound.UTC().Format(models.DateLayout)
+}
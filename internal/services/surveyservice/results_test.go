@@ -0,0 +1,155 @@
+package surveyservice
+
+import (
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/repositories_mock"
+	"survey-platform/pkg/idgenerator/idgenerator_mock"
+	"survey-platform/pkg/timegenerator/timegenerator_mock"
+	"testing"
+	"time"
+)
+
+// resultsSurvey returns a published survey with a yes/no, a single choice, a rating and an nps question
+func resultsSurvey() *models.Survey {
+	max := 5.0
+	return &models.Survey{
+		ID:      ksuid.New(),
+		Version: 1,
+		Status:  models.SurveyPublished,
+		Questions: []models.Question{
+			{ID: ksuid.New(), Question: "is this place good?", Type: models.QuestionYesNo},
+			{ID: ksuid.New(), Question: "what did you eat?", Type: models.QuestionSingleChoice, Options: []string{"pizza", "pasta"}},
+			{ID: ksuid.New(), Question: "how was it?", Type: models.QuestionRating, Max: &max},
+			{ID: ksuid.New(), Question: "would you recommend us?", Type: models.QuestionNPS},
+		},
+	}
+}
+
+func resultsResponse(survey *models.Survey, createdAt time.Time, good bool, dish string, rating, nps float64) models.Response {
+	return models.Response{
+		ID:        ksuid.New(),
+		SurveyID:  survey.ID,
+		CreatedAt: createdAt,
+		Answers: []models.Answer{
+			{QuestionID: survey.Questions[0].ID, Answer: models.BoolValue(good)},
+			{QuestionID: survey.Questions[1].ID, Answer: models.TextValue(dish)},
+			{QuestionID: survey.Questions[2].ID, Answer: models.NumberValue(rating)},
+			{QuestionID: survey.Questions[3].ID, Answer: models.NumberValue(nps)},
+		},
+	}
+}
+
+func TestSurveyService_GetResults(t *testing.T) {
+	survey := resultsSurvey()
+	day1 := time.Date(2021, time.June, 1, 10, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	responses := []models.Response{
+		resultsResponse(survey, day1, true, "pizza", 5, 10),
+		resultsResponse(survey, day1, true, "pasta", 4, 8),
+		resultsResponse(survey, day2, false, "pizza", 1, 3),
+	}
+	t.Run("should tally the answers of every response", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().GetVersions(models.DefaultWorkspace, survey.ID).Return([]models.Survey{*survey}, nil)
+		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		mockResponseRepo.EXPECT().GetBySurveyID(models.DefaultWorkspace, survey.ID).Return(responses, nil)
+		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, nil, nil)
+		results, err := surveyService.GetResults(models.DefaultWorkspace, survey.ID, models.ResultsFilter{})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, results.TotalResponses)
+		assert.Equal(t, []models.DayCount{{Date: "2021-06-01", Count: 2}, {Date: "2021-06-02", Count: 1}}, results.PerDay)
+		assert.Equal(t, []models.OptionTally{
+			{Option: "yes", Count: 2, Percentage: 200.0 / 3},
+			{Option: "no", Count: 1, Percentage: 100.0 / 3},
+		}, results.Questions[0].Options)
+		assert.Equal(t, "pizza", results.Questions[1].Options[0].Option)
+		assert.Equal(t, 2, results.Questions[1].Options[0].Count)
+		assert.Equal(t, &models.NumericStats{Mean: 10.0 / 3, Median: 4, StdDev: results.Questions[2].Stats.StdDev, Min: 1, Max: 5}, results.Questions[2].Stats)
+		assert.InDelta(t, 2.082, results.Questions[2].Stats.StdDev, 0.001)
+		assert.Equal(t, &models.NPSBreakdown{Score: 0, Promoters: 1, Passives: 1, Detractors: 1}, results.Questions[3].NPS)
+	})
+	t.Run("should only tally responses saved inside the date range", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().GetVersions(models.DefaultWorkspace, survey.ID).Return([]models.Survey{*survey}, nil)
+		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		mockResponseRepo.EXPECT().GetBySurveyID(models.DefaultWorkspace, survey.ID).Return(responses, nil)
+		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, nil, nil)
+		from := time.Date(2021, time.June, 2, 0, 0, 0, 0, time.UTC)
+		results, err := surveyService.GetResults(models.DefaultWorkspace, survey.ID, models.ResultsFilter{From: &from, To: &from})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, results.TotalResponses)
+		assert.Equal(t, 1, results.Questions[0].Options[1].Count)
+		assert.Equal(t, -100.0, results.Questions[3].NPS.Score)
+	})
+	t.Run("should add saved responses without reading every response again", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().GetVersions(models.DefaultWorkspace, survey.ID).Return([]models.Survey{*survey}, nil).Times(2)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, survey.ID).Return(survey, nil)
+		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		mockResponseRepo.EXPECT().GetBySurveyID(models.DefaultWorkspace, survey.ID).Return(nil, repositories.ErrNotFound)
+		mockResponseRepo.EXPECT().Create(gomock.Any()).DoAndReturn(func(response *models.Response) (*models.Response, error) {
+			return response, nil
+		})
+		mockIDGenerator := idgenerator_mock.NewMockIDGenerator(ctrl)
+		mockIDGenerator.EXPECT().Generate().Return(ksuid.New())
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		timeGeneratorMock.EXPECT().Now().Return(day2)
+		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, mockIDGenerator, timeGeneratorMock)
+		results, err := surveyService.GetResults(models.DefaultWorkspace, survey.ID, models.ResultsFilter{})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, results.TotalResponses)
+		assert.Nil(t, results.Questions[2].Stats)
+		response := resultsResponse(survey, time.Time{}, true, "pasta", 3, 9)
+		response.ID = ksuid.Nil
+		_, err = surveyService.SaveResponse(models.DefaultWorkspace, response)
+		assert.NoError(t, err)
+		results, err = surveyService.GetResults(models.DefaultWorkspace, survey.ID, models.ResultsFilter{})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, results.TotalResponses)
+		assert.Equal(t, []models.DayCount{{Date: "2021-06-02", Count: 1}}, results.PerDay)
+		assert.Equal(t, 3.0, results.Questions[2].Stats.Mean)
+	})
+	t.Run("should keep the answers to other versions apart", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		secondVersion := *survey
+		secondVersion.Version = 2
+		secondVersion.Questions = survey.Questions[:1]
+		answeredSecond := resultsResponse(survey, day2, false, "pizza", 1, 3)
+		answeredSecond.SurveyVersion = 2
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().GetVersions(models.DefaultWorkspace, survey.ID).Return([]models.Survey{*survey, secondVersion}, nil).Times(2)
+		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		mockResponseRepo.EXPECT().GetBySurveyID(models.DefaultWorkspace, survey.ID).Return(append(responses[:2:2], answeredSecond), nil)
+		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, nil, nil)
+		results, err := surveyService.GetResults(models.DefaultWorkspace, survey.ID, models.ResultsFilter{VersionScope: models.VersionScope{Version: 2}})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, results.TotalResponses)
+		assert.Len(t, results.Questions, 1)
+		assert.Equal(t, 1, results.Questions[0].Options[1].Count)
+		results, err = surveyService.GetResults(models.DefaultWorkspace, survey.ID, models.ResultsFilter{})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, results.TotalResponses)
+		assert.Equal(t, 3, results.Questions[0].Answered)
+	})
+	t.Run("should return error when survey does not exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().GetVersions(models.DefaultWorkspace, survey.ID).Return(nil, repositories.ErrNotFound)
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
+		results, err := surveyService.GetResults(models.DefaultWorkspace, survey.ID, models.ResultsFilter{})
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Nil(t, results)
+	})
+}
@@ -0,0 +1,325 @@
+package surveyservice
+
+import (
+	"errors"
+	"fmt"
+	"github.com/segmentio/ksuid"
+	"sort"
+	"strings"
+	"survey-platform/internal/models"
+	"survey-platform/internal/services"
+	"time"
+)
+
+// questionPositions maps the id of every question to its position in the survey
+// questions without an id are left out, they get one generated and cannot be referenced by rules yet
+func questionPositions(questions []models.Question) (map[ksuid.KSUID]int, error) {
+	positions := make(map[ksuid.KSUID]int, len(questions))
+	for i, question := range questions {
+		if question.ID.IsNil() {
+			continue
+		}
+		if _, ok := positions[question.ID]; ok {
+			return nil, fmt.Errorf("question %s is part of the survey more than once", question.ID.String())
+		}
+		positions[question.ID] = i
+	}
+	return positions, nil
+}
+
+// validateRules checks that the rules of survey only reference its questions,
+// compare answers in a way the questions allow and that no question depends on itself, a broken rule is a services.ErrInvalidSurvey
+func validateRules(survey *models.Survey) error {
+	positions, err := questionPositions(survey.Questions)
+	if err != nil {
+		return fmt.Errorf("%w: %v", services.ErrInvalidSurvey, err)
+	}
+	for i, rule := range survey.Rules {
+		if err = validateRule(survey.Questions, positions, rule); err != nil {
+			return fmt.Errorf("%w: rule %d: %v", services.ErrInvalidSurvey, i+1, err)
+		}
+	}
+	graph := ruleGraph(len(survey.Questions), positions, survey.Rules)
+	if position, ok := findCycle(graph); ok {
+		return fmt.Errorf("%w: rules form a cycle through question %s", services.ErrInvalidSurvey, survey.Questions[position].ID.String())
+	}
+	return nil
+}
+
+func validateRule(questions []models.Question, positions map[ksuid.KSUID]int, rule models.Rule) error {
+	position, ok := positions[rule.QuestionID]
+	if !ok {
+		return fmt.Errorf("question %s is not part of the survey", rule.QuestionID.String())
+	}
+	conditionPosition, ok := positions[rule.Condition.QuestionID]
+	if !ok {
+		return fmt.Errorf("condition question %s is not part of the survey", rule.Condition.QuestionID.String())
+	}
+	if err := validateCondition(questions[conditionPosition], rule.Condition); err != nil {
+		return err
+	}
+	switch rule.Type {
+	case models.RuleShowIf:
+		if !rule.Target.IsNil() {
+			return errors.New("show_if rules take no target")
+		}
+	case models.RuleJump:
+		targetPosition, ok := positions[rule.Target]
+		if !ok {
+			return fmt.Errorf("jump target %s is not part of the survey", rule.Target.String())
+		}
+		if targetPosition <= position {
+			return errors.New("jump target must come after the question")
+		}
+	default:
+		return fmt.Errorf("unknown rule type %q", rule.Type)
+	}
+	return nil
+}
+
+// validateCondition checks that the operator and value of condition can be applied to answers of question
+func validateCondition(question models.Question, condition models.Condition) error {
+	switch condition.Operator {
+	case models.OpAnswered:
+		if condition.Value.Kind != models.ValueNone {
+			return errors.New("answered conditions take no value")
+		}
+	case models.OpEquals, models.OpNotEquals:
+		if err := validateAnswer(question, condition.Value); err != nil {
+			return fmt.Errorf("condition value: %w", err)
+		}
+	case models.OpLess, models.OpLessOrEqual, models.OpGreater, models.OpGreaterOrEq:
+		switch question.Kind() {
+		case models.QuestionRating, models.QuestionNPS, models.QuestionNumeric:
+			if condition.Value.Kind != models.ValueNumber {
+				return errors.New("condition value must be a number")
+			}
+		case models.QuestionDate:
+			if _, err := time.Parse(models.DateLayout, condition.Value.Text); condition.Value.Kind != models.ValueText || err != nil {
+				return fmt.Errorf("condition value must be a date formatted as %s", models.DateLayout)
+			}
+		default:
+			return fmt.Errorf("%s questions cannot be compared with %s", question.Kind(), condition.Operator)
+		}
+	case models.OpContains:
+		if question.Kind() != models.QuestionMultiChoice {
+			return errors.New("contains conditions need a multi choice question")
+		}
+		if condition.Value.Kind != models.ValueText || !hasOption(question, condition.Value.Text) {
+			return errors.New("condition value must be one of the options")
+		}
+	default:
+		return fmt.Errorf("unknown operator %q", condition.Operator)
+	}
+	return nil
+}
+
+// ruleGraph returns for every question position the positions of the questions whose visibility depends on it
+// a show_if rule makes its question depend on the condition question,
+// a jump rule makes every question it can skip depend on the jumping question and on the condition question
+func ruleGraph(size int, positions map[ksuid.KSUID]int, rules []models.Rule) [][]int {
+	graph := make([][]int, size)
+	for _, rule := range rules {
+		position, conditionPosition := positions[rule.QuestionID], positions[rule.Condition.QuestionID]
+		switch rule.Type {
+		case models.RuleShowIf:
+			graph[conditionPosition] = append(graph[conditionPosition], position)
+		case models.RuleJump:
+			for skipped := position + 1; skipped < positions[rule.Target]; skipped++ {
+				graph[position] = append(graph[position], skipped)
+				graph[conditionPosition] = append(graph[conditionPosition], skipped)
+			}
+		}
+	}
+	return graph
+}
+
+// findCycle returns a position on a cycle of graph, if there is one
+func findCycle(graph [][]int) (int, bool) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(graph))
+	var visit func(node int) (int, bool)
+	visit = func(node int) (int, bool) {
+		state[node] = visiting
+		for _, next := range graph[node] {
+			switch state[next] {
+			case visiting:
+				return next, true
+			case unvisited:
+				if position, ok := visit(next); ok {
+					return position, true
+				}
+			}
+		}
+		state[node] = visited
+		return 0, false
+	}
+	for node := range graph {
+		if state[node] == unvisited {
+			if position, ok := visit(node); ok {
+				return position, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// topologicalOrder orders the positions of graph so that every question comes after the questions it depends on
+// positions on a cycle, which validated surveys do not have, are appended in survey order
+func topologicalOrder(graph [][]int) []int {
+	inDegree := make([]int, len(graph))
+	for _, dependents := range graph {
+		for _, dependent := range dependents {
+			inDegree[dependent]++
+		}
+	}
+	order := make([]int, 0, len(graph))
+	for position, degree := range inDegree {
+		if degree == 0 {
+			order = append(order, position)
+		}
+	}
+	for i := 0; i < len(order); i++ {
+		for _, dependent := range graph[order[i]] {
+			if inDegree[dependent]--; inDegree[dependent] == 0 {
+				order = append(order, dependent)
+			}
+		}
+	}
+	if len(order) < len(graph) {
+		for position, degree := range inDegree {
+			if degree > 0 {
+				order = append(order, position)
+			}
+		}
+	}
+	return order
+}
+
+// visibleQuestions evaluates the rules of survey against answers and reports which questions are shown
+func visibleQuestions(survey *models.Survey, answers map[ksuid.KSUID]models.Value) map[ksuid.KSUID]bool {
+	positions, err := questionPositions(survey.Questions)
+	visible := make(map[ksuid.KSUID]bool, len(survey.Questions))
+	if err != nil || len(survey.Rules) == 0 {
+		for _, question := range survey.Questions {
+			visible[question.ID] = true
+		}
+		return visible
+	}
+	showIf := make(map[int][]models.Rule)
+	jumps := make(map[int][]models.Rule)
+	for _, rule := range survey.Rules {
+		position := positions[rule.QuestionID]
+		switch rule.Type {
+		case models.RuleShowIf:
+			showIf[position] = append(showIf[position], rule)
+		case models.RuleJump:
+			for skipped := position + 1; skipped < positions[rule.Target]; skipped++ {
+				jumps[skipped] = append(jumps[skipped], rule)
+			}
+		}
+	}
+	holds := func(condition models.Condition) bool {
+		answer, answered := answers[condition.QuestionID]
+		if !answered || !visible[condition.QuestionID] {
+			return false
+		}
+		return conditionHolds(condition, answer)
+	}
+	for _, position := range topologicalOrder(ruleGraph(len(survey.Questions), positions, survey.Rules)) {
+		shown := true
+		for _, rule := range showIf[position] {
+			shown = shown && holds(rule.Condition)
+		}
+		for _, rule := range jumps[position] {
+			shown = shown && !(visible[rule.QuestionID] && holds(rule.Condition))
+		}
+		visible[survey.Questions[position].ID] = shown
+	}
+	return visible
+}
+
+// conditionHolds applies the operator of condition to answer
+func conditionHolds(condition models.Condition, answer models.Value) bool {
+	switch condition.Operator {
+	case models.OpAnswered:
+		return true
+	case models.OpEquals:
+		return valuesEqual(answer, condition.Value)
+	case models.OpNotEquals:
+		return !valuesEqual(answer, condition.Value)
+	case models.OpLess, models.OpLessOrEqual, models.OpGreater, models.OpGreaterOrEq:
+		order, ok := compareValues(answer, condition.Value)
+		if !ok {
+			return false
+		}
+		switch condition.Operator {
+		case models.OpLess:
+			return order < 0
+		case models.OpLessOrEqual:
+			return order <= 0
+		case models.OpGreater:
+			return order > 0
+		default:
+			return order >= 0
+		}
+	case models.OpContains:
+		for _, choice := range answer.Choices {
+			if choice == condition.Value.Text {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// valuesEqual compares two values of the same kind, the order of picked choices does not matter
+func valuesEqual(a, b models.Value) bool {
+	if a.Kind != b.Kind {
+		return false
+	}
+	switch a.Kind {
+	case models.ValueBool:
+		return a.Bool == b.Bool
+	case models.ValueNumber:
+		return a.Number == b.Number
+	case models.ValueText:
+		return a.Text == b.Text
+	case models.ValueChoices:
+		if len(a.Choices) != len(b.Choices) {
+			return false
+		}
+		sortedA := append([]string(nil), a.Choices...)
+		sortedB := append([]string(nil), b.Choices...)
+		sort.Strings(sortedA)
+		sort.Strings(sortedB)
+		for i := range sortedA {
+			if sortedA[i] != sortedB[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return true
+}
+
+// compareValues orders numbers and dates, dates compare as text since DateLayout sorts chronologically
+// values of different kinds cannot be ordered
+func compareValues(a, b models.Value) (int, bool) {
+	switch {
+	case a.Kind == models.ValueNumber && b.Kind == models.ValueNumber:
+		if a.Number < b.Number {
+			return -1, true
+		} else if a.Number > b.Number {
+			return 1, true
+		}
+		return 0, true
+	case a.Kind == models.ValueText && b.Kind == models.ValueText:
+		return strings.Compare(a.Text, b.Text), true
+	}
+	return 0, false
+}
@@ -0,0 +1,138 @@
+package surveyservice
+
+import (
+	"errors"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"survey-platform/internal/models"
+	"survey-platform/internal/services"
+	"testing"
+)
+
+// branchingSurvey returns a survey where a "no" to the first question jumps to the last one
+// and the third question is shown only for ratings below 5
+func branchingSurvey() *models.Survey {
+	survey := &models.Survey{
+		ID: ksuid.New(),
+		Questions: []models.Question{
+			{ID: ksuid.New(), Question: "did you visit?", Type: models.QuestionYesNo, Required: true},
+			{ID: ksuid.New(), Question: "how was it?", Type: models.QuestionRating, Max: float(5), Required: true},
+			{ID: ksuid.New(), Question: "what went wrong?", Type: models.QuestionText, Required: true},
+			{ID: ksuid.New(), Question: "anything else?", Type: models.QuestionText},
+		},
+	}
+	q := survey.Questions
+	survey.Rules = []models.Rule{
+		{
+			Type:       models.RuleJump,
+			QuestionID: q[0].ID,
+			Condition:  models.Condition{QuestionID: q[0].ID, Operator: models.OpEquals, Value: models.BoolValue(false)},
+			Target:     q[3].ID,
+		},
+		{
+			Type:       models.RuleShowIf,
+			QuestionID: q[2].ID,
+			Condition:  models.Condition{QuestionID: q[1].ID, Operator: models.OpLess, Value: models.NumberValue(5)},
+		},
+	}
+	return survey
+}
+
+func TestValidateRules(t *testing.T) {
+	t.Run("should accept rules referencing earlier questions", func(t *testing.T) {
+		assert.NoError(t, validateRules(branchingSurvey()))
+	})
+	t.Run("should reject rules referencing unknown questions", func(t *testing.T) {
+		survey := branchingSurvey()
+		survey.Rules[1].Condition.QuestionID = ksuid.New()
+		assert.Error(t, validateRules(survey))
+		survey = branchingSurvey()
+		survey.Rules[0].Target = ksuid.New()
+		assert.Error(t, validateRules(survey))
+	})
+	t.Run("should reject backward jumps", func(t *testing.T) {
+		survey := branchingSurvey()
+		survey.Rules[0].QuestionID, survey.Rules[0].Target = survey.Questions[3].ID, survey.Questions[0].ID
+		assert.Error(t, validateRules(survey))
+	})
+	t.Run("should reject rules forming a cycle", func(t *testing.T) {
+		survey := branchingSurvey()
+		q := survey.Questions
+		survey.Rules = append(survey.Rules, models.Rule{
+			Type:       models.RuleShowIf,
+			QuestionID: q[1].ID,
+			Condition:  models.Condition{QuestionID: q[2].ID, Operator: models.OpAnswered},
+		})
+		assert.Error(t, validateRules(survey))
+	})
+	t.Run("should reject jumps skipping the question of their condition", func(t *testing.T) {
+		survey := branchingSurvey()
+		survey.Rules[0].Condition = models.Condition{QuestionID: survey.Questions[2].ID, Operator: models.OpAnswered}
+		assert.Error(t, validateRules(survey))
+	})
+	t.Run("should reject conditions the question cannot satisfy", func(t *testing.T) {
+		conditions := []models.Condition{
+			{Operator: models.OpEquals, Value: models.TextValue("yes")},
+			{Operator: models.OpLess, Value: models.BoolValue(true)},
+			{Operator: models.OpContains, Value: models.TextValue("yes")},
+			{Operator: models.OpAnswered, Value: models.BoolValue(true)},
+			{Operator: "like", Value: models.BoolValue(true)},
+		}
+		for _, condition := range conditions {
+			survey := branchingSurvey()
+			condition.QuestionID = survey.Questions[0].ID
+			survey.Rules[0].Condition = condition
+			assert.Error(t, validateRules(survey), string(condition.Operator))
+		}
+	})
+}
+
+func TestValidateResponse_Rules(t *testing.T) {
+	survey := branchingSurvey()
+	q := survey.Questions
+	t.Run("should skip the questions jumped over", func(t *testing.T) {
+		err := validateResponse(survey, models.Response{Answers: []models.Answer{
+			{QuestionID: q[0].ID, Answer: models.BoolValue(false)},
+			{QuestionID: q[3].ID, Answer: models.TextValue("no")},
+		}})
+		assert.NoError(t, err)
+	})
+	t.Run("should show question when its condition holds and require it", func(t *testing.T) {
+		err := validateResponse(survey, models.Response{Answers: []models.Answer{
+			{QuestionID: q[0].ID, Answer: models.BoolValue(true)},
+			{QuestionID: q[1].ID, Answer: models.NumberValue(3)},
+		}})
+		var validationErr *services.ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, []services.Violation{{QuestionID: q[2].ID, Message: "question is required"}}, validationErr.Violations)
+	})
+	t.Run("should reject answers to hidden questions", func(t *testing.T) {
+		err := validateResponse(survey, models.Response{Answers: []models.Answer{
+			{QuestionID: q[0].ID, Answer: models.BoolValue(true)},
+			{QuestionID: q[1].ID, Answer: models.NumberValue(5)},
+			{QuestionID: q[2].ID, Answer: models.TextValue("nothing")},
+		}})
+		var validationErr *services.ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, []services.Violation{{QuestionID: q[2].ID, Message: "question is hidden by the survey rules"}}, validationErr.Violations)
+	})
+	t.Run("should accept answers following the visible path", func(t *testing.T) {
+		err := validateResponse(survey, models.Response{Answers: []models.Answer{
+			{QuestionID: q[0].ID, Answer: models.BoolValue(true)},
+			{QuestionID: q[1].ID, Answer: models.NumberValue(2)},
+			{QuestionID: q[2].ID, Answer: models.TextValue("it was cold")},
+		}})
+		assert.NoError(t, err)
+	})
+}
+
+func TestConditionHolds(t *testing.T) {
+	t.Run("should compare values by operator", func(t *testing.T) {
+		assert.True(t, conditionHolds(models.Condition{Operator: models.OpEquals, Value: models.ChoicesValue("a", "b")}, models.ChoicesValue("b", "a")))
+		assert.True(t, conditionHolds(models.Condition{Operator: models.OpNotEquals, Value: models.TextValue("a")}, models.TextValue("b")))
+		assert.True(t, conditionHolds(models.Condition{Operator: models.OpGreaterOrEq, Value: models.NumberValue(9)}, models.NumberValue(9)))
+		assert.True(t, conditionHolds(models.Condition{Operator: models.OpLess, Value: models.TextValue("2021-08-01")}, models.TextValue("2021-07-30")))
+		assert.True(t, conditionHolds(models.Condition{Operator: models.OpContains, Value: models.TextValue("a")}, models.ChoicesValue("b", "a")))
+		assert.False(t, conditionHolds(models.Condition{Operator: models.OpLessOrEqual, Value: models.NumberValue(1)}, models.TextValue("1")))
+	})
+}
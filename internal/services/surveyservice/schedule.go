@@ -0,0 +1,87 @@
+package surveyservice
+
+import (
+	"fmt"
+	"math"
+	"survey-platform/internal/models"
+	"survey-platform/internal/services"
+	"time"
+)
+
+// validateSchedule checks the response window and quota of a survey, a bad schedule is a services.ErrInvalidSurvey
+func validateSchedule(survey *models.Survey) error {
+	if survey.OpensAt != nil && survey.ClosesAt != nil && !survey.ClosesAt.After(*survey.OpensAt) {
+		return fmt.Errorf("%w: survey must close after it opens", services.ErrInvalidSurvey)
+	}
+	if survey.MaxResponses < 0 {
+		return fmt.Errorf("%w: max responses cannot be negative", services.ErrInvalidSurvey)
+	}
+	if survey.EditWindowSeconds < 0 {
+		return fmt.Errorf("%w: edit window cannot be negative", services.ErrInvalidSurvey)
+	}
+	return nil
+}
+
+// acceptingResponses returns why survey does not accept a response at now when it already has count responses
+// nil is returned when the response can be saved
+func acceptingResponses(survey *models.Survey, now time.Time, count int) error {
+	if survey.State() != models.SurveyPublished {
+		return services.ErrSurveyNotPublished
+	}
+	if survey.OpensAt != nil && now.Before(*survey.OpensAt) {
+		return services.ErrSurveyNotOpen
+	}
+	if survey.ClosesAt != nil && !now.Before(*survey.ClosesAt) {
+		return services.ErrSurveyClosed
+	}
+	if survey.MaxResponses > 0 && count >= survey.MaxResponses {
+		return services.ErrQuotaReached
+	}
+	return nil
+}
+
+// editable returns why response to survey cannot be edited or withdrawn at now, nil is returned when it can
+// responses are changed within the edit window of the survey while the survey still accepts responses
+func editable(survey *models.Survey, response *models.Response, now time.Time) error {
+	until := survey.EditableUntil(response.CreatedAt)
+	if until == nil || !now.Before(*until) {
+		return services.ErrEditWindowClosed
+	}
+	if survey.State() != models.SurveyPublished {
+		return services.ErrSurveyNotPublished
+	}
+	if survey.ClosesAt != nil && !now.Before(*survey.ClosesAt) {
+		return services.ErrSurveyClosed
+	}
+	return nil
+}
+
+// availability describes whether survey accepts responses at now when it already has count responses
+func availability(survey *models.Survey, now time.Time, count int) models.Availability {
+	var available models.Availability
+	if err := acceptingResponses(survey, now, count); err != nil {
+		available.Reason = err.Error()
+	} else {
+		available.AcceptingResponses = true
+	}
+	if survey.MaxResponses > 0 {
+		remaining := survey.MaxResponses - count
+		if remaining < 0 {
+			remaining = 0
+		}
+		available.RemainingResponses = &remaining
+	}
+	if survey.OpensAt != nil && now.Before(*survey.OpensAt) {
+		available.OpensInSeconds = secondsUntil(*survey.OpensAt, now)
+	}
+	if survey.ClosesAt != nil && now.Before(*survey.ClosesAt) {
+		available.ClosesInSeconds = secondsUntil(*survey.ClosesAt, now)
+	}
+	return available
+}
+
+// secondsUntil returns the whole seconds left from now until t, rounded up so that it is zero only once t is reached
+func secondsUntil(t, now time.Time) *int64 {
+	seconds := int64(math.Ceil(t.Sub(now).Seconds()))
+	return &seconds
+}
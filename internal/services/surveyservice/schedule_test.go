@@ -0,0 +1,74 @@
+package surveyservice
+
+import (
+	"github.com/stretchr/testify/assert"
+	"survey-platform/internal/models"
+	"survey-platform/internal/services"
+	"testing"
+	"time"
+)
+
+func TestValidateSchedule(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	t.Run("should accept open ended windows and quotas", func(t *testing.T) {
+		assert.NoError(t, validateSchedule(&models.Survey{}))
+		assert.NoError(t, validateSchedule(&models.Survey{OpensAt: &now}))
+		assert.NoError(t, validateSchedule(&models.Survey{OpensAt: &now, ClosesAt: &later, MaxResponses: 10, EditWindowSeconds: 60}))
+	})
+	t.Run("should reject surveys closing before they open, negative quotas or negative edit windows", func(t *testing.T) {
+		assert.Error(t, validateSchedule(&models.Survey{OpensAt: &later, ClosesAt: &now}))
+		assert.Error(t, validateSchedule(&models.Survey{OpensAt: &now, ClosesAt: &now}))
+		assert.Error(t, validateSchedule(&models.Survey{MaxResponses: -1}))
+		assert.Error(t, validateSchedule(&models.Survey{EditWindowSeconds: -1}))
+	})
+}
+
+func TestAcceptingResponses(t *testing.T) {
+	now := time.Now()
+	opensAt, closesAt := now.Add(-time.Hour), now.Add(time.Hour)
+	survey := &models.Survey{Status: models.SurveyPublished, OpensAt: &opensAt, ClosesAt: &closesAt, MaxResponses: 2}
+	t.Run("should accept responses inside the window and under the quota", func(t *testing.T) {
+		assert.NoError(t, acceptingResponses(survey, now, 1))
+		assert.NoError(t, acceptingResponses(survey, opensAt, 0))
+	})
+	t.Run("should reject responses outside the window", func(t *testing.T) {
+		assert.Equal(t, services.ErrSurveyNotOpen, acceptingResponses(survey, opensAt.Add(-time.Nanosecond), 0))
+		assert.Equal(t, services.ErrSurveyClosed, acceptingResponses(survey, closesAt, 0))
+	})
+	t.Run("should reject responses once the quota is reached", func(t *testing.T) {
+		assert.Equal(t, services.ErrQuotaReached, acceptingResponses(survey, now, 2))
+	})
+	t.Run("should reject responses to surveys which are not published", func(t *testing.T) {
+		closed := *survey
+		closed.Status = models.SurveyClosed
+		assert.Equal(t, services.ErrSurveyNotPublished, acceptingResponses(&closed, now, 0))
+	})
+}
+
+func TestAvailability(t *testing.T) {
+	now := time.Now()
+	t.Run("should report remaining quota and time to close", func(t *testing.T) {
+		closesAt := now.Add(90*time.Second + time.Millisecond)
+		available := availability(&models.Survey{ClosesAt: &closesAt, MaxResponses: 5}, now, 3)
+		assert.True(t, available.AcceptingResponses)
+		assert.Empty(t, available.Reason)
+		assert.Equal(t, 2, *available.RemainingResponses)
+		assert.Equal(t, int64(91), *available.ClosesInSeconds)
+		assert.Nil(t, available.OpensInSeconds)
+	})
+	t.Run("should report time to open and why responses are not accepted", func(t *testing.T) {
+		opensAt := now.Add(time.Minute)
+		available := availability(&models.Survey{OpensAt: &opensAt}, now, 0)
+		assert.False(t, available.AcceptingResponses)
+		assert.Equal(t, services.ErrSurveyNotOpen.Error(), available.Reason)
+		assert.Equal(t, int64(60), *available.OpensInSeconds)
+		assert.Nil(t, available.RemainingResponses)
+		assert.Nil(t, available.ClosesInSeconds)
+	})
+	t.Run("should not report negative quota", func(t *testing.T) {
+		available := availability(&models.Survey{MaxResponses: 1}, now, 3)
+		assert.Equal(t, 0, *available.RemainingResponses)
+		assert.Equal(t, services.ErrQuotaReached.Error(), available.Reason)
+	})
+}
@@ -0,0 +1,98 @@
+package surveyservice
+
+import (
+	"github.com/segmentio/ksuid"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/pkg/search"
+	"sync"
+)
+
+const (
+	nameWeight     = 3
+	questionWeight = 1
+)
+
+// surveyIndex indexes the name and questions of the current version of every survey, with an index per workspace
+// so that searches never rank or return the surveys of another workspace
+// the index of a workspace is built from the survey repo on its first search and kept up to date as surveys are written
+type surveyIndex struct {
+	mu sync.Mutex
+	// indexes holds the index of each workspace searched so far, surveys written before are read from the repo
+	indexes map[models.WorkspaceID]*search.Index
+}
+
+// add indexes survey, replacing its previous version
+func (i *surveyIndex) add(survey *models.Survey) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if index := i.indexes[survey.Workspace()]; index != nil {
+		index.Add(survey.ID, surveyFields(survey)...)
+	}
+}
+
+func (i *surveyIndex) remove(workspace models.WorkspaceID, id ksuid.KSUID) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if index := i.indexes[workspace]; index != nil {
+		index.Remove(id)
+	}
+}
+
+// load returns the index of workspace, building it from the surveys returned by surveys when it was not built yet
+func (i *surveyIndex) load(workspace models.WorkspaceID, surveys func() ([]models.Survey, error)) (*search.Index, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if index := i.indexes[workspace]; index != nil {
+		return index, nil
+	}
+	stored, err := surveys()
+	if err != nil {
+		return nil, err
+	}
+	index := search.NewIndex()
+	for j := range stored {
+		index.Add(stored[j].ID, surveyFields(&stored[j])...)
+	}
+	if i.indexes == nil {
+		i.indexes = make(map[models.WorkspaceID]*search.Index)
+	}
+	i.indexes[workspace] = index
+	return index, nil
+}
+
+func surveyFields(survey *models.Survey) []search.Field {
+	fields := []search.Field{{Text: survey.Name, Weight: nameWeight}}
+	for _, question := range survey.Questions {
+		fields = append(fields, search.Field{Text: question.Question, Weight: questionWeight})
+	}
+	return fields
+}
+
+// SearchSurveys returns at most limit surveys of workspace whose name or questions match query, best match first
+// query words match the same word in another form, e.g. a plural, and the words they are a prefix of
+func (s *SurveyService) SearchSurveys(workspace models.WorkspaceID, query string, limit int) ([]models.SearchResult, error) {
+	index, err := s.search.load(workspace, func() ([]models.Survey, error) {
+		page, err := s.surveyRepo.GetAll(workspace, models.SurveyQuery{})
+		if err != nil {
+			return nil, err
+		}
+		return page.Surveys, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	results := make([]models.SearchResult, 0)
+	for _, hit := range index.Search(query, limit) {
+		survey, err := s.surveyRepo.Get(workspace, hit.ID)
+		if err == repositories.ErrNotFound {
+			// deleted since it was found
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, models.SearchResult{Survey: *survey, Score: hit.Score})
+	}
+	return results, nil
+}
@@ -0,0 +1,130 @@
+package surveyservice
+
+import (
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/repositories_mock"
+	"survey-platform/pkg/timegenerator/timegenerator_mock"
+	"testing"
+	"time"
+)
+
+func searchResultIDs(results []models.SearchResult) []ksuid.KSUID {
+	ids := make([]ksuid.KSUID, 0, len(results))
+	for _, result := range results {
+		ids = append(ids, result.ID)
+	}
+	return ids
+}
+
+func TestSurveyService_SearchSurveys(t *testing.T) {
+	restaurant := models.Survey{ID: ksuid.New(), Name: "Restaurant feedback", Questions: []models.Question{{ID: ksuid.New(), Question: "Did you like the food?"}}}
+	parking := models.Survey{ID: ksuid.New(), Name: "Parking", Questions: []models.Question{{Question: "Was the restaurant easy to reach?"}}}
+	stored := func(mockSurveyRepo *repositories_mock.MockSurveyRepoInterface, surveys ...models.Survey) {
+		mockSurveyRepo.EXPECT().GetAll(models.DefaultWorkspace, models.SurveyQuery{}).Return(&models.SurveyPage{Surveys: surveys}, nil).Times(1)
+		for i := range surveys {
+			survey := surveys[i]
+			mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, survey.ID).Return(&survey, nil).AnyTimes()
+		}
+	}
+	t.Run("should rank surveys matching by name before surveys matching by question", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		stored(mockSurveyRepo, restaurant, parking)
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
+		results, err := surveyService.SearchSurveys(models.DefaultWorkspace, "restaurants", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []ksuid.KSUID{restaurant.ID, parking.ID}, searchResultIDs(results))
+		assert.Greater(t, results[0].Score, results[1].Score)
+		assert.Equal(t, "Restaurant feedback", results[0].Name)
+		results, err = surveyService.SearchSurveys(models.DefaultWorkspace, "restaurant", 1)
+		require.NoError(t, err)
+		assert.Equal(t, []ksuid.KSUID{restaurant.ID}, searchResultIDs(results))
+	})
+	t.Run("should only search the surveys of the workspace", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		stored(mockSurveyRepo, restaurant)
+		acme := models.Survey{ID: ksuid.New(), WorkspaceID: "acme", Name: "Restaurant opening hours"}
+		mockSurveyRepo.EXPECT().GetAll(models.WorkspaceID("acme"), models.SurveyQuery{}).Return(&models.SurveyPage{Surveys: []models.Survey{acme}}, nil)
+		mockSurveyRepo.EXPECT().Get(models.WorkspaceID("acme"), acme.ID).Return(&acme, nil).AnyTimes()
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
+		results, err := surveyService.SearchSurveys("acme", "restaurant", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []ksuid.KSUID{acme.ID}, searchResultIDs(results))
+		results, err = surveyService.SearchSurveys(models.DefaultWorkspace, "restaurant", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []ksuid.KSUID{restaurant.ID}, searchResultIDs(results))
+		other := models.Survey{ID: ksuid.New(), WorkspaceID: "acme", Name: "Restaurant menu"}
+		surveyService.search.add(&other)
+		mockSurveyRepo.EXPECT().Get(models.WorkspaceID("acme"), other.ID).Return(&other, nil)
+		results, err = surveyService.SearchSurveys(models.DefaultWorkspace, "menu", 10)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+		results, err = surveyService.SearchSurveys("acme", "menu", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []ksuid.KSUID{other.ID}, searchResultIDs(results))
+	})
+	t.Run("should keep the index up to date as surveys are written and deleted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		stored(mockSurveyRepo, restaurant)
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		timeGeneratorMock.EXPECT().Now().Return(time.Now()).AnyTimes()
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, timeGeneratorMock)
+		surveyService.search.add(&parking)
+		results, err := surveyService.SearchSurveys(models.DefaultWorkspace, "park", 10)
+		require.NoError(t, err)
+		assert.Empty(t, results, "surveys written before the first search are read from the repo")
+		surveyService.search.add(&parking)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, parking.ID).Return(&parking, nil).AnyTimes()
+		results, err = surveyService.SearchSurveys(models.DefaultWorkspace, "park", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []ksuid.KSUID{parking.ID}, searchResultIDs(results))
+		renamed := restaurant
+		renamed.Name = "Diner"
+		mockSurveyRepo.EXPECT().Update(models.DefaultWorkspace, restaurant.ID, gomock.Any()).DoAndReturn(func(_ models.WorkspaceID, _ ksuid.KSUID, survey *models.Survey) (*models.Survey, error) {
+			return survey, nil
+		})
+		_, err = surveyService.UpdateSurvey(models.DefaultWorkspace, restaurant.ID, renamed)
+		require.NoError(t, err)
+		results, err = surveyService.SearchSurveys(models.DefaultWorkspace, "restaurant", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []ksuid.KSUID{parking.ID}, searchResultIDs(results))
+		mockSurveyRepo.EXPECT().Delete(models.DefaultWorkspace, parking.ID, false).Return(nil)
+		require.NoError(t, surveyService.DeleteSurvey(models.DefaultWorkspace, parking.ID, false))
+		results, err = surveyService.SearchSurveys(models.DefaultWorkspace, "park", 10)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+	t.Run("should leave out surveys deleted while searching", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().GetAll(models.DefaultWorkspace, models.SurveyQuery{}).Return(&models.SurveyPage{Surveys: []models.Survey{parking}}, nil)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, parking.ID).Return(nil, repositories.ErrNotFound)
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
+		results, err := surveyService.SearchSurveys(models.DefaultWorkspace, "parking", 10)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+	t.Run("should return error when the surveys cannot be read", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		expectedErr := errors.New("something went wrong")
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().GetAll(models.DefaultWorkspace, models.SurveyQuery{}).Return(nil, expectedErr)
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
+		results, err := surveyService.SearchSurveys(models.DefaultWorkspace, "parking", 10)
+		assert.Equal(t, expectedErr, err)
+		assert.Nil(t, results)
+	})
+}
@@ -1,22 +1,45 @@
 package surveyservice
 
 import (
-	"errors"
+	"crypto/rand"
 	"fmt"
 	"github.com/segmentio/ksuid"
+	"io"
 	"log"
+	"sort"
 	"survey-platform/internal/models"
 	"survey-platform/internal/repositories"
+	"survey-platform/internal/services"
 	"survey-platform/pkg/idgenerator"
 	"survey-platform/pkg/timegenerator"
+	"sync"
+	"time"
 )
 
 type SurveyService struct {
+	// mu serialises the changes made on top of a stored survey, so that a status change
+	// cannot interleave with an update of the same survey
+	mu sync.Mutex
+	// quotaMu serialises counting and saving the responses of surveys with a quota,
+	// so that concurrent responses cannot go over MaxResponses
+	quotaMu sync.Mutex
+	// editMu serialises the edits and withdrawals of responses, so that concurrent edits cannot drop revisions
+	editMu        sync.Mutex
 	maxQuestions  int
 	surveyRepo    repositories.SurveyRepoInterface
 	responseRepo  repositories.ResponseRepoInterface
 	idGenerator   idgenerator.IDGenerator
 	timeGenerator timegenerator.TimeGenInterface
+	results       *resultsCache
+	search        *surveyIndex
+	// drafts holds the responses answered over several requests, nil disables drafts
+	drafts   repositories.DraftRepoInterface
+	draftTTL time.Duration
+	// draftMu serialises the changes made on top of a stored draft, so that concurrent updates cannot drop answers
+	// and the starts of drafts with the deletion of surveys, so that no draft outlives its survey
+	draftMu sync.Mutex
+	// random is the source of the resume tokens of drafts
+	random io.Reader
 }
 
 func NewSurveyService(maxQuestions int, surveyRepo repositories.SurveyRepoInterface,
@@ -28,36 +51,127 @@ func NewSurveyService(maxQuestions int, surveyRepo repositories.SurveyRepoInterf
 		responseRepo:  responseRepo,
 		idGenerator:   idGenerator,
 		timeGenerator: timeGenerator,
+		results:       newResultsCache(),
+		search:        &surveyIndex{},
+		random:        rand.Reader,
 	}
 }
 
-func (s *SurveyService) CreateSurvey(survey *models.Survey) (*models.Survey, error) {
-	if len(survey.Questions) > s.maxQuestions {
-		return nil, errors.New("survey cannot have more than 3 questions")
-	}
-	if len(survey.Questions) == 0 {
-		return nil, errors.New("survey cannot be empty")
-	}
-	if survey.Name == "" {
-		return nil, errors.New("survey needs a name")
+// SetDrafts lets responses be drafted in draftRepo, drafts which are not updated for ttl expire
+func (s *SurveyService) SetDrafts(draftRepo repositories.DraftRepoInterface, ttl time.Duration) {
+	s.drafts = draftRepo
+	s.draftTTL = ttl
+}
+
+// CreateSurvey stores survey as a draft in workspace, whatever workspace the survey names
+func (s *SurveyService) CreateSurvey(workspace models.WorkspaceID, survey *models.Survey) (*models.Survey, error) {
+	if err := s.validateSurvey(survey); err != nil {
+		return nil, err
 	}
 	survey.ID = s.idGenerator.Generate()
+	survey.WorkspaceID = workspace
 	questions := survey.Questions
+	generated := make(map[ksuid.KSUID]ksuid.KSUID, len(questions))
 	for i := 0; i < len(questions); i++ {
-		questions[i].ID = s.idGenerator.Generate()
+		id := s.idGenerator.Generate()
+		if !questions[i].ID.IsNil() {
+			generated[questions[i].ID] = id
+		}
+		questions[i].ID = id
 	}
 	survey.Questions = questions
+	remapRules(survey.Rules, generated)
+	survey.Status = models.SurveyDraft
+	survey.Version = 1
 	now := s.timeGenerator.Now()
 	survey.CreatedAt, survey.UpdatedAt = now, now
-	return s.surveyRepo.Create(survey)
+	created, err := s.surveyRepo.Create(survey)
+	if err != nil {
+		return nil, err
+	}
+	s.search.add(created)
+	return created, nil
+}
+
+// validateSurvey checks the rules every new survey has to follow, breaking them is a services.ErrInvalidSurvey
+func (s *SurveyService) validateSurvey(survey *models.Survey) error {
+	if len(survey.Questions) > s.maxQuestions {
+		return fmt.Errorf("%w: survey cannot have more than 3 questions", services.ErrInvalidSurvey)
+	}
+	if len(survey.Questions) == 0 {
+		return fmt.Errorf("%w: survey cannot be empty", services.ErrInvalidSurvey)
+	}
+	if survey.Name == "" {
+		return fmt.Errorf("%w: survey needs a name", services.ErrInvalidSurvey)
+	}
+	if err := validateQuestions(survey.Questions); err != nil {
+		return err
+	}
+	if err := validateRules(survey); err != nil {
+		return err
+	}
+	return validateSchedule(survey)
+}
+
+// remapRules points the rules at the generated question ids
+// ids sent while creating a survey are only used to link rules to questions
+func remapRules(rules []models.Rule, generated map[ksuid.KSUID]ksuid.KSUID) {
+	for i := range rules {
+		rules[i].QuestionID = generated[rules[i].QuestionID]
+		rules[i].Condition.QuestionID = generated[rules[i].Condition.QuestionID]
+		if rules[i].Type == models.RuleJump {
+			rules[i].Target = generated[rules[i].Target]
+		}
+	}
+}
+
+func (s *SurveyService) GetSurvey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error) {
+	return s.surveyRepo.Get(workspace, id)
 }
 
-func (s *SurveyService) GetSurvey(id ksuid.KSUID) (*models.Survey, error) {
-	return s.surveyRepo.Get(id)
+// GetSurveyDetails returns the survey along with whether it accepts responses right now
+func (s *SurveyService) GetSurveyDetails(workspace models.WorkspaceID, id ksuid.KSUID) (*models.SurveyDetails, error) {
+	survey, err := s.surveyRepo.Get(workspace, id)
+	if err != nil {
+		return nil, err
+	}
+	count := 0
+	if survey.MaxResponses > 0 {
+		if count, err = s.responseRepo.CountBySurveyID(workspace, id); err != nil {
+			return nil, err
+		}
+	}
+	return &models.SurveyDetails{
+		Survey:       *survey,
+		Availability: availability(survey, s.timeGenerator.Now(), count),
+	}, nil
 }
 
-func (s *SurveyService) UpdateSurvey(id ksuid.KSUID, survey models.Survey) (*models.Survey, error) {
+// UpdateSurvey stores the survey as a new version, responses keep pointing at the version they answered
+func (s *SurveyService) UpdateSurvey(workspace models.WorkspaceID, id ksuid.KSUID, survey models.Survey) (*models.Survey, error) {
+	if err := validateQuestions(survey.Questions); err != nil {
+		return nil, err
+	}
+	if err := validateRules(&survey); err != nil {
+		return nil, err
+	}
+	if err := validateSchedule(&survey); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, err := s.surveyRepo.Get(workspace, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing.State() == models.SurveyArchived {
+		return nil, services.ErrSurveyArchived
+	}
 	survey.ID = id
+	survey.WorkspaceID = existing.WorkspaceID
+	survey.Version = existing.CurrentVersion() + 1
+	survey.Status = existing.Status
+	survey.CreatedAt = existing.CreatedAt
 	questions := survey.Questions
 	for i := 0; i < len(questions); i++ {
 		if questions[i].ID.IsNil() || questions[i].ID.String() == "" {
@@ -66,38 +180,212 @@ func (s *SurveyService) UpdateSurvey(id ksuid.KSUID, survey models.Survey) (*mod
 	}
 	survey.Questions = questions
 	survey.UpdatedAt = s.timeGenerator.Now()
-	return s.surveyRepo.Update(id, &survey)
+	updated, err := s.surveyRepo.Update(workspace, id, &survey)
+	if err != nil {
+		return nil, err
+	}
+	s.search.add(updated)
+	return updated, nil
+}
+
+// DeleteSurvey deletes a survey without responses, a survey with responses is only deleted along with them when cascade is set
+// the drafts of the survey are deleted along with it, drafts left behind by a failed purge can no longer be updated or submitted
+func (s *SurveyService) DeleteSurvey(workspace models.WorkspaceID, id ksuid.KSUID, cascade bool) error {
+	s.draftMu.Lock()
+	defer s.draftMu.Unlock()
+	if err := s.surveyRepo.Delete(workspace, id, cascade); err != nil {
+		return err
+	}
+	if s.drafts != nil {
+		if err := s.drafts.DeleteBySurveyID(workspace, id); err != nil {
+			return err
+		}
+	}
+	s.results.drop(id)
+	s.search.remove(workspace, id)
+	return nil
+}
+
+// GetAllSurveys returns the page of surveys of workspace selected by query
+func (s *SurveyService) GetAllSurveys(workspace models.WorkspaceID, query models.SurveyQuery) (*models.SurveyPage, error) {
+	return s.surveyRepo.GetAll(workspace, query)
+}
+
+// PublishSurvey opens a draft or closed survey for responses
+func (s *SurveyService) PublishSurvey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error) {
+	return s.moveTo(workspace, id, models.SurveyPublished)
+}
+
+// CloseSurvey stops a published survey from accepting responses
+func (s *SurveyService) CloseSurvey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error) {
+	return s.moveTo(workspace, id, models.SurveyClosed)
+}
+
+// ArchiveSurvey makes a draft or closed survey read only
+func (s *SurveyService) ArchiveSurvey(workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error) {
+	return s.moveTo(workspace, id, models.SurveyArchived)
+}
+
+func (s *SurveyService) moveTo(workspace models.WorkspaceID, id ksuid.KSUID, status models.SurveyStatus) (*models.Survey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	survey, err := s.surveyRepo.Get(workspace, id)
+	if err != nil {
+		return nil, err
+	}
+	if !survey.State().CanMoveTo(status) {
+		return nil, fmt.Errorf("%w: %s to %s", services.ErrInvalidTransition, survey.State(), status)
+	}
+	survey.Status = status
+	survey.Version = survey.CurrentVersion()
+	survey.UpdatedAt = s.timeGenerator.Now()
+	return s.surveyRepo.Update(workspace, id, survey)
 }
 
-func (s *SurveyService) DeleteSurvey(id ksuid.KSUID) error {
-	return s.surveyRepo.Delete(id)
+// GetSurveyVersions returns every version of a survey oldest first
+func (s *SurveyService) GetSurveyVersions(workspace models.WorkspaceID, id ksuid.KSUID) ([]models.Survey, error) {
+	return s.surveyRepo.GetVersions(workspace, id)
 }
 
-func (s *SurveyService) GetAllSurveys() ([]models.Survey, error) {
-	return s.surveyRepo.GetAll()
+// SaveResponse stores a response to a survey of workspace
+func (s *SurveyService) SaveResponse(workspace models.WorkspaceID, response models.Response) (*models.Response, error) {
+	return s.saveResponse(workspace, response, ksuid.Nil, func(response *models.Response) (*models.Response, error) {
+		return s.responseRepo.Create(response)
+	})
 }
 
-func (s *SurveyService) SaveResponse(response models.Response) (*models.Response, error) {
-	_, err := s.surveyRepo.Get(response.SurveyID)
+// saveResponse validates response against the survey it answers and stores it with id through create
+// a nil id is generated once the response is valid
+func (s *SurveyService) saveResponse(workspace models.WorkspaceID, response models.Response, id ksuid.KSUID,
+	create func(*models.Response) (*models.Response, error)) (*models.Response, error) {
+	survey, err := s.surveyRepo.Get(workspace, response.SurveyID)
 	if err != nil {
 		log.Println("error while validating survey for response ", err, "surveyID ", response.SurveyID.String())
 		return nil, err
 	}
-	if len(response.Answers) > s.maxQuestions {
-		return nil, fmt.Errorf("max number of questions allowed is %d", s.maxQuestions)
+	if survey.State() != models.SurveyPublished {
+		return nil, services.ErrSurveyNotPublished
+	}
+	if err = validateResponse(survey, response); err != nil {
+		return nil, err
+	}
+	now := s.timeGenerator.Now()
+	count := 0
+	if survey.MaxResponses > 0 {
+		s.quotaMu.Lock()
+		defer s.quotaMu.Unlock()
+		if count, err = s.responseRepo.CountBySurveyID(workspace, survey.ID); err != nil {
+			return nil, err
+		}
+	}
+	if err = acceptingResponses(survey, now, count); err != nil {
+		return nil, err
+	}
+	if id.IsNil() {
+		id = s.idGenerator.Generate()
+	}
+	response.ID = id
+	response.WorkspaceID = workspace
+	response.SurveyVersion = survey.CurrentVersion()
+	response.CreatedAt = now
+	created, err := create(&response)
+	if err != nil {
+		return nil, err
+	}
+	s.results.add(survey, *created)
+	return created, nil
+}
+
+// GetResponses returns the page of responses selected by query to the versions of the survey selected by scope
+func (s *SurveyService) GetResponses(workspace models.WorkspaceID, query models.ResponseQuery, scope models.VersionScope) (*models.ResponsePage, error) {
+	versions, err := s.surveyRepo.GetVersions(workspace, query.SurveyID)
+	if err != nil {
+		return nil, err
+	}
+	_, selected, err := selectVersions(versions, scope)
+	if err != nil {
+		return nil, err
 	}
-	response.ID = s.idGenerator.Generate()
-	response.CreatedAt = s.timeGenerator.Now()
-	return s.responseRepo.Create(&response)
+	query.Versions = nil
+	if selected != nil {
+		query.Versions = make([]int, 0, len(selected))
+		for version := range selected {
+			query.Versions = append(query.Versions, version)
+		}
+		sort.Ints(query.Versions)
+	}
+	return s.responseRepo.GetAll(workspace, query)
 }
 
-func (s *SurveyService) GetResponses(surveyID ksuid.KSUID) ([]models.Response, error) {
-	return s.responseRepo.GetBySurveyID(surveyID)
+// selectVersions returns the version selected by scope along with the numbers of the scoped versions
+// the zero scope selects the current version and scopes every version with a nil map,
+// ErrNotFound is returned when the scoped version does not exist
+func selectVersions(versions []models.Survey, scope models.VersionScope) (*models.Survey, map[int]bool, error) {
+	if scope.Version == 0 {
+		if len(versions) == 0 {
+			return nil, nil, repositories.ErrNotFound
+		}
+		return &versions[len(versions)-1], nil, nil
+	}
+	var selected *models.Survey
+	for i := range versions {
+		if versions[i].CurrentVersion() == scope.Version {
+			selected = &versions[i]
+		}
+	}
+	if selected == nil {
+		return nil, nil, repositories.ErrNotFound
+	}
+	scoped := map[int]bool{scope.Version: true}
+	if scope.Compatible {
+		for _, version := range versions {
+			if version.CompatibleWith(*selected) {
+				scoped[version.CurrentVersion()] = true
+			}
+		}
+	}
+	return selected, scoped, nil
+}
+
+// GetResults aggregates the answers to a survey selected by filter
+// the answers are tallied once per survey and kept up to date as responses are saved,
+// the drafts in progress are tallied on every request when the filter includes them
+func (s *SurveyService) GetResults(workspace models.WorkspaceID, surveyID ksuid.KSUID, filter models.ResultsFilter) (*models.Results, error) {
+	versions, err := s.surveyRepo.GetVersions(workspace, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	selected, scoped, err := selectVersions(versions, filter.VersionScope)
+	if err != nil {
+		return nil, err
+	}
+	total, perDay, err := s.results.merged(surveyID, versions, scoped, formatDay(filter.From), formatDay(filter.To), func() ([]models.Response, error) {
+		responses, err := s.responseRepo.GetBySurveyID(workspace, surveyID)
+		if err == repositories.ErrNotFound {
+			return nil, nil
+		}
+		return responses, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	drafts := 0
+	if filter.IncludeDrafts {
+		if drafts, err = s.addDrafts(workspace, surveyID, versions, scoped, filter, total, perDay); err != nil {
+			return nil, err
+		}
+	}
+	result := results(selected, total, perDay)
+	result.Drafts = drafts
+	return result, nil
 }
 
+// Entries returns the surveys and responses of every workspace, partitioned by workspace, along with the drafts
+// and the expired drafts which were deleted
 func (s *SurveyService) Entries() *models.DBEntry {
-	return &models.DBEntry{
-		Responses: s.responseRepo.Entries(),
-		Surveys:   s.surveyRepo.Entries(),
+	entry := models.NewDBEntry(s.surveyRepo.Entries(), s.surveyRepo.VersionEntries(), s.responseRepo.Entries())
+	if s.drafts != nil {
+		entry.Drafts, entry.ExpiredDrafts = s.drafts.EntriesWithExpired()
 	}
+	return entry
 }
@@ -8,6 +8,7 @@ import (
 	"survey-platform/internal/models"
 	"survey-platform/internal/repositories"
 	"survey-platform/internal/repositories/repositories_mock"
+	"survey-platform/internal/services"
 	"survey-platform/pkg/idgenerator/idgenerator_mock"
 	"survey-platform/pkg/timegenerator/timegenerator_mock"
 	"testing"
@@ -46,7 +47,7 @@ func TestSurveyService_CreateSurvey(t *testing.T) {
 		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
 		timeGeneratorMock.EXPECT().Now().Return(now)
 		surveyService := NewSurveyService(3, mockSurveyRepo, nil, idGeneratorMock, timeGeneratorMock)
-		createdSurvey, err := surveyService.CreateSurvey(&survey)
+		createdSurvey, err := surveyService.CreateSurvey(models.DefaultWorkspace, &survey)
 		assert.NoError(t, err)
 		assert.Equal(t, survey, *createdSurvey)
 	})
@@ -82,7 +83,7 @@ func TestSurveyService_CreateSurvey(t *testing.T) {
 		timeGeneratorMock.EXPECT().Now().Return(now)
 		mockSurveyRepo.EXPECT().Create(&survey).Return(nil, errors.New("something went wrong"))
 		surveyService := NewSurveyService(3, mockSurveyRepo, nil, idGeneratorMock, timeGeneratorMock)
-		createdSurvey, err := surveyService.CreateSurvey(&survey)
+		createdSurvey, err := surveyService.CreateSurvey(models.DefaultWorkspace, &survey)
 		assert.Error(t, err)
 		assert.Nil(t, createdSurvey)
 	})
@@ -107,7 +108,7 @@ func TestSurveyService_CreateSurvey(t *testing.T) {
 				},
 			}}
 		surveyService := NewSurveyService(3, nil, nil, nil, nil)
-		createdSurvey, err := surveyService.CreateSurvey(&survey)
+		createdSurvey, err := surveyService.CreateSurvey(models.DefaultWorkspace, &survey)
 		assert.Error(t, err)
 		assert.Nil(t, createdSurvey)
 	})
@@ -119,7 +120,7 @@ func TestSurveyService_CreateSurvey(t *testing.T) {
 			UpdatedAt: time.Now(),
 			Questions: []models.Question{}}
 		surveyService := NewSurveyService(3, nil, nil, nil, nil)
-		createdSurvey, err := surveyService.CreateSurvey(&survey)
+		createdSurvey, err := surveyService.CreateSurvey(models.DefaultWorkspace, &survey)
 		assert.Error(t, err)
 		assert.Nil(t, createdSurvey)
 	})
@@ -140,7 +141,7 @@ func TestSurveyService_CreateSurvey(t *testing.T) {
 				},
 			}}
 		surveyService := NewSurveyService(3, nil, nil, nil, nil)
-		createdSurvey, err := surveyService.CreateSurvey(&survey)
+		createdSurvey, err := surveyService.CreateSurvey(models.DefaultWorkspace, &survey)
 		assert.Error(t, err)
 		assert.Nil(t, createdSurvey)
 	})
@@ -181,12 +182,48 @@ func TestSurveyService_CreateSurvey(t *testing.T) {
 		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
 		mockSurveyRepo.EXPECT().Create(&survey).Return(&survey, nil)
 		surveyService := NewSurveyService(3, mockSurveyRepo, nil, idGeneratorMock, timeGeneratorMock)
-		createdSurvey, err := surveyService.CreateSurvey(&survey)
+		createdSurvey, err := surveyService.CreateSurvey(models.DefaultWorkspace, &survey)
 		assert.NoError(t, err)
 		assert.NotNil(t, createdSurvey.ID)
 		assert.NotNil(t, createdSurvey.Questions[0].ID)
 		assert.NotZero(t, createdSurvey.CreatedAt)
 		assert.NotZero(t, createdSurvey.UpdatedAt)
+		assert.Equal(t, models.SurveyDraft, createdSurvey.Status)
+	})
+}
+
+func TestSurveyService_CreateSurvey_Rules(t *testing.T) {
+	t.Run("should point rules at the generated question ids", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		survey := branchingSurvey()
+		survey.Name = "branching survey"
+		generated := []ksuid.KSUID{ksuid.New(), ksuid.New(), ksuid.New(), ksuid.New(), ksuid.New()}
+		idGeneratorMock := idgenerator_mock.NewMockIDGenerator(ctrl)
+		for _, id := range generated {
+			idGeneratorMock.EXPECT().Generate().Return(id)
+		}
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		timeGeneratorMock.EXPECT().Now().Return(time.Now())
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Create(survey).Return(survey, nil)
+		surveyService := NewSurveyService(4, mockSurveyRepo, nil, idGeneratorMock, timeGeneratorMock)
+		createdSurvey, err := surveyService.CreateSurvey(models.DefaultWorkspace, survey)
+		assert.NoError(t, err)
+		assert.Equal(t, generated[1], createdSurvey.Rules[0].QuestionID)
+		assert.Equal(t, generated[1], createdSurvey.Rules[0].Condition.QuestionID)
+		assert.Equal(t, generated[4], createdSurvey.Rules[0].Target)
+		assert.Equal(t, generated[3], createdSurvey.Rules[1].QuestionID)
+		assert.Equal(t, generated[2], createdSurvey.Rules[1].Condition.QuestionID)
+	})
+	t.Run("should return error when rules form a cycle", func(t *testing.T) {
+		survey := branchingSurvey()
+		survey.Name = "branching survey"
+		survey.Rules[1].Condition.QuestionID = survey.Rules[1].QuestionID
+		surveyService := NewSurveyService(4, nil, nil, nil, nil)
+		createdSurvey, err := surveyService.CreateSurvey(models.DefaultWorkspace, survey)
+		assert.True(t, errors.Is(err, services.ErrInvalidSurvey), err)
+		assert.Nil(t, createdSurvey)
 	})
 }
 
@@ -211,9 +248,9 @@ func TestSurveyService_GetSurvey(t *testing.T) {
 				},
 			},
 		}
-		mockSurveyRepo.EXPECT().Get(survey.ID).Return(&survey, nil)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, survey.ID).Return(&survey, nil)
 		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
-		returnedSurvey, err := surveyService.GetSurvey(survey.ID)
+		returnedSurvey, err := surveyService.GetSurvey(models.DefaultWorkspace, survey.ID)
 		assert.NoError(t, err)
 		assert.Equal(t, survey, *returnedSurvey)
 	})
@@ -222,9 +259,9 @@ func TestSurveyService_GetSurvey(t *testing.T) {
 		defer ctrl.Finish()
 		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
 		surveyID := ksuid.New()
-		mockSurveyRepo.EXPECT().Get(surveyID).Return(nil, errors.New("something went wrong"))
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(nil, errors.New("something went wrong"))
 		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
-		returnedSurvey, err := surveyService.GetSurvey(surveyID)
+		returnedSurvey, err := surveyService.GetSurvey(models.DefaultWorkspace, surveyID)
 		assert.Error(t, err)
 		assert.Nil(t, returnedSurvey)
 	})
@@ -242,6 +279,7 @@ func TestSurveyService_UpdateSurvey(t *testing.T) {
 		timeGeneratorMock.EXPECT().Now().Return(now)
 		survey := models.Survey{
 			ID:        surveyID,
+			Version:   2,
 			CreatedAt: now,
 			UpdatedAt: now,
 			Questions: []models.Question{
@@ -258,10 +296,12 @@ func TestSurveyService_UpdateSurvey(t *testing.T) {
 		oldSurvey := survey
 		oldSurvey.UpdatedAt = now.AddDate(0, 0, -1)
 		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
-		mockSurveyRepo.EXPECT().Update(surveyID, &survey).Return(&survey, nil)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(&models.Survey{ID: surveyID, CreatedAt: now}, nil)
+		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Update(models.DefaultWorkspace, surveyID, &survey).Return(&survey, nil)
 		idGeneratorMock := idgenerator_mock.NewMockIDGenerator(ctrl)
-		surveyService := NewSurveyService(3, mockSurveyRepo, nil, idGeneratorMock, timeGeneratorMock)
-		updatedSurvey, err := surveyService.UpdateSurvey(surveyID, oldSurvey)
+		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, idGeneratorMock, timeGeneratorMock)
+		updatedSurvey, err := surveyService.UpdateSurvey(models.DefaultWorkspace, surveyID, oldSurvey)
 		assert.NoError(t, err)
 		assert.NotEqual(t, oldSurvey.UpdatedAt, updatedSurvey.UpdatedAt)
 	})
@@ -275,6 +315,7 @@ func TestSurveyService_UpdateSurvey(t *testing.T) {
 		timeGeneratorMock.EXPECT().Now().Return(now)
 		survey := models.Survey{
 			ID:        surveyID,
+			Version:   2,
 			CreatedAt: now,
 			UpdatedAt: now,
 			Questions: []models.Question{
@@ -290,12 +331,14 @@ func TestSurveyService_UpdateSurvey(t *testing.T) {
 		oldSurvey := survey
 		oldSurvey.UpdatedAt = now.AddDate(0, 0, -1)
 		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
-		mockSurveyRepo.EXPECT().Update(surveyID, &survey).Return(&survey, nil)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(&models.Survey{ID: surveyID, CreatedAt: now}, nil)
+		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Update(models.DefaultWorkspace, surveyID, &survey).Return(&survey, nil)
 		qID2 := ksuid.New()
 		idGeneratorMock := idgenerator_mock.NewMockIDGenerator(ctrl)
 		idGeneratorMock.EXPECT().Generate().Return(qID2)
-		surveyService := NewSurveyService(3, mockSurveyRepo, nil, idGeneratorMock, timeGeneratorMock)
-		updatedSurvey, err := surveyService.UpdateSurvey(surveyID, oldSurvey)
+		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, idGeneratorMock, timeGeneratorMock)
+		updatedSurvey, err := surveyService.UpdateSurvey(models.DefaultWorkspace, surveyID, oldSurvey)
 		assert.NoError(t, err)
 		assert.NotEqual(t, oldSurvey.UpdatedAt, updatedSurvey.UpdatedAt)
 		assert.False(t, updatedSurvey.Questions[1].ID.IsNil())
@@ -311,6 +354,7 @@ func TestSurveyService_UpdateSurvey(t *testing.T) {
 		timeGeneratorMock.EXPECT().Now().Return(now)
 		survey := models.Survey{
 			ID:        surveyID,
+			Version:   2,
 			CreatedAt: now,
 			UpdatedAt: now,
 			Questions: []models.Question{
@@ -327,12 +371,75 @@ func TestSurveyService_UpdateSurvey(t *testing.T) {
 		oldSurvey := survey
 		oldSurvey.UpdatedAt = now.AddDate(0, 0, -1)
 		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
-		mockSurveyRepo.EXPECT().Update(surveyID, &survey).Return(&survey, nil)
-		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, timeGeneratorMock)
-		updatedSurvey, err := surveyService.UpdateSurvey(surveyID, oldSurvey)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(&models.Survey{ID: surveyID, CreatedAt: now}, nil)
+		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Update(models.DefaultWorkspace, surveyID, &survey).Return(&survey, nil)
+		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, nil, timeGeneratorMock)
+		updatedSurvey, err := surveyService.UpdateSurvey(models.DefaultWorkspace, surveyID, oldSurvey)
 		assert.NoError(t, err)
 		assert.NotEqual(t, oldSurvey.UpdatedAt, updatedSurvey.UpdatedAt)
 	})
+	t.Run("should store the update as the next version of the survey", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		survey := models.Survey{
+			Name:      "new survey",
+			Version:   7,
+			Questions: []models.Question{{ID: ksuid.New(), Question: "is this place good?"}},
+		}
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(&models.Survey{ID: surveyID, Version: 2, Status: models.SurveyPublished}, nil)
+		mockSurveyRepo.EXPECT().Update(models.DefaultWorkspace, surveyID, gomock.Any()).DoAndReturn(func(_ models.WorkspaceID, id ksuid.KSUID, survey *models.Survey) (*models.Survey, error) {
+			return survey, nil
+		})
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		timeGeneratorMock.EXPECT().Now().Return(time.Now())
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, timeGeneratorMock)
+		updatedSurvey, err := surveyService.UpdateSurvey(models.DefaultWorkspace, surveyID, survey)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, updatedSurvey.Version)
+	})
+	t.Run("should return error when survey is archived", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		survey := models.Survey{
+			Name:      "new survey",
+			Questions: []models.Question{{ID: ksuid.New(), Question: "is this place good?"}},
+		}
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(&models.Survey{ID: surveyID, Status: models.SurveyArchived}, nil)
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
+		updatedSurvey, err := surveyService.UpdateSurvey(models.DefaultWorkspace, surveyID, survey)
+		assert.Equal(t, services.ErrSurveyArchived, err)
+		assert.Nil(t, updatedSurvey)
+	})
+	t.Run("should keep status and creation time of the stored survey", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		createdAt, now := time.Now().Add(-time.Hour), time.Now()
+		survey := models.Survey{
+			Name:      "new survey",
+			Status:    models.SurveyArchived,
+			Questions: []models.Question{{ID: ksuid.New(), Question: "is this place good?"}},
+		}
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(&models.Survey{ID: surveyID, Status: models.SurveyDraft, CreatedAt: createdAt}, nil)
+		mockSurveyRepo.EXPECT().Update(models.DefaultWorkspace, surveyID, gomock.Any()).DoAndReturn(func(_ models.WorkspaceID, id ksuid.KSUID, survey *models.Survey) (*models.Survey, error) {
+			return survey, nil
+		})
+		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		timeGeneratorMock.EXPECT().Now().Return(now)
+		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, nil, timeGeneratorMock)
+		updatedSurvey, err := surveyService.UpdateSurvey(models.DefaultWorkspace, surveyID, survey)
+		assert.NoError(t, err)
+		assert.Equal(t, models.SurveyDraft, updatedSurvey.Status)
+		assert.Equal(t, createdAt, updatedSurvey.CreatedAt)
+		assert.Equal(t, now, updatedSurvey.UpdatedAt)
+	})
 }
 
 func TestSurveyService_DeleteSurvey(t *testing.T) {
@@ -341,9 +448,9 @@ func TestSurveyService_DeleteSurvey(t *testing.T) {
 		defer ctrl.Finish()
 		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
 		surveyID := ksuid.New()
-		mockSurveyRepo.EXPECT().Delete(surveyID).Return(nil)
+		mockSurveyRepo.EXPECT().Delete(models.DefaultWorkspace, surveyID, false).Return(nil)
 		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
-		err := surveyService.DeleteSurvey(surveyID)
+		err := surveyService.DeleteSurvey(models.DefaultWorkspace, surveyID, false)
 		assert.NoError(t, err)
 	})
 	t.Run("should return error when repo returns error", func(t *testing.T) {
@@ -351,11 +458,24 @@ func TestSurveyService_DeleteSurvey(t *testing.T) {
 		defer ctrl.Finish()
 		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
 		surveyID := ksuid.New()
-		mockSurveyRepo.EXPECT().Delete(surveyID).Return(repositories.ErrNotFound)
+		mockSurveyRepo.EXPECT().Delete(models.DefaultWorkspace, surveyID, false).Return(repositories.ErrNotFound)
 		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
-		err := surveyService.DeleteSurvey(surveyID)
+		err := surveyService.DeleteSurvey(models.DefaultWorkspace, surveyID, false)
 		assert.Equal(t, repositories.ErrNotFound, err)
 	})
+	t.Run("should refuse deleting survey with responses unless cascading to them", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		surveyID := ksuid.New()
+		gomock.InOrder(
+			mockSurveyRepo.EXPECT().Delete(models.DefaultWorkspace, surveyID, false).Return(repositories.ErrSurveyHasResponses),
+			mockSurveyRepo.EXPECT().Delete(models.DefaultWorkspace, surveyID, true).Return(nil),
+		)
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
+		assert.Equal(t, repositories.ErrSurveyHasResponses, surveyService.DeleteSurvey(models.DefaultWorkspace, surveyID, false))
+		assert.NoError(t, surveyService.DeleteSurvey(models.DefaultWorkspace, surveyID, true))
+	})
 }
 
 func TestSurveyService_GetAllSurveys(t *testing.T) {
@@ -391,22 +511,80 @@ func TestSurveyService_GetAllSurveys(t *testing.T) {
 				},
 			},
 		}}
+		query := models.SurveyQuery{Name: "place", Status: models.SurveyPublished, PageQuery: models.PageQuery{Limit: 2}}
 		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
-		mockSurveyRepo.EXPECT().GetAll().Return(mockSurveys, nil)
+		mockSurveyRepo.EXPECT().GetAll(models.DefaultWorkspace, query).Return(&models.SurveyPage{Surveys: mockSurveys, Next: "next"}, nil)
 		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
-		surveys, err := surveyService.GetAllSurveys()
+		page, err := surveyService.GetAllSurveys(models.DefaultWorkspace, query)
 		assert.NoError(t, err)
-		assert.Equal(t, mockSurveys, surveys)
+		assert.Equal(t, &models.SurveyPage{Surveys: mockSurveys, Next: "next"}, page)
 	})
 	t.Run("should return error when repo returns error", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
+		expectedErr := errors.New("something went wrong")
 		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
-		mockSurveyRepo.EXPECT().GetAll().Return(nil, repositories.ErrNotFound)
+		mockSurveyRepo.EXPECT().GetAll(models.DefaultWorkspace, models.SurveyQuery{}).Return(nil, expectedErr)
 		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
-		surveys, err := surveyService.GetAllSurveys()
+		page, err := surveyService.GetAllSurveys(models.DefaultWorkspace, models.SurveyQuery{})
+		assert.Equal(t, expectedErr, err)
+		assert.Nil(t, page)
+	})
+}
+
+func TestSurveyService_ChangeStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    models.SurveyStatus
+		move    func(s *SurveyService, workspace models.WorkspaceID, id ksuid.KSUID) (*models.Survey, error)
+		to      models.SurveyStatus
+		allowed bool
+	}{
+		{"publish draft", models.SurveyDraft, (*SurveyService).PublishSurvey, models.SurveyPublished, true},
+		{"close published", models.SurveyPublished, (*SurveyService).CloseSurvey, models.SurveyClosed, true},
+		{"close legacy survey", "", (*SurveyService).CloseSurvey, models.SurveyClosed, true},
+		{"reopen closed", models.SurveyClosed, (*SurveyService).PublishSurvey, models.SurveyPublished, true},
+		{"archive closed", models.SurveyClosed, (*SurveyService).ArchiveSurvey, models.SurveyArchived, true},
+		{"archive draft", models.SurveyDraft, (*SurveyService).ArchiveSurvey, models.SurveyArchived, true},
+		{"close draft", models.SurveyDraft, (*SurveyService).CloseSurvey, models.SurveyClosed, false},
+		{"archive published", models.SurveyPublished, (*SurveyService).ArchiveSurvey, models.SurveyArchived, false},
+		{"publish published", models.SurveyPublished, (*SurveyService).PublishSurvey, models.SurveyPublished, false},
+		{"publish archived", models.SurveyArchived, (*SurveyService).PublishSurvey, models.SurveyPublished, false},
+	}
+	for _, test := range tests {
+		t.Run("should "+test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			surveyID, now := ksuid.New(), time.Now()
+			mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+			mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(&models.Survey{ID: surveyID, Status: test.from}, nil)
+			timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+			if test.allowed {
+				timeGeneratorMock.EXPECT().Now().Return(now)
+				expected := &models.Survey{ID: surveyID, Version: 1, Status: test.to, UpdatedAt: now}
+				mockSurveyRepo.EXPECT().Update(models.DefaultWorkspace, surveyID, expected).Return(expected, nil)
+			}
+			surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, timeGeneratorMock)
+			survey, err := test.move(surveyService, models.DefaultWorkspace, surveyID)
+			if test.allowed {
+				assert.NoError(t, err)
+				assert.Equal(t, test.to, survey.Status)
+			} else {
+				assert.True(t, errors.Is(err, services.ErrInvalidTransition))
+				assert.Nil(t, survey)
+			}
+		})
+	}
+	t.Run("should return error when survey is not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(nil, repositories.ErrNotFound)
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
+		survey, err := surveyService.PublishSurvey(models.DefaultWorkspace, surveyID)
 		assert.Equal(t, repositories.ErrNotFound, err)
-		assert.Nil(t, surveys)
+		assert.Nil(t, survey)
 	})
 }
 
@@ -419,28 +597,36 @@ func TestSurveyService_SaveResponse(t *testing.T) {
 		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
 		timeGeneratorMock.EXPECT().Now().Return(now)
 		mockResponse := models.Response{
-			ID:        responseID,
-			SurveyID:  surveyID,
-			CreatedAt: now,
+			ID:            responseID,
+			WorkspaceID:   models.DefaultWorkspace,
+			SurveyID:      surveyID,
+			SurveyVersion: 1,
+			CreatedAt:     now,
 			Answers: []models.Answer{
 				{
 					QuestionID: qID1,
-					Answer:     false,
+					Answer:     models.BoolValue(false),
 				},
 				{
 					QuestionID: qID2,
-					Answer:     true,
+					Answer:     models.BoolValue(true),
 				},
 			},
 		}
 		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
 		mockResponseRepo.EXPECT().Create(&mockResponse).Return(&mockResponse, nil)
 		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
-		mockSurveyRepo.EXPECT().Get(surveyID).Return(&models.Survey{}, nil)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(&models.Survey{
+			ID: surveyID,
+			Questions: []models.Question{
+				{ID: qID1, Question: "is this place good?", Type: models.QuestionYesNo},
+				{ID: qID2, Question: "does this place has parking?", Type: models.QuestionYesNo},
+			},
+		}, nil)
 		mockIDGenerator := idgenerator_mock.NewMockIDGenerator(ctrl)
 		mockIDGenerator.EXPECT().Generate().Return(responseID)
 		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, mockIDGenerator, timeGeneratorMock)
-		response, err := surveyService.SaveResponse(mockResponse)
+		response, err := surveyService.SaveResponse(models.DefaultWorkspace, mockResponse)
 		assert.NoError(t, err)
 		assert.Equal(t, mockResponse, *response)
 	})
@@ -454,30 +640,43 @@ func TestSurveyService_SaveResponse(t *testing.T) {
 			Answers: []models.Answer{
 				{
 					QuestionID: qID1,
-					Answer:     false,
+					Answer:     models.BoolValue(false),
 				},
 				{
 					QuestionID: qID2,
-					Answer:     true,
+					Answer:     models.BoolValue(true),
 				},
 				{
 					QuestionID: qID1,
-					Answer:     false,
+					Answer:     models.BoolValue(false),
 				},
 				{
 					QuestionID: qID2,
-					Answer:     true,
+					Answer:     models.BoolValue(true),
 				},
 			},
 		}
 		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
 		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
-		mockSurveyRepo.EXPECT().Get(surveyID).Return(nil, repositories.ErrNotFound)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(nil, repositories.ErrNotFound)
 		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, nil, nil)
-		response, err := surveyService.SaveResponse(mockResponse)
+		response, err := surveyService.SaveResponse(models.DefaultWorkspace, mockResponse)
 		assert.Equal(t, repositories.ErrNotFound, err)
 		assert.Nil(t, response)
 	})
+	t.Run("should return error when survey is not published", func(t *testing.T) {
+		for _, status := range []models.SurveyStatus{models.SurveyDraft, models.SurveyClosed, models.SurveyArchived} {
+			ctrl := gomock.NewController(t)
+			surveyID := ksuid.New()
+			mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+			mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(&models.Survey{ID: surveyID, Status: status}, nil)
+			surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
+			response, err := surveyService.SaveResponse(models.DefaultWorkspace, models.Response{SurveyID: surveyID})
+			assert.Equal(t, services.ErrSurveyNotPublished, err, string(status))
+			assert.Nil(t, response)
+			ctrl.Finish()
+		}
+	})
 	t.Run("should return error when answers are more than allowed questions", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
@@ -489,37 +688,255 @@ func TestSurveyService_SaveResponse(t *testing.T) {
 			Answers: []models.Answer{
 				{
 					QuestionID: qID1,
-					Answer:     false,
+					Answer:     models.BoolValue(false),
 				},
 				{
 					QuestionID: qID2,
-					Answer:     true,
+					Answer:     models.BoolValue(true),
 				},
 				{
 					QuestionID: qID1,
-					Answer:     false,
+					Answer:     models.BoolValue(false),
 				},
 				{
 					QuestionID: qID2,
-					Answer:     true,
+					Answer:     models.BoolValue(true),
 				},
 			},
 		}
 		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
 		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
-		mockSurveyRepo.EXPECT().Get(surveyID).Return(&models.Survey{}, nil)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(&models.Survey{}, nil)
 		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, nil, nil)
-		response, err := surveyService.SaveResponse(mockResponse)
+		response, err := surveyService.SaveResponse(models.DefaultWorkspace, mockResponse)
 		assert.Error(t, err)
 		assert.Nil(t, response)
 	})
 }
 
-func TestSurveyService_GetResponses(t *testing.T) {
-	t.Run("should successfully get responses for a survey", func(t *testing.T) {
+func TestSurveyService_SaveResponse_Validation(t *testing.T) {
+	max := 5.0
+	surveyID, ratingID, choiceID := ksuid.New(), ksuid.New(), ksuid.New()
+	survey := &models.Survey{
+		ID: surveyID,
+		Questions: []models.Question{
+			{ID: ratingID, Question: "how was it?", Type: models.QuestionRating, Max: &max},
+			{ID: choiceID, Question: "what did you eat?", Type: models.QuestionSingleChoice, Options: []string{"pizza", "pasta"}},
+		},
+	}
+	t.Run("should return error when answer does not match the question type", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(survey, nil)
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
+		response, err := surveyService.SaveResponse(models.DefaultWorkspace, models.Response{
+			SurveyID: surveyID,
+			Answers:  []models.Answer{{QuestionID: ratingID, Answer: models.BoolValue(true)}},
+		})
+		assert.Error(t, err)
+		assert.Nil(t, response)
+	})
+	t.Run("should return error when answer is not an option", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(survey, nil)
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
+		response, err := surveyService.SaveResponse(models.DefaultWorkspace, models.Response{
+			SurveyID: surveyID,
+			Answers:  []models.Answer{{QuestionID: choiceID, Answer: models.TextValue("salad")}},
+		})
+		assert.Error(t, err)
+		assert.Nil(t, response)
+	})
+	t.Run("should return error when question is not part of the survey", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(survey, nil)
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
+		response, err := surveyService.SaveResponse(models.DefaultWorkspace, models.Response{
+			SurveyID: surveyID,
+			Answers:  []models.Answer{{QuestionID: ksuid.New(), Answer: models.NumberValue(4)}},
+		})
+		assert.Error(t, err)
+		assert.Nil(t, response)
+	})
+	t.Run("should return every violation with the question it belongs to", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		requiredID, unknownID := ksuid.New(), ksuid.New()
+		requiredSurvey := *survey
+		requiredSurvey.Questions = append([]models.Question{
+			{ID: requiredID, Question: "is this place good?", Type: models.QuestionYesNo, Required: true},
+		}, survey.Questions...)
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(&requiredSurvey, nil)
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
+		response, err := surveyService.SaveResponse(models.DefaultWorkspace, models.Response{
+			SurveyID: surveyID,
+			Answers: []models.Answer{
+				{QuestionID: unknownID, Answer: models.BoolValue(true)},
+				{QuestionID: ratingID, Answer: models.NumberValue(4)},
+				{QuestionID: ratingID, Answer: models.NumberValue(5)},
+				{QuestionID: choiceID, Answer: models.TextValue("salad")},
+			},
+		})
+		assert.Nil(t, response)
+		var validationErr *services.ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, []services.Violation{
+			{QuestionID: unknownID, Message: "question is not part of the survey"},
+			{QuestionID: ratingID, Message: "question is answered more than once"},
+			{QuestionID: choiceID, Message: `"salad" is not an option`},
+			{QuestionID: requiredID, Message: "question is required"},
+		}, validationErr.Violations)
+	})
+	t.Run("should allow optional questions to be skipped", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(survey, nil)
+		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		mockResponseRepo.EXPECT().Create(gomock.Any()).DoAndReturn(func(response *models.Response) (*models.Response, error) {
+			return response, nil
+		})
+		mockIDGenerator := idgenerator_mock.NewMockIDGenerator(ctrl)
+		mockIDGenerator.EXPECT().Generate().Return(ksuid.New())
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		timeGeneratorMock.EXPECT().Now().Return(time.Now())
+		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, mockIDGenerator, timeGeneratorMock)
+		response, err := surveyService.SaveResponse(models.DefaultWorkspace, models.Response{SurveyID: surveyID})
+		assert.NoError(t, err)
+		assert.Empty(t, response.Answers)
+	})
+	t.Run("should save typed answers", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		now := time.Now()
+		responseID := ksuid.New()
+		expected := models.Response{
+			ID:            responseID,
+			WorkspaceID:   models.DefaultWorkspace,
+			SurveyID:      surveyID,
+			SurveyVersion: 1,
+			CreatedAt:     now,
+			Answers: []models.Answer{
+				{QuestionID: ratingID, Answer: models.NumberValue(4)},
+				{QuestionID: choiceID, Answer: models.TextValue("pasta")},
+			},
+		}
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(survey, nil)
+		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		mockResponseRepo.EXPECT().Create(&expected).Return(&expected, nil)
+		mockIDGenerator := idgenerator_mock.NewMockIDGenerator(ctrl)
+		mockIDGenerator.EXPECT().Generate().Return(responseID)
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		timeGeneratorMock.EXPECT().Now().Return(now)
+		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, mockIDGenerator, timeGeneratorMock)
+		response, err := surveyService.SaveResponse(models.DefaultWorkspace, models.Response{SurveyID: surveyID, Answers: expected.Answers})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, *response)
+	})
+}
+
+func TestSurveyService_SaveResponse_Schedule(t *testing.T) {
+	now := time.Now()
+	surveyID, questionID := ksuid.New(), ksuid.New()
+	newResponse := func() models.Response {
+		return models.Response{SurveyID: surveyID, Answers: []models.Answer{{QuestionID: questionID, Answer: models.BoolValue(true)}}}
+	}
+	newSurvey := func() *models.Survey {
+		return &models.Survey{ID: surveyID, Status: models.SurveyPublished, Questions: []models.Question{{ID: questionID, Question: "is this place good?"}}}
+	}
+	t.Run("should return error before the survey opens and after it closes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		opensAt, closesAt := now.Add(time.Hour), now.Add(2*time.Hour)
+		survey := newSurvey()
+		survey.OpensAt, survey.ClosesAt = &opensAt, &closesAt
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(survey, nil).Times(2)
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		gomock.InOrder(
+			timeGeneratorMock.EXPECT().Now().Return(now),
+			timeGeneratorMock.EXPECT().Now().Return(closesAt),
+		)
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, timeGeneratorMock)
+		_, err := surveyService.SaveResponse(models.DefaultWorkspace, newResponse())
+		assert.Equal(t, services.ErrSurveyNotOpen, err)
+		_, err = surveyService.SaveResponse(models.DefaultWorkspace, newResponse())
+		assert.Equal(t, services.ErrSurveyClosed, err)
+	})
+	t.Run("should save responses until the quota is reached", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		survey := newSurvey()
+		survey.MaxResponses = 2
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(survey, nil).Times(2)
+		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		gomock.InOrder(
+			mockResponseRepo.EXPECT().CountBySurveyID(models.DefaultWorkspace, surveyID).Return(1, nil),
+			mockResponseRepo.EXPECT().Create(gomock.Any()).DoAndReturn(func(response *models.Response) (*models.Response, error) {
+				return response, nil
+			}),
+			mockResponseRepo.EXPECT().CountBySurveyID(models.DefaultWorkspace, surveyID).Return(2, nil),
+		)
+		mockIDGenerator := idgenerator_mock.NewMockIDGenerator(ctrl)
+		mockIDGenerator.EXPECT().Generate().Return(ksuid.New())
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		timeGeneratorMock.EXPECT().Now().Return(now).Times(2)
+		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, mockIDGenerator, timeGeneratorMock)
+		response, err := surveyService.SaveResponse(models.DefaultWorkspace, newResponse())
+		assert.NoError(t, err)
+		assert.Equal(t, now, response.CreatedAt)
+		response, err = surveyService.SaveResponse(models.DefaultWorkspace, newResponse())
+		assert.Equal(t, services.ErrQuotaReached, err)
+		assert.Nil(t, response)
+	})
+}
+
+func TestSurveyService_GetSurveyDetails(t *testing.T) {
+	t.Run("should return survey with remaining quota and time to close", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
+		surveyID, now := ksuid.New(), time.Now()
+		closesAt := now.Add(time.Minute)
+		survey := &models.Survey{ID: surveyID, Status: models.SurveyPublished, ClosesAt: &closesAt, MaxResponses: 10}
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(survey, nil)
 		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		mockResponseRepo.EXPECT().CountBySurveyID(models.DefaultWorkspace, surveyID).Return(4, nil)
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		timeGeneratorMock.EXPECT().Now().Return(now)
+		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, nil, timeGeneratorMock)
+		details, err := surveyService.GetSurveyDetails(models.DefaultWorkspace, surveyID)
+		assert.NoError(t, err)
+		assert.Equal(t, *survey, details.Survey)
+		assert.True(t, details.Availability.AcceptingResponses)
+		assert.Equal(t, 6, *details.Availability.RemainingResponses)
+		assert.Equal(t, int64(60), *details.Availability.ClosesInSeconds)
+	})
+	t.Run("should return error when survey is not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(nil, repositories.ErrNotFound)
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
+		details, err := surveyService.GetSurveyDetails(models.DefaultWorkspace, surveyID)
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Nil(t, details)
+	})
+}
+
+func TestSurveyService_GetResponses(t *testing.T) {
+	t.Run("should successfully get a page of responses for a survey", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
 		surveyID, qID1, qID2 := ksuid.New(), ksuid.New(), ksuid.New()
 		mockResponses := []models.Response{
 			{
@@ -529,32 +946,81 @@ func TestSurveyService_GetResponses(t *testing.T) {
 				Answers: []models.Answer{
 					{
 						QuestionID: qID1,
-						Answer:     false,
+						Answer:     models.BoolValue(false),
 					},
 					{
 						QuestionID: qID2,
-						Answer:     true,
+						Answer:     models.BoolValue(true),
 					},
 				},
 			},
 		}
-		mockResponseRepo.EXPECT().GetBySurveyID(surveyID).Return(mockResponses, nil)
-		surveyService := NewSurveyService(3, nil, mockResponseRepo, nil, nil)
-		responses, err := surveyService.GetResponses(surveyID)
+		query := models.ResponseQuery{SurveyID: surveyID, PageQuery: models.PageQuery{Limit: 1}}
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().GetVersions(models.DefaultWorkspace, surveyID).Return([]models.Survey{{ID: surveyID, Version: 1}}, nil)
+		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+		mockResponseRepo.EXPECT().GetAll(models.DefaultWorkspace, query).Return(&models.ResponsePage{Responses: mockResponses, Next: "next"}, nil)
+		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, nil, nil)
+		page, err := surveyService.GetResponses(models.DefaultWorkspace, query, models.VersionScope{})
 		assert.NoError(t, err)
-		assert.Equal(t, mockResponses, responses)
+		assert.Equal(t, &models.ResponsePage{Responses: mockResponses, Next: "next"}, page)
 	})
-	t.Run("should return error when repo returns error", func(t *testing.T) {
+	t.Run("should return error when survey does not exist", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
-		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
 		surveyID := ksuid.New()
-		mockResponseRepo.EXPECT().GetBySurveyID(surveyID).Return(nil, repositories.ErrNotFound)
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().GetVersions(models.DefaultWorkspace, surveyID).Return(nil, repositories.ErrNotFound)
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
+		page, err := surveyService.GetResponses(models.DefaultWorkspace, models.ResponseQuery{SurveyID: surveyID}, models.VersionScope{})
+		assert.Equal(t, repositories.ErrNotFound, err)
+		assert.Nil(t, page)
+	})
+}
 
-		surveyService := NewSurveyService(3, nil, mockResponseRepo, nil, nil)
-		responses, err := surveyService.GetResponses(surveyID)
+func TestSurveyService_GetResponses_Versions(t *testing.T) {
+	surveyID, qID1, qID2 := ksuid.New(), ksuid.New(), ksuid.New()
+	first := models.Survey{ID: surveyID, Version: 1, Questions: []models.Question{{ID: qID1, Question: "is this place good?"}}}
+	reworded := first
+	reworded.Version = 2
+	reworded.Questions = []models.Question{{ID: qID1, Question: "do you like this place?"}}
+	extended := first
+	extended.Version = 3
+	extended.Questions = []models.Question{{ID: qID1, Question: "do you like this place?"}, {ID: qID2, Question: "would you come back?"}}
+	tests := []struct {
+		name     string
+		scope    models.VersionScope
+		expected []int
+	}{
+		{"query responses of every version", models.VersionScope{}, nil},
+		{"query responses of one version", models.VersionScope{Version: 2}, []int{2}},
+		{"query responses of the first version", models.VersionScope{Version: 1}, []int{1}},
+		{"merge responses of compatible versions", models.VersionScope{Version: 2, Compatible: true}, []int{1, 2}},
+		{"not merge responses of incompatible versions", models.VersionScope{Version: 3, Compatible: true}, []int{3}},
+	}
+	for _, test := range tests {
+		t.Run("should "+test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
+			mockResponseRepo.EXPECT().GetAll(models.DefaultWorkspace, models.ResponseQuery{SurveyID: surveyID, Versions: test.expected}).
+				Return(&models.ResponsePage{Responses: []models.Response{}}, nil)
+			mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+			mockSurveyRepo.EXPECT().GetVersions(models.DefaultWorkspace, surveyID).Return([]models.Survey{first, reworded, extended}, nil)
+			surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, nil, nil)
+			_, err := surveyService.GetResponses(models.DefaultWorkspace, models.ResponseQuery{SurveyID: surveyID}, test.scope)
+			assert.NoError(t, err)
+		})
+	}
+	t.Run("should return error when the version does not exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().GetVersions(models.DefaultWorkspace, surveyID).Return([]models.Survey{first}, nil)
+		surveyService := NewSurveyService(3, mockSurveyRepo, nil, nil, nil)
+		page, err := surveyService.GetResponses(models.DefaultWorkspace, models.ResponseQuery{SurveyID: surveyID}, models.VersionScope{Version: 4})
 		assert.Equal(t, repositories.ErrNotFound, err)
-		assert.Nil(t, responses)
+		assert.Nil(t, page)
 	})
 }
 
@@ -582,18 +1048,23 @@ func TestSurveyService_Dump(t *testing.T) {
 					SurveyID:  surveyID,
 					CreatedAt: time.Now(),
 					Answers: []models.Answer{
-						{QuestionID: qID1, Answer: true},
-						{QuestionID: qID2, Answer: false},
+						{QuestionID: qID1, Answer: models.BoolValue(true)},
+						{QuestionID: qID2, Answer: models.BoolValue(false)},
 					},
 				},
 			},
 		}
 		mockResponseRepo := repositories_mock.NewMockResponseRepoInterface(ctrl)
 		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		versionEntries := map[ksuid.KSUID][]models.Survey{surveyID: {surveyEntries[surveyID]}}
 		mockSurveyRepo.EXPECT().Entries().Return(surveyEntries)
+		mockSurveyRepo.EXPECT().VersionEntries().Return(versionEntries)
 		mockResponseRepo.EXPECT().Entries().Return(responseEntries)
 		surveyService := NewSurveyService(3, mockSurveyRepo, mockResponseRepo, nil, nil)
 		repoEntries := surveyService.Entries()
-		assert.Equal(t, models.DBEntry{Surveys: surveyEntries, Responses: responseEntries}, *repoEntries)
+		expected := models.DBEntry{Workspaces: map[models.WorkspaceID]*models.WorkspaceEntry{
+			models.DefaultWorkspace: {Surveys: surveyEntries, SurveyVersions: versionEntries, Responses: responseEntries},
+		}}
+		assert.Equal(t, expected, *repoEntries)
 	})
 }
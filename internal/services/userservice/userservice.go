@@ -0,0 +1,147 @@
+package userservice
+
+import (
+	"fmt"
+	"github.com/segmentio/ksuid"
+	"strings"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/services"
+	"survey-platform/pkg/idgenerator"
+	"survey-platform/pkg/timegenerator"
+)
+
+type UserService struct {
+	userRepo      repositories.UserRepoInterface
+	surveyRepo    repositories.SurveyRepoInterface
+	idGenerator   idgenerator.IDGenerator
+	timeGenerator timegenerator.TimeGenInterface
+}
+
+func NewUserService(userRepo repositories.UserRepoInterface, surveyRepo repositories.SurveyRepoInterface,
+	idGenerator idgenerator.IDGenerator, timeGenerator timegenerator.TimeGenInterface) *UserService {
+	return &UserService{
+		userRepo:      userRepo,
+		surveyRepo:    surveyRepo,
+		idGenerator:   idGenerator,
+		timeGenerator: timeGenerator,
+	}
+}
+
+// CreateUser adds user to workspace with the name, email and roles of user
+func (s *UserService) CreateUser(workspace models.WorkspaceID, user models.User) (*models.User, error) {
+	if strings.TrimSpace(user.Name) == "" {
+		return nil, services.ErrInvalidUser
+	}
+	if !workspace.Valid() {
+		return nil, fmt.Errorf("%w: invalid workspace %q", services.ErrInvalidUser, workspace)
+	}
+	roles, err := s.validRoles(workspace, user.Roles)
+	if err != nil {
+		return nil, err
+	}
+	return s.userRepo.Create(&models.User{
+		ID:          s.idGenerator.Generate(),
+		WorkspaceID: workspace,
+		Name:        user.Name,
+		Email:       user.Email,
+		Roles:       roles,
+		CreatedAt:   s.timeGenerator.Now(),
+	})
+}
+
+// GetUsers returns every user of workspace
+func (s *UserService) GetUsers(workspace models.WorkspaceID) ([]models.User, error) {
+	return s.userRepo.GetAll(workspace)
+}
+
+func (s *UserService) GetUser(workspace models.WorkspaceID, id ksuid.KSUID) (*models.User, error) {
+	return s.userRepo.Get(workspace, id)
+}
+
+// SetRoles replaces every role of the user, roles on surveys need the survey to be in the workspace of the user
+func (s *UserService) SetRoles(workspace models.WorkspaceID, id ksuid.KSUID, roles []models.RoleAssignment) (*models.User, error) {
+	user, err := s.userRepo.Get(workspace, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Roles, err = s.validRoles(workspace, roles); err != nil {
+		return nil, err
+	}
+	return s.userRepo.Update(workspace, id, user)
+}
+
+// DeleteUser removes a user of workspace, the keys bound to the user stop being allowed anything
+func (s *UserService) DeleteUser(workspace models.WorkspaceID, id ksuid.KSUID) error {
+	return s.userRepo.Delete(workspace, id)
+}
+
+// Authorize returns ErrForbidden when key is bound to a user who holds no role granting every scope in scopes
+// on the survey surveyID, roles on the whole workspace applying to every survey, a nil surveyID asks for workspace roles
+// keys which are not bound to a user are only limited by their scopes, which are checked when authenticating
+func (s *UserService) Authorize(key *models.APIKey, surveyID *ksuid.KSUID, scopes ...models.Scope) error {
+	if key.UserID == nil {
+		return nil
+	}
+	user, err := s.userRepo.Get(key.Workspace(), *key.UserID)
+	if err == repositories.ErrNotFound {
+		return fmt.Errorf("%w: the user of the api key was deleted", services.ErrForbidden)
+	} else if err != nil {
+		return err
+	}
+	target := "the workspace"
+	if surveyID != nil {
+		target = "survey " + surveyID.String()
+	}
+	for _, scope := range scopes {
+		if user.Can(scope, surveyID) {
+			continue
+		}
+		roles := user.RolesOn(surveyID)
+		if len(roles) == 0 {
+			return fmt.Errorf("%w: user %s has no role on %s", services.ErrForbidden, user.Name, target)
+		}
+		return fmt.Errorf("%w: user %s is %s on %s, which does not grant %s",
+			services.ErrForbidden, user.Name, joinRoles(roles), target, scope)
+	}
+	return nil
+}
+
+// Entries returns the stored users, to be persisted
+func (s *UserService) Entries() map[ksuid.KSUID]models.User {
+	return s.userRepo.Entries()
+}
+
+// validRoles returns roles without duplicates, or ErrInvalidUser for unknown roles and surveys outside of workspace
+func (s *UserService) validRoles(workspace models.WorkspaceID, roles []models.RoleAssignment) ([]models.RoleAssignment, error) {
+	valid := make([]models.RoleAssignment, 0, len(roles))
+	seen := make(map[string]bool, len(roles))
+	for _, assignment := range roles {
+		if !assignment.Role.Valid() {
+			return nil, fmt.Errorf("%w: unknown role %q", services.ErrInvalidUser, assignment.Role)
+		}
+		key := string(assignment.Role)
+		if assignment.SurveyID != nil {
+			_, err := s.surveyRepo.Get(workspace, *assignment.SurveyID)
+			if err == repositories.ErrNotFound {
+				return nil, fmt.Errorf("%w: unknown survey %s", services.ErrInvalidUser, assignment.SurveyID.String())
+			} else if err != nil {
+				return nil, err
+			}
+			key += " " + assignment.SurveyID.String()
+		}
+		if !seen[key] {
+			seen[key] = true
+			valid = append(valid, assignment)
+		}
+	}
+	return valid, nil
+}
+
+func joinRoles(roles []models.Role) string {
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		names = append(names, string(role))
+	}
+	return strings.Join(names, " and ")
+}
@@ -0,0 +1,156 @@
+package userservice
+
+import (
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"survey-platform/internal/models"
+	"survey-platform/internal/repositories"
+	"survey-platform/internal/repositories/repositories_mock"
+	"survey-platform/internal/services"
+	"survey-platform/pkg/idgenerator/idgenerator_mock"
+	"survey-platform/pkg/timegenerator/timegenerator_mock"
+	"testing"
+	"time"
+)
+
+func TestUserService_CreateUser(t *testing.T) {
+	t.Run("should store the user with its roles once", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		id, surveyID, now := ksuid.New(), ksuid.New(), time.Now()
+		idGeneratorMock := idgenerator_mock.NewMockIDGenerator(ctrl)
+		idGeneratorMock.EXPECT().Generate().Return(id)
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		timeGeneratorMock.EXPECT().Now().Return(now)
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.WorkspaceID("acme"), surveyID).Return(&models.Survey{ID: surveyID}, nil).Times(2)
+		expected := models.User{
+			ID:          id,
+			WorkspaceID: "acme",
+			Name:        "alice",
+			Email:       "alice@example.com",
+			Roles:       []models.RoleAssignment{{Role: models.RoleAnalyst}, {Role: models.RoleEditor, SurveyID: &surveyID}},
+			CreatedAt:   now,
+		}
+		mockUserRepo := repositories_mock.NewMockUserRepoInterface(ctrl)
+		mockUserRepo.EXPECT().Create(&expected).Return(&expected, nil)
+		userService := NewUserService(mockUserRepo, mockSurveyRepo, idGeneratorMock, timeGeneratorMock)
+		created, err := userService.CreateUser("acme", models.User{
+			ID:          ksuid.New(),
+			WorkspaceID: "other",
+			Name:        "alice",
+			Email:       "alice@example.com",
+			Roles: []models.RoleAssignment{
+				{Role: models.RoleAnalyst}, {Role: models.RoleEditor, SurveyID: &surveyID}, {Role: models.RoleEditor, SurveyID: &surveyID},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, expected, *created)
+	})
+	t.Run("should reject users without a name, with unknown roles or with roles on surveys of other workspaces", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		surveyID := ksuid.New()
+		mockSurveyRepo := repositories_mock.NewMockSurveyRepoInterface(ctrl)
+		mockSurveyRepo.EXPECT().Get(models.DefaultWorkspace, surveyID).Return(nil, repositories.ErrNotFound)
+		userService := NewUserService(nil, mockSurveyRepo, nil, nil)
+		for _, user := range []models.User{
+			{Name: " ", Roles: []models.RoleAssignment{{Role: models.RoleOwner}}},
+			{Name: "alice", Roles: []models.RoleAssignment{{Role: "admin"}}},
+			{Name: "alice", Roles: []models.RoleAssignment{{Role: models.RoleEditor, SurveyID: &surveyID}}},
+		} {
+			created, err := userService.CreateUser(models.DefaultWorkspace, user)
+			assert.True(t, errors.Is(err, services.ErrInvalidUser), user)
+			assert.Nil(t, created)
+		}
+	})
+}
+
+func TestUserService_SetRoles(t *testing.T) {
+	t.Run("should replace the roles of the user", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		user := models.User{ID: ksuid.New(), Name: "alice", Roles: []models.RoleAssignment{{Role: models.RoleAnalyst}}}
+		mockUserRepo := repositories_mock.NewMockUserRepoInterface(ctrl)
+		mockUserRepo.EXPECT().Get(models.DefaultWorkspace, user.ID).Return(&user, nil)
+		updated := user
+		updated.Roles = []models.RoleAssignment{{Role: models.RoleOwner}}
+		mockUserRepo.EXPECT().Update(models.DefaultWorkspace, user.ID, &updated).Return(&updated, nil)
+		userService := NewUserService(mockUserRepo, nil, nil, nil)
+		stored, err := userService.SetRoles(models.DefaultWorkspace, user.ID, []models.RoleAssignment{{Role: models.RoleOwner}})
+		require.NoError(t, err)
+		assert.Equal(t, updated, *stored)
+	})
+	t.Run("should return ErrNotFound for users of other workspaces", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		id := ksuid.New()
+		mockUserRepo := repositories_mock.NewMockUserRepoInterface(ctrl)
+		mockUserRepo.EXPECT().Get(models.DefaultWorkspace, id).Return(nil, repositories.ErrNotFound)
+		userService := NewUserService(mockUserRepo, nil, nil, nil)
+		_, err := userService.SetRoles(models.DefaultWorkspace, id, nil)
+		assert.Equal(t, repositories.ErrNotFound, err)
+	})
+}
+
+func TestUserService_Authorize(t *testing.T) {
+	surveyID, otherID, userID := ksuid.New(), ksuid.New(), ksuid.New()
+	user := models.User{ID: userID, Name: "alice", Roles: []models.RoleAssignment{
+		{Role: models.RoleAnalyst},
+		{Role: models.RoleEditor, SurveyID: &surveyID},
+	}}
+	for _, tc := range []struct {
+		name     string
+		surveyID *ksuid.KSUID
+		scopes   []models.Scope
+		reason   string
+	}{
+		{name: "read the results of any survey", surveyID: &otherID, scopes: []models.Scope{models.ScopeResponsesRead}},
+		{name: "list the surveys of the workspace", scopes: []models.Scope{models.ScopeSurveysRead}},
+		{name: "edit the survey of the editor role", surveyID: &surveyID, scopes: []models.Scope{models.ScopeSurveysWrite}},
+		{name: "edit other surveys", surveyID: &otherID, scopes: []models.Scope{models.ScopeSurveysWrite},
+			reason: "access denied: user alice is analyst on survey " + otherID.String() + ", which does not grant surveys:write"},
+		{name: "answer surveys", surveyID: &surveyID, scopes: []models.Scope{models.ScopeSurveysRead, models.ScopeResponsesWrite},
+			reason: "access denied: user alice is analyst and editor on survey " + surveyID.String() + ", which does not grant responses:write"},
+		{name: "manage keys", scopes: []models.Scope{models.ScopeKeysManage},
+			reason: "access denied: user alice is analyst on the workspace, which does not grant keys:manage"},
+	} {
+		t.Run("should decide whether an analyst may "+tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockUserRepo := repositories_mock.NewMockUserRepoInterface(ctrl)
+			mockUserRepo.EXPECT().Get(models.DefaultWorkspace, userID).Return(&user, nil)
+			userService := NewUserService(mockUserRepo, nil, nil, nil)
+			err := userService.Authorize(&models.APIKey{UserID: &userID}, tc.surveyID, tc.scopes...)
+			if tc.reason == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, services.ErrForbidden))
+			assert.EqualError(t, err, tc.reason)
+		})
+	}
+	t.Run("should deny users without a role on the survey", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		respondent := models.User{ID: userID, Name: "bob", Roles: []models.RoleAssignment{{Role: models.RoleRespondent, SurveyID: &surveyID}}}
+		mockUserRepo := repositories_mock.NewMockUserRepoInterface(ctrl)
+		mockUserRepo.EXPECT().Get(models.DefaultWorkspace, userID).Return(&respondent, nil)
+		userService := NewUserService(mockUserRepo, nil, nil, nil)
+		err := userService.Authorize(&models.APIKey{UserID: &userID}, &otherID, models.ScopeSurveysRead)
+		assert.EqualError(t, err, "access denied: user bob has no role on survey "+otherID.String())
+	})
+	t.Run("should deny keys of deleted users and only check the scopes of keys without users", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockUserRepo := repositories_mock.NewMockUserRepoInterface(ctrl)
+		mockUserRepo.EXPECT().Get(models.WorkspaceID("acme"), userID).Return(nil, repositories.ErrNotFound)
+		userService := NewUserService(mockUserRepo, nil, nil, nil)
+		err := userService.Authorize(&models.APIKey{WorkspaceID: "acme", UserID: &userID}, nil, models.ScopeSurveysRead)
+		assert.True(t, errors.Is(err, services.ErrForbidden))
+		assert.NoError(t, userService.Authorize(&models.APIKey{}, nil, models.ScopeKeysManage))
+	})
+}
@@ -0,0 +1,86 @@
+// Package export writes tables to files row by row, so that large tables never have to be held in memory
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+	FormatXLSX   Format = "xlsx"
+)
+
+// ContentType returns the media type of files written in the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// Column is a column of a table, Key identifies it in keyed formats and Title heads it in tabular ones
+type Column struct {
+	Key   string
+	Title string
+}
+
+// Writer writes a table, the header has to be written before any row and Close must be called once the last row is written
+// cells hold nil, string, float64, bool, []string or time.Time values, in the order of the columns
+type Writer interface {
+	WriteHeader(columns []Column) error
+	WriteRow(cells []interface{}) error
+	Close() error
+}
+
+// NewWriter returns a writer writing the table to w in the given format
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatNDJSON:
+		return newNDJSONWriter(w), nil
+	case FormatXLSX:
+		return newXLSXWriter(w), nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+}
+
+// ListSeparator joins the options of a list cell in formats without lists
+const ListSeparator = "; "
+
+// formatCell returns the text of a cell in formats without typed cells
+func formatCell(cell interface{}) string {
+	switch value := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case []string:
+		return strings.Join(value, ListSeparator)
+	case time.Time:
+		return value.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(value)
+	}
+}
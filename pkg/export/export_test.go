@@ -0,0 +1,82 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+var (
+	testColumns = []Column{{Key: "id", Title: "ID"}, {Key: "q1", Title: "is it good?"}, {Key: "q2", Title: "what, & why?"}}
+	testRows    = [][]interface{}{
+		{"1", true, []string{"pizza", "pasta"}},
+		{"2", 4.5, nil},
+		{"3", time.Date(2021, time.June, 1, 10, 0, 0, 0, time.UTC), "<none>"},
+	}
+)
+
+func writeTable(t *testing.T, format Format) []byte {
+	var buf bytes.Buffer
+	writer, err := NewWriter(format, &buf)
+	require.NoError(t, err)
+	require.NoError(t, writer.WriteHeader(testColumns))
+	for _, row := range testRows {
+		require.NoError(t, writer.WriteRow(row))
+	}
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestNewWriter(t *testing.T) {
+	t.Run("should return error for unknown formats", func(t *testing.T) {
+		writer, err := NewWriter("pdf", io.Discard)
+		assert.True(t, errors.Is(err, ErrUnknownFormat))
+		assert.Nil(t, writer)
+	})
+}
+
+func TestCSVWriter(t *testing.T) {
+	t.Run("should write the titles and one record per row", func(t *testing.T) {
+		assert.Equal(t, "ID,is it good?,\"what, & why?\"\n"+
+			"1,true,pizza; pasta\n"+
+			"2,4.5,\n"+
+			"3,2021-06-01T10:00:00Z,<none>\n", string(writeTable(t, FormatCSV)))
+	})
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	t.Run("should write one object per row keyed in column order", func(t *testing.T) {
+		assert.Equal(t, `{"id":"1","q1":true,"q2":["pizza","pasta"]}`+"\n"+
+			`{"id":"2","q1":4.5,"q2":null}`+"\n"+
+			`{"id":"3","q1":"2021-06-01T10:00:00Z","q2":"<none>"}`+"\n", string(writeTable(t, FormatNDJSON)))
+	})
+}
+
+func TestXLSXWriter(t *testing.T) {
+	t.Run("should write a workbook with a row per row", func(t *testing.T) {
+		data := writeTable(t, FormatXLSX)
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		parts := make(map[string]string)
+		for _, file := range archive.File {
+			reader, err := file.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			parts[file.Name] = string(content)
+		}
+		assert.Contains(t, parts, "[Content_Types].xml")
+		assert.Contains(t, parts, "xl/workbook.xml")
+		sheet := parts["xl/worksheets/sheet1.xml"]
+		assert.Contains(t, sheet, `<t xml:space="preserve">what, &amp; why?</t>`)
+		assert.Contains(t, sheet, `<c t="b"><v>1</v></c>`)
+		assert.Contains(t, sheet, `<c t="n"><v>4.5</v></c><c/></row>`)
+		assert.Contains(t, sheet, `<t xml:space="preserve">&lt;none&gt;</t>`)
+		assert.Equal(t, 4, bytes.Count([]byte(sheet), []byte("<row>")))
+	})
+}
@@ -0,0 +1,72 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// ndjsonWriter writes every row as a json object keyed by the column keys, in the order of the columns
+// values are encoded into scratch without escaping html, the output is data rather than markup
+type ndjsonWriter struct {
+	writer  *bufio.Writer
+	keys    [][]byte
+	scratch bytes.Buffer
+	encoder *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	n := &ndjsonWriter{writer: bufio.NewWriter(w)}
+	n.encoder = json.NewEncoder(&n.scratch)
+	n.encoder.SetEscapeHTML(false)
+	return n
+}
+
+// encode returns the json encoding of value, valid until the next call
+func (n *ndjsonWriter) encode(value interface{}) ([]byte, error) {
+	n.scratch.Reset()
+	if err := n.encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(n.scratch.Bytes(), []byte("\n")), nil
+}
+
+// WriteHeader only remembers the column keys, ndjson has no header line
+func (n *ndjsonWriter) WriteHeader(columns []Column) error {
+	n.keys = make([][]byte, len(columns))
+	for i, column := range columns {
+		key, err := n.encode(column.Key)
+		if err != nil {
+			return err
+		}
+		n.keys[i] = append([]byte(nil), key...)
+	}
+	return nil
+}
+
+func (n *ndjsonWriter) WriteRow(cells []interface{}) error {
+	n.writer.WriteByte('{')
+	for i, cell := range cells {
+		if i > 0 {
+			n.writer.WriteByte(',')
+		}
+		if t, ok := cell.(time.Time); ok {
+			cell = t.UTC()
+		}
+		value, err := n.encode(cell)
+		if err != nil {
+			return err
+		}
+		n.writer.Write(n.keys[i])
+		n.writer.WriteByte(':')
+		n.writer.Write(value)
+	}
+	n.writer.WriteString("}\n")
+	return nil
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.writer.Flush()
+}
@@ -0,0 +1,120 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+// the parts of a workbook holding a single sheet, the sheet itself is streamed into xlsxSheetPath
+const (
+	xlsxSheetPath    = "xl/worksheets/sheet1.xml"
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="responses" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxWriter streams a workbook with a single sheet, strings are written inline so no shared string table is kept
+type xlsxWriter struct {
+	archive *zip.Writer
+	sheet   *bufio.Writer
+}
+
+func newXLSXWriter(w io.Writer) *xlsxWriter {
+	return &xlsxWriter{archive: zip.NewWriter(w)}
+}
+
+// WriteHeader starts the sheet with a row of column titles
+func (x *xlsxWriter) WriteHeader(columns []Column) error {
+	sheet, err := x.archive.Create(xlsxSheetPath)
+	if err != nil {
+		return err
+	}
+	x.sheet = bufio.NewWriter(sheet)
+	x.sheet.WriteString(xlsxSheetStart)
+	cells := make([]interface{}, len(columns))
+	for i, column := range columns {
+		cells[i] = column.Title
+	}
+	return x.WriteRow(cells)
+}
+
+func (x *xlsxWriter) WriteRow(cells []interface{}) error {
+	x.sheet.WriteString("<row>")
+	for _, cell := range cells {
+		switch value := cell.(type) {
+		case nil:
+			x.sheet.WriteString("<c/>")
+		case float64:
+			x.sheet.WriteString(`<c t="n"><v>`)
+			x.sheet.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+			x.sheet.WriteString("</v></c>")
+		case bool:
+			x.sheet.WriteString(`<c t="b"><v>`)
+			if value {
+				x.sheet.WriteString("1")
+			} else {
+				x.sheet.WriteString("0")
+			}
+			x.sheet.WriteString("</v></c>")
+		default:
+			x.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			if err := xml.EscapeText(x.sheet, []byte(formatCell(cell))); err != nil {
+				return err
+			}
+			x.sheet.WriteString("</t></is></c>")
+		}
+	}
+	_, err := x.sheet.WriteString("</row>")
+	return err
+}
+
+// Close ends the sheet and writes the parts of the workbook referencing it
+func (x *xlsxWriter) Close() error {
+	if x.sheet == nil {
+		if err := x.WriteHeader(nil); err != nil {
+			return err
+		}
+	}
+	x.sheet.WriteString(xlsxSheetEnd)
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	parts := []struct{ path, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		w, err := x.archive.Create(part.path)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(w, part.content); err != nil {
+			return err
+		}
+	}
+	return x.archive.Close()
+}
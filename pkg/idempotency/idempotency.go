@@ -0,0 +1,115 @@
+// Package idempotency remembers the outcome of requests by the key the client sent along, so that retries are answered
+// with the outcome of the first request instead of being performed again
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"survey-platform/pkg/timegenerator"
+	"sync"
+	"time"
+)
+
+// ErrKeyReused is returned when a key comes back with another request than the one it was first used for
+var ErrKeyReused = errors.New("idempotency key was already used for another request")
+
+// Result is the outcome of a request, replayed to the retries of the request
+type Result struct {
+	Status int
+	Body   interface{}
+}
+
+// Store keeps the results of requests by key for ttl, it is safe for concurrent use
+// results are only kept in memory, so retries reaching another process or arriving after a restart run again
+type Store struct {
+	mu            sync.Mutex
+	ttl           time.Duration
+	timeGenerator timegenerator.TimeGenInterface
+	entries       map[string]*entry
+	// nextSweep is when the expired entries are dropped next
+	nextSweep time.Time
+}
+
+// entry is a request identified by a key, done is closed once result is set
+type entry struct {
+	fingerprint string
+	done        chan struct{}
+	result      Result
+	stored      bool
+	expiresAt   time.Time
+}
+
+func NewStore(ttl time.Duration, timeGenerator timegenerator.TimeGenInterface) *Store {
+	return &Store{
+		ttl:           ttl,
+		timeGenerator: timeGenerator,
+		entries:       make(map[string]*entry),
+	}
+}
+
+// Do runs fn for the first request with key and returns its result, which is replayed to the retries with the same
+// fingerprint until ttl passed, retries arriving while fn runs wait for it to finish, or for ctx to be done
+// results with a 5xx status are not kept so that a retry runs fn again, a retry with another fingerprint gets ErrKeyReused
+func (s *Store) Do(ctx context.Context, key, fingerprint string, fn func() Result) (result Result, replayed bool, err error) {
+	for {
+		s.mu.Lock()
+		now := s.timeGenerator.Now()
+		s.sweep(now)
+		existing, ok := s.entries[key]
+		if ok && existing.stored && !now.Before(existing.expiresAt) {
+			delete(s.entries, key)
+			ok = false
+		}
+		if !ok {
+			claimed := &entry{fingerprint: fingerprint, done: make(chan struct{})}
+			s.entries[key] = claimed
+			s.mu.Unlock()
+			return s.run(key, claimed, fn), false, nil
+		}
+		s.mu.Unlock()
+		if existing.fingerprint != fingerprint {
+			return Result{}, false, ErrKeyReused
+		}
+		select {
+		case <-existing.done:
+		case <-ctx.Done():
+			return Result{}, false, ctx.Err()
+		}
+		if existing.stored {
+			return existing.result, true, nil
+		}
+	}
+}
+
+// run calls fn for the request claimed under key and keeps its result, unless it is a server error
+// the claim is released even when fn panics so that the waiting retries run fn themselves
+func (s *Store) run(key string, claimed *entry, fn func() Result) Result {
+	var result Result
+	keep := false
+	defer func() {
+		s.mu.Lock()
+		if keep {
+			claimed.result, claimed.stored, claimed.expiresAt = result, true, s.timeGenerator.Now().Add(s.ttl)
+		} else {
+			delete(s.entries, key)
+		}
+		s.mu.Unlock()
+		close(claimed.done)
+	}()
+	result = fn()
+	keep = result.Status < 500
+	return result
+}
+
+// sweep drops the expired entries at most once per ttl, the caller must hold mu
+func (s *Store) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	for key, existing := range s.entries {
+		if existing.stored && !now.Before(existing.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+	s.nextSweep = now.Add(s.ttl)
+}
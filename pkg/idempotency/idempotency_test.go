@@ -0,0 +1,130 @@
+package idempotency
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"survey-platform/pkg/timegenerator/timegenerator_mock"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStore_Do(t *testing.T) {
+	now := time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC)
+	newStore := func(ctrl *gomock.Controller, times ...time.Time) *Store {
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		for _, at := range times {
+			timeGeneratorMock.EXPECT().Now().Return(at)
+		}
+		return NewStore(time.Hour, timeGeneratorMock)
+	}
+	t.Run("should run the first request and replay its result to retries", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		store := newStore(ctrl, now, now, now.Add(time.Minute))
+		calls := 0
+		fn := func() Result {
+			calls++
+			return Result{Status: http.StatusCreated, Body: calls}
+		}
+		result, replayed, err := store.Do(context.Background(), "key", "body", fn)
+		require.NoError(t, err)
+		assert.False(t, replayed)
+		assert.Equal(t, Result{Status: http.StatusCreated, Body: 1}, result)
+		result, replayed, err = store.Do(context.Background(), "key", "body", fn)
+		require.NoError(t, err)
+		assert.True(t, replayed)
+		assert.Equal(t, Result{Status: http.StatusCreated, Body: 1}, result)
+		assert.Equal(t, 1, calls)
+	})
+	t.Run("should reject a key reused for another request", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		store := newStore(ctrl, now, now, now)
+		_, _, err := store.Do(context.Background(), "key", "body", func() Result { return Result{Status: http.StatusCreated} })
+		require.NoError(t, err)
+		_, _, err = store.Do(context.Background(), "key", "other body", func() Result {
+			t.Fatal("a reused key must not run")
+			return Result{}
+		})
+		assert.Equal(t, ErrKeyReused, err)
+	})
+	t.Run("should run requests again once their result expired or when they failed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		store := newStore(ctrl, now, now, now.Add(time.Hour), now.Add(time.Hour), now.Add(time.Hour))
+		calls := 0
+		fn := func() Result {
+			calls++
+			if calls == 2 {
+				return Result{Status: http.StatusInternalServerError}
+			}
+			return Result{Status: http.StatusCreated}
+		}
+		for i := 0; i < 3; i++ {
+			_, replayed, err := store.Do(context.Background(), "key", "body", fn)
+			require.NoError(t, err)
+			assert.False(t, replayed)
+		}
+		assert.Equal(t, 3, calls)
+	})
+	t.Run("should make concurrent retries wait for the first request", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		timeGeneratorMock.EXPECT().Now().Return(now).AnyTimes()
+		store := NewStore(time.Hour, timeGeneratorMock)
+		started, release := make(chan struct{}), make(chan struct{})
+		go func() {
+			_, _, _ = store.Do(context.Background(), "key", "body", func() Result {
+				close(started)
+				<-release
+				return Result{Status: http.StatusCreated, Body: "first"}
+			})
+		}()
+		<-started
+		var wg sync.WaitGroup
+		results := make([]Result, 4)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				result, replayed, err := store.Do(context.Background(), "key", "body", func() Result {
+					return Result{Status: http.StatusCreated, Body: "retry"}
+				})
+				assert.NoError(t, err)
+				assert.True(t, replayed)
+				results[i] = result
+			}(i)
+		}
+		close(release)
+		wg.Wait()
+		for _, result := range results {
+			assert.Equal(t, Result{Status: http.StatusCreated, Body: "first"}, result)
+		}
+	})
+	t.Run("should stop waiting when the context of the retry is done", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		timeGeneratorMock := timegenerator_mock.NewMockTimeGenInterface(ctrl)
+		timeGeneratorMock.EXPECT().Now().Return(now).AnyTimes()
+		store := NewStore(time.Hour, timeGeneratorMock)
+		started, release := make(chan struct{}), make(chan struct{})
+		defer close(release)
+		go func() {
+			_, _, _ = store.Do(context.Background(), "key", "body", func() Result {
+				close(started)
+				<-release
+				return Result{Status: http.StatusCreated}
+			})
+		}()
+		<-started
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, _, err := store.Do(ctx, "key", "body", func() Result { return Result{} })
+		assert.Equal(t, context.Canceled, err)
+	})
+}
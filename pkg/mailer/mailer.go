@@ -0,0 +1,18 @@
+// Package mailer renders emails from templates and sends them over SMTP
+package mailer
+
+//go:generate mockgen -source=mailer.go -destination=./mailer_mock/mailer_mock.go -package=mailer_mock
+
+// Message is a plain text email to a single recipient
+// ID identifies the message across retries, it ends up in the Message-ID header
+type Message struct {
+	ID      string
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers messages, an error means the message may not have been delivered and can be sent again
+type Sender interface {
+	Send(message Message) error
+}
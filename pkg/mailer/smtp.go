@@ -0,0 +1,77 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPSender sends messages through the SMTP server at addr as from
+// auth is nil for servers accepting mail without authentication, STARTTLS is used whenever the server offers it
+type SMTPSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+	// now dates the messages, it is replaced in tests
+	now func() time.Time
+}
+
+func NewSMTPSender(addr, from string, auth smtp.Auth) *SMTPSender {
+	return &SMTPSender{
+		addr: addr,
+		from: from,
+		auth: auth,
+		now:  time.Now,
+	}
+}
+
+// PlainAuth returns the PLAIN authentication for username on the server at addr, nil when there is no username
+// credentials are only sent over TLS, or to a server on localhost
+func PlainAuth(addr, username, password string) smtp.Auth {
+	if username == "" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	return smtp.PlainAuth("", username, password, host)
+}
+
+func (s *SMTPSender) Send(message Message) error {
+	data, err := s.format(message)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{message.To}, data)
+}
+
+// format returns message with its headers, the subject is encoded when it is not plain ascii
+func (s *SMTPSender) format(message Message) ([]byte, error) {
+	for _, header := range []string{s.from, message.To, message.ID} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, fmt.Errorf("header %q spans several lines", header)
+		}
+	}
+	domain := "localhost"
+	if at := strings.LastIndex(s.from, "@"); at >= 0 {
+		domain = strings.Trim(s.from[at+1:], "<> ")
+	}
+	var data bytes.Buffer
+	fmt.Fprintf(&data, "From: %s\r\n", s.from)
+	fmt.Fprintf(&data, "To: %s\r\n", message.To)
+	fmt.Fprintf(&data, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(message.Subject), " ")))
+	fmt.Fprintf(&data, "Date: %s\r\n", s.now().Format(time.RFC1123Z))
+	if message.ID != "" {
+		fmt.Fprintf(&data, "Message-ID: <%s@%s>\r\n", message.ID, domain)
+	}
+	data.WriteString("MIME-Version: 1.0\r\n")
+	data.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	data.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	data.WriteString(strings.ReplaceAll(strings.ReplaceAll(message.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return data.Bytes(), nil
+}
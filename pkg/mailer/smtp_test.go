@@ -0,0 +1,168 @@
+package mailer
+
+import (
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receivedMail is a message accepted by fakeSMTPServer
+type receivedMail struct {
+	from string
+	to   []string
+	data string
+	// auth is the decoded PLAIN credentials the client authenticated with
+	auth string
+}
+
+// fakeSMTPServer speaks just enough SMTP on localhost for net/smtp to deliver messages to it
+// rejecting rejects every message with a temporary failure
+type fakeSMTPServer struct {
+	listener  net.Listener
+	mu        sync.Mutex
+	received  []receivedMail
+	rejecting bool
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &fakeSMTPServer{listener: listener}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (s *fakeSMTPServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeSMTPServer) messages() []receivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedMail(nil), s.received...)
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(textproto.NewConn(conn))
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn *textproto.Conn) {
+	defer conn.Close()
+	var mail receivedMail
+	conn.PrintfLine("220 fake ESMTP")
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case command == "EHLO":
+			conn.PrintfLine("250-fake\r\n250-8BITMIME\r\n250 AUTH PLAIN")
+		case command == "AUTH":
+			credentials, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, "AUTH PLAIN "))
+			mail.auth = string(credentials)
+			conn.PrintfLine("235 authenticated")
+		case command == "MAIL":
+			s.mu.Lock()
+			rejecting := s.rejecting
+			s.mu.Unlock()
+			if rejecting {
+				conn.PrintfLine("451 try again later")
+				continue
+			}
+			mail.from = strings.Fields(strings.TrimPrefix(line, "MAIL FROM:"))[0]
+			conn.PrintfLine("250 ok")
+		case command == "RCPT":
+			mail.to = append(mail.to, strings.TrimPrefix(line, "RCPT TO:"))
+			conn.PrintfLine("250 ok")
+		case command == "DATA":
+			conn.PrintfLine("354 go ahead")
+			data, err := conn.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			mail.data = string(data)
+			s.mu.Lock()
+			s.received = append(s.received, mail)
+			s.mu.Unlock()
+			mail = receivedMail{auth: mail.auth}
+			conn.PrintfLine("250 queued")
+		case command == "QUIT":
+			conn.PrintfLine("221 bye")
+			return
+		default:
+			conn.PrintfLine("250 ok")
+		}
+	}
+}
+
+func TestSMTPSender_Send(t *testing.T) {
+	message := Message{ID: "2Ez7QnPSq9", To: "alice@example.com", Subject: "Invitation to Café survey", Body: "Hello\n.\nAnswer it"}
+	t.Run("should deliver the message with its headers to the smtp server", func(t *testing.T) {
+		server := newFakeSMTPServer(t)
+		sender := NewSMTPSender(server.addr(), "surveys@example.com", nil)
+		sender.now = func() time.Time { return time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC) }
+		require.NoError(t, sender.Send(message))
+		received := server.messages()
+		require.Len(t, received, 1)
+		assert.Equal(t, "<surveys@example.com>", received[0].from)
+		assert.Equal(t, []string{"<alice@example.com>"}, received[0].to)
+		assert.Equal(t, "From: surveys@example.com\n"+
+			"To: alice@example.com\n"+
+			"Subject: =?utf-8?q?Invitation_to_Caf=C3=A9_survey?=\n"+
+			"Date: Thu, 01 Jul 2021 10:00:00 +0000\n"+
+			"Message-ID: <2Ez7QnPSq9@example.com>\n"+
+			"MIME-Version: 1.0\n"+
+			"Content-Type: text/plain; charset=utf-8\n"+
+			"Content-Transfer-Encoding: 8bit\n"+
+			"\n"+
+			"Hello\n.\nAnswer it\n", received[0].data)
+	})
+	t.Run("should authenticate with the credentials", func(t *testing.T) {
+		server := newFakeSMTPServer(t)
+		sender := NewSMTPSender(server.addr(), "surveys@example.com", PlainAuth(server.addr(), "surveys", "secret"))
+		require.NoError(t, sender.Send(message))
+		received := server.messages()
+		require.Len(t, received, 1)
+		assert.Equal(t, "\x00surveys\x00secret", received[0].auth)
+	})
+	t.Run("should return error when the server rejects the message", func(t *testing.T) {
+		server := newFakeSMTPServer(t)
+		server.rejecting = true
+		assert.Error(t, NewSMTPSender(server.addr(), "surveys@example.com", nil).Send(message))
+		assert.Empty(t, server.messages())
+	})
+	t.Run("should return error when the server is unreachable", func(t *testing.T) {
+		server := newFakeSMTPServer(t)
+		server.listener.Close()
+		assert.Error(t, NewSMTPSender(server.addr(), "surveys@example.com", nil).Send(message))
+	})
+	t.Run("should not send headers spanning several lines", func(t *testing.T) {
+		server := newFakeSMTPServer(t)
+		injected := message
+		injected.To = "alice@example.com\r\nBcc: eve@example.com"
+		assert.Error(t, NewSMTPSender(server.addr(), "surveys@example.com", nil).Send(injected))
+		assert.Empty(t, server.messages())
+	})
+}
+
+func TestPlainAuth(t *testing.T) {
+	t.Run("should not authenticate without a username", func(t *testing.T) {
+		assert.Nil(t, PlainAuth("localhost:25", "", ""))
+		assert.NotNil(t, PlainAuth("localhost:25", "surveys", "secret"))
+	})
+}
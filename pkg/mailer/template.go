@@ -0,0 +1,73 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// DefaultInvitation and DefaultReminder are the templates used unless others are configured
+const (
+	DefaultInvitation = `{{define "subject"}}You are invited to answer {{.Survey}}{{end}}
+{{define "body"}}Hello{{if .Name}} {{.Name}}{{end}},
+
+you are invited to answer the survey {{.Survey}}
+{{- if .ClosesAt}} before {{.ClosesAt.Format "January 2, 2006 15:04 MST"}}{{end}}, answer it at
+{{.Link}}
+
+The link is personal, please do not share it.
+{{end}}`
+	DefaultReminder = `{{define "subject"}}Reminder: {{.Survey}} is waiting for your answers{{end}}
+{{define "body"}}Hello{{if .Name}} {{.Name}}{{end}},
+
+you have not answered the survey {{.Survey}} yet
+{{- if .ClosesAt}}, it closes on {{.ClosesAt.Format "January 2, 2006 15:04 MST"}}{{end}}. Answer it at
+{{.Link}}
+
+The link is personal, please do not share it.
+{{end}}`
+)
+
+// Template renders the subject and the body of an email from the "subject" and "body" templates it defines
+type Template struct {
+	template *template.Template
+}
+
+// ParseTemplate parses text, which has to define a "subject" and a "body" template
+func ParseTemplate(name, text string) (*Template, error) {
+	parsed, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	for _, required := range []string{"subject", "body"} {
+		if parsed.Lookup(required) == nil {
+			return nil, fmt.Errorf("template %s does not define %q", name, required)
+		}
+	}
+	return &Template{template: parsed}, nil
+}
+
+// ParseTemplateFile parses the template in fileName, see ParseTemplate
+func ParseTemplateFile(fileName string) (*Template, error) {
+	text, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTemplate(filepath.Base(fileName), string(text))
+}
+
+// Render executes the template with data, line breaks in the subject are replaced by spaces as headers are single lines
+func (t *Template) Render(data interface{}) (subject, body string, err error) {
+	var rendered strings.Builder
+	if err = t.template.ExecuteTemplate(&rendered, "subject", data); err != nil {
+		return "", "", err
+	}
+	subject = strings.Join(strings.Fields(rendered.String()), " ")
+	rendered.Reset()
+	if err = t.template.ExecuteTemplate(&rendered, "body", data); err != nil {
+		return "", "", err
+	}
+	return subject, rendered.String(), nil
+}
@@ -0,0 +1,73 @@
+package mailer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type invitation struct {
+	Name     string
+	Survey   string
+	Link     string
+	ClosesAt *time.Time
+}
+
+func TestTemplate_Render(t *testing.T) {
+	t.Run("should render the default invitation", func(t *testing.T) {
+		template, err := ParseTemplate("invitation", DefaultInvitation)
+		require.NoError(t, err)
+		closesAt := time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)
+		subject, body, err := template.Render(invitation{Name: "Alice", Survey: "Onboarding", Link: "https://example.com/t", ClosesAt: &closesAt})
+		require.NoError(t, err)
+		assert.Equal(t, "You are invited to answer Onboarding", subject)
+		assert.Equal(t, "Hello Alice,\n\nyou are invited to answer the survey Onboarding before July 1, 2021 10:00 UTC, answer it at\n"+
+			"https://example.com/t\n\nThe link is personal, please do not share it.\n", body)
+	})
+	t.Run("should render the default reminder without name and closing time", func(t *testing.T) {
+		template, err := ParseTemplate("reminder", DefaultReminder)
+		require.NoError(t, err)
+		subject, body, err := template.Render(invitation{Survey: "Onboarding", Link: "https://example.com/t"})
+		require.NoError(t, err)
+		assert.Equal(t, "Reminder: Onboarding is waiting for your answers", subject)
+		assert.Equal(t, "Hello,\n\nyou have not answered the survey Onboarding yet. Answer it at\nhttps://example.com/t\n\n"+
+			"The link is personal, please do not share it.\n", body)
+	})
+	t.Run("should keep the subject on a single line", func(t *testing.T) {
+		template, err := ParseTemplate("invitation", `{{define "subject"}}{{.Survey}}{{end}}{{define "body"}}{{end}}`)
+		require.NoError(t, err)
+		subject, _, err := template.Render(invitation{Survey: "two\r\nlines"})
+		require.NoError(t, err)
+		assert.Equal(t, "two lines", subject)
+	})
+	t.Run("should return error when data misses a field", func(t *testing.T) {
+		template, err := ParseTemplate("invitation", `{{define "subject"}}{{.Unknown}}{{end}}{{define "body"}}{{end}}`)
+		require.NoError(t, err)
+		_, _, err = template.Render(invitation{})
+		assert.Error(t, err)
+	})
+}
+
+func TestParseTemplate(t *testing.T) {
+	t.Run("should reject templates without subject or body", func(t *testing.T) {
+		_, err := ParseTemplate("invitation", `{{define "subject"}}hello{{end}}`)
+		assert.Error(t, err)
+		_, err = ParseTemplate("invitation", `{{define "subject"}}hello`)
+		assert.Error(t, err)
+	})
+	t.Run("should parse templates from files", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "invitation.tmpl")
+		require.NoError(t, os.WriteFile(fileName, []byte(`{{define "subject"}}hi {{.Name}}{{end}}{{define "body"}}{{.Link}}{{end}}`), 0600))
+		template, err := ParseTemplateFile(fileName)
+		require.NoError(t, err)
+		subject, body, err := template.Render(invitation{Name: "Alice", Link: "https://example.com/t"})
+		require.NoError(t, err)
+		assert.Equal(t, "hi Alice", subject)
+		assert.Equal(t, "https://example.com/t", body)
+		_, err = ParseTemplateFile(filepath.Join(t.TempDir(), "missing.tmpl"))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,43 @@
+package search
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	t.Run("should stem words as the reference implementation does", func(t *testing.T) {
+		// pairs taken from the vocabulary published along with the algorithm
+		pairs := map[string]string{
+			"caresses": "caress", "ponies": "poni", "ties": "ti", "caress": "caress", "cats": "cat",
+			"feed": "feed", "agreed": "agre", "plastered": "plaster", "bled": "bled", "motoring": "motor", "sing": "sing",
+			"conflated": "conflat", "troubled": "troubl", "sized": "size", "hopping": "hop", "tanned": "tan",
+			"falling": "fall", "hissing": "hiss", "fizzed": "fizz", "failing": "fail", "filing": "file",
+			"happy": "happi", "sky": "sky",
+			"relational": "relat", "conditional": "condit", "rational": "ration", "valenci": "valenc", "digitizer": "digit",
+			"operator": "oper", "feudalism": "feudal", "decisiveness": "decis", "hopefulness": "hope",
+			"callousness": "callous", "formaliti": "formal", "sensitiviti": "sensit", "sensibiliti": "sensibl",
+			"triplicate": "triplic", "formative": "form", "formalize": "formal", "electriciti": "electr",
+			"electrical": "electr", "hopeful": "hope", "goodness": "good",
+			"revival": "reviv", "allowance": "allow", "inference": "infer", "airliner": "airlin", "gyroscopic": "gyroscop",
+			"adjustable": "adjust", "defensible": "defens", "irritant": "irrit", "replacement": "replac",
+			"adjustment": "adjust", "dependent": "depend", "adoption": "adopt", "homologous": "homolog",
+			"communism": "commun", "activate": "activ", "angulariti": "angular", "effective": "effect",
+			"bowdlerize": "bowdler", "probate": "probat", "rate": "rate", "cease": "ceas", "controll": "control",
+			"roll": "roll", "generalizations": "gener", "oscillators": "oscil",
+		}
+		for word, stem := range pairs {
+			assert.Equal(t, stem, Stem(word), word)
+		}
+	})
+	t.Run("should give the same stem to the forms of a word", func(t *testing.T) {
+		assert.Equal(t, Stem("restaurant"), Stem("restaurants"))
+		assert.Equal(t, Stem("satisfied"), Stem("satisfies"))
+		assert.Equal(t, Stem("connection"), Stem("connecting"))
+	})
+	t.Run("should keep short and non ascii words", func(t *testing.T) {
+		assert.Equal(t, "is", Stem("is"))
+		assert.Equal(t, "café", Stem("café"))
+		assert.Equal(t, "covid19", Stem("covid19"))
+	})
+}
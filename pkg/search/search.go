@@ -0,0 +1,206 @@
+// Package search contains an in memory inverted index ranking documents against free text queries
+package search
+
+import (
+	"github.com/segmentio/ksuid"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// prefixWeight scales the score of words matched by prefix, so that whole words rank first
+const prefixWeight = 0.5
+
+// stopWords are left out of the index and of queries, they would match nearly every document
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true, "do": true,
+	"does": true, "for": true, "from": true, "has": true, "have": true, "how": true, "in": true, "is": true, "it": true,
+	"of": true, "on": true, "or": true, "that": true, "the": true, "this": true, "to": true, "was": true, "were": true,
+	"what": true, "which": true, "with": true,
+}
+
+// Field is a text of a document, each word found in a field counts Weight times
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Hit is a document matching a query along with its score, higher scores match better
+type Hit struct {
+	ID    ksuid.KSUID
+	Score float64
+}
+
+// Index maps the stems of the words of documents to the documents containing them, it is safe for concurrent use
+// a query word matches the documents containing a word with the same stem, or a word it is a prefix of
+type Index struct {
+	mu sync.RWMutex
+	// postings holds the weighted frequency of each stem in each document
+	postings map[string]map[ksuid.KSUID]float64
+	// documents keeps what each document added to the index, so that it can be removed
+	documents map[ksuid.KSUID]document
+	// words counts the documents containing each word, sorted holds the same words in order for prefix lookups
+	words  map[string]int
+	sorted []string
+}
+
+type document struct {
+	stems []string
+	words []string
+}
+
+func NewIndex() *Index {
+	return &Index{
+		postings:  make(map[string]map[ksuid.KSUID]float64),
+		documents: make(map[ksuid.KSUID]document),
+		words:     make(map[string]int),
+	}
+}
+
+// Tokenize splits text into lowercase words, leaving out punctuation and stop words
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words := fields[:0]
+	for _, word := range fields {
+		if !stopWords[word] {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// Len returns the number of indexed documents
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.documents)
+}
+
+// Add indexes the document id with its fields, replacing what was indexed for it before
+func (i *Index) Add(id ksuid.KSUID, fields ...Field) {
+	frequencies := make(map[string]float64)
+	seen := make(map[string]bool)
+	var doc document
+	for _, field := range fields {
+		for _, word := range Tokenize(field.Text) {
+			stem := Stem(word)
+			if _, ok := frequencies[stem]; !ok {
+				doc.stems = append(doc.stems, stem)
+			}
+			frequencies[stem] += field.Weight
+			if !seen[word] {
+				seen[word] = true
+				doc.words = append(doc.words, word)
+			}
+		}
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.remove(id)
+	for stem, frequency := range frequencies {
+		if i.postings[stem] == nil {
+			i.postings[stem] = make(map[ksuid.KSUID]float64)
+		}
+		i.postings[stem][id] = frequency
+	}
+	for _, word := range doc.words {
+		if i.words[word]++; i.words[word] == 1 {
+			at := sort.SearchStrings(i.sorted, word)
+			i.sorted = append(i.sorted, "")
+			copy(i.sorted[at+1:], i.sorted[at:])
+			i.sorted[at] = word
+		}
+	}
+	i.documents[id] = doc
+}
+
+// Remove drops the document id from the index, removing a document which is not indexed does nothing
+func (i *Index) Remove(id ksuid.KSUID) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.remove(id)
+}
+
+// remove drops the document id, the caller must hold mu
+func (i *Index) remove(id ksuid.KSUID) {
+	doc, ok := i.documents[id]
+	if !ok {
+		return
+	}
+	delete(i.documents, id)
+	for _, stem := range doc.stems {
+		delete(i.postings[stem], id)
+		if len(i.postings[stem]) == 0 {
+			delete(i.postings, stem)
+		}
+	}
+	for _, word := range doc.words {
+		if i.words[word]--; i.words[word] == 0 {
+			delete(i.words, word)
+			at := sort.SearchStrings(i.sorted, word)
+			i.sorted = append(i.sorted[:at], i.sorted[at+1:]...)
+		}
+	}
+}
+
+// Search returns the documents matching any word of query, best first, ties are ordered by id
+// each query word adds the score of its best matching stem in a document, which grows with the weighted frequency
+// of the stem in the document and with how rare the stem is across documents, stems of words merely starting with
+// the query word count prefixWeight times
+// at most limit hits are returned, all of them when limit is not positive
+func (i *Index) Search(query string, limit int) []Hit {
+	words := Tokenize(query)
+	i.mu.RLock()
+	scores := make(map[ksuid.KSUID]float64)
+	for _, word := range words {
+		matches := map[string]float64{Stem(word): 1}
+		for _, prefixed := range i.prefixed(word) {
+			if stem := Stem(prefixed); matches[stem] == 0 {
+				matches[stem] = prefixWeight
+			}
+		}
+		// a document scores once per query word, with its best matching stem
+		best := make(map[ksuid.KSUID]float64)
+		for stem, weight := range matches {
+			postings := i.postings[stem]
+			idf := math.Log(1 + float64(len(i.documents))/float64(len(postings)))
+			for id, frequency := range postings {
+				if score := weight * idf * math.Log1p(frequency); score > best[id] {
+					best[id] = score
+				}
+			}
+		}
+		for id, score := range best {
+			scores[id] += score
+		}
+	}
+	i.mu.RUnlock()
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return ksuid.Compare(hits[a].ID, hits[b].ID) < 0
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// prefixed returns the indexed words starting with prefix, the caller must hold mu
+func (i *Index) prefixed(prefix string) []string {
+	at := sort.SearchStrings(i.sorted, prefix)
+	end := at
+	for end < len(i.sorted) && strings.HasPrefix(i.sorted[end], prefix) {
+		end++
+	}
+	return i.sorted[at:end]
+}
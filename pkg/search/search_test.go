@@ -0,0 +1,73 @@
+package search
+
+import (
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func ids(hits []Hit) []ksuid.KSUID {
+	found := make([]ksuid.KSUID, 0, len(hits))
+	for _, hit := range hits {
+		found = append(found, hit.ID)
+	}
+	return found
+}
+
+func TestTokenize(t *testing.T) {
+	t.Run("should lowercase words and leave out punctuation and stop words", func(t *testing.T) {
+		assert.Equal(t, []string{"did", "you", "enjoy", "café", "2021"}, Tokenize("Did you enjoy the Café (2021)?"))
+	})
+}
+
+func TestIndex_Search(t *testing.T) {
+	restaurant, parking, delivery := ksuid.New(), ksuid.New(), ksuid.New()
+	newIndex := func() *Index {
+		index := NewIndex()
+		index.Add(restaurant, Field{Text: "Restaurant feedback", Weight: 3}, Field{Text: "Did you like the food?", Weight: 1})
+		index.Add(parking, Field{Text: "Parking", Weight: 3}, Field{Text: "Was the restaurant easy to reach?", Weight: 1})
+		index.Add(delivery, Field{Text: "Delivery", Weight: 3}, Field{Text: "Was the food delivered warm?", Weight: 1})
+		return index
+	}
+	t.Run("should rank documents matching in heavier fields first", func(t *testing.T) {
+		hits := newIndex().Search("restaurant", 0)
+		assert.Equal(t, []ksuid.KSUID{restaurant, parking}, ids(hits))
+		assert.Greater(t, hits[0].Score, hits[1].Score)
+	})
+	t.Run("should match words with the same stem", func(t *testing.T) {
+		assert.Equal(t, []ksuid.KSUID{restaurant, parking}, ids(newIndex().Search("Restaurants", 0)))
+		assert.Equal(t, []ksuid.KSUID{delivery}, ids(newIndex().Search("delivering", 0)))
+	})
+	t.Run("should match words by prefix after whole words", func(t *testing.T) {
+		assert.Equal(t, []ksuid.KSUID{delivery}, ids(newIndex().Search("deliv", 0)))
+		assert.Equal(t, []ksuid.KSUID{restaurant, parking}, ids(newIndex().Search("resta", 0)))
+		index := newIndex()
+		parkway := ksuid.New()
+		index.Add(parkway, Field{Text: "Parkway", Weight: 3})
+		assert.Equal(t, []ksuid.KSUID{parking, parkway}, ids(index.Search("park", 0)))
+	})
+	t.Run("should rank documents matching more query words first", func(t *testing.T) {
+		hits := newIndex().Search("food delivery", 0)
+		assert.Equal(t, delivery, hits[0].ID)
+		assert.Len(t, hits, 2)
+	})
+	t.Run("should return at most limit hits", func(t *testing.T) {
+		assert.Len(t, newIndex().Search("food delivery", 1), 1)
+	})
+	t.Run("should return no hits for stop words and unknown words", func(t *testing.T) {
+		assert.Empty(t, newIndex().Search("the", 0))
+		assert.Empty(t, newIndex().Search("zebra", 0))
+		assert.Empty(t, newIndex().Search("", 0))
+	})
+	t.Run("should replace a document added again and drop removed ones", func(t *testing.T) {
+		index := newIndex()
+		index.Add(parking, Field{Text: "Car park", Weight: 3})
+		assert.Equal(t, []ksuid.KSUID{restaurant}, ids(index.Search("restaurant", 0)))
+		assert.Equal(t, []ksuid.KSUID{parking}, ids(index.Search("car", 0)))
+		index.Remove(parking)
+		index.Remove(ksuid.New())
+		assert.Empty(t, index.Search("car", 0))
+		assert.Empty(t, index.Search("par", 0))
+		assert.Equal(t, 2, index.Len())
+	})
+}
@@ -0,0 +1,52 @@
+// Package signer signs values with HMAC-SHA256, so that the values can be handed out and checked when they come back
+// without storing them
+package signer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidSignature is returned when a signed value was not signed with the secret of the signer or was altered since
+var ErrInvalidSignature = errors.New("signature is invalid")
+
+// separator ends the value of a signed value, the signature follows it
+const separator = "."
+
+// Signer signs values with a secret, it is safe for concurrent use
+type Signer struct {
+	secret []byte
+}
+
+// NewSigner returns a signer using secret, values signed with one secret only verify with the same secret
+func NewSigner(secret []byte) *Signer {
+	return &Signer{secret: secret}
+}
+
+// Sign returns value followed by its signature, the signature is url safe so the result fits in links
+// when value is url safe as well
+func (s *Signer) Sign(value string) string {
+	return value + separator + s.signature(value)
+}
+
+// Verify returns the value of signed, ErrInvalidSignature is returned when the signature does not match the value
+func (s *Signer) Verify(signed string) (string, error) {
+	i := strings.LastIndex(signed, separator)
+	if i < 0 {
+		return "", ErrInvalidSignature
+	}
+	value, signature := signed[:i], signed[i+len(separator):]
+	if !hmac.Equal([]byte(signature), []byte(s.signature(value))) {
+		return "", ErrInvalidSignature
+	}
+	return value, nil
+}
+
+func (s *Signer) signature(value string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
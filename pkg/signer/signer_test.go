@@ -0,0 +1,32 @@
+package signer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestSigner(t *testing.T) {
+	t.Run("should verify the values it signed", func(t *testing.T) {
+		s := NewSigner([]byte("secret"))
+		signed := s.Sign("survey.invitation")
+		assert.True(t, strings.HasPrefix(signed, "survey.invitation."))
+		value, err := s.Verify(signed)
+		assert.NoError(t, err)
+		assert.Equal(t, "survey.invitation", value)
+	})
+	t.Run("should reject altered values and values signed with another secret", func(t *testing.T) {
+		s := NewSigner([]byte("secret"))
+		signed := s.Sign("survey.invitation")
+		for _, tampered := range []string{
+			"survey.other" + signed[len("survey.invitation"):],
+			signed[:len(signed)-1],
+			NewSigner([]byte("other secret")).Sign("survey.invitation"),
+			"unsigned",
+			"",
+		} {
+			_, err := s.Verify(tampered)
+			assert.Equal(t, ErrInvalidSignature, err, tampered)
+		}
+	})
+}
@@ -0,0 +1,158 @@
+// Package stats contains the descriptive statistics used to summarise survey answers
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Mean returns the arithmetic mean of values, zero when there are none
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+// Median returns the middle value of values, the mean of the two middle values for an even count
+// and zero when there are none, values is not modified
+func Median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// StdDev returns the sample standard deviation of values, zero when there are less than two
+func StdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	mean := Mean(values)
+	squares := 0.0
+	for _, value := range values {
+		squares += (value - mean) * (value - mean)
+	}
+	return math.Sqrt(squares / float64(len(values)-1))
+}
+
+// Percentage returns part as a percentage of total, zero when total is zero
+func Percentage(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
+
+// ChiSquare runs Pearson's chi-square test of independence on a contingency table of observed counts
+// rows and columns without any observation are left out, the test needs at least two of each to have a degree of freedom
+// otherwise the statistic and degrees of freedom are zero and the p value is one
+func ChiSquare(observed [][]int) (statistic float64, degreesOfFreedom int, pValue float64) {
+	rowTotals, columnTotals, total := totals(observed)
+	rows, columns := 0, 0
+	for _, rowTotal := range rowTotals {
+		if rowTotal > 0 {
+			rows++
+		}
+	}
+	for _, columnTotal := range columnTotals {
+		if columnTotal > 0 {
+			columns++
+		}
+	}
+	if rows < 2 || columns < 2 {
+		return 0, 0, 1
+	}
+	for i, row := range observed {
+		for j, count := range row {
+			if rowTotals[i] == 0 || columnTotals[j] == 0 {
+				continue
+			}
+			expected := float64(rowTotals[i]) * float64(columnTotals[j]) / float64(total)
+			statistic += (float64(count) - expected) * (float64(count) - expected) / expected
+		}
+	}
+	degreesOfFreedom = (rows - 1) * (columns - 1)
+	return statistic, degreesOfFreedom, ChiSquareSurvival(statistic, degreesOfFreedom)
+}
+
+// totals returns the row, column and grand totals of a table
+func totals(table [][]int) (rowTotals, columnTotals []int, total int) {
+	rowTotals = make([]int, len(table))
+	for i, row := range table {
+		if len(row) > len(columnTotals) {
+			columnTotals = append(columnTotals, make([]int, len(row)-len(columnTotals))...)
+		}
+		for j, count := range row {
+			rowTotals[i] += count
+			columnTotals[j] += count
+			total += count
+		}
+	}
+	return rowTotals, columnTotals, total
+}
+
+// ChiSquareSurvival returns the probability of a chi-square statistic of at least x with the given degrees of freedom
+func ChiSquareSurvival(x float64, degreesOfFreedom int) float64 {
+	if x <= 0 || degreesOfFreedom <= 0 {
+		return 1
+	}
+	return upperGamma(float64(degreesOfFreedom)/2, x/2)
+}
+
+const (
+	gammaIterations = 200
+	gammaEpsilon    = 1e-14
+	gammaTiny       = 1e-300
+)
+
+// upperGamma returns the regularized upper incomplete gamma function Q(a, x)
+// using the series expansion below a+1 and the continued fraction above it
+func upperGamma(a, x float64) float64 {
+	lgamma, _ := math.Lgamma(a)
+	prefix := math.Exp(a*math.Log(x) - x - lgamma)
+	if x < a+1 {
+		term, sum := 1/a, 1/a
+		for n := 1; n < gammaIterations; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*gammaEpsilon {
+				break
+			}
+		}
+		return math.Max(0, 1-sum*prefix)
+	}
+	b := x + 1 - a
+	c := 1 / gammaTiny
+	d := 1 / b
+	h := d
+	for n := 1; n < gammaIterations; n++ {
+		an := -float64(n) * (float64(n) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < gammaTiny {
+			d = gammaTiny
+		}
+		c = b + an/c
+		if math.Abs(c) < gammaTiny {
+			c = gammaTiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < gammaEpsilon {
+			break
+		}
+	}
+	return prefix * h
+}
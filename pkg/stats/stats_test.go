@@ -0,0 +1,80 @@
+package stats
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMean(t *testing.T) {
+	t.Run("should return the mean of the values", func(t *testing.T) {
+		assert.Equal(t, 2.5, Mean([]float64{1, 2, 3, 4}))
+	})
+	t.Run("should return zero without values", func(t *testing.T) {
+		assert.Equal(t, 0.0, Mean(nil))
+	})
+}
+
+func TestMedian(t *testing.T) {
+	t.Run("should return the middle value of an odd count", func(t *testing.T) {
+		assert.Equal(t, 3.0, Median([]float64{5, 1, 3}))
+	})
+	t.Run("should return the mean of the middle values of an even count without sorting the input", func(t *testing.T) {
+		values := []float64{4, 1, 3, 2}
+		assert.Equal(t, 2.5, Median(values))
+		assert.Equal(t, []float64{4, 1, 3, 2}, values)
+	})
+	t.Run("should return zero without values", func(t *testing.T) {
+		assert.Equal(t, 0.0, Median(nil))
+	})
+}
+
+func TestStdDev(t *testing.T) {
+	t.Run("should return the sample standard deviation", func(t *testing.T) {
+		assert.InDelta(t, 2.138, StdDev([]float64{2, 4, 4, 4, 5, 5, 7, 9}), 0.001)
+	})
+	t.Run("should return zero for a single value", func(t *testing.T) {
+		assert.Equal(t, 0.0, StdDev([]float64{3}))
+	})
+}
+
+func TestPercentage(t *testing.T) {
+	t.Run("should return part as a percentage of total", func(t *testing.T) {
+		assert.Equal(t, 25.0, Percentage(1, 4))
+	})
+	t.Run("should return zero when total is zero", func(t *testing.T) {
+		assert.Equal(t, 0.0, Percentage(0, 0))
+	})
+}
+
+func TestChiSquare(t *testing.T) {
+	t.Run("should test the independence of a table", func(t *testing.T) {
+		statistic, degreesOfFreedom, pValue := ChiSquare([][]int{{10, 20}, {30, 40}})
+		assert.InDelta(t, 0.794, statistic, 0.001)
+		assert.Equal(t, 1, degreesOfFreedom)
+		assert.InDelta(t, 0.373, pValue, 0.001)
+	})
+	t.Run("should leave out empty rows and columns", func(t *testing.T) {
+		statistic, degreesOfFreedom, pValue := ChiSquare([][]int{{10, 0, 20}, {0, 0, 0}, {30, 0, 40}})
+		assert.InDelta(t, 0.794, statistic, 0.001)
+		assert.Equal(t, 1, degreesOfFreedom)
+		assert.InDelta(t, 0.373, pValue, 0.001)
+	})
+	t.Run("should not test tables with a single row or column", func(t *testing.T) {
+		statistic, degreesOfFreedom, pValue := ChiSquare([][]int{{10, 20}, {0, 0}})
+		assert.Equal(t, 0.0, statistic)
+		assert.Equal(t, 0, degreesOfFreedom)
+		assert.Equal(t, 1.0, pValue)
+	})
+}
+
+func TestChiSquareSurvival(t *testing.T) {
+	t.Run("should return the upper tail probability", func(t *testing.T) {
+		assert.InDelta(t, 0.05, ChiSquareSurvival(3.841, 1), 0.0005)
+		assert.InDelta(t, 0.05, ChiSquareSurvival(9.488, 4), 0.0005)
+		assert.InDelta(t, 0.01, ChiSquareSurvival(23.209, 10), 0.0005)
+		assert.InDelta(t, 0.9, ChiSquareSurvival(0.0158, 1), 0.001)
+	})
+	t.Run("should return one without a statistic", func(t *testing.T) {
+		assert.Equal(t, 1.0, ChiSquareSurvival(0, 3))
+	})
+}